@@ -84,6 +84,15 @@ type GlobalConfig struct {
 	VMPoolSize                int      `toml:"vm_pool_size" comment:"The size of the pool of virtual machines (VMs) used to execute ASP scripts. Each VM can execute one script at a time, so having a pool of VMs allows the server to handle multiple requests concurrently faster. Setting this value to a bigger number can help improve performance by allowing more scripts to be executed simultaneously, but setting it too high may lead to increased memory usage and resource contention, while setting it too low may result in slower response times during periods of high traffic. Adjust this value based on the expected traffic to your website and the available resources on your server. You should use a number bigger than 1. A pool size of 10 VMs in a server with 512mb of memory can respond to approximately 2000 simultaneous requests for simple pages."`
 	GolangMemoryLimitMB       int      `toml:"golang_memory_limit_mb" comment:"The maximum amount of memory in megabytes that the Go runtime is allowed to use. This can help prevent the server from consuming too much memory and potentially crashing. Setting it to 0 means no limit, but it's generally recommended to set a reasonable limit to ensure stability. If your server has limited memory resources, you may want to set this to a lower value to prevent out-of-memory errors. Setting this value too low may lead to performance issues or out-of-memory errors, while setting it too high may allow the server to consume more memory than is available, leading to crashes. Also note that this setting may not be strictly enforced by the Go runtime, and actual memory usage may vary based on the workload and garbage collection behavior. If your server is missing requests, low the vm_pool_size and up the memory limit, as this usually means the requests are getting blocked by the Garbage Collector. This directive is more important than  vm_pool_size, and directly influence how some libraries like zstd work."`
 	SessionFlushIntervalSecs  int      `toml:"session_flush_interval_seconds" comment:"Interval in seconds used to asynchronously flush dirty in-memory sessions to disk. A value greater than 0 keeps session writes off the request hot path while still guaranteeing a safe flush on process shutdown."`
+	SessionStore              string   `toml:"session_store" comment:"Where sessions are persisted. \"file\" (default) keeps one .g3ses file per session in temp_dir/session, so a session only exists on the node that created it. \"sql\" keeps sessions in a database table reached through the G3DB drivers, so every node of a load-balanced farm shares them. The connection uses the [g3db] settings of session_store_driver."`
+	SessionStoreDriver        string   `toml:"session_store_driver" comment:"G3DB driver used by the SQL session store: \"sqlite\", \"mysql\", \"postgres\" or \"mssql\"."`
+	SessionStoreTable         string   `toml:"session_store_table" comment:"Table used by the SQL session store. It is created on first start when it does not exist."`
+	SessionCookieName         string   `toml:"session_cookie_name" comment:"Name of the session cookie. Nested applications of axonasp-http add their path to it, e.g. ASPSESSIONID_SHOP. Sites declared with [[server.sites]] and Caddy site blocks can override the three session_cookie keys."`
+	SessionCookieSecure       bool     `toml:"session_cookie_secure" comment:"Sends the session cookie with the Secure flag, so browsers only return it over HTTPS. Enable it when every request reaches the site over HTTPS, including through a TLS-terminating proxy."`
+	SessionCookieSameSite     string   `toml:"session_cookie_samesite" comment:"SameSite mode of the session cookie: \"lax\" (default), \"strict\" or \"none\". \"none\" lets the session follow cross-site requests, such as pages embedded in another site, and always adds the Secure flag, as browsers require."`
+	SessionSigningKey         string   `toml:"session_signing_key" comment:"Secret used to sign the session cookie with HMAC-SHA256. When set, the cookie holds the session ID followed by its signature, and cookies without a valid signature start a new session, so clients cannot make up or guess session IDs. Setting or changing it starts a new session for every visitor. Leave it empty and set the AXONASP_SESSION_SIGNING_KEY environment variable to keep the secret out of this file. Every node of a farm must use the same key."`
+	SessionEncryptionKey      string   `toml:"session_encryption_key" comment:"Secret used to encrypt stored sessions with AES-256-GCM, in the session files and in the SQL session store. Use a long random value. Sessions stored before the key was set are still read and are encrypted when they are saved again; sessions encrypted with another key are discarded. Leave it empty and set the AXONASP_SESSION_ENCRYPTION_KEY environment variable to keep the secret out of this file. Every node of a farm must use the same key."`
+	ApplicationStore          string   `toml:"application_store" comment:"Where Application.Contents is kept. \"memory\" (default) keeps it in each process, so the workers of axonasp-fpm or several FastCGI workers each have their own Application. \"file\" shares it between the axonasp-fastcgi workers or the concurrent axonasp-cgi requests of a site through one locked file in temp_dir/application: Application.Lock holds every worker, Application_OnStart runs in the first worker that starts and Application_OnEnd in the last that stops."`
 	AdodbPlatformArchitecture string   `toml:"adodb_platform_architecture" comment:"The architecture of the platform for which the ADODB library is called from (just for Access Database and in Windows). This is important for compatibility with the database drivers used by your ASP applications. If you are running a 64-bit operating system, you should set this to \"amd64\". If you are running a 32-bit operating system, you should set this to \"386\". You can use the 386 ADODB on your 64-bit Windows server, but you need to install the 32-bit version. You can also set it to \"auto\" to let the server automatically detect the architecture of the platform it is running on."`
 	ExecuteAsASP              []string `toml:"execute_as_asp" comment:"List of file extensions that will be treated as ASP scripts and executed by the server. You can add or remove extensions from this list based on your needs. For example, if you want to execute .aspx files as ASP scripts, you can add \".aspx\" to the list. Make sure to include the dot before the extension. The server will check the requested file's extension against this list to determine whether to execute it as an ASP script or serve it as a static file."`
 	ExecuteAsVBScript         []string `toml:"execute_as_vbscript" comment:"List of file extensions that will be treated as VBScript and executed by the server. You can add or remove extensions from this list based on your needs. Make sure to include the dot before the extension. The server will check the requested file's extension against this list to determine whether to execute it or serve it as a static file. This will only be used if engine_mode is set to vbscript."`
@@ -91,6 +100,8 @@ type GlobalConfig struct {
 	ViperWatchConfig          bool     `toml:"viper_watch_config" comment:"When enabled, the server will watch for changes in the configuration file and automatically reload the configuration without needing to restart the server. This can be useful for making changes to the server settings on the fly, but it may also introduce some overhead as the server needs to monitor the file for changes. It's generally recommended to keep this setting disabled in production environments for better performance and stability, and only enable it during development or when you need to make frequent changes to the configuration. This setting isn't full implented yet."`
 	ViperAutomaticEnv         bool     `toml:"viper_automatic_env" comment:"When enabled, the server will automatically read configuration values from environment variables that match the settings in this configuration file. This allows you to easily override settings without modifying the configuration file directly, which can be especially useful in containerized environments or when using a secrets management solution. The environment variables should be in uppercase and use underscores instead of dots. For example, to override the default_charset setting, you would set an environment variable named DEFAULT_CHARSET with the desired value. This provides flexibility in managing configurations across different environments (development, staging, production) without changing the code or configuration files."`
 	TempDir                   string   `toml:"temp_dir" comment:"Directory for temporary files used by the engine. This directory is used for storing temporary files created during the execution of ASP scripts, such as session data, cached compiled scripts, and other temporary resources. Make sure this directory is writable by the server process and has sufficient space to accommodate the temporary files generated by your applications. You can change this path to a different directory if needed, but ensure that it is properly secured and not accessible to unauthorized users."`
+	EnableAppOffline          bool     `toml:"enable_app_offline" comment:"When enabled, the server takes the application offline while a file named app_offline.htm exists in the web root, like IIS. New requests receive the content of the file with status 503, in-flight requests are allowed to finish and Application_OnEnd runs. Removing the file restarts the application: the script cache is cleared and Application_OnStart runs again. This allows deployments without restarting the server process."`
+	AppOfflineDrainTimeout    int      `toml:"app_offline_drain_timeout_seconds" comment:"Maximum time in seconds to wait for in-flight requests to finish after app_offline.htm appears, before Application_OnEnd runs. Requests still running when the time expires keep running."`
 }
 
 // CliConfig maps the [cli] configuration section.
@@ -114,6 +125,19 @@ type ServerConfig struct {
 	EnableDirectoryListing     bool     `toml:"enable_directory_listing" comment:"When enabled, the server will allow directory listing for directories that do not contain a default page. This means that if a client requests a directory and there is no default page (e.g., index.html) in that directory, the server will return a list of the files and subdirectories within that directory. This can be useful for development and debugging purposes, but it can also pose a security risk if sensitive files are exposed. It's generally recommended to keep this setting disabled in production environments to prevent unauthorized access to directory contents."`
 	DirectoryListingTemplate   string   `toml:"directory_listing_template" comment:"The path to the HTML template used for directory listing when enable_directory_listing is set to true. This template should include placeholders (see the default directory listing template) where the server will inject the list of files and directories. You can customize this template to match the design of your website and provide a better user experience when directory listing is enabled. Make sure to set this to the correct path where your custom directory listing template is located."`
 	EngineMode                 string   `toml:"engine_mode" comment:"Set the engine mode. Can be set to: default to execute ASP pages/code, vbscript to execute vbscript only or javascript, to execute javascript only."`
+	EnableTLS                  bool     `toml:"enable_tls" comment:"Enables the native HTTPS listener of axonasp-http. When enabled, the server keeps listening for plain HTTP on server_port and also accepts TLS connections on tls_port, so you no longer need Caddy or nginx in front of it just for HTTPS. HTTP/2 is negotiated automatically over TLS unless enable_http2 is false. The HTTPS, HTTPS_KEYSIZE, HTTPS_SERVER_*, CERT_* and SERVER_PORT_SECURE server variables reflect the real TLS connection state."`
+	TLSPort                    int      `toml:"tls_port" comment:"The port for the HTTPS listener. Only used when enable_tls is true."`
+	TLSCertFile                string   `toml:"tls_cert_file" comment:"The default certificate (PEM file). This certificate is served to clients that do not send SNI or request a host name that no other certificate covers."`
+	TLSKeyFile                 string   `toml:"tls_key_file" comment:"The private key (PEM file) of the default certificate."`
+	TLSMinVersion              string   `toml:"tls_min_version" comment:"Minimum TLS version accepted by the HTTPS listener: 1.0, 1.1, 1.2 or 1.3."`
+	TLSClientAuth              string   `toml:"tls_client_auth" comment:"Client certificate mode: none, request (ask for a certificate but accept requests without one) or require. Client certificate details are exposed through the CERT_* server variables."`
+	TLSClientCAFile            string   `toml:"tls_client_ca_file" comment:"When set, presented client certificates are verified against the CA certificates in this PEM file."`
+	TLSRedirectHTTP            bool     `toml:"tls_redirect_http" comment:"When true and enable_tls is set, the plain HTTP listener on server_port answers every request with a 301 redirect to the HTTPS listener instead of serving content."`
+	EnableHTTP2                bool     `toml:"enable_http2" comment:"Enables HTTP/2 on the HTTPS listener. HTTP/2 is never used on the plain HTTP listener."`
+	TLSReloadDebounceMs        int      `toml:"tls_reload_debounce_ms" comment:"The certificate and key files are watched for changes and reloaded automatically, so renewed certificates are picked up without restarting the server. This is the delay, in milliseconds, used to wait for the renewal to finish writing both files before reloading."`
+	EnableGracefulUpgrade      bool     `toml:"enable_graceful_upgrade" comment:"Enables zero-downtime upgrades of axonasp-http on Linux and other Unix systems. On SIGUSR2 or SIGHUP the running server starts its executable again, hands over its listening sockets and waits until the new process is ready. The old process then stops accepting connections, drains the requests in flight, flushes the sessions and exits. When disabled, these signals only log error 3012 and the server keeps running."`
+	UpgradeReadyTimeout        int      `toml:"upgrade_ready_timeout_seconds" comment:"Maximum time in seconds to wait for the new process to report that it is ready during a graceful upgrade. When it expires, the new process is stopped and the running server keeps serving."`
+	ShutdownTimeout            int      `toml:"shutdown_timeout_seconds" comment:"Maximum time in seconds to wait for in-flight requests when the server stops or hands over to a new process. Connections still open when it expires are closed. 0 closes them at once."`
 }
 
 // FastcgiConfig maps the [fastcgi] configuration section.
@@ -121,6 +145,75 @@ type FastcgiConfig struct {
 	DefaultPages []string `toml:"default_pages" comment:"List of default pages to try when a directory is accessed. The server will look for these files in order and serve the first one it finds when requested for a directory. This is similar to the default_pages setting in the [server] section, but it applies specifically to the FastCGI server. You can customize this list based on the default pages you want to serve for directories when using FastCGI."`
 	ServerPort   int      `toml:"server_port" comment:"Set the port number to the fastcgi server. Can also be a path to socket, e.g. \"unix:/tmp/axonasp.sock\" on *nix systems"`
 	EngineMode   string   `toml:"engine_mode" comment:"Set the engine mode. Can be set to: default to execute ASP pages/code, vbscript to execute vbscript only or javascript, to execute javascript only."`
+
+	RequestSlowlogTimeout   int    `toml:"request_slowlog_timeout" comment:"Number of seconds after which a request still running is written to the slow log, with the VBScript/JScript call stack of the page (procedure names, files and lines, and the #include chain). Set to 0 to disable the slow log. AxonASP-FPM sets it from the request_slowlog_timeout pool directive."`
+	RequestTerminateTimeout int    `toml:"request_terminate_timeout" comment:"Number of seconds after which a request still running is terminated with error 4013, whatever its Server.ScriptTimeout. Set to 0 to disable it."`
+	Slowlog                 string `toml:"slowlog" comment:"Slow log file. When empty, slow requests are written to standard error."`
+}
+
+// AccessLogConfig maps the [access_log] configuration section.
+type AccessLogConfig struct {
+	EnableAccessLog bool     `toml:"enable_access_log" comment:"When enabled, the http and fastcgi servers write one line per request to a W3C extended access log, the same format used by IIS. Log analyzers that understand IIS logs can read these files without changes. The http server writes to <log_directory>/http and the fastcgi server writes to <log_directory>/fastcgi, so both can run side by side."`
+	LogFormat       string   `toml:"log_format" comment:"Format of the access log files. Use \"w3c\" for the IIS W3C extended format (space separated fields with #Fields headers, .log files) or \"json\" for JSON lines (one JSON object per request, .json files), which is easier to ship to log collectors."`
+	LogDirectory    string   `toml:"log_directory" comment:"Directory where access log files are written. It is created if it does not exist. Make sure it is writable by the server process and monitor its size when rotation is disabled."`
+	LogFilePrefix   string   `toml:"log_file_prefix" comment:"Prefix of the access log file names. The default \"u_ex\" produces IIS-style names like u_ex260117.log. Size rotation appends a sequence number, e.g. u_ex260117_1.log."`
+	LogFields       []string `toml:"log_fields" comment:"W3C fields written to each line, in order. Supported fields: date, time, s-sitename, s-computername, s-ip, cs-method, cs-uri-stem, cs-uri-query, s-port, cs-username, c-ip, cs-version, cs(User-Agent), cs(Referer), cs(Cookie), cs-host, sc-status, sc-substatus, sc-win32-status, sc-bytes, cs-bytes and time-taken (milliseconds). Text written by Response.AppendToLog is appended to cs-uri-query, like IIS."`
+	LogRotation     string   `toml:"log_rotation" comment:"Rotation schedule of the access log files. Use \"daily\" to start a new file each day or \"none\" to keep one file. Size based rotation is controlled by log_max_size_mb and works with both values."`
+	LogMaxSizeMB    int      `toml:"log_max_size_mb" comment:"Maximum size in megabytes of one access log file before a new numbered file is started. Set to 0 to disable size based rotation."`
+	LogLocalTime    bool     `toml:"log_local_time" comment:"When enabled, the date and time fields use the server local time instead of UTC. IIS W3C logs use UTC by default, so keep this disabled if your log analyzer expects UTC."`
+}
+
+// CompressionConfig maps the [compression] configuration section.
+type CompressionConfig struct {
+	EnableCompression        bool     `toml:"enable_compression" comment:"When enabled, the http and fastcgi servers compress ASP output and static files for clients that send a matching Accept-Encoding header. Compression reduces bandwidth at the cost of some CPU time. It is disabled by default. Compressing pages that echo request data next to secrets over HTTPS exposes them to the BREACH attack. Leave it disabled if a reverse proxy in front of AxonASP already compresses responses."`
+	CompressionEncodings     []string `toml:"compression_encodings" comment:"Content codings offered to clients, in server preference order. Supported values are \"zstd\", \"gzip\" and \"deflate\". The client q-values win; this order only breaks ties."`
+	CompressionLevel         string   `toml:"compression_level" comment:"Compression level used by every coding: \"fastest\", \"default\" or \"best\". \"best\" produces smaller responses but uses noticeably more CPU time per request."`
+	CompressionMinSizeBytes  int      `toml:"compression_min_size_bytes" comment:"Responses smaller than this number of bytes are sent uncompressed, because the compression overhead is bigger than the gain. A page that calls Response.Flush before writing this many bytes is also sent uncompressed so streaming is never delayed."`
+	CompressionMIMETypes     []string `toml:"compression_mime_types" comment:"Content types that are compressed. Use exact types like \"application/json\" or wildcards like \"text/*\". When a type matches this list and compression_skip_mime_types, the most specific rule wins and the skip list wins ties."`
+	CompressionSkipMIMETypes []string `toml:"compression_skip_mime_types" comment:"Content types that are never compressed, usually because they are already compressed (images, video, archives, woff2 fonts) or streamed (text/event-stream)."`
+	ServePrecompressedFiles  bool     `toml:"serve_precompressed_files" comment:"When enabled, a request for a static file such as app.js is answered with app.js.zst or app.js.gz when that file exists, is not older than app.js and the client accepts the coding. This lets you compress large assets once at build time with the best ratio."`
+}
+
+// AuthenticationConfig maps the [authentication] configuration section.
+type AuthenticationConfig struct {
+	EnableAuthentication bool     `toml:"enable_authentication" comment:"When enabled, the http and fastcgi servers verify HTTP Basic credentials and expose the user name to ASP pages as AUTH_USER, LOGON_USER and REMOTE_USER. The server refuses to start when the provider cannot be loaded, so protected content is never served anonymously."`
+	AuthRealm            string   `toml:"auth_realm" comment:"Realm shown by the browser login prompt. A web.config basicAuthentication realm attribute overrides it for its folder."`
+	AuthProvider         string   `toml:"auth_provider" comment:"Where credentials are verified: \"htpasswd\" reads htpasswd_file, \"sql\" runs sql_query on the G3DB connection selected by sql_driver."`
+	HtpasswdFile         string   `toml:"htpasswd_file" comment:"htpasswd-style file with one user:hash line per user. Create bcrypt entries with htpasswd -B or SHA-1 entries with htpasswd -s. The file is reloaded when it changes."`
+	GroupFile            string   `toml:"group_file" comment:"Optional htgroup-style file with one \"Role: user1 user2\" line per role, used by role rules."`
+	SQLDriver            string   `toml:"sql_driver" comment:"G3DB driver used by the sql provider: \"mysql\", \"postgres\", \"mssql\", \"sqlite\" or \"oracle\". The connection uses the [g3db] settings of that driver."`
+	SQLQuery             string   `toml:"sql_query" comment:"Query returning the password hash of the user name passed as its only parameter. Use ? placeholders for every driver."`
+	SQLRolesQuery        string   `toml:"sql_roles_query" comment:"Optional query returning one role name per row for the user name passed as its only parameter."`
+	ProtectedPaths       []string `toml:"protected_paths" comment:"URL prefixes that require an authenticated user, such as \"/admin/\". Restrict a prefix with \"/reports/ = alice, bob, @Managers\", where @ marks a role."`
+	AuthCacheSeconds     int      `toml:"auth_cache_seconds" comment:"Seconds a successful login is remembered, so bcrypt hashes are not verified on every request. Password changes take effect after this delay. Use 0 to verify every request."`
+}
+
+// MetricsConfig maps the [metrics] configuration section.
+type MetricsConfig struct {
+	EnableMetrics          bool      `toml:"enable_metrics" comment:"When enabled, request, VM pool, bytecode cache, session, G3AxonLive and Go runtime statistics are exposed in the Prometheus text format."`
+	MetricsListen          string    `toml:"metrics_listen" comment:"Address of a dedicated listener for the metrics, such as \"127.0.0.1:9464\". Leave empty to serve the metrics on the main server at metrics_path instead."`
+	MetricsPath            string    `toml:"metrics_path" comment:"URL path that serves the metrics."`
+	MetricsAllowedIPs      []string  `toml:"metrics_allowed_ips" comment:"Addresses and CIDR ranges allowed to read the metrics. Other clients receive 403. Use an empty list to allow every client."`
+	MetricsDurationBuckets []float64 `toml:"metrics_duration_buckets" comment:"Upper bounds, in seconds, of the request duration histogram buckets."`
+}
+
+// AdmissionConfig maps the [admission] configuration section.
+type AdmissionConfig struct {
+	EnableAdmissionControl bool     `toml:"enable_admission_control" comment:"When enabled, requests waiting for a VM pool slot use a bounded queue and receive 503 with Retry-After when it is full or the wait times out."`
+	MaxQueueLength         int      `toml:"max_queue_length" comment:"Maximum number of requests waiting for a VM pool slot. Use 0 to reject requests as soon as every slot is busy."`
+	QueueTimeoutSeconds    int      `toml:"queue_timeout_seconds" comment:"Seconds a request may wait for a VM pool slot before it receives 503. Use 0 to wait until the client disconnects."`
+	RetryAfterSeconds      int      `toml:"retry_after_seconds" comment:"Value of the Retry-After header sent with 503 responses. Use 0 to omit the header."`
+	MaxRequestsPerClient   int      `toml:"max_requests_per_client" comment:"Maximum number of ASP requests a single client IP may run or queue at the same time. Use 0 for no limit."`
+	HighPriorityPaths      []string `toml:"high_priority_paths" comment:"URL prefixes served before other requests, such as \"/admin/\"."`
+	LowPriorityPaths       []string `toml:"low_priority_paths" comment:"URL prefixes served after other requests, such as \"/reports/\"."`
+}
+
+// ReverseProxyConfig maps the [reverse_proxy] configuration section.
+type ReverseProxyConfig struct {
+	EnableProxyHeaders          bool     `toml:"enable_proxy_headers" comment:"When enabled, requests from trusted proxies have REMOTE_ADDR, REMOTE_HOST, HTTPS, SERVER_NAME and SERVER_PORT rewritten from the Forwarded and X-Forwarded-* headers."`
+	TrustedProxies              []string `toml:"trusted_proxies" comment:"Addresses and CIDR ranges of the trusted proxies, such as \"10.0.0.0/8\"."`
+	EnableProxyProtocol         bool     `toml:"enable_proxy_protocol" comment:"Read the HAProxy PROXY protocol v1 or v2 header that trusted proxies send at the start of each connection. Only the http server listeners support it."`
+	ProxyProtocolTimeoutSeconds int      `toml:"proxy_protocol_timeout_seconds" comment:"Seconds to wait for the PROXY protocol header before the connection is closed."`
 }
 
 // G3dbConfig maps the [g3db] configuration section.
@@ -206,18 +299,24 @@ type JavascriptConfig struct {
 
 // Config is the canonical schema for axonasp.toml.
 type Config struct {
-	Global      GlobalConfig      `toml:"global"`
-	Cli         CliConfig         `toml:"cli"`
-	Server      ServerConfig      `toml:"server"`
-	Fastcgi     FastcgiConfig     `toml:"fastcgi"`
-	G3db        G3dbConfig        `toml:"g3db"`
-	G3mail      G3mailConfig      `toml:"g3mail"`
-	G3axonlive  G3axonliveConfig  `toml:"g3axonlive"`
-	Axfunctions AxfunctionsConfig `toml:"axfunctions"`
-	Mcp         McpConfig         `toml:"mcp"`
-	Mswc        MswcConfig        `toml:"mswc"`
-	Service     ServiceConfig     `toml:"service"`
-	Javascript  JavascriptConfig  `toml:"javascript"`
+	Global         GlobalConfig         `toml:"global"`
+	Cli            CliConfig            `toml:"cli"`
+	Server         ServerConfig         `toml:"server"`
+	Fastcgi        FastcgiConfig        `toml:"fastcgi"`
+	AccessLog      AccessLogConfig      `toml:"access_log"`
+	Compression    CompressionConfig    `toml:"compression"`
+	Authentication AuthenticationConfig `toml:"authentication"`
+	Metrics        MetricsConfig        `toml:"metrics"`
+	Admission      AdmissionConfig      `toml:"admission"`
+	ReverseProxy   ReverseProxyConfig   `toml:"reverse_proxy"`
+	G3db           G3dbConfig           `toml:"g3db"`
+	G3mail         G3mailConfig         `toml:"g3mail"`
+	G3axonlive     G3axonliveConfig     `toml:"g3axonlive"`
+	Axfunctions    AxfunctionsConfig    `toml:"axfunctions"`
+	Mcp            McpConfig            `toml:"mcp"`
+	Mswc           MswcConfig           `toml:"mswc"`
+	Service        ServiceConfig        `toml:"service"`
+	Javascript     JavascriptConfig     `toml:"javascript"`
 }
 
 // FPMPoolConfig is the canonical schema for a pool file in ./fpm/fpm.d/*.conf.
@@ -232,6 +331,10 @@ type FPMPoolConfig struct {
 	MemoryLimitMB int    `toml:"memory_limit_mb" comment:"Per-worker memory limit in MB. The supervisor can restart workers that exceed this value to protect host stability."`
 	MaxRestarts   int    `toml:"max_restarts" comment:"Maximum restart attempts for this worker pool. Set to 0 to disable the cap."`
 	TmpDir        string `toml:"tmp_dir" comment:"Temporary directory used by the worker process. Ensure write permissions for the configured UID/GID."`
+
+	RequestSlowlogTimeout   int    `toml:"request_slowlog_timeout" comment:"Seconds after which a request still running is written to the slow log with its ASP call stack. Set to 0 to disable the slow log."`
+	RequestTerminateTimeout int    `toml:"request_terminate_timeout" comment:"Seconds after which a request still running is terminated, whatever its Server.ScriptTimeout. Set to 0 to disable it."`
+	Slowlog                 string `toml:"slowlog" comment:"Slow log file of the pool, created by the FPM and owned by uid and gid. When empty, slow requests are written to the FPM output."`
 }
 
 // ProcessTelemetry tracks runtime counts and memory usage for monitored executables.
@@ -322,6 +425,8 @@ func NewDefaultConfig() Config {
 			ViperWatchConfig:          false,
 			ViperAutomaticEnv:         true,
 			TempDir:                   "./temp",
+			EnableAppOffline:          true,
+			AppOfflineDrainTimeout:    30,
 		},
 		Cli: CliConfig{
 			EnableCli:                   true,
@@ -362,6 +467,14 @@ func NewDefaultConfig() Config {
 			EnableDirectoryListing:   true,
 			DirectoryListingTemplate: "./www/axonasp-pages/directory-listing.html",
 			EngineMode:               "default",
+			TLSPort:                  8443,
+			TLSMinVersion:            "1.2",
+			TLSClientAuth:            "none",
+			EnableHTTP2:              true,
+			TLSReloadDebounceMs:      500,
+			EnableGracefulUpgrade:    true,
+			UpgradeReadyTimeout:      30,
+			ShutdownTimeout:          30,
 		},
 		Fastcgi: FastcgiConfig{
 			DefaultPages: []string{
@@ -382,6 +495,62 @@ func NewDefaultConfig() Config {
 			ServerPort: 9000,
 			EngineMode: "default",
 		},
+		AccessLog: AccessLogConfig{
+			EnableAccessLog: false,
+			LogFormat:       "w3c",
+			LogDirectory:    "./temp/logs",
+			LogFilePrefix:   "u_ex",
+			LogFields: []string{
+				"date", "time", "s-ip", "cs-method", "cs-uri-stem", "cs-uri-query", "s-port",
+				"cs-username", "c-ip", "cs(User-Agent)", "sc-status", "time-taken", "sc-bytes",
+			},
+			LogRotation:  "daily",
+			LogMaxSizeMB: 100,
+			LogLocalTime: false,
+		},
+		Compression: CompressionConfig{
+			EnableCompression:       true,
+			CompressionEncodings:    []string{"zstd", "gzip", "deflate"},
+			CompressionLevel:        "default",
+			CompressionMinSizeBytes: 1024,
+			CompressionMIMETypes: []string{
+				"text/*", "application/javascript", "application/json", "application/xml", "application/xhtml+xml",
+				"application/rss+xml", "application/atom+xml", "application/manifest+json", "application/wasm",
+				"image/svg+xml", "image/x-icon", "font/ttf", "font/otf",
+			},
+			CompressionSkipMIMETypes: []string{
+				"text/event-stream", "image/*", "video/*", "audio/*", "font/woff", "font/woff2", "application/zip",
+				"application/gzip", "application/x-gzip", "application/zstd", "application/x-7z-compressed",
+				"application/x-rar-compressed", "application/pdf", "application/octet-stream",
+			},
+			ServePrecompressedFiles: true,
+		},
+		Authentication: AuthenticationConfig{
+			AuthRealm:        "AxonASP",
+			AuthProvider:     "htpasswd",
+			HtpasswdFile:     "./config/.htpasswd",
+			SQLDriver:        "sqlite",
+			SQLQuery:         "SELECT password_hash FROM users WHERE username = ?",
+			ProtectedPaths:   []string{},
+			AuthCacheSeconds: 300,
+		},
+		Metrics: MetricsConfig{
+			MetricsListen:          "127.0.0.1:9464",
+			MetricsPath:            "/metrics",
+			MetricsAllowedIPs:      []string{"127.0.0.1", "::1"},
+			MetricsDurationBuckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		Admission: AdmissionConfig{
+			MaxQueueLength:      100,
+			QueueTimeoutSeconds: 30,
+			RetryAfterSeconds:   5,
+			HighPriorityPaths:   []string{},
+			LowPriorityPaths:    []string{},
+		},
+		ReverseProxy: ReverseProxyConfig{
+			TrustedProxies:              []string{"127.0.0.1", "::1"},
+			ProxyProtocolTimeoutSeconds: 5,
+		},
 		G3db: G3dbConfig{
 			MysqlDatabase:     "test",
 			MysqlHost:         "localhost",
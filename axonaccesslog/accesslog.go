@@ -0,0 +1,508 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonaccesslog writes IIS-compatible W3C Extended access logs, or a JSON-lines
+// alternative, for the AxonASP HTTP and FastCGI hosts.
+package axonaccesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// FormatW3C selects the W3C Extended Log File Format used by IIS.
+	FormatW3C = "w3c"
+	// FormatJSON selects one JSON object per line, keyed by W3C field names.
+	FormatJSON = "json"
+
+	// RotationDaily starts a new file every day, named <prefix>yymmdd like IIS.
+	RotationDaily = "daily"
+	// RotationNone keeps writing to one file, unless max_size_mb is reached.
+	RotationNone = "none"
+
+	flushInterval = time.Second
+)
+
+// DefaultFields is the IIS default W3C field set.
+var DefaultFields = []string{
+	"date", "time", "s-ip", "cs-method", "cs-uri-stem", "cs-uri-query", "s-port",
+	"cs-username", "c-ip", "cs(User-Agent)", "sc-status", "time-taken", "sc-bytes",
+}
+
+// supportedFields maps lowercase field names to their canonical W3C spelling.
+var supportedFields = map[string]string{
+	"date":            "date",
+	"time":            "time",
+	"s-sitename":      "s-sitename",
+	"s-computername":  "s-computername",
+	"s-ip":            "s-ip",
+	"cs-method":       "cs-method",
+	"cs-uri-stem":     "cs-uri-stem",
+	"cs-uri-query":    "cs-uri-query",
+	"s-port":          "s-port",
+	"cs-username":     "cs-username",
+	"c-ip":            "c-ip",
+	"cs-version":      "cs-version",
+	"cs(user-agent)":  "cs(User-Agent)",
+	"cs(referer)":     "cs(Referer)",
+	"cs(cookie)":      "cs(Cookie)",
+	"cs-host":         "cs-host",
+	"sc-status":       "sc-status",
+	"sc-substatus":    "sc-substatus",
+	"sc-win32-status": "sc-win32-status",
+	"sc-bytes":        "sc-bytes",
+	"cs-bytes":        "cs-bytes",
+	"time-taken":      "time-taken",
+}
+
+// numericFields are written as JSON numbers in the JSON-lines format.
+var numericFields = map[string]bool{
+	"sc-status":       true,
+	"sc-substatus":    true,
+	"sc-win32-status": true,
+	"sc-bytes":        true,
+	"cs-bytes":        true,
+	"time-taken":      true,
+}
+
+// Config controls one access log writer.
+type Config struct {
+	Enabled    bool
+	Format     string
+	Directory  string
+	FilePrefix string
+	Fields     []string
+	Rotation   string
+	MaxSizeMB  int
+	LocalTime  bool
+	// SiteName is the default s-sitename value; routed sites override it per request.
+	SiteName string
+}
+
+// ConfigFromViper reads the [access_log] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		Format:     FormatW3C,
+		Directory:  filepath.Join("temp", "logs"),
+		FilePrefix: "u_ex",
+		Fields:     DefaultFields,
+		Rotation:   RotationDaily,
+		MaxSizeMB:  100,
+		SiteName:   "W3SVC1",
+	}
+	if v == nil {
+		return cfg
+	}
+	cfg.Enabled = v.GetBool("access_log.enable_access_log")
+	if format := strings.ToLower(strings.TrimSpace(v.GetString("access_log.log_format"))); format != "" {
+		cfg.Format = format
+	}
+	if dir := strings.TrimSpace(v.GetString("access_log.log_directory")); dir != "" {
+		cfg.Directory = dir
+	}
+	if v.IsSet("access_log.log_file_prefix") {
+		cfg.FilePrefix = strings.TrimSpace(v.GetString("access_log.log_file_prefix"))
+	}
+	if fields := v.GetStringSlice("access_log.log_fields"); len(fields) > 0 {
+		cfg.Fields = fields
+	}
+	if rotation := strings.ToLower(strings.TrimSpace(v.GetString("access_log.log_rotation"))); rotation != "" {
+		cfg.Rotation = rotation
+	}
+	if v.IsSet("access_log.log_max_size_mb") {
+		cfg.MaxSizeMB = v.GetInt("access_log.log_max_size_mb")
+	}
+	cfg.LocalTime = v.GetBool("access_log.log_local_time")
+	return cfg
+}
+
+// Entry is one logged request.
+type Entry struct {
+	Time          time.Time
+	SiteName      string
+	ServerIP      string
+	ServerPort    string
+	Method        string
+	URIStem       string
+	URIQuery      string
+	Username      string
+	ClientIP      string
+	Protocol      string
+	UserAgent     string
+	Referer       string
+	Cookie        string
+	Host          string
+	Status        int
+	SubStatus     int
+	BytesSent     int64
+	BytesReceived int64
+	TimeTaken     time.Duration
+}
+
+// Writer appends entries to the current log file and rotates it by day and size.
+// A nil *Writer is a disabled logger.
+type Writer struct {
+	cfg          Config
+	fields       []string
+	computerName string
+	now          func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	buf      *bufio.Writer
+	period   string
+	sequence int
+	size     int64
+	closed   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New validates the configuration, creates the log directory and starts the background flusher.
+func New(cfg Config) (*Writer, error) {
+	switch cfg.Format {
+	case FormatW3C, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported access log format %q", cfg.Format)
+	}
+	switch cfg.Rotation {
+	case RotationDaily, RotationNone:
+	default:
+		return nil, fmt.Errorf("unsupported access log rotation %q", cfg.Rotation)
+	}
+	if len(cfg.Fields) == 0 {
+		cfg.Fields = DefaultFields
+	}
+	fields := make([]string, 0, len(cfg.Fields))
+	for _, field := range cfg.Fields {
+		canonical, ok := supportedFields[strings.ToLower(strings.TrimSpace(field))]
+		if !ok {
+			return nil, fmt.Errorf("unsupported access log field %q", field)
+		}
+		fields = append(fields, canonical)
+	}
+	if err := os.MkdirAll(cfg.Directory, 0o755); err != nil {
+		return nil, fmt.Errorf("create access log directory: %w", err)
+	}
+
+	computerName, _ := os.Hostname()
+	w := &Writer{
+		cfg:          cfg,
+		fields:       fields,
+		computerName: computerName,
+		now:          time.Now,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go w.flushLoop()
+	return w, nil
+}
+
+// Fields returns the canonical field list written by the logger.
+func (w *Writer) Fields() []string {
+	if w == nil {
+		return nil
+	}
+	return w.fields
+}
+
+// Log writes one entry. Errors are reported on stderr so logging never fails a request.
+func (w *Writer) Log(entry *Entry) {
+	if w == nil || entry == nil {
+		return
+	}
+	stamp := entry.Time
+	if stamp.IsZero() {
+		stamp = w.now()
+	}
+	if w.cfg.LocalTime {
+		stamp = stamp.Local()
+	} else {
+		stamp = stamp.UTC()
+	}
+
+	var line []byte
+	if w.cfg.Format == FormatJSON {
+		line = w.formatJSON(entry, stamp)
+	} else {
+		line = w.formatW3C(entry, stamp)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	if err := w.ensureFile(stamp); err != nil {
+		fmt.Fprintf(os.Stderr, "access log: %v\n", err)
+		return
+	}
+	n, err := w.buf.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "access log: %v\n", err)
+		return
+	}
+	if w.cfg.MaxSizeMB > 0 && w.size >= int64(w.cfg.MaxSizeMB)*1024*1024 {
+		w.closeFileLocked()
+		w.sequence++
+	}
+}
+
+// Flush writes buffered entries to disk.
+func (w *Writer) Flush() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf != nil {
+		_ = w.buf.Flush()
+	}
+}
+
+// Close flushes and closes the current log file and stops the background flusher.
+func (w *Writer) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.closeFileLocked()
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return err
+}
+
+// flushLoop flushes buffered entries once per second.
+func (w *Writer) flushLoop() {
+	defer close(w.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.Flush()
+		}
+	}
+}
+
+// ensureFile opens the file for the entry period, rotating when the day changes.
+func (w *Writer) ensureFile(stamp time.Time) error {
+	period := ""
+	if w.cfg.Rotation == RotationDaily {
+		period = stamp.Format("060102")
+	}
+	if w.file != nil && period == w.period {
+		return nil
+	}
+	if period != w.period {
+		w.closeFileLocked()
+		w.period = period
+		w.sequence = 0
+	}
+
+	// Continue the last file of the period after a restart, skipping full ones.
+	var path string
+	var info os.FileInfo
+	for {
+		path = w.filePath()
+		stat, err := os.Stat(path)
+		if err != nil || w.cfg.MaxSizeMB <= 0 || stat.Size() < int64(w.cfg.MaxSizeMB)*1024*1024 {
+			info = stat
+			break
+		}
+		w.sequence++
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.buf = bufio.NewWriterSize(file, 32*1024)
+	w.size = 0
+	if info != nil {
+		w.size = info.Size()
+	}
+	if w.cfg.Format == FormatW3C {
+		// IIS writes a directive block whenever it opens a log file.
+		header := "#Software: AxonASP\r\n#Version: 1.0\r\n#Date: " + stamp.Format("2006-01-02 15:04:05") + "\r\n#Fields: " + strings.Join(w.fields, " ") + "\r\n"
+		n, _ := w.buf.WriteString(header)
+		w.size += int64(n)
+	}
+	return nil
+}
+
+// filePath builds the current file name: <prefix><yymmdd>[_<n>].log, or .json for JSON lines.
+func (w *Writer) filePath() string {
+	name := w.cfg.FilePrefix + w.period
+	if name == "" {
+		name = "access"
+	}
+	if w.sequence > 0 {
+		name += "_" + strconv.Itoa(w.sequence)
+	}
+	if w.cfg.Format == FormatJSON {
+		name += ".json"
+	} else {
+		name += ".log"
+	}
+	return filepath.Join(w.cfg.Directory, name)
+}
+
+// closeFileLocked flushes and closes the current file. The caller holds w.mu.
+func (w *Writer) closeFileLocked() error {
+	if w.file == nil {
+		return nil
+	}
+	flushErr := w.buf.Flush()
+	closeErr := w.file.Close()
+	w.file = nil
+	w.buf = nil
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// fieldValue returns the raw value of one field, or "" when unknown.
+func (w *Writer) fieldValue(field string, entry *Entry, stamp time.Time) string {
+	switch field {
+	case "date":
+		return stamp.Format("2006-01-02")
+	case "time":
+		return stamp.Format("15:04:05")
+	case "s-sitename":
+		if entry.SiteName != "" {
+			return entry.SiteName
+		}
+		return w.cfg.SiteName
+	case "s-computername":
+		return w.computerName
+	case "s-ip":
+		return entry.ServerIP
+	case "cs-method":
+		return entry.Method
+	case "cs-uri-stem":
+		return entry.URIStem
+	case "cs-uri-query":
+		return entry.URIQuery
+	case "s-port":
+		return entry.ServerPort
+	case "cs-username":
+		return entry.Username
+	case "c-ip":
+		return entry.ClientIP
+	case "cs-version":
+		return entry.Protocol
+	case "cs(User-Agent)":
+		return entry.UserAgent
+	case "cs(Referer)":
+		return entry.Referer
+	case "cs(Cookie)":
+		return entry.Cookie
+	case "cs-host":
+		return entry.Host
+	case "sc-status":
+		return strconv.Itoa(entry.Status)
+	case "sc-substatus":
+		return strconv.Itoa(entry.SubStatus)
+	case "sc-win32-status":
+		return "0"
+	case "sc-bytes":
+		return strconv.FormatInt(entry.BytesSent, 10)
+	case "cs-bytes":
+		return strconv.FormatInt(entry.BytesReceived, 10)
+	case "time-taken":
+		return strconv.FormatInt(entry.TimeTaken.Milliseconds(), 10)
+	}
+	return ""
+}
+
+// formatW3C renders one space-separated line. Empty values become "-" and spaces become "+", as in IIS.
+func (w *Writer) formatW3C(entry *Entry, stamp time.Time) []byte {
+	var builder strings.Builder
+	builder.Grow(256)
+	for index, field := range w.fields {
+		if index > 0 {
+			builder.WriteByte(' ')
+		}
+		value := w.fieldValue(field, entry, stamp)
+		if value == "" {
+			builder.WriteByte('-')
+			continue
+		}
+		for i := 0; i < len(value); i++ {
+			c := value[i]
+			switch {
+			case c == ' ':
+				builder.WriteByte('+')
+			case c < 0x20 || c == 0x7f:
+				builder.WriteByte('_')
+			default:
+				builder.WriteByte(c)
+			}
+		}
+	}
+	builder.WriteString("\r\n")
+	return []byte(builder.String())
+}
+
+// formatJSON renders one JSON object keyed by the W3C field names.
+func (w *Writer) formatJSON(entry *Entry, stamp time.Time) []byte {
+	line := make([]byte, 0, 384)
+	line = append(line, '{')
+	for index, field := range w.fields {
+		if index > 0 {
+			line = append(line, ',')
+		}
+		key, _ := json.Marshal(field)
+		line = append(line, key...)
+		line = append(line, ':')
+		value := w.fieldValue(field, entry, stamp)
+		if numericFields[field] {
+			line = append(line, value...)
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		line = append(line, encoded...)
+	}
+	line = append(line, '}', '\n')
+	return line
+}
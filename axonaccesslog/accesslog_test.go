@@ -0,0 +1,143 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonaccesslog
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestWriter creates a writer in a temporary directory.
+func newTestWriter(t *testing.T, cfg Config) *Writer {
+	t.Helper()
+	cfg.Directory = t.TempDir()
+	if cfg.Format == "" {
+		cfg.Format = FormatW3C
+	}
+	if cfg.Rotation == "" {
+		cfg.Rotation = RotationDaily
+	}
+	if cfg.FilePrefix == "" {
+		cfg.FilePrefix = "u_ex"
+	}
+	writer, err := New(cfg)
+	if err != nil {
+		t.Fatalf("create writer: %v", err)
+	}
+	t.Cleanup(func() { writer.Close() })
+	return writer
+}
+
+// readLogFile closes the writer and returns the content of one log file.
+func readLogFile(t *testing.T, writer *Writer, name string) string {
+	t.Helper()
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(writer.cfg.Directory, name))
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	return string(data)
+}
+
+// TestHandlerWritesW3CLine verifies the IIS default fields, header lines and
+// Response.AppendToLog text appended to cs-uri-query.
+func TestHandlerWritesW3CLine(t *testing.T) {
+	writer := newTestWriter(t, Config{})
+	handler := writer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AppendToURIQuery(r, "&user=42")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "hello")
+	}), func(*http.Request) (string, string) { return "10.0.0.1", "8801" })
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/report.asp?id=7", nil)
+	req.RemoteAddr = "192.168.0.20:51000"
+	req.Header.Set("User-Agent", "Test Agent")
+	req.SetBasicAuth("admin", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	content := readLogFile(t, writer, "u_ex"+time.Now().UTC().Format("060102")+".log")
+	lines := strings.Split(strings.TrimSpace(content), "\r\n")
+	if len(lines) != 5 || lines[0] != "#Software: AxonASP" || lines[3] != "#Fields: "+strings.Join(DefaultFields, " ") {
+		t.Fatalf("unexpected header:\n%s", content)
+	}
+	values := strings.Split(lines[4], " ")
+	if len(values) != len(DefaultFields) {
+		t.Fatalf("expected %d values, got %q", len(DefaultFields), lines[4])
+	}
+	expected := []string{"10.0.0.1", "GET", "/report.asp", "id=7&user=42", "8801", "admin", "192.168.0.20", "Test+Agent", "201"}
+	if got := values[2:11]; strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Fatalf("unexpected values %q", got)
+	}
+	if values[12] != "5" {
+		t.Fatalf("expected sc-bytes 5, got %q", values[12])
+	}
+}
+
+// TestWriterJSONLines verifies the JSON lines format writes numeric fields as numbers.
+func TestWriterJSONLines(t *testing.T) {
+	writer := newTestWriter(t, Config{Format: FormatJSON, Rotation: RotationNone, Fields: []string{"cs-method", "cs-uri-query", "sc-status", "cs(referer)"}})
+	writer.Log(&Entry{Method: "POST", Status: 404})
+
+	content := readLogFile(t, writer, "u_ex.json")
+	var record map[string]any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &record); err != nil {
+		t.Fatalf("decode %q: %v", content, err)
+	}
+	if record["cs-method"] != "POST" || record["sc-status"] != float64(404) || record["cs-uri-query"] != "" {
+		t.Fatalf("unexpected record %v", record)
+	}
+	if _, ok := record["cs(Referer)"]; !ok {
+		t.Fatalf("expected canonical field name cs(Referer), got %v", record)
+	}
+}
+
+// TestWriterRotatesBySize verifies a numbered file is started once max size is reached.
+func TestWriterRotatesBySize(t *testing.T) {
+	writer := newTestWriter(t, Config{Rotation: RotationNone, MaxSizeMB: 1, Fields: []string{"cs(User-Agent)"}})
+	agent := strings.Repeat("a", 600*1024)
+	for i := 0; i < 3; i++ {
+		writer.Log(&Entry{UserAgent: agent})
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+	for _, name := range []string{"u_ex.log", "u_ex_1.log"} {
+		if _, err := os.Stat(filepath.Join(writer.cfg.Directory, name)); err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+	}
+}
+
+// TestNewRejectsUnknownField verifies configuration mistakes are reported at startup.
+func TestNewRejectsUnknownField(t *testing.T) {
+	if _, err := New(Config{Format: FormatW3C, Rotation: RotationDaily, Directory: t.TempDir(), Fields: []string{"cs-nope"}}); err == nil {
+		t.Fatalf("expected error for an unknown field")
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonaccesslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerAddressFunc resolves the s-ip and s-port values of one request.
+type ServerAddressFunc func(r *http.Request) (string, string)
+
+// requestRecordKey stores the *requestRecord of the request being logged.
+type requestRecordKey struct{}
+
+// requestRecord collects values that are only known inside the host, such as
+// Response.AppendToLog text and the routed site name.
+type requestRecord struct {
+	mu        sync.Mutex
+	appended  strings.Builder
+	siteName  string
+	subStatus int
+	username  string
+}
+
+// AppendToURIQuery appends Response.AppendToLog text to the cs-uri-query field of the
+// current request, the way IIS does. It is a no-op when access logging is disabled.
+func AppendToURIQuery(r *http.Request, text string) {
+	record := recordFromRequest(r)
+	if record == nil || text == "" {
+		return
+	}
+	record.mu.Lock()
+	record.appended.WriteString(text)
+	record.mu.Unlock()
+}
+
+// SetSiteName records the s-sitename of the site that served the request.
+func SetSiteName(r *http.Request, name string) {
+	record := recordFromRequest(r)
+	if record == nil {
+		return
+	}
+	record.mu.Lock()
+	record.siteName = name
+	record.mu.Unlock()
+}
+
+// SetSubStatus records the IIS sc-substatus of the response, such as 7 for a 404.7.
+func SetSubStatus(r *http.Request, subStatus int) {
+	record := recordFromRequest(r)
+	if record == nil {
+		return
+	}
+	record.mu.Lock()
+	record.subStatus = subStatus
+	record.mu.Unlock()
+}
+
+// SetUsername records the cs-username of an authenticated request.
+func SetUsername(r *http.Request, name string) {
+	record := recordFromRequest(r)
+	if record == nil {
+		return
+	}
+	record.mu.Lock()
+	record.username = name
+	record.mu.Unlock()
+}
+
+// recordFromRequest returns the record attached by Handler, or nil.
+func recordFromRequest(r *http.Request) *requestRecord {
+	if r == nil {
+		return nil
+	}
+	record, _ := r.Context().Value(requestRecordKey{}).(*requestRecord)
+	return record
+}
+
+// Handler wraps next and writes one entry per request after it completes.
+// serverAddr may be nil, in which case the listener address is used.
+// A nil *Writer returns next unchanged.
+func (w *Writer) Handler(next http.Handler, serverAddr ServerAddressFunc) http.Handler {
+	if w == nil {
+		return next
+	}
+	if serverAddr == nil {
+		serverAddr = listenerAddress
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		record := &requestRecord{}
+		recorder := &responseRecorder{ResponseWriter: rw}
+		uriStem := r.URL.Path
+		rawQuery := r.URL.RawQuery
+		r = r.WithContext(context.WithValue(r.Context(), requestRecordKey{}, record))
+
+		defer func() {
+			serverIP, serverPort := serverAddr(r)
+			username, _, _ := r.BasicAuth()
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			record.mu.Lock()
+			query := rawQuery + record.appended.String()
+			siteName := record.siteName
+			subStatus := record.subStatus
+			if record.username != "" {
+				username = record.username
+			}
+			record.mu.Unlock()
+
+			w.Log(&Entry{
+				Time:          start,
+				SiteName:      siteName,
+				ServerIP:      serverIP,
+				ServerPort:    serverPort,
+				Method:        r.Method,
+				URIStem:       uriStem,
+				URIQuery:      query,
+				Username:      username,
+				ClientIP:      remoteHost(r.RemoteAddr),
+				Protocol:      r.Proto,
+				UserAgent:     r.UserAgent(),
+				Referer:       r.Referer(),
+				Cookie:        r.Header.Get("Cookie"),
+				Host:          r.Host,
+				Status:        status,
+				SubStatus:     subStatus,
+				BytesSent:     recorder.bytes,
+				BytesReceived: max(r.ContentLength, 0),
+				TimeTaken:     time.Since(start),
+			})
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// listenerAddress resolves s-ip and s-port from the accepting listener.
+func listenerAddress(r *http.Request) (string, string) {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr != nil {
+		if host, port, err := net.SplitHostPort(addr.String()); err == nil {
+			return host, port
+		}
+	}
+	if _, port, err := net.SplitHostPort(r.Host); err == nil {
+		return "", port
+	}
+	return "", ""
+}
+
+// remoteHost strips the port from a remote address.
+func remoteHost(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// responseRecorder captures the status code and body size while keeping
+// streaming and connection upgrades working.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the first status code.
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write counts the body bytes sent to the client.
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush forwards flush operations to the underlying writer.
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack forwards connection takeover, used by WebSocket upgrades.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
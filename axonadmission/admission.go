@@ -0,0 +1,253 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonadmission decides which ASP requests may wait for a VM pool slot, how
+// long they wait and in which order, so a flood of slow pages cannot exhaust the
+// AxonASP hosts.
+package axonadmission
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+	"github.com/spf13/viper"
+)
+
+// ErrClientLimit is returned when one client address already runs its maximum number of requests.
+var ErrClientLimit = errors.New("the client has too many concurrent requests")
+
+// Config controls admission to the VM pool.
+type Config struct {
+	Enabled bool
+	// MaxQueueLength is the number of requests that may wait for a slot. 0 rejects
+	// every request that finds the pool busy.
+	MaxQueueLength int
+	// QueueTimeout bounds the wait for a slot.
+	QueueTimeout time.Duration
+	// RetryAfterSeconds is sent in the Retry-After header of rejected requests.
+	RetryAfterSeconds int
+	// MaxRequestsPerClient limits the concurrent ASP requests of one client address. 0 disables the limit.
+	MaxRequestsPerClient int
+	// HighPriorityPaths and LowPriorityPaths are URL prefixes of the priority classes.
+	HighPriorityPaths []string
+	LowPriorityPaths  []string
+}
+
+// ConfigFromViper reads the [admission] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		MaxQueueLength:    100,
+		QueueTimeout:      30 * time.Second,
+		RetryAfterSeconds: 5,
+	}
+	if v == nil {
+		return cfg
+	}
+	cfg.Enabled = v.GetBool("admission.enable_admission_control")
+	if v.IsSet("admission.max_queue_length") {
+		cfg.MaxQueueLength = max(v.GetInt("admission.max_queue_length"), 0)
+	}
+	if v.IsSet("admission.queue_timeout_seconds") {
+		cfg.QueueTimeout = time.Duration(max(v.GetInt("admission.queue_timeout_seconds"), 0)) * time.Second
+	}
+	if v.IsSet("admission.retry_after_seconds") {
+		cfg.RetryAfterSeconds = max(v.GetInt("admission.retry_after_seconds"), 0)
+	}
+	cfg.MaxRequestsPerClient = max(v.GetInt("admission.max_requests_per_client"), 0)
+	cfg.HighPriorityPaths = cleanPrefixes(v.GetStringSlice("admission.high_priority_paths"))
+	cfg.LowPriorityPaths = cleanPrefixes(v.GetStringSlice("admission.low_priority_paths"))
+	return cfg
+}
+
+// cleanPrefixes trims the configured URL prefixes and drops empty entries.
+func cleanPrefixes(values []string) []string {
+	prefixes := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.HasPrefix(value, "/") {
+			value = "/" + value
+		}
+		prefixes = append(prefixes, value)
+	}
+	return prefixes
+}
+
+// Controller admits ASP requests to the VM pool. A nil *Controller admits every
+// request without a queue limit, the behavior of a server without admission control.
+type Controller struct {
+	cfg Config
+
+	mu      sync.Mutex
+	clients map[string]int
+}
+
+// New builds a controller, or returns nil when cfg.Enabled is false.
+func New(cfg Config) *Controller {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Controller{cfg: cfg, clients: make(map[string]int)}
+}
+
+// Ticket is the admission of one request. Its reservation must be handed to
+// axonvm.AcquireVMWithReservation, and Release must be called when the request ends.
+type Ticket struct {
+	controller  *Controller
+	client      string
+	reservation *axonvm.VMPoolReservation
+}
+
+// Reservation returns the VM pool slot reserved for the request, or nil.
+func (t *Ticket) Reservation() *axonvm.VMPoolReservation {
+	if t == nil {
+		return nil
+	}
+	return t.reservation
+}
+
+// Release frees the client counter and any slot not handed to a VM.
+func (t *Ticket) Release() {
+	if t == nil {
+		return
+	}
+	t.reservation.Release()
+	if t.client != "" {
+		t.controller.releaseClient(t.client)
+		t.client = ""
+	}
+}
+
+// Admit applies the client limit and waits in the queue for a VM pool slot.
+func (c *Controller) Admit(r *http.Request) (*Ticket, error) {
+	if c == nil {
+		return nil, nil
+	}
+	ticket := &Ticket{controller: c}
+	if c.cfg.MaxRequestsPerClient > 0 {
+		client := clientAddress(r.RemoteAddr)
+		if !c.acquireClient(client) {
+			return nil, ErrClientLimit
+		}
+		ticket.client = client
+	}
+	reservation, err := axonvm.ReserveVMPoolSlot(r.Context(), c.Priority(r.URL.Path), c.cfg.MaxQueueLength, c.cfg.QueueTimeout)
+	if err != nil {
+		ticket.Release()
+		return nil, err
+	}
+	ticket.reservation = reservation
+	return ticket, nil
+}
+
+// AdmitErrorPage reserves a slot for an ASP error page without waiting, so the page
+// that reports an overloaded pool never queues itself.
+func (c *Controller) AdmitErrorPage(r *http.Request) (*Ticket, error) {
+	if c == nil {
+		return nil, nil
+	}
+	reservation, err := axonvm.ReserveVMPoolSlot(r.Context(), axonvm.VMPoolPriorityHigh, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Ticket{controller: c, reservation: reservation}, nil
+}
+
+// Priority returns the priority class of a URL path. High priority prefixes win
+// when a path matches both lists.
+func (c *Controller) Priority(urlPath string) axonvm.VMPoolPriority {
+	if c == nil {
+		return axonvm.VMPoolPriorityNormal
+	}
+	for _, prefix := range c.cfg.HighPriorityPaths {
+		if matchesPrefix(urlPath, prefix) {
+			return axonvm.VMPoolPriorityHigh
+		}
+	}
+	for _, prefix := range c.cfg.LowPriorityPaths {
+		if matchesPrefix(urlPath, prefix) {
+			return axonvm.VMPoolPriorityLow
+		}
+	}
+	return axonvm.VMPoolPriorityNormal
+}
+
+// RetryAfter returns the Retry-After header value, or an empty string when disabled.
+func (c *Controller) RetryAfter() string {
+	if c == nil || c.cfg.RetryAfterSeconds <= 0 {
+		return ""
+	}
+	return strconv.Itoa(c.cfg.RetryAfterSeconds)
+}
+
+// SubStatus returns the IIS sub-status of a rejected request: 503.2 when the client
+// exceeded its concurrent request limit, otherwise 0.
+func SubStatus(err error) int {
+	if errors.Is(err, ErrClientLimit) {
+		return 2
+	}
+	return 0
+}
+
+// acquireClient counts one more request for a client, unless it reached the limit.
+func (c *Controller) acquireClient(client string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clients[client] >= c.cfg.MaxRequestsPerClient {
+		return false
+	}
+	c.clients[client]++
+	return true
+}
+
+// releaseClient counts one request less for a client.
+func (c *Controller) releaseClient(client string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.clients[client] <= 1 {
+		delete(c.clients, client)
+		return
+	}
+	c.clients[client]--
+}
+
+// clientAddress strips the port from a remote address.
+func clientAddress(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// matchesPrefix reports whether urlPath is prefix or lies below it, ignoring case.
+func matchesPrefix(urlPath, prefix string) bool {
+	if len(urlPath) < len(prefix) || !strings.EqualFold(urlPath[:len(prefix)], prefix) {
+		return false
+	}
+	return strings.HasSuffix(prefix, "/") || len(urlPath) == len(prefix) || urlPath[len(prefix)] == '/' || urlPath[len(prefix)] == '.'
+}
@@ -0,0 +1,109 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonadmission
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+)
+
+// newTestController creates a controller for a pool of one slot.
+func newTestController(t *testing.T, cfg Config) *Controller {
+	t.Helper()
+	axonvm.SetVMPoolSizeLimit(1)
+	t.Cleanup(func() { axonvm.SetVMPoolSizeLimit(0) })
+	cfg.Enabled = true
+	return New(cfg)
+}
+
+// newClientRequest builds a request for urlPath sent from remoteAddr.
+func newClientRequest(urlPath, remoteAddr string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	r.RemoteAddr = remoteAddr
+	return r
+}
+
+// TestPriorityClasses verifies prefix matching and that high priority wins.
+func TestPriorityClasses(t *testing.T) {
+	cfg := ConfigFromViper(nil)
+	cfg.Enabled = true
+	cfg.HighPriorityPaths = cleanPrefixes([]string{"/admin/", "reports/urgent"})
+	cfg.LowPriorityPaths = cleanPrefixes([]string{"/reports"})
+	c := New(cfg)
+	cases := map[string]axonvm.VMPoolPriority{
+		"/Admin/users.asp":            axonvm.VMPoolPriorityHigh,
+		"/reports/urgent/today.asp":   axonvm.VMPoolPriorityHigh,
+		"/reports/yearly.asp":         axonvm.VMPoolPriorityLow,
+		"/reports.asp":                axonvm.VMPoolPriorityLow,
+		"/reportsarchive/default.asp": axonvm.VMPoolPriorityNormal,
+		"/default.asp":                axonvm.VMPoolPriorityNormal,
+	}
+	for urlPath, expected := range cases {
+		if got := c.Priority(urlPath); got != expected {
+			t.Fatalf("%s: expected priority %d, got %d", urlPath, expected, got)
+		}
+	}
+	if New(ConfigFromViper(nil)) != nil {
+		t.Fatal("expected a nil controller when admission control is disabled")
+	}
+}
+
+// TestAdmitQueueAndClientLimit verifies queue rejection, timeouts and the per-client limit.
+func TestAdmitQueueAndClientLimit(t *testing.T) {
+	c := newTestController(t, Config{MaxQueueLength: 0, QueueTimeout: 20 * time.Millisecond, RetryAfterSeconds: 7, MaxRequestsPerClient: 1})
+
+	first, err := c.Admit(newClientRequest("/a.asp", "192.0.2.1:1000"))
+	if err != nil {
+		t.Fatalf("expected the first request to be admitted, got %v", err)
+	}
+	if _, err := c.Admit(newClientRequest("/b.asp", "192.0.2.1:1001")); !errors.Is(err, ErrClientLimit) || SubStatus(err) != 2 {
+		t.Fatalf("expected ErrClientLimit with sub-status 2, got %v", err)
+	}
+	if _, err := c.Admit(newClientRequest("/b.asp", "192.0.2.2:1000")); !errors.Is(err, axonvm.ErrVMPoolQueueFull) || SubStatus(err) != 0 {
+		t.Fatalf("expected ErrVMPoolQueueFull for another client, got %v", err)
+	}
+	if _, err := c.AdmitErrorPage(newClientRequest("/503.asp", "192.0.2.2:1000")); !errors.Is(err, axonvm.ErrVMPoolQueueFull) {
+		t.Fatalf("expected error pages not to wait, got %v", err)
+	}
+	if c.RetryAfter() != "7" {
+		t.Fatalf("expected Retry-After 7, got %q", c.RetryAfter())
+	}
+
+	c.cfg.MaxQueueLength = 10
+	if _, err := c.Admit(newClientRequest("/b.asp", "192.0.2.3:1000")); !errors.Is(err, axonvm.ErrVMPoolQueueTimeout) {
+		t.Fatalf("expected ErrVMPoolQueueTimeout, got %v", err)
+	}
+
+	first.Release()
+	second, err := c.Admit(newClientRequest("/b.asp", "192.0.2.1:1002"))
+	if err != nil {
+		t.Fatalf("expected the client to be admitted after its first request ended, got %v", err)
+	}
+	second.Release()
+	if len(c.clients) != 0 {
+		t.Fatalf("expected the client counters to be empty, got %v", c.clients)
+	}
+}
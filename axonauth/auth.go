@@ -0,0 +1,292 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonauth implements HTTP Basic authentication and IIS-style URL
+// authorization for the AxonASP hosts. Credentials are verified against an
+// htpasswd file or a SQL table reached through the G3DB drivers.
+package axonauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// ProviderHtpasswd verifies credentials against an htpasswd-style file.
+	ProviderHtpasswd = "htpasswd"
+	// ProviderSQL verifies credentials against a SQL table through the G3DB drivers.
+	ProviderSQL = "sql"
+
+	// AuthTypeBasic is the AUTH_TYPE reported for Basic-authenticated requests.
+	AuthTypeBasic = "Basic"
+)
+
+// ErrInvalidCredentials is returned when the request carries a wrong user name or password.
+var ErrInvalidCredentials = errors.New("invalid user name or password")
+
+// Config controls the authentication provider and the protected paths.
+type Config struct {
+	Enabled        bool
+	Realm          string
+	Provider       string
+	HtpasswdFile   string
+	GroupFile      string
+	SQLDriver      string
+	SQLQuery       string
+	SQLRolesQuery  string
+	ProtectedPaths []string
+	CacheSeconds   int
+}
+
+// ConfigFromViper reads the [authentication] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		Realm:        "AxonASP",
+		Provider:     ProviderHtpasswd,
+		HtpasswdFile: filepath.Join("config", ".htpasswd"),
+		SQLQuery:     "SELECT password_hash FROM users WHERE username = ?",
+		CacheSeconds: 300,
+	}
+	if v == nil {
+		return cfg
+	}
+	cfg.Enabled = v.GetBool("authentication.enable_authentication")
+	if realm := strings.TrimSpace(v.GetString("authentication.auth_realm")); realm != "" {
+		cfg.Realm = realm
+	}
+	if provider := strings.ToLower(strings.TrimSpace(v.GetString("authentication.auth_provider"))); provider != "" {
+		cfg.Provider = provider
+	}
+	if file := strings.TrimSpace(v.GetString("authentication.htpasswd_file")); file != "" {
+		cfg.HtpasswdFile = file
+	}
+	cfg.GroupFile = strings.TrimSpace(v.GetString("authentication.group_file"))
+	cfg.SQLDriver = strings.TrimSpace(v.GetString("authentication.sql_driver"))
+	if query := strings.TrimSpace(v.GetString("authentication.sql_query")); query != "" {
+		cfg.SQLQuery = query
+	}
+	cfg.SQLRolesQuery = strings.TrimSpace(v.GetString("authentication.sql_roles_query"))
+	cfg.ProtectedPaths = v.GetStringSlice("authentication.protected_paths")
+	if v.IsSet("authentication.auth_cache_seconds") {
+		cfg.CacheSeconds = v.GetInt("authentication.auth_cache_seconds")
+	}
+	return cfg
+}
+
+// User is one authenticated caller.
+type User struct {
+	Name     string
+	Password string
+	Roles    []string
+	AuthType string
+}
+
+// InRole reports whether the user belongs to role, ignoring case.
+func (u *User) InRole(role string) bool {
+	if u == nil {
+		return false
+	}
+	for _, candidate := range u.Roles {
+		if strings.EqualFold(candidate, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Provider verifies one user name and password and returns the user's roles.
+// ok is false for unknown users and wrong passwords; err reports lookup failures.
+type Provider interface {
+	Verify(username, password string) (roles []string, ok bool, err error)
+}
+
+// Authenticator validates Basic credentials and holds the protected_paths rules.
+// A nil *Authenticator means authentication is disabled.
+type Authenticator struct {
+	realm    string
+	provider Provider
+	paths    []PathRule
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[[32]byte]cachedLogin
+}
+
+type cachedLogin struct {
+	roles   []string
+	expires time.Time
+}
+
+// New creates the Authenticator described by cfg. It returns nil when
+// authentication is disabled.
+func New(cfg Config) (*Authenticator, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	var provider Provider
+	switch strings.ToLower(strings.TrimSpace(cfg.Provider)) {
+	case ProviderHtpasswd, "":
+		fileProvider, err := NewFileProvider(cfg.HtpasswdFile, cfg.GroupFile)
+		if err != nil {
+			return nil, err
+		}
+		provider = fileProvider
+	case ProviderSQL:
+		sqlProvider, err := NewSQLProvider(cfg.SQLDriver, cfg.SQLQuery, cfg.SQLRolesQuery)
+		if err != nil {
+			return nil, err
+		}
+		provider = sqlProvider
+	default:
+		return nil, fmt.Errorf("unsupported auth_provider %q", cfg.Provider)
+	}
+	return NewWithProvider(cfg, provider)
+}
+
+// NewWithProvider creates an Authenticator that verifies credentials with provider.
+func NewWithProvider(cfg Config, provider Provider) (*Authenticator, error) {
+	paths := make([]PathRule, 0, len(cfg.ProtectedPaths))
+	for _, entry := range cfg.ProtectedPaths {
+		rule, err := ParsePathRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, rule)
+	}
+	realm := strings.TrimSpace(cfg.Realm)
+	if realm == "" {
+		realm = "AxonASP"
+	}
+	return &Authenticator{
+		realm:    realm,
+		provider: provider,
+		paths:    paths,
+		cacheTTL: time.Duration(max(cfg.CacheSeconds, 0)) * time.Second,
+		cache:    make(map[[32]byte]cachedLogin),
+	}, nil
+}
+
+// Realm returns the default realm sent in WWW-Authenticate challenges.
+func (a *Authenticator) Realm() string {
+	if a == nil {
+		return ""
+	}
+	return a.realm
+}
+
+// PathRules returns the access rules of every protected_paths entry covering
+// urlPath, and whether any entry covers it.
+func (a *Authenticator) PathRules(urlPath string) ([]AccessRule, bool) {
+	if a == nil {
+		return nil, false
+	}
+	var rules []AccessRule
+	protected := false
+	for _, path := range a.paths {
+		if path.Matches(urlPath) {
+			protected = true
+			rules = append(rules, path.Rules...)
+		}
+	}
+	return rules, protected
+}
+
+// Authenticate validates the Basic credentials carried by r. It returns nil and
+// no error when the request has no Basic credentials, and ErrInvalidCredentials
+// when they are wrong.
+func (a *Authenticator) Authenticate(r *http.Request) (*User, error) {
+	if a == nil {
+		return nil, nil
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	if username == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Browsers resend Basic credentials on every request and bcrypt is slow by
+	// design, so successful logins are remembered for a short time.
+	key := sha256.Sum256([]byte(username + "\x00" + password))
+	if a.cacheTTL > 0 {
+		a.mu.Lock()
+		entry, found := a.cache[key]
+		a.mu.Unlock()
+		if found && time.Now().Before(entry.expires) {
+			return &User{Name: username, Password: password, Roles: entry.roles, AuthType: AuthTypeBasic}, nil
+		}
+	}
+
+	roles, valid, err := a.provider.Verify(username, password)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidCredentials
+	}
+	if a.cacheTTL > 0 {
+		a.mu.Lock()
+		now := time.Now()
+		for cachedKey, cached := range a.cache {
+			if now.After(cached.expires) {
+				delete(a.cache, cachedKey)
+			}
+		}
+		a.cache[key] = cachedLogin{roles: roles, expires: now.Add(a.cacheTTL)}
+		a.mu.Unlock()
+	}
+	return &User{Name: username, Password: password, Roles: roles, AuthType: AuthTypeBasic}, nil
+}
+
+// Challenge sets the WWW-Authenticate header of a 401 response.
+func Challenge(w http.ResponseWriter, realm string) {
+	realm = strings.ReplaceAll(realm, `"`, "")
+	w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+}
+
+// userContextKey stores the authenticated *User in the request context.
+type userContextKey struct{}
+
+// WithUser returns r carrying user for the ASP host.
+func WithUser(r *http.Request, user *User) *http.Request {
+	if user == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), userContextKey{}, user))
+}
+
+// UserFromRequest returns the user authenticated for r, or nil for anonymous requests.
+func UserFromRequest(r *http.Request) *User {
+	if r == nil {
+		return nil
+	}
+	user, _ := r.Context().Value(userContextKey{}).(*User)
+	return user
+}
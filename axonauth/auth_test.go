@@ -0,0 +1,175 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonauth
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// writeFile writes content to name inside dir and returns the full path.
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	target := filepath.Join(dir, name)
+	if err := os.WriteFile(target, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return target
+}
+
+func TestFileProviderFormatsAndGroups(t *testing.T) {
+	dir := t.TempDir()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	passwords := writeFile(t, dir, ".htpasswd", "# users\nalice:"+string(hash)+"\nbob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")
+	groups := writeFile(t, dir, ".htgroup", "Admins: alice\nStaff: alice bob\n")
+
+	authenticator, err := New(Config{Enabled: true, Provider: ProviderHtpasswd, HtpasswdFile: passwords, GroupFile: groups})
+	if err != nil {
+		t.Fatalf("new authenticator: %v", err)
+	}
+
+	cases := []struct {
+		user     string
+		password string
+		roles    int
+		err      error
+	}{
+		{"alice", "secret", 2, nil},
+		{"bob", "password", 1, nil},
+		{"bob", "wrong", 0, ErrInvalidCredentials},
+		{"carol", "secret", 0, ErrInvalidCredentials},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(tc.user, tc.password)
+		user, err := authenticator.Authenticate(req)
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("%s: expected error %v, got %v", tc.user, tc.err, err)
+		}
+		if tc.err == nil && (user == nil || user.Name != tc.user || len(user.Roles) != tc.roles || user.AuthType != AuthTypeBasic) {
+			t.Fatalf("%s: unexpected user %+v", tc.user, user)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if user, err := authenticator.Authenticate(req); user != nil || err != nil {
+		t.Fatalf("expected anonymous request, got %+v, %v", user, err)
+	}
+}
+
+// TestUnknownUserSpendsBcryptTime verifies that unknown users are rejected
+// after a bcrypt comparison, so their response time matches a wrong password.
+func TestUnknownUserSpendsBcryptTime(t *testing.T) {
+	cost, err := bcrypt.Cost(unknownUserHash())
+	if err != nil || cost != bcrypt.DefaultCost {
+		t.Fatalf("expected a bcrypt hash with the default cost, got cost %d, %v", cost, err)
+	}
+
+	passwords := writeFile(t, t.TempDir(), ".htpasswd", "alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n")
+	provider, err := NewFileProvider(passwords, "")
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	if roles, ok, err := provider.Verify("mallory", "password"); ok || roles != nil || err != nil {
+		t.Fatalf("expected the unknown user to be rejected, got %v, %v, %v", roles, ok, err)
+	}
+}
+
+func TestPathRulesAndAuthorization(t *testing.T) {
+	authenticator, err := NewWithProvider(Config{ProtectedPaths: []string{"/admin/", "/reports = alice, @Managers"}}, nil)
+	if err != nil {
+		t.Fatalf("new authenticator: %v", err)
+	}
+
+	if _, protected := authenticator.PathRules("/Admin/users.asp"); !protected {
+		t.Fatalf("expected /Admin/users.asp to be protected")
+	}
+	if _, protected := authenticator.PathRules("/administrator.asp"); protected {
+		t.Fatalf("expected /administrator.asp to be public")
+	}
+
+	rules, protected := authenticator.PathRules("/reports/q1.asp")
+	if !protected {
+		t.Fatalf("expected /reports/q1.asp to be protected")
+	}
+	alice := &User{Name: "Alice"}
+	manager := &User{Name: "dave", Roles: []string{"managers"}}
+	bob := &User{Name: "bob"}
+	if !Authorized(rules, alice, http.MethodGet) || !Authorized(rules, manager, http.MethodGet) || Authorized(rules, bob, http.MethodGet) {
+		t.Fatalf("unexpected protected_paths authorization result")
+	}
+
+	webRules := []AccessRule{
+		{Allow: true, Users: []string{"*"}},
+		{Allow: false, Users: []string{"?"}},
+		{Allow: false, Users: []string{"bob"}, Verbs: []string{"POST"}},
+	}
+	if Authorized(webRules, nil, http.MethodGet) {
+		t.Fatalf("expected anonymous caller to be denied")
+	}
+	if !Authorized(webRules, bob, http.MethodGet) || Authorized(webRules, bob, http.MethodPost) {
+		t.Fatalf("expected verb-specific deny for bob")
+	}
+	if !Authorized(nil, nil, http.MethodGet) {
+		t.Fatalf("expected empty rule list to allow everyone")
+	}
+}
+
+func TestSQLProvider(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	statements := []string{
+		"CREATE TABLE users (username TEXT, password_hash TEXT)",
+		"CREATE TABLE user_roles (username TEXT, role TEXT)",
+		"INSERT INTO users VALUES ('bob', '{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=')",
+		"INSERT INTO user_roles VALUES ('bob', 'Staff'), ('bob', 'Editors')",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	provider := NewSQLProviderWithDB(db, "SELECT password_hash FROM users WHERE username = ?", "SELECT role FROM user_roles WHERE username = ?")
+	roles, ok, err := provider.Verify("bob", "password")
+	if err != nil || !ok || len(roles) != 2 {
+		t.Fatalf("expected bob with two roles, got %v %v %v", roles, ok, err)
+	}
+	if _, ok, err := provider.Verify("bob", "nope"); ok || err != nil {
+		t.Fatalf("expected wrong password to fail without error, got %v %v", ok, err)
+	}
+	if _, ok, err := provider.Verify("nobody", "password"); ok || err != nil {
+		t.Fatalf("expected unknown user to fail without error, got %v %v", ok, err)
+	}
+}
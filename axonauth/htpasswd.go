@@ -0,0 +1,188 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonauth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// VerifyPassword checks password against one stored hash. Supported formats are
+// bcrypt ($2a$, $2b$, $2y$), {SHA} as written by htpasswd -s, and the
+// {SHA256} and {SHA512} variants using the same base64 encoding.
+func VerifyPassword(hash string, password string) bool {
+	hash = strings.TrimSpace(hash)
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		return constantTimeEqual(strings.TrimPrefix(hash, "{SHA}"), base64.StdEncoding.EncodeToString(sum[:]))
+	case strings.HasPrefix(hash, "{SHA256}"):
+		sum := sha256.Sum256([]byte(password))
+		return constantTimeEqual(strings.TrimPrefix(hash, "{SHA256}"), base64.StdEncoding.EncodeToString(sum[:]))
+	case strings.HasPrefix(hash, "{SHA512}"):
+		sum := sha512.Sum512([]byte(password))
+		return constantTimeEqual(strings.TrimPrefix(hash, "{SHA512}"), base64.StdEncoding.EncodeToString(sum[:]))
+	}
+	return false
+}
+
+// unknownUserHash is compared against the password of users that do not exist,
+// so a request for an unknown user takes as long as one with a wrong password
+// and the response time does not reveal which user names are valid.
+var unknownUserHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("axonasp unknown user"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil
+	}
+	return hash
+})
+
+// rejectUnknownUser spends the time of one bcrypt comparison and returns false.
+func rejectUnknownUser(password string) bool {
+	_ = bcrypt.CompareHashAndPassword(unknownUserHash(), []byte(password))
+	return false
+}
+
+func constantTimeEqual(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// FileProvider verifies credentials against an htpasswd file and reads roles
+// from an optional htgroup file ("group: user1 user2"). Both files are reloaded
+// when they change on disk.
+type FileProvider struct {
+	passwordFile string
+	groupFile    string
+
+	mu          sync.Mutex
+	passwords   map[string]string
+	roles       map[string][]string
+	passwordMod time.Time
+	groupMod    time.Time
+}
+
+// NewFileProvider loads an htpasswd file and, when groupFile is not empty, an htgroup file.
+func NewFileProvider(passwordFile string, groupFile string) (*FileProvider, error) {
+	provider := &FileProvider{passwordFile: passwordFile, groupFile: groupFile}
+	if err := provider.reload(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// Verify implements Provider.
+func (p *FileProvider) Verify(username string, password string) ([]string, bool, error) {
+	if err := p.reload(); err != nil {
+		return nil, false, err
+	}
+	p.mu.Lock()
+	hash, found := p.passwords[username]
+	roles := p.roles[strings.ToLower(username)]
+	p.mu.Unlock()
+	if !found {
+		return nil, rejectUnknownUser(password), nil
+	}
+	if !VerifyPassword(hash, password) {
+		return nil, false, nil
+	}
+	return roles, true, nil
+}
+
+// reload re-reads the files whose modification time changed since the last load.
+func (p *FileProvider) reload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.passwordFile)
+	if err != nil {
+		return fmt.Errorf("read htpasswd file: %w", err)
+	}
+	if p.passwords == nil || !info.ModTime().Equal(p.passwordMod) {
+		passwords := make(map[string]string)
+		err := readColonFile(p.passwordFile, func(name string, value string) {
+			passwords[name] = value
+		})
+		if err != nil {
+			return fmt.Errorf("read htpasswd file: %w", err)
+		}
+		p.passwords = passwords
+		p.passwordMod = info.ModTime()
+	}
+
+	if p.groupFile == "" {
+		return nil
+	}
+	info, err = os.Stat(p.groupFile)
+	if err != nil {
+		return fmt.Errorf("read group file: %w", err)
+	}
+	if p.roles == nil || !info.ModTime().Equal(p.groupMod) {
+		roles := make(map[string][]string)
+		err := readColonFile(p.groupFile, func(group string, members string) {
+			for _, member := range strings.Fields(members) {
+				key := strings.ToLower(member)
+				roles[key] = append(roles[key], group)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("read group file: %w", err)
+		}
+		p.roles = roles
+		p.groupMod = info.ModTime()
+	}
+	return nil
+}
+
+// readColonFile calls add for every "name:value" line, skipping blanks and # comments.
+func readColonFile(path string, add func(name string, value string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return scanner.Err()
+}
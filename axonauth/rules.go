@@ -0,0 +1,142 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonauth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccessRule is one IIS URL Authorization entry. Users may contain "*" for
+// everyone and "?" for anonymous callers. Empty Verbs match every method.
+type AccessRule struct {
+	Allow bool
+	Users []string
+	Roles []string
+	Verbs []string
+}
+
+// Matches reports whether the rule applies to user calling method. user is nil
+// for anonymous requests.
+func (rule AccessRule) Matches(user *User, method string) bool {
+	if len(rule.Verbs) > 0 && !containsFold(rule.Verbs, method) {
+		return false
+	}
+	for _, name := range rule.Users {
+		switch {
+		case name == "*":
+			return true
+		case name == "?":
+			if user == nil {
+				return true
+			}
+		case user != nil && strings.EqualFold(name, user.Name):
+			return true
+		}
+	}
+	for _, role := range rule.Roles {
+		if user.InRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorized evaluates rules the way IIS URL Authorization does: a matching
+// Deny rule always wins, otherwise a matching Allow rule grants access. An
+// empty rule list allows everyone.
+func Authorized(rules []AccessRule, user *User, method string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	allowed := false
+	for _, rule := range rules {
+		if !rule.Matches(user, method) {
+			continue
+		}
+		if !rule.Allow {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+// PathRule is one protected_paths entry: a URL prefix that requires an
+// authenticated user, optionally restricted to some users and roles.
+type PathRule struct {
+	Prefix string
+	Rules  []AccessRule
+}
+
+// ParsePathRule parses a protected_paths entry such as "/admin/" or
+// "/reports/ = alice, bob, @Managers", where @ marks a role.
+func ParsePathRule(entry string) (PathRule, error) {
+	prefix, list, _ := strings.Cut(entry, "=")
+	prefix = strings.TrimSpace(prefix)
+	if !strings.HasPrefix(prefix, "/") {
+		return PathRule{}, fmt.Errorf("protected path %q must start with /", entry)
+	}
+	rule := PathRule{Prefix: prefix}
+	var allow AccessRule
+	allow.Allow = true
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case strings.HasPrefix(item, "@"):
+			allow.Roles = append(allow.Roles, strings.TrimPrefix(item, "@"))
+		default:
+			allow.Users = append(allow.Users, item)
+		}
+	}
+	if len(allow.Users) > 0 || len(allow.Roles) > 0 {
+		rule.Rules = []AccessRule{allow}
+	}
+	return rule, nil
+}
+
+// Matches reports whether urlPath is the protected path or lies below it, ignoring case.
+func (p PathRule) Matches(urlPath string) bool {
+	prefix := strings.ToLower(strings.TrimSuffix(p.Prefix, "/"))
+	urlPath = strings.ToLower(urlPath)
+	return prefix == "" || urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/")
+}
+
+// ParseList splits an IIS comma-separated users, roles or verbs attribute.
+func ParseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
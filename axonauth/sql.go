@@ -0,0 +1,111 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+)
+
+const sqlQueryTimeout = 5 * time.Second
+
+// SQLProvider verifies credentials against a table reached through the G3DB
+// drivers and the [g3db] connection settings. The password query receives the
+// user name as its only parameter and returns one hash column in a format
+// accepted by VerifyPassword. The optional roles query returns one role per row.
+type SQLProvider struct {
+	db         *sql.DB
+	query      string
+	rolesQuery string
+}
+
+// NewSQLProvider opens the G3DB connection for driver and prepares the provider.
+func NewSQLProvider(driver string, query string, rolesQuery string) (*SQLProvider, error) {
+	db, normalized, err := axonvm.OpenG3DBFromConfig(driver)
+	if err != nil {
+		return nil, fmt.Errorf("open authentication database: %w", err)
+	}
+	provider := &SQLProvider{db: db, query: axonvm.G3DBRewritePlaceholders(query, normalized)}
+	if rolesQuery != "" {
+		provider.rolesQuery = axonvm.G3DBRewritePlaceholders(rolesQuery, normalized)
+	}
+	return provider, nil
+}
+
+// NewSQLProviderWithDB creates a provider on an existing connection pool. The
+// queries must already use the driver's placeholder syntax.
+func NewSQLProviderWithDB(db *sql.DB, query string, rolesQuery string) *SQLProvider {
+	return &SQLProvider{db: db, query: query, rolesQuery: rolesQuery}
+}
+
+// Verify implements Provider.
+func (p *SQLProvider) Verify(username string, password string) ([]string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlQueryTimeout)
+	defer cancel()
+
+	var hash sql.NullString
+	err := p.db.QueryRowContext(ctx, p.query, username).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, rejectUnknownUser(password), nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("query authentication database: %w", err)
+	}
+	if !hash.Valid {
+		return nil, rejectUnknownUser(password), nil
+	}
+	if !VerifyPassword(hash.String, password) {
+		return nil, false, nil
+	}
+	if p.rolesQuery == "" {
+		return nil, true, nil
+	}
+
+	rows, err := p.db.QueryContext(ctx, p.rolesQuery, username)
+	if err != nil {
+		return nil, false, fmt.Errorf("query authentication roles: %w", err)
+	}
+	defer rows.Close()
+	var roles []string
+	for rows.Next() {
+		var role sql.NullString
+		if err := rows.Scan(&role); err != nil {
+			return nil, false, fmt.Errorf("query authentication roles: %w", err)
+		}
+		if role.Valid {
+			roles = append(roles, role.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("query authentication roles: %w", err)
+	}
+	return roles, true, nil
+}
+
+// Close releases the connection pool.
+func (p *SQLProvider) Close() error {
+	return p.db.Close()
+}
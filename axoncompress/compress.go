@@ -0,0 +1,332 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+
+// Package axoncompress negotiates Accept-Encoding and compresses responses of the AxonASP
+// HTTP and FastCGI hosts with gzip, deflate or zstd, and serves precompressed static files.
+package axoncompress
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/viper"
+)
+
+const (
+	// EncodingGzip is the gzip content coding.
+	EncodingGzip = "gzip"
+	// EncodingDeflate is the deflate content coding.
+	EncodingDeflate = "deflate"
+	// EncodingZstd is the zstd content coding.
+	EncodingZstd = "zstd"
+
+	// LevelFastest favors CPU time over ratio.
+	LevelFastest = "fastest"
+	// LevelDefault balances CPU time and ratio.
+	LevelDefault = "default"
+	// LevelBest favors ratio over CPU time.
+	LevelBest = "best"
+)
+
+// DefaultEncodings is the server preference order used when the client accepts several codings equally.
+var DefaultEncodings = []string{EncodingZstd, EncodingGzip, EncodingDeflate}
+
+// DefaultMIMETypes are the compressible content types.
+var DefaultMIMETypes = []string{
+	"text/*",
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"application/xhtml+xml",
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/manifest+json",
+	"application/wasm",
+	"image/svg+xml",
+	"image/x-icon",
+	"font/ttf",
+	"font/otf",
+}
+
+// DefaultSkipMIMETypes are content types that are already compressed or must not be buffered.
+var DefaultSkipMIMETypes = []string{
+	"text/event-stream",
+	"image/*",
+	"video/*",
+	"audio/*",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+}
+
+// precompressedExtensions maps content codings to the file suffix of precompressed siblings.
+var precompressedExtensions = map[string]string{
+	EncodingGzip: ".gz",
+	EncodingZstd: ".zst",
+}
+
+// Config controls response compression.
+type Config struct {
+	Enabled            bool
+	Encodings          []string
+	Level              string
+	MinSizeBytes       int
+	MIMETypes          []string
+	SkipMIMETypes      []string
+	ServePrecompressed bool
+}
+
+// ConfigFromViper reads the [compression] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		Encodings:          DefaultEncodings,
+		Level:              LevelDefault,
+		MinSizeBytes:       1024,
+		MIMETypes:          DefaultMIMETypes,
+		SkipMIMETypes:      DefaultSkipMIMETypes,
+		ServePrecompressed: true,
+	}
+	if v == nil {
+		return cfg
+	}
+	cfg.Enabled = v.GetBool("compression.enable_compression")
+	if encodings := v.GetStringSlice("compression.compression_encodings"); len(encodings) > 0 {
+		cfg.Encodings = encodings
+	}
+	if level := strings.ToLower(strings.TrimSpace(v.GetString("compression.compression_level"))); level != "" {
+		cfg.Level = level
+	}
+	if v.IsSet("compression.compression_min_size_bytes") {
+		cfg.MinSizeBytes = v.GetInt("compression.compression_min_size_bytes")
+	}
+	if v.IsSet("compression.compression_mime_types") {
+		cfg.MIMETypes = v.GetStringSlice("compression.compression_mime_types")
+	}
+	if v.IsSet("compression.compression_skip_mime_types") {
+		cfg.SkipMIMETypes = v.GetStringSlice("compression.compression_skip_mime_types")
+	}
+	if v.IsSet("compression.serve_precompressed_files") {
+		cfg.ServePrecompressed = v.GetBool("compression.serve_precompressed_files")
+	}
+	return cfg
+}
+
+// encoder is the common surface of the gzip, deflate and zstd writers.
+type encoder interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Reset(w io.Writer)
+}
+
+// Compressor negotiates and compresses responses. A nil *Compressor is disabled.
+type Compressor struct {
+	encodings     []string
+	minSize       int
+	mimeTypes     []string
+	skipMIMETypes []string
+	precompressed bool
+	pools         map[string]*sync.Pool
+}
+
+// New validates the configuration and prepares one encoder pool per content coding.
+func New(cfg Config) (*Compressor, error) {
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = DefaultEncodings
+	}
+	c := &Compressor{
+		minSize:       max(cfg.MinSizeBytes, 0),
+		mimeTypes:     normalizeMIMETypes(cfg.MIMETypes),
+		skipMIMETypes: normalizeMIMETypes(cfg.SkipMIMETypes),
+		precompressed: cfg.ServePrecompressed,
+		pools:         make(map[string]*sync.Pool, len(cfg.Encodings)),
+	}
+	for _, name := range cfg.Encodings {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if slices.Contains(c.encodings, name) {
+			continue
+		}
+		newEncoder, err := encoderFactory(name, cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+		// Building one encoder up front reports invalid levels at startup.
+		if _, err := newEncoder(); err != nil {
+			return nil, fmt.Errorf("create %s encoder: %w", name, err)
+		}
+		c.encodings = append(c.encodings, name)
+		c.pools[name] = &sync.Pool{New: func() any {
+			enc, _ := newEncoder()
+			return enc
+		}}
+	}
+	return c, nil
+}
+
+// encoderFactory returns a constructor for one content coding and level.
+func encoderFactory(name string, level string) (func() (encoder, error), error) {
+	flateLevel := flate.DefaultCompression
+	zstdLevel := zstd.SpeedDefault
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case LevelFastest:
+		flateLevel = flate.BestSpeed
+		zstdLevel = zstd.SpeedFastest
+	case LevelDefault, "":
+	case LevelBest:
+		flateLevel = flate.BestCompression
+		zstdLevel = zstd.SpeedBetterCompression
+	default:
+		return nil, fmt.Errorf("unsupported compression level %q", level)
+	}
+
+	switch name {
+	case EncodingGzip:
+		return func() (encoder, error) { return gzip.NewWriterLevel(io.Discard, flateLevel) }, nil
+	case EncodingDeflate:
+		return func() (encoder, error) { return flate.NewWriter(io.Discard, flateLevel) }, nil
+	case EncodingZstd:
+		return func() (encoder, error) {
+			return zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstdLevel), zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported compression encoding %q", name)
+}
+
+// acquire takes an encoder for the coding from its pool and points it at w.
+func (c *Compressor) acquire(name string, w io.Writer) encoder {
+	enc := c.pools[name].Get().(encoder)
+	enc.Reset(w)
+	return enc
+}
+
+// release returns an encoder to its pool.
+func (c *Compressor) release(name string, enc encoder) {
+	enc.Reset(io.Discard)
+	c.pools[name].Put(enc)
+}
+
+// Negotiate picks the content coding for an Accept-Encoding header among the offered ones.
+// The highest client q-value wins and ties follow the offered order. It returns "" when the
+// client accepts none of them.
+func Negotiate(acceptEncoding string, offered []string) string {
+	if strings.TrimSpace(acceptEncoding) == "" || len(offered) == 0 {
+		return ""
+	}
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if name == "x-gzip" {
+			name = EncodingGzip
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		weights[name] = q
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, name := range offered {
+		q, ok := weights[name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best = name
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// Compressible reports whether a Content-Type matches the compression rules.
+// The most specific matching rule wins: an exact type beats "type/*", which beats "*/*".
+// On a tie the skip list wins.
+func (c *Compressor) Compressible(contentType string) bool {
+	if c == nil {
+		return false
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(contentType))
+	if index := strings.IndexByte(mediaType, ';'); index >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:index])
+	}
+	if mediaType == "" {
+		return false
+	}
+	return matchSpecificity(c.mimeTypes, mediaType) > matchSpecificity(c.skipMIMETypes, mediaType)
+}
+
+// matchSpecificity returns 3 for an exact match, 2 for "type/*", 1 for "*/*" and 0 otherwise.
+func matchSpecificity(patterns []string, mediaType string) int {
+	best := 0
+	majorType, _, _ := strings.Cut(mediaType, "/")
+	for _, pattern := range patterns {
+		switch {
+		case pattern == mediaType:
+			return 3
+		case pattern == majorType+"/*":
+			best = max(best, 2)
+		case pattern == "*/*" || pattern == "*":
+			best = max(best, 1)
+		}
+	}
+	return best
+}
+
+// normalizeMIMETypes lowercases and trims the configured patterns.
+func normalizeMIMETypes(patterns []string) []string {
+	normalized := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			normalized = append(normalized, pattern)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,181 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axoncompress
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// newTestCompressor creates a compressor with the default rules and a 64-byte threshold.
+func newTestCompressor(t *testing.T) *Compressor {
+	t.Helper()
+	cfg := ConfigFromViper(nil)
+	cfg.MinSizeBytes = 64
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatalf("create compressor: %v", err)
+	}
+	return c
+}
+
+// TestNegotiate verifies q-values, wildcards and the server preference order.
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"gzip, deflate, br, zstd":   EncodingZstd,
+		"gzip;q=1.0, zstd;q=0.5":    EncodingGzip,
+		"zstd;q=0, gzip":            EncodingGzip,
+		"identity":                  "",
+		"*":                         EncodingZstd,
+		"*;q=0.1, deflate":          EncodingDeflate,
+		"x-gzip":                    EncodingGzip,
+		"br;q=1, gzip;q=0.2, *;q=0": EncodingGzip,
+	}
+	for header, expected := range cases {
+		if got := Negotiate(header, DefaultEncodings); got != expected {
+			t.Fatalf("Accept-Encoding %q: expected %q, got %q", header, expected, got)
+		}
+	}
+}
+
+// TestCompressibleRules verifies exact rules beat wildcard rules and skip rules win ties.
+func TestCompressibleRules(t *testing.T) {
+	c := newTestCompressor(t)
+	cases := map[string]bool{
+		"text/html; charset=utf-8": true,
+		"application/json":         true,
+		"image/svg+xml":            true,
+		"image/png":                false,
+		"text/event-stream":        false,
+		"application/zip":          false,
+		"":                         false,
+	}
+	for contentType, expected := range cases {
+		if got := c.Compressible(contentType); got != expected {
+			t.Fatalf("%q: expected %v, got %v", contentType, expected, got)
+		}
+	}
+}
+
+// TestHandlerCompressesLargeResponses verifies eligible bodies are compressed and small ones are not.
+func TestHandlerCompressesLargeResponses(t *testing.T) {
+	c := newTestCompressor(t)
+	body := strings.Repeat("<p>AxonASP</p>", 50)
+	handler := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, r.URL.Query().Get("prefix"))
+		if r.URL.Query().Get("small") == "" {
+			io.WriteString(w, body)
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/page.asp", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != EncodingGzip || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("expected gzip response, got headers %v", rec.Header())
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("open gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	if string(decoded) != body {
+		t.Fatalf("unexpected decoded body %q", decoded)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/page.asp?small=1&prefix=tiny", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "tiny" {
+		t.Fatalf("expected small identity response, got %v %q", rec.Header(), rec.Body.String())
+	}
+}
+
+// TestHandlerKeepsFlushStreaming verifies flushed chunks reach the client while compressing.
+func TestHandlerKeepsFlushStreaming(t *testing.T) {
+	c := newTestCompressor(t)
+	chunk := strings.Repeat("streamed chunk ", 10)
+	flushed := make(chan int, 1)
+	handler := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, chunk)
+		w.(http.Flusher).Flush()
+		flushed <- w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder).Body.Len()
+		io.WriteString(w, chunk)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/stream.asp", nil)
+	req.Header.Set("Accept-Encoding", "zstd")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if <-flushed == 0 || !rec.Flushed {
+		t.Fatalf("expected the first chunk to be flushed to the client")
+	}
+	decoder, err := zstd.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("open zstd body: %v", err)
+	}
+	defer decoder.Close()
+	decoded, _ := io.ReadAll(decoder)
+	if string(decoded) != chunk+chunk {
+		t.Fatalf("unexpected decoded body %q", decoded)
+	}
+}
+
+// TestServePrecompressedSibling verifies a fresh .gz sibling is served with the original type.
+func TestServePrecompressedSibling(t *testing.T) {
+	c := newTestCompressor(t)
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "app.js")
+	if err := os.WriteFile(filePath, []byte("console.log('plain');"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := os.WriteFile(filePath+".gz", []byte("gzip bytes"), 0o644); err != nil {
+		t.Fatalf("write sibling: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	rec := httptest.NewRecorder()
+	if !c.ServePrecompressed(rec, req, filePath) {
+		t.Fatalf("expected the .gz sibling to be served")
+	}
+	if rec.Header().Get("Content-Encoding") != EncodingGzip || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/javascript") || rec.Body.String() != "gzip bytes" {
+		t.Fatalf("unexpected response %v %q", rec.Header(), rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	if c.ServePrecompressed(httptest.NewRecorder(), req, filePath) {
+		t.Fatalf("expected no precompressed file without Accept-Encoding")
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axoncompress
+
+import (
+	"bufio"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Handler wraps next and compresses eligible responses for clients that accept one of
+// the configured codings. A nil *Compressor returns next unchanged.
+func (c *Compressor) Handler(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ranges address the identity body, HEAD has no body and upgrades take the connection.
+		if r.Method == http.MethodHead || r.Header.Get("Range") != "" || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{
+			ResponseWriter: w,
+			compressor:     c,
+			encoding:       Negotiate(r.Header.Get("Accept-Encoding"), c.encodings),
+		}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// ServePrecompressed serves a .gz or .zst sibling of a static file when the client accepts
+// that coding and the sibling is not older than the file. It reports whether it served the
+// request; when it returns false the caller serves filePath as usual.
+func (c *Compressor) ServePrecompressed(w http.ResponseWriter, r *http.Request, filePath string) bool {
+	if c == nil || !c.precompressed {
+		return false
+	}
+	original, err := os.Stat(filePath)
+	if err != nil || original.IsDir() {
+		return false
+	}
+
+	available := make([]string, 0, len(precompressedExtensions))
+	siblings := make(map[string]os.FileInfo, len(precompressedExtensions))
+	for _, name := range c.encodings {
+		suffix, ok := precompressedExtensions[name]
+		if !ok {
+			continue
+		}
+		info, err := os.Stat(filePath + suffix)
+		if err != nil || info.IsDir() || info.ModTime().Before(original.ModTime()) {
+			continue
+		}
+		available = append(available, name)
+		siblings[name] = info
+	}
+	encoding := Negotiate(r.Header.Get("Accept-Encoding"), available)
+	if encoding == "" {
+		if len(available) > 0 {
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+		return false
+	}
+
+	file, err := os.Open(filePath + precompressedExtensions[encoding])
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath)))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+	}
+	header.Set("Content-Encoding", encoding)
+	header.Add("Vary", "Accept-Encoding")
+	http.ServeContent(w, r, filepath.Base(filePath), siblings[encoding].ModTime(), file)
+	return true
+}
+
+// compressWriter holds back the first bytes of a response until it can decide whether to
+// compress it, then streams either through an encoder or straight to the client.
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *Compressor
+	encoding   string
+
+	status   int
+	pending  []byte
+	decided  bool
+	enc      encoder
+	encName  string
+	hijacked bool
+	closed   bool
+}
+
+// WriteHeader records the status until the compression decision is made.
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.status != 0 {
+		return
+	}
+	if statusCode < 200 {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.status = statusCode
+	// Decide early when the body size is already known to be too small, or absent.
+	if w.knownSmallBody() || !bodyAllowed(statusCode) {
+		_ = w.decide(false)
+	}
+}
+
+// Write buffers up to the minimum size, then compresses or passes the body through.
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.pending = append(w.pending, data...)
+		if len(w.pending) < w.compressor.minSize && !w.knownLargeBody() {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends what was written so far. A response flushed before reaching the minimum
+// size is sent uncompressed, so Response.Flush streaming never waits for more output.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		_ = w.decide(len(w.pending) >= w.compressor.minSize)
+	}
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack forwards connection takeover, used by WebSocket upgrades.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	w.hijacked = true
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide chooses between compression and identity, writes the status line and the
+// pending bytes. allowCompression is false when the body is known to be too small.
+func (w *compressWriter) decide(allowCompression bool) error {
+	w.decided = true
+	header := w.ResponseWriter.Header()
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if header.Get("Content-Encoding") == "" && bodyAllowed(status) {
+		contentType := header.Get("Content-Type")
+		if contentType == "" && len(w.pending) > 0 {
+			contentType = http.DetectContentType(w.pending)
+			header.Set("Content-Type", contentType)
+		}
+		if w.compressor.Compressible(contentType) {
+			header.Add("Vary", "Accept-Encoding")
+			if allowCompression && w.encoding != "" {
+				header.Set("Content-Encoding", w.encoding)
+				header.Del("Content-Length")
+				header.Del("Accept-Ranges")
+				if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+					header.Set("ETag", "W/"+etag)
+				}
+				w.encName = w.encoding
+				w.enc = w.compressor.acquire(w.encoding, w.ResponseWriter)
+			}
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+	pending := w.pending
+	w.pending = nil
+	if len(pending) == 0 {
+		return nil
+	}
+	if w.enc != nil {
+		_, err := w.enc.Write(pending)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(pending)
+	return err
+}
+
+// close finishes the response: small bodies are sent as-is and encoders are flushed and pooled.
+func (w *compressWriter) close() {
+	if w.closed || w.hijacked {
+		return
+	}
+	w.closed = true
+	if !w.decided {
+		if w.status == 0 && len(w.pending) == 0 {
+			// The handler wrote nothing; let net/http send its implicit 200.
+			return
+		}
+		_ = w.decide(len(w.pending) >= w.compressor.minSize)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+		w.compressor.release(w.encName, w.enc)
+		w.enc = nil
+	}
+}
+
+// knownSmallBody reports whether Content-Length announces a body below the minimum size.
+func (w *compressWriter) knownSmallBody() bool {
+	length, err := strconv.Atoi(w.ResponseWriter.Header().Get("Content-Length"))
+	return err == nil && length < w.compressor.minSize
+}
+
+// knownLargeBody reports whether Content-Length announces a body of at least the minimum size.
+func (w *compressWriter) knownLargeBody() bool {
+	length, err := strconv.Atoi(w.ResponseWriter.Header().Get("Content-Length"))
+	return err == nil && length >= w.compressor.minSize
+}
+
+// bodyAllowed reports whether a status code carries a response body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,172 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+
+// Package axonfpm connects axonasp-fastcgi workers to the axonasp-fpm process
+// manager. The manager owns the listening socket of a pool and hands it to every
+// worker it starts, so the workers accept connections from the same socket. Each
+// worker reports the start and end of its requests through a status pipe, which
+// the manager reads to know which workers are busy and how many requests they served.
+package axonfpm
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// envListenFD is the descriptor of the pool listening socket.
+	envListenFD = "AXONASP_FPM_LISTEN_FD"
+	// envStatusFD is the descriptor of the status pipe.
+	envStatusFD = "AXONASP_FPM_STATUS_FD"
+	// firstExtraFD is the descriptor of the first file in exec.Cmd.ExtraFiles.
+	firstExtraFD = 3
+)
+
+// Status pipe events. A worker writes one byte per event.
+const (
+	// EventBegin is written when a request starts.
+	EventBegin byte = 'B'
+	// EventEnd is written when a request ends.
+	EventEnd byte = 'E'
+)
+
+// drainPollInterval is how often Drain checks for in-flight requests.
+const drainPollInterval = 10 * time.Millisecond
+
+// PassFiles hands the pool listener and the write end of the status pipe to cmd.
+func PassFiles(cmd *exec.Cmd, listener *os.File, status *os.File) {
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	fd := firstExtraFD + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, listener, status)
+	cmd.Env = append(env,
+		envListenFD+"="+strconv.Itoa(fd),
+		envStatusFD+"="+strconv.Itoa(fd+1))
+}
+
+// Worker is the FPM side of an axonasp-fastcgi process.
+type Worker struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	status   *os.File
+	inFlight atomic.Int64
+}
+
+// Join adopts the listener and status pipe passed by axonasp-fpm. It returns nil
+// without error when the process was not started by FPM. The variables are
+// removed so that processes started by ASP pages do not inherit them.
+func Join() (*Worker, error) {
+	listenValue, statusValue := os.Getenv(envListenFD), os.Getenv(envStatusFD)
+	_ = os.Unsetenv(envListenFD)
+	_ = os.Unsetenv(envStatusFD)
+	if listenValue == "" {
+		return nil, nil
+	}
+	listenFD, err := strconv.Atoi(listenValue)
+	if err != nil || listenFD < firstExtraFD {
+		return nil, errors.New("invalid " + envListenFD + " value " + strconv.Quote(listenValue))
+	}
+
+	file := os.NewFile(uintptr(listenFD), "axonasp-fpm-listener")
+	listener, err := net.FileListener(file)
+	_ = file.Close()
+	if err != nil {
+		return nil, err
+	}
+	w := &Worker{listener: listener}
+	if statusFD, err := strconv.Atoi(statusValue); err == nil && statusFD >= firstExtraFD {
+		w.status = os.NewFile(uintptr(statusFD), "axonasp-fpm-status")
+	}
+	return w, nil
+}
+
+// Listener returns the pool listening socket.
+func (w *Worker) Listener() net.Listener {
+	return w.listener
+}
+
+// Handler reports every request served by next to the process manager. A nil
+// Worker returns next unchanged.
+func (w *Worker) Handler(next http.Handler) http.Handler {
+	if w == nil {
+		return next
+	}
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w.inFlight.Add(1)
+		w.report(EventBegin)
+		defer func() {
+			w.report(EventEnd)
+			w.inFlight.Add(-1)
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// report writes one event to the status pipe. A manager that went away only
+// stops the reports.
+func (w *Worker) report(event byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == nil {
+		return
+	}
+	if _, err := w.status.Write([]byte{event}); err != nil {
+		_ = w.status.Close()
+		w.status = nil
+	}
+}
+
+// Drain stops accepting connections and waits until the requests in progress
+// finish, or timeout expires. The socket stays open in the manager and the other
+// workers of the pool.
+func (w *Worker) Drain(timeout time.Duration) {
+	if w == nil {
+		return
+	}
+	_ = w.listener.Close()
+	deadline := time.Now().Add(timeout)
+	for w.inFlight.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// Close closes the status pipe.
+func (w *Worker) Close() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status != nil {
+		_ = w.status.Close()
+		w.status = nil
+	}
+}
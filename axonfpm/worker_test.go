@@ -0,0 +1,65 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonfpm
+
+import (
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestJoinWithoutManager verifies that a worker started by hand runs on its own.
+func TestJoinWithoutManager(t *testing.T) {
+	t.Setenv(envListenFD, "")
+	worker, err := Join()
+	if err != nil || worker != nil {
+		t.Fatalf("expected no worker, got %v, %v", worker, err)
+	}
+	handler := http.NotFoundHandler()
+	if got := worker.Handler(handler); got == nil {
+		t.Fatal("expected the nil worker to return the handler")
+	}
+	worker.Drain(time.Second)
+	worker.Close()
+}
+
+// TestPassFilesNumbersDescriptorsAfterExtraFiles verifies the variables set for the worker.
+func TestPassFilesNumbersDescriptorsAfterExtraFiles(t *testing.T) {
+	cmd := exec.Command("worker")
+	cmd.Env = []string{"A=1"}
+	cmd.ExtraFiles = []*os.File{os.Stdin}
+	PassFiles(cmd, os.Stdout, os.Stderr)
+
+	if len(cmd.ExtraFiles) != 3 || cmd.ExtraFiles[1] != os.Stdout || cmd.ExtraFiles[2] != os.Stderr {
+		t.Fatalf("unexpected extra files %v", cmd.ExtraFiles)
+	}
+	want := []string{"A=1", envListenFD + "=4", envStatusFD + "=5"}
+	if len(cmd.Env) != len(want) {
+		t.Fatalf("unexpected env %v", cmd.Env)
+	}
+	for i := range want {
+		if cmd.Env[i] != want[i] {
+			t.Fatalf("unexpected env %v", cmd.Env)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+//go:build !windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonfpm
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestJoinAdoptsListenerAndReportsRequests verifies the worker side of the handoff.
+func TestJoinAdoptsListenerAndReportsRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	listenFile, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("listener file: %v", err)
+	}
+	statusReader, statusWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer statusReader.Close()
+
+	// Join owns the descriptors it adopts, so it gets copies.
+	listenFD, err := syscall.Dup(int(listenFile.Fd()))
+	if err != nil {
+		t.Fatalf("dup listener: %v", err)
+	}
+	statusFD, err := syscall.Dup(int(statusWriter.Fd()))
+	if err != nil {
+		t.Fatalf("dup status: %v", err)
+	}
+	_ = listenFile.Close()
+	_ = statusWriter.Close()
+
+	t.Setenv(envListenFD, strconv.Itoa(listenFD))
+	t.Setenv(envStatusFD, strconv.Itoa(statusFD))
+	worker, err := Join()
+	if err != nil || worker == nil {
+		t.Fatalf("join: %v, %v", worker, err)
+	}
+	if os.Getenv(envListenFD) != "" || os.Getenv(envStatusFD) != "" {
+		t.Fatal("expected the FPM variables to be removed")
+	}
+	if worker.Listener().Addr().String() != listener.Addr().String() {
+		t.Fatalf("expected listener on %s, got %s", listener.Addr(), worker.Listener().Addr())
+	}
+
+	handler := worker.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	worker.Drain(time.Second)
+	worker.Close()
+
+	events, err := io.ReadAll(statusReader)
+	if err != nil {
+		t.Fatalf("read status: %v", err)
+	}
+	if string(events) != string([]byte{EventBegin, EventEnd}) {
+		t.Fatalf("unexpected events %q", events)
+	}
+}
@@ -0,0 +1,549 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"g3pix.com.br/axonasp/axonaccesslog"
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// Stage names the step of a page that failed.
+type Stage string
+
+const (
+	// StageCompile reports a page that did not compile.
+	StageCompile Stage = "Compilation Error"
+	// StageRuntime reports a page that raised an error while running.
+	StageRuntime Stage = "Runtime Error"
+)
+
+// Ticket is a VM pool slot granted by Hooks.Admit for one page.
+type Ticket interface {
+	Reservation() *axonvm.VMPoolReservation
+	Release()
+}
+
+// Hooks let a host adjust one step of a request. Every hook is optional.
+type Hooks struct {
+	// Prepare adjusts the host options of one request, for example its site root.
+	Prepare func(r *http.Request, opts *HostOptions)
+	// Admit waits for a VM pool slot before a page runs. errorPage is true for
+	// error pages, which should not queue behind the pages that failed.
+	Admit func(r *http.Request, errorPage bool) (Ticket, error)
+	// Rejected answers a request refused by Admit.
+	Rejected func(w http.ResponseWriter, r *http.Request, err error, defaultStatus int)
+	// ScriptError reports a compilation or runtime error before the error response is written.
+	ScriptError func(r *http.Request, stage Stage, err *asp.ASPError)
+	// Timeout answers a page that ran longer than its Server.ScriptTimeout.
+	Timeout func(w http.ResponseWriter, r *http.Request, timeout int, filePath string)
+	// SlowRequest reports a page still running after Options.SlowRequestTimeout,
+	// with its call stack at that moment, the innermost procedure first.
+	SlowRequest func(r *http.Request, filePath string, elapsed time.Duration, stack []axonvm.StackFrame)
+	// Error writes the error page of one HTTP status.
+	Error func(w http.ResponseWriter, r *http.Request, status int)
+}
+
+// Options configures a Handler.
+type Options struct {
+	// Root is the folder served by ServeHTTP. It is also Server.MapPath("/")
+	// unless Host.RootDir is set.
+	Root string
+	// DefaultDocuments are tried in order for folder requests. They default to
+	// default.asp and index.asp.
+	DefaultDocuments []string
+	// ScriptExtensions are run as ASP pages. They default to .asp.
+	ScriptExtensions []string
+	// BlockedExtensions are never served. They default to .asa, .inc and .config.
+	BlockedExtensions []string
+	// ErrorPagesDir holds <status>.asp or <status>.html error pages.
+	ErrorPagesDir string
+	// Debug shows compilation and runtime errors with their source line.
+	Debug bool
+	// LogSource prefixes the errors written to the AxonASP error log.
+	LogSource string
+	// SlowRequestTimeout reports pages that run longer through Hooks.SlowRequest.
+	// Zero disables the report.
+	SlowRequestTimeout time.Duration
+	// TerminateTimeout stops pages that run longer, whatever their
+	// Server.ScriptTimeout. Zero disables it.
+	TerminateTimeout time.Duration
+	// Host is shared by every request. Application is created when nil.
+	Host HostOptions
+	// Hooks adjust single steps of a request.
+	Hooks Hooks
+}
+
+// Handler serves one folder of ASP pages and static files.
+type Handler struct {
+	opts      Options
+	ownsCache bool
+}
+
+// New returns a Handler for opts. Call Start before serving requests and Stop
+// when the handler is no longer used.
+func New(opts Options) *Handler {
+	if opts.Host.RootDir == "" {
+		opts.Host.RootDir = opts.Root
+	}
+	if opts.Root == "" {
+		opts.Root = opts.Host.RootDir
+	}
+	if opts.Host.Application == nil {
+		opts.Host.Application = asp.NewApplication()
+	}
+	if len(opts.DefaultDocuments) == 0 {
+		opts.DefaultDocuments = []string{"default.asp", "index.asp"}
+	}
+	if len(opts.ScriptExtensions) == 0 {
+		opts.ScriptExtensions = []string{".asp"}
+	}
+	if opts.BlockedExtensions == nil {
+		opts.BlockedExtensions = []string{".asa", ".inc", ".config"}
+	}
+	if opts.LogSource == "" {
+		opts.LogSource = "axonhandler"
+	}
+	return &Handler{opts: opts}
+}
+
+// terminateGracePeriod is how long a terminated page may take to reach its next
+// statement before the handler detaches it, like a page blocked in a database call.
+const terminateGracePeriod = time.Second
+
+// Application returns the Application object shared by the pages of h.
+func (h *Handler) Application() *asp.Application {
+	return h.opts.Host.Application
+}
+
+// Start loads global.asa from the root folder, runs Application_OnStart and
+// registers its Session_OnEnd for the sessions of the handler. When the
+// Application has a shared store, only the first process that joins it runs
+// Application_OnStart.
+// Without Host.ScriptCache it also creates a memory cache that drops the pages
+// changed below Root. Pages run before Start are compiled on every request.
+func (h *Handler) Start() error {
+	if h.opts.Host.ScriptCache == nil {
+		h.opts.Host.ScriptCache = axonvm.NewScriptCache(axonvm.BytecodeCacheMemoryOnly, "", 64)
+		h.ownsCache = true
+		if err := h.opts.Host.ScriptCache.StartInvalidator([]string{h.opts.Root}); err != nil {
+			return err
+		}
+	}
+	if h.opts.Host.GlobalASA == nil {
+		h.opts.Host.GlobalASA = axonvm.NewGlobalASA()
+	}
+	globalASA := h.opts.Host.GlobalASA
+	if err := globalASA.LoadAndCompileApplication(h.opts.Host.RootDir, h.opts.Host.RootDir, h.opts.Host.VirtualDirectories, h.opts.Host.Application); err != nil {
+		return err
+	}
+	var startErr error
+	err := h.opts.Host.Application.JoinStore(func() {
+		startErr = globalASA.ExecuteApplicationOnStart(h.eventHost())
+	})
+	if err := errors.Join(err, startErr); err != nil {
+		return err
+	}
+	RegisterSessionOnEnd(h.opts.Host)
+	return nil
+}
+
+// Stop runs Application_OnEnd of the global.asa loaded by Start. When the
+// Application has a shared store, only the last process that leaves it runs
+// Application_OnEnd.
+func (h *Handler) Stop() error {
+	if h.ownsCache {
+		h.opts.Host.ScriptCache.StopInvalidator()
+	}
+	if h.opts.Host.GlobalASA == nil || !h.opts.Host.GlobalASA.IsLoaded() {
+		return h.opts.Host.Application.LeaveStore(nil)
+	}
+	UnregisterSessionOnEnd(h.opts.Host)
+	var endErr error
+	err := h.opts.Host.Application.LeaveStore(func() {
+		endErr = h.opts.Host.GlobalASA.ExecuteApplicationOnEnd(h.eventHost())
+	})
+	return errors.Join(err, endErr)
+}
+
+// eventHost returns a host without a client for global.asa application events.
+func (h *Handler) eventHost() *Host {
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://localhost/", nil)
+	req.RemoteAddr = "127.0.0.1:0"
+	opts := h.opts.Host
+	opts.GlobalASA = nil
+	return NewHost(DiscardWriter{}, req, opts)
+}
+
+// HostOptions returns the host options of one request after Hooks.Prepare.
+func (h *Handler) HostOptions(r *http.Request) HostOptions {
+	opts := h.opts.Host
+	if h.opts.Hooks.Prepare != nil {
+		h.opts.Hooks.Prepare(r, &opts)
+	}
+	return opts
+}
+
+// ServeHTTP maps the URL path below Root. Folders use the default documents,
+// script extensions run as ASP and other files are served as they are.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	h.ServePath(w, r, filepath.Join(h.opts.Root, filepath.FromSlash(urlPath)))
+}
+
+// ServePath serves filePath for the URL path of r, for hosts that map URLs to
+// files themselves. It handles folders and extensions like ServeHTTP.
+func (h *Handler) ServePath(w http.ResponseWriter, r *http.Request, filePath string) {
+	urlPath := path.Clean("/" + r.URL.Path)
+	info, err := os.Stat(filePath)
+	if err != nil {
+		h.ServeError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if info.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := r.URL.Path + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		document, documentInfo, found := h.defaultDocument(filePath)
+		if !found {
+			h.ServeError(w, r, http.StatusForbidden)
+			return
+		}
+		r = r.Clone(r.Context())
+		r.URL.Path = path.Join(urlPath, filepath.Base(document))
+		filePath, info = document, documentInfo
+	}
+
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if slices.Contains(h.opts.BlockedExtensions, ext) {
+		h.ServeError(w, r, http.StatusNotFound)
+		return
+	}
+	if slices.Contains(h.opts.ScriptExtensions, ext) {
+		h.ExecuteFile(w, r, filePath, 0)
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		h.ServeError(w, r, http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// defaultDocument returns the first default document that exists in dir.
+func (h *Handler) defaultDocument(dir string) (string, os.FileInfo, bool) {
+	for _, name := range h.opts.DefaultDocuments {
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, info, true
+		}
+	}
+	return "", nil, false
+}
+
+// ExecuteFile compiles and executes an ASP file, running vm.Run() in a
+// goroutine so that a blocking CGO/COM call (e.g. OLE ADODB.Execute) cannot hold
+// the HTTP handler indefinitely. If the goroutine does not complete within
+// Server.ScriptTimeout seconds the handler detaches the response writer, answers
+// through Hooks.Timeout and returns. The goroutine continues until the CGO call
+// unblocks, then its deferred CleanupRequestResources drains OLE objects.
+// Options.SlowRequestTimeout and Options.TerminateTimeout report and stop
+// long pages the same way, independent of Server.ScriptTimeout.
+// A defaultStatus above zero is sent instead of 200, for error pages.
+func (h *Handler) ExecuteFile(w http.ResponseWriter, r *http.Request, filePath string, defaultStatus int) {
+	cancelRequest := context.CancelFunc(func() {})
+	if h.opts.TerminateTimeout > 0 {
+		// Terminating a page also cancels the outgoing HTTP calls it waits on.
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		r = r.WithContext(ctx)
+		cancelRequest = cancel
+	}
+
+	var ticket Ticket
+	if h.opts.Hooks.Admit != nil {
+		admitted, err := h.opts.Hooks.Admit(r, defaultStatus > 0)
+		if err != nil {
+			h.reject(w, r, err, defaultStatus)
+			return
+		}
+		ticket = admitted
+		defer ticket.Release()
+	}
+
+	opts := h.HostOptions(r)
+	single := NewSingleHeaderWriter(w, defaultStatus)
+	cw := NewCancellableWriter(single)
+	host := NewHost(cw, r, opts)
+
+	// Without a script cache every request compiles the page again.
+	cache := opts.ScriptCache
+	if cache == nil {
+		cache = axonvm.NewScriptCache(axonvm.BytecodeCacheDisabled, "", 1)
+	}
+	program, err := cache.LoadOrCompileWithOptions(filePath, axonvm.ScriptCompileOptions{IncludeSiteRoot: host.Server().MapPath("/"), IncludeVirtualDirectories: host.Server().VirtualDirectories()})
+	if err != nil {
+		h.scriptError(w, r, host, StageCompile, axonvm.CompilerErrorToASPError(err, filePath), defaultStatus)
+		return
+	}
+
+	var vm *axonvm.VM
+	if ticket != nil {
+		vm = axonvm.AcquireVMWithReservation(program, ticket.Reservation())
+		vm.SetContext(r.Context())
+	} else {
+		vm, err = host.acquire(program)
+		if err != nil {
+			h.reject(w, r, err, defaultStatus)
+			return
+		}
+	}
+	vm.SetHost(host)
+	var tracer *axonvm.StackTracer
+	if h.opts.SlowRequestTimeout > 0 {
+		tracer = axonvm.NewStackTracer()
+		vm.SetStackTracer(tracer)
+	}
+
+	timeoutSec := requestScriptTimeout(host, opts.ScriptTimeout)
+
+	type vmResult struct{ err error }
+	done := make(chan vmResult, 1)
+	go func() {
+		defer vm.Release()
+		// A page that ends inside Application.Lock must not keep other pages waiting.
+		defer host.Application().ReleaseForServer(host.Server())
+		runErr := func() (err error) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					err = fmt.Errorf("panic recovered in vm.Run: %v", recovered)
+				}
+			}()
+			return vm.Run()
+		}()
+		done <- vmResult{err: runErr}
+	}()
+
+	start := time.Now()
+	watchdog := time.NewTicker(250 * time.Millisecond)
+	defer watchdog.Stop()
+	var terminatedAt time.Time
+
+	for {
+		select {
+		case res := <-done:
+			for _, message := range host.Response().LogEntries() {
+				axonaccesslog.AppendToURIQuery(r, message)
+			}
+			if res.err != nil {
+				h.scriptError(w, r, host, StageRuntime, axonvm.RuntimeErrorToASPError(res.err, filePath), defaultStatus)
+				return
+			}
+			host.PersistSession()
+			host.Response().Flush()
+			host.Response().ReleaseBuffer()
+			return
+
+		case <-watchdog.C:
+			elapsed := time.Since(start)
+			if tracer != nil && elapsed >= h.opts.SlowRequestTimeout {
+				h.slowRequest(r, filePath, elapsed, tracer.Snapshot())
+				tracer = nil
+			}
+			if h.opts.TerminateTimeout > 0 && elapsed >= h.opts.TerminateTimeout {
+				if terminatedAt.IsZero() {
+					// The page stops at its next statement with ErrRequestTerminated.
+					terminatedAt = time.Now()
+					host.Server().Terminate()
+					cancelRequest()
+				} else if time.Since(terminatedAt) >= terminateGracePeriod {
+					cw.Cancel()
+					h.terminated(w, r, filePath)
+					return
+				}
+			}
+			effectiveTimeout := requestScriptTimeout(host, timeoutSec)
+			if elapsed >= time.Duration(effectiveTimeout)*time.Second {
+				cw.Cancel()
+				h.timeout(w, r, effectiveTimeout, filePath)
+				return
+			}
+		}
+	}
+}
+
+// requestScriptTimeout returns the effective timeout in seconds for one request,
+// preferring the current ASP Server.ScriptTimeout value over the fallback.
+func requestScriptTimeout(host axonvm.ASPHostEnvironment, fallback int) int {
+	if host != nil {
+		if server := host.Server(); server != nil {
+			if timeout := server.GetScriptTimeout(); timeout > 0 {
+				return timeout
+			}
+		}
+	}
+	if fallback > 0 {
+		return fallback
+	}
+	return 60
+}
+
+// reject answers a request refused by Hooks.Admit.
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request, err error, defaultStatus int) {
+	if h.opts.Hooks.Rejected != nil {
+		h.opts.Hooks.Rejected(w, r, err, defaultStatus)
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// scriptError reports a failed page and writes the 500 response. A failing
+// error page answers with plain text, so it cannot call itself again.
+func (h *Handler) scriptError(w http.ResponseWriter, r *http.Request, host *Host, stage Stage, aspErr *asp.ASPError, defaultStatus int) {
+	host.Server().SetLastError(aspErr)
+	if h.opts.Hooks.ScriptError != nil {
+		h.opts.Hooks.ScriptError(r, stage, aspErr)
+	} else if stage == StageCompile {
+		axonvm.LogASPProcessedError(aspErr, h.opts.LogSource+".executeASP.compile")
+	} else {
+		axonvm.LogASPProcessedError(aspErr, h.opts.LogSource+".executeASP.runtime")
+	}
+
+	switch {
+	case h.opts.Debug:
+		RenderError(w, http.StatusInternalServerError, string(stage), aspErr)
+	case defaultStatus > 0:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	default:
+		h.ServeError(w, r, http.StatusInternalServerError)
+	}
+}
+
+// timeout answers a page detached after its script timeout.
+func (h *Handler) timeout(w http.ResponseWriter, r *http.Request, timeout int, filePath string) {
+	if h.opts.Hooks.Timeout != nil {
+		h.opts.Hooks.Timeout(w, r, timeout, filePath)
+		return
+	}
+	axonvm.ReportInternalError(
+		axonvm.ErrScriptTimeoutDetachedGoroutine,
+		fmt.Errorf("script timeout reached after %ds", timeout),
+		fmt.Sprintf("Detached blocked ASP execution goroutine after script timeout (%ds).", timeout),
+		filePath,
+		0,
+	)
+	http.Error(w, "Script execution timed out", http.StatusServiceUnavailable)
+}
+
+// slowRequest reports a page that runs longer than Options.SlowRequestTimeout.
+func (h *Handler) slowRequest(r *http.Request, filePath string, elapsed time.Duration, stack []axonvm.StackFrame) {
+	if h.opts.Hooks.SlowRequest != nil {
+		h.opts.Hooks.SlowRequest(r, filePath, elapsed, stack)
+		return
+	}
+	log.Printf("[%s] Slow request %s %s (%s) running for %s\n%s", h.opts.LogSource, r.Method, r.RequestURI, filePath, elapsed.Round(time.Millisecond), axonvm.FormatStackTrace(stack))
+}
+
+// terminated answers a page that did not stop after Options.TerminateTimeout,
+// detaching it like a page past its script timeout.
+func (h *Handler) terminated(w http.ResponseWriter, r *http.Request, filePath string) {
+	axonvm.ReportInternalError(
+		axonvm.ErrRequestTerminated,
+		fmt.Errorf("request terminate timeout reached after %s", h.opts.TerminateTimeout),
+		fmt.Sprintf("Detached blocked ASP execution goroutine after the request terminate timeout (%s).", h.opts.TerminateTimeout),
+		filePath,
+		0,
+	)
+	http.Error(w, "Request terminated", http.StatusServiceUnavailable)
+}
+
+// ServeError writes the error page of status through Hooks.Error, or from
+// ErrorPagesDir as <status>.asp or <status>.html, or as plain text.
+func (h *Handler) ServeError(w http.ResponseWriter, r *http.Request, status int) {
+	if h.opts.Hooks.Error != nil {
+		h.opts.Hooks.Error(w, r, status)
+		return
+	}
+	if h.opts.ErrorPagesDir != "" {
+		aspPagePath := filepath.Join(h.opts.ErrorPagesDir, fmt.Sprintf("%d.asp", status))
+		if info, err := os.Stat(aspPagePath); err == nil && !info.IsDir() {
+			h.ExecuteFile(w, r, aspPagePath, status)
+			return
+		}
+		htmlPagePath := filepath.Join(h.opts.ErrorPagesDir, fmt.Sprintf("%d.html", status))
+		if info, err := os.Stat(htmlPagePath); err == nil && !info.IsDir() {
+			http.ServeFile(NewSingleHeaderWriter(w, status), r, htmlPagePath)
+			return
+		}
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// RenderError renders an ASP/VBScript-style debug page while preserving HTTP status.
+func RenderError(w http.ResponseWriter, statusCode int, stage string, err *asp.ASPError) {
+	if err == nil {
+		http.Error(w, http.StatusText(statusCode), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+
+	source := html.EscapeString(strings.TrimSpace(err.Source))
+	if source == "" {
+		source = "VBScript runtime"
+	}
+	description := html.EscapeString(strings.TrimSpace(err.Description))
+	if description == "" {
+		description = "Unknown runtime error"
+	}
+	fileName := html.EscapeString(strings.TrimSpace(err.File))
+	if fileName == "" {
+		fileName = "unknown"
+	}
+
+	fmt.Fprintf(w, "<!doctype html><html><head><meta charset=\"utf-8\"><meta name=\"viewport\" content=\"width=device-width,initial-scale=1\"><title>500 - Internal Server Error - AxonASP Server</title><style>body{margin:0;background:#f4f4f4;font-family:\"IBM Plex Sans\",Helvetica,sans-serif;color:#161616;font-size:13px}#h{height:60px;padding:0 15px;font-size:24px;display:flex;align-items:center;font-weight:600;border-bottom:1px solid #d9d9d9;background:#f4f4f4}.shell{padding:40px 20px;display:flex;justify-content:center}.card{background:#fff;border:1px solid #d9d9d9;max-width:760px;width:100%%;padding:28px;box-shadow:0 10px 20px rgba(22,22,22,.06)}h1{margin:0 0 16px;font-size:24px;border-bottom:1px solid #d9d9d9;padding-bottom:8px}p{margin:0 0 12px}table{width:100%%;border-collapse:collapse;border:1px solid #d9d9d9;margin:14px 0}td{border:1px solid #d9d9d9;padding:7px 10px;font-size:12px}td.k{width:120px;background:#f8f8f8;font-weight:600}.ft{margin-top:24px;border-top:1px solid #d9d9d9;padding-top:10px;font-size:11px;color:#525252}</style></head><body><div id=\"h\">❖ AxonASP Server</div><div class=\"shell\"><div class=\"card\"><h1>Application error</h1><p><b>%s error '%08X'</b></p><p>%s</p><table><tr><td class=\"k\">File</td><td>%s</td></tr><tr><td class=\"k\">Line</td><td>%d</td></tr><tr><td class=\"k\">Column</td><td>%d</td></tr><tr><td class=\"k\">Stage</td><td>%s</td></tr></table><div class=\"ft\">G3Pix ❖ AxonASP</div></div></div></body></html>", source, uint32(int32(err.Number)), description, fileName, err.Line, err.Column, html.EscapeString(stage))
+}
@@ -0,0 +1,329 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonhandler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+	"g3pix.com.br/axonasp/vbscript"
+)
+
+// writeTestFile creates name below root with content.
+func writeTestFile(t *testing.T, root string, name string, content string) {
+	t.Helper()
+	fullPath := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useTestSessionDir stores the sessions of the test in a temporary directory.
+func useTestSessionDir(t *testing.T) {
+	t.Helper()
+	asp.SetSessionStorageDir(filepath.Join(t.TempDir(), "session"))
+	t.Cleanup(func() {
+		asp.WaitSessionWrites()
+		asp.SetSessionStorageDir("")
+	})
+}
+
+// newTestHandler returns a started handler for root.
+func newTestHandler(t *testing.T, root string) *Handler {
+	t.Helper()
+	useTestSessionDir(t)
+	h := New(Options{Root: root})
+	if err := h.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Stop() })
+	return h
+}
+
+// TestHandlerServesASPAndStaticFiles verifies ASP pages, default documents,
+// folder redirects, blocked extensions and static files.
+func TestHandlerServesASPAndStaticFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "hello.asp", `<% Response.Write "Hello " & Request.QueryString("name") %>`)
+	writeTestFile(t, root, "sub/default.asp", `<% Response.Write "sub default" %>`)
+	writeTestFile(t, root, "style.css", "body{}")
+	writeTestFile(t, root, "global.asa", "")
+	h := newTestHandler(t, root)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/hello.asp?name=Axon", http.StatusOK, "Hello Axon"},
+		{"/sub/", http.StatusOK, "sub default"},
+		{"/sub", http.StatusMovedPermanently, ""},
+		{"/style.css", http.StatusOK, "body{}"},
+		{"/global.asa", http.StatusNotFound, ""},
+		{"/missing.asp", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, rec.Code)
+			continue
+		}
+		if tt.body != "" && !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s: expected body to contain %q, got %q", tt.path, tt.body, rec.Body.String())
+		}
+	}
+}
+
+// TestHandlerStartRunsApplicationOnStart verifies that Start runs the
+// global.asa application event before the first request.
+func TestHandlerStartRunsApplicationOnStart(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "global.asa", `<script language="VBScript" runat="server">
+Sub Application_OnStart
+	Application("started") = "yes"
+End Sub
+</script>`)
+	writeTestFile(t, root, "default.asp", `<% Response.Write "started=" & Application("started") %>`)
+	h := newTestHandler(t, root)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), "started=yes") {
+		t.Fatalf("expected Application_OnStart value, got %q", rec.Body.String())
+	}
+}
+
+// TestHandlerSessionAbandonRunsSessionOnEnd verifies that Session.Abandon
+// runs the global.asa Session_OnEnd with the contents of the ended session.
+func TestHandlerSessionAbandonRunsSessionOnEnd(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "global.asa", `<script language="VBScript" runat="server">
+Sub Session_OnEnd
+	Application("ended") = Session("user")
+End Sub
+</script>`)
+	writeTestFile(t, root, "logout.asp", `<% Session("user") = "ana" : Session.Abandon %>`)
+	writeTestFile(t, root, "default.asp", `<% Response.Write "ended=" & Application("ended") %>`)
+	h := newTestHandler(t, root)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/logout.asp", nil))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(rec.Body.String(), "ended=ana") {
+		t.Fatalf("expected Session_OnEnd value, got %q", rec.Body.String())
+	}
+}
+
+// TestHandlerSessionRegenerateReissuesCookie verifies that Session.Regenerate
+// sends a new signed cookie that keeps the contents, and that the old cookie
+// and unsigned cookies no longer reach the session.
+func TestHandlerSessionRegenerateReissuesCookie(t *testing.T) {
+	root := t.TempDir()
+	asp.SetSessionSigningKey("handler test key")
+	t.Cleanup(func() { asp.SetSessionSigningKey("") })
+	writeTestFile(t, root, "login.asp", `<% Session("user") = "ana" : Session.Regenerate %>`)
+	writeTestFile(t, root, "whoami.asp", `<% Response.Write "user=" & Session("user") %>`)
+	h := newTestHandler(t, root)
+
+	serve := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	sessionCookie := func(rec *httptest.ResponseRecorder) *http.Cookie {
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == DefaultSessionCookieName {
+				return cookie
+			}
+		}
+		t.Fatalf("expected a session cookie")
+		return nil
+	}
+
+	before := sessionCookie(serve("/whoami.asp", nil))
+	after := sessionCookie(serve("/login.asp", before))
+	if after.Value == before.Value {
+		t.Fatalf("expected Regenerate to send a new session cookie")
+	}
+	newID, ok := asp.SessionIDFromCookie(after.Value)
+	if !ok || !strings.HasPrefix(after.Value, newID+".") {
+		t.Fatalf("expected a signed session cookie, got %q", after.Value)
+	}
+
+	if body := serve("/whoami.asp", after).Body.String(); body != "user=ana" {
+		t.Fatalf("expected the new cookie to keep the contents, got %q", body)
+	}
+	if body := serve("/whoami.asp", before).Body.String(); body != "user=" {
+		t.Fatalf("expected the old cookie to start a new session, got %q", body)
+	}
+	if body := serve("/whoami.asp", &http.Cookie{Name: DefaultSessionCookieName, Value: newID}).Body.String(); body != "user=" {
+		t.Fatalf("expected an unsigned cookie to start a new session, got %q", body)
+	}
+}
+
+// TestHandlerSessionRegenerateAfterFlushKeepsSession verifies that
+// Session.Regenerate raises an error once the headers were sent and leaves the
+// session under the ID the client still holds.
+func TestHandlerSessionRegenerateAfterFlushKeepsSession(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "late.asp", "<%\r\nSession(\"user\") = \"ana\"\r\nResponse.Flush\r\nOn Error Resume Next\r\nSession.Regenerate\r\nResponse.Write \"err=\" & Err.Number\r\n%>")
+	writeTestFile(t, root, "whoami.asp", `<% Response.Write "user=" & Session("user") %>`)
+	h := newTestHandler(t, root)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/late.asp", nil))
+	want := fmt.Sprintf("err=%d", vbscript.HRESULTFromVBScriptCode(vbscript.CannotPerformTheRequestedOperation))
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("expected %q, got %q", want, rec.Body.String())
+	}
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == DefaultSessionCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatalf("expected a session cookie")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami.asp", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Body.String() != "user=ana" {
+		t.Fatalf("expected the sent cookie to keep the session, got %q", rec.Body.String())
+	}
+}
+
+// TestHandlerReleasesApplicationLockAtPageEnd verifies that a page ending
+// inside Application.Lock does not keep the next pages waiting.
+func TestHandlerReleasesApplicationLockAtPageEnd(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "lock.asp", `<% Application.Lock : Application("hits") = 1 %>`)
+	writeTestFile(t, root, "read.asp", `<% Response.Write "hits=" & Application("hits") %>`)
+	h := newTestHandler(t, root)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/lock.asp", nil))
+	if h.Application().IsLocked() {
+		t.Fatalf("expected the Application lock to be released when the page ended")
+	}
+
+	done := make(chan string, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/read.asp", nil))
+		done <- rec.Body.String()
+	}()
+	select {
+	case body := <-done:
+		if !strings.Contains(body, "hits=1") {
+			t.Fatalf("unexpected body %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the next page to run after the locking page ended")
+	}
+}
+
+// TestHandlerHooksPrepareAndError verifies that hooks can add server
+// variables and replace the built-in error pages.
+func TestHandlerHooksPrepareAndError(t *testing.T) {
+	root := t.TempDir()
+	useTestSessionDir(t)
+	writeTestFile(t, root, "vars.asp", `<% Response.Write Request.ServerVariables("APP_TENANT") %>`)
+	h := New(Options{
+		Root: root,
+		Hooks: Hooks{
+			Prepare: func(r *http.Request, opts *HostOptions) {
+				opts.ServerVariables = func(r *http.Request, vars *asp.RequestCollection) {
+					vars.Add("APP_TENANT", "acme")
+				}
+			},
+			Error: func(w http.ResponseWriter, r *http.Request, status int) {
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte("custom error"))
+			},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/vars.asp", nil))
+	if strings.TrimSpace(rec.Body.String()) != "acme" {
+		t.Fatalf("expected hook server variable, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nothing.asp", nil))
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "custom error" {
+		t.Fatalf("expected custom 404, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+// TestHandlerSlowRequestAndTerminateTimeout verifies that a looping page is
+// reported with its call stack and then stopped by the terminate timeout.
+func TestHandlerSlowRequestAndTerminateTimeout(t *testing.T) {
+	root := t.TempDir()
+	useTestSessionDir(t)
+	writeTestFile(t, root, "spin.asp", "<%\r\nSub Spin()\r\n  Do\r\n  Loop\r\nEnd Sub\r\nSpin\r\n%>")
+
+	var slowStack []axonvm.StackFrame
+	var errorStage Stage
+	h := New(Options{
+		Root:               root,
+		SlowRequestTimeout: 250 * time.Millisecond,
+		TerminateTimeout:   500 * time.Millisecond,
+		Hooks: Hooks{
+			SlowRequest: func(r *http.Request, filePath string, elapsed time.Duration, stack []axonvm.StackFrame) {
+				slowStack = stack
+			},
+			ScriptError: func(r *http.Request, stage Stage, err *asp.ASPError) {
+				errorStage = stage
+			},
+			Error: func(w http.ResponseWriter, r *http.Request, status int) {
+				w.WriteHeader(status)
+			},
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/spin.asp", nil))
+	if rec.Code != http.StatusInternalServerError || errorStage != StageRuntime {
+		t.Fatalf("expected the terminated page to fail at runtime, got %d %q", rec.Code, errorStage)
+	}
+	if len(slowStack) != 2 || slowStack[0].Procedure != "Spin" || slowStack[0].Line != 3 || slowStack[1].Line != 6 {
+		t.Fatalf("unexpected slow request stack %+v", slowStack)
+	}
+}
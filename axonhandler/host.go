@@ -0,0 +1,605 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonhandler runs Classic ASP pages behind a standard net/http Handler.
+// The AxonASP servers are built on it, and Go programs can mount an ASP folder
+// next to their own routes with it.
+package axonhandler
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"g3pix.com.br/axonasp/axonauth"
+	"g3pix.com.br/axonasp/axonproxy"
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// DefaultSessionCookieName is the session cookie of Classic ASP.
+const DefaultSessionCookieName = "ASPSESSIONID"
+
+var (
+	executeScriptCacheOnce sync.Once
+	executeScriptCache     *axonvm.ScriptCache
+)
+
+// defaultScriptCache returns the in-memory cache used when a host has no script cache.
+func defaultScriptCache() *axonvm.ScriptCache {
+	executeScriptCacheOnce.Do(func() {
+		executeScriptCache = axonvm.NewScriptCache(axonvm.BytecodeCacheMemoryOnly, "", 64)
+	})
+	return executeScriptCache
+}
+
+// HostOptions describes the environment of one ASP request.
+type HostOptions struct {
+	// RootDir is the physical folder that Server.MapPath("/") returns.
+	RootDir string
+	// RequestPath is the virtual path of the page. It defaults to the URL path.
+	RequestPath string
+	// VirtualDirectories maps virtual paths outside RootDir for MapPath and includes.
+	VirtualDirectories []asp.VirtualDirectory
+	// Application holds the Application object. A nil value uses a new one.
+	Application *asp.Application
+	// GlobalASA runs Session_OnStart for new sessions when it is loaded.
+	// RegisterSessionOnEnd runs its Session_OnEnd when they end.
+	GlobalASA *axonvm.GlobalASA
+	// Session replaces the session cookie. Every request of a single user
+	// desktop application can use its own session, which is never sent as a cookie.
+	Session *asp.Session
+	// SessionCookieName defaults to ASPSESSIONID.
+	SessionCookieName string
+	// SessionCookiePath defaults to "/".
+	SessionCookiePath string
+	// SessionCookieSecure sends the session cookie only over HTTPS.
+	SessionCookieSecure bool
+	// SessionCookieSameSite defaults to http.SameSiteLaxMode. SameSite=None
+	// cookies are always sent with Secure, as browsers require.
+	SessionCookieSameSite http.SameSite
+	// EngineMode selects how pages are parsed.
+	EngineMode axonvm.EngineMode
+	// ResponseBufferLimit is the largest buffered response in bytes. 0 keeps the default.
+	ResponseBufferLimit int
+	// ScriptTimeout is the initial Server.ScriptTimeout in seconds. 0 keeps the default.
+	ScriptTimeout int
+	// UnrestrictedFS lets the file system objects reach paths outside RootDir.
+	UnrestrictedFS bool
+	// ServerSoftware is the SERVER_SOFTWARE variable. It defaults to AxonASP.
+	ServerSoftware string
+	// ApplicationPhysicalPath is the APPL_PHYSICAL_PATH variable. It defaults to RootDir.
+	ApplicationPhysicalPath string
+	// ApplicationMetabasePath is the APPL_MD_PATH variable. It is omitted when empty.
+	ApplicationMetabasePath string
+	// ApplicationKey ties the sessions started with these options to the
+	// Session_OnEnd handler registered by RegisterSessionOnEnd. It defaults to
+	// the absolute ApplicationPhysicalPath or RootDir.
+	ApplicationKey string
+	// SaveSessionSync writes the session before the request ends instead of
+	// queuing it, for processes that exit after one request.
+	SaveSessionSync bool
+	// ScriptCache compiles the pages run by Server.Execute and Server.Transfer.
+	// A nil value uses a small process-wide memory cache.
+	ScriptCache *axonvm.ScriptCache
+	// VMPool lends the VMs of the pages. It defaults to axonvm.DefaultVMPool.
+	VMPool axonvm.VMPool
+	// ServerVariables adds or replaces server variables after the standard ones are set.
+	ServerVariables func(r *http.Request, vars *asp.RequestCollection)
+}
+
+// Host implements axonvm.ASPHostEnvironment for one net/http request.
+type Host struct {
+	response       *asp.Response
+	request        *asp.Request
+	server         *asp.Server
+	session        *asp.Session
+	application    *asp.Application
+	sessionEnabled bool
+	engineMode     axonvm.EngineMode
+	cookieName     string
+	cookiePath     string
+	cookieSecure   bool
+	cookieSameSite http.SameSite
+	noCookie       bool
+	saveSync       bool
+	scriptCache    *axonvm.ScriptCache
+	vmPool         axonvm.VMPool
+	ctx            context.Context
+}
+
+// NewHost creates the ASP intrinsic objects for one request. It loads or creates
+// the session and runs Session_OnStart of opts.GlobalASA for new sessions.
+func NewHost(w http.ResponseWriter, r *http.Request, opts HostOptions) *Host {
+	cookieName := opts.SessionCookieName
+	if cookieName == "" {
+		cookieName = DefaultSessionCookieName
+	}
+	cookiePath := opts.SessionCookiePath
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+	cookieSameSite := opts.SessionCookieSameSite
+	if cookieSameSite == 0 {
+		cookieSameSite = http.SameSiteLaxMode
+	}
+	application := opts.Application
+	if application == nil {
+		application = asp.NewApplication()
+	}
+	session, isNew := opts.Session, false
+	if session == nil {
+		session, isNew = loadOrCreateSession(r, cookieName)
+		if isNew {
+			session.SetApplicationKey(SessionApplicationKey(opts))
+		}
+	}
+
+	host := &Host{
+		response:       asp.NewResponse(w),
+		request:        asp.NewRequest(),
+		server:         asp.NewServer(),
+		session:        session,
+		application:    application,
+		sessionEnabled: true,
+		engineMode:     opts.EngineMode,
+		cookieName:     cookieName,
+		cookiePath:     cookiePath,
+		cookieSecure:   opts.SessionCookieSecure || cookieSameSite == http.SameSiteNoneMode,
+		cookieSameSite: cookieSameSite,
+		noCookie:       opts.Session != nil,
+		saveSync:       opts.SaveSessionSync,
+		scriptCache:    opts.ScriptCache,
+		vmPool:         opts.VMPool,
+		ctx:            r.Context(),
+	}
+	requestPath := opts.RequestPath
+	if requestPath == "" {
+		requestPath = r.URL.Path
+	}
+	host.response.SetRequest(r)
+	if opts.ResponseBufferLimit > 0 {
+		host.response.SetMaxBufferBytes(opts.ResponseBufferLimit)
+	}
+	host.request.SetHTTPRequest(r)
+	host.server.SetRootDir(opts.RootDir)
+	if len(opts.VirtualDirectories) > 0 {
+		host.server.SetVirtualDirectories(opts.VirtualDirectories)
+	}
+	host.server.SetRequestPath(requestPath)
+	if opts.ScriptTimeout > 0 {
+		_ = host.server.SetScriptTimeout(opts.ScriptTimeout)
+	}
+	if opts.UnrestrictedFS {
+		host.server.SetUnrestrictedFS(true)
+	}
+
+	if len(r.URL.RawQuery) > 0 {
+		host.request.QueryString.SetLazyPayload([]byte(r.URL.RawQuery))
+	}
+
+	// Lazy body + form loaders keep GET/HEAD hot paths allocation-free.
+	host.request.SetBodyLoader(func() ([]byte, error) {
+		if r.Body == nil {
+			return []byte{}, nil
+		}
+		return io.ReadAll(r.Body)
+	})
+
+	for _, cookie := range r.Cookies() {
+		host.request.Cookies.AddCookie(cookie.Name, cookie.Value)
+	}
+
+	host.addServerVariables(r, requestPath, opts)
+	if opts.ServerVariables != nil {
+		opts.ServerVariables(r, host.request.ServerVars)
+	}
+
+	host.setSessionCookie()
+
+	if isNew && opts.GlobalASA != nil && opts.GlobalASA.IsLoaded() {
+		opts.GlobalASA.PopulateSessionStaticObjects(session)
+		_ = opts.GlobalASA.ExecuteSessionOnStart(host)
+		// Session_OnStart may call Response.End/Redirect which sets ended=true.
+		// The handler suppresses output (Output=nil) so flushInternal is a no-op,
+		// but ended stays true and would silently discard all page output.
+		// Reset it so the page executes with a clean response state.
+		if host.response.IsEnded() {
+			host.response.ResetEnded()
+		}
+		// Clear any residual output written during Session_OnStart so it
+		// does not leak into the page response body.
+		host.response.Clear()
+	}
+
+	return host
+}
+
+// addServerVariables fills the Request.ServerVariables collection shared by every host.
+func (h *Host) addServerVariables(r *http.Request, requestPath string, opts HostOptions) {
+	vars := h.request.ServerVars
+	software := opts.ServerSoftware
+	if software == "" {
+		software = "AxonASP"
+	}
+	applicationPath := opts.ApplicationPhysicalPath
+	if applicationPath == "" {
+		applicationPath = h.server.MapPath("/")
+	}
+	https := "off"
+	if axonproxy.IsHTTPS(r) {
+		https = "on"
+	}
+	authType, authUser, authPassword := AuthType(r), "", ""
+	if user := axonauth.UserFromRequest(r); user != nil {
+		authType, authUser, authPassword = user.AuthType, user.Name, user.Password
+	}
+
+	vars.Add("QUERY_STRING", r.URL.RawQuery)
+	vars.Add("HTTP_HOST", r.Host)
+	vars.Add("HTTP_CONTENT_TYPE", r.Header.Get("Content-Type"))
+	vars.Add("HTTP_X_G3AXONLIVE", r.Header.Get("X-G3AxonLive"))
+	vars.Add("HTTP_X_G3AXONLIVE_SESSIONID", r.Header.Get("X-G3AxonLive-SessionId"))
+	vars.Add("HTTP_X_G3AXONLIVE_COMPONENTID", r.Header.Get("X-G3AxonLive-ComponentId"))
+	vars.Add("HTTP_X_G3AXONLIVE_EVENTNAME", r.Header.Get("X-G3AxonLive-EventName"))
+	vars.Add("HTTP_X_G3AXONLIVE_EVENTARGS", r.Header.Get("X-G3AxonLive-EventArgs"))
+	vars.Add("HTTPS", https)
+	vars.Add("AUTH_TYPE", authType)
+	vars.Add("AUTH_USER", authUser)
+	vars.Add("AUTH_PASSWORD", authPassword)
+	vars.Add("LOGON_USER", authUser)
+	vars.Add("REMOTE_USER", authUser)
+	vars.Add("SERVER_ADDR", ServerAddr(r))
+	vars.Add("GATEWAY_INTERFACE", "CGI/1.1")
+	vars.Add("SERVER_SOFTWARE", software)
+	vars.Add("SERVER_PROTOCOL", r.Proto)
+	vars.Add("REQUEST_URI", r.URL.RequestURI())
+	vars.Add("PATH_INFO", requestPath)
+	vars.Add("PATH_TRANSLATED", h.server.MapPath(requestPath))
+	vars.Add("APPL_PHYSICAL_PATH", applicationPath)
+	if opts.ApplicationMetabasePath != "" {
+		vars.Add("APPL_MD_PATH", opts.ApplicationMetabasePath)
+	}
+	vars.Add("REMOTE_ADDR", RemoteAddr(r.RemoteAddr))
+	vars.Add("REMOTE_HOST", RemoteAddr(r.RemoteAddr))
+	vars.Add("REQUEST_METHOD", r.Method)
+	vars.Add("SERVER_NAME", ServerName(r))
+	vars.Add("SERVER_PORT", ServerPort(r))
+	vars.Add("SCRIPT_NAME", requestPath)
+	vars.Add("URL", requestPath)
+	vars.Add("HTTP_USER_AGENT", r.UserAgent())
+	vars.Add("HTTP_ACCEPT_LANGUAGE", r.Header.Get("Accept-Language"))
+	vars.Add("CONTENT_LENGTH", strconv.FormatInt(h.request.TotalBytes(), 10))
+	vars.Add("CONTENT_TYPE", r.Header.Get("Content-Type"))
+	allHTTP, allRaw := AggregateHeaderServerVariables(r.Header)
+	vars.Add("ALL_HTTP", allHTTP)
+	vars.Add("ALL_RAW", allRaw)
+
+	// Expose all request headers for ASP access (HTTP_<HEADER_NAME>).
+	for headerName, values := range r.Header {
+		if len(values) == 0 {
+			continue
+		}
+		vars.Add(ServerVariableFromHeader(headerName), strings.Join(values, ","))
+	}
+}
+
+// Response returns the ASP Response intrinsic object.
+func (h *Host) Response() *asp.Response { return h.response }
+
+// Request returns the ASP Request intrinsic object.
+func (h *Host) Request() *asp.Request { return h.request }
+
+// Server returns the ASP Server intrinsic object.
+func (h *Host) Server() *asp.Server { return h.server }
+
+// Session returns the ASP Session intrinsic object.
+func (h *Host) Session() *asp.Session { return h.session }
+
+// Application returns the ASP Application intrinsic object.
+func (h *Host) Application() *asp.Application { return h.application }
+
+// SetSessionEnabled toggles session state for the current ASP page execution.
+func (h *Host) SetSessionEnabled(enabled bool) { h.sessionEnabled = enabled }
+
+// SessionEnabled reports whether session state is enabled for the current ASP page.
+func (h *Host) SessionEnabled() bool { return h.sessionEnabled }
+
+// EngineMode returns the current language mode for the host.
+func (h *Host) EngineMode() axonvm.EngineMode { return h.engineMode }
+
+// Write forwards raw bytes into the ASP Response buffer.
+func (h *Host) Write(p []byte) (int, error) {
+	h.response.Write(string(p))
+	return len(p), nil
+}
+
+// WriteString forwards text into the ASP Response buffer.
+func (h *Host) WriteString(s string) {
+	h.response.Write(s)
+}
+
+// PersistSession commits or removes session data after request execution.
+func (h *Host) PersistSession() {
+	if h.session == nil || !h.sessionEnabled {
+		return
+	}
+
+	// A session given in HostOptions belongs to the caller, which decides when it ends.
+	if h.noCookie {
+		h.saveSession()
+		return
+	}
+
+	// Delete runs Session_OnEnd of an abandoned session before the new session starts.
+	if h.session.IsAbandoned() {
+		_ = h.session.Delete()
+		newSession, err := asp.CreateSession()
+		if err == nil {
+			newSession.SetApplicationKey(h.session.ApplicationKey())
+			h.session = newSession
+			h.saveSession()
+			h.setSessionCookie()
+		}
+		return
+	}
+
+	h.saveSession()
+	h.setSessionCookie()
+}
+
+// saveSession writes the session if it changed. The asynchronous write-behind
+// releases the VM back to the pool faster; a shared session store is written
+// before the response ends so the next request can land on any node.
+func (h *Host) saveSession() {
+	if h.saveSync || asp.SessionStoreShared() {
+		_ = h.session.SaveIfDirty()
+		return
+	}
+	h.session.QueueSaveIfDirty()
+}
+
+// ExecuteASPFile compiles and executes another ASP file within the current host context.
+// The child script shares the same Response, Session, and Application as the parent.
+func (h *Host) ExecuteASPFile(absPath string) error {
+	previousRequestPath := h.server.GetRequestPath()
+	h.server.SetRequestPath(h.server.VirtualPathFromAbsolutePath(absPath))
+	defer h.server.SetRequestPath(previousRequestPath)
+
+	cache := h.scriptCache
+	if cache == nil {
+		cache = defaultScriptCache()
+	}
+	program, found := cache.Get(absPath)
+	if !found {
+		compiled, err := cache.LoadOrCompileWithOptions(absPath, axonvm.ScriptCompileOptions{IncludeSiteRoot: h.server.MapPath("/"), IncludeVirtualDirectories: h.server.VirtualDirectories()})
+		if err != nil {
+			return err
+		}
+		program = compiled
+	}
+
+	childVM, err := h.acquire(program)
+	if err != nil {
+		return err
+	}
+	childVM.SetHost(h)
+	defer childVM.Release()
+	return childVM.Run()
+}
+
+// acquire borrows a VM bound to the request context from the configured pool.
+func (h *Host) acquire(program axonvm.CachedProgram) (*axonvm.VM, error) {
+	pool := h.vmPool
+	if pool == nil {
+		pool = axonvm.DefaultVMPool()
+	}
+	return pool.Acquire(h.ctx, program)
+}
+
+// setSessionCookie updates the session cookie to match the current host session.
+func (h *Host) setSessionCookie() {
+	if h.noCookie || h.response == nil || h.response.Output == nil || h.session == nil {
+		return
+	}
+
+	writer, ok := h.response.Output.(http.ResponseWriter)
+	if !ok {
+		return
+	}
+
+	ReplaceResponseCookie(writer, h.cookieName)
+	http.SetCookie(writer, &http.Cookie{
+		Name:     h.cookieName,
+		Value:    asp.SessionCookieValue(h.session.GetID()),
+		Path:     h.cookiePath,
+		HttpOnly: true,
+		Secure:   h.cookieSecure,
+		SameSite: h.cookieSameSite,
+	})
+}
+
+// ReissueSessionCookie sends the session cookie again after Session.Regenerate
+// changed the session ID. Session.Regenerate does not change the ID once the
+// headers were sent, because the new cookie could not reach the client.
+func (h *Host) ReissueSessionCookie() {
+	h.setSessionCookie()
+}
+
+// loadOrCreateSession resolves the session from the session cookie or creates a new one.
+// A cookie without a valid signature starts a new session.
+func loadOrCreateSession(r *http.Request, cookieName string) (*asp.Session, bool) {
+	sessionID := SessionIDFromRequest(r, cookieName)
+
+	session, isNew, err := asp.GetOrCreateSession(sessionID)
+	if err != nil {
+		return asp.NewSession(), true
+	}
+	return session, isNew
+}
+
+// ReplaceResponseCookie removes any pending Set-Cookie header for one cookie name before rewriting it.
+func ReplaceResponseCookie(writer http.ResponseWriter, cookieName string) {
+	if writer == nil {
+		return
+	}
+
+	headers := writer.Header()
+	if headers == nil {
+		return
+	}
+
+	existing := headers.Values("Set-Cookie")
+	if len(existing) == 0 {
+		return
+	}
+
+	prefix := cookieName + "="
+	filtered := make([]string, 0, len(existing))
+	for _, value := range existing {
+		if strings.HasPrefix(value, prefix) {
+			continue
+		}
+		filtered = append(filtered, value)
+	}
+
+	headers.Del("Set-Cookie")
+	for _, value := range filtered {
+		headers.Add("Set-Cookie", value)
+	}
+}
+
+// AggregateHeaderServerVariables builds Classic ASP-style ALL_HTTP and ALL_RAW values.
+func AggregateHeaderServerVariables(header http.Header) (string, string) {
+	if len(header) == 0 {
+		return "", ""
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var allHTTP strings.Builder
+	var allRaw strings.Builder
+	for _, name := range names {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		joined := strings.Join(values, ",")
+
+		if allHTTP.Len() > 0 {
+			allHTTP.WriteString("\r\n")
+		}
+		allHTTP.WriteString(ServerVariableFromHeader(name))
+		allHTTP.WriteString(":")
+		allHTTP.WriteString(joined)
+
+		if allRaw.Len() > 0 {
+			allRaw.WriteString("\r\n")
+		}
+		allRaw.WriteString(name)
+		allRaw.WriteString(":")
+		allRaw.WriteString(joined)
+	}
+
+	return allHTTP.String(), allRaw.String()
+}
+
+// ServerVariableFromHeader converts an HTTP header name to classic ASP
+// ServerVariables key format: HTTP_<UPPERCASE_WITH_UNDERSCORES>.
+func ServerVariableFromHeader(headerName string) string {
+	return "HTTP_" + strings.ToUpper(strings.ReplaceAll(headerName, "-", "_"))
+}
+
+// RemoteAddr normalizes RemoteAddr into the client host without the port suffix.
+func RemoteAddr(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err == nil && host != "" {
+		return host
+	}
+	return remoteAddr
+}
+
+// ServerName resolves the ASP SERVER_NAME variable from the request host.
+func ServerName(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if host := r.URL.Hostname(); host != "" {
+		return host
+	}
+	host, _, err := net.SplitHostPort(r.Host)
+	if err == nil && host != "" {
+		return host
+	}
+	return r.Host
+}
+
+// ServerPort resolves the ASP SERVER_PORT variable using explicit or default scheme ports.
+func ServerPort(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if port := r.URL.Port(); port != "" {
+		return port
+	}
+	_, port, err := net.SplitHostPort(r.Host)
+	if err == nil && port != "" {
+		return port
+	}
+	if axonproxy.IsHTTPS(r) {
+		return "443"
+	}
+	return "80"
+}
+
+// ServerAddr resolves the ASP SERVER_ADDR variable using the best available host or remote address.
+func ServerAddr(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if host := ServerName(r); host != "" {
+		return host
+	}
+	return RemoteAddr(r.RemoteAddr)
+}
+
+// AuthType resolves the ASP AUTH_TYPE variable from the Authorization header when available.
+func AuthType(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	authorization := strings.TrimSpace(r.Header.Get("Authorization"))
+	if authorization == "" {
+		return ""
+	}
+	if space := strings.IndexByte(authorization, ' '); space > 0 {
+		return authorization[:space]
+	}
+	return authorization
+}
@@ -0,0 +1,112 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonhandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// TestNewHostAddsServerVariables verifies the standard request variables.
+func TestNewHostAddsServerVariables(t *testing.T) {
+	useTestSessionDir(t)
+	req := httptest.NewRequest(http.MethodPost, "http://example.com:8080/app/page.asp?id=7", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+	req.Header.Set("X-Custom-Header", "value")
+	host := NewHost(httptest.NewRecorder(), req, HostOptions{RootDir: t.TempDir(), ServerSoftware: "Test"})
+
+	vars := host.Request().ServerVars
+	expected := map[string]string{
+		"REQUEST_METHOD":       "POST",
+		"QUERY_STRING":         "id=7",
+		"SERVER_NAME":          "example.com",
+		"SERVER_PORT":          "8080",
+		"REMOTE_ADDR":          "10.1.2.3",
+		"SCRIPT_NAME":          "/app/page.asp",
+		"SERVER_SOFTWARE":      "Test",
+		"HTTP_X_CUSTOM_HEADER": "value",
+	}
+	for key, want := range expected {
+		if got := vars.Get(key); got != want {
+			t.Errorf("%s: expected %q, got %q", key, want, got)
+		}
+	}
+}
+
+// TestNewHostSessionCookie verifies that a new session sends its cookie and
+// that a session given in the options never does.
+func TestNewHostSessionCookie(t *testing.T) {
+	useTestSessionDir(t)
+	rec := httptest.NewRecorder()
+	host := NewHost(rec, httptest.NewRequest(http.MethodGet, "/", nil), HostOptions{RootDir: t.TempDir(), SessionCookieName: "TESTSESSION"})
+	host.PersistSession()
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "TESTSESSION" {
+		t.Fatalf("expected TESTSESSION cookie, got %v", cookies)
+	}
+
+	rec = httptest.NewRecorder()
+	host = NewHost(rec, httptest.NewRequest(http.MethodGet, "/", nil), HostOptions{RootDir: t.TempDir(), Session: asp.NewSession()})
+	host.PersistSession()
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Fatalf("expected no session cookie, got %v", cookies)
+	}
+}
+
+// TestNewHostSessionCookieAttributes verifies the Secure and SameSite options
+// of the session cookie and the validation of the cookie settings.
+func TestNewHostSessionCookieAttributes(t *testing.T) {
+	useTestSessionDir(t)
+	rec := httptest.NewRecorder()
+	host := NewHost(rec, httptest.NewRequest(http.MethodGet, "/", nil), HostOptions{RootDir: t.TempDir()})
+	host.PersistSession()
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expected a Lax cookie without Secure, got %v", cookies)
+	}
+
+	cfg := SessionCookieConfig{Name: "SHOPSESSION", Secure: true, SameSite: "Strict"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+	opts := HostOptions{RootDir: t.TempDir()}
+	cfg.Apply(&opts)
+	rec = httptest.NewRecorder()
+	host = NewHost(rec, httptest.NewRequest(http.MethodGet, "/", nil), opts)
+	host.PersistSession()
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "SHOPSESSION" || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteStrictMode {
+		t.Fatalf("expected a Secure Strict SHOPSESSION cookie, got %v", cookies)
+	}
+
+	rec = httptest.NewRecorder()
+	host = NewHost(rec, httptest.NewRequest(http.MethodGet, "/", nil), HostOptions{RootDir: t.TempDir(), SessionCookieSameSite: http.SameSiteNoneMode})
+	host.PersistSession()
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteNoneMode {
+		t.Fatalf("expected SameSite=None to add Secure, got %v", cookies)
+	}
+
+	for _, invalid := range []SessionCookieConfig{{Name: "bad name", SameSite: "lax"}, {Name: "ASPSESSIONID", SameSite: "sometimes"}} {
+		if invalid.Validate() == nil {
+			t.Fatalf("expected %+v to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonhandler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+	"github.com/spf13/viper"
+)
+
+// SessionCookieConfig holds the session cookie keys of the [global] section.
+// Sites and Caddy handlers can override them.
+type SessionCookieConfig struct {
+	// Name defaults to ASPSESSIONID.
+	Name string
+	// Secure sends the cookie only over HTTPS.
+	Secure bool
+	// SameSite is "lax" (default), "strict" or "none".
+	SameSite string
+}
+
+// SessionCookieConfigFromViper reads the session_cookie keys of the [global] section.
+func SessionCookieConfigFromViper(v *viper.Viper) SessionCookieConfig {
+	cfg := SessionCookieConfig{Name: DefaultSessionCookieName, SameSite: "lax"}
+	if v == nil {
+		return cfg
+	}
+	if name := strings.TrimSpace(v.GetString("global.session_cookie_name")); name != "" {
+		cfg.Name = name
+	}
+	cfg.Secure = v.GetBool("global.session_cookie_secure")
+	if sameSite := strings.ToLower(strings.TrimSpace(v.GetString("global.session_cookie_samesite"))); sameSite != "" {
+		cfg.SameSite = sameSite
+	}
+	return cfg
+}
+
+// Validate reports a cookie name that is not a valid HTTP token or an unknown SameSite mode.
+func (c SessionCookieConfig) Validate() error {
+	if !validCookieName(c.Name) {
+		return fmt.Errorf("invalid session cookie name %q", c.Name)
+	}
+	switch strings.ToLower(c.SameSite) {
+	case "", "lax", "strict", "none":
+		return nil
+	}
+	return fmt.Errorf("invalid session cookie SameSite mode %q, expected lax, strict or none", c.SameSite)
+}
+
+// SameSiteMode returns the http.SameSite value of c.SameSite. Unknown modes use Lax.
+func (c SessionCookieConfig) SameSiteMode() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+// Apply copies the cookie settings of c into opts.
+func (c SessionCookieConfig) Apply(opts *HostOptions) {
+	opts.SessionCookieName = c.Name
+	opts.SessionCookieSecure = c.Secure
+	opts.SessionCookieSameSite = c.SameSiteMode()
+}
+
+// SessionIDFromRequest returns the session ID held in the cookieName cookie
+// of r, or "" when r has no session cookie or its signature is not valid.
+func SessionIDFromRequest(r *http.Request, cookieName string) string {
+	if cookieName == "" {
+		cookieName = DefaultSessionCookieName
+	}
+	cookie, err := r.Cookie(cookieName)
+	if err != nil || cookie == nil {
+		return ""
+	}
+	sessionID, _ := asp.SessionIDFromCookie(cookie.Value)
+	return sessionID
+}
+
+// validCookieName reports whether name is an RFC 6265 cookie name token.
+func validCookieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune("()<>@,;:\\\"/[]?={}", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonhandler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// SessionApplicationKey returns the key that ties the sessions started with
+// opts to the Session_OnEnd handler of their application: opts.ApplicationKey
+// or the absolute application folder.
+func SessionApplicationKey(opts HostOptions) string {
+	if opts.ApplicationKey != "" {
+		return opts.ApplicationKey
+	}
+	root := opts.ApplicationPhysicalPath
+	if root == "" {
+		root = opts.RootDir
+	}
+	if root == "" {
+		return ""
+	}
+	if absRoot, err := filepath.Abs(root); err == nil {
+		root = absRoot
+	}
+	return filepath.Clean(root)
+}
+
+// RegisterSessionOnEnd runs Session_OnEnd of opts.GlobalASA when a session
+// started with opts is abandoned, expires in memory or is reaped from the
+// session store. The event runs on a host without a client whose Session holds
+// the contents of the ended session. Call it after Application_OnStart.
+func RegisterSessionOnEnd(opts HostOptions) {
+	globalASA := opts.GlobalASA
+	if globalASA == nil {
+		return
+	}
+	opts.GlobalASA = nil
+	asp.RegisterSessionEndHandler(SessionApplicationKey(opts), func(session *asp.Session) {
+		if !globalASA.IsLoaded() {
+			return
+		}
+		if err := globalASA.ExecuteSessionOnEnd(newSessionEndHost(opts, session)); err != nil {
+			log.Printf("Warning: Session_OnEnd for session %s failed: %v\n", session.GetID(), err)
+		}
+	})
+}
+
+// UnregisterSessionOnEnd stops running Session_OnEnd for the application of
+// opts, for example after its Application_OnEnd.
+func UnregisterSessionOnEnd(opts HostOptions) {
+	asp.UnregisterSessionEndHandler(SessionApplicationKey(opts))
+}
+
+// newSessionEndHost returns the host without a client that runs Session_OnEnd
+// for session. The session is given in the options, so no cookie is written.
+func newSessionEndHost(opts HostOptions, session *asp.Session) *Host {
+	path := opts.SessionCookiePath
+	if path == "" {
+		path = "/"
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://localhost"+path, nil)
+	req.RemoteAddr = "127.0.0.1:0"
+	opts.Session = session
+	opts.RequestPath = path
+	return NewHost(DiscardWriter{}, req, opts)
+}
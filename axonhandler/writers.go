@@ -0,0 +1,151 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonhandler
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// SingleHeaderWriter prevents duplicate WriteHeader calls and can apply a default status code.
+type SingleHeaderWriter struct {
+	http.ResponseWriter
+	wroteHeader   bool
+	defaultStatus int
+}
+
+// NewSingleHeaderWriter wraps a response writer with duplicate WriteHeader protection.
+// A defaultStatus above zero replaces 200, so an error page keeps its status.
+func NewSingleHeaderWriter(w http.ResponseWriter, defaultStatus int) *SingleHeaderWriter {
+	return &SingleHeaderWriter{ResponseWriter: w, defaultStatus: defaultStatus}
+}
+
+// WriteHeader writes the status code only once.
+func (w *SingleHeaderWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	if w.defaultStatus > 0 && (statusCode <= 0 || statusCode == http.StatusOK) {
+		statusCode = w.defaultStatus
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write applies the default status if needed and writes the response body.
+func (w *SingleHeaderWriter) Write(data []byte) (int, error) {
+	w.ensureHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush forwards flush operations to the underlying writer.
+func (w *SingleHeaderWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// ReadFrom forwards optimized copy operations to the underlying writer when available.
+func (w *SingleHeaderWriter) ReadFrom(reader io.Reader) (int64, error) {
+	w.ensureHeader()
+	if readFrom, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return readFrom.ReadFrom(reader)
+	}
+	return io.Copy(w.ResponseWriter, reader)
+}
+
+// ensureHeader writes the default status before the first body byte.
+func (w *SingleHeaderWriter) ensureHeader() {
+	if w.wroteHeader {
+		return
+	}
+	if w.defaultStatus > 0 {
+		w.WriteHeader(w.defaultStatus)
+		return
+	}
+	w.wroteHeader = true
+}
+
+// CancellableWriter wraps an http.ResponseWriter and silently discards all writes
+// after Cancel is called. It detaches a goroutine that is still executing ASP
+// (typically stuck inside a blocking CGO/COM call) from the real writer after
+// the script timeout has fired.
+type CancellableWriter struct {
+	mu       sync.Mutex
+	inner    http.ResponseWriter
+	canceled bool
+}
+
+// NewCancellableWriter wraps w until Cancel is called.
+func NewCancellableWriter(w http.ResponseWriter) *CancellableWriter {
+	return &CancellableWriter{inner: w}
+}
+
+// Header returns the wrapped headers, or a detached map after Cancel.
+func (c *CancellableWriter) Header() http.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.canceled {
+		return make(http.Header)
+	}
+	return c.inner.Header()
+}
+
+// Write forwards p until Cancel is called and discards it afterwards.
+func (c *CancellableWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.canceled {
+		return len(p), nil
+	}
+	return c.inner.Write(p)
+}
+
+// WriteHeader forwards status until Cancel is called.
+func (c *CancellableWriter) WriteHeader(status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.canceled {
+		return
+	}
+	c.inner.WriteHeader(status)
+}
+
+// Cancel detaches the wrapped writer.
+func (c *CancellableWriter) Cancel() {
+	c.mu.Lock()
+	c.canceled = true
+	c.mu.Unlock()
+}
+
+// DiscardWriter is a response writer that drops all output. Hosts created for
+// global.asa events outside a request write into it.
+type DiscardWriter struct{}
+
+// Header returns a new empty header map.
+func (DiscardWriter) Header() http.Header { return make(http.Header) }
+
+// Write discards b.
+func (DiscardWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+// WriteHeader discards the status code.
+func (DiscardWriter) WriteHeader(int) {}
@@ -0,0 +1,52 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonhandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestSingleHeaderWriterOverrides200WithDefault verifies that configured
+// default error statuses are not downgraded to 200 by wrapped handlers.
+func TestSingleHeaderWriterOverrides200WithDefault(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewSingleHeaderWriter(rec, http.StatusNotFound)
+
+	w.WriteHeader(http.StatusOK)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// TestSingleHeaderWriterPreservesNon200 verifies explicit non-200 statuses remain intact.
+func TestSingleHeaderWriterPreservesNon200(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewSingleHeaderWriter(rec, http.StatusNotFound)
+
+	w.WriteHeader(http.StatusInternalServerError)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
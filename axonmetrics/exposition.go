@@ -0,0 +1,281 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonmetrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// expositionWriter writes metric families in the Prometheus text format 0.0.4.
+type expositionWriter struct {
+	w *bufio.Writer
+}
+
+// family writes the HELP and TYPE lines of one metric.
+func (e *expositionWriter) family(name, kind, help string) {
+	e.w.WriteString("# HELP " + name + " " + help + "\n")
+	e.w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+// sample writes one sample line. labels alternates names and values.
+func (e *expositionWriter) sample(name string, value float64, labels ...string) {
+	e.w.WriteString(name)
+	if len(labels) > 0 {
+		e.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			e.w.WriteString(labels[i] + `="` + escapeLabelValue(labels[i+1]) + `"`)
+		}
+		e.w.WriteByte('}')
+	}
+	e.w.WriteByte(' ')
+	e.w.WriteString(formatFloat(value))
+	e.w.WriteByte('\n')
+}
+
+// single writes a metric family that has one unlabeled sample.
+func (e *expositionWriter) single(name, kind, help string, value float64) {
+	e.family(name, kind, help)
+	e.sample(name, value)
+}
+
+// escapeLabelValue escapes backslashes, quotes and line feeds in a label value.
+func escapeLabelValue(value string) string {
+	if !strings.ContainsAny(value, "\\\"\n") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
+
+// formatFloat renders a sample value the way Prometheus expects.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// boolFloat converts a flag into a 0 or 1 gauge value.
+func boolFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// WriteTo renders every metric to out.
+func (m *Metrics) WriteTo(out io.Writer) (int64, error) {
+	counter := &countingWriter{w: out}
+	e := &expositionWriter{w: bufio.NewWriter(counter)}
+	e.family("axonasp_build_info", "gauge", "AxonASP version and host type, always 1.")
+	e.sample("axonasp_build_info", 1, "version", m.version, "host", m.host)
+	e.single("axonasp_start_time_seconds", "gauge", "Start time of the host since the Unix epoch in seconds.", float64(m.start.UnixNano())/1e9)
+	m.writeRequests(e)
+	writeVMPool(e)
+	m.writeScriptCaches(e)
+	writeSessions(e)
+	writeAxonLive(e)
+	writeGoRuntime(e)
+	err := e.w.Flush()
+	return counter.n, err
+}
+
+// writeRequests writes the request counters and the duration histogram.
+func (m *Metrics) writeRequests(e *expositionWriter) {
+	type snapshot struct {
+		key    requestKey
+		series requestSeries
+	}
+	m.mu.Lock()
+	rows := make([]snapshot, 0, len(m.requests))
+	for key, series := range m.requests {
+		copied := *series
+		copied.buckets = append([]uint64(nil), series.buckets...)
+		rows = append(rows, snapshot{key: key, series: copied})
+	}
+	m.mu.Unlock()
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].key.code != rows[j].key.code {
+			return rows[i].key.code < rows[j].key.code
+		}
+		return rows[i].key.extension < rows[j].key.extension
+	})
+
+	e.single("axonasp_http_requests_in_flight", "gauge", "Requests currently being served.", float64(m.inFlight.Load()))
+
+	e.family("axonasp_http_requests_total", "counter", "Completed requests by status code and file extension.")
+	for _, row := range rows {
+		e.sample("axonasp_http_requests_total", float64(row.series.count), "code", strconv.Itoa(row.key.code), "extension", row.key.extension)
+	}
+
+	e.family("axonasp_http_request_duration_seconds", "histogram", "Request latency by status code and file extension.")
+	for _, row := range rows {
+		code := strconv.Itoa(row.key.code)
+		for i, bound := range m.buckets {
+			e.sample("axonasp_http_request_duration_seconds_bucket", float64(row.series.buckets[i]), "code", code, "extension", row.key.extension, "le", formatFloat(bound))
+		}
+		e.sample("axonasp_http_request_duration_seconds_bucket", float64(row.series.count), "code", code, "extension", row.key.extension, "le", "+Inf")
+		e.sample("axonasp_http_request_duration_seconds_sum", row.series.sum, "code", code, "extension", row.key.extension)
+		e.sample("axonasp_http_request_duration_seconds_count", float64(row.series.count), "code", code, "extension", row.key.extension)
+	}
+}
+
+// writeVMPool writes the interpreter pool statistics.
+func writeVMPool(e *expositionWriter) {
+	stats := axonvm.GetVMPoolStats()
+	e.single("axonasp_vm_pool_slots", "gauge", "Configured vm_pool_size, 0 when unlimited.", float64(stats.SlotLimit))
+	e.single("axonasp_vm_pool_slots_in_use", "gauge", "VM pool slots held by running requests.", float64(stats.SlotsInUse))
+	e.single("axonasp_vm_pool_waiting", "gauge", "Requests waiting for a free VM pool slot.", float64(stats.Waiting))
+	e.single("axonasp_vm_pool_checked_out", "gauge", "VMs currently borrowed from the pool.", float64(stats.CheckedOut))
+	e.single("axonasp_vm_pool_idle", "gauge", "VMs retained in the pool, ready for reuse.", float64(stats.Idle))
+	e.single("axonasp_vm_pool_programs", "gauge", "Compiled programs that own a VM pool.", float64(stats.Programs))
+	e.single("axonasp_vm_pool_acquired_total", "counter", "VM checkouts since start.", float64(stats.Acquired))
+	e.single("axonasp_vm_pool_waits_total", "counter", "VM checkouts that waited for a free slot.", float64(stats.Waits))
+	e.single("axonasp_vm_pool_wait_seconds_total", "counter", "Time spent waiting for VM pool slots.", stats.WaitTime.Seconds())
+	e.single("axonasp_vm_pool_queue_rejected_total", "counter", "Requests rejected because the admission queue was full.", float64(stats.QueueRejected))
+	e.single("axonasp_vm_pool_queue_timeouts_total", "counter", "Requests that timed out waiting in the admission queue.", float64(stats.QueueTimeouts))
+}
+
+// writeScriptCaches writes the bytecode cache and file watcher statistics.
+func (m *Metrics) writeScriptCaches(e *expositionWriter) {
+	type cacheRow struct {
+		name    string
+		stats   axonvm.ScriptCacheStats
+		active  bool
+		errors  uint32
+		watched int
+	}
+	m.cacheMu.RLock()
+	rows := make([]cacheRow, 0, len(m.caches))
+	for name, cache := range m.caches {
+		active, errorCount, roots := cache.GetWatcherStatus()
+		rows = append(rows, cacheRow{name: name, stats: cache.Stats(), active: active, errors: errorCount, watched: roots})
+	}
+	m.cacheMu.RUnlock()
+	sort.Slice(rows, func(i, j int) bool { return rows[i].name < rows[j].name })
+
+	families := []struct {
+		name, kind, help string
+		value            func(cacheRow) float64
+	}{
+		{"axonasp_script_cache_hits_total", "counter", "Scripts served from the memory cache.", func(r cacheRow) float64 { return float64(r.stats.Hits) }},
+		{"axonasp_script_cache_misses_total", "counter", "Scripts not found in the memory cache.", func(r cacheRow) float64 { return float64(r.stats.Misses) }},
+		{"axonasp_script_cache_disk_hits_total", "counter", "Cache misses loaded from the disk cache.", func(r cacheRow) float64 { return float64(r.stats.DiskHits) }},
+		{"axonasp_script_cache_compiles_total", "counter", "Successful script compilations.", func(r cacheRow) float64 { return float64(r.stats.Compiles) }},
+		{"axonasp_script_cache_compile_errors_total", "counter", "Failed script compilations.", func(r cacheRow) float64 { return float64(r.stats.CompileErrors) }},
+		{"axonasp_script_cache_evictions_total", "counter", "Programs evicted to stay under cache_max_size_mb.", func(r cacheRow) float64 { return float64(r.stats.Evictions) }},
+		{"axonasp_script_cache_entries", "gauge", "Compiled programs held in memory.", func(r cacheRow) float64 { return float64(r.stats.Entries) }},
+		{"axonasp_script_cache_size_bytes", "gauge", "Estimated memory used by the cached programs.", func(r cacheRow) float64 { return float64(r.stats.SizeBytes) }},
+		{"axonasp_script_cache_max_bytes", "gauge", "Configured memory limit of the cache.", func(r cacheRow) float64 { return float64(r.stats.MaxBytes) }},
+		{"axonasp_script_cache_watcher_active", "gauge", "1 when the file watcher that invalidates the cache is running.", func(r cacheRow) float64 { return boolFloat(r.active) }},
+		{"axonasp_script_cache_watcher_errors_total", "counter", "Errors reported by the file watcher.", func(r cacheRow) float64 { return float64(r.errors) }},
+		{"axonasp_script_cache_watcher_roots", "gauge", "Root directories watched for changes.", func(r cacheRow) float64 { return float64(r.watched) }},
+	}
+	for _, family := range families {
+		e.family(family.name, family.kind, family.help)
+		for _, row := range rows {
+			e.sample(family.name, family.value(row), "cache", row.name)
+		}
+	}
+}
+
+// writeSessions writes the session registry statistics.
+func writeSessions(e *expositionWriter) {
+	stats := asp.GetSessionStats()
+	e.single("axonasp_sessions_registered", "gauge", "Sessions held in memory.", float64(stats.Registered))
+	e.single("axonasp_session_write_queue_length", "gauge", "Sessions waiting to be written to disk.", float64(stats.QueuedWrites))
+	e.single("axonasp_session_flushes_total", "counter", "Session flush runs since start.", float64(stats.Flushes))
+	e.single("axonasp_session_flush_errors_total", "counter", "Session flush runs that reported an error.", float64(stats.FlushErrors))
+	e.single("axonasp_session_flush_seconds_total", "counter", "Time spent flushing sessions.", stats.FlushTime.Seconds())
+	e.single("axonasp_session_last_flush_seconds", "gauge", "Duration of the most recent session flush.", stats.LastFlushTime.Seconds())
+}
+
+// writeAxonLive writes the G3AxonLive component store size.
+func writeAxonLive(e *expositionWriter) {
+	properties, sessions := axonvm.G3ALStoreStats()
+	e.single("axonasp_axonlive_component_properties", "gauge", "Component properties held by the G3AxonLive store.", float64(properties))
+	e.single("axonasp_axonlive_sessions", "gauge", "Sessions with a registered G3AxonLive page.", float64(sessions))
+}
+
+// writeGoRuntime writes the Go runtime statistics.
+func writeGoRuntime(e *expositionWriter) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	e.single("go_goroutines", "gauge", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	e.single("go_threads", "gauge", "Number of OS threads created.", float64(threadCount()))
+	e.single("go_gomaxprocs", "gauge", "Value of GOMAXPROCS.", float64(runtime.GOMAXPROCS(0)))
+	e.single("go_memstats_alloc_bytes", "gauge", "Bytes of allocated heap objects.", float64(mem.Alloc))
+	e.single("go_memstats_alloc_bytes_total", "counter", "Cumulative bytes allocated for heap objects.", float64(mem.TotalAlloc))
+	e.single("go_memstats_sys_bytes", "gauge", "Bytes of memory obtained from the OS.", float64(mem.Sys))
+	e.single("go_memstats_heap_inuse_bytes", "gauge", "Bytes in in-use heap spans.", float64(mem.HeapInuse))
+	e.single("go_memstats_heap_idle_bytes", "gauge", "Bytes in idle heap spans.", float64(mem.HeapIdle))
+	e.single("go_memstats_heap_objects", "gauge", "Number of allocated heap objects.", float64(mem.HeapObjects))
+	e.single("go_memstats_stack_inuse_bytes", "gauge", "Bytes in stack spans.", float64(mem.StackInuse))
+	e.single("go_memstats_mallocs_total", "counter", "Cumulative count of heap objects allocated.", float64(mem.Mallocs))
+	e.single("go_memstats_frees_total", "counter", "Cumulative count of heap objects freed.", float64(mem.Frees))
+	e.single("go_memstats_next_gc_bytes", "gauge", "Heap size target of the next GC cycle.", float64(mem.NextGC))
+	e.single("go_gc_cycles_total", "counter", "Completed GC cycles.", float64(mem.NumGC))
+	e.single("go_gc_pause_seconds_total", "counter", "Cumulative stop-the-world GC pause time.", float64(mem.PauseTotalNs)/float64(time.Second))
+	if mem.LastGC > 0 {
+		e.single("go_memstats_last_gc_time_seconds", "gauge", "Time of the last GC since the Unix epoch in seconds.", float64(mem.LastGC)/1e9)
+	}
+}
+
+// threadCount returns the number of OS threads created by the runtime.
+func threadCount() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
+
+// countingWriter counts the bytes written for WriteTo.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write forwards data and adds its length to the total.
+func (c *countingWriter) Write(data []byte) (int, error) {
+	n, err := c.w.Write(data)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,178 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonmetrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Handler wraps next and records one observation per request. When no dedicated
+// listener is configured, requests for the metrics path are answered directly.
+// A nil *Metrics returns next unchanged.
+func (m *Metrics) Handler(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.listen == "" && r.URL.Path == m.path {
+			m.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		m.inFlight.Add(1)
+		defer func() {
+			m.inFlight.Add(-1)
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			m.Observe(status, r.URL.Path, time.Since(start))
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// ServeHTTP writes the metrics to clients in the allowed address list.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.clientAllowed(r.RemoteAddr) {
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == http.MethodHead {
+		return
+	}
+	m.WriteTo(w)
+}
+
+// clientAllowed reports whether the remote address may read the metrics.
+func (m *Metrics) clientAllowed(remoteAddr string) bool {
+	if len(m.allowed) == 0 {
+		return true
+	}
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range m.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start opens the dedicated metrics listener, when one is configured.
+func (m *Metrics) Start() error {
+	return m.StartWithListen(net.Listen)
+}
+
+// StartWithListen opens the dedicated metrics listener through listen, so a host
+// can pass a socket inherited from the process it replaces.
+func (m *Metrics) StartWithListen(listen func(network, addr string) (net.Listener, error)) error {
+	if m == nil || m.listen == "" {
+		return nil
+	}
+	listener, err := listen("tcp", m.listen)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(m.path, m)
+	m.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = m.server.Serve(listener)
+	}()
+	return nil
+}
+
+// Close stops the dedicated metrics listener.
+func (m *Metrics) Close() error {
+	if m == nil || m.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return m.server.Shutdown(ctx)
+}
+
+// statusRecorder captures the response status while keeping streaming and
+// connection upgrades working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the first status code.
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write marks an implicit 200 status before the first body bytes.
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Flush forwards flush operations to the underlying writer.
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack forwards connection takeover, used by WebSocket upgrades.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
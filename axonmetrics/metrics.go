@@ -0,0 +1,306 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonmetrics exposes request, interpreter, cache and session statistics of the
+// AxonASP hosts in the Prometheus text exposition format.
+package axonmetrics
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultListen is the address of the dedicated metrics listener.
+	DefaultListen = "127.0.0.1:9464"
+	// DefaultPath is the URL path that serves the metrics.
+	DefaultPath = "/metrics"
+
+	// maxExtensions bounds the number of distinct extension label values.
+	maxExtensions = 64
+)
+
+// DefaultBuckets are the request duration histogram bounds, in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Config controls the metrics endpoint.
+type Config struct {
+	Enabled bool
+	// Listen is the address of a dedicated listener. When empty, the metrics are
+	// served by the host itself at Path.
+	Listen string
+	Path   string
+	// AllowedIPs lists the addresses and CIDR ranges allowed to read the metrics.
+	// An empty list allows every client.
+	AllowedIPs []string
+	Buckets    []float64
+}
+
+// ConfigFromViper reads the [metrics] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		Listen:     DefaultListen,
+		Path:       DefaultPath,
+		AllowedIPs: []string{"127.0.0.1", "::1"},
+		Buckets:    DefaultBuckets,
+	}
+	if v == nil {
+		return cfg
+	}
+	cfg.Enabled = v.GetBool("metrics.enable_metrics")
+	if v.IsSet("metrics.metrics_listen") {
+		cfg.Listen = strings.TrimSpace(v.GetString("metrics.metrics_listen"))
+	}
+	if p := strings.TrimSpace(v.GetString("metrics.metrics_path")); p != "" {
+		cfg.Path = p
+	}
+	if v.IsSet("metrics.metrics_allowed_ips") {
+		cfg.AllowedIPs = v.GetStringSlice("metrics.metrics_allowed_ips")
+	}
+	if raw := v.GetStringSlice("metrics.metrics_duration_buckets"); len(raw) > 0 {
+		buckets := make([]float64, 0, len(raw))
+		for _, item := range raw {
+			if bound, err := strconv.ParseFloat(strings.TrimSpace(item), 64); err == nil && bound > 0 {
+				buckets = append(buckets, bound)
+			}
+		}
+		if len(buckets) > 0 {
+			cfg.Buckets = buckets
+		}
+	}
+	return cfg
+}
+
+// requestKey identifies one request series.
+type requestKey struct {
+	code      int
+	extension string
+}
+
+// requestSeries holds the counter and histogram of one request series.
+type requestSeries struct {
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// Metrics collects request statistics and renders every AxonASP metric.
+// A nil *Metrics is valid and disables collection.
+type Metrics struct {
+	host     string
+	version  string
+	path     string
+	listen   string
+	buckets  []float64
+	allowed  []*net.IPNet
+	start    time.Time
+	inFlight atomic.Int64
+
+	mu         sync.Mutex
+	requests   map[requestKey]*requestSeries
+	extensions map[string]struct{}
+
+	cacheMu sync.RWMutex
+	caches  map[string]*axonvm.ScriptCache
+
+	server *http.Server
+}
+
+// New builds the collector of one host, such as "http", "fastcgi" or "caddy".
+// It returns nil when cfg.Enabled is false.
+func New(cfg Config, host, version string) (*Metrics, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	metricsPath := cfg.Path
+	if metricsPath == "" {
+		metricsPath = DefaultPath
+	}
+	if !strings.HasPrefix(metricsPath, "/") {
+		return nil, errors.New("metrics path must start with /: " + metricsPath)
+	}
+	allowed, err := parseAllowedIPs(cfg.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	buckets := cfg.Buckets
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Metrics{
+		host:       host,
+		version:    version,
+		path:       metricsPath,
+		listen:     cfg.Listen,
+		buckets:    sortedBuckets(buckets),
+		allowed:    allowed,
+		start:      time.Now(),
+		requests:   make(map[requestKey]*requestSeries),
+		extensions: make(map[string]struct{}),
+		caches:     make(map[string]*axonvm.ScriptCache),
+	}, nil
+}
+
+// parseAllowedIPs converts addresses and CIDR ranges into networks.
+func parseAllowedIPs(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, errors.New("invalid metrics allowed address: " + value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.New("invalid metrics allowed range: " + value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// sortedBuckets returns a sorted copy of the bucket bounds without duplicates.
+func sortedBuckets(values []float64) []float64 {
+	out := make([]float64, 0, len(values))
+	for _, value := range values {
+		i := 0
+		for i < len(out) && out[i] < value {
+			i++
+		}
+		if i < len(out) && out[i] == value {
+			continue
+		}
+		out = append(out, 0)
+		copy(out[i+1:], out[i:])
+		out[i] = value
+	}
+	return out
+}
+
+// AddScriptCache registers a bytecode cache under a name used as the cache label.
+func (m *Metrics) AddScriptCache(name string, cache *axonvm.ScriptCache) {
+	if m == nil || cache == nil {
+		return
+	}
+	if name == "" {
+		name = "default"
+	}
+	m.cacheMu.Lock()
+	m.caches[name] = cache
+	m.cacheMu.Unlock()
+}
+
+// RemoveScriptCache unregisters a bytecode cache added with AddScriptCache.
+func (m *Metrics) RemoveScriptCache(cache *axonvm.ScriptCache) {
+	if m == nil || cache == nil {
+		return
+	}
+	m.cacheMu.Lock()
+	for name, registered := range m.caches {
+		if registered == cache {
+			delete(m.caches, name)
+		}
+	}
+	m.cacheMu.Unlock()
+}
+
+// Observe records one completed request.
+func (m *Metrics) Observe(status int, urlPath string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	seconds := elapsed.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := requestKey{code: status, extension: m.extensionLabelLocked(urlPath)}
+	series := m.requests[key]
+	if series == nil {
+		series = &requestSeries{buckets: make([]uint64, len(m.buckets))}
+		m.requests[key] = series
+	}
+	series.count++
+	series.sum += seconds
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			series.buckets[i]++
+		}
+	}
+}
+
+// extensionLabelLocked maps a URL path to a bounded extension label.
+func (m *Metrics) extensionLabelLocked(urlPath string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(urlPath), "."))
+	if ext == "" {
+		return "none"
+	}
+	if len(ext) > 10 {
+		return "other"
+	}
+	for _, ch := range ext {
+		if (ch < 'a' || ch > 'z') && (ch < '0' || ch > '9') {
+			return "other"
+		}
+	}
+	if _, ok := m.extensions[ext]; !ok {
+		if len(m.extensions) >= maxExtensions {
+			return "other"
+		}
+		m.extensions[ext] = struct{}{}
+	}
+	return ext
+}
+
+// Listen returns the address of the dedicated listener, or an empty string.
+func (m *Metrics) Listen() string {
+	if m == nil {
+		return ""
+	}
+	return m.listen
+}
+
+// Path returns the URL path that serves the metrics.
+func (m *Metrics) Path() string {
+	if m == nil {
+		return ""
+	}
+	return m.path
+}
@@ -0,0 +1,151 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonmetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+)
+
+// newTestMetrics creates a collector served on the host listener.
+func newTestMetrics(t *testing.T) *Metrics {
+	t.Helper()
+	cfg := ConfigFromViper(nil)
+	cfg.Enabled = true
+	cfg.Listen = ""
+	m, err := New(cfg, "http", "1.0.0")
+	if err != nil {
+		t.Fatalf("create metrics: %v", err)
+	}
+	return m
+}
+
+// scrape requests the metrics path through handler and returns the body.
+func scrape(t *testing.T, handler http.Handler, remoteAddr string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+// TestHandlerRecordsRequests verifies the counters, histogram and extension labels.
+func TestHandlerRecordsRequests(t *testing.T) {
+	m := newTestMetrics(t)
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".css") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	for _, target := range []string{"/default.asp", "/Shop/Cart.ASP?id=1", "/", "/site.css", "/a.b%22c"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	m.Observe(http.StatusOK, "/slow.asp", 3*time.Second)
+
+	code, body := scrape(t, handler, "127.0.0.1:5000")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 from the metrics path, got %d", code)
+	}
+	expected := []string{
+		`axonasp_build_info{version="1.0.0",host="http"} 1`,
+		`axonasp_http_requests_total{code="200",extension="asp"} 3`,
+		`axonasp_http_requests_total{code="200",extension="none"} 1`,
+		`axonasp_http_requests_total{code="200",extension="other"} 1`,
+		`axonasp_http_requests_total{code="404",extension="css"} 1`,
+		`axonasp_http_request_duration_seconds_bucket{code="200",extension="asp",le="2.5"} 2`,
+		`axonasp_http_request_duration_seconds_bucket{code="200",extension="asp",le="+Inf"} 3`,
+		`axonasp_http_request_duration_seconds_count{code="200",extension="asp"} 3`,
+		`axonasp_http_requests_in_flight 0`,
+		"# TYPE axonasp_http_request_duration_seconds histogram",
+		"# TYPE go_goroutines gauge",
+		"axonasp_vm_pool_checked_out ",
+		"axonasp_sessions_registered ",
+		"axonasp_axonlive_component_properties ",
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Fatalf("expected %q in metrics output:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, `extension="metrics"`) || strings.Contains(body, `axonasp_http_requests_total{code="200",extension="none"} 2`) {
+		t.Fatalf("scrape requests must not be counted:\n%s", body)
+	}
+}
+
+// TestMetricsAllowedIPs verifies clients outside metrics_allowed_ips are refused.
+func TestMetricsAllowedIPs(t *testing.T) {
+	m := newTestMetrics(t)
+	handler := m.Handler(http.NotFoundHandler())
+	if code, _ := scrape(t, handler, "192.0.2.10:4000"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a remote client, got %d", code)
+	}
+	if code, _ := scrape(t, handler, "[::1]:4000"); code != http.StatusOK {
+		t.Fatalf("expected 200 for ::1, got %d", code)
+	}
+
+	cfg := ConfigFromViper(nil)
+	cfg.Enabled = true
+	cfg.AllowedIPs = []string{"10.0.0.0/8"}
+	ranged, err := New(cfg, "fastcgi", "1.0.0")
+	if err != nil {
+		t.Fatalf("create metrics: %v", err)
+	}
+	if code, _ := scrape(t, ranged, "10.2.3.4:80"); code != http.StatusOK {
+		t.Fatalf("expected 200 inside the allowed range, got %d", code)
+	}
+
+	cfg.AllowedIPs = []string{"not-an-ip"}
+	if _, err := New(cfg, "http", "1.0.0"); err == nil {
+		t.Fatal("expected an invalid allowed address to be rejected")
+	}
+	if disabled, err := New(ConfigFromViper(nil), "http", "1.0.0"); disabled != nil || err != nil {
+		t.Fatalf("expected a nil collector when disabled, got %v %v", disabled, err)
+	}
+}
+
+// TestScriptCacheMetrics verifies registered caches are reported with their name.
+func TestScriptCacheMetrics(t *testing.T) {
+	m := newTestMetrics(t)
+	cache := axonvm.NewScriptCache(axonvm.BytecodeCacheMemoryOnly, t.TempDir(), 1)
+	m.AddScriptCache("intranet", cache)
+	_, body := scrape(t, m.Handler(http.NotFoundHandler()), "127.0.0.1:1")
+	for _, line := range []string{
+		`axonasp_script_cache_entries{cache="intranet"} 0`,
+		`axonasp_script_cache_max_bytes{cache="intranet"} 1048576`,
+		`axonasp_script_cache_watcher_active{cache="intranet"} 0`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("expected %q in metrics output:\n%s", line, body)
+		}
+	}
+	m.RemoveScriptCache(cache)
+	if _, body = scrape(t, m.Handler(http.NotFoundHandler()), "127.0.0.1:1"); strings.Contains(body, `cache="intranet"`) {
+		t.Fatalf("expected the removed cache to disappear:\n%s", body)
+	}
+}
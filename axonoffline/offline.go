@@ -0,0 +1,343 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonoffline takes an ASP application offline while an app_offline.htm
+// file exists in its root, the way IIS does, and restarts it when the file is removed.
+package axonoffline
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// FileName is the file that takes an application offline.
+const FileName = "app_offline.htm"
+
+// DefaultDrainTimeout bounds the wait for in-flight requests before Application_OnEnd runs.
+const DefaultDrainTimeout = 30 * time.Second
+
+// maxContentBytes caps the part of app_offline.htm kept in memory and served.
+const maxContentBytes = 1 << 20
+
+// drainPollInterval is how often the drain checks for in-flight requests.
+const drainPollInterval = 10 * time.Millisecond
+
+// defaultContent is served when app_offline.htm is empty.
+var defaultContent = []byte("<!DOCTYPE html><html><head><title>Service Unavailable</title></head><body><h1>Service Unavailable</h1><p>The application is offline for maintenance.</p></body></html>")
+
+// Config controls the app_offline.htm monitor.
+type Config struct {
+	Enabled bool
+	// DrainTimeout bounds the wait for in-flight requests when the application goes offline.
+	DrainTimeout time.Duration
+}
+
+// ConfigFromViper reads the app_offline keys of the [global] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		Enabled:      true,
+		DrainTimeout: DefaultDrainTimeout,
+	}
+	if v == nil {
+		return cfg
+	}
+	if v.IsSet("global.enable_app_offline") {
+		cfg.Enabled = v.GetBool("global.enable_app_offline")
+	}
+	if v.IsSet("global.app_offline_drain_timeout_seconds") {
+		cfg.DrainTimeout = time.Duration(max(v.GetInt("global.app_offline_drain_timeout_seconds"), 0)) * time.Second
+	}
+	return cfg
+}
+
+// Hooks start and stop the application guarded by a Monitor.
+type Hooks struct {
+	// Start loads global.asa and runs Application_OnStart. restart is true when the
+	// application starts again after app_offline.htm was removed.
+	Start func(restart bool)
+	// Stop runs Application_OnEnd after the in-flight requests finished.
+	Stop func()
+}
+
+// Monitor watches one application root for app_offline.htm and answers 503 while it exists.
+type Monitor struct {
+	root  string
+	cfg   Config
+	hooks Hooks
+
+	offline  atomic.Bool
+	inFlight atomic.Int64
+	content  atomic.Pointer[[]byte]
+
+	// mu serializes the transitions and guards the fields below.
+	mu       sync.Mutex
+	running  bool
+	started  bool
+	watching bool
+	watcher  *fsnotify.Watcher
+	signal   chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New creates a monitor for the application in root. With cfg disabled, the
+// monitor only runs the hooks on Start and Stop.
+func New(root string, cfg Config, hooks Hooks) *Monitor {
+	if absRoot, err := filepath.Abs(root); err == nil {
+		root = absRoot
+	}
+	if cfg.DrainTimeout < 0 {
+		cfg.DrainTimeout = 0
+	}
+	return &Monitor{
+		root:   filepath.Clean(root),
+		cfg:    cfg,
+		hooks:  hooks,
+		signal: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start starts the application, unless app_offline.htm already exists, and begins
+// watching the root. It reuses the invalidator of cache when it watches the root,
+// and otherwise opens its own watcher. The returned error only reports that
+// watching failed; the application is started either way.
+func (m *Monitor) Start(cache *axonvm.ScriptCache) error {
+	if m == nil {
+		return nil
+	}
+	if !m.cfg.Enabled {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if !m.running {
+			m.startApplication()
+		}
+		return nil
+	}
+
+	var watchErr error
+	m.mu.Lock()
+	if !m.watching {
+		m.watching = true
+		if cache.WatchesDir(m.root) {
+			cache.AddWatchListener(m.notify)
+		} else {
+			watcher, err := fsnotify.NewWatcher()
+			if err == nil {
+				if err = watcher.Add(m.root); err != nil {
+					_ = watcher.Close()
+				} else {
+					m.watcher = watcher
+				}
+			}
+			watchErr = err
+		}
+		go m.loop(m.watcher)
+	}
+	m.mu.Unlock()
+
+	m.sync()
+	return watchErr
+}
+
+// Stop stops watching the root and runs the Stop hook when the application is running.
+func (m *Monitor) Stop() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	watching := m.watching
+	if watching {
+		m.watching = false
+		close(m.stop)
+	}
+	m.mu.Unlock()
+	if watching {
+		<-m.done
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.watcher != nil {
+		_ = m.watcher.Close()
+		m.watcher = nil
+	}
+	if m.running {
+		m.running = false
+		if m.hooks.Stop != nil {
+			m.hooks.Stop()
+		}
+	}
+}
+
+// Offline reports whether the application is offline.
+func (m *Monitor) Offline() bool {
+	return m != nil && m.offline.Load()
+}
+
+// Handler wraps next with Serve.
+func (m *Monitor) Handler(next http.Handler) http.Handler {
+	if m == nil || !m.cfg.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Serve(w, r, next)
+	})
+}
+
+// Serve answers 503 with the content of app_offline.htm while the application is
+// offline, and otherwise passes the request to next and counts it as in flight.
+func (m *Monitor) Serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if m == nil || !m.cfg.Enabled {
+		next.ServeHTTP(w, r)
+		return
+	}
+	m.inFlight.Add(1)
+	if m.offline.Load() {
+		m.inFlight.Add(-1)
+		m.writeOffline(w)
+		return
+	}
+	defer m.inFlight.Add(-1)
+	next.ServeHTTP(w, r)
+}
+
+// writeOffline sends the stored app_offline.htm content.
+func (m *Monitor) writeOffline(w http.ResponseWriter) {
+	content := defaultContent
+	if stored := m.content.Load(); stored != nil && len(*stored) > 0 {
+		content = *stored
+	}
+	header := w.Header()
+	header.Set("Content-Type", "text/html")
+	header.Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = w.Write(content)
+}
+
+// notify wakes the loop for events on app_offline.htm in the root.
+func (m *Monitor) notify(path string) {
+	if !strings.EqualFold(filepath.Base(path), FileName) {
+		return
+	}
+	if !strings.EqualFold(filepath.Clean(filepath.Dir(path)), m.root) {
+		return
+	}
+	select {
+	case m.signal <- struct{}{}:
+	default:
+	}
+}
+
+// loop applies the file changes until Stop. watcher is nil when the script cache
+// invalidator delivers the events through notify.
+func (m *Monitor) loop(watcher *fsnotify.Watcher) {
+	defer close(m.done)
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if watcher != nil {
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-m.signal:
+			m.sync()
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				m.notify(event.Name)
+			}
+		case _, ok := <-errs:
+			if !ok {
+				errs = nil
+			}
+		}
+	}
+}
+
+// sync brings the application in line with the presence of app_offline.htm.
+func (m *Monitor) sync() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content, exists := readContent(filepath.Join(m.root, FileName))
+	if exists {
+		m.content.Store(&content)
+		m.offline.Store(true)
+		if m.running {
+			m.drain()
+			m.running = false
+			if m.hooks.Stop != nil {
+				m.hooks.Stop()
+			}
+		}
+		return
+	}
+	if !m.running {
+		m.startApplication()
+	}
+	m.offline.Store(false)
+}
+
+// startApplication runs the Start hook. The caller must hold m.mu.
+func (m *Monitor) startApplication() {
+	if m.hooks.Start != nil {
+		m.hooks.Start(m.started)
+	}
+	m.started = true
+	m.running = true
+}
+
+// drain waits until the requests admitted before the application went offline
+// finish, or DrainTimeout expires.
+func (m *Monitor) drain() {
+	deadline := time.Now().Add(m.cfg.DrainTimeout)
+	for m.inFlight.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// readContent reads up to maxContentBytes of path and reports whether it exists.
+func readContent(path string) ([]byte, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, !os.IsNotExist(err)
+	}
+	defer file.Close()
+	content, _ := io.ReadAll(io.LimitReader(file, maxContentBytes))
+	return content, true
+}
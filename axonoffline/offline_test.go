@@ -0,0 +1,163 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonoffline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestMonitorOfflineDrainAndRestart verifies the 503 response, the drain of
+// in-flight requests and the restart when app_offline.htm is removed.
+func TestMonitorOfflineDrainAndRestart(t *testing.T) {
+	root := t.TempDir()
+	events := make(chan string, 8)
+	m := New(root, Config{Enabled: true, DrainTimeout: 5 * time.Second}, Hooks{
+		Start: func(restart bool) {
+			if restart {
+				events <- "restart"
+			} else {
+				events <- "start"
+			}
+		},
+		Stop: func() { events <- "stop" },
+	})
+	if err := m.Start(nil); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer m.Stop()
+	if event := <-events; event != "start" {
+		t.Fatalf("expected start, got %s", event)
+	}
+
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	slowDone := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		m.Serve(rec, httptest.NewRequest(http.MethodGet, "/slow.asp", nil), slow)
+		slowDone <- rec.Code
+	}()
+	<-entered
+
+	offlinePath := filepath.Join(root, FileName)
+	if err := os.WriteFile(offlinePath, []byte("<h1>Back soon</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.notify(offlinePath)
+	waitFor(t, "offline", m.Offline)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/default.asp", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "<h1>Back soon</h1>" {
+		t.Fatalf("expected 503 with the offline page, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("expected Cache-Control no-store, got %q", rec.Header().Get("Cache-Control"))
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("expected the stop to wait for the in-flight request, got %s", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if code := <-slowDone; code != http.StatusOK {
+		t.Fatalf("expected the in-flight request to finish with 200, got %d", code)
+	}
+	if event := <-events; event != "stop" {
+		t.Fatalf("expected stop, got %s", event)
+	}
+
+	if err := os.Remove(offlinePath); err != nil {
+		t.Fatal(err)
+	}
+	m.notify(offlinePath)
+	if event := <-events; event != "restart" {
+		t.Fatalf("expected restart, got %s", event)
+	}
+	waitFor(t, "online", func() bool { return !m.Offline() })
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/default.asp", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after the restart, got %d", rec.Code)
+	}
+}
+
+// TestMonitorStartsOfflineAndStopsOnce verifies a root that already holds
+// app_offline.htm and that Stop does not end a stopped application again.
+func TestMonitorStartsOfflineAndStopsOnce(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, FileName), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	starts, stops := 0, 0
+	m := New(root, ConfigFromViper(nil), Hooks{
+		Start: func(bool) { starts++ },
+		Stop:  func() { stops++ },
+	})
+	if err := m.Start(nil); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !m.Offline() || starts != 0 {
+		t.Fatalf("expected the application to stay offline, offline=%v starts=%d", m.Offline(), starts)
+	}
+	rec := httptest.NewRecorder()
+	m.Serve(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.NotFoundHandler())
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.Len() == 0 {
+		t.Fatalf("expected 503 with the default page, got %d %q", rec.Code, rec.Body.String())
+	}
+	m.Stop()
+	if stops != 0 {
+		t.Fatalf("expected no Stop hook for an offline application, got %d", stops)
+	}
+
+	disabled := New(root, Config{}, Hooks{Start: func(bool) { starts++ }, Stop: func() { stops++ }})
+	_ = disabled.Start(nil)
+	disabled.Stop()
+	if starts != 1 || stops != 1 {
+		t.Fatalf("expected a disabled monitor to only run the hooks, starts=%d stops=%d", starts, stops)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonproxy
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// forwardedHop holds the client details reported for one request.
+type forwardedHop struct {
+	client     net.IP
+	clientPort string
+	proto      string
+	host       string
+	hostPort   string
+}
+
+// resolve reads the client details from the Forwarded header, or from the
+// X-Forwarded-* headers when Forwarded is absent. The client is the first address,
+// counted from the right, that is not a trusted proxy.
+func (p *Proxy) resolve(header http.Header) (forwardedHop, bool) {
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		return p.resolveForwarded(values)
+	}
+	return p.resolveXForwarded(header)
+}
+
+// resolveForwarded reads the RFC 7239 Forwarded header.
+func (p *Proxy) resolveForwarded(values []string) (forwardedHop, bool) {
+	var elements []map[string]string
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			pairs := make(map[string]string)
+			for _, pair := range splitQuoted(element, ';') {
+				name, val, found := strings.Cut(pair, "=")
+				if !found {
+					continue
+				}
+				pairs[strings.ToLower(strings.TrimSpace(name))] = unquote(strings.TrimSpace(val))
+			}
+			elements = append(elements, pairs)
+		}
+	}
+	if len(elements) == 0 {
+		return forwardedHop{}, false
+	}
+	index := len(elements) - 1
+	for ; index > 0; index-- {
+		if ip, _ := parseNode(elements[index]["for"]); ip == nil || !p.Trusted(ip) {
+			break
+		}
+	}
+	element := elements[index]
+	hop := forwardedHop{proto: parseProto(element["proto"])}
+	hop.client, hop.clientPort = parseNode(element["for"])
+	hop.host = parseHost(element["host"])
+	return hop, true
+}
+
+// resolveXForwarded reads the X-Forwarded-For, -Proto, -Host and -Port headers.
+// Proto, host and port lists that match the length of X-Forwarded-For are read at
+// the client position, other lists at their last entry, set by the nearest proxy.
+func (p *Proxy) resolveXForwarded(header http.Header) (forwardedHop, bool) {
+	nodes := splitList(header.Values("X-Forwarded-For"))
+	protos := splitList(header.Values("X-Forwarded-Proto"))
+	hosts := splitList(header.Values("X-Forwarded-Host"))
+	ports := splitList(header.Values("X-Forwarded-Port"))
+	if len(nodes) == 0 && len(protos) == 0 && len(hosts) == 0 && len(ports) == 0 {
+		return forwardedHop{}, false
+	}
+	index := len(nodes) - 1
+	for ; index > 0; index-- {
+		if ip, _ := parseNode(nodes[index]); ip == nil || !p.Trusted(ip) {
+			break
+		}
+	}
+	var hop forwardedHop
+	if index >= 0 {
+		hop.client, hop.clientPort = parseNode(nodes[index])
+	}
+	hop.proto = parseProto(pick(protos, index, len(nodes)))
+	hop.host = parseHost(pick(hosts, index, len(nodes)))
+	if port := pick(ports, index, len(nodes)); validPort(port) {
+		hop.hostPort = port
+	}
+	return hop, true
+}
+
+// pick returns the entry of values that belongs to the client at index.
+func pick(values []string, index, nodes int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	if len(values) == nodes && index >= 0 {
+		return values[index]
+	}
+	return values[len(values)-1]
+}
+
+// requestHost combines the forwarded host and port into an HTTP Host value.
+// currentHost supplies the host name when the proxy only reported a port.
+func (hop forwardedHop) requestHost(currentHost string) string {
+	host := hop.host
+	if host == "" {
+		if hop.hostPort == "" {
+			return ""
+		}
+		host = currentHost
+	}
+	if hop.hostPort == "" {
+		return host
+	}
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if (hop.proto == "https" && hop.hostPort == "443") || (hop.proto != "https" && hop.hostPort == "80") {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+	return net.JoinHostPort(host, hop.hostPort)
+}
+
+// parseNode reads an address such as "192.0.2.1", "192.0.2.1:4711" or
+// "[2001:db8::1]:4711". Obfuscated and "unknown" nodes return a nil IP.
+func parseNode(value string) (net.IP, string) {
+	value = unquote(strings.TrimSpace(value))
+	if value == "" {
+		return nil, ""
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		return ip, ""
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")), ""
+	}
+	if !validPort(port) {
+		port = ""
+	}
+	return net.ParseIP(host), port
+}
+
+// parseProto accepts only the http and https schemes.
+func parseProto(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "http":
+		return "http"
+	case "https":
+		return "https"
+	}
+	return ""
+}
+
+// parseHost accepts host names, IP addresses and an optional port, and rejects
+// values that could not appear in a Host header.
+func parseHost(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > 255 {
+		return ""
+	}
+	for _, c := range value {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '-', c == '_', c == ':', c == '[', c == ']':
+		default:
+			return ""
+		}
+	}
+	return value
+}
+
+// validPort reports whether value is a TCP port number.
+func validPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// splitList splits comma-separated header values into trimmed, non-empty items.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// splitQuoted splits value at sep, ignoring separators inside quoted strings.
+func splitQuoted(value string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				if part := strings.TrimSpace(value[start:i]); part != "" {
+					parts = append(parts, part)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if start < len(value) {
+		if part := strings.TrimSpace(value[start:]); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// unquote removes the quotes and escapes of a quoted-string value.
+func unquote(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	value = value[1 : len(value)-1]
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
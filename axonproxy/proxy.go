@@ -0,0 +1,229 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonproxy restores the client address, scheme and host of requests that
+// reach AxonASP through trusted reverse proxies and load balancers. It reads the
+// Forwarded and X-Forwarded-* request headers and the HAProxy PROXY protocol.
+package axonproxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultProxyProtocolTimeout bounds the wait for the PROXY protocol header.
+const DefaultProxyProtocolTimeout = 5 * time.Second
+
+// Config controls the trusted proxy support.
+type Config struct {
+	// HeadersEnabled rewrites requests from trusted proxies using the
+	// Forwarded and X-Forwarded-* headers.
+	HeadersEnabled bool
+	// TrustedProxies lists the addresses and CIDR ranges of the proxies.
+	TrustedProxies []string
+	// ProxyProtocolEnabled reads a PROXY protocol v1 or v2 header from every
+	// connection opened by a trusted proxy.
+	ProxyProtocolEnabled bool
+	ProxyProtocolTimeout time.Duration
+}
+
+// ConfigFromViper reads the [reverse_proxy] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		TrustedProxies:       []string{"127.0.0.1", "::1"},
+		ProxyProtocolTimeout: DefaultProxyProtocolTimeout,
+	}
+	if v == nil {
+		return cfg
+	}
+	cfg.HeadersEnabled = v.GetBool("reverse_proxy.enable_proxy_headers")
+	cfg.ProxyProtocolEnabled = v.GetBool("reverse_proxy.enable_proxy_protocol")
+	if v.IsSet("reverse_proxy.trusted_proxies") {
+		cfg.TrustedProxies = v.GetStringSlice("reverse_proxy.trusted_proxies")
+	}
+	if seconds := v.GetInt("reverse_proxy.proxy_protocol_timeout_seconds"); seconds > 0 {
+		cfg.ProxyProtocolTimeout = time.Duration(seconds) * time.Second
+	}
+	return cfg
+}
+
+// Proxy applies the trusted proxy rules of one host.
+// A nil *Proxy is valid and leaves requests and connections untouched.
+type Proxy struct {
+	headers       bool
+	proxyProtocol bool
+	timeout       time.Duration
+	trusted       []*net.IPNet
+}
+
+// New builds the trusted proxy rules. It returns nil when both the headers and
+// the PROXY protocol are disabled.
+func New(cfg Config) (*Proxy, error) {
+	if !cfg.HeadersEnabled && !cfg.ProxyProtocolEnabled {
+		return nil, nil
+	}
+	trusted, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	timeout := cfg.ProxyProtocolTimeout
+	if timeout <= 0 {
+		timeout = DefaultProxyProtocolTimeout
+	}
+	return &Proxy{
+		headers:       cfg.HeadersEnabled,
+		proxyProtocol: cfg.ProxyProtocolEnabled,
+		timeout:       timeout,
+		trusted:       trusted,
+	}, nil
+}
+
+// parseTrustedProxies converts addresses and CIDR ranges into networks.
+func parseTrustedProxies(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, errors.New("invalid trusted proxy address: " + value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.New("invalid trusted proxy range: " + value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Trusted reports whether ip belongs to a trusted proxy.
+func (p *Proxy) Trusted(ip net.IP) bool {
+	if p == nil || ip == nil {
+		return false
+	}
+	for _, network := range p.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedAddr reports whether the host part of a net.Addr string is a trusted proxy.
+func (p *Proxy) trustedAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return p.Trusted(net.ParseIP(host))
+}
+
+// forwardedKey stores the Forwarded value of a rewritten request in its context.
+type forwardedKey struct{}
+
+// Forwarded describes what a trusted proxy reported about the original request.
+type Forwarded struct {
+	// Peer is the address of the proxy that sent the request.
+	Peer string
+	// Proto is "http" or "https", or empty when the proxy did not report it.
+	Proto string
+}
+
+// FromRequest returns the proxy details of a request rewritten by Handler.
+func FromRequest(r *http.Request) (Forwarded, bool) {
+	if r == nil {
+		return Forwarded{}, false
+	}
+	forwarded, ok := r.Context().Value(forwardedKey{}).(Forwarded)
+	return forwarded, ok
+}
+
+// IsHTTPS reports whether the client reached the server over HTTPS, either
+// directly or through a trusted proxy that terminated TLS.
+func IsHTTPS(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	if r.TLS != nil {
+		return true
+	}
+	forwarded, ok := FromRequest(r)
+	return ok && forwarded.Proto == "https"
+}
+
+// Handler rewrites RemoteAddr and Host of requests sent by trusted proxies, so
+// every later handler, the access log and the ASP server variables see the client.
+func (p *Proxy) Handler(next http.Handler) http.Handler {
+	if p == nil || !p.headers {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rewritten, ok := p.rewrite(r); ok {
+			r = rewritten
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rewrite returns a copy of r carrying the client details reported by the proxies.
+func (p *Proxy) rewrite(r *http.Request) (*http.Request, bool) {
+	if !p.trustedAddr(r.RemoteAddr) {
+		return nil, false
+	}
+	hop, ok := p.resolve(r.Header)
+	if !ok {
+		return nil, false
+	}
+	rewritten := r.WithContext(context.WithValue(r.Context(), forwardedKey{}, Forwarded{Peer: r.RemoteAddr, Proto: hop.proto}))
+	if hop.client != nil {
+		port := hop.clientPort
+		if port == "" {
+			port = "0"
+		}
+		rewritten.RemoteAddr = net.JoinHostPort(hop.client.String(), port)
+	}
+	if host := hop.requestHost(r.Host); host != "" {
+		rewritten.Host = host
+		if r.URL != nil && r.URL.Host != "" {
+			target := *r.URL
+			target.Host = host
+			rewritten.URL = &target
+		}
+	}
+	return rewritten, true
+}
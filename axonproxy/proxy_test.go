@@ -0,0 +1,161 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonproxy
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestProxy builds a Proxy that trusts the 10.0.0.0/8 range and the loopback address.
+func newTestProxy(t *testing.T, headers, proxyProtocol bool) *Proxy {
+	t.Helper()
+	p, err := New(Config{
+		HeadersEnabled:       headers,
+		ProxyProtocolEnabled: proxyProtocol,
+		TrustedProxies:       []string{"10.0.0.0/8", "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("create proxy: %v", err)
+	}
+	return p
+}
+
+// serve runs one request through the proxy handler and returns what the next handler saw.
+func serve(p *Proxy, req *http.Request) *http.Request {
+	var seen *http.Request
+	p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	return seen
+}
+
+// TestHandlerRewritesTrustedRequests verifies the X-Forwarded-* and Forwarded headers
+// are applied only for trusted peers, and that spoofed addresses are skipped.
+func TestHandlerRewritesTrustedRequests(t *testing.T) {
+	p := newTestProxy(t, true, false)
+
+	req := httptest.NewRequest(http.MethodGet, "http://backend:8801/default.asp", nil)
+	req.RemoteAddr = "10.0.0.5:40000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.9, 203.0.113.7, 10.0.0.2")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "www.example.com")
+	req.Header.Set("X-Forwarded-Port", "8443")
+	seen := serve(p, req)
+	if seen.RemoteAddr != "203.0.113.7:0" {
+		t.Fatalf("expected the first untrusted address from the right, got %q", seen.RemoteAddr)
+	}
+	if seen.Host != "www.example.com:8443" || !IsHTTPS(seen) {
+		t.Fatalf("expected https://www.example.com:8443, got host %q https %v", seen.Host, IsHTTPS(seen))
+	}
+	if forwarded, ok := FromRequest(seen); !ok || forwarded.Peer != "10.0.0.5:40000" {
+		t.Fatalf("expected the proxy peer to be kept, got %+v", forwarded)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://backend/", nil)
+	req.RemoteAddr = "10.0.0.5:40000"
+	req.Header.Set("Forwarded", `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711";proto=https;host="shop.example.com"`)
+	seen = serve(p, req)
+	if seen.RemoteAddr != "[2001:db8:cafe::17]:4711" || seen.Host != "shop.example.com" || !IsHTTPS(seen) {
+		t.Fatalf("unexpected Forwarded result: addr %q host %q https %v", seen.RemoteAddr, seen.Host, IsHTTPS(seen))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://backend/", nil)
+	req.RemoteAddr = "192.0.2.1:5000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	seen = serve(p, req)
+	if seen.RemoteAddr != "192.0.2.1:5000" || seen.Host != "backend" || IsHTTPS(seen) {
+		t.Fatalf("untrusted peers must not rewrite the request, got addr %q host %q", seen.RemoteAddr, seen.Host)
+	}
+}
+
+// TestListenerReadsProxyProtocol verifies v1 and v2 headers from trusted peers.
+func TestListenerReadsProxyProtocol(t *testing.T) {
+	p := newTestProxy(t, false, true)
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	listener := p.Listener(inner)
+	defer listener.Close()
+
+	v2 := append([]byte(nil), proxyV2Signature...)
+	v2 = append(v2, 0x21, 0x11, 0, 12+3)
+	v2 = append(v2, 198, 51, 100, 4, 192, 0, 2, 10)
+	v2 = binary.BigEndian.AppendUint16(v2, 51000)
+	v2 = binary.BigEndian.AppendUint16(v2, 443)
+	v2 = append(v2, 0x04, 0x00, 0x00)
+
+	cases := []struct {
+		name   string
+		header []byte
+		remote string
+	}{
+		{"v1", []byte("PROXY TCP4 203.0.113.7 192.0.2.10 4711 80\r\n"), "203.0.113.7:4711"},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\n"), ""},
+		{"v2", v2, "198.51.100.4:51000"},
+	}
+	for _, tc := range cases {
+		client, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatalf("%s: dial: %v", tc.name, err)
+		}
+		_, _ = client.Write(append(tc.header, "hello"...))
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("%s: accept: %v", tc.name, err)
+		}
+		remote := tc.remote
+		if remote == "" {
+			remote = client.LocalAddr().String()
+		}
+		if got := conn.RemoteAddr().String(); got != remote {
+			t.Fatalf("%s: expected remote address %q, got %q", tc.name, remote, got)
+		}
+		body := make([]byte, 5)
+		if _, err := io.ReadFull(conn, body); err != nil || string(body) != "hello" {
+			t.Fatalf("%s: expected the data after the header, got %q (%v)", tc.name, body, err)
+		}
+		conn.Close()
+		client.Close()
+	}
+
+	client, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	_, _ = client.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != ErrProxyProtocolHeader {
+		t.Fatalf("expected a trusted peer without header to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyV2Signature starts every PROXY protocol v2 header.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	// proxyV1MaxLength is the longest valid PROXY protocol v1 header.
+	proxyV1MaxLength = 107
+	// proxyV2HeaderLength is the fixed part of a PROXY protocol v2 header.
+	proxyV2HeaderLength = 16
+)
+
+// ErrProxyProtocolHeader reports a missing or malformed PROXY protocol header.
+var ErrProxyProtocolHeader = errors.New("invalid PROXY protocol header")
+
+// Listener returns l unchanged, or wrapped so connections from trusted proxies
+// must start with a PROXY protocol header that names the client address. Other
+// connections are served as they are.
+func (p *Proxy) Listener(l net.Listener) net.Listener {
+	if p == nil || !p.proxyProtocol {
+		return l
+	}
+	return &proxyListener{Listener: l, proxy: p}
+}
+
+// proxyListener wraps accepted connections in proxyConn.
+type proxyListener struct {
+	net.Listener
+	proxy *Proxy
+}
+
+// Accept waits for the next connection. The header is read later, by the
+// connection goroutine, so a slow proxy cannot stall the accept loop.
+func (l *proxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{Conn: conn, proxy: l.proxy}, nil
+}
+
+// proxyConn reads the PROXY protocol header before the first Read or address lookup.
+type proxyConn struct {
+	net.Conn
+	proxy  *Proxy
+	once   sync.Once
+	reader *bufio.Reader
+	remote net.Addr
+	local  net.Addr
+	err    error
+}
+
+// init reads the header once.
+func (c *proxyConn) init() {
+	c.once.Do(func() {
+		if !c.proxy.trustedAddr(c.Conn.RemoteAddr().String()) {
+			return
+		}
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.proxy.timeout))
+		c.reader = bufio.NewReader(c.Conn)
+		c.remote, c.local, c.err = readProxyHeader(c.reader)
+		_ = c.Conn.SetReadDeadline(time.Time{})
+		if c.err != nil {
+			_ = c.Conn.Close()
+		}
+	})
+}
+
+// Read reads the connection data that follows the header.
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.reader != nil && c.reader.Buffered() > 0 {
+		return c.reader.Read(b)
+	}
+	return c.Conn.Read(b)
+}
+
+// ReadFrom keeps the sendfile path of the wrapped TCP connection.
+func (c *proxyConn) ReadFrom(r io.Reader) (int64, error) {
+	if rf, ok := c.Conn.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(c.Conn, r)
+}
+
+// RemoteAddr returns the client address named by the header.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to, as named by the header.
+func (c *proxyConn) LocalAddr() net.Addr {
+	c.init()
+	if c.local != nil {
+		return c.local
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readProxyHeader reads a v1 or v2 header. Nil addresses mean the proxy sent a
+// LOCAL or UNKNOWN header, such as a health check, and the real addresses apply.
+func readProxyHeader(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch first[0] {
+	case 'P':
+		return readProxyV1(r)
+	case '\r':
+		return readProxyV2(r)
+	}
+	return nil, nil, ErrProxyProtocolHeader
+}
+
+// readProxyV1 reads a header such as "PROXY TCP4 192.0.2.1 198.51.100.1 4711 80\r\n".
+func readProxyV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= proxyV1MaxLength {
+			return nil, nil, ErrProxyProtocolHeader
+		}
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		line = append(line, c)
+	}
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, nil, ErrProxyProtocolHeader
+	}
+	if fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, ErrProxyProtocolHeader
+	}
+	source, err := proxyV1Addr(fields[2], fields[4], fields[1] == "TCP4")
+	if err != nil {
+		return nil, nil, err
+	}
+	destination, err := proxyV1Addr(fields[3], fields[5], fields[1] == "TCP4")
+	if err != nil {
+		return nil, nil, err
+	}
+	return source, destination, nil
+}
+
+// proxyV1Addr parses one address and port of a v1 header.
+func proxyV1Addr(host, port string, ipv4 bool) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil || (ip.To4() != nil) != ipv4 {
+		return nil, ErrProxyProtocolHeader
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 0 || number > 65535 {
+		return nil, ErrProxyProtocolHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: number}, nil
+}
+
+// readProxyV2 reads a binary header and skips its TLV extensions.
+func readProxyV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	header := make([]byte, proxyV2HeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(header[:12], proxyV2Signature) || header[12]>>4 != 2 {
+		return nil, nil, ErrProxyProtocolHeader
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, err
+	}
+	switch header[12] & 0x0f {
+	case 0x0:
+		// LOCAL: the proxy opened the connection for itself.
+		return nil, nil, nil
+	case 0x1:
+	default:
+		return nil, nil, ErrProxyProtocolHeader
+	}
+	switch header[13] >> 4 {
+	case 0x1:
+		if len(payload) < 12 {
+			return nil, nil, ErrProxyProtocolHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))},
+			&net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:12]))}, nil
+	case 0x2:
+		if len(payload) < 36 {
+			return nil, nil, ErrProxyProtocolHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))},
+			&net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:36]))}, nil
+	}
+	// AF_UNSPEC and AF_UNIX carry no TCP client address.
+	return nil, nil, nil
+}
@@ -0,0 +1,282 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+// Package axonupgrade lets axonasp-http replace its binary without dropping
+// connections. The running process hands its listening sockets to a new process,
+// waits until that process is ready and then drains. The package also adopts the
+// sockets of systemd socket activation and reports the service state through sd_notify.
+package axonupgrade
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// envListenFDs is the number of listeners handed over by an upgrade or by the service wrapper.
+	envListenFDs = "AXONASP_LISTEN_FDS"
+	// envReadyFD is the pipe the new process writes to when it is ready.
+	envReadyFD = "AXONASP_READY_FD"
+	// listenFDsStart is the first inherited descriptor, as in systemd socket activation.
+	listenFDsStart = 3
+)
+
+// ErrUnsupported is returned by Upgrade on platforms without descriptor passing.
+var ErrUnsupported = errors.New("graceful upgrade is not supported on this platform")
+
+// Config controls graceful upgrades and shutdown.
+type Config struct {
+	Enabled bool
+	// ReadyTimeout bounds the wait for the new process to report that it is ready.
+	ReadyTimeout time.Duration
+	// ShutdownTimeout bounds the drain of in-flight requests before the process exits.
+	ShutdownTimeout time.Duration
+}
+
+// ConfigFromViper reads the upgrade keys of the [server] section.
+func ConfigFromViper(v *viper.Viper) Config {
+	cfg := Config{
+		Enabled:         true,
+		ReadyTimeout:    30 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+	}
+	if v == nil {
+		return cfg
+	}
+	if v.IsSet("server.enable_graceful_upgrade") {
+		cfg.Enabled = v.GetBool("server.enable_graceful_upgrade")
+	}
+	if v.IsSet("server.upgrade_ready_timeout_seconds") {
+		cfg.ReadyTimeout = time.Duration(max(v.GetInt("server.upgrade_ready_timeout_seconds"), 1)) * time.Second
+	}
+	if v.IsSet("server.shutdown_timeout_seconds") {
+		cfg.ShutdownTimeout = time.Duration(max(v.GetInt("server.shutdown_timeout_seconds"), 0)) * time.Second
+	}
+	return cfg
+}
+
+// Upgrader owns the listening sockets of the process.
+type Upgrader struct {
+	cfg Config
+
+	mu        sync.Mutex
+	inherited []net.Listener
+	active    []net.Listener
+	readyFile *os.File
+	upgraded  bool
+	ready     bool
+	// handedOver is set when Upgrade made another process the main process of the service.
+	handedOver bool
+	watchdog   chan struct{}
+}
+
+// New adopts the listeners passed by a previous process or by systemd.
+func New(cfg Config) *Upgrader {
+	u := &Upgrader{cfg: cfg}
+	u.inherited, u.readyFile, u.upgraded = inheritListeners()
+	return u
+}
+
+// Upgraded reports whether the process was started by a graceful upgrade.
+func (u *Upgrader) Upgraded() bool {
+	return u != nil && u.upgraded
+}
+
+// Inherited returns the number of adopted listeners still waiting for a Listen call.
+func (u *Upgrader) Inherited() int {
+	if u == nil {
+		return 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.inherited)
+}
+
+// Listen returns the adopted listener bound to addr, or opens a new one. The
+// returned listener is handed to the next process by Upgrade.
+func (u *Upgrader) Listen(network, addr string) (net.Listener, error) {
+	if u == nil {
+		return net.Listen(network, addr)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for i, listener := range u.inherited {
+		if listenerMatches(listener, network, addr) {
+			u.inherited = append(u.inherited[:i], u.inherited[i+1:]...)
+			u.active = append(u.active, listener)
+			return listener, nil
+		}
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	u.active = append(u.active, listener)
+	return listener, nil
+}
+
+// listenerMatches reports whether listener is bound to the port of addr and to its
+// address, unless addr leaves the address unspecified.
+func listenerMatches(listener net.Listener, network, addr string) bool {
+	bound, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	wanted, err := net.ResolveTCPAddr(network, addr)
+	if err != nil || wanted.Port != bound.Port {
+		return false
+	}
+	return wanted.IP == nil || wanted.IP.IsUnspecified() || wanted.IP.Equal(bound.IP)
+}
+
+// Ready closes the adopted listeners nobody claimed, tells the previous process
+// and the service manager that the server accepts requests, and starts the watchdog.
+func (u *Upgrader) Ready() {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.ready {
+		return
+	}
+	u.ready = true
+	for _, listener := range u.inherited {
+		_ = listener.Close()
+	}
+	u.inherited = nil
+	if u.readyFile != nil {
+		_, _ = u.readyFile.WriteString("READY\n")
+		_ = u.readyFile.Close()
+		u.readyFile = nil
+	}
+	state := "READY=1"
+	if u.upgraded {
+		state += "\nMAINPID=" + strconv.Itoa(os.Getpid())
+	}
+	_ = Notify(state)
+	if interval := watchdogInterval(u.upgraded); interval > 0 {
+		u.watchdog = make(chan struct{})
+		go runWatchdog(interval, u.watchdog)
+	}
+}
+
+// Stopping tells the service manager that the process is shutting down and stops the watchdog.
+// After a successful Upgrade the service keeps running in the new process, so
+// only the watchdog is stopped.
+func (u *Upgrader) Stopping() {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.watchdog != nil {
+		close(u.watchdog)
+		u.watchdog = nil
+	}
+	if u.handedOver {
+		return
+	}
+	_ = Notify("STOPPING=1")
+}
+
+// Notify sends state to the service manager named by NOTIFY_SOCKET, using the
+// sd_notify protocol. It does nothing when the variable is not set.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("connect to NOTIFY_SOCKET: %w", err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns how often WATCHDOG=1 must be sent, half of WATCHDOG_USEC.
+// A WATCHDOG_PID of another process is ignored, except after an upgrade, because
+// the new process takes over as the main process of the service.
+func watchdogInterval(upgraded bool) time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && !upgraded && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// runWatchdog pings the service manager until stop is closed.
+func runWatchdog(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			_ = Notify("WATCHDOG=1")
+		}
+	}
+}
+
+// PassListeners hands files to cmd as listening sockets. The child adopts them
+// with New, like the sockets of an upgrade, but it does not report readiness through a pipe.
+func PassListeners(cmd *exec.Cmd, files []*os.File) {
+	if len(files) == 0 {
+		return
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.ExtraFiles = append(files, cmd.ExtraFiles...)
+	cmd.Env = append(withoutEnv(env, envListenFDs, envReadyFD, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"),
+		envListenFDs+"="+strconv.Itoa(len(files)))
+}
+
+// withoutEnv returns env without the named variables.
+func withoutEnv(env []string, names ...string) []string {
+	filtered := make([]string, 0, len(env))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if !slices.Contains(names, name) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,100 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonupgrade
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestListenReusesInheritedListener verifies address matching of adopted listeners.
+func TestListenReusesInheritedListener(t *testing.T) {
+	inherited, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(inherited.Addr().(*net.TCPAddr).Port)
+	u := &Upgrader{cfg: ConfigFromViper(nil), inherited: []net.Listener{inherited}}
+
+	if listenerMatches(inherited, "tcp", "127.0.0.2:"+port) {
+		t.Fatal("expected a different address not to match")
+	}
+	listener, err := u.Listen("tcp", ":"+port)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	if listener != inherited || u.Inherited() != 0 || len(u.active) != 1 {
+		t.Fatalf("expected the inherited listener to be reused, got %v", listener.Addr())
+	}
+
+	other, err := u.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer other.Close()
+	if other == inherited || len(u.active) != 2 {
+		t.Fatal("expected a new listener for another address")
+	}
+	u.Ready()
+	_ = inherited.Close()
+}
+
+// TestNotifyAndWatchdogInterval verifies the sd_notify datagram and WATCHDOG_USEC parsing.
+func TestNotifyAndWatchdogInterval(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unixgram sockets are not available on Windows")
+	}
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+
+	if err := Notify("READY=1"); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Fatalf("expected READY=1, got %q (%v)", buf[:n], err)
+	}
+
+	t.Setenv("WATCHDOG_USEC", "4000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if interval := watchdogInterval(false); interval != 2*time.Second {
+		t.Fatalf("expected a 2s watchdog interval, got %s", interval)
+	}
+	t.Setenv("WATCHDOG_PID", "1")
+	if interval := watchdogInterval(false); interval != 0 {
+		t.Fatalf("expected no watchdog for another process, got %s", interval)
+	}
+	if interval := watchdogInterval(true); interval != 2*time.Second {
+		t.Fatalf("expected an upgraded process to take over the watchdog, got %s", interval)
+	}
+}
@@ -0,0 +1,180 @@
+//go:build !windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonupgrade
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Signals returns the signals that start a graceful upgrade.
+func Signals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR2, syscall.SIGHUP}
+}
+
+// IsUpgradeSignal reports whether sig starts a graceful upgrade.
+func IsUpgradeSignal(sig os.Signal) bool {
+	return sig == syscall.SIGUSR2 || sig == syscall.SIGHUP
+}
+
+// ActivationFiles returns the sockets passed to this process by systemd socket
+// activation and removes the LISTEN_ variables.
+func ActivationFiles() []*os.File {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+	count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(name)
+	}
+	files := make([]*os.File, 0, max(count, 0))
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		syscall.CloseOnExec(fd)
+		files = append(files, os.NewFile(uintptr(fd), "axonasp-listener-"+strconv.Itoa(fd)))
+	}
+	return files
+}
+
+// inheritListeners adopts the listeners of a previous process, or of systemd when
+// LISTEN_PID names this process. The variables are removed so that processes
+// started by ASP pages do not inherit them.
+func inheritListeners() ([]net.Listener, *os.File, bool) {
+	count, upgraded := 0, false
+	var readyFile *os.File
+	if value := os.Getenv(envListenFDs); value != "" {
+		count, _ = strconv.Atoi(value)
+		if fd, err := strconv.Atoi(os.Getenv(envReadyFD)); err == nil && fd >= listenFDsStart {
+			syscall.CloseOnExec(fd)
+			readyFile = os.NewFile(uintptr(fd), "axonasp-ready")
+			upgraded = true
+		}
+	} else if os.Getenv("LISTEN_PID") == strconv.Itoa(os.Getpid()) {
+		count, _ = strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	}
+	for _, name := range []string{envListenFDs, envReadyFD, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(name)
+	}
+
+	listeners := make([]net.Listener, 0, max(count, 0))
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		syscall.CloseOnExec(fd)
+		file := os.NewFile(uintptr(fd), "axonasp-listener-"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, readyFile, upgraded
+}
+
+// filer is implemented by listeners backed by a socket descriptor.
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Upgrade starts the current executable again with the listening sockets and waits
+// until it calls Ready. It returns the process ID of the new process. The caller
+// keeps serving until it shuts down, so no connection is refused in between.
+func (u *Upgrader) Upgrade() (int, error) {
+	if u == nil || !u.cfg.Enabled {
+		return 0, errors.New("graceful upgrade is disabled")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	files := make([]*os.File, 0, len(u.active)+1)
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	for _, listener := range u.active {
+		socket, ok := listener.(filer)
+		if !ok {
+			return 0, fmt.Errorf("listener %s cannot be handed over", listener.Addr())
+		}
+		file, err := socket.File()
+		if err != nil {
+			return 0, fmt.Errorf("duplicate listener %s: %w", listener.Addr(), err)
+		}
+		files = append(files, file)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("resolve executable: %w", err)
+	}
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer readyReader.Close()
+	listenerCount := len(files)
+	files = append(files, readyWriter)
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(withoutEnv(os.Environ(), envListenFDs, envReadyFD, "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"),
+		envListenFDs+"="+strconv.Itoa(listenerCount),
+		envReadyFD+"="+strconv.Itoa(listenFDsStart+listenerCount),
+	)
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("start %s: %w", executable, err)
+	}
+	_ = readyWriter.Close()
+	files = files[:listenerCount]
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	ready := make(chan bool, 1)
+	go func() {
+		buf := make([]byte, 16)
+		n, _ := readyReader.Read(buf)
+		ready <- n > 0
+	}()
+
+	timer := time.NewTimer(u.cfg.ReadyTimeout)
+	defer timer.Stop()
+	select {
+	case ok := <-ready:
+		if ok {
+			_ = Notify("MAINPID=" + strconv.Itoa(cmd.Process.Pid))
+			u.handedOver = true
+			return cmd.Process.Pid, nil
+		}
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("the new process exited before it was ready: %v", <-exited)
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("the new process was not ready after %s", u.cfg.ReadyTimeout)
+	}
+}
@@ -0,0 +1,124 @@
+//go:build !windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonupgrade
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envTestAddr passes the listener address to the process started by the upgrade test.
+const envTestAddr = "AXONASP_UPGRADE_TEST_ADDR"
+
+// TestUpgradeHandsOverListener starts the test binary again through Upgrade and
+// verifies that the new process accepts connections on the same socket, and
+// that the old process does not report STOPPING=1 for the service it handed over.
+func TestUpgradeHandsOverListener(t *testing.T) {
+	u := New(Config{Enabled: true, ReadyTimeout: 20 * time.Second})
+	if u.Upgraded() {
+		// This is the new process: answer one connection and exit.
+		listener, err := u.Listen("tcp", os.Getenv(envTestAddr))
+		if err != nil || u.Inherited() != 0 {
+			t.Fatalf("expected the inherited listener, got %v", err)
+		}
+		u.Ready()
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = conn.Write([]byte("new"))
+		_ = conn.Close()
+		return
+	}
+	if os.Getenv(envTestAddr) != "" {
+		t.Skip("nested upgrade test process")
+	}
+
+	listener, err := u.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envTestAddr, listener.Addr().String())
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	notify, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notify.Close()
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	pid, err := u.Upgrade()
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("Upgrade failed: %v", err)
+	}
+	if pid == os.Getpid() {
+		t.Fatal("expected a new process")
+	}
+	u.Stopping()
+	_ = listener.Close()
+
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected the new process to accept connections: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	reply, _ := io.ReadAll(conn)
+	if string(reply) != "new" {
+		t.Fatalf("expected the new process to answer, got %q", reply)
+	}
+
+	var states []string
+	buf := make([]byte, 256)
+	for {
+		_ = notify.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		n, err := notify.Read(buf)
+		if err != nil {
+			break
+		}
+		states = append(states, string(buf[:n]))
+	}
+	if !slices.Contains(states, "MAINPID="+strconv.Itoa(pid)) {
+		t.Fatalf("expected MAINPID of the new process, got %q", states)
+	}
+	for _, state := range states {
+		if strings.Contains(state, "STOPPING=1") {
+			t.Fatalf("expected no STOPPING=1 after the handoff, got %q", states)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonupgrade
+
+import (
+	"net"
+	"os"
+)
+
+// Signals returns the signals that start a graceful upgrade. Windows has none.
+func Signals() []os.Signal {
+	return nil
+}
+
+// IsUpgradeSignal reports whether sig starts a graceful upgrade.
+func IsUpgradeSignal(os.Signal) bool {
+	return false
+}
+
+// ActivationFiles returns no sockets on Windows.
+func ActivationFiles() []*os.File {
+	return nil
+}
+
+// inheritListeners adopts no listeners on Windows.
+func inheritListeners() ([]net.Listener, *os.File, bool) {
+	return nil, nil, false
+}
+
+// Upgrade is not supported on Windows.
+func (u *Upgrader) Upgrade() (int, error) {
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,285 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonvm
+
+import (
+	"fmt"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// encodeApplicationObject stores one object by value. Dictionaries,
+// Collections, plain JScript objects and disconnected Recordsets are copied
+// with their contents; any other object is rejected because its ID means
+// nothing outside the VM that created it.
+func (vm *VM) encodeApplicationObject(v Value, visiting map[int64]struct{}) (asp.ApplicationValue, error) {
+	if _, busy := visiting[v.Num]; busy {
+		return asp.ApplicationValue{}, fmt.Errorf("Cannot store %s in Session or Application: it contains a circular reference", vm.applicationObjectTypeName(v))
+	}
+	if visiting == nil {
+		visiting = make(map[int64]struct{}, 4)
+	}
+	visiting[v.Num] = struct{}{}
+	defer delete(visiting, v.Num)
+
+	switch v.Type {
+	case VTNativeObject:
+		if dict, ok := vm.dictionaryItems[v.Num]; ok && dict != nil {
+			keys, err := vm.encodeApplicationValues(dict.keys, visiting)
+			if err != nil {
+				return asp.ApplicationValue{}, err
+			}
+			items, err := vm.encodeApplicationValues(dict.values, visiting)
+			if err != nil {
+				return asp.ApplicationValue{}, err
+			}
+			return asp.NewApplicationDictionary(dict.compareMode, keys, items), nil
+		}
+		if collection, ok := vm.collectionItems[v.Num]; ok && collection != nil {
+			items, err := vm.encodeApplicationValues(collection.items, visiting)
+			if err != nil {
+				return asp.ApplicationValue{}, err
+			}
+			return asp.NewApplicationCollection(items), nil
+		}
+		if stored, isRecordset, err := vm.adodbRecordsetToApplicationValue(v.Num, visiting); isRecordset {
+			return stored, err
+		}
+	case VTJSObject:
+		if vm.jsIsPlainObject(v) {
+			obj := vm.jsObjectItems[v.Num]
+			names := vm.jsObjectOwnEnumerableKeys(v.Num)
+			keys := make([]asp.ApplicationValue, 0, len(names))
+			values := make([]asp.ApplicationValue, 0, len(names))
+			for _, name := range names {
+				item := obj[name]
+				if item.Type == VTJSUndefined {
+					continue
+				}
+				value, err := vm.encodeApplicationValue(item, visiting)
+				if err != nil {
+					return asp.ApplicationValue{}, err
+				}
+				keys = append(keys, asp.NewApplicationString(name))
+				values = append(values, value)
+			}
+			return asp.NewApplicationJSPlainObject(keys, values), nil
+		}
+	}
+	return asp.ApplicationValue{}, vm.applicationObjectError(v)
+}
+
+// encodeApplicationValues converts a list of VM values.
+func (vm *VM) encodeApplicationValues(values []Value, visiting map[int64]struct{}) ([]asp.ApplicationValue, error) {
+	encoded := make([]asp.ApplicationValue, len(values))
+	for i := range values {
+		value, err := vm.encodeApplicationValue(values[i], visiting)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = value
+	}
+	return encoded, nil
+}
+
+// applicationObjectError explains why v cannot be stored in Session or Application.
+func (vm *VM) applicationObjectError(v Value) error {
+	if v.Type == VTNativeObject {
+		if _, ok := vm.adodbRecordsetItems[v.Num]; ok {
+			return fmt.Errorf("Cannot store a connected Recordset in Session or Application. Set its ActiveConnection to Nothing first")
+		}
+	}
+	return fmt.Errorf("Cannot store %s in Session or Application. Only Scripting.Dictionary, Collection, arrays, plain JScript objects and disconnected Recordset objects can be stored", vm.applicationObjectTypeName(v))
+}
+
+// applicationObjectTypeName names v for Session and Application error messages.
+func (vm *VM) applicationObjectTypeName(v Value) string {
+	switch v.Type {
+	case VTJSObject:
+		return "a JScript " + vm.jsObjectToStringTag(v)
+	case VTJSFunction:
+		return "a JScript function"
+	case VTJSPromise:
+		return "a JScript Promise"
+	case VTJSGenerator:
+		return "a JScript generator"
+	case VTJSProxy:
+		return "a JScript Proxy"
+	}
+	name, _ := vbsTypeNameVM(vm, []Value{v})
+	return "an object of type " + name.String()
+}
+
+// jsIsPlainObject reports whether v is an ordinary JScript object created by an
+// object literal, new Object() or JSON.parse.
+func (vm *VM) jsIsPlainObject(v Value) bool {
+	obj, ok := vm.jsObjectItems[v.Num]
+	if !ok || obj == nil {
+		return false
+	}
+	if vm.jsObjectStringProperty(v, "__js_type") != "" || vm.jsObjectStringProperty(v, "__js_ctor") != "" {
+		return false
+	}
+	proto, hasProto := obj["__js_proto"]
+	if !hasProto {
+		return true
+	}
+	objectProto := vm.jsGetIntrinsicPrototype("Object")
+	return proto.Type == VTJSObject && objectProto.Type == VTJSObject && proto.Num == objectProto.Num
+}
+
+// decodeApplicationObject creates a new VM object from a stored by-value object.
+func (vm *VM) decodeApplicationObject(v asp.ApplicationValue) Value {
+	switch v.Type {
+	case asp.ApplicationValueDictionary:
+		dictVal := vm.newDictionaryObject()
+		dict := vm.dictionaryItems[dictVal.Num]
+		dict.compareMode = int(v.Num)
+		for i := range v.Keys {
+			if i < len(v.Arr) {
+				dict.addEntry(vm.applicationValueToValue(v.Keys[i]), vm.applicationValueToValue(v.Arr[i]))
+			}
+		}
+		return dictVal
+	case asp.ApplicationValueCollection:
+		collectionVal := vm.newCollectionObject()
+		collection := vm.collectionItems[collectionVal.Num]
+		for i := range v.Arr {
+			collection.items = append(collection.items, vm.applicationValueToValue(v.Arr[i]))
+		}
+		return collectionVal
+	case asp.ApplicationValueJSPlainObject:
+		objVal := vm.jsConstructObject(nil)
+		for i := range v.Keys {
+			if i < len(v.Arr) {
+				vm.jsMemberSet(objVal, v.Keys[i].Str, vm.applicationValueToValue(v.Arr[i]))
+			}
+		}
+		return objVal
+	case asp.ApplicationValueRecordset:
+		return vm.adodbRecordsetFromApplicationValue(v)
+	}
+	return Value{Type: VTEmpty}
+}
+
+// stateContents is the Contents collection of a Session or an Application.
+type stateContents interface {
+	Get(key string) (asp.ApplicationValue, bool)
+	Set(key string, value asp.ApplicationValue)
+}
+
+// stateObjectKey names one Session or Application entry.
+type stateObjectKey struct {
+	contents stateContents
+	name     string
+}
+
+// stateObjectBinding is the live object of one Session or Application entry
+// and the value last written to the entry.
+type stateObjectBinding struct {
+	object Value
+	stored asp.ApplicationValue
+}
+
+// isStoredApplicationObject reports whether v is an object stored by value.
+func isStoredApplicationObject(v asp.ApplicationValue) bool {
+	switch v.Type {
+	case asp.ApplicationValueDictionary, asp.ApplicationValueCollection, asp.ApplicationValueJSPlainObject, asp.ApplicationValueRecordset:
+		return true
+	}
+	return false
+}
+
+// stateValue reads one Session or Application entry. An object entry is
+// decoded once per request and the same object is returned until the request
+// ends, so changes made through Session("cart") or Application("list") are kept.
+func (vm *VM) stateValue(contents stateContents, key string) (Value, bool) {
+	name := strings.ToLower(key)
+	if binding, ok := vm.stateObjects[stateObjectKey{contents, name}]; ok {
+		return binding.object, true
+	}
+	stored, ok := contents.Get(key)
+	if !ok {
+		return Value{Type: VTEmpty}, false
+	}
+	value := vm.applicationValueToValue(stored)
+	if isStoredApplicationObject(stored) {
+		vm.bindStateObject(stateObjectKey{contents, name}, value, stored)
+	}
+	return value, true
+}
+
+// setStateValue writes one Session or Application entry. An object keeps its
+// binding to the entry until the request ends.
+func (vm *VM) setStateValue(contents stateContents, key string, v Value) {
+	stored, ok := vm.valueToApplicationValue(v)
+	if !ok {
+		return
+	}
+	contents.Set(key, stored)
+	name := strings.ToLower(key)
+	if isStoredApplicationObject(stored) {
+		vm.bindStateObject(stateObjectKey{contents, name}, v, stored)
+		return
+	}
+	delete(vm.stateObjects, stateObjectKey{contents, name})
+}
+
+// bindStateObject binds object to one Session or Application entry.
+func (vm *VM) bindStateObject(key stateObjectKey, object Value, stored asp.ApplicationValue) {
+	if vm.stateObjects == nil {
+		vm.stateObjects = make(map[stateObjectKey]*stateObjectBinding)
+	}
+	vm.stateObjects[key] = &stateObjectBinding{object: object, stored: stored}
+}
+
+// unbindStateValue drops the live object of one entry removed from contents.
+func (vm *VM) unbindStateValue(contents stateContents, key string) {
+	delete(vm.stateObjects, stateObjectKey{contents, strings.ToLower(key)})
+}
+
+// unbindStateValues drops the live objects of every entry of contents.
+func (vm *VM) unbindStateValues(contents stateContents) {
+	for key := range vm.stateObjects {
+		if key.contents == contents {
+			delete(vm.stateObjects, key)
+		}
+	}
+}
+
+// flushStateObjects writes back the objects bound to Session and Application
+// entries that changed since they were read or stored, and ends their binding.
+// It runs when the page ends, before the session is saved, and before
+// Server.Execute and Server.Transfer run another page with the same host.
+func (vm *VM) flushStateObjects() {
+	for key, binding := range vm.stateObjects {
+		stored, err := vm.encodeApplicationValue(binding.object, nil)
+		if err != nil || stored.Equal(binding.stored) {
+			continue
+		}
+		if application, ok := key.contents.(*asp.Application); ok && vm.host != nil {
+			application.WaitForServer(vm.host.Server())
+		}
+		key.contents.Set(key.name, stored)
+	}
+	clear(vm.stateObjects)
+}
@@ -0,0 +1,73 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonvm
+
+import (
+	"fmt"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ApplicationStoreMemory keeps Application.Contents in the memory of each process.
+	ApplicationStoreMemory = "memory"
+	// ApplicationStoreFile shares Application.Contents between the processes of
+	// a site through one locked file in temp_dir/application.
+	ApplicationStoreFile = "file"
+)
+
+// ApplicationStoreConfig holds the application_store key of the [global] section.
+type ApplicationStoreConfig struct {
+	Backend string
+}
+
+// ApplicationStoreConfigFromViper reads the application_store key of the [global] section.
+func ApplicationStoreConfigFromViper(v *viper.Viper) ApplicationStoreConfig {
+	cfg := ApplicationStoreConfig{Backend: ApplicationStoreMemory}
+	if v == nil {
+		return cfg
+	}
+	if backend := strings.ToLower(strings.TrimSpace(v.GetString("global.application_store"))); backend != "" {
+		cfg.Backend = backend
+	}
+	return cfg
+}
+
+// ConfigureApplicationStore gives app the store selected by cfg. The file
+// store keeps the contents of the application identified by key in dir, so
+// every process started with the same dir and key shares them.
+func ConfigureApplicationStore(app *asp.Application, cfg ApplicationStoreConfig, dir string, key string) error {
+	switch cfg.Backend {
+	case "", ApplicationStoreMemory:
+		return nil
+	case ApplicationStoreFile:
+		store, err := asp.NewFileApplicationStore(dir, key)
+		if err != nil {
+			return fmt.Errorf("open application store: %w", err)
+		}
+		app.SetStore(store)
+		return nil
+	default:
+		return fmt.Errorf("unknown application store %q", cfg.Backend)
+	}
+}
@@ -21,9 +21,12 @@
 package asp
 
 import (
+	"log"
 	"maps"
+	"math"
 	"strings"
 	"sync"
+	"time"
 )
 
 // ApplicationValueType identifies the stored primitive variant kind.
@@ -52,14 +55,35 @@ const (
 	ApplicationValueJSObject     ApplicationValueType = 9
 )
 
+// Values stored by value, so they keep their meaning in the next request and
+// in the G3SES file. Objects keep their contents in Keys and Arr.
+const (
+	// ApplicationValueNull stores the Null variant.
+	ApplicationValueNull ApplicationValueType = 10
+	// ApplicationValueDate stores a date in Num as UTC Unix nanoseconds.
+	ApplicationValueDate ApplicationValueType = 11
+	// ApplicationValueDictionary stores a Scripting.Dictionary: Keys and Arr
+	// hold the entries in insertion order and Num the CompareMode.
+	ApplicationValueDictionary ApplicationValueType = 12
+	// ApplicationValueCollection stores a Collection: Arr holds the items.
+	ApplicationValueCollection ApplicationValueType = 13
+	// ApplicationValueJSPlainObject stores a plain JScript object: Keys holds
+	// the property names and Arr their values.
+	ApplicationValueJSPlainObject ApplicationValueType = 14
+	// ApplicationValueRecordset stores a disconnected ADODB.Recordset: Keys holds
+	// one array per field, Arr one array per row and Num the current row.
+	ApplicationValueRecordset ApplicationValueType = 15
+)
+
 type ApplicationValue struct {
 	Type      ApplicationValueType
 	Num       int64
 	Flt       float64
 	Str       string
 	Interface string
-	Arr       []ApplicationValue // Elements when Type == ApplicationValueArray.
+	Arr       []ApplicationValue // Elements when Type == ApplicationValueArray, items of stored objects.
 	ArrLower  int                // Lower bound of this array dimension.
+	Keys      []ApplicationValue // Keys of stored Dictionary, JScript and Recordset values.
 }
 
 // NewApplicationArray creates an ApplicationValue holding a VBScript array dimension.
@@ -115,6 +139,43 @@ func NewApplicationJSObject(id int64, str string, iface string) ApplicationValue
 	return ApplicationValue{Type: ApplicationValueJSObject, Num: id, Str: str, Interface: iface}
 }
 
+// NewApplicationNull creates a Null application value.
+func NewApplicationNull() ApplicationValue {
+	return ApplicationValue{Type: ApplicationValueNull}
+}
+
+// NewApplicationDate creates a date application value.
+func NewApplicationDate(v time.Time) ApplicationValue {
+	return ApplicationValue{Type: ApplicationValueDate, Num: v.UTC().UnixNano()}
+}
+
+// NewApplicationDictionary creates a stored Scripting.Dictionary with keys and
+// items in insertion order.
+func NewApplicationDictionary(compareMode int, keys []ApplicationValue, items []ApplicationValue) ApplicationValue {
+	return ApplicationValue{Type: ApplicationValueDictionary, Num: int64(compareMode), Keys: keys, Arr: items}
+}
+
+// NewApplicationCollection creates a stored Collection.
+func NewApplicationCollection(items []ApplicationValue) ApplicationValue {
+	return ApplicationValue{Type: ApplicationValueCollection, Arr: items}
+}
+
+// NewApplicationJSPlainObject creates a stored plain JScript object.
+func NewApplicationJSPlainObject(names []ApplicationValue, values []ApplicationValue) ApplicationValue {
+	return ApplicationValue{Type: ApplicationValueJSPlainObject, Keys: names, Arr: values}
+}
+
+// NewApplicationRecordset creates a stored disconnected ADODB.Recordset
+// positioned on currentRow.
+func NewApplicationRecordset(fields []ApplicationValue, rows []ApplicationValue, currentRow int) ApplicationValue {
+	return ApplicationValue{Type: ApplicationValueRecordset, Num: int64(currentRow), Keys: fields, Arr: rows}
+}
+
+// Time returns the date held by a date value.
+func (v ApplicationValue) Time() time.Time {
+	return time.Unix(0, v.Num).UTC()
+}
+
 // Bool returns the boolean representation for boolean values.
 func (v ApplicationValue) Bool() bool {
 	return v.Num != 0
@@ -122,7 +183,33 @@ func (v ApplicationValue) Bool() bool {
 
 // IsObject reports whether this application value is an object reference.
 func (v ApplicationValue) IsObject() bool {
-	return v.Type == ApplicationValueNativeObject || v.Type == ApplicationValueObject || v.Type == ApplicationValueJSObject || v.Type == ApplicationValueNothing
+	switch v.Type {
+	case ApplicationValueNativeObject, ApplicationValueObject, ApplicationValueJSObject, ApplicationValueNothing,
+		ApplicationValueDictionary, ApplicationValueCollection, ApplicationValueJSPlainObject, ApplicationValueRecordset:
+		return true
+	}
+	return false
+}
+
+// Equal reports whether v and other hold the same value, including the
+// contents of arrays and stored objects.
+func (v ApplicationValue) Equal(other ApplicationValue) bool {
+	if v.Type != other.Type || v.Num != other.Num || math.Float64bits(v.Flt) != math.Float64bits(other.Flt) ||
+		v.Str != other.Str || v.Interface != other.Interface || v.ArrLower != other.ArrLower ||
+		len(v.Arr) != len(other.Arr) || len(v.Keys) != len(other.Keys) {
+		return false
+	}
+	for i := range v.Arr {
+		if !v.Arr[i].Equal(other.Arr[i]) {
+			return false
+		}
+	}
+	for i := range v.Keys {
+		if !v.Keys[i].Equal(other.Keys[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 // normalizeApplicationKey normalizes a key for case-insensitive lookup semantics.
@@ -148,6 +235,12 @@ type Application struct {
 	locked bool
 	// lockCount tracks nested Lock/Unlock pairs.
 	lockCount int
+	// store shares contents with the other processes of the site when it is not nil.
+	store ApplicationStore
+	// storeMu serializes the use of store in this process.
+	storeMu sync.Mutex
+	// storeLocked is true while Lock holds the lock of store.
+	storeLocked bool
 }
 
 // NewApplication creates a VM-native Application object.
@@ -165,10 +258,83 @@ func (app *Application) Lock() {
 	app.LockForServer(nil)
 }
 
+// SetStore shares Contents with the other processes that use store. It must
+// be called before the application starts.
+func (app *Application) SetStore(store ApplicationStore) {
+	app.storeMu.Lock()
+	defer app.storeMu.Unlock()
+	app.store = store
+}
+
+// JoinStore counts this process as a user of the store and runs onStart when
+// no other process uses it. Without a store it runs onStart.
+func (app *Application) JoinStore(onStart func()) error {
+	if app.store == nil {
+		if onStart != nil {
+			onStart()
+		}
+		return nil
+	}
+	return app.store.Join(onStart)
+}
+
+// LeaveStore stops counting this process as a user of the store and runs
+// onEnd when no other process uses it. Without a store it runs onEnd.
+func (app *Application) LeaveStore(onEnd func()) error {
+	if app.store == nil {
+		if onEnd != nil {
+			onEnd()
+		}
+		return nil
+	}
+	return app.store.Leave(onEnd)
+}
+
+// syncStore loads the shared contents into app.contents and, when update is
+// not nil, applies it and saves the result. It does nothing without a store.
+// Reads only take the shared lock of the store, so workers do not queue
+// behind each other to read Contents.
+// On errors the process keeps working with the contents it loaded last.
+func (app *Application) syncStore(update func(contents map[string]ApplicationValue)) {
+	if app.store == nil {
+		return
+	}
+	app.storeMu.Lock()
+	defer app.storeMu.Unlock()
+	if !app.storeLocked {
+		lock := app.store.Lock
+		if update == nil {
+			lock = app.store.RLock
+		}
+		if err := lock(); err != nil {
+			log.Printf("Warning: Failed to lock the shared Application store: %v\n", err)
+			return
+		}
+		defer app.store.Unlock()
+	}
+
+	contents, err := app.store.Load()
+	if err != nil {
+		log.Printf("Warning: Failed to read the shared Application store: %v\n", err)
+		return
+	}
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	app.contents = contents
+	if update == nil {
+		return
+	}
+	update(app.contents)
+	if err := app.store.Save(app.contents); err != nil {
+		log.Printf("Warning: Failed to write the shared Application store: %v\n", err)
+	}
+}
+
 // LockForServer enters an application-wide critical section owned by one request server.
+// With a store, the outermost level also holds the lock of the store, so the
+// critical section spans every process of the site.
 func (app *Application) LockForServer(owner *Server) {
 	app.stateMu.Lock()
-	defer app.stateMu.Unlock()
 	for app.lockCount > 0 && app.lockOwner != owner {
 		app.stateCond.Wait()
 	}
@@ -176,6 +342,18 @@ func (app *Application) LockForServer(owner *Server) {
 	app.lockOwner = owner
 	app.lockCount++
 	app.locked = app.lockCount > 0
+	outermost := app.lockCount == 1
+	app.stateMu.Unlock()
+
+	if outermost && app.store != nil {
+		app.storeMu.Lock()
+		if err := app.store.Lock(); err != nil {
+			log.Printf("Warning: Failed to lock the shared Application store: %v\n", err)
+		} else {
+			app.storeLocked = true
+		}
+		app.storeMu.Unlock()
+	}
 }
 
 // Unlock leaves an application-wide critical section for one nesting level.
@@ -197,9 +375,42 @@ func (app *Application) UnlockForServer(owner *Server) {
 	app.lockCount--
 	app.locked = app.lockCount > 0
 	if app.lockCount == 0 {
-		app.lockOwner = nil
-		app.stateCond.Broadcast()
+		app.releaseLock()
+	}
+}
+
+// ReleaseForServer leaves every nesting level that owner still holds, as IIS
+// does when the page that called Application.Lock ends.
+func (app *Application) ReleaseForServer(owner *Server) {
+	if owner == nil {
+		return
+	}
+	app.stateMu.Lock()
+	defer app.stateMu.Unlock()
+	if app.lockCount == 0 || app.lockOwner != owner {
+		return
+	}
+
+	app.lockCount = 0
+	app.locked = false
+	app.releaseLock()
+}
+
+// releaseLock releases the lock of the store and wakes the waiting requests.
+// The caller holds stateMu.
+func (app *Application) releaseLock() {
+	app.lockOwner = nil
+	if app.store != nil {
+		app.storeMu.Lock()
+		if app.storeLocked {
+			app.storeLocked = false
+			if err := app.store.Unlock(); err != nil {
+				log.Printf("Warning: Failed to unlock the shared Application store: %v\n", err)
+			}
+		}
+		app.storeMu.Unlock()
 	}
+	app.stateCond.Broadcast()
 }
 
 // WaitForServer blocks until the current Application lock is released or owned by the same request server.
@@ -229,6 +440,12 @@ func (app *Application) GetLockCount() int {
 
 // Set stores a value in Application.Contents using case-insensitive keys.
 func (app *Application) Set(key string, value ApplicationValue) {
+	if app.store != nil {
+		app.syncStore(func(contents map[string]ApplicationValue) {
+			contents[normalizeApplicationKey(key)] = value
+		})
+		return
+	}
 	app.mutex.Lock()
 	defer app.mutex.Unlock()
 
@@ -237,6 +454,7 @@ func (app *Application) Set(key string, value ApplicationValue) {
 
 // Get returns a value from Application.Contents using case-insensitive keys.
 func (app *Application) Get(key string) (ApplicationValue, bool) {
+	app.syncStore(nil)
 	app.mutex.RLock()
 	defer app.mutex.RUnlock()
 
@@ -246,6 +464,7 @@ func (app *Application) Get(key string) (ApplicationValue, bool) {
 
 // ContainsContent reports whether a Contents key exists.
 func (app *Application) ContainsContent(key string) bool {
+	app.syncStore(nil)
 	app.mutex.RLock()
 	defer app.mutex.RUnlock()
 
@@ -255,6 +474,12 @@ func (app *Application) ContainsContent(key string) bool {
 
 // Remove deletes one key from Application.Contents.
 func (app *Application) Remove(key string) {
+	if app.store != nil {
+		app.syncStore(func(contents map[string]ApplicationValue) {
+			delete(contents, normalizeApplicationKey(key))
+		})
+		return
+	}
 	app.mutex.Lock()
 	defer app.mutex.Unlock()
 
@@ -263,14 +488,32 @@ func (app *Application) Remove(key string) {
 
 // RemoveAll clears all keys from Application.Contents.
 func (app *Application) RemoveAll() {
+	if app.store != nil {
+		app.syncStore(func(contents map[string]ApplicationValue) {
+			clear(contents)
+		})
+		return
+	}
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	app.contents = make(map[string]ApplicationValue)
+}
+
+// Reset clears Contents and StaticObjects before the application starts again.
+// With a store it only drops the contents this process loaded last; the first
+// process that joins the store clears the shared contents.
+func (app *Application) Reset() {
 	app.mutex.Lock()
 	defer app.mutex.Unlock()
 
 	app.contents = make(map[string]ApplicationValue)
+	app.staticObjects = make(map[string]ApplicationValue)
 }
 
 // Count returns total number of entries in Contents and StaticObjects.
 func (app *Application) Count() int {
+	app.syncStore(nil)
 	app.mutex.RLock()
 	defer app.mutex.RUnlock()
 
@@ -305,6 +548,7 @@ func (app *Application) ContainsStaticObject(key string) bool {
 
 // GetContentsCopy returns a snapshot copy of Contents for safe enumeration.
 func (app *Application) GetContentsCopy() map[string]ApplicationValue {
+	app.syncStore(nil)
 	app.mutex.RLock()
 	defer app.mutex.RUnlock()
 
@@ -325,6 +569,7 @@ func (app *Application) GetStaticObjectsCopy() map[string]ApplicationValue {
 
 // GetAllKeys returns all keys from both Contents and StaticObjects.
 func (app *Application) GetAllKeys() []string {
+	app.syncStore(nil)
 	app.mutex.RLock()
 	defer app.mutex.RUnlock()
 
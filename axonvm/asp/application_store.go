@@ -0,0 +1,276 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ApplicationStore shares Application.Contents between the worker processes of
+// one site. StaticObjects stay in each process.
+type ApplicationStore interface {
+	// Lock blocks until the calling process holds the lock of the store. Only
+	// one process holds it at a time.
+	Lock() error
+	// RLock blocks until the calling process holds a shared lock of the store.
+	// Several processes may hold it at once to read, but not with Lock.
+	RLock() error
+	// Unlock releases the lock taken by Lock or RLock.
+	Unlock() error
+	// Load returns the stored contents. It is called with the lock or the
+	// shared lock held and may return the map of the previous Load when
+	// nothing changed since.
+	Load() (map[string]ApplicationValue, error)
+	// Save replaces the stored contents. It is called with the lock held.
+	Save(contents map[string]ApplicationValue) error
+	// Join counts the calling process as a user of the store. When no other
+	// process uses it, Join clears the contents and runs onFirst before any
+	// other process can join.
+	Join(onFirst func()) error
+	// Leave stops counting the calling process as a user of the store. When no
+	// other process uses it, Leave runs onLast first.
+	Leave(onLast func()) error
+}
+
+// applicationStoreHeaderSize is the size of the G3APP header: the magic, the
+// format version and the generation, which grows with every Save.
+const applicationStoreHeaderSize = 14
+
+var applicationStoreMagic = []byte("G3APP")
+
+// FileApplicationStore keeps the Application contents of one site in a
+// .g3app file. The processes that share it lock the file with the advisory
+// locks of the operating system, and a .users file next to it tells the first
+// and the last of them apart.
+type FileApplicationStore struct {
+	data  *os.File
+	users *os.File
+
+	// contents and generation cache the last Load, so a process only decodes
+	// the file again after another process saved it.
+	contents   map[string]ApplicationValue
+	generation uint64
+	joined     bool
+}
+
+// NewFileApplicationStore opens the store of the application identified by
+// key in dir. Every process that passes the same dir and key shares it.
+func NewFileApplicationStore(dir string, key string) (*FileApplicationStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(key))
+	base := filepath.Join(dir, hex.EncodeToString(sum[:8]))
+
+	data, err := os.OpenFile(base+".g3app", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	users, err := os.OpenFile(base+".users", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		_ = data.Close()
+		return nil, err
+	}
+	return &FileApplicationStore{data: data, users: users}, nil
+}
+
+// Lock implements ApplicationStore.
+func (s *FileApplicationStore) Lock() error {
+	_, err := lockApplicationFile(s.data, true, true)
+	return err
+}
+
+// RLock implements ApplicationStore.
+func (s *FileApplicationStore) RLock() error {
+	_, err := lockApplicationFile(s.data, false, true)
+	return err
+}
+
+// Unlock implements ApplicationStore.
+func (s *FileApplicationStore) Unlock() error {
+	return unlockApplicationFile(s.data)
+}
+
+// Load implements ApplicationStore.
+func (s *FileApplicationStore) Load() (map[string]ApplicationValue, error) {
+	var header [applicationStoreHeaderSize]byte
+	n, err := s.data.ReadAt(header[:], 0)
+	if n == 0 && errors.Is(err, io.EOF) {
+		if s.contents == nil || s.generation != 0 {
+			s.contents = make(map[string]ApplicationValue)
+			s.generation = 0
+		}
+		return s.contents, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:5], applicationStoreMagic) || header[5] != 1 {
+		return nil, fmt.Errorf("%s is not an Application store", s.data.Name())
+	}
+	generation := binary.LittleEndian.Uint64(header[6:])
+	if s.contents != nil && generation == s.generation {
+		return s.contents, nil
+	}
+
+	info, err := s.data.Stat()
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([]byte, info.Size())
+	if _, err := s.data.ReadAt(encoded, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	s.contents = deserializeApplicationMap(bytes.NewReader(encoded[applicationStoreHeaderSize:]))
+	s.generation = generation
+	return s.contents, nil
+}
+
+// Save implements ApplicationStore.
+func (s *FileApplicationStore) Save(contents map[string]ApplicationValue) error {
+	var buf bytes.Buffer
+	buf.Write(applicationStoreMagic)
+	buf.WriteByte(1)
+	var generation [8]byte
+	binary.LittleEndian.PutUint64(generation[:], s.generation+1)
+	buf.Write(generation[:])
+	serializeApplicationMap(&buf, contents)
+
+	if _, err := s.data.WriteAt(buf.Bytes(), 0); err != nil {
+		s.contents = nil
+		return err
+	}
+	if err := s.data.Truncate(int64(buf.Len())); err != nil {
+		s.contents = nil
+		return err
+	}
+	s.contents = contents
+	s.generation++
+	return nil
+}
+
+// Join implements ApplicationStore. The process holds a shared lock on the
+// .users file while it uses the store. The first process holds an exclusive
+// lock until onFirst returns, so the processes that join meanwhile wait. The
+// last process holds it while onLast runs, so a process that joins meanwhile
+// waits and then becomes the first.
+func (s *FileApplicationStore) Join(onFirst func()) error {
+	if s.joined {
+		return nil
+	}
+	for {
+		if err := s.Lock(); err != nil {
+			return err
+		}
+		first, err := lockApplicationFile(s.users, true, false)
+		if err != nil {
+			_ = s.Unlock()
+			return err
+		}
+		if first {
+			break
+		}
+		joined, err := lockApplicationFile(s.users, false, false)
+		_ = s.Unlock()
+		if err != nil {
+			return err
+		}
+		if joined {
+			s.joined = true
+			return nil
+		}
+		// The first process is still running onFirst or the last one onLast,
+		// and both need the data lock this process just released. Wait for it,
+		// then check again which of the two it was.
+		if _, err := lockApplicationFile(s.users, false, true); err != nil {
+			return err
+		}
+		if err := unlockApplicationFile(s.users); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.Load(); err != nil {
+		s.generation = 0
+	}
+	err := s.Save(make(map[string]ApplicationValue))
+	_ = s.Unlock()
+	if err != nil {
+		_ = unlockApplicationFile(s.users)
+		return err
+	}
+	if onFirst != nil {
+		onFirst()
+	}
+
+	// Trade the exclusive lock for a shared one while holding the data lock,
+	// so no process that joins in between can take it for the first.
+	if err := s.Lock(); err != nil {
+		_ = unlockApplicationFile(s.users)
+		return err
+	}
+	defer s.Unlock()
+	if err := unlockApplicationFile(s.users); err != nil {
+		return err
+	}
+	if _, err := lockApplicationFile(s.users, false, true); err != nil {
+		return err
+	}
+	s.joined = true
+	return nil
+}
+
+// Leave implements ApplicationStore.
+func (s *FileApplicationStore) Leave(onLast func()) error {
+	if !s.joined {
+		return nil
+	}
+	if err := s.Lock(); err != nil {
+		return err
+	}
+	s.joined = false
+	if err := unlockApplicationFile(s.users); err != nil {
+		_ = s.Unlock()
+		return err
+	}
+	last, err := lockApplicationFile(s.users, true, false)
+	_ = s.Unlock()
+	if err != nil || !last {
+		return err
+	}
+	if onLast != nil {
+		onLast()
+	}
+	return unlockApplicationFile(s.users)
+}
+
+// Close closes the files of the store, which releases its locks.
+func (s *FileApplicationStore) Close() error {
+	return errors.Join(s.data.Close(), s.users.Close())
+}
@@ -0,0 +1,39 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"errors"
+	"os"
+)
+
+// lockApplicationFile reports that this platform has no file locks to share
+// the Application between processes.
+func lockApplicationFile(file *os.File, exclusive bool, wait bool) (bool, error) {
+	return false, errors.ErrUnsupported
+}
+
+// unlockApplicationFile reports that this platform has no file locks.
+func unlockApplicationFile(file *os.File) error {
+	return errors.ErrUnsupported
+}
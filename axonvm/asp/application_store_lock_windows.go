@@ -0,0 +1,62 @@
+//go:build windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// applicationLockOffsetHigh places the locked byte far past the end of the
+// file, so the lock never blocks reads and writes of the data itself.
+const applicationLockOffsetHigh = 0x7FFFFFFF
+
+// lockApplicationFile takes a shared or exclusive LockFileEx lock on file.
+// Without wait it reports false instead of blocking when another process
+// holds a conflicting lock.
+func lockApplicationFile(file *os.File, exclusive bool, wait bool) (bool, error) {
+	var flags uint32
+	if exclusive {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	if !wait {
+		flags |= windows.LOCKFILE_FAIL_IMMEDIATELY
+	}
+	overlapped := windows.Overlapped{OffsetHigh: applicationLockOffsetHigh}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, &overlapped)
+	if err == nil {
+		return true, nil
+	}
+	if !wait && errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return false, err
+}
+
+// unlockApplicationFile releases the lock taken by lockApplicationFile.
+func unlockApplicationFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: applicationLockOffsetHigh}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -0,0 +1,256 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas GuimarÃ£es - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"testing"
+	"time"
+)
+
+// newSharedTestApplications returns two Applications sharing one file store,
+// as two workers of a site do. Each store opens its own files, so their locks
+// conflict like the locks of two processes.
+func newSharedTestApplications(t *testing.T) (*Application, *Application) {
+	t.Helper()
+	dir := t.TempDir()
+	apps := make([]*Application, 2)
+	for i := range apps {
+		store, err := NewFileApplicationStore(dir, "/var/www/site")
+		if err != nil {
+			t.Fatalf("open store: %v", err)
+		}
+		t.Cleanup(func() { _ = store.Close() })
+		apps[i] = NewApplication()
+		apps[i].SetStore(store)
+	}
+	return apps[0], apps[1]
+}
+
+// TestFileApplicationStoreSharesContents verifies that writes and removals of
+// one worker are seen by the other.
+func TestFileApplicationStoreSharesContents(t *testing.T) {
+	first, second := newSharedTestApplications(t)
+
+	first.Set("Counter", NewApplicationInteger(1))
+	first.Set("Greeting", NewApplicationString("hello"))
+	value, ok := second.Get("counter")
+	if !ok || value.Num != 1 {
+		t.Fatalf("expected the second worker to read Counter=1, got %#v, %v", value, ok)
+	}
+
+	second.Set("Counter", NewApplicationInteger(2))
+	second.Remove("Greeting")
+	if value, _ := first.Get("Counter"); value.Num != 2 {
+		t.Fatalf("expected the first worker to read Counter=2, got %#v", value)
+	}
+	if first.ContainsContent("Greeting") {
+		t.Fatalf("expected Contents.Remove to reach the first worker")
+	}
+
+	first.RemoveAll()
+	if second.Count() != 0 {
+		t.Fatalf("expected RemoveAll to empty the contents of the second worker, got %d", second.Count())
+	}
+}
+
+// TestFileApplicationStoreLockSpansWorkers verifies that Application.Lock in
+// one worker holds back the writes of the other until Unlock.
+func TestFileApplicationStoreLockSpansWorkers(t *testing.T) {
+	first, second := newSharedTestApplications(t)
+	first.Set("Counter", NewApplicationInteger(0))
+
+	owner := NewServer()
+	first.LockForServer(owner)
+	written := make(chan struct{})
+	go func() {
+		second.Set("Counter", NewApplicationInteger(100))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatalf("expected the second worker to wait while the first holds the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+	value, _ := first.Get("Counter")
+	first.Set("Counter", NewApplicationInteger(value.Num+1))
+	first.ReleaseForServer(owner)
+
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the second worker to write after the lock was released")
+	}
+	if value, _ := first.Get("Counter"); value.Num != 100 {
+		t.Fatalf("expected Counter=100 after the second write, got %#v", value)
+	}
+}
+
+// TestFileApplicationStoreStartAndEnd verifies that only the first worker
+// runs onStart and clears stale contents, and only the last runs onEnd.
+func TestFileApplicationStoreStartAndEnd(t *testing.T) {
+	first, second := newSharedTestApplications(t)
+	first.Set("Stale", NewApplicationString("from a previous run"))
+
+	started := 0
+	if err := first.JoinStore(func() {
+		started++
+		first.Set("StartedBy", NewApplicationString("first"))
+	}); err != nil {
+		t.Fatalf("first join: %v", err)
+	}
+	if err := second.JoinStore(func() { started++ }); err != nil {
+		t.Fatalf("second join: %v", err)
+	}
+	if started != 1 {
+		t.Fatalf("expected Application_OnStart to run once, ran %d times", started)
+	}
+	if second.ContainsContent("Stale") {
+		t.Fatalf("expected the first worker to clear the contents of a previous run")
+	}
+	if value, _ := second.Get("StartedBy"); value.Str != "first" {
+		t.Fatalf("expected the second worker to see the contents set by OnStart, got %#v", value)
+	}
+
+	ended := 0
+	if err := first.LeaveStore(func() { ended++ }); err != nil {
+		t.Fatalf("first leave: %v", err)
+	}
+	if ended != 0 {
+		t.Fatalf("expected Application_OnEnd to wait for the last worker")
+	}
+	if err := second.LeaveStore(func() { ended++ }); err != nil {
+		t.Fatalf("second leave: %v", err)
+	}
+	if ended != 1 {
+		t.Fatalf("expected the last worker to run Application_OnEnd once, ran %d times", ended)
+	}
+}
+
+// TestFileApplicationStoreJoinDuringEnd verifies that a worker joining while
+// the last worker runs onEnd waits for it and then starts the application
+// again, as when a pool scaled to zero receives a request.
+func TestFileApplicationStoreJoinDuringEnd(t *testing.T) {
+	first, second := newSharedTestApplications(t)
+	if err := first.JoinStore(nil); err != nil {
+		t.Fatalf("first join: %v", err)
+	}
+
+	inEnd := make(chan struct{})
+	endDone := make(chan struct{})
+	left := make(chan error, 1)
+	go func() {
+		left <- first.LeaveStore(func() {
+			first.Set("Stale", NewApplicationString("from the ended application"))
+			close(inEnd)
+			<-endDone
+		})
+	}()
+	<-inEnd
+
+	started := make(chan bool, 1)
+	joined := make(chan error, 1)
+	go func() {
+		ran := false
+		err := second.JoinStore(func() { ran = true })
+		started <- ran
+		joined <- err
+	}()
+	select {
+	case <-joined:
+		t.Fatalf("expected the second worker to wait while Application_OnEnd runs")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(endDone)
+
+	select {
+	case ran := <-started:
+		if err := <-joined; err != nil {
+			t.Fatalf("second join: %v", err)
+		}
+		if !ran {
+			t.Fatalf("expected the second worker to run Application_OnStart after the application ended")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the second worker to join after Application_OnEnd")
+	}
+	if err := <-left; err != nil {
+		t.Fatalf("leave: %v", err)
+	}
+	if second.ContainsContent("Stale") {
+		t.Fatalf("expected the second worker to clear the contents of the ended application")
+	}
+}
+
+// TestFileApplicationStoreReadsShareLock verifies that a worker reads Contents
+// while another worker holds the shared lock for its own read.
+func TestFileApplicationStoreReadsShareLock(t *testing.T) {
+	first, second := newSharedTestApplications(t)
+	first.Set("Counter", NewApplicationInteger(7))
+
+	if err := first.store.RLock(); err != nil {
+		t.Fatalf("shared lock: %v", err)
+	}
+	defer first.store.Unlock()
+	read := make(chan ApplicationValue, 1)
+	go func() {
+		value, _ := second.Get("Counter")
+		read <- value
+	}()
+
+	select {
+	case value := <-read:
+		if value.Num != 7 {
+			t.Fatalf("expected the second worker to read Counter=7, got %#v", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the second worker to read while the first holds a shared lock")
+	}
+}
+
+// BenchmarkApplicationGet measures Application("x") reads in memory and
+// through the file store, which is the hot path of shared Applications.
+func BenchmarkApplicationGet(b *testing.B) {
+	b.Run("memory", func(b *testing.B) {
+		app := NewApplication()
+		app.Set("Counter", NewApplicationInteger(1))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			app.Get("Counter")
+		}
+	})
+	b.Run("file", func(b *testing.B) {
+		store, err := NewFileApplicationStore(b.TempDir(), "/var/www/site")
+		if err != nil {
+			b.Fatalf("open store: %v", err)
+		}
+		defer store.Close()
+		app := NewApplication()
+		app.SetStore(store)
+		app.Set("Counter", NewApplicationInteger(1))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			app.Get("Counter")
+		}
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -270,6 +271,13 @@ func (r *Response) AppendToLog(message string) {
 	appendRuntimeLogLine(message)
 }
 
+// LogEntries returns the messages passed to AppendToLog during the request.
+func (r *Response) LogEntries() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.logEntries)
+}
+
 // appendRuntimeLogLine writes one log line to temp/<runtime>.log using best-effort semantics.
 func appendRuntimeLogLine(message string) {
 	tempDir := resolveConfiguredTempDir()
@@ -666,6 +674,14 @@ func (r *Response) IsEnded() bool {
 	return r.ended
 }
 
+// HeadersSent reports whether the HTTP headers were written to the client, after
+// which headers and cookies can no longer change.
+func (r *Response) HeadersSent() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.flushed
+}
+
 // ResetEnded clears the ended and flushed flags so the response can continue
 // accepting output. This is used after Session_OnStart execution, where
 // Response.End/Redirect may have been called inside the suppressed-output scope
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"html"
 	"net/url"
+	pathpkg "path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -43,9 +44,11 @@ type Server struct {
 	scriptTimeout  int
 	rootDir        string
 	requestPath    string
+	virtualDirs    []VirtualDirectory
 	lastError      *ASPError
 	execStart      time.Time
 	execDepth      int
+	terminated     bool
 	unrestrictedFS bool
 }
 
@@ -69,6 +72,21 @@ func (s *Server) SetRootDir(rootDir string) {
 	s.rootDir = rootDir
 }
 
+// SetVirtualDirectories defines the URL prefixes that MapPath resolves outside the web root.
+// The mappings must come from NormalizeVirtualDirectories.
+func (s *Server) SetVirtualDirectories(dirs []VirtualDirectory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.virtualDirs = dirs
+}
+
+// VirtualDirectories returns the virtual directory mappings used by MapPath.
+func (s *Server) VirtualDirectories() []VirtualDirectory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.virtualDirs
+}
+
 // SetUnrestrictedFS enables or disables unrestricted filesystem access for
 // trusted desktop applications (e.g., AxonHTA). When enabled, the FSO sandbox
 // check in fsoResolvePath is bypassed, allowing Scripting.FileSystemObject to
@@ -111,10 +129,28 @@ func (s *Server) GetRequestPath() string {
 func (s *Server) VirtualPathFromAbsolutePath(absPath string) string {
 	s.mu.RLock()
 	rootDir := s.rootDir
+	virtualDirs := s.virtualDirs
 	s.mu.RUnlock()
 	if strings.TrimSpace(absPath) == "" {
 		return "/"
 	}
+	if len(virtualDirs) > 0 {
+		if absTarget, err := filepath.Abs(absPath); err == nil {
+			for _, dir := range virtualDirs {
+				relPath, relErr := filepath.Rel(dir.PhysicalPath, absTarget)
+				if relErr != nil {
+					continue
+				}
+				cleaned := filepath.ToSlash(relPath)
+				if cleaned == "." {
+					return dir.VirtualPath
+				}
+				if cleaned != ".." && !strings.HasPrefix(cleaned, "../") {
+					return dir.VirtualPath + "/" + cleaned
+				}
+			}
+		}
+	}
 	absRoot, err := filepath.Abs(rootDir)
 	if err != nil {
 		return "/" + filepath.ToSlash(filepath.Base(absPath))
@@ -185,6 +221,21 @@ func (s *Server) EndExecution() {
 	}
 }
 
+// Terminate asks the running script of this request to stop at its next
+// timeout check, for request_terminate_timeout.
+func (s *Server) Terminate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.terminated = true
+}
+
+// IsTerminated reports whether Terminate was called for this request.
+func (s *Server) IsTerminated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.terminated
+}
+
 // HasTimedOut reports whether the current request execution exceeded the current ScriptTimeout value.
 func (s *Server) HasTimedOut() bool {
 	s.mu.RLock()
@@ -252,6 +303,7 @@ func (s *Server) MapPath(path string) string {
 	s.mu.RLock()
 	rootDir := s.rootDir
 	requestPath := s.requestPath
+	virtualDirs := s.virtualDirs
 	s.mu.RUnlock()
 	if path == "" || path == "/" || path == "\\" {
 		absRoot, err := filepath.Abs(rootDir)
@@ -262,6 +314,20 @@ func (s *Server) MapPath(path string) string {
 	}
 
 	normalized := strings.ReplaceAll(path, "\\", "/")
+	if len(virtualDirs) > 0 {
+		virtualPath := normalized
+		if !strings.HasPrefix(virtualPath, "/") {
+			virtualPath = pathpkg.Join(pathpkg.Dir(strings.ReplaceAll(requestPath, "\\", "/")), virtualPath)
+		}
+		if dir, rest, ok := MatchVirtualDirectory(virtualDirs, pathpkg.Clean("/"+virtualPath)); ok {
+			fullPath := filepath.Join(dir.PhysicalPath, filepath.FromSlash(rest))
+			absPath, err := filepath.Abs(fullPath)
+			if err != nil {
+				return fullPath
+			}
+			return absPath
+		}
+	}
 	if after, ok := strings.CutPrefix(normalized, "/"); ok {
 		fullPath := filepath.Join(rootDir, after)
 		absPath, err := filepath.Abs(fullPath)
@@ -21,6 +21,7 @@
 package asp
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -64,6 +65,29 @@ func TestServerMapPath(t *testing.T) {
 	}
 }
 
+// TestServerMapPathVirtualDirectories verifies MapPath and its reverse honor virtual directory mappings.
+func TestServerMapPathVirtualDirectories(t *testing.T) {
+	sharedDir := t.TempDir()
+	server := NewServer()
+	server.SetRootDir("./www")
+	server.SetVirtualDirectories(NormalizeVirtualDirectories([]VirtualDirectory{{VirtualPath: "/Shared/", PhysicalPath: sharedDir}}))
+	server.SetRequestPath("/shared/pages/view.asp")
+
+	expected := filepath.Join(sharedDir, "img", "logo.png")
+	if got := server.MapPath("/shared/img/logo.png"); got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	if got := server.MapPath("../img/logo.png"); got != expected {
+		t.Fatalf("expected relative path %q, got %q", expected, got)
+	}
+	if got := server.MapPath("/sharedother/a.asp"); !strings.HasSuffix(strings.ReplaceAll(got, "\\", "/"), "/www/sharedother/a.asp") {
+		t.Fatalf("expected prefix look-alike to stay under the web root, got %q", got)
+	}
+	if got := server.VirtualPathFromAbsolutePath(expected); got != "/Shared/img/logo.png" {
+		t.Fatalf("unexpected virtual path %q", got)
+	}
+}
+
 // TestServerCreateObjectError verifies CreateObject unsupported behavior and last error tracking.
 func TestServerCreateObjectError(t *testing.T) {
 	server := NewServer()
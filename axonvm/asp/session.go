@@ -25,12 +25,14 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"hash/fnv"
+	"io"
 	"maps"
 	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"g3pix.com.br/axonasp/axonconfig"
@@ -59,11 +61,26 @@ type Session struct {
 	version   uint64
 
 	lastSavedVersion uint64
+
+	// appKey names the application whose Session_OnEnd handler runs when
+	// the session ends. It is persisted with the session.
+	appKey string
+	// abandonedData keeps the contents cleared by Abandon for Session_OnEnd.
+	abandonedData map[string]ApplicationValue
+	// stored reports that the session was saved to or loaded from the store,
+	// and storedAccess is the last access time the store holds.
+	stored       bool
+	storedAccess time.Time
+	ended        atomic.Bool
 }
 
 // sessionWriteQueue is a buffered channel for asynchronous session persistence.
 var sessionWriteQueue = make(chan *Session, 10000)
 
+// sessionWritesPending counts the sessions queued or being written by the
+// session writers.
+var sessionWritesPending sync.WaitGroup
+
 func init() {
 	// Start background session writers to offload disk I/O from request threads.
 	// 4 workers provide controlled concurrency to avoid OS thread starvation.
@@ -80,9 +97,16 @@ func sessionWriterWorker() {
 		}
 		// Save performing actual disk I/O.
 		_ = s.Save()
+		sessionWritesPending.Done()
 	}
 }
 
+// WaitSessionWrites blocks until the sessions queued by QueueSaveIfDirty are
+// written.
+func WaitSessionWrites() {
+	sessionWritesPending.Wait()
+}
+
 const (
 	defaultSessionTimeoutMinutes = 20
 	defaultSessionLCID           = 1033
@@ -111,8 +135,44 @@ var (
 	sessionAutoFlushMu   sync.Mutex
 	sessionAutoFlushStop chan struct{}
 	sessionAutoFlushDone chan struct{}
+
+	sessionFlushCount  atomic.Uint64
+	sessionFlushErrors atomic.Uint64
+	sessionFlushNanos  atomic.Int64
+	sessionFlushLast   atomic.Int64
 )
 
+// SessionStats is a point-in-time snapshot of the session registry.
+type SessionStats struct {
+	// Registered is the number of sessions held in memory.
+	Registered int
+	// QueuedWrites is the number of sessions waiting for the background writers.
+	QueuedWrites int
+	// Flushes counts FlushRegisteredSessions runs.
+	Flushes uint64
+	// FlushErrors counts flushes that reported an error.
+	FlushErrors uint64
+	// FlushTime is the total time spent flushing.
+	FlushTime time.Duration
+	// LastFlushTime is the duration of the most recent flush.
+	LastFlushTime time.Duration
+}
+
+// GetSessionStats returns the registered session count and flush timings.
+func GetSessionStats() SessionStats {
+	sessionRegistryMu.RLock()
+	registered := len(sessionRegistry)
+	sessionRegistryMu.RUnlock()
+	return SessionStats{
+		Registered:    registered,
+		QueuedWrites:  len(sessionWriteQueue),
+		Flushes:       sessionFlushCount.Load(),
+		FlushErrors:   sessionFlushErrors.Load(),
+		FlushTime:     time.Duration(sessionFlushNanos.Load()),
+		LastFlushTime: time.Duration(sessionFlushLast.Load()),
+	}
+}
+
 // sessionBufferPool reduces allocations during session serialization.
 var sessionBufferPool = sync.Pool{
 	New: func() any {
@@ -150,9 +210,18 @@ func NewSessionWithID(sessionID string) *Session {
 	}
 }
 
+// GetID returns the ID of the session. Regenerate changes the ID, so code that
+// may run beside the request reads it here rather than from the ID field.
+func (s *Session) GetID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ID
+}
+
 // SessionIDNumeric returns a numeric representation of SessionID for ASP compatibility.
 func (s *Session) SessionIDNumeric() int64 {
-	return numericSessionID(s.ID)
+	return numericSessionID(s.GetID())
 }
 
 // Set stores one value in session contents using case-insensitive keys.
@@ -392,15 +461,43 @@ func (s *Session) Abandon() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if len(s.data) > 0 {
-		s.data = make(map[string]ApplicationValue)
-	}
 	if !s.abandoned {
 		s.abandoned = true
+		s.abandonedData = s.data
+		s.data = make(map[string]ApplicationValue)
+	} else if len(s.data) > 0 {
+		s.data = make(map[string]ApplicationValue)
 	}
 	s.markDirtyLocked()
 }
 
+// Regenerate moves the session to a new ID, keeping its contents, and removes
+// the record of the old ID from the session store without running
+// Session_OnEnd. Calling it after a login keeps an ID planted before the login
+// from reaching the session. The host must send the new ID to the client.
+func (s *Session) Regenerate() error {
+	newID := newSessionID()
+	s.mu.Lock()
+	oldID := s.ID
+	s.ID = newID
+	s.stored = false
+	s.markDirtyLocked()
+	s.mu.Unlock()
+
+	sessionRegistryMu.Lock()
+	if sessionRegistry[oldID] == s {
+		delete(sessionRegistry, oldID)
+	}
+	sessionRegistry[newID] = s
+	sessionRegistryMu.Unlock()
+
+	if strings.TrimSpace(oldID) == "" {
+		return nil
+	}
+	_, err := CurrentSessionStore().Delete(oldID)
+	return err
+}
+
 // IsAbandoned reports whether Abandon has been called.
 func (s *Session) IsAbandoned() bool {
 	s.mu.RLock()
@@ -459,16 +556,19 @@ func (s *Session) QueueSaveIfDirty() bool {
 		return false
 	}
 
+	sessionWritesPending.Add(1)
 	select {
 	case sessionWriteQueue <- s:
 		return true
 	default:
-		println("Warning: Session write queue overflow. Persistence delayed for session:", s.ID)
+		sessionWritesPending.Done()
+		println("Warning: Session write queue overflow. Persistence delayed for session:", s.GetID())
 		return false
 	}
 }
 
-// Save persists session state using a high-performance binary format (.g3ses).
+// Save persists session state in the installed SessionStore using a
+// high-performance binary format (.g3ses).
 // Binary Format Specification:
 // - Magic Bytes: [6]byte{'G','3','S','E','S', 0x00}
 // - Version: uint8 (Value: 1)
@@ -481,6 +581,15 @@ func (s *Session) QueueSaveIfDirty() bool {
 // - Block Separator: 0x1E (RS) followed by uint32 (Payload Length)
 // - Data Block 1: session.data map
 // - Data Block 2: session.staticObjects map
+// - Data Block 3: application key (optional, absent in older files)
+//
+// With SetSessionEncryptionKey the version is 2 and the data blocks are
+// replaced by a 12-byte nonce and their AES-GCM sealed form, so the header
+// stays readable for expiry and touches.
+//
+// Each map value starts with its ApplicationValueType byte. Stored Dictionary,
+// Collection, JScript object and Recordset values write Num as int64, then a
+// uint32 count of Keys and a uint32 count of Arr, each followed by the values.
 func (s *Session) Save() error {
 	s.mu.RLock()
 	version := s.version
@@ -492,6 +601,7 @@ func (s *Session) Save() error {
 	timeout := int16(s.Timeout)
 	createdAt := s.CreatedAt.Unix()
 	lastAccessed := s.LastAccessed.Unix()
+	appKey := s.appKey
 
 	if lcid == 0 {
 		lcid = uint16(resolveDefaultSessionLCID())
@@ -504,10 +614,6 @@ func (s *Session) Save() error {
 	}
 	s.mu.RUnlock()
 
-	if err := os.MkdirAll(sessionStorageDir, 0o755); err != nil {
-		return err
-	}
-
 	buf := sessionBufferPool.Get().(*bytes.Buffer)
 	buf.Reset()
 	defer sessionBufferPool.Put(buf)
@@ -543,48 +649,113 @@ func (s *Session) Save() error {
 	buf.Write(staticBuf.Bytes())
 	sessionBufferPool.Put(staticBuf)
 
-	if err := os.WriteFile(sessionFilePath(id), buf.Bytes(), 0o600); err != nil {
+	// Block 3: Application key
+	buf.WriteByte(0x1E)
+	writeUint32(buf, uint32(len(appKey)))
+	buf.WriteString(appKey)
+
+	encoded := buf.Bytes()
+	if aead := currentSessionAEAD(); aead != nil {
+		encoded[6] = sessionEncryptedVersion
+		sealed, err := sealSessionBlocks(aead, append([]byte(nil), encoded[:55]...), sessionBlocksAD(encoded, id), encoded[55:])
+		if err != nil {
+			return err
+		}
+		encoded = sealed
+	}
+
+	record := SessionRecord{
+		ID:           id,
+		Data:         encoded,
+		LastAccessed: time.Unix(lastAccessed, 0),
+		Timeout:      time.Duration(timeout) * time.Minute,
+	}
+	if err := CurrentSessionStore().Save(record); err != nil {
 		return err
 	}
 
 	s.mu.Lock()
+	// Regenerate ran during the write, which stored the contents under the
+	// old ID again. Remove them; the new ID is saved by the next write.
+	if s.ID != id {
+		s.mu.Unlock()
+		_, err := CurrentSessionStore().Delete(id)
+		return err
+	}
 	if s.version == version {
 		s.dirty = false
 		s.lastSavedVersion = version
 	}
+	s.stored = true
+	s.storedAccess = record.LastAccessed
 	s.mu.Unlock()
 	return nil
 }
 
-// Delete removes persisted session from disk.
+// Delete removes the persisted session from the session store and runs its
+// Session_OnEnd handler. A session that was stored only ends in the process
+// that removed it from the store, so the handler runs at most once even when
+// several flushers or processes expire the same session.
 func (s *Session) Delete() error {
-	err := os.Remove(sessionFilePath(s.ID))
-	if err != nil && !os.IsNotExist(err) {
+	id := s.GetID()
+	deleted, err := CurrentSessionStore().Delete(id)
+	if err != nil {
 		return err
 	}
-	unregisterSession(s.ID)
+	unregisterSession(id)
+
+	s.mu.RLock()
+	stored := s.stored
+	s.mu.RUnlock()
+	if deleted || !stored {
+		s.end()
+	}
 	return nil
 }
 
-// LoadSession loads a persisted binary session by ID.
+// LoadSession loads a persisted binary session by ID from the session store
+// and records the access in the store.
 func LoadSession(sessionID string) (*Session, bool, error) {
-	data, err := os.ReadFile(sessionFilePath(sessionID))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, false, nil
-		}
+	store := CurrentSessionStore()
+	record, found, err := store.Load(sessionID)
+	if err != nil || !found {
 		return nil, false, err
 	}
 
-	if len(data) < 55 || string(data[0:6]) != "G3SES\x00" {
+	s := decodeSession(sessionID, record.Data)
+	if s == nil {
 		return nil, false, nil
 	}
+	if record.LastAccessed.After(s.LastAccessed) {
+		s.LastAccessed = record.LastAccessed
+	}
 
-	ver := data[6]
-	if ver != 1 {
+	s.stored = true
+	if s.IsExpired() {
+		_ = s.Delete()
 		return nil, false, nil
 	}
 
+	s.Touch()
+	if err := store.Touch(sessionID, s.LastAccessed); err != nil {
+		return nil, false, err
+	}
+	s.storedAccess = s.LastAccessed
+	return s, true, nil
+}
+
+// decodeSession parses one G3SES record, or returns nil when data is not a
+// supported G3SES record.
+func decodeSession(sessionID string, data []byte) *Session {
+	if len(data) < 55 || string(data[0:6]) != "G3SES\x00" {
+		return nil
+	}
+
+	ver := data[6]
+	if ver != 1 && ver != sessionEncryptedVersion {
+		return nil
+	}
+
 	createdAt := int64(binary.LittleEndian.Uint64(data[31:39]))
 	lastAccessed := int64(binary.LittleEndian.Uint64(data[39:47]))
 	timeout := int16(binary.LittleEndian.Uint16(data[47:49]))
@@ -598,7 +769,15 @@ func LoadSession(sessionID string) (*Session, bool, error) {
 	s.LCID = lcid
 	s.CodePage = codePage
 
-	r := bytes.NewReader(data[55:])
+	blocks := data[55:]
+	if ver == sessionEncryptedVersion {
+		opened, err := openSessionBlocks(currentSessionAEAD(), sessionBlocksAD(data, sessionID), blocks)
+		if err != nil {
+			return nil
+		}
+		blocks = opened
+	}
+	r := bytes.NewReader(blocks)
 
 	// Read Data Block
 	if sep, _ := r.ReadByte(); sep == 0x1E {
@@ -614,16 +793,18 @@ func LoadSession(sessionID string) (*Session, bool, error) {
 		s.staticObjects = deserializeApplicationMap(r)
 	}
 
-	s.dirty = false
-	s.version = 0
-
-	if s.IsExpired() {
-		_ = s.Delete()
-		return nil, false, nil
+	// Read Application Key Block
+	if sep, err := r.ReadByte(); err == nil && sep == 0x1E {
+		if length := int(readUint32(r)); length <= r.Len() {
+			key := make([]byte, length)
+			_, _ = r.Read(key)
+			s.appKey = string(key)
+		}
 	}
 
-	s.Touch()
-	return s, true, nil
+	s.dirty = false
+	s.version = 0
+	return s
 }
 
 // CreateSession creates a brand-new session and persists it asynchronously.
@@ -635,10 +816,13 @@ func CreateSession() (*Session, error) {
 	return registerSession(session), nil
 }
 
-// GetOrCreateSession loads an existing session or creates a new one.
+// GetOrCreateSession loads an existing session or creates a new one. With a
+// shared session store the session is always read from the store, because
+// another node may have changed it since this process last saw it.
 func GetOrCreateSession(sessionID string) (*Session, bool, error) {
+	shared := SessionStoreShared()
 	normalizedID := strings.TrimSpace(sessionID)
-	if normalizedID != "" {
+	if normalizedID != "" && !shared {
 		if existing := getRegisteredSession(normalizedID); existing != nil {
 			existing.Touch()
 			return existing, false, nil
@@ -651,6 +835,9 @@ func GetOrCreateSession(sessionID string) (*Session, bool, error) {
 			return nil, false, err
 		}
 		if found {
+			if shared {
+				return replaceRegisteredSession(session), false, nil
+			}
 			return registerSession(session), false, nil
 		}
 	}
@@ -672,15 +859,35 @@ func getRegisteredSession(sessionID string) *Session {
 }
 
 func registerSession(session *Session) *Session {
-	if session == nil || strings.TrimSpace(session.ID) == "" {
+	if session == nil {
+		return session
+	}
+	id := session.GetID()
+	if strings.TrimSpace(id) == "" {
 		return session
 	}
 	sessionRegistryMu.Lock()
 	defer sessionRegistryMu.Unlock()
-	if existing := sessionRegistry[session.ID]; existing != nil {
+	if existing := sessionRegistry[id]; existing != nil {
 		return existing
 	}
-	sessionRegistry[session.ID] = session
+	sessionRegistry[id] = session
+	return session
+}
+
+// replaceRegisteredSession registers session in place of any older copy of
+// the same session.
+func replaceRegisteredSession(session *Session) *Session {
+	if session == nil {
+		return session
+	}
+	id := session.GetID()
+	if strings.TrimSpace(id) == "" {
+		return session
+	}
+	sessionRegistryMu.Lock()
+	defer sessionRegistryMu.Unlock()
+	sessionRegistry[id] = session
 	return session
 }
 
@@ -750,13 +957,31 @@ func StopSessionAutoFlush() {
 
 // FlushRegisteredSessions persists registered sessions.
 func FlushRegisteredSessions(force bool) error {
+	start := time.Now()
+	err := flushRegisteredSessions(force)
+	elapsed := time.Since(start)
+	sessionFlushCount.Add(1)
+	sessionFlushNanos.Add(int64(elapsed))
+	sessionFlushLast.Store(int64(elapsed))
+	if err != nil {
+		sessionFlushErrors.Add(1)
+	}
+	return err
+}
+
+// flushRegisteredSessions saves or deletes every registered session and removes
+// expired sessions from the store. With a shared store a session that expired in
+// this process may still be in use on another node, so it is only unregistered,
+// and only pending changes are saved so a stale copy never overwrites newer data.
+func flushRegisteredSessions(force bool) error {
+	shared := SessionStoreShared()
 	sessionRegistryMu.RLock()
 	sessions := make([]*Session, 0, len(sessionRegistry))
 	registeredIDs := make(map[string]struct{}, len(sessionRegistry))
-	for _, session := range sessionRegistry {
+	for id, session := range sessionRegistry {
 		sessions = append(sessions, session)
 		if session != nil {
-			registeredIDs[session.ID] = struct{}{}
+			registeredIDs[id] = struct{}{}
 		}
 	}
 	sessionRegistryMu.RUnlock()
@@ -768,6 +993,10 @@ func FlushRegisteredSessions(force bool) error {
 			continue
 		}
 
+		if shared && !session.IsAbandoned() && session.isExpiredAt(now) {
+			unregisterSession(session.GetID())
+			continue
+		}
 		if session.IsAbandoned() || session.isExpiredAt(now) {
 			err := session.Delete()
 			if err != nil && firstErr == nil {
@@ -777,70 +1006,95 @@ func FlushRegisteredSessions(force bool) error {
 		}
 
 		var err error
-		if force {
+		if force && !shared {
 			err = session.Save()
 		} else {
 			err = session.SaveIfDirty()
 		}
+		if err == nil {
+			err = session.touchStoreIfAccessed()
+		}
 		if err != nil && firstErr == nil {
 			firstErr = err
 		}
 	}
 
-	if err := cleanupExpiredSessionFilesOnDisk(now, registeredIDs); err != nil && firstErr == nil {
+	if err := cleanupExpiredStoredSessions(now, registeredIDs); err != nil && firstErr == nil {
 		firstErr = err
 	}
 
 	return firstErr
 }
 
-// cleanupExpiredSessionFilesOnDisk removes expired persisted sessions that are not registered in memory.
-func cleanupExpiredSessionFilesOnDisk(now time.Time, registeredIDs map[string]struct{}) error {
-	entries, err := os.ReadDir(sessionStorageDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
+// CleanupExpiredSessionFiles removes the expired sessions of the session store
+// that no session of this process holds. Processes that exit after each request call it instead
+// of running StartSessionAutoFlush.
+func CleanupExpiredSessionFiles() error {
+	sessionRegistryMu.RLock()
+	registeredIDs := make(map[string]struct{}, len(sessionRegistry))
+	for id := range sessionRegistry {
+		registeredIDs[id] = struct{}{}
 	}
-
-	var firstErr error
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		name := entry.Name()
-		if !strings.HasSuffix(name, ".g3ses") {
-			continue
-		}
-
-		sessionID := strings.TrimSuffix(name, ".g3ses")
+	sessionRegistryMu.RUnlock()
+	return cleanupExpiredStoredSessions(time.Now(), registeredIDs)
+}
+
+// cleanupExpiredStoredSessions deletes the expired sessions of the session
+// store that are not registered in memory. When Session_OnEnd handlers are
+// registered, each session is read before it is deleted, and its handler runs
+// only in the process whose delete removed it.
+func cleanupExpiredStoredSessions(now time.Time, registeredIDs map[string]struct{}) error {
+	store := CurrentSessionStore()
+	expiredIDs, firstErr := store.ListExpired(now)
+	withHandlers := hasSessionEndHandlers()
+	for _, sessionID := range expiredIDs {
 		if _, ok := registeredIDs[sessionID]; ok {
 			continue
 		}
 
-		path := filepath.Join(sessionStorageDir, name)
-		expired, checkErr := persistedSessionFileExpired(path, now)
-		if checkErr != nil {
-			if firstErr == nil {
-				firstErr = checkErr
+		var expired *Session
+		if withHandlers {
+			if record, found, err := store.Load(sessionID); err == nil && found {
+				expired = decodeSession(sessionID, record.Data)
 			}
-			continue
-		}
-		if !expired {
-			continue
 		}
-
-		if removeErr := os.Remove(path); removeErr != nil && !os.IsNotExist(removeErr) {
+		deleted, err := store.Delete(sessionID)
+		if err != nil {
 			if firstErr == nil {
-				firstErr = removeErr
+				firstErr = err
 			}
+			continue
+		}
+		if deleted && expired != nil {
+			expired.end()
 		}
 	}
-
 	return firstErr
 }
 
+// touchStoreIfAccessed records in the store an access that did not change the
+// session, so other processes do not reap a session that is still in use.
+func (s *Session) touchStoreIfAccessed() error {
+	s.mu.RLock()
+	stored := s.stored
+	lastAccessed := s.LastAccessed
+	storedAccess := s.storedAccess
+	s.mu.RUnlock()
+
+	if !stored || lastAccessed.Unix() <= storedAccess.Unix() {
+		return nil
+	}
+	if err := CurrentSessionStore().Touch(s.GetID(), lastAccessed); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if lastAccessed.After(s.storedAccess) {
+		s.storedAccess = lastAccessed
+	}
+	s.mu.Unlock()
+	return nil
+}
+
 // persistedSessionFileExpired checks expiration using only timeout and last access fields from binary header.
 func persistedSessionFileExpired(path string, now time.Time) (bool, error) {
 	file, err := os.Open(path)
@@ -1027,7 +1281,23 @@ func serializeApplicationValue(buf *bytes.Buffer, v ApplicationValue) {
 		buf.WriteString(v.Str)
 		writeUint32(buf, uint32(len(v.Interface)))
 		buf.WriteString(v.Interface)
-	case ApplicationValueNothing:
+	case ApplicationValueDate:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(v.Num))
+		buf.Write(b[:])
+	case ApplicationValueDictionary, ApplicationValueCollection, ApplicationValueJSPlainObject, ApplicationValueRecordset:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(v.Num))
+		buf.Write(b[:])
+		writeUint32(buf, uint32(len(v.Keys)))
+		for i := range v.Keys {
+			serializeApplicationValue(buf, v.Keys[i])
+		}
+		writeUint32(buf, uint32(len(v.Arr)))
+		for i := range v.Arr {
+			serializeApplicationValue(buf, v.Arr[i])
+		}
+	case ApplicationValueNothing, ApplicationValueNull:
 		// No extra bytes needed
 	}
 }
@@ -1080,8 +1350,35 @@ func deserializeApplicationValue(r *bytes.Reader) (ApplicationValue, error) {
 			_, _ = r.Read(iface)
 			v.Interface = string(iface)
 		}
-	case ApplicationValueNothing:
+	case ApplicationValueDate:
+		v.Num = int64(readUint64(r))
+	case ApplicationValueDictionary, ApplicationValueCollection, ApplicationValueJSPlainObject, ApplicationValueRecordset:
+		v.Num = int64(readUint64(r))
+		if v.Keys, err = deserializeApplicationValues(r); err != nil {
+			return v, err
+		}
+		if v.Arr, err = deserializeApplicationValues(r); err != nil {
+			return v, err
+		}
+	case ApplicationValueNothing, ApplicationValueNull:
 		// No extra bytes needed
 	}
 	return v, nil
 }
+
+// deserializeApplicationValues reads a uint32 count followed by that many values.
+func deserializeApplicationValues(r *bytes.Reader) ([]ApplicationValue, error) {
+	l := readUint32(r)
+	if int64(l) > int64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	values := make([]ApplicationValue, l)
+	for i := range values {
+		elem, err := deserializeApplicationValue(r)
+		if err != nil {
+			return values, err
+		}
+		values[i] = elem
+	}
+	return values, nil
+}
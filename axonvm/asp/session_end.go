@@ -0,0 +1,126 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"log"
+	"maps"
+	"sync"
+)
+
+// SessionEndHandler runs Session_OnEnd for one session that was abandoned or
+// expired. The session holds the contents it had when it ended.
+type SessionEndHandler func(session *Session)
+
+var (
+	sessionEndHandlersMu sync.RWMutex
+	sessionEndHandlers   = make(map[string]SessionEndHandler)
+)
+
+// RegisterSessionEndHandler runs handler when a session of the application
+// appKey ends. A later registration for the same key replaces the handler.
+func RegisterSessionEndHandler(appKey string, handler SessionEndHandler) {
+	sessionEndHandlersMu.Lock()
+	defer sessionEndHandlersMu.Unlock()
+	if handler == nil {
+		delete(sessionEndHandlers, appKey)
+		return
+	}
+	sessionEndHandlers[appKey] = handler
+}
+
+// UnregisterSessionEndHandler removes the handler of the application appKey.
+func UnregisterSessionEndHandler(appKey string) {
+	RegisterSessionEndHandler(appKey, nil)
+}
+
+// hasSessionEndHandlers reports whether any application handles session ends.
+func hasSessionEndHandlers() bool {
+	sessionEndHandlersMu.RLock()
+	defer sessionEndHandlersMu.RUnlock()
+	return len(sessionEndHandlers) > 0
+}
+
+// SetApplicationKey records the application that owns the session. Its
+// Session_OnEnd handler runs when the session ends, in any process that
+// shares the session store.
+func (s *Session) SetApplicationKey(appKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appKey = appKey
+}
+
+// ApplicationKey returns the application that owns the session.
+func (s *Session) ApplicationKey() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.appKey
+}
+
+// end runs the Session_OnEnd handler of the session application once. The
+// handler receives a copy with the contents the session had before Abandon, so
+// it never changes a session that another request still holds.
+func (s *Session) end() {
+	if !s.ended.CompareAndSwap(false, true) {
+		return
+	}
+
+	s.mu.RLock()
+	appKey := s.appKey
+	s.mu.RUnlock()
+	sessionEndHandlersMu.RLock()
+	handler := sessionEndHandlers[appKey]
+	sessionEndHandlersMu.RUnlock()
+	if handler == nil {
+		return
+	}
+
+	ended := s.endSnapshot()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Warning: Session_OnEnd for session %s failed: %v\n", ended.ID, r)
+		}
+	}()
+	handler(ended)
+}
+
+// endSnapshot copies the session for its Session_OnEnd handler.
+func (s *Session) endSnapshot() *Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := s.data
+	if s.abandoned && s.abandonedData != nil {
+		data = s.abandonedData
+	}
+	snapshot := NewSessionWithID(s.ID)
+	snapshot.data = maps.Clone(data)
+	snapshot.staticObjects = maps.Clone(s.staticObjects)
+	snapshot.LCID = s.LCID
+	snapshot.CodePage = s.CodePage
+	snapshot.Timeout = s.Timeout
+	snapshot.CreatedAt = s.CreatedAt
+	snapshot.LastAccessed = s.LastAccessed
+	snapshot.appKey = s.appKey
+	snapshot.dirty = false
+	snapshot.ended.Store(true)
+	return snapshot
+}
@@ -0,0 +1,148 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas GuimarÃ£es - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// resetSessionEndTest isolates the session storage, registry and handlers of one test.
+func resetSessionEndTest(t *testing.T) {
+	t.Helper()
+	SetSessionStorageDir(filepath.Join(t.TempDir(), "session"))
+	sessionRegistryMu.Lock()
+	sessionRegistry = make(map[string]*Session)
+	sessionRegistryMu.Unlock()
+	t.Cleanup(func() {
+		SetSessionStorageDir("")
+		sessionEndHandlersMu.Lock()
+		sessionEndHandlers = make(map[string]SessionEndHandler)
+		sessionEndHandlersMu.Unlock()
+	})
+}
+
+// TestSessionEndRunsOnceWithAbandonedContents verifies Abandon hands the previous contents to Session_OnEnd exactly once.
+func TestSessionEndRunsOnceWithAbandonedContents(t *testing.T) {
+	resetSessionEndTest(t)
+
+	var calls atomic.Int32
+	var cart string
+	RegisterSessionEndHandler("/app", func(ended *Session) {
+		calls.Add(1)
+		if value, ok := ended.Get("Cart"); ok {
+			cart = value.Str
+		}
+	})
+
+	session := NewSessionWithID("end-abandon")
+	session.SetApplicationKey("/app")
+	session.Set("Cart", NewApplicationString("book"))
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	registerSession(session)
+	session.Abandon()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = FlushRegisteredSessions(false)
+		}()
+	}
+	wg.Wait()
+	if err := session.Delete(); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected Session_OnEnd to run once, ran %d times", calls.Load())
+	}
+	if cart != "book" {
+		t.Fatalf("expected the contents before Abandon, got %q", cart)
+	}
+	if session.Count() != 0 {
+		t.Fatalf("expected the abandoned session to stay empty")
+	}
+}
+
+// TestSessionEndRunsForSessionReapedFromStore verifies an expired stored session runs the handler of the application saved with it.
+func TestSessionEndRunsForSessionReapedFromStore(t *testing.T) {
+	resetSessionEndTest(t)
+
+	expired := NewSessionWithID("end-reaped")
+	expired.SetApplicationKey("/app")
+	expired.SetTimeout(1)
+	expired.Set("User", NewApplicationString("ana"))
+	expired.mu.Lock()
+	expired.LastAccessed = time.Now().Add(-2 * time.Minute)
+	expired.mu.Unlock()
+	if err := expired.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	var ended []*Session
+	RegisterSessionEndHandler("/app", func(session *Session) {
+		ended = append(ended, session)
+	})
+	RegisterSessionEndHandler("/other", func(session *Session) {
+		t.Errorf("unexpected Session_OnEnd of another application for %s", session.ID)
+	})
+
+	for range 2 {
+		if err := FlushRegisteredSessions(false); err != nil {
+			t.Fatalf("FlushRegisteredSessions returned error: %v", err)
+		}
+	}
+
+	if len(ended) != 1 {
+		t.Fatalf("expected Session_OnEnd to run once, ran %d times", len(ended))
+	}
+	if ended[0].ID != expired.ID || ended[0].ApplicationKey() != "/app" {
+		t.Fatalf("unexpected ended session %s of %q", ended[0].ID, ended[0].ApplicationKey())
+	}
+	if user, ok := ended[0].Get("User"); !ok || user.Str != "ana" {
+		t.Fatalf("expected the stored contents, got %#v", user)
+	}
+}
+
+// TestSessionEndHandlerPanicIsContained verifies a failing handler does not stop the flush.
+func TestSessionEndHandlerPanicIsContained(t *testing.T) {
+	resetSessionEndTest(t)
+
+	RegisterSessionEndHandler("/app", func(*Session) { panic("boom") })
+	session := NewSessionWithID("end-panic")
+	session.SetApplicationKey("/app")
+	registerSession(session)
+	session.Abandon()
+
+	if err := FlushRegisteredSessions(false); err != nil {
+		t.Fatalf("FlushRegisteredSessions returned error: %v", err)
+	}
+	if getRegisteredSession(session.ID) != nil {
+		t.Fatalf("expected the abandoned session to be unregistered")
+	}
+}
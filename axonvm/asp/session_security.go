@@ -0,0 +1,160 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// sessionEncryptedVersion is the G3SES version of records whose data blocks
+// are sealed with AES-GCM. The header stays readable so stores can expire and
+// touch sessions without the key.
+const sessionEncryptedVersion = 2
+
+var (
+	sessionSecurityMu sync.RWMutex
+	sessionSigningKey []byte
+	sessionAEAD       cipher.AEAD
+)
+
+// SetSessionSigningKey makes hosts sign the session ID sent in the session
+// cookie with HMAC-SHA256 under key, and reject cookies without a valid
+// signature. An empty key sends the plain session ID.
+func SetSessionSigningKey(key string) {
+	sessionSecurityMu.Lock()
+	defer sessionSecurityMu.Unlock()
+	if key == "" {
+		sessionSigningKey = nil
+		return
+	}
+	sessionSigningKey = []byte(key)
+}
+
+// SetSessionEncryptionKey makes Session.Save seal the session contents with
+// AES-256-GCM under a key derived from key with SHA-256. An empty key stores
+// new sessions in plain text. Sessions saved before the key was set are still
+// read and are encrypted on their next save.
+func SetSessionEncryptionKey(key string) error {
+	if key == "" {
+		sessionSecurityMu.Lock()
+		sessionAEAD = nil
+		sessionSecurityMu.Unlock()
+		return nil
+	}
+	derived := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	sessionSecurityMu.Lock()
+	sessionAEAD = aead
+	sessionSecurityMu.Unlock()
+	return nil
+}
+
+// SessionCookieValue returns the session cookie value of sessionID, signed
+// when a signing key is set.
+func SessionCookieValue(sessionID string) string {
+	sessionSecurityMu.RLock()
+	key := sessionSigningKey
+	sessionSecurityMu.RUnlock()
+	if key == nil || sessionID == "" {
+		return sessionID
+	}
+	return sessionID + "." + sessionIDSignature(key, sessionID)
+}
+
+// SessionIDFromCookie returns the session ID held in a session cookie value.
+// When a signing key is set, ok is false for values without a valid signature.
+func SessionIDFromCookie(value string) (sessionID string, ok bool) {
+	value = strings.TrimSpace(value)
+	sessionSecurityMu.RLock()
+	key := sessionSigningKey
+	sessionSecurityMu.RUnlock()
+	if key == nil {
+		return value, value != ""
+	}
+	sessionID, signature, found := strings.Cut(value, ".")
+	if !found || sessionID == "" {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signature), []byte(sessionIDSignature(key, sessionID))) {
+		return "", false
+	}
+	return sessionID, true
+}
+
+// sessionIDSignature returns the unpadded base64url HMAC-SHA256 of sessionID.
+func sessionIDSignature(key []byte, sessionID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// currentSessionAEAD returns the cipher of SetSessionEncryptionKey, or nil.
+func currentSessionAEAD() cipher.AEAD {
+	sessionSecurityMu.RLock()
+	defer sessionSecurityMu.RUnlock()
+	return sessionAEAD
+}
+
+// sessionBlocksAD returns the data authenticated with the sealed blocks: the
+// magic and version of the header and the full session ID, so a record cannot
+// be moved to another session.
+func sessionBlocksAD(header []byte, sessionID string) []byte {
+	ad := make([]byte, 0, 7+len(sessionID))
+	ad = append(ad, header[:7]...)
+	return append(ad, sessionID...)
+}
+
+// sealSessionBlocks appends the nonce and the AES-GCM sealed blocks to dst.
+func sealSessionBlocks(aead cipher.AEAD, dst, ad, blocks []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	dst = append(dst, nonce...)
+	return aead.Seal(dst, nonce, blocks, ad), nil
+}
+
+// openSessionBlocks reverses sealSessionBlocks.
+func openSessionBlocks(aead cipher.AEAD, ad, sealed []byte) ([]byte, error) {
+	if aead == nil {
+		return nil, errors.New("encrypted session without an encryption key")
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("truncated encrypted session")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, ad)
+}
@@ -0,0 +1,162 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas GuimarÃ£es - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestSessionEncryptionAtRest verifies that an encryption key seals the stored
+// contents, that sealed records only open under their own ID and key, and that
+// plain records saved before the key was set still load.
+func TestSessionEncryptionAtRest(t *testing.T) {
+	resetSessionEndTest(t)
+	t.Cleanup(func() { _ = SetSessionEncryptionKey("") })
+
+	plain, _ := CreateSession()
+	plain.Set("note", NewApplicationString("written before the key"))
+	if err := plain.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	if err := SetSessionEncryptionKey("correct horse battery staple"); err != nil {
+		t.Fatalf("SetSessionEncryptionKey returned error: %v", err)
+	}
+	session, _ := CreateSession()
+	session.Set("card", NewApplicationString("4111-1111-1111-1111"))
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(sessionFilePath(session.ID))
+	if err != nil {
+		t.Fatalf("read session file: %v", err)
+	}
+	if data[6] != sessionEncryptedVersion || bytes.Contains(data, []byte("4111-1111")) {
+		t.Fatalf("expected an encrypted G3SES record, got version %d", data[6])
+	}
+	if expired, err := persistedSessionFileExpired(sessionFilePath(session.ID), session.LastAccessed); err != nil || expired {
+		t.Fatalf("expected the header to stay readable, expired=%v err=%v", expired, err)
+	}
+
+	loaded := decodeSession(session.ID, data)
+	if loaded == nil {
+		t.Fatalf("expected the encrypted record to load")
+	}
+	if card, ok := loaded.Get("card"); !ok || card.Str != "4111-1111-1111-1111" {
+		t.Fatalf("unexpected decrypted value %#v", card)
+	}
+	if moved := decodeSession(strings.Repeat("B", 24), data); moved != nil {
+		t.Fatalf("expected a record copied to another session ID to be rejected")
+	}
+	if legacy, found, err := LoadSession(plain.ID); err != nil || !found {
+		t.Fatalf("expected the plain record to load, found=%v err=%v", found, err)
+	} else if note, _ := legacy.Get("note"); note.Str != "written before the key" {
+		t.Fatalf("unexpected plain value %#v", note)
+	}
+
+	if err := SetSessionEncryptionKey("another key"); err != nil {
+		t.Fatalf("SetSessionEncryptionKey returned error: %v", err)
+	}
+	if decodeSession(session.ID, data) != nil {
+		t.Fatalf("expected the record to be rejected under another key")
+	}
+}
+
+// TestSessionCookieSignature verifies signed cookie values and the rejection
+// of unsigned or forged ones.
+func TestSessionCookieSignature(t *testing.T) {
+	t.Cleanup(func() { SetSessionSigningKey("") })
+	const id = "ABCDEFGHIJKLMNOPQRSTUVWX"
+
+	if value := SessionCookieValue(id); value != id {
+		t.Fatalf("expected the plain ID without a signing key, got %q", value)
+	}
+	if got, ok := SessionIDFromCookie(" " + id + " "); !ok || got != id {
+		t.Fatalf("expected %q, got %q ok=%v", id, got, ok)
+	}
+
+	SetSessionSigningKey("cookie secret")
+	value := SessionCookieValue(id)
+	if !strings.HasPrefix(value, id+".") {
+		t.Fatalf("expected a signed value, got %q", value)
+	}
+	if got, ok := SessionIDFromCookie(value); !ok || got != id {
+		t.Fatalf("expected the signed value to verify, got %q ok=%v", got, ok)
+	}
+	for _, forged := range []string{id, "ZZZZZZZZZZZZZZZZZZZZZZZZ" + value[len(id):], value + "x", "." + value[len(id)+1:]} {
+		if got, ok := SessionIDFromCookie(forged); ok {
+			t.Fatalf("expected %q to be rejected, got %q", forged, got)
+		}
+	}
+}
+
+// TestSessionRegenerateMovesContents verifies that Regenerate keeps the
+// contents under a new ID, removes the old record and does not end the session.
+func TestSessionRegenerateMovesContents(t *testing.T) {
+	resetSessionEndTest(t)
+	ended := 0
+	RegisterSessionEndHandler("app", func(*Session) { ended++ })
+
+	session, _ := CreateSession()
+	session.SetApplicationKey("app")
+	session.Set("user", NewApplicationString("ana"))
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	oldID := session.ID
+
+	if err := session.Regenerate(); err != nil {
+		t.Fatalf("Regenerate returned error: %v", err)
+	}
+	if session.ID == oldID || len(session.ID) != 24 {
+		t.Fatalf("expected a new 24 character ID, got %q", session.ID)
+	}
+	if _, found, _ := CurrentSessionStore().Load(oldID); found {
+		t.Fatalf("expected the record of the old ID to be removed")
+	}
+	if getRegisteredSession(oldID) != nil || getRegisteredSession(session.ID) != session {
+		t.Fatalf("expected the registry to hold the session under its new ID only")
+	}
+	if !session.IsDirty() {
+		t.Fatalf("expected the regenerated session to need a save")
+	}
+	if err := session.SaveIfDirty(); err != nil {
+		t.Fatalf("SaveIfDirty returned error: %v", err)
+	}
+	loaded, found, err := LoadSession(session.ID)
+	if err != nil || !found {
+		t.Fatalf("expected the new ID to load, found=%v err=%v", found, err)
+	}
+	if user, _ := loaded.Get("user"); user.Str != "ana" {
+		t.Fatalf("expected the contents to move, got %#v", user)
+	}
+
+	if session2, isNew, _ := GetOrCreateSession(oldID); !isNew || session2 == session {
+		t.Fatalf("expected the old ID to start a new session")
+	}
+	if ended != 0 {
+		t.Fatalf("expected Regenerate not to run Session_OnEnd, ran %d times", ended)
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionRecord is one persisted session as a SessionStore keeps it.
+type SessionRecord struct {
+	ID string
+	// Data is the G3SES encoding of the session written by Session.Save.
+	Data []byte
+	// LastAccessed is the most recent access known to the store. It can be
+	// newer than the time inside Data when the store was only touched.
+	LastAccessed time.Time
+	// Timeout is the idle time after which the session expires.
+	Timeout time.Duration
+}
+
+// SessionStore persists sessions between requests. The file store is the
+// default; SetSessionStore installs another backend for every host.
+type SessionStore interface {
+	// Load returns the record of id. found is false when the store does not hold it.
+	Load(id string) (record SessionRecord, found bool, err error)
+	// Save inserts or replaces record. The store must not keep record.Data
+	// after Save returns.
+	Save(record SessionRecord) error
+	// Delete removes id and reports whether this call removed it. Deleting a
+	// missing session is not an error. Session_OnEnd runs only for the caller
+	// that removed a stored session, so the report must hold across processes.
+	Delete(id string) (deleted bool, err error)
+	// Touch updates the last access time of id without rewriting its data.
+	Touch(id string, lastAccessed time.Time) error
+	// ListExpired returns the IDs of the stored sessions idle for longer than
+	// their timeout at now.
+	ListExpired(now time.Time) ([]string, error)
+}
+
+// SharedSessionStore is implemented by stores that several processes or
+// nodes use at once. With a shared store every request reads the session from
+// the store and writes it back before the response ends, so a session is not
+// tied to the node that created it.
+type SharedSessionStore interface {
+	SessionStore
+	Shared() bool
+}
+
+var (
+	sessionStoreMu     sync.RWMutex
+	sessionStore       SessionStore = fileSessionStore{}
+	sessionStoreShared bool
+)
+
+// SetSessionStore installs the session store used by every host. A nil store
+// restores the default file store in the session storage directory.
+func SetSessionStore(store SessionStore) {
+	shared := false
+	if store == nil {
+		store = fileSessionStore{}
+	} else if s, ok := store.(SharedSessionStore); ok {
+		shared = s.Shared()
+	}
+	sessionStoreMu.Lock()
+	sessionStore = store
+	sessionStoreShared = shared
+	sessionStoreMu.Unlock()
+}
+
+// CurrentSessionStore returns the installed session store.
+func CurrentSessionStore() SessionStore {
+	sessionStoreMu.RLock()
+	defer sessionStoreMu.RUnlock()
+	return sessionStore
+}
+
+// SessionStoreShared reports whether the installed store is shared with other
+// processes, in which case hosts save sessions before the response ends.
+func SessionStoreShared() bool {
+	sessionStoreMu.RLock()
+	defer sessionStoreMu.RUnlock()
+	return sessionStoreShared
+}
+
+// fileSessionStore keeps one .g3ses file per session in sessionStorageDir.
+type fileSessionStore struct{}
+
+// Load implements SessionStore.
+func (fileSessionStore) Load(id string) (SessionRecord, bool, error) {
+	data, err := os.ReadFile(sessionFilePath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SessionRecord{}, false, nil
+		}
+		return SessionRecord{}, false, err
+	}
+	return SessionRecord{ID: id, Data: data}, true, nil
+}
+
+// Save implements SessionStore.
+func (fileSessionStore) Save(record SessionRecord) error {
+	if err := os.MkdirAll(sessionStorageDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(sessionFilePath(record.ID), record.Data, 0o600)
+}
+
+// Delete implements SessionStore.
+func (fileSessionStore) Delete(id string) (bool, error) {
+	err := os.Remove(sessionFilePath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Touch implements SessionStore by rewriting the last access field of the header.
+func (fileSessionStore) Touch(id string, lastAccessed time.Time) error {
+	file, err := os.OpenFile(sessionFilePath(id), os.O_WRONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	var field [8]byte
+	binary.LittleEndian.PutUint64(field[:], uint64(lastAccessed.Unix()))
+	_, err = file.WriteAt(field[:], 39)
+	return err
+}
+
+// ListExpired implements SessionStore. Corrupt files count as expired. It
+// keeps scanning after an unreadable file and returns the first error with
+// the IDs it found.
+func (fileSessionStore) ListExpired(now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(sessionStorageDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var expiredIDs []string
+	var firstErr error
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".g3ses") {
+			continue
+		}
+
+		expired, checkErr := persistedSessionFileExpired(filepath.Join(sessionStorageDir, name), now)
+		if checkErr != nil {
+			if firstErr == nil {
+				firstErr = checkErr
+			}
+			continue
+		}
+		if expired {
+			expiredIDs = append(expiredIDs, strings.TrimSuffix(name, ".g3ses"))
+		}
+	}
+	return expiredIDs, firstErr
+}
@@ -355,3 +355,91 @@ func TestFlushRegisteredSessionsRemovesExpiredOrphanedDiskSession(t *testing.T)
 		t.Fatalf("expected active orphaned session file to remain, got err=%v", err)
 	}
 }
+
+// TestFileSessionStoreTouchAndListExpired verifies the default store refreshes the header access time and reports expired files.
+func TestFileSessionStoreTouchAndListExpired(t *testing.T) {
+	tempDir := t.TempDir()
+	SetSessionStorageDir(filepath.Join(tempDir, "session"))
+	t.Cleanup(func() { SetSessionStorageDir("") })
+
+	store := CurrentSessionStore()
+	if SessionStoreShared() {
+		t.Fatalf("expected the file store not to be shared")
+	}
+
+	session := NewSessionWithID("file-store-touch")
+	session.SetTimeout(1)
+	session.mu.Lock()
+	session.LastAccessed = time.Now().Add(-2 * time.Minute)
+	session.mu.Unlock()
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	expired, err := store.ListExpired(time.Now())
+	if err != nil || len(expired) != 1 || expired[0] != session.ID {
+		t.Fatalf("expected %s to be expired, got %v err=%v", session.ID, expired, err)
+	}
+
+	if err := store.Touch(session.ID, time.Now()); err != nil {
+		t.Fatalf("Touch returned error: %v", err)
+	}
+	expired, err = store.ListExpired(time.Now())
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("expected no expired sessions after Touch, got %v err=%v", expired, err)
+	}
+
+	loaded, found, err := LoadSession(session.ID)
+	if err != nil || !found {
+		t.Fatalf("expected touched session to load, found=%v err=%v", found, err)
+	}
+	if time.Since(loaded.LastAccessed) > time.Minute {
+		t.Fatalf("expected a recent last access, got %v", loaded.LastAccessed)
+	}
+}
+
+// TestSessionSaveRoundTripsStoredObjects verifies that by-value objects, Null and dates survive the G3SES format.
+func TestSessionSaveRoundTripsStoredObjects(t *testing.T) {
+	tempDir := t.TempDir()
+	SetSessionStorageDir(filepath.Join(tempDir, "session"))
+	t.Cleanup(func() { SetSessionStorageDir("") })
+
+	born := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	line := NewApplicationJSPlainObject([]ApplicationValue{NewApplicationString("sku")}, []ApplicationValue{NewApplicationString("A-1")})
+	cart := NewApplicationDictionary(1,
+		[]ApplicationValue{NewApplicationString("lines"), NewApplicationString("born")},
+		[]ApplicationValue{NewApplicationCollection([]ApplicationValue{line, NewApplicationNull()}), NewApplicationDate(born)})
+	rs := NewApplicationRecordset(
+		[]ApplicationValue{NewApplicationArray(0, []ApplicationValue{NewApplicationString("Name"), NewApplicationString("200"), NewApplicationInteger(200), NewApplicationInteger(50), NewApplicationInteger(0), NewApplicationInteger(0)})},
+		[]ApplicationValue{NewApplicationArray(0, []ApplicationValue{NewApplicationString("ana")})}, 0)
+
+	session := NewSessionWithID("stored-objects")
+	session.Set("cart", cart)
+	session.Set("rs", rs)
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	loaded, found, err := LoadSession(session.ID)
+	if err != nil || !found {
+		t.Fatalf("LoadSession found=%v err=%v", found, err)
+	}
+
+	got, _ := loaded.Get("cart")
+	if got.Type != ApplicationValueDictionary || got.Num != 1 || len(got.Keys) != 2 || got.Keys[1].Str != "born" {
+		t.Fatalf("unexpected dictionary %#v", got)
+	}
+	if !got.IsObject() || !got.Arr[1].Time().Equal(born) {
+		t.Fatalf("unexpected date %v", got.Arr[1].Time())
+	}
+	lines := got.Arr[0]
+	if lines.Type != ApplicationValueCollection || len(lines.Arr) != 2 || lines.Arr[1].Type != ApplicationValueNull {
+		t.Fatalf("unexpected collection %#v", lines)
+	}
+	if item := lines.Arr[0]; item.Type != ApplicationValueJSPlainObject || item.Keys[0].Str != "sku" || item.Arr[0].Str != "A-1" {
+		t.Fatalf("unexpected JScript object %#v", item)
+	}
+	gotRS, _ := loaded.Get("rs")
+	if gotRS.Type != ApplicationValueRecordset || len(gotRS.Keys) != 1 || gotRS.Keys[0].Arr[3].Num != 50 || gotRS.Arr[0].Arr[0].Str != "ana" {
+		t.Fatalf("unexpected recordset %#v", gotRS)
+	}
+}
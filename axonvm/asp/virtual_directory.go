@@ -0,0 +1,94 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package asp
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VirtualDirectory maps one URL path prefix to a physical folder, like an IIS virtual directory.
+type VirtualDirectory struct {
+	VirtualPath  string
+	PhysicalPath string
+}
+
+// NormalizeVirtualPath cleans one URL path prefix into the "/name/sub" form used for matching.
+func NormalizeVirtualPath(virtualPath string) string {
+	cleaned := path.Clean("/" + strings.Trim(strings.ReplaceAll(strings.TrimSpace(virtualPath), "\\", "/"), "/"))
+	if cleaned == "." {
+		return "/"
+	}
+	return cleaned
+}
+
+// NormalizeVirtualDirectories cleans every mapping, resolves absolute physical paths and
+// sorts them longest prefix first so nested mappings win over their parents.
+// Entries without a physical path or mapped to the root prefix are dropped.
+func NormalizeVirtualDirectories(dirs []VirtualDirectory) []VirtualDirectory {
+	if len(dirs) == 0 {
+		return nil
+	}
+	normalized := make([]VirtualDirectory, 0, len(dirs))
+	for _, dir := range dirs {
+		virtualPath := NormalizeVirtualPath(dir.VirtualPath)
+		physicalPath := strings.TrimSpace(dir.PhysicalPath)
+		if virtualPath == "/" || physicalPath == "" {
+			continue
+		}
+		if absPath, err := filepath.Abs(physicalPath); err == nil {
+			physicalPath = absPath
+		}
+		normalized = append(normalized, VirtualDirectory{VirtualPath: virtualPath, PhysicalPath: filepath.Clean(physicalPath)})
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return len(normalized[i].VirtualPath) > len(normalized[j].VirtualPath)
+	})
+	return normalized
+}
+
+// VirtualPathHasPrefix reports whether a URL path equals or lies below a virtual prefix.
+// The comparison is case-insensitive, as in IIS.
+func VirtualPathHasPrefix(virtualPath string, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	if len(virtualPath) < len(prefix) || !strings.EqualFold(virtualPath[:len(prefix)], prefix) {
+		return false
+	}
+	return len(virtualPath) == len(prefix) || virtualPath[len(prefix)] == '/'
+}
+
+// MatchVirtualDirectory returns the longest mapping that contains the URL path and the
+// remaining path below it. The mappings must come from NormalizeVirtualDirectories.
+func MatchVirtualDirectory(dirs []VirtualDirectory, virtualPath string) (VirtualDirectory, string, bool) {
+	if len(dirs) == 0 {
+		return VirtualDirectory{}, "", false
+	}
+	for _, dir := range dirs {
+		if VirtualPathHasPrefix(virtualPath, dir.VirtualPath) {
+			return dir, strings.TrimPrefix(virtualPath[len(dir.VirtualPath):], "/"), true
+		}
+	}
+	return VirtualDirectory{}, "", false
+}
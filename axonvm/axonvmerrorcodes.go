@@ -82,6 +82,7 @@ const (
 	ErrTimeExecutionError     AxonASPErrorCode = 3009
 	ErrExpired                AxonASPErrorCode = 3010
 	ErrServerForcedToShutdown AxonASPErrorCode = 3011
+	ErrServerUpgradeFailed    AxonASPErrorCode = 3012
 
 	ErrCompileError                         AxonASPErrorCode = 4000
 	ErrScriptTimeout                        AxonASPErrorCode = 4001
@@ -96,6 +97,7 @@ const (
 	ErrResponseBufferLimitExceeded          AxonASPErrorCode = 4010
 	ErrScriptTimeoutDetachedGoroutine       AxonASPErrorCode = 4011
 	ErrLibraryDisabled                      AxonASPErrorCode = 4012
+	ErrRequestTerminated                    AxonASPErrorCode = 4013
 
 	ErrInvalidCacheVersion          AxonASPErrorCode = 5000
 	ErrInvalidCacheFile             AxonASPErrorCode = 5001
@@ -280,6 +282,7 @@ var AxonASPErrorMessages = map[AxonASPErrorCode]string{
 	ErrTimeExecutionError:     "Time execution error",
 	ErrExpired:                "Expired",
 	ErrServerForcedToShutdown: "Server forced to shutdown",
+	ErrServerUpgradeFailed:    "Graceful server upgrade failed",
 
 	// Script / AxonVM
 	ErrCompileError:                         "Compile Error",
@@ -295,6 +298,7 @@ var AxonASPErrorMessages = map[AxonASPErrorCode]string{
 	ErrResponseBufferLimitExceeded:          "Response buffer limit exceeded",
 	ErrScriptTimeoutDetachedGoroutine:       "Script timeout reached and execution goroutine was detached",
 	ErrLibraryDisabled:                      "The requested library was not compiled into this AxonASP executable. You must compile the server without the `lib_%s_disabled` build tag to enable it.",
+	ErrRequestTerminated:                    "Request terminated after exceeding request_terminate_timeout",
 
 	// Cache
 	ErrInvalidCacheVersion:          "Invalid cache version",
@@ -27,12 +27,14 @@ import (
 	"maps"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 	"sync/atomic"
 
+	"g3pix.com.br/axonasp/axonvm/asp"
 	"g3pix.com.br/axonasp/jscript"
 	jsast "g3pix.com.br/axonasp/jscript/ast"
 	"g3pix.com.br/axonasp/vbscript"
@@ -60,9 +62,25 @@ type SourceMapEntry struct {
 	OriginalLine    int
 }
 
+// SourceMapInclude records the merged lines of one expanded #include directive.
+type SourceMapInclude struct {
+	MergedLineStart int
+	MergedLineEnd   int // first merged line after the included file
+	File            string
+	IncludedFrom    string
+	IncludedAtLine  int
+}
+
+// SourceLocation is one line of one source file.
+type SourceLocation struct {
+	File string
+	Line int
+}
+
 // SourceMap maps merged lines to original source lines using sparse boundaries.
 type SourceMap struct {
-	entries []SourceMapEntry
+	entries  []SourceMapEntry
+	includes []SourceMapInclude
 }
 
 // jscriptCompileLineAnchor maps generated JScript program lines back to merged source lines.
@@ -122,6 +140,50 @@ func (m *SourceMap) ResolveLine(mergedLine int) (string, int, bool) {
 	return entry.OriginalFile, resolvedLine, true
 }
 
+// AddInclude records one expanded #include directive.
+func (m *SourceMap) AddInclude(include SourceMapInclude) {
+	if m == nil || include.MergedLineEnd <= include.MergedLineStart {
+		return
+	}
+	m.includes = append(m.includes, include)
+}
+
+// IncludeChain returns the #include directives that brought one merged line into
+// the page, the innermost first.
+func (m *SourceMap) IncludeChain(mergedLine int) []SourceLocation {
+	if m == nil || mergedLine <= 0 {
+		return nil
+	}
+	var chain []SourceMapInclude
+	for _, include := range m.includes {
+		if mergedLine >= include.MergedLineStart && mergedLine < include.MergedLineEnd {
+			chain = append(chain, include)
+		}
+	}
+	// Nested includes start after their parent, or at the same line and end sooner.
+	sort.Slice(chain, func(i, j int) bool {
+		if chain[i].MergedLineStart != chain[j].MergedLineStart {
+			return chain[i].MergedLineStart > chain[j].MergedLineStart
+		}
+		return chain[i].MergedLineEnd < chain[j].MergedLineEnd
+	})
+	locations := make([]SourceLocation, len(chain))
+	for i, include := range chain {
+		locations[i] = SourceLocation{File: include.IncludedFrom, Line: include.IncludedAtLine}
+	}
+	return locations
+}
+
+// Includes returns a copy of the recorded #include directives.
+func (m *SourceMap) Includes() []SourceMapInclude {
+	if m == nil || len(m.includes) == 0 {
+		return nil
+	}
+	out := make([]SourceMapInclude, len(m.includes))
+	copy(out, m.includes)
+	return out
+}
+
 // Entries returns a copy of sparse source-map entries.
 func (m *SourceMap) Entries() []SourceMapEntry {
 	if m == nil || len(m.entries) == 0 {
@@ -139,11 +201,12 @@ func (m *SourceMap) Clone() SourceMap {
 	}
 	cloned := make([]SourceMapEntry, len(m.entries))
 	copy(cloned, m.entries)
-	return SourceMap{entries: cloned}
+	return SourceMap{entries: cloned, includes: m.Includes()}
 }
 
 type includeResolveOptions struct {
 	siteRoot        string
+	virtualDirs     []asp.VirtualDirectory
 	caseInsensitive bool
 }
 
@@ -218,6 +281,7 @@ type Compiler struct {
 	optionInfer            bool // (Future) Allows type inference
 	sourceName             string
 	includeSiteRoot        string
+	includeVirtualDirs     []asp.VirtualDirectory
 	includeCaseInsensitive bool
 	sourceMap              SourceMap
 	includeDeps            []string
@@ -1162,9 +1226,17 @@ func preprocessASPIncludesWithDepsWithOptions(source string, sourceName string,
 		}
 
 		mergeSourceMap(&sourceMap, childMap, currentMergedLine)
+		includedLines := countLogicalLines(expanded)
+		sourceMap.AddInclude(SourceMapInclude{
+			MergedLineStart: currentMergedLine,
+			MergedLineEnd:   currentMergedLine + includedLines,
+			File:            resolvedPath,
+			IncludedFrom:    sourceName,
+			IncludedAtLine:  currentSourceLine,
+		})
 		builder.WriteString(expanded)
-		currentMergedLine += countLogicalLines(expanded)
-		currentSourceLine += countLogicalLines(source[replaceStart:replaceEnd])
+		currentMergedLine += countLineBreaks(expanded)
+		currentSourceLine += countLineBreaks(source[replaceStart:replaceEnd])
 		cursor = replaceEnd
 	}
 
@@ -1282,15 +1354,17 @@ func countLogicalLines(s string) int {
 }
 
 // appendMappedSegment appends one source segment and updates merged/source line cursors.
+// A segment that does not end with a line break leaves the cursors on its last
+// line, where the next segment continues.
 func appendMappedSegment(builder *strings.Builder, sourceMap *SourceMap, segment string, sourceName string, currentMergedLine *int, currentSourceLine *int) {
 	if segment == "" {
 		return
 	}
 	sourceMap.AddBoundary(*currentMergedLine, sourceName, *currentSourceLine)
 	builder.WriteString(segment)
-	logicalLines := countLogicalLines(segment)
-	*currentMergedLine += logicalLines
-	*currentSourceLine += logicalLines
+	lineBreaks := countLineBreaks(segment)
+	*currentMergedLine += lineBreaks
+	*currentSourceLine += lineBreaks
 }
 
 // mergeSourceMap appends one child source map into a parent map at one merged-line offset.
@@ -1302,6 +1376,11 @@ func mergeSourceMap(target *SourceMap, child SourceMap, mergedLineStart int) {
 		entry := child.entries[i]
 		target.AddBoundary(mergedLineStart+entry.MergedLineStart-1, entry.OriginalFile, entry.OriginalLine)
 	}
+	for _, include := range child.includes {
+		include.MergedLineStart += mergedLineStart - 1
+		include.MergedLineEnd += mergedLineStart - 1
+		target.AddInclude(include)
+	}
 }
 
 // resolveIncludePath resolves one ASP include path against current source path context.
@@ -1340,6 +1419,10 @@ func resolveIncludePathWithOptions(sourceName string, includePath string, isVirt
 			trimmed = strings.TrimLeft(trimmed, string(filepath.Separator))
 		}
 		rel := strings.TrimLeft(trimmed, string(filepath.Separator))
+		if dir, rest, ok := asp.MatchVirtualDirectory(options.virtualDirs, path.Clean("/"+filepath.ToSlash(rel))); ok {
+			siteRoot = dir.PhysicalPath
+			rel = filepath.FromSlash(rest)
+		}
 		candidate := filepath.Clean(filepath.Join(siteRoot, rel))
 		if !pathInsideRoot(siteRoot, candidate) {
 			return "", fmt.Errorf("virtual include escapes site root: %s", includePath)
@@ -2360,6 +2443,14 @@ func (c *Compiler) SetIncludeSiteRoot(rootDir string) {
 	c.includeSiteRoot = normalizeIncludeSiteRoot(rootDir)
 }
 
+// SetIncludeVirtualDirectories sets the virtual directory mappings honored by SSI include virtual resolution.
+func (c *Compiler) SetIncludeVirtualDirectories(dirs []asp.VirtualDirectory) {
+	if c == nil {
+		return
+	}
+	c.includeVirtualDirs = dirs
+}
+
 // IncludeSiteRoot returns the normalized virtual site root used for SSI include resolution.
 func (c *Compiler) IncludeSiteRoot() string {
 	if c == nil {
@@ -2401,6 +2492,14 @@ func (c *Compiler) SourceMapEntries() []SourceMapEntry {
 	return c.sourceMap.Entries()
 }
 
+// SourceMapIncludes returns a stable copy of the expanded #include directives.
+func (c *Compiler) SourceMapIncludes() []SourceMapInclude {
+	if c == nil {
+		return nil
+	}
+	return c.sourceMap.Includes()
+}
+
 func (c *Compiler) lineColumnFromSourceOffset(offset int) (int, int) {
 	if c == nil || offset < 0 {
 		return 0, 0
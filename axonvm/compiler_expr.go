@@ -1164,6 +1164,7 @@ func (c *Compiler) Compile() (err error) {
 		if strings.Contains(strings.ToLower(c.sourceCode), "#include") {
 			includeOptions := includeResolveOptions{
 				siteRoot:        c.includeSiteRoot,
+				virtualDirs:     c.includeVirtualDirs,
 				caseInsensitive: c.includeCaseInsensitive,
 			}
 			expanded, mappedSource, preprocessErr := preprocessASPIncludesWithDepsWithOptions(c.sourceCode, c.sourceName, map[string]bool{}, 0, &c.includeDeps, includeOptions)
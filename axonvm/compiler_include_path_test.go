@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
 )
 
 func TestResolveIncludeVirtualAnchorsToConfiguredRoot(t *testing.T) {
@@ -34,6 +36,38 @@ func TestResolveIncludeVirtualAnchorsToConfiguredRoot(t *testing.T) {
 	}
 }
 
+func TestResolveIncludeVirtualUsesVirtualDirectory(t *testing.T) {
+	rootDir := t.TempDir()
+	sharedDir := t.TempDir()
+	includePath := filepath.Join(sharedDir, "lib", "common.inc")
+	if err := os.MkdirAll(filepath.Dir(includePath), 0o755); err != nil {
+		t.Fatalf("mkdir include dir failed: %v", err)
+	}
+	if err := os.WriteFile(includePath, []byte("<% 'shared %>"), 0o644); err != nil {
+		t.Fatalf("write include failed: %v", err)
+	}
+	sourcePath := filepath.Join(rootDir, "default.asp")
+	if err := os.WriteFile(sourcePath, []byte("<% 'test %>"), 0o644); err != nil {
+		t.Fatalf("write source failed: %v", err)
+	}
+
+	options := includeResolveOptions{
+		siteRoot:        rootDir,
+		virtualDirs:     asp.NormalizeVirtualDirectories([]asp.VirtualDirectory{{VirtualPath: "/shared", PhysicalPath: sharedDir}}),
+		caseInsensitive: true,
+	}
+	resolved, err := resolveIncludePathWithOptions(sourcePath, "/shared/lib/common.inc", true, options)
+	if err != nil {
+		t.Fatalf("resolve include through virtual directory failed: %v", err)
+	}
+	if filepath.Clean(resolved) != filepath.Clean(includePath) {
+		t.Fatalf("unexpected resolved path: got %q want %q", resolved, includePath)
+	}
+	if _, err := resolveIncludePathWithOptions(sourcePath, "/shared/../../secret.inc", true, options); err == nil {
+		t.Fatalf("expected escape outside the virtual directory to fail")
+	}
+}
+
 func TestResolveIncludeVirtualRejectsParentEscape(t *testing.T) {
 	rootDir := t.TempDir()
 	sourcePath := filepath.Join(rootDir, "pages", "default.asp")
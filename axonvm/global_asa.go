@@ -68,6 +68,12 @@ func GetGlobalASA() *GlobalASA {
 	return globalASAInstance
 }
 
+// NewGlobalASA creates an independent GlobalASA for hosts that serve several
+// applications from one process, each with its own global.asa and events.
+func NewGlobalASA() *GlobalASA {
+	return &GlobalASA{}
+}
+
 // ResetGlobalASA discards the singleton and allows a fresh LoadAndCompile
 // with a different global.asa. Intended for test use only.
 func ResetGlobalASA() {
@@ -77,10 +83,20 @@ func ResetGlobalASA() {
 
 // LoadAndCompile reads, compiles, and registers the global.asa file from the specified path.
 func (g *GlobalASA) LoadAndCompile(webRoot string, app *asp.Application) error {
+	return g.LoadAndCompileApplication(webRoot, webRoot, nil, app)
+}
+
+// LoadAndCompileApplication loads the global.asa of one application folder. Nested
+// applications keep resolving #include virtual against the site root and its virtual directories.
+func (g *GlobalASA) LoadAndCompileApplication(appRoot string, siteRoot string, virtualDirs []asp.VirtualDirectory, app *asp.Application) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	globalASAPath := filepath.Join(webRoot, "global.asa")
+	// A reload after app_offline.htm is removed must not keep events or
+	// Session static objects of a global.asa that changed or disappeared.
+	g.resetLocked()
+
+	globalASAPath := filepath.Join(appRoot, "global.asa")
 
 	if _, err := os.Stat(globalASAPath); os.IsNotExist(err) {
 		g.isLoaded = true
@@ -97,7 +113,8 @@ func (g *GlobalASA) LoadAndCompile(webRoot string, app *asp.Application) error {
 
 	compiler := NewASPCompiler(string(content))
 	compiler.SetSourceName(globalASAPath)
-	compiler.SetIncludeSiteRoot(webRoot)
+	compiler.SetIncludeSiteRoot(siteRoot)
+	compiler.SetIncludeVirtualDirectories(virtualDirs)
 
 	if err := compiler.Compile(); err != nil {
 		return fmt.Errorf("failed to compile global.asa: %w", err)
@@ -251,6 +268,11 @@ func (g *GlobalASA) Reset() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	g.resetLocked()
+}
+
+// resetLocked clears the compiled state. The caller must hold g.mu.
+func (g *GlobalASA) resetLocked() {
 	g.compiler = nil
 	g.bytecode = nil
 	g.constants = nil
@@ -47,6 +47,13 @@ type ASPHostEnvironment interface {
 	WriteString(s string)
 }
 
+// SessionCookieHost is implemented by hosts that send the session ID in a
+// cookie. Session.Regenerate calls ReissueSessionCookie after the ID changes,
+// and raises an error instead when the response headers were already sent.
+type SessionCookieHost interface {
+	ReissueSessionCookie()
+}
+
 // MockHost is a purely in-memory implementation for testing and CLI.
 type MockHost struct {
 	response         *asp.Response
@@ -132,7 +139,7 @@ func (m *MockHost) ExecuteASPFile(absPath string) error {
 	if !cacheHit {
 		var err error
 		if cache != nil {
-			program, err = cache.LoadOrCompileWithOptions(absPath, ScriptCompileOptions{IncludeSiteRoot: m.server.MapPath("/")})
+			program, err = cache.LoadOrCompileWithOptions(absPath, ScriptCompileOptions{IncludeSiteRoot: m.server.MapPath("/"), IncludeVirtualDirectories: m.server.VirtualDirectories()})
 		} else {
 			content, readErr := os.ReadFile(absPath)
 			if readErr != nil {
@@ -143,6 +150,7 @@ func (m *MockHost) ExecuteASPFile(absPath string) error {
 			compiler := NewASPCompiler(string(content))
 			compiler.SetSourceName(absPath)
 			compiler.SetIncludeSiteRoot(m.server.MapPath("/"))
+			compiler.SetIncludeVirtualDirectories(m.server.VirtualDirectories())
 			if compileErr := compiler.Compile(); compileErr != nil {
 				return compileErr
 			}
@@ -1088,7 +1088,7 @@ func (vm *VM) jsNodeDoHTTPRequest(moduleType string, method string, args []Value
 		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
-	req, err := http.NewRequest(reqMethod, urlStr, bodyReader)
+	req, err := http.NewRequestWithContext(vm.Context(), reqMethod, urlStr, bodyReader)
 	if err != nil {
 		vm.jsThrowTypeError(moduleType + ".request failed: " + err.Error())
 		return Value{Type: VTJSUndefined}, true
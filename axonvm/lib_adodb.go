@@ -40,6 +40,7 @@ import (
 	"unicode"
 	"unicode/utf16"
 
+	"g3pix.com.br/axonasp/axonvm/asp"
 	"g3pix.com.br/axonasp/vbscript"
 	_ "github.com/denisenkom/go-mssqldb"
 	"github.com/go-ole/go-ole"
@@ -1009,8 +1010,14 @@ func (vm *VM) dispatchADODBRecordsetPropertyGet(rs *adodbRecordset, member strin
 func (vm *VM) dispatchADODBRecordsetPropertySet(rs *adodbRecordset, member string, val Value) bool {
 	switch {
 	case strings.EqualFold(member, "ActiveConnection"):
-		if val.Type == VTNativeObject {
+		switch val.Type {
+		case VTNativeObject:
 			rs.activeConnection = val.Num
+		case VTNothing, VTObject, VTEmpty:
+			// Set rs.ActiveConnection = Nothing disconnects a client-side recordset.
+			if val.Num == 0 {
+				rs.activeConnection = 0
+			}
 		}
 		return true
 	case strings.EqualFold(member, "ActiveCommand"):
@@ -1721,6 +1728,106 @@ func (vm *VM) adodbRecordsetClone(rs *adodbRecordset) Value {
 	return cloneVal
 }
 
+// adodbRecordsetToApplicationValue stores one disconnected recordset by value
+// for Session and Application. isRecordset is false when objID is not a
+// recordset; a recordset that is closed or still bound to a connection is an error.
+func (vm *VM) adodbRecordsetToApplicationValue(objID int64, visiting map[int64]struct{}) (stored asp.ApplicationValue, isRecordset bool, err error) {
+	rs, ok := vm.adodbRecordsetItems[objID]
+	if !ok || rs == nil {
+		return asp.ApplicationValue{}, false, nil
+	}
+	if rs.state != adStateOpen || rs.activeConnection != 0 || rs.oleRecordset != nil {
+		return asp.ApplicationValue{}, true, vm.applicationObjectError(Value{Type: VTNativeObject, Num: objID})
+	}
+
+	fields := make([]asp.ApplicationValue, len(rs.columns))
+	for i, name := range rs.columns {
+		lowerName := strings.ToLower(name)
+		typeName := ""
+		if i < len(rs.columnTypes) {
+			typeName = rs.columnTypes[i]
+		}
+		fields[i] = asp.NewApplicationArray(0, []asp.ApplicationValue{
+			asp.NewApplicationString(name),
+			asp.NewApplicationString(typeName),
+			asp.NewApplicationInteger(int64(rs.columnTypeByName[lowerName])),
+			asp.NewApplicationInteger(int64(rs.columnSizeByName[lowerName])),
+			asp.NewApplicationInteger(int64(rs.columnAttrByName[lowerName])),
+			asp.NewApplicationInteger(int64(rs.columnScaleByName[lowerName])),
+		})
+	}
+	rows := make([]asp.ApplicationValue, 0, len(rs.data))
+	for _, row := range rs.data {
+		if row == nil {
+			continue
+		}
+		values := make([]asp.ApplicationValue, len(rs.columns))
+		for i, name := range rs.columns {
+			value, err := vm.encodeApplicationValue(row[strings.ToLower(name)], visiting)
+			if err != nil {
+				return asp.ApplicationValue{}, true, err
+			}
+			values[i] = value
+		}
+		rows = append(rows, asp.NewApplicationArray(0, values))
+	}
+	return asp.NewApplicationRecordset(fields, rows, rs.currentRow), true, nil
+}
+
+// adodbRecordsetFromApplicationValue creates a disconnected client-side
+// recordset from a stored one, positioned on the row it was stored on.
+func (vm *VM) adodbRecordsetFromApplicationValue(v asp.ApplicationValue) Value {
+	rsVal := vm.newADODBRecordset()
+	rs := vm.adodbRecordsetItems[rsVal.Num]
+	if rs == nil {
+		return Value{Type: VTEmpty}
+	}
+	for _, field := range v.Keys {
+		if len(field.Arr) < 6 {
+			continue
+		}
+		name := field.Arr[0].Str
+		lowerName := strings.ToLower(name)
+		rs.columns = append(rs.columns, name)
+		rs.columnTypes = append(rs.columnTypes, field.Arr[1].Str)
+		rs.columnTypeByName[lowerName] = int(field.Arr[2].Num)
+		rs.columnSizeByName[lowerName] = int(field.Arr[3].Num)
+		rs.columnAttrByName[lowerName] = int(field.Arr[4].Num)
+		rs.columnScaleByName[lowerName] = int(field.Arr[5].Num)
+	}
+	vm.adodbRecordsetRebuildColumnIndex(rs)
+
+	rs.data = make([]map[string]Value, 0, len(v.Arr))
+	for _, stored := range v.Arr {
+		row := make(map[string]Value, len(rs.columns))
+		for i, name := range rs.columns {
+			value := Value{Type: VTEmpty}
+			if i < len(stored.Arr) {
+				value = vm.applicationValueToValue(stored.Arr[i])
+			}
+			row[strings.ToLower(name)] = value
+		}
+		rs.data = append(rs.data, row)
+	}
+	rs.cursorLocation = adUseClient
+	rs.cursorType = adOpenStatic
+	rs.lockType = adLockBatchOptimistic
+	vm.adodbFinalizeDisconnectedRecordset(rs)
+
+	if position := int(v.Num); rs.recordCount > 0 && position != rs.currentRow {
+		switch {
+		case position >= rs.recordCount:
+			rs.currentRow = rs.recordCount
+			rs.bookmark = 0
+			rs.eof = true
+		case position >= 0:
+			rs.currentRow = position
+			rs.bookmark = position + 1
+		}
+	}
+	return rsVal
+}
+
 // adodbRecordsetApplyFilterSort applies compact in-memory filter/sort compatibility behavior.
 func (vm *VM) adodbRecordsetApplyFilterSort(rs *adodbRecordset) {
 	if rs == nil {
@@ -25,6 +25,7 @@ package axonvm
 import (
 	"fmt"
 
+	"g3pix.com.br/axonasp/axonvm/asp"
 	"g3pix.com.br/axonasp/vbscript"
 )
 
@@ -198,3 +199,13 @@ func (vm *VM) stopSTAWorker()  {}
 func (vm *VM) runOnSTA(f func()) {
 	f()
 }
+
+// adodbRecordsetToApplicationValue reports that no recordset exists because ADODB support is compiled out.
+func (vm *VM) adodbRecordsetToApplicationValue(objID int64, visiting map[int64]struct{}) (asp.ApplicationValue, bool, error) {
+	return asp.ApplicationValue{}, false, nil
+}
+
+// adodbRecordsetFromApplicationValue panics with ErrLibraryDisabled because ADODB support is compiled out.
+func (vm *VM) adodbRecordsetFromApplicationValue(v asp.ApplicationValue) Value {
+	return vm.newADODBRecordset()
+}
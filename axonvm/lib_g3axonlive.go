@@ -211,6 +211,15 @@ func G3ALStartCleanup(idleMinutes int) {
 	}()
 }
 
+// G3ALStoreStats returns the number of stored component properties and the
+// number of sessions with a registered page.
+func G3ALStoreStats() (properties int, sessions int) {
+	s := getG3ALStore()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.componentValues), len(s.pageRegistry)
+}
+
 // Call this during server shutdown.
 func G3ALStopCleanup() {
 	if g3alCleanupStop != nil {
@@ -268,7 +277,7 @@ func (p *G3ALComponentProxy) DispatchPropertyGet(propertyName string) Value {
 	// Determine the active session ID.
 	sessionID := ""
 	if p.parent.vm.host.Session() != nil {
-		sessionID = p.parent.vm.host.Session().ID
+		sessionID = p.parent.vm.host.Session().GetID()
 	}
 	if sessionID == "" && p.parent.eventSessionID != "" {
 		sessionID = p.parent.eventSessionID
@@ -319,7 +328,7 @@ func (p *G3ALComponentProxy) DispatchPropertySet(propertyName string, args []Val
 	// 2. Persistence: Update the global state so future reads reflect this change.
 	sessionID := ""
 	if p.parent.vm.host.Session() != nil {
-		sessionID = p.parent.vm.host.Session().ID
+		sessionID = p.parent.vm.host.Session().GetID()
 	}
 	if sessionID == "" && p.parent.eventSessionID != "" {
 		sessionID = p.parent.eventSessionID
@@ -587,9 +596,9 @@ func (g *G3AXONLIVE) initPage() Value {
 		strings.TrimSpace(req.ServerVars.Get("HTTP_X_G3AXONLIVE_EVENTNAME")) != ""
 	if !strings.EqualFold(strings.TrimSpace(headerVal), "true") && !hasForwardedEvent && !isJSONPost {
 		// Regular full-page load — register this page for future async calls.
-		if sess != nil && sess.ID != "" {
+		if sess != nil && sess.GetID() != "" {
 			scriptURL := req.ServerVars.Get("SCRIPT_NAME")
-			G3ALRegisterPage(sess.ID, scriptURL)
+			G3ALRegisterPage(sess.GetID(), scriptURL)
 		}
 		g.isAsyncRequest = false
 		return Value{Type: VTBool, Num: 0}
@@ -622,7 +631,7 @@ func (g *G3AXONLIVE) initPage() Value {
 	payloadSessionID := strings.TrimSpace(event.SessionID)
 	hostSessionID := ""
 	if sess != nil {
-		hostSessionID = strings.TrimSpace(sess.ID)
+		hostSessionID = strings.TrimSpace(sess.GetID())
 	}
 	if hostSessionID != "" {
 		g.eventSessionID = hostSessionID
@@ -652,7 +661,7 @@ func (g *G3AXONLIVE) loadAsyncEventFromForwardHeaders() {
 	req := g.vm.host.Request()
 	hostSessionID := ""
 	if g.vm.host.Session() != nil {
-		hostSessionID = strings.TrimSpace(g.vm.host.Session().ID)
+		hostSessionID = strings.TrimSpace(g.vm.host.Session().GetID())
 	}
 	if hostSessionID != "" {
 		g.eventSessionID = hostSessionID
@@ -60,3 +60,6 @@ func G3ALStartCleanup(_ int) {}
 
 // G3ALStopCleanup is a no-op stub for the disabled library.
 func G3ALStopCleanup() {}
+
+// G3ALStoreStats is a no-op stub that always reports an empty store.
+func G3ALStoreStats() (int, int) { return 0, 0 }
@@ -44,6 +44,7 @@ package axonvm
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -263,18 +264,33 @@ func (g *G3DB) open(driver, dsn string) bool {
 		return false
 	}
 
-	db, err := sql.Open(driver, dsn)
+	db, err := g3dbOpenPool(driver, dsn)
 	if err != nil {
-		g.lastError = ErrG3DBPingFailed.String() + ": " + err.Error()
+		g.lastError = err.Error()
 		return false
 	}
 
+	g.db = db
+	g.driver = driver
+	g.dsn = dsn
+	g.isOpen = true
+	g.lastError = ""
+	return true
+}
+
+// g3dbOpenPool opens a *sql.DB for a normalized driver and verifies it with a
+// 5-second ping.
+func g3dbOpenPool(driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.New(ErrG3DBPingFailed.String() + ": " + err.Error())
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := db.PingContext(ctx); err != nil {
 		_ = db.Close()
-		g.lastError = ErrG3DBPingFailed.String() + ": " + err.Error()
-		return false
+		return nil, errors.New(ErrG3DBPingFailed.String() + ": " + err.Error())
 	}
 
 	// SQLite optimization for concurrency, POSIX file locking, and WAL journal mode
@@ -284,13 +300,32 @@ func (g *G3DB) open(driver, dsn string) bool {
 		_, _ = db.Exec("PRAGMA journal_mode = WAL")
 		_, _ = db.Exec("PRAGMA busy_timeout = 6000")
 	}
+	return db, nil
+}
 
-	g.db = db
-	g.driver = driver
-	g.dsn = dsn
-	g.isOpen = true
-	g.lastError = ""
-	return true
+// OpenG3DBFromConfig opens a connection pool for driver using the [g3db] section of
+// axonasp.toml, the same settings G3DB.OpenFromEnv uses. It returns the normalized
+// driver name expected by G3DBRewritePlaceholders.
+func OpenG3DBFromConfig(driver string) (*sql.DB, string, error) {
+	driver = g3dbNormalizeDriver(driver)
+	if driver == "" {
+		return nil, "", errors.New(ErrG3DBUnsupportedDriver.String())
+	}
+	dsn := g3dbBuildDSN(g3dbNewConfigViper(), driver)
+	if dsn == "" {
+		return nil, driver, errors.New(ErrG3DBMissingConfigKeys.String())
+	}
+	db, err := g3dbOpenPool(driver, dsn)
+	if err != nil {
+		return nil, driver, err
+	}
+	return db, driver, nil
+}
+
+// G3DBRewritePlaceholders converts ? placeholders to the native syntax of a
+// normalized driver, as G3DB does for script queries.
+func G3DBRewritePlaceholders(sqlText, driver string) string {
+	return g3dbRewritePlaceholders(sqlText, driver)
 }
 
 // openFromEnv reads connection parameters from axonasp.toml (with ENV override)
@@ -79,7 +79,7 @@ func (h *G3HTTP) executeRequest(reqUrl, method, bodyStr string) Value {
 		bodyReader = strings.NewReader(bodyStr)
 	}
 
-	req, err := http.NewRequest(method, reqUrl, bodyReader)
+	req, err := http.NewRequestWithContext(h.vm.Context(), method, reqUrl, bodyReader)
 	if err != nil {
 		return NewEmpty()
 	}
@@ -180,7 +180,7 @@ func (s *MsXML2ServerXMLHTTP) send(args []any) any {
 		bodyHasContent = bodyReader != nil
 	}
 
-	req, err := http.NewRequest(s.method, s.url, bodyReader)
+	req, err := http.NewRequestWithContext(s.ctx.Context(), s.method, s.url, bodyReader)
 	if err != nil {
 		s.status = 0
 		s.statusText = err.Error()
@@ -35,8 +35,10 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"g3pix.com.br/axonasp/axonvm/asp"
 	"github.com/cespare/xxhash/v2"
 	"github.com/fsnotify/fsnotify"
 	"golang.org/x/sync/singleflight"
@@ -45,7 +47,7 @@ import (
 const (
 	scriptCacheDependencyMapLimit = 1000
 	scriptCacheMagicSize          = 6
-	scriptCacheBinaryVersion      = uint16(14)
+	scriptCacheBinaryVersion      = uint16(15)
 	scriptCacheDebounceWindow     = 1000 * time.Millisecond
 )
 
@@ -120,6 +122,7 @@ type CachedProgram struct {
 	FuncParamDefaults   map[int][]int
 	IncludeDependencies []string
 	SourceMapEntries    []SourceMapEntry
+	SourceMapIncludes   []SourceMapInclude
 	// RecordDecls and RecordDeclLookup carry compiled UDT metadata required by
 	// ExtOpInitRecord/ExtOpGetRecordMember/ExtOpSetRecordMember in cached VM startup paths.
 	RecordDecls      []CompiledRecordDecl
@@ -142,7 +145,8 @@ type cachedProgramBinaryPayload struct {
 
 // ScriptCompileOptions controls context-sensitive compilation behavior.
 type ScriptCompileOptions struct {
-	IncludeSiteRoot string
+	IncludeSiteRoot           string
+	IncludeVirtualDirectories []asp.VirtualDirectory
 }
 
 var scriptCacheProcessBinaryModUnix = currentProcessBinaryModUnix
@@ -258,6 +262,9 @@ func (p *cachedProgramBinaryPayload) Serialize(writer io.Writer) error {
 	if err := binary.Write(buffered, binary.LittleEndian, p.Program.JSICNodeCount); err != nil {
 		return err
 	}
+	if err := writeSourceMapIncludes(buffered, p.Program.SourceMapIncludes); err != nil {
+		return err
+	}
 
 	return buffered.Flush()
 }
@@ -283,7 +290,7 @@ func (p *cachedProgramBinaryPayload) Deserialize(reader io.Reader) error {
 	if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
 		return err
 	}
-	if version != 1 && version != 2 && version != 8 && version != 10 && version != scriptCacheBinaryVersion && version != 13 {
+	if version != 1 && version != 2 && version != 8 && version != 10 && version != scriptCacheBinaryVersion && version != 13 && version != 14 {
 		return NewAxonASPError(ErrInvalidCacheVersion, nil, ErrInvalidCacheVersion.String(), "", 0)
 	}
 
@@ -451,6 +458,13 @@ func (p *cachedProgramBinaryPayload) Deserialize(reader io.Reader) error {
 								return err
 							}
 							p.Program.JSICNodeCount = jsICNodeCount
+							if version >= 15 {
+								sourceMapIncludes, err := readSourceMapIncludes(reader)
+								if err != nil {
+									return err
+								}
+								p.Program.SourceMapIncludes = sourceMapIncludes
+							}
 						}
 					}
 				}
@@ -495,6 +509,15 @@ type ScriptCache struct {
 	watchStop           chan struct{}
 	watcherActive       bool
 	watcherErrorCount   uint32
+	watchListeners      []WatchListener
+
+	// Counters reported by Stats.
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+	diskHits      atomic.Uint64
+	compiles      atomic.Uint64
+	compileErrors atomic.Uint64
+	evictions     atomic.Uint64
 
 	// Engine configuration for mode-based compilation.
 	engineMode   EngineMode
@@ -640,6 +663,7 @@ func (c *ScriptCache) SetMaxSizeMB(maxSizeMB int) {
 	for len(c.programOrder) > 0 && c.totalBytes > c.maxBytes {
 		victim := c.programOrder[0]
 		c.removeProgramNoLock(victim)
+		c.evictions.Add(1)
 	}
 }
 
@@ -749,6 +773,13 @@ func (c *ScriptCache) handleWatchEvent(watcher *fsnotify.Watcher, event fsnotify
 		return
 	}
 
+	c.mu.RLock()
+	listeners := c.watchListeners
+	c.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(event.Name)
+	}
+
 	if event.Op&fsnotify.Create != 0 {
 		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
 			_ = c.addWatchRecursiveTracked(watcher, event.Name)
@@ -769,6 +800,37 @@ func (c *ScriptCache) handleWatchEvent(watcher *fsnotify.Watcher, event fsnotify
 	c.Invalidate(event.Name)
 }
 
+// WatchListener receives the path of a file created, written, removed or renamed
+// under the roots watched by StartInvalidator.
+type WatchListener func(path string)
+
+// AddWatchListener registers fn for every file event of the invalidator, whatever the
+// file extension. Listeners run on the watcher goroutine and must not block.
+func (c *ScriptCache) AddWatchListener(fn WatchListener) {
+	if c == nil || fn == nil {
+		return
+	}
+	c.mu.Lock()
+	c.watchListeners = append(slices.Clip(c.watchListeners), fn)
+	c.mu.Unlock()
+}
+
+// WatchesDir reports whether the active invalidator receives the file events of dir.
+func (c *ScriptCache) WatchesDir(dir string) bool {
+	if c == nil {
+		return false
+	}
+	normalized, err := c.normalizeAbsolutePath(dir)
+	if err != nil {
+		return false
+	}
+	normalized = normalizeScriptCacheKey(normalized)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, watched := c.watchedPaths[normalized]
+	return c.watcherActive && watched
+}
+
 // StopInvalidator stops the active file watcher and goroutine.
 func (c *ScriptCache) StopInvalidator() {
 	if c == nil {
@@ -814,6 +876,49 @@ func (c *ScriptCache) GetWatcherStatus() (active bool, errorCount uint32, roots
 	return c.watcherActive, c.watcherErrorCount, len(c.watchRoots)
 }
 
+// ScriptCacheStats is a point-in-time snapshot of one ScriptCache.
+type ScriptCacheStats struct {
+	// Hits counts requests served from the memory tier.
+	Hits uint64
+	// Misses counts requests that were not found in the memory tier.
+	Misses uint64
+	// DiskHits counts misses served from the disk tier without compiling.
+	DiskHits uint64
+	// Compiles counts successful compilations.
+	Compiles uint64
+	// CompileErrors counts compilations that failed.
+	CompileErrors uint64
+	// Evictions counts programs removed to stay under the memory limit.
+	Evictions uint64
+	// Entries is the number of programs held in memory.
+	Entries int
+	// SizeBytes is the estimated memory used by the cached programs.
+	SizeBytes int64
+	// MaxBytes is the configured memory limit.
+	MaxBytes int64
+}
+
+// Stats returns the cache counters and current size.
+func (c *ScriptCache) Stats() ScriptCacheStats {
+	if c == nil {
+		return ScriptCacheStats{}
+	}
+	c.mu.RLock()
+	stats := ScriptCacheStats{
+		Entries:   len(c.programs),
+		SizeBytes: c.totalBytes,
+		MaxBytes:  c.maxBytes,
+	}
+	c.mu.RUnlock()
+	stats.Hits = c.hits.Load()
+	stats.Misses = c.misses.Load()
+	stats.DiskHits = c.diskHits.Load()
+	stats.Compiles = c.compiles.Load()
+	stats.CompileErrors = c.compileErrors.Load()
+	stats.Evictions = c.evictions.Load()
+	return stats
+}
+
 // Get returns a memory-cached program for one absolute source path.
 func (c *ScriptCache) Get(filePath string) (CachedProgram, bool) {
 	if c == nil || !c.mode.HasMemoryTier() {
@@ -881,6 +986,23 @@ func (c *ScriptCache) Invalidate(filePath string) {
 	}
 }
 
+// Clear drops every program from the memory tier, as when the application restarts.
+// Disk tier entries stay, because they are checked against their source files on load.
+func (c *ScriptCache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.programs)
+	clear(c.programSizes)
+	clear(c.dependencyMap)
+	clear(c.scriptDependencies)
+	c.programOrder = c.programOrder[:0]
+	c.dependencyOrder = c.dependencyOrder[:0]
+	c.totalBytes = 0
+}
+
 // LoadOrCompile applies memory, disk, and compiler fallback flow for one ASP file.
 func (c *ScriptCache) LoadOrCompile(filePath string) (CachedProgram, error) {
 	return c.LoadOrCompileWithModeAndOptions(filePath, ExecutionModeServer, ScriptCompileOptions{})
@@ -925,6 +1047,7 @@ func (c *ScriptCache) LoadOrCompileWithModeAndOptions(filePath string, mode Exec
 
 	if program, found := c.getByCacheKey(cacheKey); found {
 		if includeSiteRootMatches(program, options) {
+			c.hits.Add(1)
 			return program, nil
 		}
 	}
@@ -932,6 +1055,7 @@ func (c *ScriptCache) LoadOrCompileWithModeAndOptions(filePath string, mode Exec
 	if c == nil {
 		return c.compileOnly(normalized, options)
 	}
+	c.misses.Add(1)
 
 	// singleflight deduplicates concurrent compilations for the same cache key,
 	// preventing cache-stampede memory exhaustion under concurrent cache misses.
@@ -949,8 +1073,10 @@ func (c *ScriptCache) LoadOrCompileWithModeAndOptions(filePath string, mode Exec
 			return nil, statErr
 		}
 
-		if c.mode.HasDiskTier() && strings.TrimSpace(options.IncludeSiteRoot) == "" {
-			if program, found := c.loadDiskProgram(normalized, sourceInfo); found {
+		// A page compiled for another site root resolves include virtual differently.
+		if c.mode.HasDiskTier() {
+			if program, found := c.loadDiskProgram(normalized, sourceInfo); found && includeSiteRootMatches(program, options) {
+				c.diskHits.Add(1)
 				if c.mode.HasMemoryTier() {
 					c.putByCacheKey(cacheKey, program, program.IncludeDependencies, estimateProgramSizeBytes(program))
 				}
@@ -979,13 +1105,16 @@ func (c *ScriptCache) LoadOrCompileWithModeAndOptions(filePath string, mode Exec
 
 		compiler.SetSourceName(cacheKey)
 		compiler.SetIncludeSiteRoot(options.IncludeSiteRoot)
+		compiler.SetIncludeVirtualDirectories(options.IncludeVirtualDirectories)
 		if compErr := compiler.Compile(); compErr != nil {
+			c.compileErrors.Add(1)
 			return nil, compErr
 		}
+		c.compiles.Add(1)
 
 		program := buildCachedProgramFromCompiler(compiler)
 
-		if c.mode.HasDiskTier() && strings.TrimSpace(options.IncludeSiteRoot) == "" {
+		if c.mode.HasDiskTier() {
 			if storeErr := c.storeDiskProgram(normalized, sourceInfo.ModTime(), program); storeErr != nil {
 				log.Printf("Warning: failed to persist bytecode cache to disk for %s: %v", normalized, storeErr)
 			}
@@ -1032,6 +1161,7 @@ func (c *ScriptCache) compileOnly(filePath string, options ScriptCompileOptions)
 
 	compiler.SetSourceName(filePath)
 	compiler.SetIncludeSiteRoot(options.IncludeSiteRoot)
+	compiler.SetIncludeVirtualDirectories(options.IncludeVirtualDirectories)
 	if err := compiler.Compile(); err != nil {
 		return CachedProgram{}, err
 	}
@@ -1140,7 +1270,7 @@ func NewVMFromCachedProgram(program CachedProgram) *VM {
 	vm.optionExplicit = program.OptionExplicit
 	vm.sourceName = program.SourceName
 	if len(program.SourceMapEntries) > 0 {
-		vm.sourceMap = SourceMap{entries: cloneSourceMapEntries(program.SourceMapEntries)}
+		vm.sourceMap = SourceMap{entries: cloneSourceMapEntries(program.SourceMapEntries), includes: cloneSourceMapIncludes(program.SourceMapIncludes)}
 	} else {
 		vm.sourceMap = buildIdentitySourceMap(program.SourceName)
 	}
@@ -1766,6 +1896,68 @@ func readSourceMapEntries(reader io.Reader) ([]SourceMapEntry, error) {
 	return entries, nil
 }
 
+func writeSourceMapIncludes(writer io.Writer, includes []SourceMapInclude) error {
+	if uint64(len(includes)) > uint64(^uint32(0)) {
+		return errors.New("source map includes too large")
+	}
+	if err := binary.Write(writer, binary.LittleEndian, uint32(len(includes))); err != nil {
+		return err
+	}
+	for i := range includes {
+		include := includes[i]
+		if err := binary.Write(writer, binary.LittleEndian, [2]int32{int32(include.MergedLineStart), int32(include.MergedLineEnd)}); err != nil {
+			return err
+		}
+		if err := writeString(writer, include.File); err != nil {
+			return err
+		}
+		if err := writeString(writer, include.IncludedFrom); err != nil {
+			return err
+		}
+		if err := binary.Write(writer, binary.LittleEndian, int32(include.IncludedAtLine)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSourceMapIncludes(reader io.Reader) ([]SourceMapInclude, error) {
+	var length uint32
+	if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	includes := make([]SourceMapInclude, int(length))
+	for i := 0; i < int(length); i++ {
+		var mergedLines [2]int32
+		if err := binary.Read(reader, binary.LittleEndian, &mergedLines); err != nil {
+			return nil, err
+		}
+		file, err := readString(reader)
+		if err != nil {
+			return nil, err
+		}
+		includedFrom, err := readString(reader)
+		if err != nil {
+			return nil, err
+		}
+		var includedAtLine int32
+		if err := binary.Read(reader, binary.LittleEndian, &includedAtLine); err != nil {
+			return nil, err
+		}
+		includes[i] = SourceMapInclude{
+			MergedLineStart: int(mergedLines[0]),
+			MergedLineEnd:   int(mergedLines[1]),
+			File:            file,
+			IncludedFrom:    includedFrom,
+			IncludedAtLine:  int(includedAtLine),
+		}
+	}
+	return includes, nil
+}
+
 func writeSerializedValue(writer io.Writer, value Value) error {
 	if err := binary.Write(writer, binary.LittleEndian, uint8(value.Type)); err != nil {
 		return err
@@ -1862,6 +2054,7 @@ func cloneCachedProgram(program CachedProgram) CachedProgram {
 		ConstGlobalNames:    cloneStringSlice(program.ConstGlobalNames),
 		IncludeDependencies: cloneStringSlice(program.IncludeDependencies),
 		SourceMapEntries:    cloneSourceMapEntries(program.SourceMapEntries),
+		SourceMapIncludes:   cloneSourceMapIncludes(program.SourceMapIncludes),
 		RecordDecls:         cloneRecordDeclSlice(program.RecordDecls),
 		RecordDeclLookup:    cloneIntMap(program.RecordDeclLookup),
 	}
@@ -1927,6 +2120,15 @@ func cloneSourceMapEntries(values []SourceMapEntry) []SourceMapEntry {
 	return cloned
 }
 
+func cloneSourceMapIncludes(values []SourceMapInclude) []SourceMapInclude {
+	if len(values) == 0 {
+		return nil
+	}
+	cloned := make([]SourceMapInclude, len(values))
+	copy(cloned, values)
+	return cloned
+}
+
 func cloneIntMap(values map[string]int) map[string]int {
 	if len(values) == 0 {
 		return nil
@@ -172,6 +172,7 @@ func buildCachedProgramFromCompiler(compiler *Compiler) CachedProgram {
 		FuncParamDefaults:   cloneFuncParamDefaultsMap(compiler.funcParamDefaults),
 		IncludeDependencies: compiler.IncludeDependencies(),
 		SourceMapEntries:    compiler.SourceMapEntries(),
+		SourceMapIncludes:   compiler.SourceMapIncludes(),
 		RecordDecls:         cloneRecordDeclSlice(compiler.recordDecls),
 		RecordDeclLookup:    cloneIntMap(compiler.recordDeclLookup),
 		JSICNodeCount:       compiler.jsICNodeCount,
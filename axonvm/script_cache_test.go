@@ -147,6 +147,51 @@ func TestScriptCacheDependencyInvalidation(t *testing.T) {
 	}
 }
 
+// TestScriptCacheClearAndWatchListener verifies Clear and that listeners receive
+// events for files outside the watched extensions.
+func TestScriptCacheClearAndWatchListener(t *testing.T) {
+	cache := NewScriptCache(BytecodeCacheMemoryOnly, t.TempDir(), 8)
+	root := t.TempDir()
+	scriptPath := filepath.Join(root, "default.asp")
+	cache.Put(scriptPath, CachedProgram{Bytecode: []byte{1}, GlobalCount: 1}, []string{filepath.Join(root, "header.inc")})
+
+	if err := cache.StartInvalidator([]string{root}); err != nil {
+		t.Fatalf("start invalidator: %v", err)
+	}
+	defer cache.StopInvalidator()
+	if !cache.WatchesDir(root) {
+		t.Fatalf("expected the invalidator to watch %s", root)
+	}
+	events := make(chan string, 8)
+	cache.AddWatchListener(func(path string) {
+		select {
+		case events <- path:
+		default:
+		}
+	})
+
+	offlinePath := filepath.Join(root, "app_offline.htm")
+	if err := os.WriteFile(offlinePath, []byte("offline"), 0o644); err != nil {
+		t.Fatalf("write offline file: %v", err)
+	}
+	select {
+	case path := <-events:
+		if !strings.EqualFold(filepath.Base(path), "app_offline.htm") {
+			t.Fatalf("expected an event for app_offline.htm, got %s", path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the listener to receive the app_offline.htm event")
+	}
+
+	cache.Clear()
+	if _, ok := cache.Get(scriptPath); ok {
+		t.Fatal("expected Clear to drop the cached program")
+	}
+	if stats := cache.Stats(); stats.Entries != 0 || stats.SizeBytes != 0 {
+		t.Fatalf("expected an empty memory tier, got %+v", stats)
+	}
+}
+
 // TestScriptCacheDiskInvalidatesWhenBinaryIsNewer verifies stale disk cache is rejected after a rebuild.
 func TestScriptCacheDiskInvalidatesWhenBinaryIsNewer(t *testing.T) {
 	cacheDir := t.TempDir()
@@ -440,3 +485,43 @@ func TestScriptCacheNormalizeScriptCacheKeyWindowsCompatibility(t *testing.T) {
 		t.Fatalf("expected non-windows cache key to preserve case, got %q want %q", normalized, mixed)
 	}
 }
+
+// TestScriptCacheDiskServesPagesCompiledForSiteRoot verifies that a new cache
+// loads pages compiled with a site root from disk, and compiles again for
+// another root.
+func TestScriptCacheDiskServesPagesCompiledForSiteRoot(t *testing.T) {
+	siteRoot := t.TempDir()
+	cacheDir := t.TempDir()
+	sourcePath := filepath.Join(siteRoot, "default.asp")
+	if err := os.MkdirAll(filepath.Join(siteRoot, "inc"), 0o755); err != nil {
+		t.Fatalf("mkdir include: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(siteRoot, "inc", "header.inc"), []byte("header"), 0o644); err != nil {
+		t.Fatalf("write include: %v", err)
+	}
+	if err := os.WriteFile(sourcePath, []byte("<!--#include virtual=\"/inc/header.inc\"--><% Response.Write 1 %>"), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+	previousHook := scriptCacheProcessBinaryModUnix
+	defer func() { scriptCacheProcessBinaryModUnix = previousHook }()
+	scriptCacheProcessBinaryModUnix = func() int64 { return 0 }
+
+	options := ScriptCompileOptions{IncludeSiteRoot: siteRoot}
+	if _, err := NewScriptCache(BytecodeCacheEnabled, cacheDir, 8).LoadOrCompileWithOptions(sourcePath, options); err != nil {
+		t.Fatalf("first compile: %v", err)
+	}
+
+	cold := NewScriptCache(BytecodeCacheEnabled, cacheDir, 8)
+	if _, err := cold.LoadOrCompileWithOptions(sourcePath, options); err != nil {
+		t.Fatalf("cold load: %v", err)
+	}
+	if stats := cold.Stats(); stats.DiskHits != 1 || stats.Compiles != 0 {
+		t.Fatalf("expected a disk hit without compiling, got %+v", stats)
+	}
+
+	other := NewScriptCache(BytecodeCacheEnabled, cacheDir, 8)
+	_, _ = other.LoadOrCompileWithOptions(sourcePath, ScriptCompileOptions{IncludeSiteRoot: filepath.Join(siteRoot, "inc")})
+	if stats := other.Stats(); stats.DiskHits != 0 {
+		t.Fatalf("expected another site root to skip the disk entry, got %+v", stats)
+	}
+}
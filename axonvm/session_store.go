@@ -0,0 +1,120 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonvm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+	"github.com/spf13/viper"
+)
+
+const (
+	// SessionStoreFile keeps one G3SES file per session in temp_dir/session.
+	SessionStoreFile = "file"
+	// SessionStoreSQL keeps sessions in a table reached through the G3DB drivers.
+	SessionStoreSQL = "sql"
+
+	defaultSessionStoreTable = "axonasp_sessions"
+
+	// SessionEncryptionKeyEnv holds the session encryption key when
+	// global.session_encryption_key is empty.
+	SessionEncryptionKeyEnv = "AXONASP_SESSION_ENCRYPTION_KEY"
+	// SessionSigningKeyEnv holds the session cookie signing key when
+	// global.session_signing_key is empty.
+	SessionSigningKeyEnv = "AXONASP_SESSION_SIGNING_KEY"
+)
+
+// SessionStoreConfig holds the session_store keys of the [global] section and
+// the keys that protect stored sessions and session cookies.
+type SessionStoreConfig struct {
+	Backend string
+	Driver  string
+	Table   string
+	// EncryptionKey seals stored sessions with AES-GCM when it is not empty.
+	EncryptionKey string
+	// SigningKey signs the session cookie with HMAC-SHA256 when it is not empty.
+	SigningKey string
+}
+
+// SessionStoreConfigFromViper reads the session store keys of the [global] section.
+func SessionStoreConfigFromViper(v *viper.Viper) SessionStoreConfig {
+	cfg := SessionStoreConfig{
+		Backend:       SessionStoreFile,
+		Driver:        "sqlite",
+		Table:         defaultSessionStoreTable,
+		EncryptionKey: os.Getenv(SessionEncryptionKeyEnv),
+		SigningKey:    os.Getenv(SessionSigningKeyEnv),
+	}
+	if v == nil {
+		return cfg
+	}
+	if backend := strings.ToLower(strings.TrimSpace(v.GetString("global.session_store"))); backend != "" {
+		cfg.Backend = backend
+	}
+	if driver := strings.TrimSpace(v.GetString("global.session_store_driver")); driver != "" {
+		cfg.Driver = driver
+	}
+	if table := strings.TrimSpace(v.GetString("global.session_store_table")); table != "" {
+		cfg.Table = table
+	}
+	if key := v.GetString("global.session_encryption_key"); key != "" {
+		cfg.EncryptionKey = key
+	}
+	if key := v.GetString("global.session_signing_key"); key != "" {
+		cfg.SigningKey = key
+	}
+	return cfg
+}
+
+// ConfigureSessionStore installs the session store selected by cfg for every
+// host of the process and closes the store it replaces. The SQL store connects
+// with the [g3db] settings. It also installs the session encryption and cookie
+// signing keys.
+func ConfigureSessionStore(cfg SessionStoreConfig) error {
+	if err := asp.SetSessionEncryptionKey(cfg.EncryptionKey); err != nil {
+		return err
+	}
+	asp.SetSessionSigningKey(cfg.SigningKey)
+
+	var store asp.SessionStore
+	switch cfg.Backend {
+	case "", SessionStoreFile:
+	case SessionStoreSQL:
+		sqlStore, err := newConfiguredSQLSessionStore(cfg)
+		if err != nil {
+			return err
+		}
+		store = sqlStore
+	default:
+		return fmt.Errorf("unknown session store %q", cfg.Backend)
+	}
+
+	previous := asp.CurrentSessionStore()
+	asp.SetSessionStore(store)
+	if closer, ok := previous.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+//go:build !wasm && !lib_g3db_disabled
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonvm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+const sessionStoreQueryTimeout = 5 * time.Second
+
+// sessionStoreTablePattern accepts plain and schema-qualified table names.
+var sessionStoreTablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// newConfiguredSQLSessionStore opens the SQL session store selected by cfg.
+func newConfiguredSQLSessionStore(cfg SessionStoreConfig) (asp.SessionStore, error) {
+	return NewSQLSessionStore(cfg.Driver, cfg.Table)
+}
+
+// SQLSessionStore keeps sessions in one table reached through the G3DB
+// drivers, so all nodes of a load-balanced farm share them. Each row holds the
+// G3SES encoding written by Session.Save. The table is created when missing:
+//
+//	id              VARCHAR(64) primary key
+//	data            binary G3SES record
+//	last_accessed   BIGINT, Unix seconds
+//	timeout_seconds INTEGER
+type SQLSessionStore struct {
+	db *sql.DB
+
+	loadQuery    string
+	upsertQuery  string
+	deleteQuery  string
+	touchQuery   string
+	expiredQuery string
+}
+
+// NewSQLSessionStore opens the G3DB connection for driver and prepares the
+// session table. SQLite, MySQL, PostgreSQL and SQL Server are supported.
+func NewSQLSessionStore(driver string, table string) (*SQLSessionStore, error) {
+	db, normalized, err := OpenG3DBFromConfig(driver)
+	if err != nil {
+		return nil, fmt.Errorf("open session database: %w", err)
+	}
+	store, err := NewSQLSessionStoreWithDB(db, normalized, table)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewSQLSessionStoreWithDB creates the store on an existing connection pool of
+// a normalized G3DB driver and creates table when it does not exist.
+func NewSQLSessionStoreWithDB(db *sql.DB, driver string, table string) (*SQLSessionStore, error) {
+	driver = g3dbNormalizeDriver(driver)
+	if table == "" {
+		table = defaultSessionStoreTable
+	}
+	if !sessionStoreTablePattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid session store table name %q", table)
+	}
+
+	var createQuery, blobType string
+	switch driver {
+	case "sqlite":
+		blobType = "BLOB"
+	case "mysql":
+		blobType = "LONGBLOB"
+	case "postgres":
+		blobType = "BYTEA"
+	case "mssql":
+		blobType = "VARBINARY(MAX)"
+	default:
+		return nil, fmt.Errorf("the session store does not support the %q driver", driver)
+	}
+	columns := "(id VARCHAR(64) NOT NULL PRIMARY KEY, data " + blobType + " NOT NULL, last_accessed BIGINT NOT NULL, timeout_seconds INTEGER NOT NULL)"
+	if driver == "mssql" {
+		createQuery = "IF OBJECT_ID(N'" + table + "', N'U') IS NULL CREATE TABLE " + table + " " + columns
+	} else {
+		createQuery = "CREATE TABLE IF NOT EXISTS " + table + " " + columns
+	}
+
+	var upsertQuery string
+	switch driver {
+	case "sqlite", "postgres":
+		upsertQuery = "INSERT INTO " + table + " (id, data, last_accessed, timeout_seconds) VALUES (?, ?, ?, ?)" +
+			" ON CONFLICT (id) DO UPDATE SET data = excluded.data, last_accessed = excluded.last_accessed, timeout_seconds = excluded.timeout_seconds"
+	case "mysql":
+		upsertQuery = "INSERT INTO " + table + " (id, data, last_accessed, timeout_seconds) VALUES (?, ?, ?, ?)" +
+			" ON DUPLICATE KEY UPDATE data = VALUES(data), last_accessed = VALUES(last_accessed), timeout_seconds = VALUES(timeout_seconds)"
+	case "mssql":
+		upsertQuery = "MERGE INTO " + table + " WITH (HOLDLOCK) AS target" +
+			" USING (SELECT ? AS id, ? AS data, ? AS last_accessed, ? AS timeout_seconds) AS source ON target.id = source.id" +
+			" WHEN MATCHED THEN UPDATE SET data = source.data, last_accessed = source.last_accessed, timeout_seconds = source.timeout_seconds" +
+			" WHEN NOT MATCHED THEN INSERT (id, data, last_accessed, timeout_seconds) VALUES (source.id, source.data, source.last_accessed, source.timeout_seconds);"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreQueryTimeout)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, createQuery); err != nil {
+		return nil, fmt.Errorf("create session table: %w", err)
+	}
+
+	return &SQLSessionStore{
+		db:           db,
+		loadQuery:    g3dbRewritePlaceholders("SELECT data, last_accessed, timeout_seconds FROM "+table+" WHERE id = ?", driver),
+		upsertQuery:  g3dbRewritePlaceholders(upsertQuery, driver),
+		deleteQuery:  g3dbRewritePlaceholders("DELETE FROM "+table+" WHERE id = ?", driver),
+		touchQuery:   g3dbRewritePlaceholders("UPDATE "+table+" SET last_accessed = ? WHERE id = ?", driver),
+		expiredQuery: g3dbRewritePlaceholders("SELECT id FROM "+table+" WHERE last_accessed + timeout_seconds < ?", driver),
+	}, nil
+}
+
+// Shared implements asp.SharedSessionStore.
+func (s *SQLSessionStore) Shared() bool {
+	return true
+}
+
+// Load implements asp.SessionStore.
+func (s *SQLSessionStore) Load(id string) (asp.SessionRecord, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreQueryTimeout)
+	defer cancel()
+
+	var data []byte
+	var lastAccessed, timeoutSeconds int64
+	err := s.db.QueryRowContext(ctx, s.loadQuery, id).Scan(&data, &lastAccessed, &timeoutSeconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return asp.SessionRecord{}, false, nil
+	}
+	if err != nil {
+		return asp.SessionRecord{}, false, fmt.Errorf("load session: %w", err)
+	}
+	return asp.SessionRecord{
+		ID:           id,
+		Data:         data,
+		LastAccessed: time.Unix(lastAccessed, 0),
+		Timeout:      time.Duration(timeoutSeconds) * time.Second,
+	}, true, nil
+}
+
+// Save implements asp.SessionStore.
+func (s *SQLSessionStore) Save(record asp.SessionRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreQueryTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, s.upsertQuery, record.ID, record.Data, record.LastAccessed.Unix(), int64(record.Timeout/time.Second))
+	if err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	return nil
+}
+
+// Delete implements asp.SessionStore.
+func (s *SQLSessionStore) Delete(id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreQueryTimeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.deleteQuery, id)
+	if err != nil {
+		return false, fmt.Errorf("delete session: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete session: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// Touch implements asp.SessionStore.
+func (s *SQLSessionStore) Touch(id string, lastAccessed time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreQueryTimeout)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, s.touchQuery, lastAccessed.Unix(), id); err != nil {
+		return fmt.Errorf("touch session: %w", err)
+	}
+	return nil
+}
+
+// ListExpired implements asp.SessionStore.
+func (s *SQLSessionStore) ListExpired(now time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionStoreQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.expiredQuery, now.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("list expired sessions: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("list expired sessions: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list expired sessions: %w", err)
+	}
+	return ids, nil
+}
+
+// Close releases the connection pool.
+func (s *SQLSessionStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,132 @@
+//go:build !wasm && !lib_g3db_disabled
+
+package axonvm
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+func newTestSQLSessionStore(t *testing.T) *SQLSessionStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	store, err := NewSQLSessionStoreWithDB(db, "sqlite", "")
+	if err != nil {
+		_ = db.Close()
+		t.Fatalf("NewSQLSessionStoreWithDB failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestSQLSessionStoreRoundtripTouchAndExpiry(t *testing.T) {
+	store := newTestSQLSessionStore(t)
+	asp.SetSessionStore(store)
+	t.Cleanup(func() { asp.SetSessionStore(nil) })
+
+	if !asp.SessionStoreShared() {
+		t.Fatal("expected the SQL store to be shared")
+	}
+
+	session, err := asp.CreateSession()
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	session.Set("Cart", asp.NewApplicationString("book"))
+	session.SetTimeout(5)
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, found, err := asp.LoadSession(session.ID)
+	if err != nil || !found {
+		t.Fatalf("LoadSession found=%v err=%v", found, err)
+	}
+	if cart, ok := loaded.Get("cart"); !ok || cart.Str != "book" {
+		t.Fatalf("unexpected cart %#v", cart)
+	}
+	if loaded.GetTimeout() != 5 {
+		t.Fatalf("expected timeout 5, got %d", loaded.GetTimeout())
+	}
+
+	later := time.Now().Add(4 * time.Minute)
+	if err := store.Touch(session.ID, later); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+	record, found, err := store.Load(session.ID)
+	if err != nil || !found || record.LastAccessed.Unix() != later.Unix() || record.Timeout != 5*time.Minute {
+		t.Fatalf("unexpected record %+v found=%v err=%v", record, found, err)
+	}
+
+	expired, err := store.ListExpired(later.Add(4 * time.Minute))
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("expected no expired sessions, got %v err=%v", expired, err)
+	}
+	expired, err = store.ListExpired(later.Add(6 * time.Minute))
+	if err != nil || len(expired) != 1 || expired[0] != session.ID {
+		t.Fatalf("expected %s to expire, got %v err=%v", session.ID, expired, err)
+	}
+
+	if err := session.Delete(); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, found, err := store.Load(session.ID); err != nil || found {
+		t.Fatalf("expected deleted session, found=%v err=%v", found, err)
+	}
+}
+
+func TestSQLSessionStoreGetOrCreateSessionSeesOtherNodeChanges(t *testing.T) {
+	store := newTestSQLSessionStore(t)
+	asp.SetSessionStore(store)
+	t.Cleanup(func() { asp.SetSessionStore(nil) })
+
+	session, _, err := asp.GetOrCreateSession("")
+	if err != nil {
+		t.Fatalf("GetOrCreateSession failed: %v", err)
+	}
+	session.Set("Step", asp.NewApplicationInteger(1))
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Another node writes the same session.
+	other := asp.NewSessionWithID(session.ID)
+	other.Set("Step", asp.NewApplicationInteger(2))
+	if err := other.Save(); err != nil {
+		t.Fatalf("Save from other node failed: %v", err)
+	}
+
+	current, isNew, err := asp.GetOrCreateSession(session.ID)
+	if err != nil || isNew {
+		t.Fatalf("GetOrCreateSession isNew=%v err=%v", isNew, err)
+	}
+	if step, ok := current.Get("Step"); !ok || step.Num != 2 {
+		t.Fatalf("expected the other node's value, got %#v", step)
+	}
+}
+
+func TestNewSQLSessionStoreRejectsInvalidTableAndDriver(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := NewSQLSessionStoreWithDB(db, "sqlite", "sessions; DROP TABLE users"); err == nil {
+		t.Fatal("expected an invalid table name to be rejected")
+	}
+	if _, err := NewSQLSessionStoreWithDB(db, "oracle", ""); err == nil {
+		t.Fatal("expected the oracle driver to be rejected")
+	}
+	if err := ConfigureSessionStore(SessionStoreConfig{Backend: "redis"}); err == nil {
+		t.Fatal("expected an unknown backend to be rejected")
+	}
+}
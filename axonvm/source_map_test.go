@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -250,3 +251,41 @@ func TestASPIncludeVBCompileErrorMissingRHSMapsToMainLine(t *testing.T) {
 		t.Fatalf("expected actionable column at or after assignment target, got %d", syntaxErr.Column)
 	}
 }
+
+func TestSourceMapIncludeChainListsNestedIncludesInnermostFirst(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainPath := filepath.Join(tmpDir, "main.asp")
+	outerPath := filepath.Join(tmpDir, "outer.inc")
+	innerPath := filepath.Join(tmpDir, "inner.inc")
+
+	if err := os.WriteFile(innerPath, []byte("inner1\r\ninner2\r\n"), 0o600); err != nil {
+		t.Fatalf("write inner include failed: %v", err)
+	}
+	if err := os.WriteFile(outerPath, []byte("outer1\r\n<!--#include file=\"inner.inc\"-->\r\nouter3\r\n"), 0o600); err != nil {
+		t.Fatalf("write outer include failed: %v", err)
+	}
+	mainSource := "<%\r\nDim a\r\n%>\r\n<!--#include file=\"outer.inc\"-->\r\nmain5\r\n"
+
+	merged, sourceMap, err := preprocessASPIncludesWithDepsWithOptions(mainSource, mainPath, map[string]bool{}, 0, nil, defaultIncludeResolveOptions())
+	if err != nil {
+		t.Fatalf("preprocess failed: %v", err)
+	}
+	lines := strings.Split(merged, "\r\n")
+	innerLine := slices.Index(lines, "inner2") + 1
+	if file, line, ok := sourceMap.ResolveLine(innerLine); !ok || file != innerPath || line != 2 {
+		t.Fatalf("expected inner.inc:2, got %s:%d", file, line)
+	}
+	chain := sourceMap.IncludeChain(innerLine)
+	want := []SourceLocation{{File: outerPath, Line: 2}, {File: mainPath, Line: 4}}
+	if !slices.Equal(chain, want) {
+		t.Fatalf("expected include chain %+v, got %+v", want, chain)
+	}
+
+	mainLine := slices.Index(lines, "main5") + 1
+	if file, line, ok := sourceMap.ResolveLine(mainLine); !ok || file != mainPath || line != 5 {
+		t.Fatalf("expected main.asp:5, got %s:%d", file, line)
+	}
+	if chain := sourceMap.IncludeChain(mainLine); len(chain) != 0 {
+		t.Fatalf("expected no include chain for the page, got %+v", chain)
+	}
+}
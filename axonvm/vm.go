@@ -23,6 +23,7 @@ package axonvm
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -426,6 +427,7 @@ type VM struct {
 	collectionItems                map[int64]*vbsCollection
 	collectionEnumeratorItems      map[int64]*vbsCollectionEnumerator
 	nativeObjectProxies            map[int64]nativeObjectProxy
+	stateObjects                   map[stateObjectKey]*stateObjectBinding
 	jsObjectItems                  map[int64]map[string]Value
 	jsObjectKeyOrder               map[int64][]string
 	jsObjectSlots                  map[int64][]Value
@@ -566,6 +568,7 @@ type VM struct {
 	constGlobals         map[string]bool   // Global Const protection state for dynamic compilation.
 	sourceName           string            // Source file path used for dynamic execution error reporting.
 	sourceMap            SourceMap         // Sparse merged-to-original source line mapping for include-aware errors.
+	tracer               *StackTracer      // Optional call stack mirror read by slow request logs.
 	dynamicProgramStarts map[uint64]int    // Per-VM start offsets for already-appended cached dynamic fragments.
 	jsStringWorkBytes    int64             // Per-run cumulative bytes produced by JScript string operations.
 
@@ -608,6 +611,8 @@ type VM struct {
 
 	pooledFrom      *vmProgramPool
 	pooledSlot      chan struct{}
+	pooledCounted   bool
+	requestCtx      context.Context
 	comInitialized  bool
 	comThreadLocked bool
 	staTaskChan     chan func()
@@ -775,6 +780,7 @@ func NewVM(bytecode []byte, constants []Value, globalCount int) *VM {
 		collectionItems:                make(map[int64]*vbsCollection),
 		collectionEnumeratorItems:      make(map[int64]*vbsCollectionEnumerator),
 		nativeObjectProxies:            make(map[int64]nativeObjectProxy),
+		stateObjects:                   make(map[stateObjectKey]*stateObjectBinding),
 		jsObjectItems:                  make(map[int64]map[string]Value),
 		jsObjectKeyOrder:               make(map[int64][]string),
 		jsObjectSlots:                  make(map[int64][]Value),
@@ -1550,6 +1556,9 @@ func (vm *VM) cloneForExecuteGlobal(startIP int) *VM {
 	child.fp = 0
 	child.callStack = make([]CallFrame, 0, 16)
 	child.withStack = make([]Value, 0, 8)
+	// The child restarts callStack at depth zero, so it cannot share the
+	// parent's tracer. Slow logs show the ExecuteGlobal statement instead.
+	child.tracer = nil
 	// Copy declared type arrays for VB6 As Type support.
 	child.localTypes = vm.localTypes
 	child.globalTypes = make([]ValueType, len(vm.globalTypes))
@@ -1783,6 +1792,7 @@ func (vm *VM) syncExecuteGlobalState(child *VM) {
 	vm.collectionItems = child.collectionItems
 	vm.collectionEnumeratorItems = child.collectionEnumeratorItems
 	vm.nativeObjectProxies = child.nativeObjectProxies
+	vm.stateObjects = child.stateObjects
 	vm.jsObjectItems = child.jsObjectItems
 	vm.jsObjectKeyOrder = child.jsObjectKeyOrder
 	vm.jsObjectStateItems = child.jsObjectStateItems
@@ -1824,6 +1834,25 @@ func (vm *VM) SetHost(h ASPHostEnvironment) {
 	vm.output = h
 }
 
+// SetContext attaches the context of the request served by the VM. Libraries
+// use it to cancel outgoing work when the client goes away.
+func (vm *VM) SetContext(ctx context.Context) {
+	if vm != nil {
+		vm.requestCtx = ctx
+	}
+}
+
+// Context returns the request context set by SetContext on the VM or its
+// parent, or context.Background.
+func (vm *VM) Context() context.Context {
+	for current := vm; current != nil; current = current.parentVM {
+		if current.requestCtx != nil {
+			return current.requestCtx
+		}
+	}
+	return context.Background()
+}
+
 // SetOutput sets the output writer for the VM.
 func (vm *VM) SetOutput(w io.Writer) {
 	vm.output = w
@@ -1891,6 +1920,11 @@ func (vm *VM) Run() (err error) {
 			vm.jsCleanupCollections()
 		}
 	}()
+	if isRootRun {
+		// Objects read from or stored in Session and Application are written
+		// back before the host saves the session.
+		defer vm.flushStateObjects()
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			if endSignal, ok := r.(string); ok && endSignal == asp.ResponseEndSignal {
@@ -2005,8 +2039,13 @@ aspExecLoop:
 		if operationCount&63 == 0 {
 			vm.jsPumpNodeAsyncTasks(32)
 		}
-		if operationCount&1023 == 0 && vm.host != nil && vm.host.Server() != nil && vm.host.Server().HasTimedOut() {
-			return vm.newMappedAxonASPError(ErrScriptTimeout, nil, fmt.Sprintf("Script execution exceeded the configured timeout of %d second(s)", vm.host.Server().GetScriptTimeout()))
+		if operationCount&1023 == 0 && vm.host != nil && vm.host.Server() != nil {
+			if vm.host.Server().IsTerminated() {
+				return vm.newMappedAxonASPError(ErrRequestTerminated, nil, "Script execution was terminated by the server")
+			}
+			if vm.host.Server().HasTimedOut() {
+				return vm.newMappedAxonASPError(ErrScriptTimeout, nil, fmt.Sprintf("Script execution exceeded the configured timeout of %d second(s)", vm.host.Server().GetScriptTimeout()))
+			}
 		}
 		op := OpCode(vm.bytecode[vm.ip])
 		vm.ip++
@@ -4081,6 +4120,9 @@ aspExecLoop:
 				vm.lastColumn = 0
 				vm.ip += 2
 			}
+			if vm.tracer != nil {
+				vm.traceLine()
+			}
 			// If Resume Next absorbed an error mid-statement, restore the stack and clear the flag.
 			if vm.skipToNextStmt {
 				vm.sp = vm.stmtSP
@@ -5814,6 +5856,9 @@ func (vm *VM) prepareTransactionEventCall() bool {
 		oldSP:    vm.sp,
 		boundObj: vm.activeClassObjectID,
 	})
+	if vm.tracer != nil {
+		vm.traceCall(handler)
+	}
 	vm.fp = vm.sp + 1
 	localCount := handler.UserSubLocalCount()
 	for i := range localCount {
@@ -7028,6 +7073,7 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 			// Server.Execute(path) — runs another ASP file inline, sharing the current host context.
 			if len(args) >= 1 {
 				absPath := server.MapPath(args[0].String())
+				vm.flushStateObjects()
 				if err := vm.host.ExecuteASPFile(absPath); err != nil {
 					var aspErr *asp.ASPError
 					if os.IsNotExist(err) || errors.Is(err, os.ErrNotExist) {
@@ -7078,6 +7124,7 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 			if len(args) >= 1 {
 				absPath := server.MapPath(args[0].String())
 				vm.host.Response().Clear()
+				vm.flushStateObjects()
 				_ = vm.host.ExecuteASPFile(absPath)
 				panic(asp.ResponseEndSignal)
 			}
@@ -7092,12 +7139,12 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		switch {
 		case member == "":
 			if len(args) >= 2 {
-				session.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(session, args[0].String(), args[1])
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
-				if sessionValue, ok := session.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(sessionValue)
+				if value, ok := vm.stateValue(session, args[0].String()); ok {
+					return value
 				}
 			}
 			return Value{Type: VTEmpty}
@@ -7109,48 +7156,68 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Set"):
 			if len(args) >= 2 {
-				session.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(session, args[0].String(), args[1])
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Get") || strings.EqualFold(member, "Item") || strings.EqualFold(member, "Contents"):
 			if len(args) >= 1 {
-				if sessionValue, ok := session.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(sessionValue)
+				if value, ok := vm.stateValue(session, args[0].String()); ok {
+					return value
 				}
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Contents.Item") || strings.EqualFold(member, "Contents"):
 			if len(args) >= 2 {
-				session.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(session, args[0].String(), args[1])
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
-				if sessionValue, ok := session.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(sessionValue)
+				if value, ok := vm.stateValue(session, args[0].String()); ok {
+					return value
 				}
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Contents.Remove"):
 			if len(args) >= 1 {
 				session.Remove(args[0].String())
+				vm.unbindStateValue(session, args[0].String())
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Contents.RemoveAll"):
 			session.RemoveAll()
+			vm.unbindStateValues(session)
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Contents.Count"):
 			return NewInteger(int64(session.Count()))
 		case strings.EqualFold(member, "Remove"):
 			if len(args) >= 1 {
 				session.Remove(args[0].String())
+				vm.unbindStateValue(session, args[0].String())
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "RemoveAll"):
 			session.RemoveAll()
+			vm.unbindStateValues(session)
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Abandon"):
 			session.Abandon()
 			return Value{Type: VTEmpty}
+		case strings.EqualFold(member, "Regenerate"):
+			// The new ID travels in a cookie, so it cannot be sent once the
+			// headers were. Keep the old ID instead of losing the session.
+			cookieHost, sendsCookie := vm.host.(SessionCookieHost)
+			if sendsCookie && vm.host.Response().HeadersSent() {
+				vm.raise(vbscript.CannotPerformTheRequestedOperation, "Session.Regenerate must be called before the HTTP headers are sent")
+				return Value{Type: VTEmpty}
+			}
+			err := session.Regenerate()
+			if sendsCookie {
+				cookieHost.ReissueSessionCookie()
+			}
+			if err != nil {
+				vm.raise(vbscript.InternalError, err.Error())
+			}
+			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Count"):
 			return NewInteger(int64(session.Count()))
 		case strings.EqualFold(member, "Exists"):
@@ -7187,7 +7254,9 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 				return Value{Type: VTNativeObject, Num: nativeObjectSessionStaticObjects}
 			}
 			if len(args) >= 2 {
-				session.AddStaticObject(args[0].String(), vm.valueToApplicationValue(args[1]))
+				if value, ok := vm.valueToApplicationValue(args[1]); ok {
+					session.AddStaticObject(args[0].String(), value)
+				}
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
@@ -7206,13 +7275,13 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		case member == "":
 			if len(args) >= 2 {
 				application.WaitForServer(server)
-				application.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(application, args[0].String(), args[1])
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
 				application.WaitForServer(server)
-				if appValue, ok := application.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(appValue)
+				if value, ok := vm.stateValue(application, args[0].String()); ok {
+					return value
 				}
 				if appValue, ok := application.GetStaticObject(args[0].String()); ok {
 					return vm.applicationValueToValue(appValue)
@@ -7228,14 +7297,14 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		case strings.EqualFold(member, "Set"):
 			if len(args) >= 2 {
 				application.WaitForServer(server)
-				application.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(application, args[0].String(), args[1])
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Get") || strings.EqualFold(member, "Item"):
 			if len(args) >= 1 {
 				application.WaitForServer(server)
-				if appValue, ok := application.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(appValue)
+				if value, ok := vm.stateValue(application, args[0].String()); ok {
+					return value
 				}
 				if appValue, ok := application.GetStaticObject(args[0].String()); ok {
 					return vm.applicationValueToValue(appValue)
@@ -7245,13 +7314,13 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		case strings.EqualFold(member, "Contents.Item") || strings.EqualFold(member, "Contents"):
 			if len(args) >= 2 {
 				application.WaitForServer(server)
-				application.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(application, args[0].String(), args[1])
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
 				application.WaitForServer(server)
-				if appValue, ok := application.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(appValue)
+				if value, ok := vm.stateValue(application, args[0].String()); ok {
+					return value
 				}
 			}
 			return Value{Type: VTEmpty}
@@ -7259,11 +7328,13 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 			if len(args) >= 1 {
 				application.WaitForServer(server)
 				application.Remove(args[0].String())
+				vm.unbindStateValue(application, args[0].String())
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Contents.RemoveAll"):
 			application.WaitForServer(server)
 			application.RemoveAll()
+			vm.unbindStateValues(application)
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Contents.Count"):
 			application.WaitForServer(server)
@@ -7271,7 +7342,9 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		case strings.EqualFold(member, "StaticObjects.Item") || strings.EqualFold(member, "StaticObjects"):
 			if len(args) >= 2 {
 				application.WaitForServer(server)
-				application.AddStaticObject(args[0].String(), vm.valueToApplicationValue(args[1]))
+				if value, ok := vm.valueToApplicationValue(args[1]); ok {
+					application.AddStaticObject(args[0].String(), value)
+				}
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
@@ -7288,11 +7361,13 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 			if len(args) >= 1 {
 				application.WaitForServer(server)
 				application.Remove(args[0].String())
+				vm.unbindStateValue(application, args[0].String())
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "RemoveAll"):
 			application.WaitForServer(server)
 			application.RemoveAll()
+			vm.unbindStateValues(application)
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Count"):
 			application.WaitForServer(server)
@@ -7313,22 +7388,24 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		switch {
 		case member == "" || strings.EqualFold(member, "Item"):
 			if len(args) >= 2 {
-				session.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(session, args[0].String(), args[1])
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
-				if sessionValue, ok := session.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(sessionValue)
+				if value, ok := vm.stateValue(session, args[0].String()); ok {
+					return value
 				}
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Remove"):
 			if len(args) >= 1 {
 				session.Remove(args[0].String())
+				vm.unbindStateValue(session, args[0].String())
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "RemoveAll"):
 			session.RemoveAll()
+			vm.unbindStateValues(session)
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Count"):
 			return NewInteger(int64(session.Count()))
@@ -7345,8 +7422,8 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 			sort.Strings(keys)
 			values := make([]Value, 0, len(keys))
 			for i := range keys {
-				if sessionValue, ok := session.Get(keys[i]); ok {
-					values = append(values, vm.applicationValueToValue(sessionValue))
+				if value, ok := vm.stateValue(session, keys[i]); ok {
+					values = append(values, value)
 				}
 			}
 			return Value{Type: VTArray, Arr: NewVBArrayFromValues(0, values)}
@@ -7356,7 +7433,9 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		switch {
 		case member == "" || strings.EqualFold(member, "Item"):
 			if len(args) >= 2 {
-				session.AddStaticObject(args[0].String(), vm.valueToApplicationValue(args[1]))
+				if value, ok := vm.valueToApplicationValue(args[1]); ok {
+					session.AddStaticObject(args[0].String(), value)
+				}
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
@@ -7374,22 +7453,24 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		switch {
 		case member == "" || strings.EqualFold(member, "Item"):
 			if len(args) >= 2 {
-				application.Set(args[0].String(), vm.valueToApplicationValue(args[1]))
+				vm.setStateValue(application, args[0].String(), args[1])
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
-				if appValue, ok := application.Get(args[0].String()); ok {
-					return vm.applicationValueToValue(appValue)
+				if value, ok := vm.stateValue(application, args[0].String()); ok {
+					return value
 				}
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Remove"):
 			if len(args) >= 1 {
 				application.Remove(args[0].String())
+				vm.unbindStateValue(application, args[0].String())
 			}
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "RemoveAll"):
 			application.RemoveAll()
+			vm.unbindStateValues(application)
 			return Value{Type: VTEmpty}
 		case strings.EqualFold(member, "Count"):
 			return NewInteger(int64(len(application.GetContentsCopy())))
@@ -7414,8 +7495,8 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 			sort.Strings(keys)
 			values := make([]Value, 0, len(keys))
 			for i := 0; i < len(keys); i++ {
-				if appValue, ok := application.Get(keys[i]); ok {
-					values = append(values, vm.applicationValueToValue(appValue))
+				if value, ok := vm.stateValue(application, keys[i]); ok {
+					values = append(values, value)
 				}
 			}
 			return Value{Type: VTArray, Arr: NewVBArrayFromValues(0, values)}
@@ -7426,7 +7507,9 @@ func (vm *VM) dispatchNativeCall(objID int64, member string, args []Value) Value
 		switch {
 		case member == "" || strings.EqualFold(member, "Item"):
 			if len(args) >= 2 {
-				application.AddStaticObject(args[0].String(), vm.valueToApplicationValue(args[1]))
+				if value, ok := vm.valueToApplicationValue(args[1]); ok {
+					application.AddStaticObject(args[0].String(), value)
+				}
 				return Value{Type: VTEmpty}
 			}
 			if len(args) >= 1 {
@@ -8734,53 +8817,64 @@ func (vm *VM) raiseFromSyntaxError(syntaxErr *vbscript.VBSyntaxError) {
 	panic(vme)
 }
 
-// valueToApplicationValue converts a VM value into a typed application storage value.
-func (vm *VM) valueToApplicationValue(v Value) asp.ApplicationValue {
+// valueToApplicationValue converts a VM value into a typed application storage
+// value. Objects are stored by value, so they keep their contents in the next
+// request; setStateValue keeps the object itself bound to the entry until the
+// request ends. An object that cannot be stored raises a runtime error and
+// reports false, and the caller must not store anything.
+func (vm *VM) valueToApplicationValue(v Value) (asp.ApplicationValue, bool) {
+	value, err := vm.encodeApplicationValue(v, nil)
+	if err != nil {
+		vm.raise(vbscript.VariableUsesAnAutomationTypeNotSupported, err.Error())
+		return asp.NewApplicationEmpty(), false
+	}
+	return value, true
+}
+
+// encodeApplicationValue converts one VM value. visiting holds the objects
+// being encoded so a circular reference is reported instead of recursing forever.
+func (vm *VM) encodeApplicationValue(v Value, visiting map[int64]struct{}) (asp.ApplicationValue, error) {
 	switch v.Type {
 	case VTBool:
-		return asp.NewApplicationBool(v.Num != 0)
+		return asp.NewApplicationBool(v.Num != 0), nil
 	case VTInteger:
-		return asp.NewApplicationInteger(v.Num)
+		return asp.NewApplicationInteger(v.Num), nil
 	case VTDouble:
-		return asp.NewApplicationDouble(v.Flt)
+		return asp.NewApplicationDouble(v.Flt), nil
 	case VTString:
-		return asp.NewApplicationString(v.Str)
+		return asp.NewApplicationString(v.Str), nil
 	case VTEmpty:
-		return asp.NewApplicationEmpty()
+		return asp.NewApplicationEmpty(), nil
+	case VTNull:
+		return asp.NewApplicationNull(), nil
+	case VTDate:
+		return asp.NewApplicationDate(time.Unix(0, v.Num)), nil
 	case VTNothing:
-		return asp.NewApplicationNothing()
-	case VTNativeObject:
-		if v.Num == 0 {
-			return asp.NewApplicationNothing()
-		}
-		return asp.NewApplicationNativeObject(v.Num, v.Str, v.Interface)
-	case VTObject:
+		return asp.NewApplicationNothing(), nil
+	case VTNativeObject, VTObject, VTJSObject:
 		if v.Num == 0 {
-			return asp.NewApplicationNothing()
+			return asp.NewApplicationNothing(), nil
 		}
-		return asp.NewApplicationObject(v.Num, v.Str, v.Interface)
-	case VTJSObject:
-		if v.Num == 0 {
-			return asp.NewApplicationNothing()
-		}
-		return asp.NewApplicationJSObject(v.Num, v.Str, v.Interface)
+		return vm.encodeApplicationObject(v, visiting)
+	case VTJSFunction, VTJSPromise, VTJSGenerator, VTJSProxy:
+		return asp.ApplicationValue{}, vm.applicationObjectError(v)
 	case VTArray:
 		if v.Arr != nil {
-			return vm.vbArrayToApplicationValue(v.Arr)
+			return vm.vbArrayToApplicationValue(v.Arr, visiting)
 		}
-		return asp.NewApplicationEmpty()
+		return asp.NewApplicationEmpty(), nil
 	default:
-		return asp.NewApplicationString(v.String())
+		return asp.NewApplicationString(v.String()), nil
 	}
 }
 
 // vbArrayToApplicationValue recursively converts a VBArray into an ApplicationValue tree.
-func (vm *VM) vbArrayToApplicationValue(arr *VBArray) asp.ApplicationValue {
-	elements := make([]asp.ApplicationValue, len(arr.Values))
-	for i, elem := range arr.Values {
-		elements[i] = vm.valueToApplicationValue(elem)
+func (vm *VM) vbArrayToApplicationValue(arr *VBArray, visiting map[int64]struct{}) (asp.ApplicationValue, error) {
+	elements, err := vm.encodeApplicationValues(arr.Values, visiting)
+	if err != nil {
+		return asp.ApplicationValue{}, err
 	}
-	return asp.NewApplicationArray(arr.Lower, elements)
+	return asp.NewApplicationArray(arr.Lower, elements), nil
 }
 
 // applicationValueToVBArray recursively converts an ApplicationValue array tree back into a Value.
@@ -8814,8 +8908,14 @@ func (vm *VM) applicationValueToValue(v asp.ApplicationValue) Value {
 		return Value{Type: VTJSObject, Num: v.Num, Str: v.Str, Interface: v.Interface}
 	case asp.ApplicationValueNothing:
 		return Value{Type: VTNothing}
+	case asp.ApplicationValueNull:
+		return NewNull()
+	case asp.ApplicationValueDate:
+		return NewDate(v.Time())
 	case asp.ApplicationValueArray:
 		return vm.applicationValueToVBArray(v)
+	case asp.ApplicationValueDictionary, asp.ApplicationValueCollection, asp.ApplicationValueJSPlainObject, asp.ApplicationValueRecordset:
+		return vm.decodeApplicationObject(v)
 	default:
 		return Value{Type: VTEmpty}
 	}
@@ -9559,6 +9659,9 @@ func (vm *VM) beginUserSubCall(target Value, args []Value, discardReturn bool, b
 		savedSkipToNextStmt: vm.skipToNextStmt,
 		savedStmtSP:         vm.stmtSP,
 	})
+	if vm.tracer != nil {
+		vm.traceCall(target)
+	}
 	vm.activeClassObjectID = boundObjectID
 	vm.onResumeNext = false
 	vm.skipToNextStmt = false
@@ -3397,6 +3397,9 @@ func (vm *VM) jsBeginFunctionCall(fn Value, thisVal Value, args []Value, ctorObj
 		savedBlockScopeDepth: vm.jsBlockScopeDepth,
 	}
 	vm.jsCallStack = append(vm.jsCallStack, frame)
+	if vm.tracer != nil {
+		vm.traceJSCall()
+	}
 	vm.jsStrictMode = closure.isStrict
 	vm.jsNewTarget = newTarget
 	vm.jsBlockScopes = append(make([]map[string]Value, 0, len(closure.capturedBlockScopes)), closure.capturedBlockScopes...)
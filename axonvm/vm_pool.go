@@ -21,10 +21,12 @@
 package axonvm
 
 import (
+	"context"
 	"encoding/binary"
 	"maps"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"g3pix.com.br/axonasp/axonvm/asp"
@@ -35,6 +37,7 @@ type vmProgramPool struct {
 	items       []*VM
 	maxRetained int
 	program     CachedProgram
+	owner       *LocalVMPool
 }
 
 // vmProgramPool manages a pool of VM instances for a specific compiled program.
@@ -44,32 +47,103 @@ var cachedProgramPools sync.Map
 var vmPoolLimitMu sync.RWMutex
 var vmPoolLimiter chan struct{}
 
+// vmPoolCounters track pool activity for VMPoolStats.
+var (
+	vmPoolCheckedOut atomic.Int64
+	vmPoolAcquired   atomic.Uint64
+	vmPoolWaiting    atomic.Int64
+	vmPoolWaits      atomic.Uint64
+	vmPoolWaitNanos  atomic.Int64
+)
+
+// VMPoolStats is a point-in-time snapshot of the interpreter pool.
+type VMPoolStats struct {
+	// SlotLimit is the configured vm_pool_size, or 0 when checkouts are unlimited.
+	SlotLimit int
+	// SlotsInUse is the number of limiter slots held by running requests.
+	SlotsInUse int
+	// Waiting is the number of requests blocked until a slot is released.
+	Waiting int64
+	// CheckedOut is the number of VMs currently borrowed from the pool.
+	CheckedOut int64
+	// Idle is the number of VMs retained in the per-program pools.
+	Idle int
+	// Programs is the number of compiled programs that own a pool.
+	Programs int
+	// Acquired counts every VM checkout since start.
+	Acquired uint64
+	// Waits counts the checkouts that had to wait for a slot.
+	Waits uint64
+	// WaitTime is the total time spent waiting for slots.
+	WaitTime time.Duration
+	// QueueRejected counts requests refused because the wait queue was full.
+	QueueRejected uint64
+	// QueueTimeouts counts requests that gave up after the queue timeout.
+	QueueTimeouts uint64
+}
+
+// GetVMPoolStats returns the current interpreter pool counters.
+func GetVMPoolStats() VMPoolStats {
+	vmPoolLimitMu.RLock()
+	limiter := vmPoolLimiter
+	vmPoolLimitMu.RUnlock()
+	stats := VMPoolStats{
+		Waiting:       vmPoolWaiting.Load(),
+		CheckedOut:    vmPoolCheckedOut.Load(),
+		Acquired:      vmPoolAcquired.Load(),
+		Waits:         vmPoolWaits.Load(),
+		WaitTime:      time.Duration(vmPoolWaitNanos.Load()),
+		QueueRejected: vmPoolQueueRejected.Load(),
+		QueueTimeouts: vmPoolQueueTimeouts.Load(),
+	}
+	if limiter != nil {
+		stats.SlotLimit = cap(limiter)
+		stats.SlotsInUse = len(limiter)
+	}
+	cachedProgramPools.Range(func(_, value any) bool {
+		pool := value.(*vmProgramPool)
+		pool.mu.Lock()
+		stats.Idle += len(pool.items)
+		pool.mu.Unlock()
+		stats.Programs++
+		return true
+	})
+	return stats
+}
+
 // SetVMPoolSizeLimit sets the maximum number of concurrently checked-out VMs.
 func SetVMPoolSizeLimit(limit int) {
 	vmPoolLimitMu.Lock()
 	defer vmPoolLimitMu.Unlock()
 	if limit <= 0 {
 		vmPoolLimiter = nil
-		return
+	} else {
+		vmPoolLimiter = make(chan struct{}, limit)
 	}
-	vmPoolLimiter = make(chan struct{}, limit)
+	vmPoolQueueMu.Lock()
+	releaseAllVMPoolWaitersLocked()
+	vmPoolQueueMu.Unlock()
 }
 
+// acquireVMPoolSlot waits without a queue limit or timeout for a slot.
 func acquireVMPoolSlot() chan struct{} {
-	vmPoolLimitMu.RLock()
-	limiter := vmPoolLimiter
-	vmPoolLimitMu.RUnlock()
-	if limiter == nil {
-		return nil
-	}
-	limiter <- struct{}{}
-	return limiter
+	slot, _ := waitVMPoolSlot(context.Background(), VMPoolPriorityNormal, -1, 0)
+	return slot
 }
 
+// releaseVMPoolSlot hands a slot to the next waiter, or frees it when nobody waits.
 func releaseVMPoolSlot(slot chan struct{}) {
 	if slot == nil {
 		return
 	}
+	vmPoolLimitMu.RLock()
+	current := vmPoolLimiter
+	vmPoolLimitMu.RUnlock()
+	vmPoolQueueMu.Lock()
+	defer vmPoolQueueMu.Unlock()
+	if slot == current && handOverVMPoolSlotLocked() {
+		return
+	}
 	select {
 	case <-slot:
 	default:
@@ -78,8 +152,19 @@ func releaseVMPoolSlot(slot chan struct{}) {
 
 // AcquireVMFromCachedProgram borrows one VM instance from the interpreter pool.
 func AcquireVMFromCachedProgram(program CachedProgram) *VM {
-	slot := acquireVMPoolSlot()
-	pool := getProgramPool(program)
+	return acquireVMWithSlot(program, acquireVMPoolSlot())
+}
+
+// acquireVMWithSlot borrows one VM that releases slot when it is returned.
+func acquireVMWithSlot(program CachedProgram, slot chan struct{}) *VM {
+	vm := checkoutVM(getProgramPool(program), slot)
+	vmPoolAcquired.Add(1)
+	vmPoolCheckedOut.Add(1)
+	return vm
+}
+
+// checkoutVM takes an idle VM of pool, or creates one, for a new request.
+func checkoutVM(pool *vmProgramPool, slot chan struct{}) *VM {
 	vm := pool.get()
 	if vm == nil {
 		vm = newPooledVMFromCachedProgram(pool, pool.program)
@@ -87,6 +172,7 @@ func AcquireVMFromCachedProgram(program CachedProgram) *VM {
 	vm.resetForReuse()
 	vm.pooledFrom = pool
 	vm.pooledSlot = slot
+	vm.pooledCounted = true
 	return vm
 }
 
@@ -103,29 +189,51 @@ func (vm *VM) Release() {
 	if vm == nil {
 		return
 	}
-	vm.CleanupRequestResources()
 	pool := vm.pooledFrom
+	var owner *LocalVMPool
+	if pool != nil {
+		owner = pool.owner
+	}
+	if owner != nil && vm.pooledCounted && owner.opts.Hooks.OnRelease != nil {
+		owner.opts.Hooks.OnRelease(vm)
+	}
+	vm.CleanupRequestResources()
 	slot := vm.pooledSlot
+	counted := vm.pooledCounted
 	vm.resetForReuse()
 	if pool != nil {
 		pool.put(vm)
 	} else {
 		vm.stopSTAWorker()
 	}
+	if owner != nil {
+		owner.release(slot, counted)
+		return
+	}
+	if counted {
+		vmPoolCheckedOut.Add(-1)
+	}
 	releaseVMPoolSlot(slot)
 }
 
+// getProgramPool returns the process-wide pool of program.
 func getProgramPool(program CachedProgram) *vmProgramPool {
+	return loadProgramPool(&cachedProgramPools, program, vmProgramPoolRetainLimit(), nil)
+}
+
+// loadProgramPool returns the pool of program stored in pools, creating it
+// with limit retained VMs when missing.
+func loadProgramPool(pools *sync.Map, program CachedProgram, limit int, owner *LocalVMPool) *vmProgramPool {
 	key := pooledProgramKey(program)
-	if existing, ok := cachedProgramPools.Load(key); ok {
+	if existing, ok := pools.Load(key); ok {
 		return existing.(*vmProgramPool)
 	}
 
-	limit := vmProgramPoolRetainLimit()
 	entry := &vmProgramPool{
 		items:       make([]*VM, 0, limit),
 		maxRetained: limit,
 		program:     immutableCachedProgramView(program),
+		owner:       owner,
 	}
 
 	// Pre-warming: Fill the pool with a few pre-allocated VMs to handle initial bursts.
@@ -137,7 +245,7 @@ func getProgramPool(program CachedProgram) *vmProgramPool {
 		entry.items = append(entry.items, vm)
 	}
 
-	actual, _ := cachedProgramPools.LoadOrStore(key, entry)
+	actual, _ := pools.LoadOrStore(key, entry)
 	return actual.(*vmProgramPool)
 }
 
@@ -305,6 +413,8 @@ func (vm *VM) resetForReuse() {
 	vm.output = nil
 	vm.pooledFrom = nil
 	vm.pooledSlot = nil
+	vm.pooledCounted = false
+	vm.requestCtx = nil
 	vm.bytecode = immutableBytecodeView(vm.baseBytecode)
 	vm.constants = immutableValueView(vm.baseConstants)
 	vm.resetGlobals()
@@ -344,6 +454,7 @@ func (vm *VM) resetForReuse() {
 	vm.errASPCodeRawSet = false
 	vm.lastLine = 0
 	vm.lastColumn = 0
+	vm.tracer = nil
 	vm.lastError = nil
 	vm.transactionState = 0
 	vm.activeClassObjectID = 0
@@ -758,6 +869,9 @@ func (vm *VM) ensureDynamicMaps() {
 	if vm.nativeObjectProxies == nil {
 		vm.nativeObjectProxies = make(map[int64]nativeObjectProxy)
 	}
+	if vm.stateObjects == nil {
+		vm.stateObjects = make(map[stateObjectKey]*stateObjectBinding)
+	}
 	if vm.jsObjectItems == nil {
 		vm.jsObjectItems = make(map[int64]map[string]Value)
 	}
@@ -967,6 +1081,7 @@ func (vm *VM) resetDynamicMaps() {
 	clear(vm.dictionaryItems)
 	clear(vm.runtimeClassItems)
 	clear(vm.nativeObjectProxies)
+	clear(vm.stateObjects)
 	clear(vm.jsObjectItems)
 	clear(vm.jsObjectKeyOrder)
 	clear(vm.jsObjectSlots)
@@ -0,0 +1,191 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonvm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// VMPool lends VMs of compiled programs to a host. Every VM returned by
+// Acquire goes back to its pool with VM.Release.
+type VMPool interface {
+	// Acquire borrows a VM for program and attaches ctx to it. It waits for a
+	// free slot until ctx is done.
+	Acquire(ctx context.Context, program CachedProgram) (*VM, error)
+	// Stats returns the counters of the pool.
+	Stats() VMPoolStats
+}
+
+// DefaultVMPool returns the process-wide pool sized by vm_pool_size.
+func DefaultVMPool() VMPool {
+	return defaultVMPool{}
+}
+
+// defaultVMPool adapts the process-wide pool to VMPool.
+type defaultVMPool struct{}
+
+// Acquire borrows a VM from the process-wide pool.
+func (defaultVMPool) Acquire(ctx context.Context, program CachedProgram) (*VM, error) {
+	slot, err := waitVMPoolSlot(ctx, VMPoolPriorityNormal, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+	vm := acquireVMWithSlot(program, slot)
+	vm.SetContext(ctx)
+	return vm, nil
+}
+
+// Stats returns the process-wide pool counters.
+func (defaultVMPool) Stats() VMPoolStats {
+	return GetVMPoolStats()
+}
+
+// VMPoolHooks run when a VM of a LocalVMPool changes hands.
+type VMPoolHooks struct {
+	// OnAcquire runs before Acquire returns vm.
+	OnAcquire func(vm *VM)
+	// OnRelease runs when vm is returned, before its request state is cleared.
+	OnRelease func(vm *VM)
+}
+
+// LocalVMPoolOptions configures NewLocalVMPool.
+type LocalVMPoolOptions struct {
+	// MaxActive limits the VMs checked out at the same time. 0 is unlimited.
+	MaxActive int
+	// MaxIdle limits the idle VMs kept for each program. 0 uses MaxActive, or
+	// 250 when MaxActive is unlimited.
+	MaxIdle int
+	// Hooks run on every checkout and return.
+	Hooks VMPoolHooks
+}
+
+// LocalVMPool is a VM pool with its own limits, separate from the
+// process-wide pool. Hosts use one per site or per module instance.
+type LocalVMPool struct {
+	opts      LocalVMPoolOptions
+	programs  sync.Map
+	slots     chan struct{}
+	active    atomic.Int64
+	waiting   atomic.Int64
+	acquired  atomic.Uint64
+	waits     atomic.Uint64
+	waitNanos atomic.Int64
+}
+
+// NewLocalVMPool returns an empty pool configured by opts.
+func NewLocalVMPool(opts LocalVMPoolOptions) *LocalVMPool {
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = vmProgramPoolDefaultRetained
+		if opts.MaxActive > 0 {
+			opts.MaxIdle = opts.MaxActive
+		}
+	}
+	p := &LocalVMPool{opts: opts}
+	if opts.MaxActive > 0 {
+		p.slots = make(chan struct{}, opts.MaxActive)
+	}
+	return p
+}
+
+// Acquire borrows a VM for program, waiting for a free slot until ctx is done.
+func (p *LocalVMPool) Acquire(ctx context.Context, program CachedProgram) (*VM, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := p.waitSlot(ctx); err != nil {
+		return nil, err
+	}
+	vm := checkoutVM(loadProgramPool(&p.programs, program, p.opts.MaxIdle, p), p.slots)
+	vm.SetContext(ctx)
+	p.acquired.Add(1)
+	p.active.Add(1)
+	if p.opts.Hooks.OnAcquire != nil {
+		p.opts.Hooks.OnAcquire(vm)
+	}
+	return vm, nil
+}
+
+// waitSlot takes a slot of p, waiting while all of them are in use.
+func (p *LocalVMPool) waitSlot(ctx context.Context) error {
+	if p.slots == nil {
+		return nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	start := time.Now()
+	p.waiting.Add(1)
+	defer func() {
+		p.waiting.Add(-1)
+		p.waits.Add(1)
+		p.waitNanos.Add(int64(time.Since(start)))
+	}()
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot of a returned VM.
+func (p *LocalVMPool) release(slot chan struct{}, counted bool) {
+	if counted {
+		p.active.Add(-1)
+	}
+	if slot == nil {
+		return
+	}
+	select {
+	case <-slot:
+	default:
+	}
+}
+
+// Stats returns the counters of p. Queue counters stay zero because the
+// pool has no queue limit.
+func (p *LocalVMPool) Stats() VMPoolStats {
+	stats := VMPoolStats{
+		SlotLimit:  p.opts.MaxActive,
+		Waiting:    p.waiting.Load(),
+		CheckedOut: p.active.Load(),
+		Acquired:   p.acquired.Load(),
+		Waits:      p.waits.Load(),
+		WaitTime:   time.Duration(p.waitNanos.Load()),
+	}
+	if p.slots != nil {
+		stats.SlotsInUse = len(p.slots)
+	}
+	p.programs.Range(func(_, value any) bool {
+		pool := value.(*vmProgramPool)
+		pool.mu.Lock()
+		stats.Idle += len(pool.items)
+		pool.mu.Unlock()
+		stats.Programs++
+		return true
+	})
+	return stats
+}
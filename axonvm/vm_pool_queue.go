@@ -0,0 +1,244 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonvm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// VMPoolPriority orders the requests waiting for a VM pool slot. A released slot
+// goes to the oldest waiter of the highest priority.
+type VMPoolPriority int
+
+const (
+	// VMPoolPriorityLow is used for pages that may wait, such as long reports.
+	VMPoolPriorityLow VMPoolPriority = iota
+	// VMPoolPriorityNormal is the priority of ordinary requests.
+	VMPoolPriorityNormal
+	// VMPoolPriorityHigh is used for pages that must stay responsive, such as admin pages.
+	VMPoolPriorityHigh
+
+	vmPoolPriorityCount = 3
+)
+
+var (
+	// ErrVMPoolQueueFull is returned when the wait queue has no room for a request.
+	ErrVMPoolQueueFull = errors.New("the VM pool wait queue is full")
+	// ErrVMPoolQueueTimeout is returned when no slot was released within the queue timeout.
+	ErrVMPoolQueueTimeout = errors.New("timed out waiting for a free VM pool slot")
+)
+
+// vmPoolWaiter is one request waiting in the VM pool queue.
+type vmPoolWaiter struct {
+	ready chan struct{}
+	// granted is set when a released slot was handed over, rejected when a
+	// higher priority request took the queue position. Both are guarded by vmPoolQueueMu.
+	granted  bool
+	rejected bool
+}
+
+var (
+	vmPoolQueueMu sync.Mutex
+	vmPoolQueue   [vmPoolPriorityCount][]*vmPoolWaiter
+	vmPoolQueued  int
+
+	vmPoolQueueRejected atomic.Uint64
+	vmPoolQueueTimeouts atomic.Uint64
+)
+
+// VMPoolReservation is a VM pool slot reserved for one request by ReserveVMPoolSlot.
+// Hand it to AcquireVMWithReservation, and always call Release when the request ends.
+type VMPoolReservation struct {
+	slot chan struct{}
+	done bool
+}
+
+// ReserveVMPoolSlot waits for a free VM pool slot for one request. maxQueue bounds the
+// number of waiting requests, or is negative for an unbounded queue; when the queue is
+// full, the newest waiter of a lower priority is rejected to make room. timeout bounds
+// the wait, or is zero to wait until ctx ends. When vm_pool_size is unlimited, the
+// reservation holds no slot and is returned immediately.
+func ReserveVMPoolSlot(ctx context.Context, priority VMPoolPriority, maxQueue int, timeout time.Duration) (*VMPoolReservation, error) {
+	slot, err := waitVMPoolSlot(ctx, priority, maxQueue, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &VMPoolReservation{slot: slot}, nil
+}
+
+// Release returns the slot to the pool unless it was handed to a VM.
+func (r *VMPoolReservation) Release() {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+	releaseVMPoolSlot(r.slot)
+}
+
+// take hands the slot over to a VM, which releases it with VM.Release.
+func (r *VMPoolReservation) take() chan struct{} {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	return r.slot
+}
+
+// AcquireVMWithReservation borrows one VM that runs on a slot reserved by
+// ReserveVMPoolSlot. A nil reservation waits for a slot like AcquireVMFromCachedProgram.
+func AcquireVMWithReservation(program CachedProgram, reservation *VMPoolReservation) *VM {
+	if reservation == nil {
+		return AcquireVMFromCachedProgram(program)
+	}
+	return acquireVMWithSlot(program, reservation.take())
+}
+
+// waitVMPoolSlot takes a free slot, or queues the caller until one is handed over.
+func waitVMPoolSlot(ctx context.Context, priority VMPoolPriority, maxQueue int, timeout time.Duration) (chan struct{}, error) {
+	vmPoolLimitMu.RLock()
+	limiter := vmPoolLimiter
+	vmPoolLimitMu.RUnlock()
+	if limiter == nil {
+		return nil, nil
+	}
+	priority = min(max(priority, VMPoolPriorityLow), VMPoolPriorityHigh)
+
+	vmPoolQueueMu.Lock()
+	if vmPoolQueued == 0 {
+		select {
+		case limiter <- struct{}{}:
+			vmPoolQueueMu.Unlock()
+			return limiter, nil
+		default:
+		}
+	}
+	if maxQueue >= 0 && vmPoolQueued >= maxQueue && !evictLowerPriorityWaiterLocked(priority, maxQueue) {
+		vmPoolQueueRejected.Add(1)
+		vmPoolQueueMu.Unlock()
+		return nil, ErrVMPoolQueueFull
+	}
+	waiter := &vmPoolWaiter{ready: make(chan struct{})}
+	vmPoolQueue[priority] = append(vmPoolQueue[priority], waiter)
+	vmPoolQueued++
+	vmPoolWaiting.Store(int64(vmPoolQueued))
+	vmPoolQueueMu.Unlock()
+
+	start := time.Now()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	var err error
+	select {
+	case <-waiter.ready:
+	case <-expired:
+		err = ErrVMPoolQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	vmPoolQueueMu.Lock()
+	defer vmPoolQueueMu.Unlock()
+	vmPoolWaits.Add(1)
+	vmPoolWaitNanos.Add(int64(time.Since(start)))
+	switch {
+	case waiter.granted:
+		return limiter, nil
+	case waiter.rejected:
+		return nil, ErrVMPoolQueueFull
+	}
+	removeVMPoolWaiterLocked(priority, waiter)
+	if err == ErrVMPoolQueueTimeout {
+		vmPoolQueueTimeouts.Add(1)
+	}
+	return nil, err
+}
+
+// evictLowerPriorityWaiterLocked rejects the newest waiter whose priority is lower than
+// priority, making room in a full queue. It reports whether a waiter was evicted.
+func evictLowerPriorityWaiterLocked(priority VMPoolPriority, maxQueue int) bool {
+	if maxQueue <= 0 {
+		return false
+	}
+	for p := VMPoolPriorityLow; p < priority; p++ {
+		count := len(vmPoolQueue[p])
+		if count == 0 {
+			continue
+		}
+		waiter := vmPoolQueue[p][count-1]
+		vmPoolQueue[p][count-1] = nil
+		vmPoolQueue[p] = vmPoolQueue[p][:count-1]
+		vmPoolQueued--
+		vmPoolWaiting.Store(int64(vmPoolQueued))
+		vmPoolQueueRejected.Add(1)
+		waiter.rejected = true
+		close(waiter.ready)
+		return true
+	}
+	return false
+}
+
+// removeVMPoolWaiterLocked drops a waiter that gave up.
+func removeVMPoolWaiterLocked(priority VMPoolPriority, waiter *vmPoolWaiter) {
+	queue := vmPoolQueue[priority]
+	for i, queued := range queue {
+		if queued == waiter {
+			copy(queue[i:], queue[i+1:])
+			queue[len(queue)-1] = nil
+			vmPoolQueue[priority] = queue[:len(queue)-1]
+			vmPoolQueued--
+			vmPoolWaiting.Store(int64(vmPoolQueued))
+			return
+		}
+	}
+}
+
+// handOverVMPoolSlotLocked gives a released slot to the oldest waiter of the highest
+// priority. It reports false when nobody is waiting.
+func handOverVMPoolSlotLocked() bool {
+	for p := VMPoolPriorityHigh; p >= VMPoolPriorityLow; p-- {
+		if len(vmPoolQueue[p]) == 0 {
+			continue
+		}
+		waiter := vmPoolQueue[p][0]
+		vmPoolQueue[p][0] = nil
+		vmPoolQueue[p] = vmPoolQueue[p][1:]
+		vmPoolQueued--
+		vmPoolWaiting.Store(int64(vmPoolQueued))
+		waiter.granted = true
+		close(waiter.ready)
+		return true
+	}
+	return false
+}
+
+// releaseAllVMPoolWaitersLocked lets every waiter through when the limiter is replaced,
+// so nobody waits for a slot of the old limiter that is never released.
+func releaseAllVMPoolWaitersLocked() {
+	for handOverVMPoolSlotLocked() {
+	}
+}
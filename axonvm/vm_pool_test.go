@@ -24,6 +24,8 @@ package axonvm
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -371,3 +373,197 @@ func TestAcquireVMFromCachedProgramClearsExtendedDynamicMaps(t *testing.T) {
 		t.Fatalf("expected extended JScript maps to be cleared on pooled reuse")
 	}
 }
+
+// waitForVMPoolQueue blocks until the given number of requests wait for a slot.
+func waitForVMPoolQueue(t *testing.T, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for GetVMPoolStats().Waiting != int64(queued) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d queued requests, got %d", queued, GetVMPoolStats().Waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestReserveVMPoolSlotQueue verifies the queue limit, the timeout and the priority order.
+func TestReserveVMPoolSlotQueue(t *testing.T) {
+	SetVMPoolSizeLimit(1)
+	t.Cleanup(func() { SetVMPoolSizeLimit(0) })
+	ctx := context.Background()
+
+	held, err := ReserveVMPoolSlot(ctx, VMPoolPriorityNormal, 0, 0)
+	if err != nil {
+		t.Fatalf("expected a free slot, got %v", err)
+	}
+	if _, err := ReserveVMPoolSlot(ctx, VMPoolPriorityHigh, 0, 0); !errors.Is(err, ErrVMPoolQueueFull) {
+		t.Fatalf("expected ErrVMPoolQueueFull without queue room, got %v", err)
+	}
+	if _, err := ReserveVMPoolSlot(ctx, VMPoolPriorityNormal, 5, 20*time.Millisecond); !errors.Is(err, ErrVMPoolQueueTimeout) {
+		t.Fatalf("expected ErrVMPoolQueueTimeout, got %v", err)
+	}
+
+	order := make(chan VMPoolPriority, 2)
+	var wg sync.WaitGroup
+	start := func(priority VMPoolPriority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := ReserveVMPoolSlot(ctx, priority, 5, 2*time.Second)
+			if err != nil {
+				t.Errorf("priority %d: %v", priority, err)
+				return
+			}
+			order <- priority
+			time.Sleep(5 * time.Millisecond)
+			reservation.Release()
+		}()
+	}
+	start(VMPoolPriorityLow)
+	waitForVMPoolQueue(t, 1)
+	start(VMPoolPriorityHigh)
+	waitForVMPoolQueue(t, 2)
+	held.Release()
+	wg.Wait()
+	if first, second := <-order, <-order; first != VMPoolPriorityHigh || second != VMPoolPriorityLow {
+		t.Fatalf("expected the high priority request first, got %d then %d", first, second)
+	}
+	if stats := GetVMPoolStats(); stats.SlotsInUse != 0 || stats.Waiting != 0 || stats.QueueTimeouts == 0 {
+		t.Fatalf("unexpected pool state after the queue drained: %+v", stats)
+	}
+}
+
+// TestReserveVMPoolSlotEvictsLowerPriority verifies a full queue makes room for a
+// higher priority request, and that a reservation runs one VM on its slot.
+func TestReserveVMPoolSlotEvictsLowerPriority(t *testing.T) {
+	SetVMPoolSizeLimit(1)
+	t.Cleanup(func() { SetVMPoolSizeLimit(0) })
+	ctx := context.Background()
+
+	held, err := ReserveVMPoolSlot(ctx, VMPoolPriorityNormal, 1, 0)
+	if err != nil {
+		t.Fatalf("expected a free slot, got %v", err)
+	}
+	lowResult := make(chan error, 1)
+	go func() {
+		reservation, err := ReserveVMPoolSlot(ctx, VMPoolPriorityLow, 1, 2*time.Second)
+		reservation.Release()
+		lowResult <- err
+	}()
+	waitForVMPoolQueue(t, 1)
+
+	highResult := make(chan *VMPoolReservation, 1)
+	go func() {
+		reservation, err := ReserveVMPoolSlot(ctx, VMPoolPriorityHigh, 1, 2*time.Second)
+		if err != nil {
+			t.Errorf("high priority request: %v", err)
+		}
+		highResult <- reservation
+	}()
+	if err := <-lowResult; !errors.Is(err, ErrVMPoolQueueFull) {
+		t.Fatalf("expected the low priority request to be evicted, got %v", err)
+	}
+	held.Release()
+	reservation := <-highResult
+
+	compiler := NewASPCompiler(`<% Response.Write "ok" %>`)
+	if err := compiler.Compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	vm := AcquireVMWithReservation(cachedProgramFromCompiler(compiler), reservation)
+	reservation.Release()
+	if stats := GetVMPoolStats(); stats.SlotsInUse != 1 {
+		t.Fatalf("expected the VM to keep the reserved slot, got %+v", stats)
+	}
+	vm.Release()
+	if stats := GetVMPoolStats(); stats.SlotsInUse != 0 {
+		t.Fatalf("expected VM.Release to free the slot, got %+v", stats)
+	}
+}
+
+// TestLocalVMPoolLimitsAndHooks verifies that a local pool enforces its own
+// checkout limit, runs its hooks and returns VMs to its own idle list.
+func TestLocalVMPoolLimitsAndHooks(t *testing.T) {
+	compiler := NewASPCompiler(`<% Response.Write "local" %>`)
+	if err := compiler.Compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	program := cachedProgramFromCompiler(compiler)
+
+	var acquired, released int
+	pool := NewLocalVMPool(LocalVMPoolOptions{
+		MaxActive: 1,
+		Hooks: VMPoolHooks{
+			OnAcquire: func(vm *VM) { acquired++ },
+			OnRelease: func(vm *VM) { released++ },
+		},
+	})
+	globalBefore := GetVMPoolStats().CheckedOut
+
+	ctx, cancel := context.WithCancel(context.Background())
+	vm, err := pool.Acquire(ctx, program)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if vm.Context() != ctx {
+		t.Fatalf("expected the request context on the VM")
+	}
+	if got := GetVMPoolStats().CheckedOut; got != globalBefore {
+		t.Fatalf("expected local checkouts outside the process-wide pool, got %d want %d", got, globalBefore)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	if _, err := pool.Acquire(waitCtx, program); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the full pool to wait until the deadline, got %v", err)
+	}
+
+	host := NewMockHost()
+	vm.SetHost(host)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	vm.Release()
+	cancel()
+
+	stats := pool.Stats()
+	if stats.CheckedOut != 0 || stats.SlotsInUse != 0 || stats.Waits != 1 || stats.Programs != 1 || stats.Idle == 0 {
+		t.Fatalf("unexpected local pool stats: %+v", stats)
+	}
+
+	reused, err := pool.Acquire(context.Background(), program)
+	if err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+	if reused.requestCtx == ctx {
+		t.Fatalf("expected the previous request context to be cleared")
+	}
+	reused.Release()
+	if acquired != 2 || released != 2 {
+		t.Fatalf("expected hooks to run twice, got acquire=%d release=%d", acquired, released)
+	}
+}
+
+// TestDefaultVMPoolHonorsContext verifies that the process-wide pool adapter
+// stops waiting for a slot when the request context ends.
+func TestDefaultVMPoolHonorsContext(t *testing.T) {
+	compiler := NewASPCompiler(`<% Response.Write "default" %>`)
+	if err := compiler.Compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	program := cachedProgramFromCompiler(compiler)
+
+	SetVMPoolSizeLimit(1)
+	defer SetVMPoolSizeLimit(0)
+	held, err := DefaultVMPool().Acquire(context.Background(), program)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer held.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := DefaultVMPool().Acquire(ctx, program); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to end with the context, got %v", err)
+	}
+}
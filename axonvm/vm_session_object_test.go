@@ -22,7 +22,10 @@ package axonvm
 
 import (
 	"bytes"
+	"path/filepath"
 	"testing"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
 )
 
 // TestASPSessionAndApplicationObjectReferences verifies storing, retrieving,
@@ -75,3 +78,238 @@ func TestASPSessionAndApplicationObjectReferences(t *testing.T) {
 		t.Fatalf("expected output %q, got %q", expected, output.String())
 	}
 }
+
+// runSessionObjectPage runs one page with session and app and returns its output.
+func runSessionObjectPage(t *testing.T, source string, session *asp.Session, app *asp.Application) string {
+	t.Helper()
+	compiler := NewASPCompiler(source)
+	if err := compiler.Compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	vm := NewVM(compiler.Bytecode(), compiler.Constants(), compiler.GlobalsCount())
+	host := NewMockHost()
+	var output bytes.Buffer
+	host.SetOutput(&output)
+	host.SetSession(session)
+	host.SetApplication(app)
+	vm.SetHost(host)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm run failed: %v", err)
+	}
+	host.Response().Flush()
+	return output.String()
+}
+
+// reloadSessionObjectSession saves session as a G3SES record and loads it back,
+// as the next request on another process would.
+func reloadSessionObjectSession(t *testing.T, session *asp.Session) *asp.Session {
+	t.Helper()
+	if err := session.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, found, err := asp.LoadSession(session.ID)
+	if err != nil || !found {
+		t.Fatalf("LoadSession found=%v err=%v", found, err)
+	}
+	return loaded
+}
+
+// TestASPSessionStoresObjectsByValue verifies that Dictionary, Collection,
+// arrays of objects, Null and dates keep their contents in the next request,
+// including the changes made after they were stored.
+func TestASPSessionStoresObjectsByValue(t *testing.T) {
+	asp.SetSessionStorageDir(filepath.Join(t.TempDir(), "session"))
+	t.Cleanup(func() { asp.SetSessionStorageDir("") })
+	app := asp.NewApplication()
+	session := asp.NewSession()
+
+	runSessionObjectPage(t, `<%
+	Dim cart, line, lines, tags
+	Set cart = Server.CreateObject("Scripting.Dictionary")
+	cart.CompareMode = 1
+	cart.Add "sku", "A-1"
+	cart.Add "qty", 3
+	Set line = Server.CreateObject("Scripting.Dictionary")
+	line.Add "price", 9.5
+	cart.Add "line", line
+	Set Session("cart") = cart
+	Set tags = Server.CreateObject("Collection")
+	tags.Add "new"
+	tags.Add "sale"
+	Set Session("tags") = tags
+	lines = Array(line, Null, DateSerial(2026, 10, 17))
+	Session("lines") = lines
+	Set Application("cart") = cart
+	cart.Add "later", "stored at the end of the page"
+	%>`, session, app)
+
+	session = reloadSessionObjectSession(t, session)
+	output := runSessionObjectPage(t, `<%
+	Dim cart, tags, lines
+	Set cart = Session("cart")
+	Response.Write TypeName(cart) & "|" & cart.Count & "|" & cart("SKU") & "|" & cart("qty") & "|" & cart("line")("price") & "|" & cart.Exists("later") & ";"
+	Set tags = Session("tags")
+	Response.Write TypeName(tags) & "|" & tags.Count & "|" & tags(2) & ";"
+	lines = Session("lines")
+	Response.Write TypeName(lines(0)) & "|" & lines(0)("price") & "|" & IsNull(lines(1)) & "|" & VarType(lines(2)) & "|" & Year(lines(2)) & ";"
+	Response.Write Application("cart").Count
+	%>`, session, app)
+
+	expected := "Dictionary|4|A-1|3|9.5|True;Object|2|sale;Dictionary|9.5|True|7|2026;4"
+	if output != expected {
+		t.Fatalf("expected output %q, got %q", expected, output)
+	}
+}
+
+// TestASPSessionObjectsStayBoundDuringRequest verifies that Session(...) and
+// Application(...) return the same object for the whole request, so changes
+// made through them are kept in the next request.
+func TestASPSessionObjectsStayBoundDuringRequest(t *testing.T) {
+	asp.SetSessionStorageDir(filepath.Join(t.TempDir(), "session"))
+	t.Cleanup(func() { asp.SetSessionStorageDir("") })
+	app := asp.NewApplication()
+	session := asp.NewSession()
+
+	output := runSessionObjectPage(t, `<%
+	Set Session("cart") = CreateObject("Scripting.Dictionary")
+	Call Session("cart").Add("a", 1)
+	Call Session("cart").Add("b", 2)
+	Response.Write Session("cart").Count & ";"
+	Set Application("visitors") = CreateObject("Collection")
+	Call Application("visitors").Add("ana")
+	Response.Write Application.Contents("visitors").Count & ";"
+	%>`, session, app)
+	if output != "2;1;" {
+		t.Fatalf("expected changes within the request, got %q", output)
+	}
+
+	session = reloadSessionObjectSession(t, session)
+	output = runSessionObjectPage(t, `<%
+	Call Session("cart").Remove("a")
+	Session.Contents("cart")("c") = 3
+	Call Application("visitors").Add("bia")
+	Response.Write Session("cart").Count & ";" & Application("visitors").Count & ";"
+	%>`, session, app)
+	if output != "2;2;" {
+		t.Fatalf("expected the stored changes in the next request, got %q", output)
+	}
+
+	session = reloadSessionObjectSession(t, session)
+	output = runSessionObjectPage(t, `<%
+	Dim key
+	For Each key In Session("cart").Keys
+		Response.Write key & "=" & Session("cart")(key) & ";"
+	Next
+	Response.Write Application("visitors")(2)
+	%>`, session, app)
+	if output != "b=2;c=3;bia" {
+		t.Fatalf("expected b=2;c=3;bia, got %q", output)
+	}
+
+	session = reloadSessionObjectSession(t, session)
+	runSessionObjectPage(t, `<%@Language="JavaScript"%>
+<%
+Session("user") = { name: "ana" };
+Session("user").name = "bia";
+Session("user").roles = ["admin"];
+%>`, session, app)
+	session = reloadSessionObjectSession(t, session)
+	output = runSessionObjectPage(t, `<%@Language="JavaScript"%>
+<%
+Response.Write(JSON.stringify(Session("user")));
+%>`, session, app)
+	if output != `{"name":"bia","roles":["admin"]}` {
+		t.Fatalf("expected the changed JScript object, got %q", output)
+	}
+}
+
+// TestASPSessionStoresDisconnectedRecordset verifies that a disconnected
+// Recordset keeps its fields, rows and position in the next request.
+func TestASPSessionStoresDisconnectedRecordset(t *testing.T) {
+	asp.SetSessionStorageDir(filepath.Join(t.TempDir(), "session"))
+	t.Cleanup(func() { asp.SetSessionStorageDir("") })
+	app := asp.NewApplication()
+	session := asp.NewSession()
+
+	runSessionObjectPage(t, `<%
+	Dim rs
+	Set rs = Server.CreateObject("ADODB.Recordset")
+	rs.Fields.Append "Name", 200, 50
+	rs.Fields.Append "Score", 3
+	rs.Open
+	rs.AddNew
+	rs("Name") = "ana"
+	rs("Score") = 7
+	rs.Update
+	rs.AddNew
+	rs("Name") = "bia"
+	rs("Score") = Null
+	rs.Update
+	rs.MoveFirst
+	rs.MoveNext
+	Set Session("rs") = rs
+	%>`, session, app)
+
+	session = reloadSessionObjectSession(t, session)
+	output := runSessionObjectPage(t, `<%
+	Dim rs
+	Set rs = Session("rs")
+	Response.Write rs.RecordCount & "|" & rs.Fields.Count & "|" & rs("Name") & "|" & IsNull(rs("Score")) & "|" & rs.Fields(1).Type & ";"
+	rs.MoveFirst
+	Response.Write rs("name") & "|" & rs("Score")
+	%>`, session, app)
+
+	expected := "2|2|bia|True|3;ana|7"
+	if output != expected {
+		t.Fatalf("expected output %q, got %q", expected, output)
+	}
+}
+
+// TestASPSessionStoresJScriptObjectsByValue verifies plain JScript objects and arrays.
+func TestASPSessionStoresJScriptObjectsByValue(t *testing.T) {
+	asp.SetSessionStorageDir(filepath.Join(t.TempDir(), "session"))
+	t.Cleanup(func() { asp.SetSessionStorageDir("") })
+	app := asp.NewApplication()
+	session := asp.NewSession()
+
+	runSessionObjectPage(t, `<%@Language="JavaScript"%>
+<%
+Session("user") = { name: "ana", roles: ["admin", "dev"], address: { city: "Recife" } };
+%>`, session, app)
+
+	session = reloadSessionObjectSession(t, session)
+	output := runSessionObjectPage(t, `<%@Language="JavaScript"%>
+<%
+var user = Session("user");
+Response.Write(user.name + "|" + user.roles.length + "|" + user.roles[1] + "|" + user.address.city + "|" + JSON.stringify(user));
+%>`, session, app)
+
+	expected := `ana|2|dev|Recife|{"name":"ana","roles":["admin","dev"],"address":{"city":"Recife"}}`
+	if output != expected {
+		t.Fatalf("expected output %q, got %q", expected, output)
+	}
+}
+
+// TestASPSessionRejectsObjectsThatCannotBeStored verifies that storing an
+// object without a by-value form raises a runtime error and stores nothing.
+func TestASPSessionRejectsObjectsThatCannotBeStored(t *testing.T) {
+	output := runSessionObjectPage(t, `<%
+	Class Basket
+		Public Items
+	End Class
+	On Error Resume Next
+	Set Session("fso") = Server.CreateObject("Scripting.FileSystemObject")
+	Response.Write CStr(Err.Number = 0) & "|" & CStr(InStr(Err.Description, "Cannot store") > 0) & "|" & IsEmpty(Session("fso")) & ";"
+	Err.Clear
+	Dim holder
+	Set holder = Server.CreateObject("Scripting.Dictionary")
+	holder.Add "basket", New Basket
+	Set Session("holder") = holder
+	Response.Write CStr(Err.Number = 0) & "|" & CStr(InStr(Err.Description, "Basket") > 0) & "|" & IsEmpty(Session("holder"))
+	%>`, asp.NewSession(), asp.NewApplication())
+
+	expected := "False|True|True;False|True|True"
+	if output != expected {
+		t.Fatalf("expected output %q, got %q", expected, output)
+	}
+}
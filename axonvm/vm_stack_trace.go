@@ -0,0 +1,252 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package axonvm
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// StackFrame is one procedure of the call stack reported by a StackTracer.
+type StackFrame struct {
+	Procedure string
+	File      string
+	Line      int
+	// IncludedFrom lists the #include directives that brought File into the
+	// page, the innermost first.
+	IncludedFrom []SourceLocation
+}
+
+// traceFrame is one VBScript or JScript call recorded by a StackTracer.
+type traceFrame struct {
+	name     string
+	js       bool
+	callLine int // merged line of the caller when the call was made
+}
+
+// StackTracer mirrors the call stack of one running VM so another goroutine
+// can read it, for example to log a request that is still running after the
+// slow request timeout. Attach it with VM.SetStackTracer before Run.
+type StackTracer struct {
+	mu         sync.Mutex
+	frames     []traceFrame
+	vbDepth    int
+	jsDepth    int
+	line       atomic.Int64
+	sourceName string
+	sourceMap  SourceMap
+
+	// names caches VBScript procedure names by entry point. Only the VM
+	// goroutine uses it.
+	names map[int]string
+}
+
+// NewStackTracer returns an empty StackTracer.
+func NewStackTracer() *StackTracer {
+	return &StackTracer{}
+}
+
+// SetStackTracer attaches t to the VM. The VM keeps it up to date at every
+// statement and call until the VM returns to its pool. A nil t detaches it.
+func (vm *VM) SetStackTracer(t *StackTracer) {
+	if vm == nil {
+		return
+	}
+	vm.tracer = t
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.frames = t.frames[:0]
+	t.vbDepth = 0
+	t.jsDepth = 0
+	t.sourceName = vm.sourceName
+	if strings.TrimSpace(t.sourceName) == "" {
+		t.sourceName = vm.baseSourceName
+	}
+	t.sourceMap = vm.sourceMap.Clone()
+	t.names = nil
+	t.mu.Unlock()
+	t.line.Store(int64(vm.lastLine))
+}
+
+// traceLine records the statement the VM is about to run and drops the frames
+// of calls that already returned.
+func (vm *VM) traceLine() {
+	t := vm.tracer
+	t.line.Store(int64(vm.lastLine))
+	if t.vbDepth > len(vm.callStack) || t.jsDepth > len(vm.jsCallStack) {
+		t.mu.Lock()
+		t.trimLocked(len(vm.callStack), len(vm.jsCallStack))
+		t.mu.Unlock()
+	}
+}
+
+// traceCall records the VBScript procedure callee, just pushed on callStack.
+func (vm *VM) traceCall(callee Value) {
+	vm.tracer.push(vm.traceProcedureName(callee), false, vm.lastLine, len(vm.callStack)-1, len(vm.jsCallStack))
+}
+
+// traceJSCall records the JScript function just pushed on jsCallStack.
+func (vm *VM) traceJSCall() {
+	name := "<anonymous>"
+	n := len(vm.jsCallStack)
+	if n > 0 {
+		name = consoleJSFrameName(vm, vm.jsCallStack[n-1])
+	}
+	vm.tracer.push(name, true, vm.lastLine, len(vm.callStack), n-1)
+}
+
+// push records one call on top of vbDepth VBScript and jsDepth JScript frames.
+func (t *StackTracer) push(name string, js bool, callLine int, vbDepth int, jsDepth int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trimLocked(vbDepth, jsDepth)
+	t.frames = append(t.frames, traceFrame{name: name, js: js, callLine: callLine})
+	if js {
+		t.jsDepth++
+	} else {
+		t.vbDepth++
+	}
+}
+
+// trimLocked drops the newest frames until at most vbDepth VBScript and
+// jsDepth JScript frames are left.
+func (t *StackTracer) trimLocked(vbDepth int, jsDepth int) {
+	for len(t.frames) > 0 && (t.vbDepth > vbDepth || t.jsDepth > jsDepth) {
+		last := t.frames[len(t.frames)-1]
+		t.frames = t.frames[:len(t.frames)-1]
+		if last.js {
+			t.jsDepth--
+		} else {
+			t.vbDepth--
+		}
+	}
+}
+
+// traceProcedureName returns the declared name of the VBScript procedure that
+// starts at callee, with its class name for class members.
+func (vm *VM) traceProcedureName(callee Value) string {
+	t := vm.tracer
+	entry := int(callee.Num)
+	if name, ok := t.names[entry]; ok {
+		return name
+	}
+	name := ""
+	if callee.Type == VTUserSub {
+		for i, global := range vm.Globals {
+			if global.Type == VTUserSub && global.Num == callee.Num && i < len(vm.globalNames) {
+				name = vm.globalNames[i]
+				break
+			}
+		}
+		if name == "" {
+			name = vm.traceClassMemberName(callee.Num)
+		}
+	}
+	if name == "" {
+		name = "<procedure>"
+	}
+	if t.names == nil {
+		t.names = make(map[int]string)
+	}
+	t.names[entry] = name
+	return name
+}
+
+// traceClassMemberName returns "Class.Member" for the class method or property
+// accessor that starts at entry.
+func (vm *VM) traceClassMemberName(entry int64) string {
+	for _, classDef := range vm.runtimeClasses {
+		for methodName, method := range classDef.Methods {
+			if method.Target.Type == VTUserSub && method.Target.Num == entry {
+				return classDef.Name + "." + methodName
+			}
+		}
+		for _, property := range classDef.Properties {
+			for _, target := range []Value{property.GetTarget, property.LetTarget, property.SetTarget} {
+				if target.Type == VTUserSub && target.Num == entry {
+					return classDef.Name + "." + property.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Snapshot returns the call stack of the VM, the innermost procedure first.
+// The last frame is the page itself, named "<global>". It is safe to call
+// while the VM runs.
+func (t *StackTracer) Snapshot() []StackFrame {
+	if t == nil {
+		return nil
+	}
+	line := int(t.line.Load())
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	frames := make([]StackFrame, 0, len(t.frames)+1)
+	for i := len(t.frames) - 1; i >= 0; i-- {
+		frames = append(frames, t.stackFrameLocked(t.frames[i].name, line))
+		line = t.frames[i].callLine
+	}
+	return append(frames, t.stackFrameLocked("<global>", line))
+}
+
+// stackFrameLocked maps one merged line to its source file, line and include chain.
+func (t *StackTracer) stackFrameLocked(procedure string, mergedLine int) StackFrame {
+	frame := StackFrame{Procedure: procedure, File: t.sourceName, Line: mergedLine}
+	if file, line, ok := t.sourceMap.ResolveLine(mergedLine); ok {
+		if strings.TrimSpace(file) != "" {
+			frame.File = file
+		}
+		if line > 0 {
+			frame.Line = line
+		}
+	}
+	frame.IncludedFrom = t.sourceMap.IncludeChain(mergedLine)
+	return frame
+}
+
+// FormatStackTrace formats frames one per line, as "at Procedure (file:line)",
+// with one "included from" line per #include directive.
+func FormatStackTrace(frames []StackFrame) string {
+	var b strings.Builder
+	for _, frame := range frames {
+		b.WriteString("    at ")
+		b.WriteString(frame.Procedure)
+		b.WriteString(" (")
+		b.WriteString(frame.File)
+		b.WriteString(":")
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteString(")\n")
+		for _, include := range frame.IncludedFrom {
+			b.WriteString("        included from ")
+			b.WriteString(include.File)
+			b.WriteString(":")
+			b.WriteString(strconv.Itoa(include.Line))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package axonvm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStackTracerSnapshotReportsIncludedProceduresAndTerminate(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainPath := filepath.Join(tmpDir, "main.asp")
+	includePath := filepath.Join(tmpDir, "db.inc")
+
+	includeSource := strings.Join([]string{
+		"<%",
+		"Sub WaitForever()",
+		"  Do",
+		"  Loop",
+		"End Sub",
+		"Class Repository",
+		"  Public Sub Fetch()",
+		"    Call WaitForever()",
+		"  End Sub",
+		"End Class",
+		"%>",
+	}, "\r\n")
+	if err := os.WriteFile(includePath, []byte(includeSource), 0o600); err != nil {
+		t.Fatalf("write include failed: %v", err)
+	}
+	mainSource := strings.Join([]string{
+		"<%",
+		"Dim repo",
+		"%>",
+		"<!--#include file=\"db.inc\"-->",
+		"<%",
+		"Set repo = New Repository",
+		"repo.Fetch",
+		"%>",
+	}, "\r\n")
+
+	compiler := NewASPCompiler(mainSource)
+	compiler.SetSourceName(mainPath)
+	if err := compiler.Compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	vm := NewVMFromCompiler(compiler)
+	host := NewMockHost()
+	vm.SetHost(host)
+	tracer := NewStackTracer()
+	vm.SetStackTracer(tracer)
+
+	done := make(chan error, 1)
+	go func() { done <- vm.Run() }()
+
+	var frames []StackFrame
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		frames = tracer.Snapshot()
+		if len(frames) == 3 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	host.Server().Terminate()
+
+	select {
+	case runErr := <-done:
+		var aspErr *AxonASPError
+		if !errors.As(runErr, &aspErr) || aspErr.Code != ErrRequestTerminated {
+			t.Fatalf("expected ErrRequestTerminated, got %T: %v", runErr, runErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("terminated script did not stop")
+	}
+
+	if len(frames) != 3 {
+		t.Fatalf("expected three frames, got %+v", frames)
+	}
+	if frames[0].Procedure != "WaitForever" || frames[0].File != includePath || frames[0].Line != 3 {
+		t.Fatalf("unexpected innermost frame %+v", frames[0])
+	}
+	if !strings.EqualFold(frames[1].Procedure, "Repository.Fetch") || frames[1].File != includePath || frames[1].Line != 8 {
+		t.Fatalf("unexpected class frame %+v", frames[1])
+	}
+	if len(frames[0].IncludedFrom) != 1 || frames[0].IncludedFrom[0] != (SourceLocation{File: mainPath, Line: 4}) {
+		t.Fatalf("unexpected include chain %+v", frames[0].IncludedFrom)
+	}
+	if frames[2].Procedure != "<global>" || frames[2].File != mainPath || frames[2].Line != 7 || len(frames[2].IncludedFrom) != 0 {
+		t.Fatalf("unexpected page frame %+v", frames[2])
+	}
+
+	text := FormatStackTrace(frames)
+	if !strings.Contains(text, "    at WaitForever ("+includePath+":3)\n        included from "+mainPath+":4\n") {
+		t.Fatalf("unexpected stack trace:\n%s", text)
+	}
+}
@@ -104,6 +104,7 @@ if ($Clean) {
     Write-Info "Cleaning previous builds..."
     Remove-Item -Path "axonasp-http.exe"    -ErrorAction SilentlyContinue
     Remove-Item -Path "axonasp-fastcgi.exe" -ErrorAction SilentlyContinue
+    Remove-Item -Path "axonasp-cgi.exe"     -ErrorAction SilentlyContinue
     Remove-Item -Path "axonasp-cli.exe"     -ErrorAction SilentlyContinue
     Remove-Item -Path "axonasp-testsuite.exe" -ErrorAction SilentlyContinue
     Remove-Item -Path "axonasp-mcp.exe"     -ErrorAction SilentlyContinue
@@ -119,6 +120,7 @@ if ($Clean) {
 $Targets = @(
     @{ Label = "HTTP Server"; Output = "axonasp-http"; Source = "./server" },
     @{ Label = "FastCGI Server"; Output = "axonasp-fastcgi"; Source = "./fastcgi" },
+    @{ Label = "CGI Program"; Output = "axonasp-cgi"; Source = "./cgi" },
     @{ Label = "CLI"; Output = "axonasp-cli"; Source = "./cli" },
     @{ Label = "Test Suite"; Output = "axonasp-testsuite"; Source = "./testsuite" },
     @{ Label = "MCP"; Output = "axonasp-mcp"; Source = "./mcp" },
@@ -259,7 +261,7 @@ if ($BuildSuccess) {
     Write-Host ""
     Write-Host "  Executables:" -ForegroundColor White
 
-    @("axonasp-http.exe", "axonasp-fastcgi.exe", "axonasp-cli.exe", "axonasp-testsuite.exe", "axonasp-mcp.exe", "axonasp-service.exe", "axonasp-admin.exe", "axonhta.exe") | ForEach-Object {
+    @("axonasp-http.exe", "axonasp-fastcgi.exe", "axonasp-cgi.exe", "axonasp-cli.exe", "axonasp-testsuite.exe", "axonasp-mcp.exe", "axonasp-service.exe", "axonasp-admin.exe", "axonhta.exe") | ForEach-Object {
         if (Test-Path $_) { Write-Host "    - $_" -ForegroundColor Cyan }
     }
 
@@ -273,6 +275,7 @@ if ($BuildSuccess) {
     Write-Host "  Quick Start:" -ForegroundColor White
     Write-Host "    HTTP Server : .\axonasp-http.exe" -ForegroundColor Gray
     Write-Host "    FastCGI     : .\axonasp-fastcgi.exe" -ForegroundColor Gray
+    Write-Host "    CGI         : .\axonasp-cgi.exe (started by the web server)" -ForegroundColor Gray
     Write-Host "    CLI         : .\axonasp-cli.exe" -ForegroundColor Gray
     Write-Host "    Test Suite  : .\axonasp-testsuite.exe .\www\tests" -ForegroundColor Gray
     Write-Host "    MCP         : .\axonasp-mcp.exe" -ForegroundColor Gray
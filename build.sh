@@ -114,16 +114,16 @@ cd "$SCRIPT_DIR" || exit 1
 # Clean previous builds
 if [ "$CLEAN" -eq 1 ]; then
     write_info "Cleaning previous builds..."
-    rm -f axonasp-http.exe axonasp-fastcgi.exe axonasp-cli.exe axonasp-testsuite.exe axonasp-mcp.exe axonasp-service.exe axonasp-admin.exe axonhta.exe axonasp-http axonasp-fastcgi axonasp-cli axonasp-testsuite axonasp-mcp axonasp-service axonasp-admin axonhta
+    rm -f axonasp-http.exe axonasp-fastcgi.exe axonasp-cgi.exe axonasp-cli.exe axonasp-testsuite.exe axonasp-mcp.exe axonasp-service.exe axonasp-admin.exe axonhta.exe axonasp-http axonasp-fastcgi axonasp-cgi axonasp-cli axonasp-testsuite axonasp-mcp axonasp-service axonasp-admin axonhta
     rm -rf build
     write_success "Cleaned."
     echo ""
 fi
 
 # Targets
-TARGET_LABELS=("HTTP Server" "FastCGI Server" "CLI" "Test Suite" "MCP" "Service Wrapper" "Admin Tool" "HTA Desktop")
-TARGET_OUTPUTS=("axonasp-http" "axonasp-fastcgi" "axonasp-cli" "axonasp-testsuite" "axonasp-mcp" "axonasp-service" "axonasp-admin" "axonhta")
-TARGET_SOURCES=("./server" "./fastcgi" "./cli" "./testsuite" "./mcp" "./service" "./admin" "./axonhta")
+TARGET_LABELS=("HTTP Server" "FastCGI Server" "CGI Program" "CLI" "Test Suite" "MCP" "Service Wrapper" "Admin Tool" "HTA Desktop")
+TARGET_OUTPUTS=("axonasp-http" "axonasp-fastcgi" "axonasp-cgi" "axonasp-cli" "axonasp-testsuite" "axonasp-mcp" "axonasp-service" "axonasp-admin" "axonhta")
+TARGET_SOURCES=("./server" "./fastcgi" "./cgi" "./cli" "./testsuite" "./mcp" "./service" "./admin" "./axonhta")
 
 BUILD_SUCCESS=true
 
@@ -272,7 +272,7 @@ if [ "$BUILD_SUCCESS" = true ]; then
     echo -e " ${WHITE} Executables:${NC}"
 
     # List root executables
-    for file in axonasp-http axonasp-fastcgi axonasp-cli axonasp-testsuite axonasp-mcp axonasp-service axonasp-admin axonhta; do
+    for file in axonasp-http axonasp-fastcgi axonasp-cgi axonasp-cli axonasp-testsuite axonasp-mcp axonasp-service axonasp-admin axonhta; do
         if [ -f "$file" ]; then echo -e "    - ${CYAN}$file${NC}"; fi
     done
 
@@ -287,6 +287,7 @@ if [ "$BUILD_SUCCESS" = true ]; then
     echo -e " ${WHITE} Quick Start:${NC}"
     echo -e "    ${DARKGRAY}HTTP Server : ./axonasp-http${NC}"
     echo -e "    ${DARKGRAY}FastCGI     : ./axonasp-fastcgi${NC}"
+    echo -e "    ${DARKGRAY}CGI         : ./axonasp-cgi (started by the web server)${NC}"
     echo -e "    ${DARKGRAY}CLI         : ./axonasp-cli${NC}"
     echo -e "    ${DARKGRAY}Test Suite  : ./axonasp-testsuite ./www/tests${NC}"
     echo -e "    ${DARKGRAY}MCP         : ./axonasp-mcp${NC}"
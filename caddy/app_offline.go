@@ -0,0 +1,128 @@
+package caddy
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"go.uber.org/zap"
+
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonoffline"
+)
+
+// offlineMonitorSet holds the app_offline.htm monitors of one handler by web root.
+type offlineMonitorSet struct {
+	mu       sync.Mutex
+	monitors map[string]*axonoffline.Monitor
+}
+
+// startOfflineMonitors starts the application of the global.asa web root through
+// its app_offline.htm monitor. Other web roots get a monitor on their first request.
+func (a *AxonASP) startOfflineMonitors() {
+	a.offlineMonitors = &offlineMonitorSet{monitors: make(map[string]*axonoffline.Monitor)}
+	if a.GlobalAsaPath == "" {
+		return
+	}
+	webRoot := a.resolveWebRoot(nil)
+	monitor := axonoffline.New(webRoot, a.offlineConfig, axonoffline.Hooks{
+		Start: func(restart bool) { a.startApplication(webRoot, restart) },
+		Stop:  func() { a.stopApplication(webRoot) },
+	})
+	a.offlineMonitors.monitors[webRoot] = monitor
+	if err := monitor.Start(nil); err != nil {
+		a.logger.Warn("Failed to watch "+axonoffline.FileName, zap.Error(err), zap.String("path", webRoot))
+	}
+}
+
+// offlineMonitor returns the app_offline.htm monitor of webRoot, creating it on first use.
+func (a *AxonASP) offlineMonitor(webRoot string) *axonoffline.Monitor {
+	set := a.offlineMonitors
+	if set == nil || !a.offlineConfig.Enabled {
+		return nil
+	}
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	if set.monitors == nil {
+		return nil
+	}
+	if monitor, ok := set.monitors[webRoot]; ok {
+		return monitor
+	}
+	monitor := axonoffline.New(webRoot, a.offlineConfig, axonoffline.Hooks{
+		Start: func(restart bool) {
+			if restart {
+				a.resetApplication()
+			}
+		},
+	})
+	set.monitors[webRoot] = monitor
+	if err := monitor.Start(nil); err != nil && a.logger != nil {
+		a.logger.Warn("Failed to watch "+axonoffline.FileName, zap.Error(err), zap.String("path", webRoot))
+	}
+	return monitor
+}
+
+// stopOfflineMonitors stops watching and runs Application_OnEnd for a running application.
+func (a *AxonASP) stopOfflineMonitors() {
+	set := a.offlineMonitors
+	if set == nil {
+		return
+	}
+	set.mu.Lock()
+	monitors := set.monitors
+	set.monitors = nil
+	set.mu.Unlock()
+	for _, monitor := range monitors {
+		monitor.Stop()
+	}
+}
+
+// serveWithAppOffline answers 503 with app_offline.htm while the web root of the
+// request is offline, keeping the error returned by the Caddy handler.
+func (a *AxonASP) serveWithAppOffline(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	monitor := a.offlineMonitor(a.resolveWebRoot(r))
+	if monitor == nil {
+		return a.serveHTTP(w, r, next)
+	}
+	var err error
+	monitor.Serve(w, r, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		err = a.serveHTTP(rw, req, next)
+	}))
+	return err
+}
+
+// startApplication loads global.asa and runs Application_OnStart. A restart after
+// app_offline.htm was removed first drops the cached scripts and Application state.
+func (a *AxonASP) startApplication(webRoot string, restart bool) {
+	if restart {
+		a.resetApplication()
+	}
+	if err := a.globalASA.LoadAndCompile(webRoot, a.application); err != nil {
+		a.logger.Warn("Failed to load global.asa", zap.Error(err), zap.String("path", a.GlobalAsaPath))
+		return
+	}
+	a.logger.Info("Loaded global.asa", zap.String("path", a.GlobalAsaPath))
+	// Execute Application_OnStart using a dummy host to initialize state
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	dummyHost := NewCaddyWebHost(axonhandler.DiscardWriter{}, req, a, webRoot)
+	_ = a.globalASA.ExecuteApplicationOnStart(dummyHost)
+	axonhandler.RegisterSessionOnEnd(a.hostOptions(webRoot))
+}
+
+// stopApplication runs Application_OnEnd.
+func (a *AxonASP) stopApplication(webRoot string) {
+	if !a.globalASA.IsLoaded() {
+		return
+	}
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	dummyHost := NewCaddyWebHost(axonhandler.DiscardWriter{}, req, a, webRoot)
+	axonhandler.UnregisterSessionOnEnd(a.hostOptions(webRoot))
+	_ = a.globalASA.ExecuteApplicationOnEnd(dummyHost)
+}
+
+// resetApplication drops the cached scripts and the Application state.
+func (a *AxonASP) resetApplication() {
+	a.scriptCache.Clear()
+	a.application.Reset()
+}
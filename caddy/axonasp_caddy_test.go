@@ -7,7 +7,6 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
-	"unsafe"
 
 	"g3pix.com.br/axonasp/axonconfig"
 	"g3pix.com.br/axonasp/axonvm"
@@ -198,7 +197,7 @@ End If
 
 	// Provision scriptCache if needed
 	a.scriptCache = axonvm.NewScriptCache(axonvm.BytecodeCacheMemoryOnly, "", 64)
-	a.vmPools = &vmPoolManager{pools: make(map[string]unsafe.Pointer)}
+	a.vmPool = axonvm.NewLocalVMPool(axonvm.LocalVMPoolOptions{})
 	a.application = asp.NewApplication()
 
 	rec = httptest.NewRecorder()
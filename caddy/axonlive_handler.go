@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"g3pix.com.br/axonasp/axonhandler"
 	"g3pix.com.br/axonasp/axonvm"
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
 )
@@ -119,7 +120,7 @@ func (a *AxonASP) handleAxonLiveFetch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authenticatedSessionID := authenticatedSessionIDFromRequest(r)
+	authenticatedSessionID := a.authenticatedSessionIDFromRequest(r)
 	normalizedSessionID, authorized := normalizeAndAuthorizeG3ALSessionID(event.SessionID, authenticatedSessionID)
 	if !authorized {
 		writeG3AlJSONError(w, http.StatusForbidden, axonvm.AxonASPErrorMessages[axonvm.ErrG3ALInvalidSessionID])
@@ -180,11 +181,8 @@ func resolveG3ALScriptURL(sessionID string) string {
 	return axonvm.G3ALGetPageForSession(strings.TrimSpace(sessionID))
 }
 
-func authenticatedSessionIDFromRequest(r *http.Request) string {
-	if c, err := r.Cookie("ASPSESSIONID"); err == nil && c != nil {
-		return strings.TrimSpace(c.Value)
-	}
-	return ""
+func (a *AxonASP) authenticatedSessionIDFromRequest(r *http.Request) string {
+	return axonhandler.SessionIDFromRequest(r, a.sessionCookie.Name)
 }
 
 func normalizeAndAuthorizeG3ALSessionID(payloadSessionID, authenticatedSessionID string) (string, bool) {
@@ -1,6 +1,8 @@
 package caddy
 
 import (
+	"strconv"
+
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
 	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
 	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
@@ -31,6 +33,34 @@ func (a *AxonASP) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 					return d.ArgErr()
 				}
 				a.GlobalAsaPath = d.Val()
+			case "vm_pool_size":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				size, err := strconv.Atoi(d.Val())
+				if err != nil || size < 0 {
+					return d.Errf("invalid vm_pool_size '%s'", d.Val())
+				}
+				a.VMPoolSize = size
+			case "session_cookie_name":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				a.SessionCookieName = d.Val()
+			case "session_cookie_secure":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				secure, err := strconv.ParseBool(d.Val())
+				if err != nil {
+					return d.Errf("invalid session_cookie_secure '%s'", d.Val())
+				}
+				a.SessionCookieSecure = &secure
+			case "session_cookie_samesite":
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				a.SessionCookieSameSite = d.Val()
 			default:
 				return d.Errf("unrecognized subdirective '%s'", d.Val())
 			}
@@ -0,0 +1,60 @@
+package caddy
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+
+	"g3pix.com.br/axonasp/axonmetrics"
+)
+
+// sharedMetrics is used by every axonasp handler of the process, because the
+// dedicated metrics listener can only be bound once and must survive config reloads.
+var (
+	sharedMetricsMu sync.Mutex
+	sharedMetrics   *axonmetrics.Metrics
+)
+
+// acquireSharedMetrics returns the process-wide collector, creating it from cfg on first use.
+// It returns nil when metrics are disabled.
+func acquireSharedMetrics(cfg axonmetrics.Config) (*axonmetrics.Metrics, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	sharedMetricsMu.Lock()
+	defer sharedMetricsMu.Unlock()
+	if sharedMetrics != nil {
+		return sharedMetrics, nil
+	}
+	m, err := axonmetrics.New(cfg, "caddy", Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Start(); err != nil {
+		return nil, err
+	}
+	sharedMetrics = m
+	return m, nil
+}
+
+// serveWithMetrics runs the handler chain inside the metrics middleware, keeping
+// the error returned by the Caddy handler.
+func (a *AxonASP) serveWithMetrics(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	if a.metrics == nil {
+		return a.serveWithAppOffline(w, r, next)
+	}
+	var err error
+	a.metrics.Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		err = a.serveWithAppOffline(rw, req, next)
+	})).ServeHTTP(w, r)
+	return err
+}
+
+// Cleanup stops the app_offline.htm monitors, which runs Application_OnEnd, and
+// removes the bytecode cache of this handler from the shared metrics.
+func (a *AxonASP) Cleanup() error {
+	a.stopOfflineMonitors()
+	a.metrics.RemoveScriptCache(a.scriptCache)
+	return nil
+}
@@ -0,0 +1,400 @@
+//go:build !wasm
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/http/cgi"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"g3pix.com.br/axonasp/axonconfig"
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// ConfigPathEnv names the environment variable with the path of axonasp.toml.
+// Web servers start CGI programs without options, so the path cannot be a flag.
+const ConfigPathEnv = "AXONASP_CONFIG"
+
+// CGI configuration values.
+var (
+	Version                       = "0.0.0.0"
+	RootDir                       = "./www"
+	DefaultPages                  = []string{"default.asp", "index.asp", "default.htm", "index.html", "default.html"}
+	ExecuteAsASPExtension         = []string{".asp"}
+	ExecuteAsVBScriptExtensions   = []string{".vbs"}
+	ExecuteAsJavaScriptExtensions = []string{".js", ".mjs"}
+	ServerEngineMode              = axonvm.EngineModeDefault
+	DefaultErrorPagesDir          = "./www/error-pages"
+	ScriptTimeout                 = 60
+	ResponseBufferLimitBytes      = 4 * 1024 * 1024
+	DebugASP                      = false
+	DefaultTimezone               = "UTC"
+	MemoryLimitMB                 = 128
+	BytecodeCachingMode           = "enabled"
+	CacheMaxSizeMB                = 128
+	SessionCleanupPercent         = 1
+	TempDir                       = filepath.Join(".", "temp")
+	SessionStoreConfig            = axonvm.SessionStoreConfigFromViper(nil)
+	SessionCookieConfig           = axonhandler.SessionCookieConfigFromViper(nil)
+	ApplicationStoreConfig        = axonvm.ApplicationStoreConfigFromViper(nil)
+)
+
+// cgiVariables are the CGI meta-variables copied to Request.ServerVariables
+// as the web server sent them.
+var cgiVariables = []string{
+	"DOCUMENT_ROOT",
+	"SCRIPT_FILENAME",
+	"PATH_TRANSLATED",
+	"SERVER_NAME",
+	"SERVER_PORT",
+	"SERVER_ADDR",
+	"REMOTE_HOST",
+	"REQUEST_SCHEME",
+}
+
+// loadCGIConfig loads the cgi and global settings of config/axonasp.toml.
+func loadCGIConfig() {
+	if configPath := strings.TrimSpace(os.Getenv(ConfigPathEnv)); configPath != "" {
+		axonconfig.SetCustomConfigPath(configPath)
+	}
+	v := axonconfig.NewViper()
+	if strings.TrimSpace(v.ConfigFileUsed()) == "" {
+		log.Printf("Warning: Failed to read configuration file, using defaults.\n")
+	}
+	if workingDir, err := os.Getwd(); err == nil {
+		axonvm.SetInternalErrorLogRootPath(workingDir)
+	}
+	DebugASP = v.GetBool("global.enable_asp_debugging")
+	axonvm.SetInternalErrorLogEnabled(v.GetBool("global.enable_error_log_file"))
+
+	if pages := v.GetStringSlice("cgi.default_pages"); len(pages) > 0 {
+		DefaultPages = pages
+	}
+	if webRoot := strings.TrimSpace(v.GetString("server.web_root")); webRoot != "" {
+		RootDir = webRoot
+	}
+	if executeAsASP := v.GetStringSlice("global.execute_as_asp"); len(executeAsASP) > 0 {
+		ExecuteAsASPExtension = normalizeExtensions(executeAsASP)
+	}
+	if executeAsVBS := v.GetStringSlice("global.execute_as_vbscript"); len(executeAsVBS) > 0 {
+		ExecuteAsVBScriptExtensions = normalizeExtensions(executeAsVBS)
+	}
+	if executeAsJS := v.GetStringSlice("global.execute_as_javascript"); len(executeAsJS) > 0 {
+		ExecuteAsJavaScriptExtensions = normalizeExtensions(executeAsJS)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(v.GetString("cgi.engine_mode"))) {
+	case "vbscript":
+		ServerEngineMode = axonvm.EngineModeVBScript
+	case "javascript":
+		ServerEngineMode = axonvm.EngineModeJavaScript
+	default:
+		ServerEngineMode = axonvm.EngineModeDefault
+	}
+
+	if errorPagesDir := strings.TrimSpace(v.GetString("server.default_error_pages_directory")); errorPagesDir != "" {
+		DefaultErrorPagesDir = errorPagesDir
+	}
+	if ScriptTimeout = v.GetInt("global.default_script_timeout"); ScriptTimeout <= 0 {
+		ScriptTimeout = 60
+	}
+	if responseBufferLimitMB := v.GetInt("global.response_buffer_limit_mb"); responseBufferLimitMB > 0 {
+		ResponseBufferLimitBytes = responseBufferLimitMB * 1024 * 1024
+	}
+	if timezone := strings.TrimSpace(v.GetString("global.default_timezone")); timezone != "" {
+		DefaultTimezone = timezone
+	}
+	if memoryMB := v.GetInt("global.golang_memory_limit_mb"); memoryMB >= 0 {
+		MemoryLimitMB = memoryMB
+	}
+	if BytecodeCachingMode = strings.TrimSpace(v.GetString("global.bytecode_caching_enabled")); BytecodeCachingMode == "" {
+		BytecodeCachingMode = "enabled"
+	}
+	if cacheSizeMB := v.GetInt("global.cache_max_size_mb"); cacheSizeMB > 0 {
+		CacheMaxSizeMB = cacheSizeMB
+	}
+	if v.IsSet("cgi.session_cleanup_percent") {
+		SessionCleanupPercent = min(max(v.GetInt("cgi.session_cleanup_percent"), 0), 100)
+	}
+	if tempDir := strings.TrimSpace(v.GetString("global.temp_dir")); tempDir != "" {
+		TempDir = filepath.Clean(tempDir)
+	}
+	asp.SetSessionStorageDir(filepath.Join(TempDir, "session"))
+	SessionStoreConfig = axonvm.SessionStoreConfigFromViper(v)
+	SessionCookieConfig = axonhandler.SessionCookieConfigFromViper(v)
+	ApplicationStoreConfig = axonvm.ApplicationStoreConfigFromViper(v)
+
+	axonvm.InitGlobalAxonFunctions(v.GetBool("axfunctions.enable_global_ax"))
+}
+
+// applyRuntimeSettings applies timezone and Go memory limit based on loaded configuration.
+func applyRuntimeSettings() {
+	if MemoryLimitMB > 0 {
+		debug.SetMemoryLimit(int64(MemoryLimitMB) * 1024 * 1024)
+	}
+
+	os.Setenv("TZ", DefaultTimezone)
+	location, err := axonvm.ResolveTimezoneLocation(DefaultTimezone)
+	if err != nil {
+		log.Printf("Warning: Could not load timezone %s, using UTC: %v\n", DefaultTimezone, err)
+		location = time.UTC
+	}
+	time.Local = location
+	axonvm.ReloadBuiltinDefaults()
+}
+
+// normalizeExtensions normalizes file extensions to lowercase ".ext" values.
+func normalizeExtensions(values []string) []string {
+	cleaned := make([]string, 0, len(values))
+	for _, value := range values {
+		ext := strings.ToLower(strings.TrimSpace(value))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if !slices.Contains(cleaned, ext) {
+			cleaned = append(cleaned, ext)
+		}
+	}
+	return cleaned
+}
+
+// scriptExtensions returns the extensions executed in the configured engine mode.
+func scriptExtensions() []string {
+	switch ServerEngineMode {
+	case axonvm.EngineModeVBScript:
+		return ExecuteAsVBScriptExtensions
+	case axonvm.EngineModeJavaScript:
+		return ExecuteAsJavaScriptExtensions
+	default:
+		return ExecuteAsASPExtension
+	}
+}
+
+// environment returns the process environment as CGI meta-variables.
+func environment() map[string]string {
+	env := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, found := strings.Cut(entry, "="); found {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// main runs one CGI request. The response goes to the original standard
+// output. Everything else printed by the runtime goes to standard error, which
+// web servers write to their error log.
+func main() {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	log.SetOutput(os.Stderr)
+
+	env := environment()
+	if env["REQUEST_METHOD"] == "" {
+		fmt.Fprintf(os.Stderr, "G3pix ❖ AxonASP CGI %s\nThis program runs one request started by a web server through CGI/1.1.\nFor more information, visit: https://g3pix.com.br/axonasp/manual/\n", Version)
+		os.Exit(1)
+	}
+
+	axonvm.SetRuntimeVersion(strings.TrimSpace(Version))
+	loadCGIConfig()
+	applyRuntimeSettings()
+	if err := axonvm.ConfigureSessionStore(SessionStoreConfig); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Failed to configure the session store.", "global.session_store", 0)
+		os.Exit(1)
+	}
+	if err := SessionCookieConfig.Validate(); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Invalid session cookie settings.", "global.session_cookie_name", 0)
+		os.Exit(1)
+	}
+
+	scriptCache := axonvm.NewScriptCache(axonvm.ParseBytecodeCacheMode(BytecodeCachingMode), filepath.Join(TempDir, "cache"), CacheMaxSizeMB)
+	scriptCache.SetEngineConfig(ServerEngineMode, ExecuteAsASPExtension, ExecuteAsVBScriptExtensions, ExecuteAsJavaScriptExtensions)
+
+	if err := serveCGI(env, os.Stdin, out, scriptCache); err != nil {
+		log.Printf("Error: %v\n", err)
+	}
+	if SessionCleanupPercent > 0 && rand.IntN(100) < SessionCleanupPercent {
+		_ = asp.CleanupExpiredSessionFiles()
+	}
+}
+
+// serveCGI runs the request described by env and body and writes the CGI
+// response to out.
+func serveCGI(env map[string]string, body io.Reader, out io.Writer, scriptCache *axonvm.ScriptCache) error {
+	w := newResponseWriter(out)
+	defer w.Flush()
+
+	r, err := cgi.RequestFromMap(env)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return err
+	}
+	r.Body = http.NoBody
+	if r.ContentLength > 0 {
+		r.Body = io.NopCloser(io.LimitReader(body, r.ContentLength))
+	}
+	w.Header().Set("X-Powered-By", "AxonASP")
+
+	root := strings.TrimSpace(env["DOCUMENT_ROOT"])
+	if root == "" {
+		root = RootDir
+	}
+	extensions := scriptExtensions()
+	hostOptions := axonhandler.HostOptions{
+		EngineMode:          ServerEngineMode,
+		ResponseBufferLimit: ResponseBufferLimitBytes,
+		ScriptTimeout:       ScriptTimeout,
+		ServerSoftware:      "AxonASP-CGI",
+		ScriptCache:         scriptCache,
+		SaveSessionSync:     true,
+		ServerVariables: func(r *http.Request, vars *asp.RequestCollection) {
+			addCGIServerVariables(env, vars)
+		},
+	}
+	SessionCookieConfig.Apply(&hostOptions)
+	handler := axonhandler.New(axonhandler.Options{
+		Root:             root,
+		DefaultDocuments: DefaultPages,
+		ScriptExtensions: extensions,
+		ErrorPagesDir:    DefaultErrorPagesDir,
+		Debug:            DebugASP,
+		LogSource:        "cgi",
+		Host:             hostOptions,
+	})
+	// The requests of a site running at the same time share one Application
+	// when application_store is "file". The first of them runs
+	// Application_OnStart and the last one Application_OnEnd.
+	if err := axonvm.ConfigureApplicationStore(handler.Application(), ApplicationStoreConfig, filepath.Join(TempDir, "application"), axonhandler.SessionApplicationKey(axonhandler.HostOptions{RootDir: root})); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	if err := handler.Start(); err != nil {
+		log.Printf("Warning: Failed to load global.asa: %v\n", err)
+	}
+	defer func() {
+		if err := handler.Stop(); err != nil {
+			log.Printf("Warning: Application_OnEnd failed: %v\n", err)
+		}
+	}()
+
+	// Handler setups, like Apache Action or ScriptAlias, pass the page in
+	// PATH_INFO and its file in PATH_TRANSLATED. Servers that start the page
+	// itself, like lighttpd or fcgiwrap, pass the page file in SCRIPT_FILENAME.
+	pathInfo := env["PATH_INFO"]
+	scriptFile := env["SCRIPT_FILENAME"]
+	switch {
+	case env["PATH_TRANSLATED"] != "" && pathInfo != "":
+		r.URL.Path = pathInfo
+		r.URL.RawPath = ""
+		handler.ServePath(w, r, env["PATH_TRANSLATED"])
+	case scriptFile != "" && slices.Contains(extensions, strings.ToLower(filepath.Ext(scriptFile))):
+		r.URL.Path = env["SCRIPT_NAME"]
+		r.URL.RawPath = ""
+		handler.ServePath(w, r, scriptFile)
+	default:
+		handler.ServeHTTP(w, r)
+	}
+	w.Flush()
+	return nil
+}
+
+// addCGIServerVariables copies the CGI meta-variables that the web server knows
+// better than the request itself, and the user it authenticated.
+func addCGIServerVariables(env map[string]string, vars *asp.RequestCollection) {
+	vars.Add("GATEWAY_INTERFACE", "CGI/1.1")
+	for _, name := range cgiVariables {
+		if value := env[name]; value != "" {
+			vars.Add(name, value)
+		}
+	}
+	if user := env["REMOTE_USER"]; user != "" && vars.Get("AUTH_USER") == "" {
+		vars.Add("AUTH_TYPE", env["AUTH_TYPE"])
+		vars.Add("AUTH_USER", user)
+		vars.Add("LOGON_USER", user)
+		vars.Add("REMOTE_USER", user)
+	}
+}
+
+// responseWriter writes a CGI response: a Status line, the headers, a blank
+// line and the body.
+type responseWriter struct {
+	out         *bufio.Writer
+	header      http.Header
+	wroteHeader bool
+}
+
+// newResponseWriter returns a responseWriter that writes to out.
+func newResponseWriter(out io.Writer) *responseWriter {
+	return &responseWriter{out: bufio.NewWriter(out), header: make(http.Header)}
+}
+
+// Header returns the headers sent with WriteHeader.
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader writes the Status line and the headers once.
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader || status < 200 {
+		return
+	}
+	w.wroteHeader = true
+	if w.header.Get("Content-Type") == "" {
+		w.header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+	fmt.Fprintf(w.out, "Status: %d %s\r\n", status, http.StatusText(status))
+	_ = w.header.Write(w.out)
+	_, _ = w.out.WriteString("\r\n")
+}
+
+// Write writes body bytes, sending a 200 header first when none was written.
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.out.Write(p)
+}
+
+// Flush sends the buffered output to the web server. It also completes
+// responses without a body.
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = w.out.Flush()
+}
@@ -0,0 +1,197 @@
+//go:build !wasm
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// runCGI serves one CGI request for the page written to a temporary document
+// root and returns the raw CGI response.
+func runCGI(t *testing.T, page string, env map[string]string, body string) string {
+	t.Helper()
+	return runCGIInRoot(t, t.TempDir(), page, env, body)
+}
+
+// runCGIInRoot serves one CGI request for the page written to root.
+func runCGIInRoot(t *testing.T, root string, page string, env map[string]string, body string) string {
+	t.Helper()
+	asp.SetSessionStorageDir(filepath.Join(t.TempDir(), "session"))
+	if err := os.WriteFile(filepath.Join(root, "page.asp"), []byte(page), 0o644); err != nil {
+		t.Fatalf("write page: %v", err)
+	}
+
+	base := map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"SERVER_PROTOCOL":   "HTTP/1.1",
+		"REQUEST_METHOD":    "GET",
+		"SERVER_NAME":       "example.test",
+		"SERVER_PORT":       "80",
+		"REMOTE_ADDR":       "192.0.2.10",
+		"DOCUMENT_ROOT":     root,
+		"SCRIPT_NAME":       "/page.asp",
+		"SCRIPT_FILENAME":   filepath.Join(root, "page.asp"),
+		"REQUEST_URI":       "/page.asp",
+	}
+	for key, value := range env {
+		base[key] = value
+	}
+
+	cache := axonvm.NewScriptCache(axonvm.BytecodeCacheMemoryOnly, "", 8)
+	cache.SetEngineConfig(axonvm.EngineModeDefault, ExecuteAsASPExtension, ExecuteAsVBScriptExtensions, ExecuteAsJavaScriptExtensions)
+	var out bytes.Buffer
+	if err := serveCGI(base, strings.NewReader(body), &out, cache); err != nil {
+		t.Fatalf("serveCGI: %v", err)
+	}
+	return out.String()
+}
+
+// TestServeCGIWritesStatusHeadersAndBody verifies the CGI response layout.
+func TestServeCGIWritesStatusHeadersAndBody(t *testing.T) {
+	out := runCGI(t, `<% Response.Write "hello" %>`, nil, "")
+	if !strings.HasPrefix(out, "Status: 200 OK\r\n") {
+		t.Fatalf("expected a Status line first, got %q", out)
+	}
+	head, body, found := strings.Cut(out, "\r\n\r\n")
+	if !found {
+		t.Fatalf("expected a blank line after the headers, got %q", out)
+	}
+	if !strings.Contains(head, "X-Powered-By: AxonASP") || !strings.Contains(head, "Content-Type: text/html") {
+		t.Fatalf("missing headers in %q", head)
+	}
+	if body != "hello" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+// TestServeCGIMapsEnvironmentToRequest verifies that query string, form body
+// and CGI meta-variables reach the ASP Request object.
+func TestServeCGIMapsEnvironmentToRequest(t *testing.T) {
+	page := `<% Response.Write Request.QueryString("q") & "|" & Request.Form("name") & "|" & _
+		Request.ServerVariables("REMOTE_ADDR") & "|" & Request.ServerVariables("GATEWAY_INTERFACE") & "|" & _
+		Request.ServerVariables("AUTH_USER") %>`
+	out := runCGI(t, page, map[string]string{
+		"REQUEST_METHOD": "POST",
+		"QUERY_STRING":   "q=1",
+		"REQUEST_URI":    "/page.asp?q=1",
+		"CONTENT_TYPE":   "application/x-www-form-urlencoded",
+		"CONTENT_LENGTH": "8",
+		"REMOTE_USER":    "alice",
+		"AUTH_TYPE":      "Basic",
+	}, "name=bobextra")
+	_, body, _ := strings.Cut(out, "\r\n\r\n")
+	if body != "1|bob|192.0.2.10|CGI/1.1|alice" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+// TestServeCGIUsesPathTranslated verifies the handler setup where the page is
+// passed in PATH_INFO and PATH_TRANSLATED.
+func TestServeCGIUsesPathTranslated(t *testing.T) {
+	root := t.TempDir()
+	page := filepath.Join(root, "other.asp")
+	if err := os.WriteFile(page, []byte(`<% Response.Write Request.ServerVariables("SCRIPT_NAME") %>`), 0o644); err != nil {
+		t.Fatalf("write page: %v", err)
+	}
+	out := runCGI(t, "", map[string]string{
+		"SCRIPT_NAME":     "/cgi-bin/axonasp-cgi",
+		"SCRIPT_FILENAME": "/usr/lib/cgi-bin/axonasp-cgi",
+		"PATH_INFO":       "/other.asp",
+		"PATH_TRANSLATED": page,
+		"REQUEST_URI":     "/other.asp",
+	}, "")
+	_, body, _ := strings.Cut(out, "\r\n\r\n")
+	if body != "/other.asp" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+// TestServeCGIPersistsSessionBeforeExit verifies that a new session is written
+// to disk before serveCGI returns, since the process exits right after.
+func TestServeCGIPersistsSessionBeforeExit(t *testing.T) {
+	out := runCGI(t, `<% Session("user") = "alice" %>`, nil, "")
+	head, _, _ := strings.Cut(out, "\r\n\r\n")
+	_, cookie, found := strings.Cut(head, "ASPSESSIONID")
+	if !found {
+		t.Fatalf("expected a session cookie, got %q", head)
+	}
+	_, sessionID, _ := strings.Cut(cookie, "=")
+	sessionID, _, _ = strings.Cut(sessionID, ";")
+
+	session, found, err := asp.LoadSession(sessionID)
+	if err != nil || !found {
+		t.Fatalf("expected session %q on disk, found=%v err=%v", sessionID, found, err)
+	}
+	if got, ok := session.Get("user"); !ok || got.Str != "alice" {
+		t.Fatalf("unexpected session value %+v", got)
+	}
+}
+
+// TestServeCGIRunsApplicationEvents verifies that a request runs
+// Application_OnStart before the page and Application_OnEnd after it through
+// the shared store of application_store = "file".
+func TestServeCGIRunsApplicationEvents(t *testing.T) {
+	previousTempDir, previousStore := TempDir, ApplicationStoreConfig
+	TempDir = t.TempDir()
+	ApplicationStoreConfig = axonvm.ApplicationStoreConfig{Backend: axonvm.ApplicationStoreFile}
+	t.Cleanup(func() { TempDir, ApplicationStoreConfig = previousTempDir, previousStore })
+
+	root := t.TempDir()
+	globalASA := `<script language="vbscript" runat="server">
+Sub Application_OnStart
+	Application("started") = "yes"
+End Sub
+Sub Application_OnEnd
+	Application("ended") = Application("started")
+End Sub
+</script>`
+	if err := os.WriteFile(filepath.Join(root, "global.asa"), []byte(globalASA), 0o644); err != nil {
+		t.Fatalf("write global.asa: %v", err)
+	}
+
+	out := runCGIInRoot(t, root, `<% Response.Write Application("started") %>`, nil, "")
+	if _, body, _ := strings.Cut(out, "\r\n\r\n"); body != "yes" {
+		t.Fatalf("expected Application_OnStart to run before the page, got %q", body)
+	}
+
+	store, err := asp.NewFileApplicationStore(filepath.Join(TempDir, "application"), axonhandler.SessionApplicationKey(axonhandler.HostOptions{RootDir: root}))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	contents, err := store.Load()
+	if err != nil {
+		t.Fatalf("load store: %v", err)
+	}
+	if value := contents["ended"]; value.Str != "yes" {
+		t.Fatalf("expected Application_OnEnd to run after the page, got %#v", value)
+	}
+}
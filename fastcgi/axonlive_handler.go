@@ -36,6 +36,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"g3pix.com.br/axonasp/axonhandler"
 	"g3pix.com.br/axonasp/axonvm"
 )
 
@@ -186,12 +187,10 @@ func resolveFCGIG3ALScriptURL(sessionID string) string {
 	return axonvm.G3ALGetPageForSession(strings.TrimSpace(sessionID))
 }
 
-// authenticatedSessionIDFromRequest extracts the ASP session ID from the request cookie.
+// authenticatedSessionIDFromRequest extracts the ASP session ID from the session
+// cookie, checking its signature when cookies are signed.
 func authenticatedSessionIDFromRequest(r *http.Request) string {
-	if c, err := r.Cookie("ASPSESSIONID"); err == nil && c != nil {
-		return strings.TrimSpace(c.Value)
-	}
-	return ""
+	return axonhandler.SessionIDFromRequest(r, SessionCookieConfig.Name)
 }
 
 // normalizeAndAuthorizeG3ALSessionID binds a fetch payload session ID to the authenticated cookie session.
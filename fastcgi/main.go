@@ -27,11 +27,10 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
-	"html"
-	"io"
 	"log"
 	"net"
 	"net/http"
@@ -44,12 +43,20 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
+	"g3pix.com.br/axonasp/axonaccesslog"
+	"g3pix.com.br/axonasp/axonadmission"
+	"g3pix.com.br/axonasp/axonauth"
 	_ "g3pix.com.br/axonasp/axonboot"
+	"g3pix.com.br/axonasp/axoncompress"
 	"g3pix.com.br/axonasp/axonconfig"
+	"g3pix.com.br/axonasp/axonfpm"
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonmetrics"
+	"g3pix.com.br/axonasp/axonoffline"
+	"g3pix.com.br/axonasp/axonproxy"
 	"g3pix.com.br/axonasp/axonvm"
 	"g3pix.com.br/axonasp/axonvm/asp"
 	"github.com/fsnotify/fsnotify"
@@ -75,6 +82,9 @@ var (
 	ServerEngineMode              = axonvm.EngineModeDefault
 	DefaultErrorPagesDir          = "./www/error-pages"
 	ScriptTimeout                 = 60
+	RequestSlowlogTimeout         time.Duration
+	RequestTerminateTimeout       time.Duration
+	SlowlogPath                   = ""
 	ResponseBufferLimitBytes      = 4 * 1024 * 1024
 	DebugASP                      = false
 	CleanupSessions               = true
@@ -89,8 +99,29 @@ var (
 	TempDir                       = filepath.Join(".", "temp")
 	serverLocation                = time.UTC
 	scriptCache                   *axonvm.ScriptCache
+	AccessLogConfig               = axonaccesslog.ConfigFromViper(nil)
+	accessLog                     *axonaccesslog.Writer
+	CompressionConfig             = axoncompress.ConfigFromViper(nil)
+	compressor                    *axoncompress.Compressor
+	AuthenticationConfig          = axonauth.ConfigFromViper(nil)
+	authenticator                 *axonauth.Authenticator
+	SessionStoreConfig            = axonvm.SessionStoreConfigFromViper(nil)
+	SessionCookieConfig           = axonhandler.SessionCookieConfigFromViper(nil)
+	ApplicationStoreConfig        = axonvm.ApplicationStoreConfigFromViper(nil)
+	MetricsConfig                 = axonmetrics.ConfigFromViper(nil)
+	AdmissionConfig               = axonadmission.ConfigFromViper(nil)
+	admission                     *axonadmission.Controller
+	ReverseProxyConfig            = axonproxy.ConfigFromViper(nil)
+	reverseProxy                  *axonproxy.Proxy
+	AppOfflineConfig              = axonoffline.ConfigFromViper(nil)
+	appOffline                    *axonoffline.Monitor
+	metrics                       *axonmetrics.Metrics
 )
 
+// FPMDrainTimeout bounds the wait for in-flight requests when axonasp-fpm stops
+// the worker. axonasp-fpm kills workers that are still running after 15 seconds.
+const FPMDrainTimeout = 10 * time.Second
+
 // buildLogPrefix creates the process log prefix used by all worker output.
 func buildLogPrefix(pid int, poolName string) string {
 	name := strings.TrimSpace(poolName)
@@ -137,6 +168,15 @@ func loadFastCGIConfig() {
 	if pflag.Lookup("server.web_root") == nil {
 		pflag.String("server.web_root", "", "Web root directory (overrides server.web_root from configuration file). When set via CLI by the FPM supervisor, this takes precedence over the TOML value.")
 	}
+	if pflag.Lookup("fastcgi.request_slowlog_timeout") == nil {
+		pflag.Int("fastcgi.request_slowlog_timeout", 0, "Seconds after which a running request is written to the slow log with its ASP call stack (0 disables it)")
+	}
+	if pflag.Lookup("fastcgi.request_terminate_timeout") == nil {
+		pflag.Int("fastcgi.request_terminate_timeout", 0, "Seconds after which a running request is terminated, whatever its Server.ScriptTimeout (0 disables it)")
+	}
+	if pflag.Lookup("fastcgi.slowlog") == nil {
+		pflag.String("fastcgi.slowlog", "", "Slow log file. Entries go to standard error when empty")
+	}
 	if pflag.Lookup("about") == nil {
 		pflag.BoolVarP(&aboutFlag, "about", "a", false, "Print AxonASP product and licensing information, then exit.")
 	}
@@ -173,6 +213,17 @@ func loadFastCGIConfig() {
 	axonvm.SetInternalErrorLogEnabled(v.GetBool("global.enable_error_log_file"))
 	axonvm.SetDumpPreprocessedSourceEnabled(v.GetBool("global.dump_preprocessed_source"))
 
+	AccessLogConfig = axonaccesslog.ConfigFromViper(v)
+	CompressionConfig = axoncompress.ConfigFromViper(v)
+	AuthenticationConfig = axonauth.ConfigFromViper(v)
+	SessionStoreConfig = axonvm.SessionStoreConfigFromViper(v)
+	SessionCookieConfig = axonhandler.SessionCookieConfigFromViper(v)
+	ApplicationStoreConfig = axonvm.ApplicationStoreConfigFromViper(v)
+	MetricsConfig = axonmetrics.ConfigFromViper(v)
+	AdmissionConfig = axonadmission.ConfigFromViper(v)
+	ReverseProxyConfig = axonproxy.ConfigFromViper(v)
+	AppOfflineConfig = axonoffline.ConfigFromViper(v)
+
 	if pages := v.GetStringSlice("fastcgi.default_pages"); len(pages) > 0 {
 		DefaultPages = pages
 	}
@@ -215,6 +266,10 @@ func loadFastCGIConfig() {
 		ResponseBufferLimitBytes = responseBufferLimitMB * 1024 * 1024
 	}
 
+	RequestSlowlogTimeout = time.Duration(max(v.GetInt("fastcgi.request_slowlog_timeout"), 0)) * time.Second
+	RequestTerminateTimeout = time.Duration(max(v.GetInt("fastcgi.request_terminate_timeout"), 0)) * time.Second
+	SlowlogPath = strings.TrimSpace(v.GetString("fastcgi.slowlog"))
+
 	rawListenEndpoint := strings.TrimSpace(v.GetString("fastcgi.server_port"))
 	if rawListenEndpoint == "" {
 		rawListenEndpoint = "9000"
@@ -527,13 +582,6 @@ func cleanupCacheFiles() {
 	}
 }
 
-// dummyResponseWriter provides a no-op http.ResponseWriter for global.asa events.
-type dummyResponseWriter struct{}
-
-func (d *dummyResponseWriter) Header() http.Header         { return make(http.Header) }
-func (d *dummyResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
-func (d *dummyResponseWriter) WriteHeader(statusCode int)  {}
-
 // main starts the FastCGI listener and serves ASP requests.
 func main() {
 	fmt.Printf("%sG3pix ❖ AxonASP FastCGI %s\n", LogPrefix, Version)
@@ -571,6 +619,19 @@ func main() {
 	}
 	defer scriptCache.StopInvalidator()
 
+	if err := axonvm.ConfigureSessionStore(SessionStoreConfig); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Failed to configure the session store.", "global.session_store", 0)
+		os.Exit(1)
+	}
+	if err := SessionCookieConfig.Validate(); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Invalid session cookie settings.", "global.session_cookie_name", 0)
+		os.Exit(1)
+	}
+	// Workers of one pool share the Application of their site when application_store is "file".
+	if err := axonvm.ConfigureApplicationStore(GetSharedApplication(), ApplicationStoreConfig, filepath.Join(TempDir, "application"), fastCGIApplicationKey()); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Failed to configure the application store.", "global.application_store", 0)
+		os.Exit(1)
+	}
 	asp.StartSessionAutoFlush(time.Duration(SessionAutoFlushSeconds) * time.Second)
 	defer asp.StopSessionAutoFlush()
 
@@ -584,13 +645,26 @@ func main() {
 	// Bind the listener BEFORE executing Application_OnStart so the kernel
 	// queues incoming connections during initialization, preventing
 	// "Connection refused" (ECONNREFUSED) in the reverse proxy.
-	listener, err := prepareFastCGIListener(ListenNetwork, ListenAddr)
+	// Workers started by axonasp-fpm accept connections on the socket of their
+	// pool, which stays open in the manager when the worker exits.
+	fpmWorker, err := axonfpm.Join()
 	if err != nil {
-		axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "FastCGI listener could not start.", ListenNetwork+"://"+ListenAddr, 0)
+		axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "FastCGI listener passed by axonasp-fpm could not be adopted.", ListenNetwork+"://"+ListenAddr, 0)
 		os.Exit(1)
 	}
+	var listener net.Listener
+	if fpmWorker != nil {
+		listener = fpmWorker.Listener()
+		defer fpmWorker.Close()
+	} else {
+		listener, err = prepareFastCGIListener(ListenNetwork, ListenAddr)
+		if err != nil {
+			axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "FastCGI listener could not start.", ListenNetwork+"://"+ListenAddr, 0)
+			os.Exit(1)
+		}
+		defer cleanupFastCGIListenerArtifact(ListenNetwork, ListenAddr)
+	}
 	defer listener.Close()
-	defer cleanupFastCGIListenerArtifact(ListenNetwork, ListenAddr)
 
 	fmt.Printf("%sFastCGI server started on: %s://%s\n", LogPrefix, ListenNetwork, ListenAddr)
 	fmt.Printf("%sRoot directory: %s\n", LogPrefix, RootDir)
@@ -598,27 +672,79 @@ func main() {
 	// Load and compile global.asa only when a concrete file location was found.
 	if shouldLoadGlobalASA {
 		fmt.Printf("%sglobal.asa source directory: %s\n", LogPrefix, globalASARoot)
-		if err := axonvm.GetGlobalASA().LoadAndCompile(globalASARoot, GetSharedApplication()); err != nil {
-			fmt.Printf("%sWarning: Failed to load global.asa: %v\n", LogPrefix, err)
-		} else if axonvm.GetGlobalASA().IsLoaded() {
-			// Execute Application_OnStart using a dummy host
-			req, _ := http.NewRequest("GET", "http://localhost/", nil)
-			dummyHost := NewFastCGIHost(&dummyResponseWriter{}, req)
-			_ = axonvm.GetGlobalASA().ExecuteApplicationOnStart(dummyHost)
-		}
 	} else {
 		fmt.Printf("%s!global.asa not found in fallback locations (CWD/server.web_root); skipping global.asa execution.\n", LogPrefix)
 	}
+	appOffline = axonoffline.New(RootDir, AppOfflineConfig, axonoffline.Hooks{
+		Start: func(restart bool) { startFastCGIApplication(globalASARoot, shouldLoadGlobalASA, restart) },
+		Stop:  stopFastCGIApplication,
+	})
+	if err := appOffline.Start(scriptCache); err != nil {
+		log.Printf("Warning: Failed to watch %s in %s: %v\n", axonoffline.FileName, RootDir, err)
+	}
 
 	mux := http.NewServeMux()
 	RegisterG3AxonLiveEndpoint(mux)
 	mux.HandleFunc("/", fastCGIMiddleware(handleRequest))
 
+	if CompressionConfig.Enabled {
+		if c, err := axoncompress.New(CompressionConfig); err != nil {
+			log.Printf("Warning: Failed to configure response compression, responses will not be compressed: %v\n", err)
+		} else {
+			compressor = c
+		}
+	}
+	admission = axonadmission.New(AdmissionConfig)
+	if p, err := axonproxy.New(ReverseProxyConfig); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Failed to configure the trusted reverse proxies.", "reverse_proxy.trusted_proxies", 0)
+		os.Exit(1)
+	} else {
+		reverseProxy = p
+	}
+	if AuthenticationConfig.Enabled {
+		a, err := axonauth.New(AuthenticationConfig)
+		if err != nil {
+			axonvm.ReportInternalError(axonvm.ErrCouldNotReadFile, err, "Failed to configure the authentication provider.", AuthenticationConfig.HtpasswdFile, 0)
+			os.Exit(1)
+		}
+		authenticator = a
+	}
+	if AccessLogConfig.Enabled {
+		accessLogConfig := AccessLogConfig
+		accessLogConfig.Directory = filepath.Join(accessLogConfig.Directory, "fastcgi")
+		writer, err := axonaccesslog.New(accessLogConfig)
+		if err != nil {
+			log.Printf("Warning: Failed to start the access log, requests will not be logged: %v\n", err)
+		} else {
+			accessLog = writer
+			defer accessLog.Close()
+		}
+	}
+	if MetricsConfig.Enabled {
+		m, err := axonmetrics.New(MetricsConfig, "fastcgi", Version)
+		if err == nil {
+			err = m.Start()
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to start the metrics endpoint, metrics will not be collected: %v\n", err)
+		} else {
+			metrics = m
+			defer metrics.Close()
+			metrics.AddScriptCache("default", scriptCache)
+			if metrics.Listen() != "" {
+				fmt.Printf("%sMetrics endpoint: http://%s%s\n", LogPrefix, metrics.Listen(), metrics.Path())
+			}
+		}
+	}
+	handler := fpmWorker.Handler(reverseProxy.Handler(accessLog.Handler(metrics.Handler(compressor.Handler(appOffline.Handler(mux))), func(r *http.Request) (string, string) {
+		return getFastCGIParam(r, "SERVER_ADDR"), axonhandler.ServerPort(r)
+	})))
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		if err := fcgi.Serve(listener, mux); err != nil {
+		if err := fcgi.Serve(listener, handler); err != nil {
 			if isExpectedFastCGIShutdownError(err) {
 				return
 			}
@@ -629,11 +755,58 @@ func main() {
 
 	<-stop
 	fmt.Printf("%s\nShutting down server...\n", LogPrefix)
+	// A worker stopped by axonasp-fpm finishes its requests while the other
+	// workers of the pool keep accepting connections.
+	fpmWorker.Drain(FPMDrainTimeout)
+
+	appOffline.Stop()
+}
 
+// startFastCGIApplication loads global.asa from globalASARoot and runs Application_OnStart.
+// A restart after app_offline.htm was removed first drops the cached scripts and Application state.
+// With a shared application store only the first worker of the site runs Application_OnStart.
+func startFastCGIApplication(globalASARoot string, loadGlobalASA bool, restart bool) {
+	if restart {
+		scriptCache.Clear()
+		GetSharedApplication().Reset()
+	}
+	if loadGlobalASA {
+		if err := axonvm.GetGlobalASA().LoadAndCompile(globalASARoot, GetSharedApplication()); err != nil {
+			fmt.Printf("%sWarning: Failed to load global.asa: %v\n", LogPrefix, err)
+		}
+	}
+	err := GetSharedApplication().JoinStore(func() {
+		if axonvm.GetGlobalASA().IsLoaded() {
+			// Execute Application_OnStart using a dummy host
+			req, _ := http.NewRequest("GET", "http://localhost/", nil)
+			dummyHost := NewFastCGIHost(axonhandler.DiscardWriter{}, req)
+			_ = axonvm.GetGlobalASA().ExecuteApplicationOnStart(dummyHost)
+		}
+	})
+	if err != nil {
+		fmt.Printf("%sWarning: Failed to join the shared application store: %v\n", LogPrefix, err)
+	}
 	if axonvm.GetGlobalASA().IsLoaded() {
 		req, _ := http.NewRequest("GET", "http://localhost/", nil)
-		dummyHost := NewFastCGIHost(&dummyResponseWriter{}, req)
-		_ = axonvm.GetGlobalASA().ExecuteApplicationOnEnd(dummyHost)
+		axonhandler.RegisterSessionOnEnd(fastCGIHostOptions(req))
+	}
+}
+
+// stopFastCGIApplication runs Application_OnEnd, in the last worker of the site
+// when the application store is shared.
+func stopFastCGIApplication() {
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	if axonvm.GetGlobalASA().IsLoaded() {
+		axonhandler.UnregisterSessionOnEnd(fastCGIHostOptions(req))
+	}
+	err := GetSharedApplication().LeaveStore(func() {
+		if axonvm.GetGlobalASA().IsLoaded() {
+			dummyHost := NewFastCGIHost(axonhandler.DiscardWriter{}, req)
+			_ = axonvm.GetGlobalASA().ExecuteApplicationOnEnd(dummyHost)
+		}
+	})
+	if err != nil {
+		fmt.Printf("%sWarning: Failed to leave the shared application store: %v\n", LogPrefix, err)
 	}
 }
 
@@ -715,6 +888,11 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		scriptPath = "/"
 	}
 
+	r, ok := authenticateRequest(w, r, scriptPath)
+	if !ok {
+		return
+	}
+
 	// Construct the full file path
 	relativePath := strings.TrimPrefix(scriptPath, "/")
 	fullPath := filepath.Join(effectiveRoot, filepath.FromSlash(relativePath))
@@ -777,6 +955,9 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !isASPExecutionExtension(fullPath) {
+		if compressor.ServePrecompressed(w, r, fullPath) {
+			return
+		}
 		http.ServeFile(w, r, fullPath)
 		return
 	}
@@ -797,279 +978,103 @@ func isASPExecutionExtension(filePath string) bool {
 	}
 }
 
-// singleHeaderResponseWriter prevents duplicate WriteHeader calls and can apply a default status code.
-type singleHeaderResponseWriter struct {
-	http.ResponseWriter
-	wroteHeader   bool
-	defaultStatus int
-}
-
-// newSingleHeaderResponseWriter wraps a response writer with duplicate WriteHeader protection.
-func newSingleHeaderResponseWriter(w http.ResponseWriter, defaultStatus int) *singleHeaderResponseWriter {
-	return &singleHeaderResponseWriter{ResponseWriter: w, defaultStatus: defaultStatus}
-}
-
-// WriteHeader writes the status code only once.
-func (w *singleHeaderResponseWriter) WriteHeader(statusCode int) {
-	if w.wroteHeader {
-		return
-	}
-	w.wroteHeader = true
-	w.ResponseWriter.WriteHeader(statusCode)
-}
-
-// Write applies the default status if needed and writes the response body.
-func (w *singleHeaderResponseWriter) Write(data []byte) (int, error) {
-	if !w.wroteHeader {
-		if w.defaultStatus > 0 {
-			w.WriteHeader(w.defaultStatus)
-		} else {
-			w.wroteHeader = true
-		}
-	}
-	return w.ResponseWriter.Write(data)
-}
-
-// Flush forwards flush operations to the underlying writer.
-func (w *singleHeaderResponseWriter) Flush() {
-	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
-		flusher.Flush()
-	}
-}
-
-// ReadFrom forwards optimized copy operations to the underlying writer when available.
-func (w *singleHeaderResponseWriter) ReadFrom(reader io.Reader) (int64, error) {
-	if !w.wroteHeader {
-		if w.defaultStatus > 0 {
-			w.WriteHeader(w.defaultStatus)
-		} else {
-			w.wroteHeader = true
-		}
-	}
-	if readFrom, ok := w.ResponseWriter.(io.ReaderFrom); ok {
-		return readFrom.ReadFrom(reader)
-	}
-	return io.Copy(w.ResponseWriter, reader)
-}
-
 // executeASP compiles and executes an ASP file using the VM and FastCGI host.
 func executeASP(w http.ResponseWriter, r *http.Request, filePath string) {
 	executeASPWithStatus(w, r, filePath, 0)
 }
 
-// resolveRequestScriptTimeout returns the effective timeout in seconds for one request,
-// preferring the current ASP Server.ScriptTimeout value over the bootstrap fallback.
-func resolveRequestScriptTimeout(host axonvm.ASPHostEnvironment, fallback int) int {
-	if host != nil {
-		if server := host.Server(); server != nil {
-			timeout := server.GetScriptTimeout()
-			if timeout > 0 {
-				return timeout
-			}
-		}
+// admitASPRequest waits for a VM pool slot under the admission control limits. ASP
+// error pages never wait, so an overloaded pool cannot queue its own 503 page.
+func admitASPRequest(r *http.Request, errorPage bool) (axonhandler.Ticket, error) {
+	if errorPage {
+		return admission.AdmitErrorPage(r)
 	}
-	if fallback > 0 {
-		return fallback
-	}
-	return 60
+	return admission.Admit(r)
 }
 
-// executeASPWithStatus compiles and executes an ASP file, running vm.Run() in a
-// goroutine so that a blocking CGO/COM call cannot hold the FastCGI handler
-// indefinitely. On timeout a 503 is returned and the goroutine is detached.
-func executeASPWithStatus(w http.ResponseWriter, r *http.Request, filePath string, defaultStatus int) {
-	single := newSingleHeaderResponseWriter(w, defaultStatus)
-	cw := newCancellableWriter(single)
-	host := NewFastCGIHost(cw, r)
-
-	cache := scriptCache
-	if cache == nil {
-		cache = axonvm.NewScriptCache(axonvm.BytecodeCacheDisabled, filepath.Join(TempDir, "cache"), 1)
-	}
-	program, err := cache.LoadOrCompileWithOptions(filePath, axonvm.ScriptCompileOptions{IncludeSiteRoot: host.Server().MapPath("/")})
-	if err != nil {
-		aspErr := axonvm.CompilerErrorToASPError(err, filePath)
-		host.Server().SetLastError(aspErr)
-		axonvm.LogASPProcessedError(aspErr, "fastcgi.executeASP.compile")
-		if !DebugASP {
-			if isErrorPageHandlerPath(filePath) {
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				return
-			}
-			serveErrorPage(w, r, http.StatusInternalServerError)
-			return
-		}
-		renderClassicASPDebugError(w, http.StatusInternalServerError, "Compilation Error", aspErr)
+// rejectASPRequest answers a request refused by admission control with 503 and Retry-After.
+func rejectASPRequest(w http.ResponseWriter, r *http.Request, err error, defaultStatus int) {
+	if errors.Is(err, context.Canceled) {
 		return
 	}
-
-	vm := axonvm.AcquireVMFromCachedProgram(program)
-	vm.SetHost(host)
-
-	timeoutSec := resolveRequestScriptTimeout(host, ScriptTimeout)
-
-	type vmResult struct{ err error }
-	done := make(chan vmResult, 1)
-	go func() {
-		defer vm.Release()
-		runErr := func() (err error) {
-			defer func() {
-				if recovered := recover(); recovered != nil {
-					err = fmt.Errorf("panic recovered in vm.Run: %v", recovered)
-				}
-			}()
-			return vm.Run()
-		}()
-		done <- vmResult{err: runErr}
-	}()
-
-	start := time.Now()
-	watchdog := time.NewTicker(250 * time.Millisecond)
-	defer watchdog.Stop()
-
-	for {
-		select {
-		case res := <-done:
-			if res.err != nil {
-				aspErr := axonvm.RuntimeErrorToASPError(res.err, filePath)
-				host.Server().SetLastError(aspErr)
-				axonvm.LogASPProcessedError(aspErr, "fastcgi.executeASP.runtime")
-				if !DebugASP {
-					if isErrorPageHandlerPath(filePath) {
-						http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-						return
-					}
-					serveErrorPage(w, r, http.StatusInternalServerError)
-					return
-				}
-				renderClassicASPDebugError(w, http.StatusInternalServerError, "Runtime Error", aspErr)
-				return
-			}
-			host.PersistSession()
-			host.Response().Flush()
-			host.Response().ReleaseBuffer()
-			return
-
-		case <-watchdog.C:
-			effectiveTimeout := resolveRequestScriptTimeout(host, timeoutSec)
-			if time.Since(start) >= time.Duration(effectiveTimeout)*time.Second {
-				cw.cancel()
-				log.Printf("[axonasp] script timeout (%ds): %s - goroutine detached\n", effectiveTimeout, filePath)
-				http.Error(w, "Script execution timed out", http.StatusServiceUnavailable)
-				return
-			}
-		}
+	if defaultStatus > 0 {
+		http.Error(w, http.StatusText(defaultStatus), defaultStatus)
+		return
 	}
-}
-
-// cancellableWriter wraps an http.ResponseWriter and silently discards all writes
-// after cancel() is called, protecting the real writer from concurrent access after
-// a script timeout detaches the VM goroutine from the FastCGI handler.
-type cancellableWriter struct {
-	mu       sync.Mutex
-	inner    http.ResponseWriter
-	canceled bool
-}
-
-func newCancellableWriter(w http.ResponseWriter) *cancellableWriter {
-	return &cancellableWriter{inner: w}
-}
-
-func (c *cancellableWriter) Header() http.Header {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.canceled {
-		return make(http.Header)
+	if retryAfter := admission.RetryAfter(); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
 	}
-	return c.inner.Header()
-}
-
-func (c *cancellableWriter) Write(p []byte) (int, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.canceled {
-		return len(p), nil
+	if subStatus := axonadmission.SubStatus(err); subStatus > 0 {
+		axonaccesslog.SetSubStatus(r, subStatus)
 	}
-	return c.inner.Write(p)
+	serveErrorPage(w, r, http.StatusServiceUnavailable)
 }
 
-func (c *cancellableWriter) WriteHeader(status int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.canceled {
-		return
-	}
-	c.inner.WriteHeader(status)
+// executeASPWithStatus runs an ASP file through the shared handler with the FastCGI
+// document root, admission control, error pages and debug settings.
+func executeASPWithStatus(w http.ResponseWriter, r *http.Request, filePath string, defaultStatus int) {
+	newASPHandler().ExecuteFile(w, r, filePath, defaultStatus)
 }
 
-func (c *cancellableWriter) cancel() {
-	c.mu.Lock()
-	c.canceled = true
-	c.mu.Unlock()
+// newASPHandler returns the page handler for the current configuration.
+func newASPHandler() *axonhandler.Handler {
+	return axonhandler.New(axonhandler.Options{
+		ErrorPagesDir:      DefaultErrorPagesDir,
+		Debug:              DebugASP,
+		LogSource:          "fastcgi",
+		SlowRequestTimeout: RequestSlowlogTimeout,
+		TerminateTimeout:   RequestTerminateTimeout,
+		Host:               axonhandler.HostOptions{Application: sharedFastCGIApplication},
+		Hooks: axonhandler.Hooks{
+			Prepare: func(r *http.Request, opts *axonhandler.HostOptions) {
+				*opts = fastCGIHostOptions(r)
+			},
+			Admit:       admitASPRequest,
+			Rejected:    rejectASPRequest,
+			SlowRequest: writeSlowRequest,
+		},
+	})
 }
 
-// isErrorPageHandlerPath reports whether the current execution target is an error page handler itself.
-func isErrorPageHandlerPath(filePath string) bool {
-	errorDirAbs, err := filepath.Abs(DefaultErrorPagesDir)
-	if err != nil {
-		return false
-	}
-
-	fileAbs, err := filepath.Abs(filePath)
-	if err != nil {
-		return false
-	}
-
-	if filepath.Dir(fileAbs) != errorDirAbs {
-		return false
-	}
-
-	name := strings.ToLower(filepath.Base(fileAbs))
-	return strings.HasSuffix(name, ".asp") || strings.HasSuffix(name, ".html")
+// authenticateRequest runs Basic authentication for the protected_paths of axonasp.toml.
+// It returns the request carrying the authenticated user, or false after answering
+// with a 401 challenge through the error page pipeline.
+func authenticateRequest(w http.ResponseWriter, r *http.Request, urlPath string) (*http.Request, bool) {
+	if authenticator == nil {
+		return r, true
+	}
+	user, err := authenticator.Authenticate(r)
+	switch {
+	case errors.Is(err, axonauth.ErrInvalidCredentials):
+		challengeRequest(w, r, 1)
+		return r, false
+	case err != nil:
+		respondInternalHTTPError(w, axonvm.ErrCouldNotReadFile, err, "Failed to verify the request credentials.", urlPath)
+		return r, false
+	}
+
+	rules, protected := authenticator.PathRules(urlPath)
+	if (user == nil && protected) || !axonauth.Authorized(rules, user, r.Method) {
+		challengeRequest(w, r, 2)
+		return r, false
+	}
+	if user == nil {
+		return r, true
+	}
+	axonaccesslog.SetUsername(r, user.Name)
+	return axonauth.WithUser(r, user), true
 }
 
-// renderClassicASPDebugError renders an ASP/VBScript-style debug page while preserving HTTP status.
-func renderClassicASPDebugError(w http.ResponseWriter, statusCode int, stage string, err *asp.ASPError) {
-	if err == nil {
-		http.Error(w, http.StatusText(statusCode), statusCode)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(statusCode)
-
-	source := html.EscapeString(strings.TrimSpace(err.Source))
-	if source == "" {
-		source = "VBScript runtime"
-	}
-	description := html.EscapeString(strings.TrimSpace(err.Description))
-	if description == "" {
-		description = "Unknown runtime error"
-	}
-	fileName := html.EscapeString(strings.TrimSpace(err.File))
-	if fileName == "" {
-		fileName = "unknown"
-	}
-
-	fmt.Fprintf(w, "<!doctype html><html><head><meta charset=\"utf-8\"><meta name=\"viewport\" content=\"width=device-width,initial-scale=1\"><title>500 - Internal Server Error - AxonASP Server</title><style>body{margin:0;background:#f4f4f4;font-family:\"IBM Plex Sans\",Helvetica,sans-serif;color:#161616;font-size:13px}#h{height:60px;padding:0 15px;font-size:24px;display:flex;align-items:center;font-weight:600;border-bottom:1px solid #d9d9d9;background:#f4f4f4}.shell{padding:40px 20px;display:flex;justify-content:center}.card{background:#fff;border:1px solid #d9d9d9;max-width:760px;width:100%%;padding:28px;box-shadow:0 10px 20px rgba(22,22,22,.06)}h1{margin:0 0 16px;font-size:24px;border-bottom:1px solid #d9d9d9;padding-bottom:8px}p{margin:0 0 12px}table{width:100%%;border-collapse:collapse;border:1px solid #d9d9d9;margin:14px 0}td{border:1px solid #d9d9d9;padding:7px 10px;font-size:12px}td.k{width:120px;background:#f8f8f8;font-weight:600}.ft{margin-top:24px;border-top:1px solid #d9d9d9;padding-top:10px;font-size:11px;color:#525252}</style></head><body><div id=\"h\">❖ AxonASP Server</div><div class=\"shell\"><div class=\"card\"><h1>Application error</h1><p><b>%s error '%08X'</b></p><p>%s</p><table><tr><td class=\"k\">File</td><td>%s</td></tr><tr><td class=\"k\">Line</td><td>%d</td></tr><tr><td class=\"k\">Column</td><td>%d</td></tr><tr><td class=\"k\">Stage</td><td>%s</td></tr></table><div class=\"ft\">G3Pix ❖ AxonASP</div></div></div></body></html>", source, uint32(int32(err.Number)), description, fileName, err.Line, err.Column, html.EscapeString(stage))
-
+// challengeRequest answers with 401.subStatus and a Basic challenge.
+func challengeRequest(w http.ResponseWriter, r *http.Request, subStatus int) {
+	axonauth.Challenge(w, authenticator.Realm())
+	axonaccesslog.SetSubStatus(r, subStatus)
+	serveErrorPage(w, r, http.StatusUnauthorized)
 }
 
 // serveErrorPage serves configured error pages using .asp or .html handlers for a given HTTP status code.
 func serveErrorPage(w http.ResponseWriter, r *http.Request, statusCode int) {
-	aspPagePath := filepath.Join(DefaultErrorPagesDir, fmt.Sprintf("%d.asp", statusCode))
-	if pageInfo, err := os.Stat(aspPagePath); err == nil && !pageInfo.IsDir() {
-		executeASPWithStatus(w, r, aspPagePath, statusCode)
-		return
-	}
-
-	htmlPagePath := filepath.Join(DefaultErrorPagesDir, fmt.Sprintf("%d.html", statusCode))
-	if pageInfo, err := os.Stat(htmlPagePath); err == nil && !pageInfo.IsDir() {
-		http.ServeFile(newSingleHeaderResponseWriter(w, statusCode), r, htmlPagePath)
-		return
-	}
-
-	http.Error(w, http.StatusText(statusCode), statusCode)
+	newASPHandler().ServeError(w, r, statusCode)
 }
 
 // respondInternalHTTPError logs an internal AxonASP error and writes a compact HTTP response.
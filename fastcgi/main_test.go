@@ -36,6 +36,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
 )
 
 func TestParseFastCGIListenEndpoint(t *testing.T) {
@@ -255,6 +257,36 @@ func newFCGIRequest(t *testing.T, method, urlPath string, cgiEnv map[string]stri
 // TestGetFastCGIParam verifies that getFastCGIParam reads from the CGI environment
 // context populated by fcgi.Serve (via fcgi.ProcessEnv). It also documents that
 // SCRIPT_NAME is filtered out of ProcessEnv (it becomes r.URL.Path instead).
+func TestWriteSlowRequestAppendsEntryWithStack(t *testing.T) {
+	originalPath, originalPrefix := SlowlogPath, LogPrefix
+	SlowlogPath = filepath.Join(t.TempDir(), "slow.log")
+	LogPrefix = "[#42] [site-a] "
+	t.Cleanup(func() { SlowlogPath, LogPrefix = originalPath, originalPrefix })
+
+	r := httptest.NewRequest(http.MethodGet, "/report.asp?id=7", nil)
+	stack := []axonvm.StackFrame{
+		{Procedure: "LoadRows", File: "/site/db.inc", Line: 12, IncludedFrom: []axonvm.SourceLocation{{File: "/site/report.asp", Line: 3}}},
+		{Procedure: "<global>", File: "/site/report.asp", Line: 9},
+	}
+	writeSlowRequest(r, "/site/report.asp", 5*time.Second, stack)
+	writeSlowRequest(r, "/site/report.asp", 6*time.Second, stack)
+
+	data, err := os.ReadFile(SlowlogPath)
+	if err != nil {
+		t.Fatalf("read slow log: %v", err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		"] [#42] [site-a] slow request, 5s elapsed\nrequest_uri = GET /report.asp?id=7\nscript_filename = /site/report.asp\n",
+		"    at LoadRows (/site/db.inc:12)\n        included from /site/report.asp:3\n    at <global> (/site/report.asp:9)\n\n",
+		"slow request, 6s elapsed",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in slow log:\n%s", want, text)
+		}
+	}
+}
+
 func TestGetFastCGIParam(t *testing.T) {
 	tests := []struct {
 		name        string
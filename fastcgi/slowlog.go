@@ -0,0 +1,70 @@
+//go:build !wasm
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm"
+)
+
+// slowlogMutex keeps the entries of concurrent slow requests apart.
+var slowlogMutex sync.Mutex
+
+// writeSlowRequest appends one slow request entry to SlowlogPath, or to standard
+// error when no slow log file is configured.
+func writeSlowRequest(r *http.Request, filePath string, elapsed time.Duration, stack []axonvm.StackFrame) {
+	entry := formatSlowRequest(time.Now(), r, filePath, elapsed, stack)
+
+	slowlogMutex.Lock()
+	defer slowlogMutex.Unlock()
+	var out io.Writer = os.Stderr
+	if SlowlogPath != "" {
+		file, err := os.OpenFile(SlowlogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%sWarning: Could not open slow log %s: %v\n", LogPrefix, SlowlogPath, err)
+		} else {
+			defer file.Close()
+			out = file
+		}
+	}
+	_, _ = io.WriteString(out, entry)
+}
+
+// formatSlowRequest formats one slow log entry: a header with the worker, the
+// request and the elapsed time, then the ASP call stack, the innermost first.
+func formatSlowRequest(now time.Time, r *http.Request, filePath string, elapsed time.Duration, stack []axonvm.StackFrame) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %sslow request, %s elapsed\n", now.Format("02-Jan-2006 15:04:05"), LogPrefix, elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "request_uri = %s %s\n", r.Method, r.RequestURI)
+	fmt.Fprintf(&b, "script_filename = %s\n", filePath)
+	b.WriteString(axonvm.FormatStackTrace(stack))
+	b.WriteString("\n")
+	return b.String()
+}
@@ -32,6 +32,7 @@ import (
 	"strings"
 	"testing"
 
+	"g3pix.com.br/axonasp/axonproxy"
 	"g3pix.com.br/axonasp/axonvm"
 	"g3pix.com.br/axonasp/axonvm/asp"
 )
@@ -71,8 +72,7 @@ func fastCGISessionCookieFromResponse(t *testing.T, recorder *httptest.ResponseR
 
 // TestNewFastCGIHostSetsSessionCookie verifies NewFastCGIHost emits ASPSESSIONID for new sessions.
 func TestNewFastCGIHostSetsSessionCookie(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	req := httptest.NewRequest(http.MethodGet, "http://example.local/default.asp", nil)
 	recorder := httptest.NewRecorder()
@@ -90,8 +90,7 @@ func TestNewFastCGIHostSetsSessionCookie(t *testing.T) {
 
 // TestNewFastCGIHostReusesExistingSession verifies cookie-bound sessions are loaded and reused.
 func TestNewFastCGIHostReusesExistingSession(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	existing, err := asp.CreateSession()
 	if err != nil {
@@ -118,8 +117,7 @@ func TestNewFastCGIHostReusesExistingSession(t *testing.T) {
 
 // TestFastCGIHostPersistSessionKeepsSingleSessionCookie verifies session persistence rewrites ASPSESSIONID instead of duplicating it.
 func TestFastCGIHostPersistSessionKeepsSingleSessionCookie(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	req := httptest.NewRequest(http.MethodGet, "http://example.local/default.asp", nil)
 	recorder := httptest.NewRecorder()
@@ -331,8 +329,7 @@ func TestNewFastCGIHostFallsBackToRootDirWithoutDocumentRoot(t *testing.T) {
 // This reproduces the "first request always blank" regression where
 // Response.ended leaked from Session_OnStart into page execution.
 func TestNewFastCGIHostSessionOnStartEndedDoesNotBlankPage(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	tmpDir := t.TempDir()
 
@@ -397,8 +394,7 @@ End Sub
 // when Session_OnStart calls Response.Redirect, the subsequent page still
 // produces output instead of silently discarding everything.
 func TestNewFastCGIHostSessionOnStartRedirectDoesNotBlankPage(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	tmpDir := t.TempDir()
 
@@ -452,8 +448,7 @@ End Sub
 // the second request (with existing ASPSESSIONID cookie) does not execute
 // Session_OnStart, preserving page output without the ended-reset path.
 func TestNewFastCGIHostSubsequentRequestDoesNotRunSessionOnStart(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	tmpDir := t.TempDir()
 
@@ -510,3 +505,46 @@ End Sub
 		t.Fatalf("expected body SECOND_REQUEST_OK, got %q", body)
 	}
 }
+
+// TestNewFastCGIHostAppliesTrustedProxyHeaders verifies requests rewritten by a trusted
+// proxy report the client address, scheme, host and port in the server variables.
+func TestNewFastCGIHostAppliesTrustedProxyHeaders(t *testing.T) {
+	useTestSessionDir(t)
+	proxy, err := axonproxy.New(axonproxy.Config{HeadersEnabled: true, TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatalf("create proxy: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://backend:8801/default.asp", nil)
+	req.RemoteAddr = "10.1.2.3:40000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "www.example.com")
+
+	var host *FastCGIHost
+	proxy.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = NewFastCGIHost(w, r)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := map[string]string{
+		"REMOTE_ADDR": "203.0.113.9",
+		"REMOTE_HOST": "203.0.113.9",
+		"HTTPS":       "on",
+		"SERVER_NAME": "www.example.com",
+		"SERVER_PORT": "443",
+	}
+	for name, want := range expected {
+		if got := host.Request().ServerVars.Get(name); got != want {
+			t.Fatalf("expected %s %q, got %q", name, want, got)
+		}
+	}
+}
+
+// useTestSessionDir stores the sessions of the test in a temporary directory.
+func useTestSessionDir(t *testing.T) {
+	t.Helper()
+	asp.SetSessionStorageDir(t.TempDir())
+	t.Cleanup(func() {
+		asp.WaitSessionWrites()
+		asp.SetSessionStorageDir("")
+	})
+}
@@ -26,9 +26,24 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Process manager modes, named after the php-fpm pm setting.
+const (
+	// pmStatic keeps max_children workers running.
+	pmStatic = "static"
+	// pmDynamic keeps between min_spare_servers and max_spare_servers idle workers.
+	pmDynamic = "dynamic"
+	// pmOndemand starts workers when connections arrive and stops them when idle.
+	pmOndemand = "ondemand"
+)
+
+// defaultProcessIdleTimeout is how long an ondemand worker may stay idle.
+const defaultProcessIdleTimeout = 10 * time.Second
+
 // PoolConfig represents the per-pool configuration parsed from a .conf TOML file.
 type PoolConfig struct {
 	SiteName      string `toml:"site_name"`
@@ -41,6 +56,107 @@ type PoolConfig struct {
 	MemoryLimitMB int    `toml:"memory_limit_mb"`
 	MaxRestarts   int    `toml:"max_restarts"`
 	TmpDir        string `toml:"tmp_dir"`
+
+	PM                 string `toml:"pm"`
+	MaxChildren        int    `toml:"max_children"`
+	StartServers       int    `toml:"start_servers"`
+	MinSpareServers    int    `toml:"min_spare_servers"`
+	MaxSpareServers    int    `toml:"max_spare_servers"`
+	ProcessIdleTimeout int    `toml:"process_idle_timeout"`
+	MaxRequests        int    `toml:"max_requests"`
+
+	// RequestSlowlogTimeout and RequestTerminateTimeout are in seconds. Zero
+	// disables them.
+	RequestSlowlogTimeout   int    `toml:"request_slowlog_timeout"`
+	RequestTerminateTimeout int    `toml:"request_terminate_timeout"`
+	Slowlog                 string `toml:"slowlog"`
+}
+
+// normalizeProcessManager applies the process manager defaults and validates
+// the worker counts. Pools without pm settings run one static worker, as before.
+func (c *PoolConfig) normalizeProcessManager() error {
+	c.PM = strings.ToLower(strings.TrimSpace(c.PM))
+	if c.PM == "" {
+		c.PM = pmStatic
+	}
+	if c.PM != pmStatic && c.PM != pmDynamic && c.PM != pmOndemand {
+		return fmt.Errorf("pm must be static, dynamic or ondemand, got %q", c.PM)
+	}
+	if c.MaxChildren <= 0 {
+		c.MaxChildren = 1
+	}
+	c.MaxRequests = max(c.MaxRequests, 0)
+
+	switch c.PM {
+	case pmDynamic:
+		if c.MinSpareServers <= 0 {
+			c.MinSpareServers = 1
+		}
+		if c.MaxSpareServers <= 0 {
+			c.MaxSpareServers = c.MaxChildren
+		}
+		if c.MinSpareServers > c.MaxSpareServers {
+			return fmt.Errorf("min_spare_servers (%d) cannot be greater than max_spare_servers (%d)", c.MinSpareServers, c.MaxSpareServers)
+		}
+		if c.MaxSpareServers > c.MaxChildren {
+			return fmt.Errorf("max_spare_servers (%d) cannot be greater than max_children (%d)", c.MaxSpareServers, c.MaxChildren)
+		}
+		if c.StartServers <= 0 {
+			c.StartServers = c.MinSpareServers + (c.MaxSpareServers-c.MinSpareServers)/2
+		}
+		if c.StartServers < c.MinSpareServers || c.StartServers > c.MaxSpareServers {
+			return fmt.Errorf("start_servers (%d) must be between min_spare_servers (%d) and max_spare_servers (%d)", c.StartServers, c.MinSpareServers, c.MaxSpareServers)
+		}
+	case pmOndemand:
+		if c.ProcessIdleTimeout <= 0 {
+			c.ProcessIdleTimeout = int(defaultProcessIdleTimeout / time.Second)
+		}
+	}
+	return nil
+}
+
+// initialWorkers returns how many workers the pool starts with.
+func (c PoolConfig) initialWorkers() int {
+	switch c.PM {
+	case pmDynamic:
+		return c.StartServers
+	case pmOndemand:
+		return 0
+	default:
+		return c.MaxChildren
+	}
+}
+
+// workersToStart returns how many workers to add to a pool with live workers,
+// idle of them without requests in progress. pending reports a connection
+// waiting on the socket, which only ondemand pools watch.
+func (c PoolConfig) workersToStart(live, idle int, pending bool) int {
+	room := max(c.MaxChildren-live, 0)
+	switch c.PM {
+	case pmDynamic:
+		return min(max(c.MinSpareServers-idle, 0), room)
+	case pmOndemand:
+		if pending && idle == 0 {
+			return min(1, room)
+		}
+		return 0
+	default:
+		return room
+	}
+}
+
+// idleWorkersToStop returns how many idle workers a dynamic pool stops.
+func (c PoolConfig) idleWorkersToStop(idle int) int {
+	if c.PM != pmDynamic {
+		return 0
+	}
+	return max(idle-c.MaxSpareServers, 0)
+}
+
+// poolMemoryLimitMB returns the cgroup memory limit of the whole pool, which
+// allows memory_limit_mb for each worker.
+func (c PoolConfig) poolMemoryLimitMB() int {
+	return c.MemoryLimitMB * c.MaxChildren
 }
 
 // normalizePoolSocketEndpoint normalizes pool socket configuration and returns
@@ -67,6 +183,19 @@ func normalizePoolSocketEndpoint(raw string) (listenEndpoint string, socketPath
 	return value, "", false, nil
 }
 
+// poolTCPAddress returns the address a worker given the TCP listen endpoint
+// would bind: a bare port or ":port" listens on the loopback interface.
+func poolTCPAddress(endpoint string) (network string, address string) {
+	value := strings.TrimSpace(endpoint)
+	if port, err := strconv.Atoi(value); err == nil {
+		return "tcp", "127.0.0.1:" + strconv.Itoa(port)
+	}
+	if strings.HasPrefix(value, ":") {
+		return "tcp", "127.0.0.1" + value
+	}
+	return "tcp", value
+}
+
 // buildWorkerArgs returns FastCGI worker startup args from pool configuration.
 // It explicitly passes --server.web_root so the FastCGI worker uses the correct
 // web root directory instead of falling back to the default ./www relative path.
@@ -89,5 +218,14 @@ func buildWorkerArgs(conf PoolConfig, listenEndpoint string) []string {
 	if appPath := strings.TrimSpace(conf.AppPath); appPath != "" {
 		args = append(args, "--server.web_root", appPath)
 	}
+	if conf.RequestSlowlogTimeout > 0 {
+		args = append(args, "--fastcgi.request_slowlog_timeout", strconv.Itoa(conf.RequestSlowlogTimeout))
+	}
+	if conf.RequestTerminateTimeout > 0 {
+		args = append(args, "--fastcgi.request_terminate_timeout", strconv.Itoa(conf.RequestTerminateTimeout))
+	}
+	if slowlog := strings.TrimSpace(conf.Slowlog); slowlog != "" {
+		args = append(args, "--fastcgi.slowlog", slowlog)
+	}
 	return args
 }
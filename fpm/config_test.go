@@ -245,6 +245,29 @@ func TestBuildWorkerArgsIncludesServerWebRoot(t *testing.T) {
 				"--server.web_root", "/var/www/trimmed-site",
 			},
 		},
+		{
+			name: "slow log and terminate timeout are passed when set",
+			conf: PoolConfig{
+				SiteName:                "slow",
+				ConfigFile:              "/opt/axonasp/config/slow.toml",
+				TmpDir:                  "/opt/axonasp/temp",
+				AppPath:                 "/var/www/slow",
+				RequestSlowlogTimeout:   5,
+				RequestTerminateTimeout: 30,
+				Slowlog:                 "/var/log/axonasp/slow.log",
+			},
+			listen: "9200",
+			expectedArgs: []string{
+				"--fastcgi.server_port", "9200",
+				"--config.config_file", "/opt/axonasp/config/slow.toml",
+				"--global.temp_dir", "/opt/axonasp/temp",
+				"--pool.name", "slow",
+				"--server.web_root", "/var/www/slow",
+				"--fastcgi.request_slowlog_timeout", "5",
+				"--fastcgi.request_terminate_timeout", "30",
+				"--fastcgi.slowlog", "/var/log/axonasp/slow.log",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -310,3 +333,147 @@ func TestBuildWorkerArgsOrdering(t *testing.T) {
 		t.Fatalf("missing optional flags: %v", optional[optionalIdx:])
 	}
 }
+
+func TestPoolConfigParsesProcessManagerFields(t *testing.T) {
+	raw := []byte(`
+pm = "dynamic"
+max_children = 8
+start_servers = 3
+min_spare_servers = 2
+max_spare_servers = 4
+process_idle_timeout = 30
+max_requests = 500
+`)
+
+	var conf PoolConfig
+	if err := toml.Unmarshal(raw, &conf); err != nil {
+		t.Fatalf("failed to parse pool TOML: %v", err)
+	}
+	want := PoolConfig{PM: "dynamic", MaxChildren: 8, StartServers: 3, MinSpareServers: 2, MaxSpareServers: 4, ProcessIdleTimeout: 30, MaxRequests: 500}
+	if !reflect.DeepEqual(conf, want) {
+		t.Fatalf("process manager fields mismatch:\n got: %+v\nwant: %+v", conf, want)
+	}
+}
+
+func TestNormalizeProcessManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   PoolConfig
+		want    PoolConfig
+		wantErr bool
+	}{
+		{
+			name:  "pool without pm settings runs one static worker",
+			input: PoolConfig{},
+			want:  PoolConfig{PM: pmStatic, MaxChildren: 1},
+		},
+		{
+			name:  "dynamic defaults start between the spare limits",
+			input: PoolConfig{PM: " Dynamic ", MaxChildren: 10, MinSpareServers: 2},
+			want:  PoolConfig{PM: pmDynamic, MaxChildren: 10, MinSpareServers: 2, MaxSpareServers: 10, StartServers: 6},
+		},
+		{
+			name:  "ondemand default idle timeout",
+			input: PoolConfig{PM: "ondemand", MaxChildren: 4, MaxRequests: -1},
+			want:  PoolConfig{PM: pmOndemand, MaxChildren: 4, ProcessIdleTimeout: 10},
+		},
+		{
+			name:    "unknown mode",
+			input:   PoolConfig{PM: "adaptive"},
+			wantErr: true,
+		},
+		{
+			name:    "min spare above max spare",
+			input:   PoolConfig{PM: "dynamic", MaxChildren: 10, MinSpareServers: 5, MaxSpareServers: 3},
+			wantErr: true,
+		},
+		{
+			name:    "max spare above max children",
+			input:   PoolConfig{PM: "dynamic", MaxChildren: 2, MaxSpareServers: 3},
+			wantErr: true,
+		},
+		{
+			name:    "start servers outside the spare limits",
+			input:   PoolConfig{PM: "dynamic", MaxChildren: 10, MinSpareServers: 2, MaxSpareServers: 4, StartServers: 8},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := tc.input
+			err := conf.normalizeProcessManager()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", conf)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(conf, tc.want) {
+				t.Fatalf("normalized config mismatch:\n got: %+v\nwant: %+v", conf, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessManagerScaling(t *testing.T) {
+	static := PoolConfig{PM: pmStatic, MaxChildren: 4}
+	dynamic := PoolConfig{PM: pmDynamic, MaxChildren: 6, MinSpareServers: 2, MaxSpareServers: 3, StartServers: 2}
+	ondemand := PoolConfig{PM: pmOndemand, MaxChildren: 2, ProcessIdleTimeout: 10}
+
+	tests := []struct {
+		name    string
+		conf    PoolConfig
+		live    int
+		idle    int
+		pending bool
+		want    int
+	}{
+		{name: "static refills to max_children", conf: static, live: 1, idle: 1, want: 3},
+		{name: "static full", conf: static, live: 4, idle: 0, want: 0},
+		{name: "dynamic below min spare", conf: dynamic, live: 3, idle: 0, want: 2},
+		{name: "dynamic limited by max_children", conf: dynamic, live: 5, idle: 0, want: 1},
+		{name: "dynamic enough spare", conf: dynamic, live: 3, idle: 2, want: 0},
+		{name: "ondemand starts on pending connection", conf: ondemand, live: 0, idle: 0, pending: true, want: 1},
+		{name: "ondemand idle worker takes the connection", conf: ondemand, live: 1, idle: 1, pending: true, want: 0},
+		{name: "ondemand limited by max_children", conf: ondemand, live: 2, idle: 0, pending: true, want: 0},
+		{name: "ondemand without connections", conf: ondemand, live: 0, idle: 0, want: 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.conf.workersToStart(tc.live, tc.idle, tc.pending); got != tc.want {
+				t.Fatalf("workersToStart(%d, %d, %v) = %d, want %d", tc.live, tc.idle, tc.pending, got, tc.want)
+			}
+		})
+	}
+
+	if got := dynamic.idleWorkersToStop(5); got != 2 {
+		t.Fatalf("expected dynamic pool to stop 2 idle workers, got %d", got)
+	}
+	if got := static.idleWorkersToStop(4); got != 0 {
+		t.Fatalf("expected static pool to keep its idle workers, got %d", got)
+	}
+	if got := static.initialWorkers(); got != 4 {
+		t.Fatalf("expected static pool to start max_children workers, got %d", got)
+	}
+	if got := ondemand.initialWorkers(); got != 0 {
+		t.Fatalf("expected ondemand pool to start without workers, got %d", got)
+	}
+}
+
+func TestPoolTCPAddressMatchesWorkerListener(t *testing.T) {
+	tests := map[string]string{
+		"9000":           "127.0.0.1:9000",
+		":9100":          "127.0.0.1:9100",
+		"0.0.0.0:9200":   "0.0.0.0:9200",
+		"10.0.0.5:9300 ": "10.0.0.5:9300",
+	}
+	for endpoint, want := range tests {
+		if network, got := poolTCPAddress(endpoint); network != "tcp" || got != want {
+			t.Fatalf("poolTCPAddress(%q) = %s %s, want tcp %s", endpoint, network, got, want)
+		}
+	}
+}
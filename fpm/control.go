@@ -0,0 +1,238 @@
+//go:build !windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ControlSocket is the unix socket of the FPM control API. It speaks HTTP, so
+// scripts can use it with curl --unix-socket:
+//
+//	GET  /status                  status of every pool
+//	GET  /pools/{name}/status     status of one pool
+//	POST /reload                  reload changed pool configurations, like SIGUSR2
+//	POST /pools/{name}/restart    replace the workers of a pool without closing its socket
+//	POST /pools/{name}/stop       stop a pool until it is started again
+//	POST /pools/{name}/start      start a stopped pool
+//
+// Status requests answer plain text, or JSON with ?json or ?format=json. A pool
+// name is its configuration file name without the .conf extension.
+const ControlSocket = "/var/run/axonasp/fpm.sock"
+
+// controlSocketPath is the control socket opened by main.
+var controlSocketPath = ControlSocket
+
+// startControlServer serves the control API on a unix socket only root and its
+// group can use.
+func startControlServer(path string) (*http.Server, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("path exists and is not a unix socket: %s", path)
+		}
+		_ = os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0660); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	server := &http.Server{Handler: newControlHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Control socket stopped: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+// newControlHandler returns the handler of the control API.
+func newControlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, r, collectPoolStatuses())
+	})
+	mux.HandleFunc("GET /pools/{name}/status", func(w http.ResponseWriter, r *http.Request) {
+		configPath, ok := knownPoolConfigPath(r.PathValue("name"))
+		if !ok {
+			http.Error(w, "Unknown pool", http.StatusNotFound)
+			return
+		}
+		writeStatus(w, r, []poolStatus{poolStatusFor(configPath, time.Now())})
+	})
+	mux.HandleFunc("POST /reload", func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Reload requested through the control socket. Reloading changed pool configurations...")
+		managerMutex.Lock()
+		scanAndLoadConfigs()
+		managerMutex.Unlock()
+		fmt.Fprintln(w, "OK")
+	})
+	mux.HandleFunc("POST /pools/{name}/{action}", handlePoolAction)
+	return mux
+}
+
+// handlePoolAction restarts, stops or starts one pool.
+func handlePoolAction(w http.ResponseWriter, r *http.Request) {
+	configPath, ok := knownPoolConfigPath(r.PathValue("name"))
+	if !ok {
+		http.Error(w, "Unknown pool", http.StatusNotFound)
+		return
+	}
+
+	managerMutex.Lock()
+	defer managerMutex.Unlock()
+
+	switch r.PathValue("action") {
+	case "restart":
+		pool := runningPool(configPath)
+		if pool == nil {
+			http.Error(w, "Pool is not running", http.StatusConflict)
+			return
+		}
+		pool.requestRestart()
+	case "stop":
+		if !stopPool(configPath) {
+			http.Error(w, "Pool is not running", http.StatusConflict)
+			return
+		}
+	case "start":
+		started, err := startPool(configPath)
+		if !started {
+			http.Error(w, "Pool is not stopped", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Unknown action", http.StatusNotFound)
+		return
+	}
+	fmt.Fprintln(w, "OK")
+}
+
+// writeStatus answers pools as JSON or as plain text.
+func writeStatus(w http.ResponseWriter, r *http.Request, pools []poolStatus) {
+	query := r.URL.Query()
+	if query.Has("json") || strings.EqualFold(query.Get("format"), "json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(pools)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writeStatusText(w, pools)
+}
+
+// poolName returns the name of the pool of configPath.
+func poolName(configPath string) string {
+	return strings.TrimSuffix(filepath.Base(configPath), ".conf")
+}
+
+// knownPoolConfigPath returns the configuration file of a running or stopped pool.
+func knownPoolConfigPath(name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	configPath := filepath.Join(configDir, name+".conf")
+	poolsMutex.Lock()
+	defer poolsMutex.Unlock()
+	_, active := activePools[configPath]
+	_, stopped := stoppedPools[configPath]
+	return configPath, active || stopped
+}
+
+// collectPoolStatuses returns the status of every running and stopped pool, by name.
+func collectPoolStatuses() []poolStatus {
+	poolsMutex.Lock()
+	configPaths := make([]string, 0, len(activePools)+len(stoppedPools))
+	for configPath := range activePools {
+		configPaths = append(configPaths, configPath)
+	}
+	for configPath := range stoppedPools {
+		configPaths = append(configPaths, configPath)
+	}
+	poolsMutex.Unlock()
+
+	slices.Sort(configPaths)
+	now := time.Now()
+	pools := make([]poolStatus, 0, len(configPaths))
+	for _, configPath := range configPaths {
+		pools = append(pools, poolStatusFor(configPath, now))
+	}
+	return pools
+}
+
+// poolStatusFor returns the status of the pool of configPath.
+func poolStatusFor(configPath string, now time.Time) poolStatus {
+	var status poolStatus
+	if pool := runningPool(configPath); pool != nil {
+		status = pool.status(now)
+	} else {
+		poolsMutex.Lock()
+		handle, active := activePools[configPath]
+		poolsMutex.Unlock()
+		switch {
+		case !active:
+			status.State = poolStateStopped
+		case isClosed(handle.done):
+			status.State = poolStateFailed
+		default:
+			status.State = poolStateStarting
+		}
+		if conf, err := loadPoolConfigFile(configPath); err == nil {
+			status.SiteName = conf.SiteName
+		}
+	}
+	status.Name = poolName(configPath)
+	status.ConfigFile = configPath
+	return status
+}
+
+// isClosed reports whether done is closed.
+func isClosed(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
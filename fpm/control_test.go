@@ -0,0 +1,179 @@
+//go:build !windows
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupControlTest points the manager at a temporary pool directory with one
+// pool and replaces the supervisor with a stub that counts starts.
+func setupControlTest(t *testing.T) (configPath string, starts func() int) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	configPath = filepath.Join(tmpDir, "site-a.conf")
+	if err := os.WriteFile(configPath, []byte("site_name='a.example'\nsocket='9001'\napp_path='/'\n"), 0644); err != nil {
+		t.Fatalf("failed to write pool: %v", err)
+	}
+
+	originalConfigDir := configDir
+	originalActivePools := activePools
+	originalStoppedPools := stoppedPools
+	originalLauncher := launchPoolSupervisor
+	configDir = tmpDir
+	activePools = make(map[string]poolHandle)
+	stoppedPools = make(map[string]struct{})
+
+	var mu sync.Mutex
+	count := 0
+	launchPoolSupervisor = func(ctx context.Context, configPath string, done chan struct{}) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+		go func() {
+			<-ctx.Done()
+			close(done)
+		}()
+	}
+	t.Cleanup(func() {
+		shutdownAllPools()
+		configDir = originalConfigDir
+		activePools = originalActivePools
+		stoppedPools = originalStoppedPools
+		launchPoolSupervisor = originalLauncher
+	})
+
+	scanAndLoadConfigs()
+	return configPath, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+func controlRequest(t *testing.T, method, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	newControlHandler().ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestControlStopAndStartPool(t *testing.T) {
+	_, starts := setupControlTest(t)
+
+	rec := controlRequest(t, http.MethodGet, "/pools/site-a/status")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "site name:            a.example") {
+		t.Fatalf("unexpected status page %d %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := controlRequest(t, http.MethodPost, "/pools/site-a/stop"); rec.Code != http.StatusOK {
+		t.Fatalf("stop failed: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := controlRequest(t, http.MethodPost, "/pools/site-a/stop"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected stopping a stopped pool to conflict, got %d", rec.Code)
+	}
+
+	rec = controlRequest(t, http.MethodGet, "/status?json")
+	var pools []poolStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &pools); err != nil {
+		t.Fatalf("invalid JSON status %q: %v", rec.Body.String(), err)
+	}
+	if len(pools) != 1 || pools[0].Name != "site-a" || pools[0].State != poolStateStopped {
+		t.Fatalf("expected the stopped pool in the status, got %+v", pools)
+	}
+
+	if rec := controlRequest(t, http.MethodPost, "/reload"); rec.Code != http.StatusOK {
+		t.Fatalf("reload failed: %d", rec.Code)
+	}
+	if got := starts(); got != 1 {
+		t.Fatalf("expected reload to leave the stopped pool alone, got %d starts", got)
+	}
+
+	if rec := controlRequest(t, http.MethodPost, "/pools/site-a/start"); rec.Code != http.StatusOK {
+		t.Fatalf("start failed: %d %q", rec.Code, rec.Body.String())
+	}
+	if got := starts(); got != 2 {
+		t.Fatalf("expected start to launch the pool again, got %d starts", got)
+	}
+	if rec := controlRequest(t, http.MethodPost, "/pools/site-a/start"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected starting a running pool to conflict, got %d", rec.Code)
+	}
+}
+
+func TestControlRejectsUnknownPoolsAndActions(t *testing.T) {
+	setupControlTest(t)
+
+	for _, target := range []string{"/pools/missing/status", "/pools/..%2Fsite-a/status"} {
+		if rec := controlRequest(t, http.MethodGet, target); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s, got %d", target, rec.Code)
+		}
+	}
+	if rec := controlRequest(t, http.MethodPost, "/pools/site-a/explode"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown action, got %d", rec.Code)
+	}
+	if rec := controlRequest(t, http.MethodPost, "/pools/site-a/restart"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected restart of a pool without workers to conflict, got %d", rec.Code)
+	}
+}
+
+func TestPoolStatusReportsWorkersAndCgroupMemory(t *testing.T) {
+	originalCgroupBase := cgroupBase
+	cgroupBase = t.TempDir()
+	t.Cleanup(func() { cgroupBase = originalCgroupBase })
+	poolCgroup := filepath.Join(cgroupBase, "a.example")
+	if err := os.MkdirAll(poolCgroup, 0755); err != nil {
+		t.Fatalf("mkdir cgroup: %v", err)
+	}
+	_ = os.WriteFile(filepath.Join(poolCgroup, "memory.current"), []byte("1048576\n"), 0644)
+	_ = os.WriteFile(filepath.Join(poolCgroup, "memory.max"), []byte("max\n"), 0644)
+
+	now := time.Now()
+	pool := &workerPool{
+		conf:         PoolConfig{SiteName: "a.example", PM: pmDynamic, MaxChildren: 4},
+		endpoint:     "9001",
+		started:      now.Add(-time.Minute),
+		workers:      make(map[int]*poolWorker),
+		crashes:      2,
+		exitedServed: 10,
+	}
+	busy := &poolWorker{pid: 200, started: now.Add(-30 * time.Second)}
+	busy.active.Store(1)
+	busy.served.Store(5)
+	idle := &poolWorker{pid: 100, started: now.Add(-10 * time.Second)}
+	idle.served.Store(3)
+	stopping := &poolWorker{pid: 300, started: now}
+	stopping.stopping.Store(true)
+	pool.workers[200], pool.workers[100], pool.workers[300] = busy, idle, stopping
+
+	status := pool.status(now)
+	if status.ActiveWorkers != 1 || status.IdleWorkers != 1 || status.TotalWorkers != 3 {
+		t.Fatalf("unexpected worker counts %+v", status)
+	}
+	if status.RequestsTotal != 18 || status.Restarts != 2 || status.StartSince != 60 {
+		t.Fatalf("unexpected pool counters %+v", status)
+	}
+	if status.MemoryUsageBytes != 1048576 || status.MemoryLimitBytes != 0 {
+		t.Fatalf("unexpected memory usage %d/%d", status.MemoryUsageBytes, status.MemoryLimitBytes)
+	}
+	if status.Workers[0].PID != 100 || status.Workers[1].State != workerStateActive || status.Workers[2].State != workerStateStopping {
+		t.Fatalf("unexpected workers %+v", status.Workers)
+	}
+
+	var buf bytes.Buffer
+	writeStatusText(&buf, []poolStatus{status})
+	for _, want := range []string{"active processes:     1", "memory usage:         1048576", "pid:                  200", "state:                stopping"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("expected %q in status page:\n%s", want, buf.String())
+		}
+	}
+}
@@ -29,20 +29,21 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
-
-	"github.com/pelletier/go-toml/v2"
 )
 
 const (
 	ConfigDir  = "/opt/axonasp/fpm/fpm.d/"
 	WorkerExec = "/opt/axonasp/axonasp-fastcgi"
+
+	// poolShutdownTimeout bounds the wait for a pool to stop its workers.
+	poolShutdownTimeout = workerStopTimeout + 5*time.Second
 )
 
 type poolConfigRevision struct {
@@ -67,9 +68,19 @@ var (
 	activePools = make(map[string]poolHandle)
 	poolsMutex  sync.Mutex
 	configDir   = ConfigDir
+	cgroupBase  = "/sys/fs/cgroup/axonasp"
+
+	// stoppedPools holds the pools stopped through the control socket, which
+	// reloads do not start again.
+	stoppedPools = make(map[string]struct{})
 
+	// managerMutex serializes reloads, shutdown and the pool actions of the
+	// control socket, which arrive from the signal loop and from control requests.
+	managerMutex sync.Mutex
+
+	// launchPoolSupervisor starts the supervisor of a pool without waiting for it.
 	launchPoolSupervisor = func(ctx context.Context, configPath string, done chan struct{}) {
-		superviseWorker(ctx, configPath, done)
+		go supervisePool(ctx, configPath, done)
 	}
 )
 
@@ -82,6 +93,14 @@ func main() {
 	// 1. Initial Load of Configurations
 	scanAndLoadConfigs()
 
+	control, err := startControlServer(controlSocketPath)
+	if err != nil {
+		log.Printf("Warning: Failed to start the control socket %s: %v", controlSocketPath, err)
+	} else {
+		log.Printf("Control socket: %s", controlSocketPath)
+		defer control.Close()
+	}
+
 	// 2. Setup Signal Handling for Graceful Reload (SIGHUP) and shutdown (SIGINT/SIGTERM)
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR2)
@@ -101,6 +120,9 @@ func main() {
 }
 
 func handleManagerSignal(sig os.Signal) bool {
+	managerMutex.Lock()
+	defer managerMutex.Unlock()
+
 	switch sig {
 	case syscall.SIGHUP:
 		log.Println("SIGHUP received. Rescanning configuration directory...")
@@ -143,7 +165,7 @@ func startPoolSupervisor(configPath string, revision poolConfigRevision) {
 	}
 	poolsMutex.Unlock()
 
-	go launchPoolSupervisor(ctx, configPath, done)
+	launchPoolSupervisor(ctx, configPath, done)
 }
 
 func waitForPoolShutdown(configPath string, done <-chan struct{}, timeout time.Duration) {
@@ -184,6 +206,10 @@ func scanAndLoadConfigs() {
 				continue
 			}
 
+			if _, stopped := stoppedPools[configPath]; stopped {
+				continue
+			}
+
 			handle, exists := activePools[configPath]
 			if !exists {
 				newPools = append(newPools, struct {
@@ -203,7 +229,7 @@ func scanAndLoadConfigs() {
 	for _, action := range restarts {
 		log.Printf("❖ Pool configuration updated: %s. Reloading worker pool...", filepath.Base(action.configPath))
 		action.previous.cancel()
-		waitForPoolShutdown(action.configPath, action.previous.done, 15*time.Second)
+		waitForPoolShutdown(action.configPath, action.previous.done, poolShutdownTimeout)
 		startPoolSupervisor(action.configPath, action.revision)
 	}
 
@@ -226,159 +252,52 @@ func shutdownAllPools() {
 		handle.cancel()
 	}
 	for configPath, handle := range handles {
-		waitForPoolShutdown(configPath, handle.done, 15*time.Second)
+		waitForPoolShutdown(configPath, handle.done, poolShutdownTimeout)
 	}
 }
 
-func superviseWorker(ctx context.Context, configPath string, done chan struct{}) {
-	defer close(done)
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		log.Printf("Error reading %s: %v", configPath, err)
-		return
+// stopPool stops the pool of configPath and keeps it stopped across reloads
+// until startPool. It reports false when the pool is not running.
+func stopPool(configPath string) bool {
+	poolsMutex.Lock()
+	handle, exists := activePools[configPath]
+	if exists {
+		delete(activePools, configPath)
+		stoppedPools[configPath] = struct{}{}
 	}
-
-	var conf PoolConfig
-	err = toml.Unmarshal(data, &conf)
-	if err != nil {
-		log.Printf("Error parsing TOML %s: %v", configPath, err)
-		return
+	poolsMutex.Unlock()
+	if !exists {
+		return false
 	}
 
-	if conf.TmpDir == "" {
-		conf.TmpDir = "/opt/axonasp/temp/"
-	}
+	log.Printf("❖ Stopping pool %s...", filepath.Base(configPath))
+	handle.cancel()
+	waitForPoolShutdown(configPath, handle.done, poolShutdownTimeout)
+	return true
+}
 
-	if conf.AppPath == "" {
-		log.Printf("[%s] Error: app_path is required in pool config", conf.SiteName)
-		return
-	}
-	appPathInfo, err := os.Stat(conf.AppPath)
-	if err != nil {
-		log.Printf("[%s] Error validating app_path %q: %v", conf.SiteName, conf.AppPath, err)
-		return
-	}
-	if !appPathInfo.IsDir() {
-		log.Printf("[%s] Error: app_path %q is not a directory", conf.SiteName, conf.AppPath)
-		return
+// startPool starts the pool of configPath again after stopPool. It reports
+// false when the pool was not stopped.
+func startPool(configPath string) (bool, error) {
+	poolsMutex.Lock()
+	_, stopped := stoppedPools[configPath]
+	delete(stoppedPools, configPath)
+	poolsMutex.Unlock()
+	if !stopped {
+		return false, nil
 	}
 
-	listenEndpoint, socketPath, isUnixSocket, err := normalizePoolSocketEndpoint(conf.Socket)
+	revision, err := readPoolConfigRevision(configPath)
 	if err != nil {
-		log.Printf("[%s] Error: invalid socket value %q: %v", conf.SiteName, conf.Socket, err)
-		return
-	}
-
-	// Create Directories
-	if err := os.MkdirAll(conf.TmpDir, 0755); err != nil {
-		log.Printf("[%s] Error creating temp directory: %v", conf.SiteName, err)
-		return
-	}
-	if err := os.Chown(conf.TmpDir, int(conf.UID), int(conf.GID)); err != nil {
-		log.Printf("[%s] Error setting permissions on temp directory: %v", conf.SiteName, err)
-		return
-	}
-
-	if isUnixSocket {
-		socketDir := filepath.Dir(socketPath)
-		if err := os.MkdirAll(socketDir, 0755); err != nil {
-			log.Printf("[%s] Error creating socket directory: %v", conf.SiteName, err)
-			return
-		}
-		if err := os.Chown(socketDir, int(conf.UID), int(conf.GID)); err != nil {
-			log.Printf("[%s] Error setting permissions on socket directory: %v", conf.SiteName, err)
-			return
-		}
-
-		_ = os.Remove(socketPath)
-	}
-
-	restarts := 0
-
-	for {
-		log.Printf("[%s] Starting Worker (Attempt: %d) with %dMB of RAM", conf.SiteName, restarts, conf.MemoryLimitMB)
-
-		args := buildWorkerArgs(conf, listenEndpoint)
-		cmd := exec.Command(WorkerExec, args...)
-
-		cmd.Dir = conf.AppPath
-		log.Printf("[%s] Executing: %s", conf.SiteName, strings.Join(cmd.Args, " "))
-		log.Printf("[%s] Current Directory: %s", conf.SiteName, cmd.Dir)
-
-		cmd.SysProcAttr = &syscall.SysProcAttr{
-			Credential: &syscall.Credential{
-				Uid: conf.UID,
-				Gid: conf.GID,
-			},
-		}
-
-		env := os.Environ()
-		env = append(env, fmt.Sprintf("GOMEMLIMIT=%dMiB", conf.MemoryLimitMB))
-		env = append(env, fmt.Sprintf("GLOBAL_GOLANG_MEMORY_LIMIT_MB=%dMiB", conf.MemoryLimitMB))
-		env = append(env, fmt.Sprintf("GLOBAL_TEMP_DIR=%s", conf.TmpDir))
-		env = append(env, fmt.Sprintf("TMPDIR=%s", conf.TmpDir))
-		cmd.Env = env
-
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Start(); err != nil {
-			log.Printf("[%s] Failed to start worker: %v", conf.SiteName, err)
-		} else {
-			processExited := make(chan struct{})
-			go func(process *os.Process) {
-				select {
-				case <-ctx.Done():
-					if process == nil {
-						return
-					}
-					if signalErr := process.Signal(syscall.SIGTERM); signalErr != nil && !errors.Is(signalErr, os.ErrProcessDone) {
-						log.Printf("[%s] Failed to send SIGTERM to worker: %v", conf.SiteName, signalErr)
-						return
-					}
-
-					select {
-					case <-processExited:
-					case <-time.After(10 * time.Second):
-						if killErr := process.Kill(); killErr != nil && !errors.Is(killErr, os.ErrProcessDone) {
-							log.Printf("[%s] Failed to force-kill worker after SIGTERM timeout: %v", conf.SiteName, killErr)
-						}
-					}
-				case <-processExited:
-				}
-			}(cmd.Process)
-
-			if err := enforceCgroupMemoryLimit(conf.SiteName, cmd.Process.Pid, conf.MemoryLimitMB); err != nil {
-				log.Printf("[%s] Warning: Failed to apply cgroup limit: %v", conf.SiteName, err)
-			}
-			err = cmd.Wait()
-			close(processExited)
-			log.Printf("[%s] Worker terminated. Error/Exit State: %v", conf.SiteName, err)
-		}
-
-		// Check if the worker stopped because we cancelled the context
-		select {
-		case <-ctx.Done():
-			log.Printf("[%s] Pool supervisor shutting down (Context Cancelled).", conf.SiteName)
-			return
-		default:
-		}
-
-		if conf.MaxRestarts != 0 && restarts >= conf.MaxRestarts {
-			log.Printf("[%s] Maximum restart limit reached (%d). Abandoning pool.", conf.SiteName, conf.MaxRestarts)
-			break
-		}
-
-		restarts++
-		// Wait a bit before restarting to avoid rapid restart loops
-		time.Sleep(2 * time.Second)
+		return true, err
 	}
+	log.Printf("❖ Starting pool %s...", filepath.Base(configPath))
+	startPoolSupervisor(configPath, revision)
+	return true, nil
 }
 
 // enforceCgroupMemoryLimit remains exactly the same as previously defined
 func enforceCgroupMemoryLimit(siteName string, pid int, memoryLimitMB int) error {
-	cgroupBase := "/sys/fs/cgroup/axonasp"
 	if err := ensureMemoryControllerDelegated(cgroupBase); err != nil {
 		return err
 	}
@@ -407,6 +326,34 @@ func enforceCgroupMemoryLimit(siteName string, pid int, memoryLimitMB int) error
 	return nil
 }
 
+// readCgroupMemoryUsage returns the memory.current and memory.max values of the
+// pool cgroup created by enforceCgroupMemoryLimit. A limit of 0 means no limit.
+func readCgroupMemoryUsage(siteName string) (current int64, limit int64, err error) {
+	poolCgroup := filepath.Join(cgroupBase, siteName)
+	current, err = readCgroupValue(filepath.Join(poolCgroup, "memory.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, err = readCgroupValue(filepath.Join(poolCgroup, "memory.max"))
+	if err != nil {
+		return current, 0, err
+	}
+	return current, limit, nil
+}
+
+// readCgroupValue reads a numeric cgroup control file. The value "max" reads as 0.
+func readCgroupValue(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
 // ensureMemoryControllerDelegated verifies memory controller availability and
 // tries to enable it for child cgroups if not already active.
 func ensureMemoryControllerDelegated(cgroupBase string) error {
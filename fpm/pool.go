@@ -0,0 +1,634 @@
+//go:build !windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"g3pix.com.br/axonasp/axonfpm"
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	// maintenanceInterval is how often a pool checks its worker counts.
+	maintenanceInterval = time.Second
+	// restartDelay is the wait before a crashed worker is replaced, to avoid rapid restart loops.
+	restartDelay = 2 * time.Second
+	// workerStopTimeout bounds the drain of a worker after SIGTERM before it is killed.
+	workerStopTimeout = 15 * time.Second
+)
+
+// poolWorker is one axonasp-fastcgi process of a pool.
+type poolWorker struct {
+	pid     int
+	process *os.Process
+	started time.Time
+	exited  chan struct{}
+
+	active    atomic.Int64
+	served    atomic.Int64
+	idleSince atomic.Int64
+	stopping  atomic.Bool
+}
+
+// idle reports whether the worker has no request in progress.
+func (w *poolWorker) idle() bool {
+	return w.active.Load() == 0
+}
+
+// workerPool runs the workers of one pool configuration on a socket it owns.
+type workerPool struct {
+	conf     PoolConfig
+	endpoint string
+	listener net.Listener
+	file     *os.File
+	started  time.Time
+	exits    chan *poolWorker
+	restart  chan struct{}
+	closed   chan struct{}
+
+	mu           sync.Mutex
+	workers      map[int]*poolWorker
+	crashes      int
+	restartAfter time.Time
+	// exitedServed counts the requests served by workers that already exited.
+	exitedServed int64
+}
+
+// runningPools holds the pool of each configuration file while its supervisor runs.
+var (
+	runningPools      = make(map[string]*workerPool)
+	runningPoolsMutex sync.Mutex
+)
+
+// runningPool returns the pool of configPath, or nil when it is not running.
+func runningPool(configPath string) *workerPool {
+	runningPoolsMutex.Lock()
+	defer runningPoolsMutex.Unlock()
+	return runningPools[configPath]
+}
+
+// supervisePool runs the workers of the pool described by configPath until ctx
+// is cancelled or the workers crash more than max_restarts times.
+func supervisePool(ctx context.Context, configPath string, done chan struct{}) {
+	defer close(done)
+
+	conf, err := loadPoolConfig(configPath)
+	if err != nil {
+		log.Printf("Error loading %s: %v", configPath, err)
+		return
+	}
+	pool, err := newWorkerPool(conf)
+	if err != nil {
+		log.Printf("[%s] Error: %v", conf.SiteName, err)
+		return
+	}
+	defer pool.close()
+
+	runningPoolsMutex.Lock()
+	runningPools[configPath] = pool
+	runningPoolsMutex.Unlock()
+	defer func() {
+		runningPoolsMutex.Lock()
+		delete(runningPools, configPath)
+		runningPoolsMutex.Unlock()
+	}()
+
+	log.Printf("[%s] Process manager: pm=%s max_children=%d", conf.SiteName, conf.PM, conf.MaxChildren)
+	for range conf.initialWorkers() {
+		if !pool.start() {
+			pool.stopAll()
+			return
+		}
+	}
+
+	var pending <-chan struct{}
+	if conf.PM == pmOndemand {
+		pending = pool.watchPending(ctx)
+	}
+	ticker := time.NewTicker(maintenanceInterval)
+	defer ticker.Stop()
+
+	for {
+		ok := true
+		select {
+		case <-ctx.Done():
+			log.Printf("[%s] Pool supervisor shutting down (Context Cancelled).", conf.SiteName)
+			pool.stopAll()
+			return
+		case w := <-pool.exits:
+			ok = pool.exited(w) && pool.maintain(false)
+		case <-pool.restart:
+			log.Printf("[%s] Restarting workers...", conf.SiteName)
+			pool.stopWorkers()
+			ok = pool.maintain(false)
+		case <-pending:
+			ok = pool.maintain(true)
+		case <-ticker.C:
+			ok = pool.maintain(false)
+		}
+		if !ok {
+			log.Printf("[%s] Maximum restart limit reached (%d). Abandoning pool.", conf.SiteName, conf.MaxRestarts)
+			pool.stopAll()
+			return
+		}
+	}
+}
+
+// loadPoolConfigFile reads a pool configuration file without validating it.
+func loadPoolConfigFile(configPath string) (PoolConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return PoolConfig{}, err
+	}
+
+	var conf PoolConfig
+	if err := toml.Unmarshal(data, &conf); err != nil {
+		return PoolConfig{}, fmt.Errorf("error parsing TOML: %w", err)
+	}
+	return conf, nil
+}
+
+// loadPoolConfig reads and validates a pool configuration file.
+func loadPoolConfig(configPath string) (PoolConfig, error) {
+	conf, err := loadPoolConfigFile(configPath)
+	if err != nil {
+		return PoolConfig{}, err
+	}
+
+	if conf.TmpDir == "" {
+		conf.TmpDir = "/opt/axonasp/temp/"
+	}
+	if conf.AppPath == "" {
+		return PoolConfig{}, errors.New("app_path is required in pool config")
+	}
+	appPathInfo, err := os.Stat(conf.AppPath)
+	if err != nil {
+		return PoolConfig{}, fmt.Errorf("error validating app_path %q: %w", conf.AppPath, err)
+	}
+	if !appPathInfo.IsDir() {
+		return PoolConfig{}, fmt.Errorf("app_path %q is not a directory", conf.AppPath)
+	}
+	if err := conf.normalizeProcessManager(); err != nil {
+		return PoolConfig{}, err
+	}
+	return conf, nil
+}
+
+// prepareSlowlog creates the slow log file of the pool, owned by the pool user,
+// so workers can append to it after they drop privileges.
+func prepareSlowlog(conf PoolConfig) error {
+	slowlog := strings.TrimSpace(conf.Slowlog)
+	if slowlog == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(slowlog), 0755); err != nil {
+		return fmt.Errorf("error creating slow log directory: %w", err)
+	}
+	file, err := os.OpenFile(slowlog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("error creating slow log: %w", err)
+	}
+	_ = file.Close()
+	if err := os.Chown(slowlog, int(conf.UID), int(conf.GID)); err != nil {
+		return fmt.Errorf("error setting permissions on slow log: %w", err)
+	}
+	return nil
+}
+
+// newWorkerPool creates the temp directory and the listening socket of the pool.
+// The socket belongs to the manager, so it survives crashed and recycled workers.
+func newWorkerPool(conf PoolConfig) (*workerPool, error) {
+	endpoint, socketPath, isUnixSocket, err := normalizePoolSocketEndpoint(conf.Socket)
+	if err != nil {
+		return nil, fmt.Errorf("invalid socket value %q: %w", conf.Socket, err)
+	}
+
+	if err := os.MkdirAll(conf.TmpDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating temp directory: %w", err)
+	}
+	if err := os.Chown(conf.TmpDir, int(conf.UID), int(conf.GID)); err != nil {
+		return nil, fmt.Errorf("error setting permissions on temp directory: %w", err)
+	}
+	if err := prepareSlowlog(conf); err != nil {
+		return nil, err
+	}
+
+	var listener net.Listener
+	if isUnixSocket {
+		listener, err = listenUnixSocket(conf, socketPath)
+	} else {
+		network, address := poolTCPAddress(endpoint)
+		listener, err = net.Listen(network, address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %w", endpoint, err)
+	}
+
+	filer, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		_ = listener.Close()
+		return nil, fmt.Errorf("listener on %s cannot be passed to workers", endpoint)
+	}
+	file, err := filer.File()
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("error duplicating listener on %s: %w", endpoint, err)
+	}
+
+	return &workerPool{
+		conf:     conf,
+		endpoint: endpoint,
+		listener: listener,
+		file:     file,
+		started:  time.Now(),
+		exits:    make(chan *poolWorker),
+		restart:  make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+		workers:  make(map[int]*poolWorker),
+	}, nil
+}
+
+// listenUnixSocket creates the unix socket of the pool, owned by the pool user.
+func listenUnixSocket(conf PoolConfig, socketPath string) (net.Listener, error) {
+	socketDir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(socketDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating socket directory: %w", err)
+	}
+	if err := os.Chown(socketDir, int(conf.UID), int(conf.GID)); err != nil {
+		return nil, fmt.Errorf("error setting permissions on socket directory: %w", err)
+	}
+	_ = os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chown(socketPath, int(conf.UID), int(conf.GID)); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("error setting owner of socket: %w", err)
+	}
+	if err := os.Chmod(socketPath, 0660); err != nil {
+		log.Printf("[%s] Warning: Failed to apply permissions to unix socket %s: %v", conf.SiteName, socketPath, err)
+	}
+	return listener, nil
+}
+
+// start starts one worker. It reports false when a failed start exceeds max_restarts.
+func (p *workerPool) start() bool {
+	if err := p.startWorker(); err != nil {
+		log.Printf("[%s] Failed to start worker: %v", p.conf.SiteName, err)
+		return p.recordCrash()
+	}
+	return true
+}
+
+// startWorker starts one axonasp-fastcgi process on the pool socket.
+func (p *workerPool) startWorker() error {
+	conf := p.conf
+	statusReader, statusWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(WorkerExec, buildWorkerArgs(conf, p.endpoint)...)
+	cmd.Dir = conf.AppPath
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid: conf.UID,
+			Gid: conf.GID,
+		},
+	}
+
+	env := os.Environ()
+	env = append(env, fmt.Sprintf("GOMEMLIMIT=%dMiB", conf.MemoryLimitMB))
+	env = append(env, fmt.Sprintf("GLOBAL_GOLANG_MEMORY_LIMIT_MB=%dMiB", conf.MemoryLimitMB))
+	env = append(env, fmt.Sprintf("GLOBAL_TEMP_DIR=%s", conf.TmpDir))
+	env = append(env, fmt.Sprintf("TMPDIR=%s", conf.TmpDir))
+	cmd.Env = env
+	axonfpm.PassFiles(cmd, p.file, statusWriter)
+
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	p.mu.Lock()
+	log.Printf("[%s] Starting Worker (%d running) with %dMB of RAM", conf.SiteName, len(p.workers), conf.MemoryLimitMB)
+	p.mu.Unlock()
+	log.Printf("[%s] Executing: %s", conf.SiteName, strings.Join(cmd.Args, " "))
+
+	err = cmd.Start()
+	_ = statusWriter.Close()
+	if err != nil {
+		_ = statusReader.Close()
+		return err
+	}
+
+	w := &poolWorker{pid: cmd.Process.Pid, process: cmd.Process, started: time.Now(), exited: make(chan struct{})}
+	w.idleSince.Store(w.started.UnixNano())
+	p.mu.Lock()
+	p.workers[w.pid] = w
+	p.mu.Unlock()
+
+	if err := enforceCgroupMemoryLimit(conf.SiteName, w.pid, conf.poolMemoryLimitMB()); err != nil {
+		log.Printf("[%s] Warning: Failed to apply cgroup limit: %v", conf.SiteName, err)
+	}
+
+	go w.readStatus(statusReader)
+	go func() {
+		err := cmd.Wait()
+		close(w.exited)
+		log.Printf("[%s] Worker %d terminated. Error/Exit State: %v", conf.SiteName, w.pid, err)
+		select {
+		case p.exits <- w:
+		case <-p.closed:
+		}
+	}()
+	return nil
+}
+
+// readStatus counts the requests reported by the worker until it exits.
+func (w *poolWorker) readStatus(status *os.File) {
+	defer status.Close()
+	buf := make([]byte, 256)
+	for {
+		n, err := status.Read(buf)
+		for _, event := range buf[:n] {
+			switch event {
+			case axonfpm.EventBegin:
+				w.active.Add(1)
+			case axonfpm.EventEnd:
+				w.served.Add(1)
+				if w.active.Add(-1) <= 0 {
+					w.idleSince.Store(time.Now().UnixNano())
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// exited removes a worker that ended. Workers that were not asked to stop count
+// as crashes; it reports false when they exceed max_restarts.
+func (p *workerPool) exited(w *poolWorker) bool {
+	p.mu.Lock()
+	delete(p.workers, w.pid)
+	p.exitedServed += w.served.Load()
+	p.mu.Unlock()
+	if w.stopping.Load() {
+		return true
+	}
+	return p.recordCrash()
+}
+
+// recordCrash counts a crashed or failed worker and delays its replacement.
+func (p *workerPool) recordCrash() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conf.MaxRestarts != 0 && p.crashes >= p.conf.MaxRestarts {
+		return false
+	}
+	p.crashes++
+	p.restartAfter = time.Now().Add(restartDelay)
+	return true
+}
+
+// maintain recycles workers that reached max_requests, stops surplus idle workers
+// and starts the workers the process manager mode asks for.
+func (p *workerPool) maintain(pending bool) bool {
+	conf := p.conf
+	now := time.Now()
+
+	p.mu.Lock()
+	live := make([]*poolWorker, 0, len(p.workers))
+	for _, w := range p.workers {
+		if w.stopping.Load() {
+			continue
+		}
+		if conf.MaxRequests > 0 && w.served.Load() >= int64(conf.MaxRequests) {
+			log.Printf("[%s] Worker %d served %d requests. Recycling...", conf.SiteName, w.pid, w.served.Load())
+			p.stopWorker(w)
+			continue
+		}
+		live = append(live, w)
+	}
+	restartAfter := p.restartAfter
+	p.mu.Unlock()
+
+	idle := make([]*poolWorker, 0, len(live))
+	for _, w := range live {
+		if w.idle() {
+			idle = append(idle, w)
+		}
+	}
+	// The workers idle for the longest time are stopped first.
+	slices.SortFunc(idle, func(a, b *poolWorker) int {
+		return cmp.Compare(a.idleSince.Load(), b.idleSince.Load())
+	})
+
+	stopped := 0
+	switch conf.PM {
+	case pmDynamic:
+		for _, w := range idle[:conf.idleWorkersToStop(len(idle))] {
+			log.Printf("[%s] Stopping idle worker %d (more than %d spare servers).", conf.SiteName, w.pid, conf.MaxSpareServers)
+			p.stopWorker(w)
+			stopped++
+		}
+	case pmOndemand:
+		timeout := time.Duration(conf.ProcessIdleTimeout) * time.Second
+		for _, w := range idle {
+			if now.Sub(time.Unix(0, w.idleSince.Load())) >= timeout {
+				log.Printf("[%s] Stopping worker %d idle for more than %s.", conf.SiteName, w.pid, timeout)
+				p.stopWorker(w)
+				stopped++
+			}
+		}
+	}
+
+	if now.Before(restartAfter) {
+		return true
+	}
+	for range conf.workersToStart(len(live)-stopped, len(idle)-stopped, pending) {
+		if !p.start() {
+			return false
+		}
+	}
+	return true
+}
+
+// stopWorker asks a worker to finish its requests and exit, and kills it when it
+// does not exit within workerStopTimeout.
+func (p *workerPool) stopWorker(w *poolWorker) {
+	if w.stopping.Swap(true) {
+		return
+	}
+	if err := w.process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		log.Printf("[%s] Failed to send SIGTERM to worker %d: %v", p.conf.SiteName, w.pid, err)
+	}
+	go func() {
+		select {
+		case <-w.exited:
+		case <-time.After(workerStopTimeout):
+			if err := w.process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				log.Printf("[%s] Failed to force-kill worker %d after SIGTERM timeout: %v", p.conf.SiteName, w.pid, err)
+			}
+		}
+	}()
+}
+
+// stopWorkers asks every worker to finish its requests and exit, and returns them.
+func (p *workerPool) stopWorkers() []*poolWorker {
+	p.mu.Lock()
+	workers := make([]*poolWorker, 0, len(p.workers))
+	for _, w := range p.workers {
+		workers = append(workers, w)
+	}
+	p.mu.Unlock()
+
+	for _, w := range workers {
+		p.stopWorker(w)
+	}
+	return workers
+}
+
+// stopAll stops every worker and waits until they exit.
+func (p *workerPool) stopAll() {
+	for _, w := range p.stopWorkers() {
+		select {
+		case <-w.exited:
+		case <-time.After(workerStopTimeout + time.Second):
+		}
+	}
+}
+
+// requestRestart replaces the workers of the pool. The old workers finish their
+// requests while the new ones accept connections on the same socket.
+func (p *workerPool) requestRestart() {
+	select {
+	case p.restart <- struct{}{}:
+	default:
+	}
+}
+
+// watchPending reports connections waiting on the socket of an ondemand pool.
+// The manager never accepts them; it only wakes up when the socket is readable.
+func (p *workerPool) watchPending(ctx context.Context) <-chan struct{} {
+	pending := make(chan struct{}, 1)
+	conn, ok := p.listener.(syscall.Conn)
+	if !ok {
+		return pending
+	}
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return pending
+	}
+	go func() {
+		for ctx.Err() == nil {
+			// The first call waits for the socket to become readable.
+			waited := false
+			err := raw.Read(func(uintptr) bool {
+				ready := waited
+				waited = true
+				return ready
+			})
+			if err != nil {
+				return
+			}
+			select {
+			case pending <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return pending
+}
+
+// close closes the pool socket and removes unix socket files.
+func (p *workerPool) close() {
+	close(p.closed)
+	_ = p.file.Close()
+	_ = p.listener.Close()
+}
+
+// status returns a snapshot of the pool and its workers.
+func (p *workerPool) status(now time.Time) poolStatus {
+	p.mu.Lock()
+	st := poolStatus{
+		SiteName:      p.conf.SiteName,
+		Socket:        p.endpoint,
+		PM:            p.conf.PM,
+		State:         poolStateRunning,
+		StartTime:     p.started,
+		StartSince:    int64(now.Sub(p.started) / time.Second),
+		MaxChildren:   p.conf.MaxChildren,
+		Restarts:      p.crashes,
+		RequestsTotal: p.exitedServed,
+		Workers:       make([]workerStatus, 0, len(p.workers)),
+	}
+	for _, w := range p.workers {
+		ws := workerStatus{
+			PID:            w.pid,
+			State:          workerStateIdle,
+			StartTime:      w.started,
+			StartSince:     int64(now.Sub(w.started) / time.Second),
+			Requests:       w.served.Load(),
+			ActiveRequests: w.active.Load(),
+		}
+		switch {
+		case w.stopping.Load():
+			ws.State = workerStateStopping
+		case ws.ActiveRequests > 0:
+			ws.State = workerStateActive
+			st.ActiveWorkers++
+		default:
+			st.IdleWorkers++
+		}
+		st.RequestsTotal += ws.Requests
+		st.Workers = append(st.Workers, ws)
+	}
+	p.mu.Unlock()
+
+	st.TotalWorkers = len(st.Workers)
+	slices.SortFunc(st.Workers, func(a, b workerStatus) int { return cmp.Compare(a.PID, b.PID) })
+	if current, limit, err := readCgroupMemoryUsage(p.conf.SiteName); err == nil {
+		st.MemoryUsageBytes = current
+		st.MemoryLimitBytes = limit
+	}
+	return st
+}
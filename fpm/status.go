@@ -0,0 +1,121 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+
+// This file contains the platform-agnostic status types reported by the
+// control socket and their plain text format.
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Pool states reported by the status page.
+const (
+	poolStateRunning = "running"
+	poolStateStopped = "stopped"
+	// poolStateFailed is a pool whose supervisor ended, after a configuration
+	// error or after its workers crashed more than max_restarts times.
+	poolStateFailed = "failed"
+	// poolStateStarting is a pool whose supervisor did not open the socket yet.
+	poolStateStarting = "starting"
+)
+
+// Worker states reported by the status page.
+const (
+	workerStateIdle     = "idle"
+	workerStateActive   = "active"
+	workerStateStopping = "stopping"
+)
+
+// poolStatus is the status of one pool.
+type poolStatus struct {
+	Name             string         `json:"pool"`
+	SiteName         string         `json:"site_name"`
+	ConfigFile       string         `json:"config_file"`
+	Socket           string         `json:"socket"`
+	PM               string         `json:"process_manager"`
+	State            string         `json:"state"`
+	StartTime        time.Time      `json:"start_time"`
+	StartSince       int64          `json:"start_since"`
+	MaxChildren      int            `json:"max_children"`
+	Restarts         int            `json:"restarts"`
+	RequestsTotal    int64          `json:"requests"`
+	ActiveWorkers    int            `json:"active_processes"`
+	IdleWorkers      int            `json:"idle_processes"`
+	TotalWorkers     int            `json:"total_processes"`
+	MemoryUsageBytes int64          `json:"memory_usage_bytes"`
+	MemoryLimitBytes int64          `json:"memory_limit_bytes"`
+	Workers          []workerStatus `json:"processes"`
+}
+
+// workerStatus is the status of one worker process.
+type workerStatus struct {
+	PID            int       `json:"pid"`
+	State          string    `json:"state"`
+	StartTime      time.Time `json:"start_time"`
+	StartSince     int64     `json:"start_since"`
+	Requests       int64     `json:"requests"`
+	ActiveRequests int64     `json:"active_requests"`
+}
+
+// writeStatusText writes pools in the "name: value" layout of the php-fpm status page.
+func writeStatusText(w io.Writer, pools []poolStatus) {
+	for i, pool := range pools {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		field := func(name string, value any) {
+			fmt.Fprintf(w, "%-21s %v\n", name+":", value)
+		}
+		field("pool", pool.Name)
+		field("site name", pool.SiteName)
+		field("config file", pool.ConfigFile)
+		field("state", pool.State)
+		if pool.State != poolStateRunning {
+			continue
+		}
+		field("socket", pool.Socket)
+		field("process manager", pool.PM)
+		field("start time", pool.StartTime.Format(time.RFC3339))
+		field("start since", pool.StartSince)
+		field("restarts", pool.Restarts)
+		field("accepted conn", pool.RequestsTotal)
+		field("idle processes", pool.IdleWorkers)
+		field("active processes", pool.ActiveWorkers)
+		field("total processes", pool.TotalWorkers)
+		field("max children", pool.MaxChildren)
+		field("memory usage", pool.MemoryUsageBytes)
+		field("memory limit", pool.MemoryLimitBytes)
+		for _, worker := range pool.Workers {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, strings.Repeat("*", 24))
+			field("pid", worker.PID)
+			field("state", worker.State)
+			field("start time", worker.StartTime.Format(time.RFC3339))
+			field("start since", worker.StartSince)
+			field("requests", worker.Requests)
+			field("active requests", worker.ActiveRequests)
+		}
+	}
+}
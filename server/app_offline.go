@@ -0,0 +1,87 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonoffline"
+)
+
+// Offline returns the app_offline.htm monitor of the site.
+func (s *Site) Offline() *axonoffline.Monitor {
+	if s == nil {
+		return legacyOffline
+	}
+	return s.offline
+}
+
+// newOfflineMonitor creates the app_offline.htm monitor that starts and stops the site application.
+func newOfflineMonitor(site *Site) *axonoffline.Monitor {
+	return axonoffline.New(site.Root(), AppOfflineConfig, axonoffline.Hooks{
+		Start: func(restart bool) { startSiteApplication(site, restart) },
+		Stop:  func() { stopSiteApplication(site) },
+	})
+}
+
+// startOfflineMonitor starts the site application through its app_offline.htm monitor.
+func startOfflineMonitor(site *Site, monitor *axonoffline.Monitor) {
+	if err := monitor.Start(site.ScriptCache()); err != nil {
+		log.Printf("Warning: Failed to watch %s in %s: %v\n", axonoffline.FileName, site.Root(), err)
+	}
+}
+
+// startSiteApplication loads global.asa, runs Application_OnStart and registers
+// Session_OnEnd for the site and its nested applications. A restart first drops the cached scripts and Application state.
+func startSiteApplication(site *Site, restart bool) {
+	apps := site.Applications()
+	if restart {
+		site.ScriptCache().Clear()
+		site.Application().Reset()
+		apps.resetApplications()
+	}
+	globalASA := site.GlobalASA()
+	if err := globalASA.LoadAndCompileApplication(site.Root(), site.Root(), apps.VirtualDirectories(), site.Application()); err != nil {
+		log.Printf("Warning: Failed to load global.asa for %s: %v\n", site.Root(), err)
+	} else if globalASA.IsLoaded() {
+		_ = globalASA.ExecuteApplicationOnStart(newSiteEventHost(site))
+		axonhandler.RegisterSessionOnEnd(webHostOptions(siteEventRequest(site)))
+	}
+	apps.Start(site)
+}
+
+// stopSiteApplication runs Application_OnEnd for the nested applications and the site.
+func stopSiteApplication(site *Site) {
+	site.Applications().Stop(site)
+	if globalASA := site.GlobalASA(); globalASA != nil && globalASA.IsLoaded() {
+		axonhandler.UnregisterSessionOnEnd(webHostOptions(siteEventRequest(site)))
+		_ = globalASA.ExecuteApplicationOnEnd(newSiteEventHost(site))
+	}
+}
+
+// withAppOffline answers 503 with app_offline.htm while the site of the request is offline.
+func withAppOffline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siteFromRequest(r).Offline().Serve(w, r, next)
+	})
+}
@@ -0,0 +1,286 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+	"github.com/spf13/viper"
+)
+
+// ApplicationConfigs stores the [[server.applications]] entries of the default site.
+var ApplicationConfigs = []ApplicationConfig{}
+
+// VirtualDirectoryConfigs stores the [[server.virtual_directories]] entries of the default site.
+var VirtualDirectoryConfigs = []VirtualDirectoryConfig{}
+
+// legacyApplications holds the virtual directories and nested applications of the [server] web root.
+var legacyApplications *applicationSet
+
+// ApplicationConfig maps one nested application entry. An empty physical_path means the
+// folder with the same name under the web root (or under a virtual directory).
+type ApplicationConfig struct {
+	Path         string `mapstructure:"path"`
+	PhysicalPath string `mapstructure:"physical_path"`
+}
+
+// VirtualDirectoryConfig maps one URL prefix to a folder outside the web root.
+type VirtualDirectoryConfig struct {
+	Path         string `mapstructure:"path"`
+	PhysicalPath string `mapstructure:"physical_path"`
+}
+
+// WebApplication is one IIS-style application boundary below a site root. It owns its
+// Application object, global.asa and session cookie, like a folder marked as an
+// application in IIS Manager.
+type WebApplication struct {
+	VirtualPath  string
+	PhysicalPath string
+	MetabasePath string
+
+	application         *asp.Application
+	globalASA           *axonvm.GlobalASA
+	sessionCookieSuffix string
+}
+
+// SessionCookieName returns the session cookie of the application: the site
+// cookie name for the root application, or that name followed by the
+// application path.
+func (app *WebApplication) SessionCookieName(siteCookieName string) string {
+	if app == nil {
+		return siteCookieName
+	}
+	return siteCookieName + app.sessionCookieSuffix
+}
+
+// CookiePath scopes the session cookie to the application's URL prefix.
+func (app *WebApplication) CookiePath() string {
+	if app == nil {
+		return "/"
+	}
+	return app.VirtualPath
+}
+
+// applicationSet stores the virtual directories and nested applications of one site.
+type applicationSet struct {
+	siteRoot     string
+	metabaseRoot string
+	virtualDirs  []asp.VirtualDirectory
+	// applications is sorted longest virtual path first so the innermost application wins.
+	applications []*WebApplication
+}
+
+// loadApplicationConfigs reads the [[server.applications]] and [[server.virtual_directories]] tables.
+func loadApplicationConfigs(v *viper.Viper) {
+	ApplicationConfigs = ApplicationConfigs[:0]
+	VirtualDirectoryConfigs = VirtualDirectoryConfigs[:0]
+	if v == nil {
+		return
+	}
+	if v.IsSet("server.applications") {
+		var configs []ApplicationConfig
+		if err := v.UnmarshalKey("server.applications", &configs); err != nil {
+			log.Printf("Warning: Failed to read [[server.applications]] configuration: %v\n", err)
+		} else {
+			ApplicationConfigs = configs
+		}
+	}
+	if v.IsSet("server.virtual_directories") {
+		var configs []VirtualDirectoryConfig
+		if err := v.UnmarshalKey("server.virtual_directories", &configs); err != nil {
+			log.Printf("Warning: Failed to read [[server.virtual_directories]] configuration: %v\n", err)
+		} else {
+			VirtualDirectoryConfigs = configs
+		}
+	}
+}
+
+// newApplicationSet resolves the virtual directories and nested applications of one site.
+// siteID is used to build IIS-style metabase paths such as /LM/W3SVC/1/ROOT/shop.
+func newApplicationSet(siteRoot string, siteID int, apps []ApplicationConfig, dirs []VirtualDirectoryConfig) (*applicationSet, error) {
+	absRoot, err := filepath.Abs(siteRoot)
+	if err != nil {
+		absRoot = filepath.Clean(siteRoot)
+	}
+	set := &applicationSet{
+		siteRoot:     absRoot,
+		metabaseRoot: "/LM/W3SVC/" + strconv.Itoa(siteID) + "/ROOT",
+	}
+
+	mappings := make([]asp.VirtualDirectory, 0, len(dirs)+len(apps))
+	for _, dir := range dirs {
+		if asp.NormalizeVirtualPath(dir.Path) == "/" || strings.TrimSpace(dir.PhysicalPath) == "" {
+			return nil, fmt.Errorf("virtual directory %q needs a path below / and a physical_path", dir.Path)
+		}
+		mappings = append(mappings, asp.VirtualDirectory{VirtualPath: dir.Path, PhysicalPath: dir.PhysicalPath})
+	}
+	// An application with its own physical_path is also a virtual directory.
+	for _, app := range apps {
+		if strings.TrimSpace(app.PhysicalPath) != "" {
+			mappings = append(mappings, asp.VirtualDirectory{VirtualPath: app.Path, PhysicalPath: app.PhysicalPath})
+		}
+	}
+	set.virtualDirs = asp.NormalizeVirtualDirectories(mappings)
+
+	seen := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		virtualPath := asp.NormalizeVirtualPath(app.Path)
+		if virtualPath == "/" {
+			return nil, fmt.Errorf("application path %q must be below the site root", app.Path)
+		}
+		key := strings.ToLower(virtualPath)
+		if seen[key] {
+			return nil, fmt.Errorf("application %q is configured twice", virtualPath)
+		}
+		seen[key] = true
+		set.applications = append(set.applications, &WebApplication{
+			VirtualPath:         virtualPath,
+			PhysicalPath:        set.physicalPath(virtualPath),
+			MetabasePath:        set.metabaseRoot + virtualPath,
+			application:         asp.NewApplication(),
+			globalASA:           axonvm.NewGlobalASA(),
+			sessionCookieSuffix: applicationSessionCookieSuffix(virtualPath),
+		})
+	}
+	slices.SortStableFunc(set.applications, func(a, b *WebApplication) int { return len(b.VirtualPath) - len(a.VirtualPath) })
+	return set, nil
+}
+
+// applicationSessionCookieSuffix derives a per-application session cookie so each
+// application keeps its own Session, e.g. /shop/admin -> ASPSESSIONID_SHOP_ADMIN.
+func applicationSessionCookieSuffix(virtualPath string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToUpper(virtualPath) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+			continue
+		}
+		builder.WriteByte('_')
+	}
+	return builder.String()
+}
+
+// physicalPath maps one virtual path to disk, honoring the virtual directories of the set.
+func (set *applicationSet) physicalPath(virtualPath string) string {
+	if dir, rest, ok := asp.MatchVirtualDirectory(set.virtualDirs, virtualPath); ok {
+		return filepath.Join(dir.PhysicalPath, filepath.FromSlash(rest))
+	}
+	return filepath.Join(set.siteRoot, filepath.FromSlash(strings.TrimPrefix(virtualPath, "/")))
+}
+
+// VirtualDirectories returns the normalized virtual directory mappings, or nil for a nil set.
+func (set *applicationSet) VirtualDirectories() []asp.VirtualDirectory {
+	if set == nil {
+		return nil
+	}
+	return set.virtualDirs
+}
+
+// virtualDirectoryRoots returns the physical folders of the mappings, for file watchers.
+func virtualDirectoryRoots(dirs []asp.VirtualDirectory) []string {
+	roots := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		roots = append(roots, dir.PhysicalPath)
+	}
+	return roots
+}
+
+// applicationFor returns the innermost nested application containing the URL path,
+// or nil when the path belongs to the root application of the site.
+func (set *applicationSet) applicationFor(urlPath string) *WebApplication {
+	if set == nil {
+		return nil
+	}
+	for _, app := range set.applications {
+		if asp.VirtualPathHasPrefix(urlPath, app.VirtualPath) {
+			return app
+		}
+	}
+	return nil
+}
+
+// rootMetabasePath returns the APPL_MD_PATH of the root application.
+func (set *applicationSet) rootMetabasePath() string {
+	if set == nil {
+		return "/LM/W3SVC/1/ROOT"
+	}
+	return set.metabaseRoot
+}
+
+// Start loads each nested global.asa, runs its Application_OnStart and registers its Session_OnEnd.
+func (set *applicationSet) Start(site *Site) {
+	if set == nil {
+		return
+	}
+	for _, app := range set.applications {
+		if err := app.globalASA.LoadAndCompileApplication(app.PhysicalPath, set.siteRoot, set.virtualDirs, app.application); err != nil {
+			log.Printf("Warning: Failed to load global.asa for application %s: %v\n", app.VirtualPath, err)
+			continue
+		}
+		if app.globalASA.IsLoaded() {
+			_ = app.globalASA.ExecuteApplicationOnStart(newApplicationEventHost(site, app))
+			axonhandler.RegisterSessionOnEnd(webHostOptions(applicationEventRequest(site, app)))
+		}
+	}
+}
+
+// resetApplications empties the Application object of every nested application before a restart.
+func (set *applicationSet) resetApplications() {
+	if set == nil {
+		return
+	}
+	for _, app := range set.applications {
+		app.application.Reset()
+	}
+}
+
+// Stop runs Application_OnEnd for every nested application.
+func (set *applicationSet) Stop(site *Site) {
+	if set == nil {
+		return
+	}
+	for _, app := range set.applications {
+		if app.globalASA.IsLoaded() {
+			axonhandler.UnregisterSessionOnEnd(webHostOptions(applicationEventRequest(site, app)))
+			_ = app.globalASA.ExecuteApplicationOnEnd(newApplicationEventHost(site, app))
+		}
+	}
+}
+
+// newApplicationEventHost creates a host with no client connection for nested application events.
+func newApplicationEventHost(site *Site, app *WebApplication) *WebHost {
+	return NewWebHost(axonhandler.DiscardWriter{}, applicationEventRequest(site, app))
+}
+
+// applicationEventRequest returns the request without a client connection of nested application events.
+func applicationEventRequest(site *Site, app *WebApplication) *http.Request {
+	req, _ := http.NewRequest("GET", "http://localhost"+app.VirtualPath+"/", nil)
+	return withSite(req, site)
+}
@@ -0,0 +1,128 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNestedApplicationScopesHostState verifies a nested application gets its own Application,
+// session cookie and APPL_* server variables while the root application keeps the legacy ones.
+func TestNestedApplicationScopesHostState(t *testing.T) {
+	useTestSessionDir(t)
+
+	rootDir := t.TempDir()
+	originalRoot := RootDir
+	originalApps := legacyApplications
+	RootDir = rootDir
+	defer func() {
+		RootDir = originalRoot
+		legacyApplications = originalApps
+	}()
+
+	apps, err := newApplicationSet(rootDir, 1, []ApplicationConfig{{Path: "/shop"}, {Path: "/shop/admin"}}, nil)
+	if err != nil {
+		t.Fatalf("create application set: %v", err)
+	}
+	legacyApplications = apps
+
+	rec := httptest.NewRecorder()
+	host := NewWebHost(rec, httptest.NewRequest(http.MethodGet, "http://localhost/Shop/admin/users.asp", nil))
+	if host.Application() == GetSharedApplication() {
+		t.Fatalf("expected nested application to have its own Application object")
+	}
+	vars := host.Request().ServerVars
+	if got := vars.Get("APPL_MD_PATH"); got != "/LM/W3SVC/1/ROOT/shop/admin" {
+		t.Fatalf("unexpected APPL_MD_PATH %q", got)
+	}
+	if got := vars.Get("APPL_PHYSICAL_PATH"); got != filepath.Join(rootDir, "shop", "admin") {
+		t.Fatalf("unexpected APPL_PHYSICAL_PATH %q", got)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "ASPSESSIONID_SHOP_ADMIN" || cookies[0].Path != "/shop/admin" {
+		t.Fatalf("expected application-scoped session cookie, got %+v", cookies)
+	}
+
+	shopHost := NewWebHost(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/shop/cart.asp", nil))
+	if shopHost.Application() == host.Application() {
+		t.Fatalf("expected /shop and /shop/admin to use different Application objects")
+	}
+
+	rootHost := NewWebHost(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/shopping.asp", nil))
+	if rootHost.Application() != GetSharedApplication() {
+		t.Fatalf("expected /shopping.asp to stay in the root application")
+	}
+	if got := rootHost.Request().ServerVars.Get("APPL_MD_PATH"); got != "/LM/W3SVC/1/ROOT" {
+		t.Fatalf("unexpected root APPL_MD_PATH %q", got)
+	}
+}
+
+// TestVirtualDirectoryServesFilesOutsideWebRoot verifies requests and MapPath follow virtual directories.
+func TestVirtualDirectoryServesFilesOutsideWebRoot(t *testing.T) {
+	rootDir := t.TempDir()
+	sharedDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sharedDir, "notice.txt"), []byte("shared content"), 0o644); err != nil {
+		t.Fatalf("write shared file: %v", err)
+	}
+
+	originalRoot := RootDir
+	originalApps := legacyApplications
+	RootDir = rootDir
+	defer func() {
+		RootDir = originalRoot
+		legacyApplications = originalApps
+	}()
+
+	apps, err := newApplicationSet(rootDir, 1, nil, []VirtualDirectoryConfig{{Path: "/assets", PhysicalPath: sharedDir}})
+	if err != nil {
+		t.Fatalf("create application set: %v", err)
+	}
+	legacyApplications = apps
+
+	rec := httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "http://localhost/assets/notice.txt", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "shared content" {
+		t.Fatalf("expected virtual directory file, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	host := NewWebHost(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/default.asp", nil))
+	if got := host.Server().MapPath("/assets/notice.txt"); got != filepath.Join(sharedDir, "notice.txt") {
+		t.Fatalf("unexpected MapPath result %q", got)
+	}
+}
+
+// TestNewApplicationSetRejectsInvalidEntries verifies misconfigured boundaries fail at startup.
+func TestNewApplicationSetRejectsInvalidEntries(t *testing.T) {
+	rootDir := t.TempDir()
+	if _, err := newApplicationSet(rootDir, 1, []ApplicationConfig{{Path: "/"}}, nil); err == nil {
+		t.Fatalf("expected error for an application at the site root")
+	}
+	if _, err := newApplicationSet(rootDir, 1, []ApplicationConfig{{Path: "/a"}, {Path: "/A/"}}, nil); err == nil {
+		t.Fatalf("expected error for a duplicate application")
+	}
+	if _, err := newApplicationSet(rootDir, 1, nil, []VirtualDirectoryConfig{{Path: "/x"}}); err == nil {
+		t.Fatalf("expected error for a virtual directory without physical_path")
+	}
+}
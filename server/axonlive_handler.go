@@ -37,6 +37,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"g3pix.com.br/axonasp/axonhandler"
 	"g3pix.com.br/axonasp/axonvm"
 )
 
@@ -157,12 +158,13 @@ func handleG3AxonLiveFetch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Resolve the registered URL to a filesystem path within RootDir.
+	// Resolve the registered URL to a filesystem path within the site web root.
+	rootDir := siteFromRequest(r).Root()
 	relativePath := strings.TrimPrefix(scriptURL, "/")
-	fullPath := filepath.Join(RootDir, filepath.FromSlash(relativePath))
+	fullPath := filepath.Join(rootDir, filepath.FromSlash(relativePath))
 
 	// Security: ensure the resolved path stays within the configured web root.
-	absRoot, rootErr := filepath.Abs(RootDir)
+	absRoot, rootErr := filepath.Abs(rootDir)
 	absPath, pathErr := filepath.Abs(fullPath)
 	if rootErr != nil || pathErr != nil || !strings.HasPrefix(absPath+string(filepath.Separator), absRoot+string(filepath.Separator)) {
 		writeG3AlJSONError(w, http.StatusForbidden, axonvm.AxonASPErrorMessages[axonvm.ErrG3ALPagePathOutsideRoot])
@@ -197,12 +199,10 @@ func resolveG3ALScriptURL(sessionID string) string {
 	return axonvm.G3ALGetPageForSession(strings.TrimSpace(sessionID))
 }
 
-// authenticatedSessionIDFromRequest extracts the ASP session ID from the request cookie.
+// authenticatedSessionIDFromRequest extracts the ASP session ID from the session
+// cookie of the request site, checking its signature when cookies are signed.
 func authenticatedSessionIDFromRequest(r *http.Request) string {
-	if c, err := r.Cookie("ASPSESSIONID"); err == nil && c != nil {
-		return strings.TrimSpace(c.Value)
-	}
-	return ""
+	return axonhandler.SessionIDFromRequest(r, siteFromRequest(r).SessionCookieSettings().Name)
 }
 
 // normalizeAndAuthorizeG3ALSessionID binds a fetch payload session ID to the authenticated cookie session.
@@ -47,6 +47,8 @@ type DirectoryListingRenderer struct {
 	blockedFiles map[string]struct{}
 	blockedDirs  map[string]struct{}
 	blockedExts  map[string]struct{}
+	// blockedPrefixes holds absolute blocked directory paths.
+	blockedPrefixes []string
 }
 
 type directoryListingEntry struct {
@@ -79,18 +81,24 @@ var cachedDirectoryListingAssets directoryListingAssets
 
 // NewDirectoryListingRenderer compiles the HTML template and initializes listing filters.
 func NewDirectoryListingRenderer(rootDir string, templatePath string) (*DirectoryListingRenderer, error) {
+	return newDirectoryListingRendererWithFilters(rootDir, templatePath, BlockedFiles, BlockedDirs, BlockedExtensions, blockedDirPrefixes)
+}
+
+// newDirectoryListingRendererWithFilters compiles the template with site-specific blocked lists.
+func newDirectoryListingRendererWithFilters(rootDir string, templatePath string, blockedFiles []string, blockedDirs []string, blockedExts []string, dirPrefixes []string) (*DirectoryListingRenderer, error) {
 	tmpl, err := template.New("directory-listing").ParseFiles(templatePath)
 	if err != nil {
 		return nil, err
 	}
 
 	renderer := &DirectoryListingRenderer{
-		rootDir:      rootDir,
-		templatePath: templatePath,
-		template:     tmpl,
-		blockedFiles: makeLookupSet(BlockedFiles),
-		blockedDirs:  makeLookupSet(BlockedDirs),
-		blockedExts:  makeLookupSet(BlockedExtensions),
+		rootDir:         rootDir,
+		templatePath:    templatePath,
+		template:        tmpl,
+		blockedFiles:    makeLookupSet(blockedFiles),
+		blockedDirs:     makeLookupSet(blockedDirs),
+		blockedExts:     makeLookupSet(blockedExts),
+		blockedPrefixes: dirPrefixes,
 	}
 	return renderer, nil
 }
@@ -116,7 +124,7 @@ func (d *DirectoryListingRenderer) Render(w http.ResponseWriter, r *http.Request
 			}
 			childPath := filepath.Join(absDirPath, name)
 			childAbs, err := filepath.Abs(childPath)
-			if err == nil && isBlockedDirectory(childAbs) {
+			if err == nil && pathInBlockedPrefixes(childAbs, d.blockedPrefixes) {
 				continue
 			}
 			if strings.EqualFold(name, ".") || strings.EqualFold(name, "..") {
@@ -28,11 +28,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"html"
-	"io"
 	"log"
 	"mime"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
@@ -43,12 +43,20 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
+	"g3pix.com.br/axonasp/axonaccesslog"
+	"g3pix.com.br/axonasp/axonadmission"
+	"g3pix.com.br/axonasp/axonauth"
 	_ "g3pix.com.br/axonasp/axonboot"
+	"g3pix.com.br/axonasp/axoncompress"
 	"g3pix.com.br/axonasp/axonconfig"
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonmetrics"
+	"g3pix.com.br/axonasp/axonoffline"
+	"g3pix.com.br/axonasp/axonproxy"
+	"g3pix.com.br/axonasp/axonupgrade"
 	"g3pix.com.br/axonasp/axonvm"
 	"g3pix.com.br/axonasp/axonvm/asp"
 	"github.com/fsnotify/fsnotify"
@@ -91,6 +99,24 @@ var (
 	scriptCache                   *axonvm.ScriptCache
 	activeWebConfig               *WebConfigProcessor
 	directoryListingRenderer      *DirectoryListingRenderer
+	AccessLogConfig               = axonaccesslog.ConfigFromViper(nil)
+	accessLog                     *axonaccesslog.Writer
+	CompressionConfig             = axoncompress.ConfigFromViper(nil)
+	compressor                    *axoncompress.Compressor
+	AuthenticationConfig          = axonauth.ConfigFromViper(nil)
+	authenticator                 *axonauth.Authenticator
+	SessionStoreConfig            = axonvm.SessionStoreConfigFromViper(nil)
+	SessionCookieConfig           = axonhandler.SessionCookieConfigFromViper(nil)
+	MetricsConfig                 = axonmetrics.ConfigFromViper(nil)
+	AdmissionConfig               = axonadmission.ConfigFromViper(nil)
+	admission                     *axonadmission.Controller
+	ReverseProxyConfig            = axonproxy.ConfigFromViper(nil)
+	reverseProxy                  *axonproxy.Proxy
+	AppOfflineConfig              = axonoffline.ConfigFromViper(nil)
+	legacyOffline                 *axonoffline.Monitor
+	UpgradeConfig                 = axonupgrade.ConfigFromViper(nil)
+	upgrader                      *axonupgrade.Upgrader
+	metrics                       *axonmetrics.Metrics
 )
 
 // init loads environment variables and applies TOML-based configuration through Viper.
@@ -244,6 +270,19 @@ func loadServerConfig() {
 	G3AxonLiveActive = v.GetBool("g3axonlive.g3axonlive_active")
 
 	blockedDirPrefixes = buildBlockedDirPrefixes(BlockedDirs)
+	loadTLSConfig(v)
+	AccessLogConfig = axonaccesslog.ConfigFromViper(v)
+	CompressionConfig = axoncompress.ConfigFromViper(v)
+	AuthenticationConfig = axonauth.ConfigFromViper(v)
+	SessionStoreConfig = axonvm.SessionStoreConfigFromViper(v)
+	SessionCookieConfig = axonhandler.SessionCookieConfigFromViper(v)
+	MetricsConfig = axonmetrics.ConfigFromViper(v)
+	AdmissionConfig = axonadmission.ConfigFromViper(v)
+	ReverseProxyConfig = axonproxy.ConfigFromViper(v)
+	AppOfflineConfig = axonoffline.ConfigFromViper(v)
+	UpgradeConfig = axonupgrade.ConfigFromViper(v)
+	loadApplicationConfigs(v)
+	loadSiteConfigs(v)
 }
 
 // applyRuntimeSettings applies timezone and Go memory limit based on loaded configuration.
@@ -315,13 +354,6 @@ func buildBlockedDirPrefixes(blockedDirs []string) []string {
 	return resolved
 }
 
-// dummyResponseWriter provides a no-op http.ResponseWriter for global.asa events.
-type dummyResponseWriter struct{}
-
-func (d *dummyResponseWriter) Header() http.Header         { return make(http.Header) }
-func (d *dummyResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
-func (d *dummyResponseWriter) WriteHeader(statusCode int)  {}
-
 // cleanupSessionFiles removes all files and folders from temp/session.
 func cleanupSessionFiles() {
 	sessionDir := filepath.Join(TempDir, "session")
@@ -368,10 +400,13 @@ func main() {
 		runtime.SetMutexProfileFraction(0)
 	}
 
-	if CleanupSessions {
+	// Adopt inherited sockets before anything can start a child process. A process
+	// started by a graceful upgrade keeps the sessions and cache of the one it replaces.
+	upgrader = axonupgrade.New(UpgradeConfig)
+	if CleanupSessions && !upgrader.Upgraded() {
 		cleanupSessionFiles()
 	}
-	if CleanupCache {
+	if CleanupCache && !upgrader.Upgraded() {
 		cleanupCacheFiles()
 	}
 
@@ -385,6 +420,13 @@ func main() {
 
 	initializeServerOptionalFeatures()
 
+	apps, err := newApplicationSet(RootDir, 1, ApplicationConfigs, VirtualDirectoryConfigs)
+	if err != nil {
+		axonvm.ReportInternalError(axonvm.ErrRootDirInvalid, err, "Failed to configure the applications and virtual directories.", RootDir, 0)
+		os.Exit(1)
+	}
+	legacyApplications = apps
+
 	cacheRoot, cacheRootErr := filepath.Abs(RootDir)
 	if cacheRootErr != nil {
 		cacheRoot = RootDir
@@ -396,22 +438,30 @@ func main() {
 	)
 	scriptCache.SetEngineConfig(ServerEngineMode, ExecuteAsASPExtensions, ExecuteAsVBScriptExtensions, ExecuteAsJavaScriptExtensions)
 	scriptCache.SetWatchedExtensions(ExecuteAsASPExtensions)
-	if err := scriptCache.StartInvalidator([]string{cacheRoot}); err != nil {
+	if err := scriptCache.StartInvalidator(append([]string{cacheRoot}, virtualDirectoryRoots(legacyApplications.VirtualDirectories())...)); err != nil {
 		log.Printf("Warning: Failed to start bytecode invalidator: %v\n", err)
 	}
 	defer scriptCache.StopInvalidator()
 
+	if err := axonvm.ConfigureSessionStore(SessionStoreConfig); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Failed to configure the session store.", "global.session_store", 0)
+		os.Exit(1)
+	}
+	if err := SessionCookieConfig.Validate(); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Invalid session cookie settings.", "global.session_cookie_name", 0)
+		os.Exit(1)
+	}
 	asp.StartSessionAutoFlush(time.Duration(SessionAutoFlushSeconds) * time.Second)
 	defer asp.StopSessionAutoFlush()
 
-	// Load and compile global.asa
-	if err := axonvm.GetGlobalASA().LoadAndCompile(RootDir, GetSharedApplication()); err != nil {
-		fmt.Printf("Warning: Failed to load global.asa: %v\n", err)
-	} else if axonvm.GetGlobalASA().IsLoaded() {
-		// Execute Application_OnStart using a dummy host
-		req, _ := http.NewRequest("GET", "http://localhost/", nil)
-		dummyHost := NewWebHost(&dummyResponseWriter{}, req)
-		_ = axonvm.GetGlobalASA().ExecuteApplicationOnStart(dummyHost)
+	// Load global.asa and run Application_OnStart unless app_offline.htm exists
+	legacyOffline = newOfflineMonitor(nil)
+	startOfflineMonitor(nil, legacyOffline)
+
+	sites, err := startConfiguredSites()
+	if err != nil {
+		axonvm.ReportInternalError(axonvm.ErrRootDirInvalid, err, "Failed to start the configured virtual host sites.", "", 0)
+		os.Exit(1)
 	}
 
 	mux := http.NewServeMux()
@@ -421,46 +471,199 @@ func main() {
 	RegisterG3AxonLiveEndpoint(mux)
 	mux.HandleFunc("/", handleRequest)
 
+	if CompressionConfig.Enabled {
+		if c, err := axoncompress.New(CompressionConfig); err != nil {
+			log.Printf("Warning: Failed to configure response compression, responses will not be compressed: %v\n", err)
+		} else {
+			compressor = c
+		}
+	}
+	admission = axonadmission.New(AdmissionConfig)
+	if p, err := axonproxy.New(ReverseProxyConfig); err != nil {
+		axonvm.ReportInternalError(axonvm.ErrInvalidConfig, err, "Failed to configure the trusted reverse proxies.", "reverse_proxy.trusted_proxies", 0)
+		os.Exit(1)
+	} else {
+		reverseProxy = p
+	}
+	if AuthenticationConfig.Enabled {
+		a, err := axonauth.New(AuthenticationConfig)
+		if err != nil {
+			axonvm.ReportInternalError(axonvm.ErrCouldNotReadFile, err, "Failed to configure the authentication provider.", AuthenticationConfig.HtpasswdFile, 0)
+			os.Exit(1)
+		}
+		authenticator = a
+	}
+	if AccessLogConfig.Enabled {
+		accessLogConfig := AccessLogConfig
+		accessLogConfig.Directory = filepath.Join(accessLogConfig.Directory, "http")
+		writer, err := axonaccesslog.New(accessLogConfig)
+		if err != nil {
+			log.Printf("Warning: Failed to start the access log, requests will not be logged: %v\n", err)
+		} else {
+			accessLog = writer
+			defer accessLog.Close()
+		}
+	}
+
+	if MetricsConfig.Enabled {
+		m, err := axonmetrics.New(MetricsConfig, "http", Version)
+		if err == nil {
+			err = m.StartWithListen(upgrader.Listen)
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to start the metrics endpoint, metrics will not be collected: %v\n", err)
+		} else {
+			metrics = m
+			defer metrics.Close()
+			metrics.AddScriptCache("default", scriptCache)
+			for _, site := range sites {
+				metrics.AddScriptCache(site.Name, site.ScriptCache())
+			}
+		}
+	}
+
+	siteHandler := compressor.Handler(withServerHeader(withSiteRouting(withAppOffline(mux))))
+	httpHandler := reverseProxy.Handler(accessLog.Handler(metrics.Handler(siteHandler), nil))
+	var tlsServer *http.Server
+	if EnableTLS {
+		store, err := newCertificateStore(configuredTLSCertificatePairs())
+		if err != nil {
+			axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "HTTPS listener could not load its certificates.", TLSPort, 0)
+			os.Exit(1)
+		}
+		if err := store.StartWatcher(); err != nil {
+			log.Printf("Warning: Failed to watch TLS certificates for changes: %v\n", err)
+		}
+		defer store.StopWatcher()
+		setActiveCertificateStore(store)
+
+		tlsConfig, err := buildServerTLSConfig(store)
+		if err != nil {
+			axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "HTTPS listener configuration is invalid.", TLSPort, 0)
+			os.Exit(1)
+		}
+		tlsServer = &http.Server{
+			Addr:      ":" + TLSPort,
+			Handler:   httpHandler,
+			TLSConfig: tlsConfig,
+			Protocols: serverProtocols(true),
+		}
+		if TLSRedirectHTTP {
+			httpHandler = reverseProxy.Handler(accessLog.Handler(metrics.Handler(withServerHeader(newHTTPSRedirectHandler(siteHandler))), nil))
+		}
+	}
+
 	httpServer := &http.Server{
-		Addr:    ":" + Port,
-		Handler: withServerHeader(mux),
+		Addr:      ":" + Port,
+		Handler:   httpHandler,
+		Protocols: serverProtocols(false),
+	}
+
+	httpListener, err := upgrader.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "HTTP server could not start listening.", Port, 0)
+		os.Exit(1)
+	}
+	var tlsListener net.Listener
+	if tlsServer != nil {
+		tlsListener, err = upgrader.Listen("tcp", tlsServer.Addr)
+		if err != nil {
+			axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "HTTPS server could not start listening.", TLSPort, 0)
+			os.Exit(1)
+		}
 	}
 
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(stop, append([]os.Signal{os.Interrupt, syscall.SIGTERM}, axonupgrade.Signals()...)...)
 
 	go func() {
 		fmt.Printf("\033[H\033[2J\033[1mG3pix ❖ AxonASP Server %s \033[0m\n", Version)
 		fmt.Printf("HTTP Server started on: %s\n", Port)
+		if tlsServer != nil {
+			fmt.Printf("HTTPS Server started on: %s\n", TLSPort)
+		}
+		if metrics != nil && metrics.Listen() != "" {
+			fmt.Printf("Metrics endpoint: http://%s%s\n", metrics.Listen(), metrics.Path())
+		}
 		fmt.Printf("Root directory: %s\n", RootDir)
 		fmt.Print("\033]0;G3pix ❖ AxonASP Server\007\033]11;#003399\007\033[1;37m")
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := serveListener(httpServer, httpListener, false); err != nil && err != http.ErrServerClosed {
 			axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "HTTP server could not start listening.", Port, 0)
 			os.Exit(1)
 		}
 	}()
 
-	<-stop
-	fmt.Println("\nShutting down server...")
-
-	if axonvm.GetGlobalASA().IsLoaded() {
-		// Execute Application_OnEnd using a dummy host
-		req, _ := http.NewRequest("GET", "http://localhost/", nil)
-		dummyHost := NewWebHost(&dummyResponseWriter{}, req)
-		_ = axonvm.GetGlobalASA().ExecuteApplicationOnEnd(dummyHost)
+	if tlsServer != nil {
+		go func() {
+			if err := serveListener(tlsServer, tlsListener, true); err != nil && err != http.ErrServerClosed {
+				axonvm.ReportInternalError(axonvm.ErrCouldNotListenOn, err, "HTTPS server could not start listening.", TLSPort, 0)
+				os.Exit(1)
+			}
+		}()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	upgrader.Ready()
+	waitForShutdownSignal(stop)
+	upgrader.Stopping()
+
+	// Drain in-flight requests first, so Application_OnEnd and the final session
+	// flush see the state they left behind.
+	ctx, cancel := context.WithTimeout(context.Background(), UpgradeConfig.ShutdownTimeout)
 	defer cancel()
 
-	if err := httpServer.Shutdown(ctx); err != nil {
-		axonvm.ReportInternalError(axonvm.ErrServerForcedToShutdown, err, "HTTP server shutdown failed.", "", 0)
+	if tlsServer != nil {
+		if err := tlsServer.Shutdown(ctx); err != nil {
+			axonvm.ReportInternalError(axonvm.ErrServerForcedToShutdown, err, "HTTPS server shutdown failed.", "", 0)
+		}
+	}
+	shutdownErr := httpServer.Shutdown(ctx)
+	if shutdownErr != nil {
+		axonvm.ReportInternalError(axonvm.ErrServerForcedToShutdown, shutdownErr, "HTTP server shutdown failed.", "", 0)
+	}
+
+	legacyOffline.Stop()
+	stopConfiguredSites(sites)
+	if err := asp.FlushRegisteredSessions(true); err != nil {
+		log.Printf("Warning: Failed to flush sessions: %v\n", err)
+	}
+	if shutdownErr != nil {
 		os.Exit(1)
 	}
 
 	fmt.Println("Server exited gracefully.")
 }
 
+// serveListener serves server on listener. Connections from trusted proxies start
+// with a PROXY protocol header when it is enabled. TLS certificates come from
+// TLSConfig.GetCertificate, so no file names are passed.
+func serveListener(server *http.Server, listener net.Listener, useTLS bool) error {
+	listener = reverseProxy.Listener(listener)
+	if useTLS {
+		return server.ServeTLS(listener, "", "")
+	}
+	return server.Serve(listener)
+}
+
+// waitForShutdownSignal blocks until the server must stop. An upgrade signal starts
+// the new binary with the listening sockets, and the server stops once it is ready.
+// A failed upgrade keeps the server running.
+func waitForShutdownSignal(stop <-chan os.Signal) {
+	for sig := range stop {
+		if !axonupgrade.IsUpgradeSignal(sig) {
+			fmt.Println("\nShutting down server...")
+			return
+		}
+		fmt.Println("\nStarting the new server process...")
+		pid, err := upgrader.Upgrade()
+		if err != nil {
+			axonvm.ReportInternalError(axonvm.ErrServerUpgradeFailed, err, "Graceful upgrade failed, the server keeps running.", "", 0)
+			continue
+		}
+		fmt.Printf("New server process %d is ready, draining requests...\n", pid)
+		return
+	}
+}
+
 // withServerHeader ensures every HTTP response advertises the AxonASP server header.
 func withServerHeader(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -491,31 +694,72 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 	if path == "" {
 		path = "/"
 	}
+	site := siteFromRequest(r)
+	rootDir := site.Root()
+
+	if !enforceWebConfigSecurity(w, r, site, path) {
+		return
+	}
+	r, ok := authenticateRequest(w, r, site, path)
+	if !ok {
+		return
+	}
 
-	if activeWebConfig != nil {
-		result, ok := activeWebConfig.Apply(path, r.URL.RawQuery)
+	if webConfig := site.WebConfig(); webConfig != nil {
+		result, ok := webConfig.ApplyRequest(r)
 		if ok {
+			r = applyRewriteServerVariables(r, result.ServerVariables)
 			switch result.ActionType {
 			case "redirect":
 				http.Redirect(w, r, result.RedirectLocation, result.RedirectStatus)
 				return
+			case "customresponse":
+				if result.StatusCode >= 400 {
+					serveErrorPageWithSubStatus(w, r, result.StatusCode, result.SubStatusCode)
+				} else if result.StatusCode > 0 {
+					w.WriteHeader(result.StatusCode)
+				}
+				return
+			case "abortrequest":
+				panic(http.ErrAbortHandler)
 			case "rewrite":
+				if r.Header.Get("X-Original-URL") == "" {
+					r.Header.Set("X-Original-URL", r.URL.RequestURI())
+				}
 				path = result.Path
 				r.URL.Path = result.Path
 				r.URL.RawQuery = result.RawQuery
+				// IIS authorizes the rewritten URL, so a public URL cannot be
+				// rewritten into a protected or filtered path.
+				if !enforceWebConfigSecurity(w, r, site, path) {
+					return
+				}
+				rewritten, authorized := authenticateRequest(w, r, site, path)
+				if !authorized {
+					return
+				}
+				r = rewritten
 			}
 		}
+		if outbound := webConfig.OutboundWriter(w, r); outbound != nil {
+			defer outbound.Close()
+			w = outbound
+		}
 	}
 
 	relativePath := strings.TrimPrefix(path, "/")
-	fullPath := filepath.Join(RootDir, filepath.FromSlash(relativePath))
+	if dir, rest, ok := asp.MatchVirtualDirectory(site.Applications().VirtualDirectories(), path); ok {
+		rootDir = dir.PhysicalPath
+		relativePath = rest
+	}
+	fullPath := filepath.Join(rootDir, filepath.FromSlash(relativePath))
 	cleanPath := filepath.Clean(fullPath)
 	requestedExt := strings.ToLower(filepath.Ext(cleanPath))
 	requestedName := strings.ToLower(filepath.Base(cleanPath))
 
-	absRoot, err := filepath.Abs(RootDir)
+	absRoot, err := filepath.Abs(rootDir)
 	if err != nil {
-		respondInternalHTTPError(w, axonvm.ErrCouldNotResolveCurrentDir, err, "Failed to resolve the configured root directory.", RootDir)
+		respondInternalHTTPError(w, axonvm.ErrCouldNotResolveCurrentDir, err, "Failed to resolve the configured root directory.", rootDir)
 		return
 	}
 
@@ -530,7 +774,7 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if isBlockedExtension(requestedExt) || isBlockedFile(requestedName) || isBlockedDirectory(absPath) {
+	if site.isBlockedExtension(requestedExt) || site.isBlockedFile(requestedName) || site.isBlockedDirectory(absPath) {
 		serveErrorPage(w, r, http.StatusNotFound)
 		return
 	}
@@ -545,6 +789,9 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	settings := site.WebConfig().Settings(rootDir, webConfigRelativeDir(relativePath, info.IsDir()), site.DefaultDocuments())
+	settings.ApplyHeaders(w.Header())
+
 	if info.IsDir() {
 		if !strings.HasSuffix(path, "/") {
 			redirectPath := path + "/"
@@ -556,11 +803,15 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		}
 
 		foundDefault := false
-		for _, page := range DefaultPages {
+		var defaultDocuments []string
+		if settings.DefaultDocumentEnabled {
+			defaultDocuments = settings.DefaultDocuments
+		}
+		for _, page := range defaultDocuments {
 			candidate := filepath.Join(fullPath, page)
 			candidateInfo, candidateErr := os.Stat(candidate)
 			if candidateErr == nil && !candidateInfo.IsDir() {
-				if isBlockedExtension(strings.ToLower(filepath.Ext(candidate))) || isBlockedFile(strings.ToLower(filepath.Base(candidate))) {
+				if site.isBlockedExtension(strings.ToLower(filepath.Ext(candidate))) || site.isBlockedFile(strings.ToLower(filepath.Base(candidate))) {
 					continue
 				}
 				fullPath = candidate
@@ -570,8 +821,8 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if !foundDefault {
-			if EnableDirectoryListing && directoryListingRenderer != nil {
-				if err := directoryListingRenderer.Render(w, r, fullPath, path); err == nil {
+			if renderer := site.DirectoryListing(); renderer != nil {
+				if err := renderer.Render(w, r, fullPath, path); err == nil {
 					return
 				}
 			}
@@ -581,6 +832,10 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !isASPExecutionExtension(fullPath) {
+		if contentType, ok := settings.MIMEType(filepath.Ext(fullPath)); ok {
+			w.Header().Set("Content-Type", contentType)
+		}
+		settings.ApplyClientCache(w.Header())
 		serveStaticFileWithMIME(w, r, fullPath)
 		return
 	}
@@ -619,7 +874,12 @@ func isBlockedFile(baseName string) bool {
 
 // isBlockedDirectory reports whether a requested absolute path belongs to any blocked directory.
 func isBlockedDirectory(absPath string) bool {
-	for _, prefix := range blockedDirPrefixes {
+	return pathInBlockedPrefixes(absPath, blockedDirPrefixes)
+}
+
+// pathInBlockedPrefixes reports whether an absolute path equals or lies below any blocked directory.
+func pathInBlockedPrefixes(absPath string, prefixes []string) bool {
+	for _, prefix := range prefixes {
 		if absPath == prefix || strings.HasPrefix(absPath, prefix+string(os.PathSeparator)) {
 			return true
 		}
@@ -628,307 +888,118 @@ func isBlockedDirectory(absPath string) bool {
 }
 
 // serveStaticFileWithMIME resolves and sets Content-Type using mime.TypeByExtension before serving files.
+// A Content-Type already set by a web.config mimeMap is kept.
 func serveStaticFileWithMIME(w http.ResponseWriter, r *http.Request, filePath string) {
 	ext := strings.ToLower(filepath.Ext(filePath))
-	if ext != "" {
+	if ext != "" && w.Header().Get("Content-Type") == "" {
 		if contentType := mime.TypeByExtension(ext); contentType != "" {
 			w.Header().Set("Content-Type", contentType)
 		}
 	}
-	http.ServeFile(w, r, filePath)
-}
-
-// singleHeaderResponseWriter prevents duplicate WriteHeader calls and can apply a default status code.
-type singleHeaderResponseWriter struct {
-	http.ResponseWriter
-	wroteHeader   bool
-	defaultStatus int
-}
-
-// newSingleHeaderResponseWriter wraps a response writer with duplicate WriteHeader protection.
-func newSingleHeaderResponseWriter(w http.ResponseWriter, defaultStatus int) *singleHeaderResponseWriter {
-	return &singleHeaderResponseWriter{ResponseWriter: w, defaultStatus: defaultStatus}
-}
-
-// WriteHeader writes the status code only once.
-func (w *singleHeaderResponseWriter) WriteHeader(statusCode int) {
-	if w.wroteHeader {
+	if compressor.ServePrecompressed(w, r, filePath) {
 		return
 	}
-	if w.defaultStatus > 0 && (statusCode <= 0 || statusCode == http.StatusOK) {
-		statusCode = w.defaultStatus
-	}
-	w.wroteHeader = true
-	w.ResponseWriter.WriteHeader(statusCode)
-}
-
-// Write applies the default status if needed and writes the response body.
-func (w *singleHeaderResponseWriter) Write(data []byte) (int, error) {
-	if !w.wroteHeader {
-		if w.defaultStatus > 0 {
-			w.WriteHeader(w.defaultStatus)
-		} else {
-			w.wroteHeader = true
-		}
-	}
-	return w.ResponseWriter.Write(data)
-}
-
-// Flush forwards flush operations to the underlying writer.
-func (w *singleHeaderResponseWriter) Flush() {
-	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
-		flusher.Flush()
-	}
-}
-
-// ReadFrom forwards optimized copy operations to the underlying writer when available.
-func (w *singleHeaderResponseWriter) ReadFrom(reader io.Reader) (int64, error) {
-	if !w.wroteHeader {
-		if w.defaultStatus > 0 {
-			w.WriteHeader(w.defaultStatus)
-		} else {
-			w.wroteHeader = true
-		}
-	}
-	if readFrom, ok := w.ResponseWriter.(io.ReaderFrom); ok {
-		return readFrom.ReadFrom(reader)
-	}
-	return io.Copy(w.ResponseWriter, reader)
-}
-
-// cancellableWriter wraps an http.ResponseWriter and silently discards all writes
-// after cancel() is called. This is used to safely detach a goroutine that is still
-// executing ASP (typically stuck inside a blocking CGO/COM call) from the real
-// http.ResponseWriter after the script timeout has fired.
-type cancellableWriter struct {
-	mu       sync.Mutex
-	inner    http.ResponseWriter
-	canceled bool
-}
-
-func newCancellableWriter(w http.ResponseWriter) *cancellableWriter {
-	return &cancellableWriter{inner: w}
+	http.ServeFile(w, r, filePath)
 }
 
-func (c *cancellableWriter) Header() http.Header {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.canceled {
-		return make(http.Header)
-	}
-	return c.inner.Header()
+func executeASP(w http.ResponseWriter, r *http.Request, filePath string) {
+	executeASPWithStatus(w, r, filePath, 0)
 }
 
-func (c *cancellableWriter) Write(p []byte) (int, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.canceled {
-		return len(p), nil
+// admitASPRequest waits for a VM pool slot under the admission control limits. ASP
+// error pages never wait, so an overloaded pool cannot queue its own 503 page.
+func admitASPRequest(r *http.Request, errorPage bool) (axonhandler.Ticket, error) {
+	if errorPage {
+		return admission.AdmitErrorPage(r)
 	}
-	return c.inner.Write(p)
+	return admission.Admit(r)
 }
 
-func (c *cancellableWriter) WriteHeader(status int) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.canceled {
+// rejectASPRequest answers a request refused by admission control with 503 and Retry-After.
+func rejectASPRequest(w http.ResponseWriter, r *http.Request, err error, defaultStatus int) {
+	if errors.Is(err, context.Canceled) {
 		return
 	}
-	c.inner.WriteHeader(status)
-}
-
-func (c *cancellableWriter) cancel() {
-	c.mu.Lock()
-	c.canceled = true
-	c.mu.Unlock()
-}
-
-func executeASP(w http.ResponseWriter, r *http.Request, filePath string) {
-	executeASPWithStatus(w, r, filePath, 0)
-}
-
-// resolveRequestScriptTimeout returns the effective timeout in seconds for one request,
-// preferring the current ASP Server.ScriptTimeout value over the bootstrap fallback.
-func resolveRequestScriptTimeout(host axonvm.ASPHostEnvironment, fallback int) int {
-	if host != nil {
-		if server := host.Server(); server != nil {
-			timeout := server.GetScriptTimeout()
-			if timeout > 0 {
-				return timeout
-			}
-		}
+	if defaultStatus > 0 {
+		http.Error(w, http.StatusText(defaultStatus), defaultStatus)
+		return
 	}
-	if fallback > 0 {
-		return fallback
+	if retryAfter := admission.RetryAfter(); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
 	}
-	return 60
+	serveErrorPageWithSubStatus(w, r, http.StatusServiceUnavailable, axonadmission.SubStatus(err))
 }
 
-// executeASPWithStatus compiles and executes an ASP file, running vm.Run() in a
-// goroutine so that a blocking CGO/COM call (e.g. OLE ADODB.Execute) cannot hold
-// the HTTP handler indefinitely. If the goroutine does not complete within
-// ScriptTimeout seconds the handler cancels the response writer, writes a 503 to
-// the client, and returns. The goroutine continues until the CGO call unblocks,
-// then its deferred CleanupRequestResources drains OLE objects.
+// executeASPWithStatus runs an ASP file through the shared handler with the site,
+// admission control, error pages and debug settings of the server.
 func executeASPWithStatus(w http.ResponseWriter, r *http.Request, filePath string, defaultStatus int) {
-	single := newSingleHeaderResponseWriter(w, defaultStatus)
-	cw := newCancellableWriter(single)
-	host := NewWebHost(cw, r)
-
-	cache := scriptCache
-	if cache == nil {
-		cache = axonvm.NewScriptCache(axonvm.BytecodeCacheDisabled, filepath.Join(TempDir, "cache"), 1)
-	}
-	program, err := cache.LoadOrCompileWithOptions(filePath, axonvm.ScriptCompileOptions{IncludeSiteRoot: host.Server().MapPath("/")})
-	if err != nil {
-		aspErr := axonvm.CompilerErrorToASPError(err, filePath)
-		host.Server().SetLastError(aspErr)
-		axonvm.LogASPProcessedError(aspErr, "server.executeASP.compile")
-		if !DebugASP {
-			if isErrorPageHandlerPath(filePath) {
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-				return
-			}
-			serveErrorPage(w, r, http.StatusInternalServerError)
-			return
-		}
-		renderClassicASPDebugError(w, http.StatusInternalServerError, "Compilation Error", aspErr)
-		return
-	}
-
-	vm := axonvm.AcquireVMFromCachedProgram(program)
-	vm.SetHost(host)
-
-	timeoutSec := resolveRequestScriptTimeout(host, ScriptTimeout)
-
-	type vmResult struct{ err error }
-	done := make(chan vmResult, 1)
-	go func() {
-		defer vm.Release()
-		runErr := func() (err error) {
-			defer func() {
-				if recovered := recover(); recovered != nil {
-					err = fmt.Errorf("panic recovered in vm.Run: %v", recovered)
-				}
-			}()
-			return vm.Run()
-		}()
-		done <- vmResult{err: runErr}
-	}()
-
-	start := time.Now()
-	watchdog := time.NewTicker(250 * time.Millisecond)
-	defer watchdog.Stop()
-
-	for {
-		select {
-		case res := <-done:
-			if res.err != nil {
-				aspErr := axonvm.RuntimeErrorToASPError(res.err, filePath)
-				host.Server().SetLastError(aspErr)
-				axonvm.LogASPProcessedError(aspErr, "server.executeASP.runtime")
-				if !DebugASP {
-					if isErrorPageHandlerPath(filePath) {
-						http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-						return
-					}
-					serveErrorPage(w, r, http.StatusInternalServerError)
-					return
-				}
-				renderClassicASPDebugError(w, http.StatusInternalServerError, "Runtime Error", aspErr)
-				return
-			}
-			host.PersistSession()
-			host.Response().Flush()
-			host.Response().ReleaseBuffer()
-			return
+	newASPHandler().ExecuteFile(w, r, filePath, defaultStatus)
+}
 
-		case <-watchdog.C:
-			effectiveTimeout := resolveRequestScriptTimeout(host, timeoutSec)
-			if time.Since(start) >= time.Duration(effectiveTimeout)*time.Second {
-				cw.cancel()
-				timeoutErr := fmt.Errorf("script timeout reached after %ds", effectiveTimeout)
+// newASPHandler returns the page handler for the current configuration. It is built
+// per request because DebugASP and the sites change when the configuration reloads.
+func newASPHandler() *axonhandler.Handler {
+	return axonhandler.New(axonhandler.Options{
+		Debug:     DebugASP,
+		LogSource: "server",
+		Host:      axonhandler.HostOptions{Application: sharedApplication},
+		Hooks: axonhandler.Hooks{
+			Prepare: func(r *http.Request, opts *axonhandler.HostOptions) {
+				*opts = webHostOptions(r)
+			},
+			Admit:    admitASPRequest,
+			Rejected: rejectASPRequest,
+			Timeout: func(w http.ResponseWriter, r *http.Request, timeout int, filePath string) {
 				respondInternalHTTPError(
 					w,
 					axonvm.ErrScriptTimeoutDetachedGoroutine,
-					timeoutErr,
-					fmt.Sprintf("Detached blocked ASP execution goroutine after script timeout (%ds).", effectiveTimeout),
+					fmt.Errorf("script timeout reached after %ds", timeout),
+					fmt.Sprintf("Detached blocked ASP execution goroutine after script timeout (%ds).", timeout),
 					filePath,
 				)
-				return
-			}
-		}
-	}
+			},
+			Error: serveErrorPage,
+		},
+	})
 }
 
-// isErrorPageHandlerPath reports whether the current execution target is an error page handler itself.
-func isErrorPageHandlerPath(filePath string) bool {
-	errorDirAbs, err := filepath.Abs(DefaultErrorPagesDirectory)
-	if err != nil {
-		return false
-	}
-
-	fileAbs, err := filepath.Abs(filePath)
-	if err != nil {
-		return false
-	}
-
-	if filepath.Dir(fileAbs) != errorDirAbs {
-		return false
-	}
-
-	name := strings.ToLower(filepath.Base(fileAbs))
-	return strings.HasSuffix(name, ".asp") || strings.HasSuffix(name, ".html")
+// serveErrorPage serves configured error pages using .asp or .html handlers for a given HTTP status code.
+func serveErrorPage(w http.ResponseWriter, r *http.Request, statusCode int) {
+	serveErrorPageWithSubStatus(w, r, statusCode, 0)
 }
 
-// renderClassicASPDebugError renders an ASP/VBScript-style debug page while preserving HTTP status.
-func renderClassicASPDebugError(w http.ResponseWriter, statusCode int, stage string, err *asp.ASPError) {
-	if err == nil {
-		http.Error(w, http.StatusText(statusCode), statusCode)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(statusCode)
-
-	source := html.EscapeString(strings.TrimSpace(err.Source))
-	if source == "" {
-		source = "VBScript runtime"
+// serveErrorPageWithSubStatus writes an error page for an IIS status such as 404.7. The
+// sub-status is recorded in the access log and shown by the built-in fallback page; custom
+// error pages are selected by the main status code, like IIS httpErrors without subStatusCode.
+func serveErrorPageWithSubStatus(w http.ResponseWriter, r *http.Request, statusCode int, subStatus int) {
+	if subStatus > 0 {
+		axonaccesslog.SetSubStatus(r, subStatus)
 	}
-	description := html.EscapeString(strings.TrimSpace(err.Description))
-	if description == "" {
-		description = "Unknown runtime error"
-	}
-	fileName := html.EscapeString(strings.TrimSpace(err.File))
-	if fileName == "" {
-		fileName = "unknown"
-	}
-
-	fmt.Fprintf(w, "<!doctype html><html><head><meta charset=\"utf-8\"><meta name=\"viewport\" content=\"width=device-width,initial-scale=1\"><title>500 - Internal Server Error - AxonASP Server</title><style>body{margin:0;background:#f4f4f4;font-family:\"IBM Plex Sans\",Helvetica,sans-serif;color:#161616;font-size:13px}#h{height:60px;padding:0 15px;font-size:24px;display:flex;align-items:center;font-weight:600;border-bottom:1px solid #d9d9d9;background:#f4f4f4}.shell{padding:40px 20px;display:flex;justify-content:center}.card{background:#fff;border:1px solid #d9d9d9;max-width:760px;width:100%%;padding:28px;box-shadow:0 10px 20px rgba(22,22,22,.06)}h1{margin:0 0 16px;font-size:24px;border-bottom:1px solid #d9d9d9;padding-bottom:8px}p{margin:0 0 12px}table{width:100%%;border-collapse:collapse;border:1px solid #d9d9d9;margin:14px 0}td{border:1px solid #d9d9d9;padding:7px 10px;font-size:12px}td.k{width:120px;background:#f8f8f8;font-weight:600}.ft{margin-top:24px;border-top:1px solid #d9d9d9;padding-top:10px;font-size:11px;color:#525252}</style></head><body><div id=\"h\">❖ AxonASP Server</div><div class=\"shell\"><div class=\"card\"><h1>Application error</h1><p><b>%s error '%08X'</b></p><p>%s</p><table><tr><td class=\"k\">File</td><td>%s</td></tr><tr><td class=\"k\">Line</td><td>%d</td></tr><tr><td class=\"k\">Column</td><td>%d</td></tr><tr><td class=\"k\">Stage</td><td>%s</td></tr></table><div class=\"ft\">G3Pix ❖ AxonASP</div></div></div></body></html>", source, uint32(int32(err.Number)), description, fileName, err.Line, err.Column, html.EscapeString(stage))
-}
-
-// serveErrorPage serves configured error pages using .asp or .html handlers for a given HTTP status code.
-func serveErrorPage(w http.ResponseWriter, r *http.Request, statusCode int) {
-	if activeWebConfig != nil {
-		if customError, ok := activeWebConfig.GetCustomError(statusCode); ok {
+	site := siteFromRequest(r)
+	if webConfig := site.WebConfig(); webConfig != nil {
+		if customError, ok := webConfig.GetCustomError(statusCode); ok {
 			if serveWebConfigCustomError(w, r, statusCode, customError) {
 				return
 			}
 		}
 	}
 
-	aspPagePath := filepath.Join(DefaultErrorPagesDirectory, fmt.Sprintf("%d.asp", statusCode))
+	aspPagePath := filepath.Join(site.ErrorPagesDir(), fmt.Sprintf("%d.asp", statusCode))
 	if pageInfo, err := os.Stat(aspPagePath); err == nil && !pageInfo.IsDir() {
 		executeASPWithStatus(w, r, aspPagePath, statusCode)
 		return
 	}
 
-	htmlPagePath := filepath.Join(DefaultErrorPagesDirectory, fmt.Sprintf("%d.html", statusCode))
+	htmlPagePath := filepath.Join(site.ErrorPagesDir(), fmt.Sprintf("%d.html", statusCode))
 	if pageInfo, err := os.Stat(htmlPagePath); err == nil && !pageInfo.IsDir() {
-		serveStaticFileWithMIME(newSingleHeaderResponseWriter(w, statusCode), r, htmlPagePath)
+		serveStaticFileWithMIME(axonhandler.NewSingleHeaderWriter(w, statusCode), r, htmlPagePath)
 		return
 	}
 
+	if subStatus > 0 {
+		http.Error(w, fmt.Sprintf("%s (%d.%d)", http.StatusText(statusCode), statusCode, subStatus), statusCode)
+		return
+	}
 	http.Error(w, http.StatusText(statusCode), statusCode)
 }
 
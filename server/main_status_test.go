@@ -21,6 +21,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -28,6 +29,7 @@ import (
 	"strings"
 	"testing"
 
+	"g3pix.com.br/axonasp/axonadmission"
 	"g3pix.com.br/axonasp/axonvm"
 )
 
@@ -93,31 +95,6 @@ func TestHandleRequestServesStaticPNG(t *testing.T) {
 	}
 }
 
-// TestSingleHeaderResponseWriterOverrides200WithDefault verifies that configured
-// default error statuses are not downgraded to 200 by wrapped handlers.
-func TestSingleHeaderResponseWriterOverrides200WithDefault(t *testing.T) {
-	rec := httptest.NewRecorder()
-	w := newSingleHeaderResponseWriter(rec, http.StatusNotFound)
-
-	w.WriteHeader(http.StatusOK)
-
-	if rec.Code != http.StatusNotFound {
-		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
-	}
-}
-
-// TestSingleHeaderResponseWriterPreservesNon200 verifies explicit non-200 statuses remain intact.
-func TestSingleHeaderResponseWriterPreservesNon200(t *testing.T) {
-	rec := httptest.NewRecorder()
-	w := newSingleHeaderResponseWriter(rec, http.StatusNotFound)
-
-	w.WriteHeader(http.StatusInternalServerError)
-
-	if rec.Code != http.StatusInternalServerError {
-		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
-	}
-}
-
 // TestServeErrorPageHTMLPreservesStatus verifies static HTML error handlers return
 // the requested HTTP status code instead of implicit 200.
 func TestServeErrorPageHTMLPreservesStatus(t *testing.T) {
@@ -238,3 +215,57 @@ function BrokenCompileBlock() {
 		t.Fatalf("unexpected VBScript compilation header in JScript debug output: %q", body)
 	}
 }
+
+// TestHandleRequestAdmissionQueueFullReturns503 verifies a request that finds every VM pool
+// slot busy and no queue room receives 503 with Retry-After instead of blocking.
+func TestHandleRequestAdmissionQueueFullReturns503(t *testing.T) {
+	useTestSessionDir(t)
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "busy.asp"), []byte(`<% Response.Write "ok" %>`), 0o644); err != nil {
+		t.Fatalf("write page: %v", err)
+	}
+
+	originalRootDir := RootDir
+	originalExecuteAsASP := ExecuteAsASPExtensions
+	originalWebConfig := activeWebConfig
+	originalErrorDir := DefaultErrorPagesDirectory
+	originalCache := scriptCache
+	originalAdmission := admission
+	defer func() {
+		RootDir = originalRootDir
+		ExecuteAsASPExtensions = originalExecuteAsASP
+		activeWebConfig = originalWebConfig
+		DefaultErrorPagesDirectory = originalErrorDir
+		scriptCache = originalCache
+		admission = originalAdmission
+		axonvm.SetVMPoolSizeLimit(0)
+	}()
+
+	RootDir = root
+	ExecuteAsASPExtensions = []string{".asp"}
+	activeWebConfig = nil
+	DefaultErrorPagesDirectory = filepath.Join(root, "error-pages")
+	scriptCache = axonvm.NewScriptCache(axonvm.BytecodeCacheDisabled, filepath.Join(root, "cache"), 1)
+	admission = axonadmission.New(axonadmission.Config{Enabled: true, RetryAfterSeconds: 7})
+	axonvm.SetVMPoolSizeLimit(1)
+
+	held, err := axonvm.ReserveVMPoolSlot(context.Background(), axonvm.VMPoolPriorityNormal, 0, 0)
+	if err != nil {
+		t.Fatalf("reserve the only slot: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "http://example.local/busy.asp", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "7" {
+		t.Fatalf("expected Retry-After 7, got %q", got)
+	}
+
+	held.Release()
+	rec = httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "http://example.local/busy.asp", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Fatalf("expected the page once the slot is free, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,520 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonaccesslog"
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonoffline"
+	"g3pix.com.br/axonasp/axonvm"
+	"g3pix.com.br/axonasp/axonvm/asp"
+	"github.com/spf13/viper"
+)
+
+// SiteConfigs stores the [[server.sites]] entries read from the configuration file.
+var SiteConfigs = []SiteConfig{}
+
+// activeSiteRouter routes requests to virtual hosts. It stays nil when no sites are configured.
+var activeSiteRouter *siteRouter
+
+// SiteConfig maps one [[server.sites]] configuration entry.
+// Unset list and bool options inherit the values of the [server] section.
+type SiteConfig struct {
+	Name                       string                   `mapstructure:"name"`
+	Hosts                      []string                 `mapstructure:"hosts"`
+	WebRoot                    string                   `mapstructure:"web_root"`
+	Default                    bool                     `mapstructure:"default"`
+	DefaultPages               []string                 `mapstructure:"default_pages"`
+	BlockedExtensions          []string                 `mapstructure:"blocked_extensions"`
+	BlockedFiles               []string                 `mapstructure:"blocked_files"`
+	BlockedDirs                []string                 `mapstructure:"blocked_dirs"`
+	DefaultErrorPagesDirectory string                   `mapstructure:"default_error_pages_directory"`
+	EnableWebConfig            *bool                    `mapstructure:"enable_webconfig"`
+	EnableDirectoryListing     *bool                    `mapstructure:"enable_directory_listing"`
+	SessionCookieName          string                   `mapstructure:"session_cookie_name"`
+	SessionCookieSecure        *bool                    `mapstructure:"session_cookie_secure"`
+	SessionCookieSameSite      string                   `mapstructure:"session_cookie_samesite"`
+	Applications               []ApplicationConfig      `mapstructure:"applications"`
+	VirtualDirectories         []VirtualDirectoryConfig `mapstructure:"virtual_directories"`
+}
+
+// Site stores the isolated runtime state of one virtual host: its web root, Application,
+// global.asa, script cache, routing lists and error pages. A nil *Site represents the
+// legacy single-site configuration from the [server] section, so every accessor falls
+// back to the package-level settings when called on nil.
+type Site struct {
+	ID                     int
+	Name                   string
+	Hosts                  []string
+	RootDir                string
+	IsDefault              bool
+	DefaultPages           []string
+	BlockedExtensions      []string
+	BlockedFiles           []string
+	BlockedDirs            []string
+	ErrorPagesDirectory    string
+	EnableWebConfig        bool
+	EnableDirectoryListing bool
+	SessionCookie          axonhandler.SessionCookieConfig
+
+	blockedDirPrefixes []string
+	apps               *applicationSet
+	application        *asp.Application
+	globalASA          *axonvm.GlobalASA
+	scriptCache        *axonvm.ScriptCache
+	offline            *axonoffline.Monitor
+	webConfig          *WebConfigProcessor
+	directoryListing   *DirectoryListingRenderer
+}
+
+// siteContextKey stores the resolved *Site in the request context.
+type siteContextKey struct{}
+
+// loadSiteConfigs reads the [[server.sites]] array of tables.
+func loadSiteConfigs(v *viper.Viper) {
+	SiteConfigs = SiteConfigs[:0]
+	if v == nil || !v.IsSet("server.sites") {
+		return
+	}
+	var configs []SiteConfig
+	if err := v.UnmarshalKey("server.sites", &configs); err != nil {
+		log.Printf("Warning: Failed to read [[server.sites]] configuration: %v\n", err)
+		return
+	}
+	SiteConfigs = configs
+}
+
+// newSiteFromConfig builds one site from its configuration, inheriting [server] defaults.
+// id is the 1-based position of the site in the configuration, used in APPL_MD_PATH.
+func newSiteFromConfig(cfg SiteConfig, id int) (*Site, error) {
+	name := strings.TrimSpace(cfg.Name)
+	if name == "" {
+		return nil, fmt.Errorf("site without a name")
+	}
+	rootDir := strings.TrimSpace(cfg.WebRoot)
+	if rootDir == "" {
+		return nil, fmt.Errorf("site %q has no web_root", name)
+	}
+
+	site := &Site{
+		ID:                     id,
+		Name:                   name,
+		RootDir:                rootDir,
+		IsDefault:              cfg.Default,
+		DefaultPages:           DefaultPages,
+		BlockedExtensions:      BlockedExtensions,
+		BlockedFiles:           BlockedFiles,
+		BlockedDirs:            BlockedDirs,
+		ErrorPagesDirectory:    DefaultErrorPagesDirectory,
+		EnableWebConfig:        EnableWebConfig,
+		EnableDirectoryListing: EnableDirectoryListing,
+		SessionCookie:          SessionCookieConfig,
+		application:            asp.NewApplication(),
+		globalASA:              axonvm.NewGlobalASA(),
+	}
+	for _, hostName := range cfg.Hosts {
+		if normalized := normalizeSiteHostName(hostName); normalized != "" && !slices.Contains(site.Hosts, normalized) {
+			site.Hosts = append(site.Hosts, normalized)
+		}
+	}
+	if len(cfg.DefaultPages) > 0 {
+		site.DefaultPages = cfg.DefaultPages
+	}
+	if len(cfg.BlockedExtensions) > 0 {
+		site.BlockedExtensions = normalizeExtensions(cfg.BlockedExtensions)
+	}
+	if len(cfg.BlockedFiles) > 0 {
+		site.BlockedFiles = normalizeNames(cfg.BlockedFiles)
+	}
+	if len(cfg.BlockedDirs) > 0 {
+		site.BlockedDirs = cfg.BlockedDirs
+	}
+	if errorPagesDir := strings.TrimSpace(cfg.DefaultErrorPagesDirectory); errorPagesDir != "" {
+		site.ErrorPagesDirectory = errorPagesDir
+	}
+	if cfg.EnableWebConfig != nil {
+		site.EnableWebConfig = *cfg.EnableWebConfig
+	}
+	if cfg.EnableDirectoryListing != nil {
+		site.EnableDirectoryListing = *cfg.EnableDirectoryListing
+	}
+	if cookieName := strings.TrimSpace(cfg.SessionCookieName); cookieName != "" {
+		site.SessionCookie.Name = cookieName
+	}
+	if cfg.SessionCookieSecure != nil {
+		site.SessionCookie.Secure = *cfg.SessionCookieSecure
+	}
+	if sameSite := strings.ToLower(strings.TrimSpace(cfg.SessionCookieSameSite)); sameSite != "" {
+		site.SessionCookie.SameSite = sameSite
+	}
+	if err := site.SessionCookie.Validate(); err != nil {
+		return nil, fmt.Errorf("site %q: %w", name, err)
+	}
+	site.blockedDirPrefixes = buildBlockedDirPrefixes(site.BlockedDirs)
+	apps, err := newApplicationSet(site.RootDir, site.ID, cfg.Applications, cfg.VirtualDirectories)
+	if err != nil {
+		return nil, fmt.Errorf("site %q: %w", name, err)
+	}
+	site.apps = apps
+	return site, nil
+}
+
+// normalizeSiteHostName lowercases a host name and strips any port suffix.
+func normalizeSiteHostName(hostName string) string {
+	hostName = strings.ToLower(strings.TrimSpace(hostName))
+	if hostName == "" {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(hostName); err == nil {
+		hostName = host
+	}
+	return strings.TrimSuffix(hostName, ".")
+}
+
+// siteFromRequest returns the site attached to the request, or nil for the legacy site.
+func siteFromRequest(r *http.Request) *Site {
+	if r == nil {
+		return nil
+	}
+	site, _ := r.Context().Value(siteContextKey{}).(*Site)
+	return site
+}
+
+// withSite returns a shallow request copy bound to one site.
+func withSite(r *http.Request, site *Site) *http.Request {
+	if site == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), siteContextKey{}, site))
+}
+
+// Root returns the web root directory of the site.
+func (s *Site) Root() string {
+	if s == nil {
+		return RootDir
+	}
+	return s.RootDir
+}
+
+// DefaultDocuments returns the ordered default page list of the site.
+func (s *Site) DefaultDocuments() []string {
+	if s == nil {
+		return DefaultPages
+	}
+	return s.DefaultPages
+}
+
+// ErrorPagesDir returns the directory holding the site's default error pages.
+func (s *Site) ErrorPagesDir() string {
+	if s == nil {
+		return DefaultErrorPagesDirectory
+	}
+	return s.ErrorPagesDirectory
+}
+
+// Application returns the isolated Application object of the site.
+func (s *Site) Application() *asp.Application {
+	if s == nil {
+		return sharedApplication
+	}
+	return s.application
+}
+
+// GlobalASA returns the global.asa state of the site.
+func (s *Site) GlobalASA() *axonvm.GlobalASA {
+	if s == nil {
+		return axonvm.GetGlobalASA()
+	}
+	return s.globalASA
+}
+
+// SessionCookieSettings returns the session cookie settings of the site.
+func (s *Site) SessionCookieSettings() axonhandler.SessionCookieConfig {
+	if s == nil {
+		return SessionCookieConfig
+	}
+	return s.SessionCookie
+}
+
+// Applications returns the virtual directories and nested applications of the site.
+func (s *Site) Applications() *applicationSet {
+	if s == nil {
+		return legacyApplications
+	}
+	return s.apps
+}
+
+// ScriptCache returns the bytecode cache of the site.
+func (s *Site) ScriptCache() *axonvm.ScriptCache {
+	if s == nil {
+		return scriptCache
+	}
+	return s.scriptCache
+}
+
+// WebConfig returns the compiled web.config of the site, or nil when disabled.
+func (s *Site) WebConfig() *WebConfigProcessor {
+	if s == nil {
+		return activeWebConfig
+	}
+	return s.webConfig
+}
+
+// DirectoryListing returns the listing renderer when directory listing is enabled for the site.
+func (s *Site) DirectoryListing() *DirectoryListingRenderer {
+	if s == nil {
+		if !EnableDirectoryListing {
+			return nil
+		}
+		return directoryListingRenderer
+	}
+	if !s.EnableDirectoryListing {
+		return nil
+	}
+	return s.directoryListing
+}
+
+// isBlockedExtension reports whether a file extension is blocked for the site.
+func (s *Site) isBlockedExtension(ext string) bool {
+	if s == nil {
+		return isBlockedExtension(ext)
+	}
+	return ext != "" && slices.Contains(s.BlockedExtensions, strings.ToLower(ext))
+}
+
+// isBlockedFile reports whether a base file name is blocked for the site.
+func (s *Site) isBlockedFile(baseName string) bool {
+	if s == nil {
+		return isBlockedFile(baseName)
+	}
+	return baseName != "" && slices.Contains(s.BlockedFiles, strings.ToLower(baseName))
+}
+
+// isBlockedDirectory reports whether an absolute path lies in one of the site's blocked directories.
+func (s *Site) isBlockedDirectory(absPath string) bool {
+	if s == nil {
+		return isBlockedDirectory(absPath)
+	}
+	return pathInBlockedPrefixes(absPath, s.blockedDirPrefixes)
+}
+
+// Start prepares the site's cache and routing helpers, then starts the application through
+// its app_offline.htm monitor, which loads global.asa and runs Application_OnStart.
+func (s *Site) Start() error {
+	if _, err := os.Stat(s.RootDir); os.IsNotExist(err) {
+		axonvm.ReportInternalError(axonvm.ErrRootDirectoryDoesNotExist, err, "Creating missing site root directory.", s.RootDir, 0)
+		if mkdirErr := os.MkdirAll(s.RootDir, 0o755); mkdirErr != nil {
+			return fmt.Errorf("create site root %s: %w", s.RootDir, mkdirErr)
+		}
+	}
+
+	cacheRoot, err := filepath.Abs(s.RootDir)
+	if err != nil {
+		cacheRoot = s.RootDir
+	}
+	s.scriptCache = axonvm.NewScriptCache(
+		axonvm.ParseBytecodeCacheMode(BytecodeCachingMode),
+		filepath.Join(TempDir, "cache", "sites", sanitizeSiteName(s.Name)),
+		CacheMaxSizeMB,
+	)
+	s.scriptCache.SetEngineConfig(ServerEngineMode, ExecuteAsASPExtensions, ExecuteAsVBScriptExtensions, ExecuteAsJavaScriptExtensions)
+	s.scriptCache.SetWatchedExtensions(ExecuteAsASPExtensions)
+	if err := s.scriptCache.StartInvalidator(append([]string{cacheRoot}, virtualDirectoryRoots(s.apps.VirtualDirectories())...)); err != nil {
+		log.Printf("Warning: Failed to start bytecode invalidator for site %s: %v\n", s.Name, err)
+	}
+
+	if s.EnableWebConfig {
+		processor, err := NewWebConfigProcessor(s.RootDir)
+		if err != nil {
+			log.Printf("Warning: Failed to load web.config for site %s, using default routing: %v\n", s.Name, err)
+		} else {
+			s.webConfig = processor
+		}
+	}
+	if s.EnableDirectoryListing {
+		renderer, err := newDirectoryListingRendererWithFilters(s.RootDir, DirectoryListingTemplate, s.BlockedFiles, s.BlockedDirs, s.BlockedExtensions, s.blockedDirPrefixes)
+		if err != nil {
+			log.Printf("Warning: Failed to load directory listing template for site %s, disabling listing: %v\n", s.Name, err)
+		} else {
+			s.directoryListing = renderer
+		}
+	}
+
+	s.offline = newOfflineMonitor(s)
+	startOfflineMonitor(s, s.offline)
+	return nil
+}
+
+// Stop runs Application_OnEnd and releases the site's file watchers.
+func (s *Site) Stop() {
+	if s.offline != nil {
+		s.offline.Stop()
+	} else {
+		stopSiteApplication(s)
+	}
+	if s.scriptCache != nil {
+		s.scriptCache.StopInvalidator()
+	}
+}
+
+// newSiteEventHost creates a host with no client connection for global.asa application events.
+func newSiteEventHost(site *Site) *WebHost {
+	return NewWebHost(axonhandler.DiscardWriter{}, siteEventRequest(site))
+}
+
+// siteEventRequest returns the request without a client connection of the site events.
+func siteEventRequest(site *Site) *http.Request {
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	return withSite(req, site)
+}
+
+// sanitizeSiteName converts a site name into a safe directory name.
+func sanitizeSiteName(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			builder.WriteRune(r)
+			continue
+		}
+		builder.WriteByte('_')
+	}
+	if builder.Len() == 0 {
+		return "site"
+	}
+	return builder.String()
+}
+
+// siteRouter maps Host header values to sites.
+type siteRouter struct {
+	sites     []*Site
+	exact     map[string]*Site
+	wildcards []siteWildcard
+	fallback  *Site
+}
+
+// siteWildcard maps a "*.example.com" host pattern to one site.
+type siteWildcard struct {
+	suffix string
+	site   *Site
+}
+
+// newSiteRouter indexes every site host name and selects the fallback site.
+// When no site is marked as default, unknown hosts are served by the [server] web root.
+func newSiteRouter(sites []*Site) (*siteRouter, error) {
+	router := &siteRouter{sites: sites, exact: make(map[string]*Site)}
+	for _, site := range sites {
+		for _, hostName := range site.Hosts {
+			if strings.HasPrefix(hostName, "*.") {
+				router.wildcards = append(router.wildcards, siteWildcard{suffix: hostName[1:], site: site})
+				continue
+			}
+			if existing, ok := router.exact[hostName]; ok && existing != site {
+				return nil, fmt.Errorf("host %q is bound to both site %q and site %q", hostName, existing.Name, site.Name)
+			}
+			router.exact[hostName] = site
+		}
+	}
+	for _, site := range sites {
+		if site.IsDefault && router.fallback == nil {
+			router.fallback = site
+		}
+	}
+	// Longer wildcard suffixes must win over shorter ones.
+	slices.SortFunc(router.wildcards, func(a, b siteWildcard) int { return len(b.suffix) - len(a.suffix) })
+	return router, nil
+}
+
+// resolve returns the site bound to one Host header value, or the fallback site.
+func (r *siteRouter) resolve(hostHeader string) *Site {
+	if r == nil {
+		return nil
+	}
+	hostName := normalizeSiteHostName(hostHeader)
+	if site, ok := r.exact[hostName]; ok {
+		return site
+	}
+	for _, wildcard := range r.wildcards {
+		if strings.HasSuffix(hostName, wildcard.suffix) {
+			return wildcard.site
+		}
+	}
+	return r.fallback
+}
+
+// withSiteRouting binds each request to the site selected by its Host header.
+func withSiteRouting(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router := activeSiteRouter
+		if router == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		site := router.resolve(r.Host)
+		if site != nil {
+			axonaccesslog.SetSiteName(r, site.Name)
+		}
+		next.ServeHTTP(w, withSite(r, site))
+	})
+}
+
+// startConfiguredSites builds and starts every [[server.sites]] entry and activates host routing.
+func startConfiguredSites() ([]*Site, error) {
+	if len(SiteConfigs) == 0 {
+		return nil, nil
+	}
+	sites := make([]*Site, 0, len(SiteConfigs))
+	for index, cfg := range SiteConfigs {
+		site, err := newSiteFromConfig(cfg, index+1)
+		if err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+	router, err := newSiteRouter(sites)
+	if err != nil {
+		return nil, err
+	}
+	for _, site := range sites {
+		if err := site.Start(); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Site %s: %s -> %s\n", site.Name, strings.Join(site.Hosts, ", "), site.RootDir)
+	}
+	activeSiteRouter = router
+	return sites, nil
+}
+
+// stopConfiguredSites runs Application_OnEnd for every site and stops their watchers.
+func stopConfiguredSites(sites []*Site) {
+	for _, site := range sites {
+		site.Stop()
+	}
+}
@@ -0,0 +1,228 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+// newTestSite builds one started site rooted in a temporary directory.
+func newTestSite(t *testing.T, name string, isDefault bool, hosts ...string) *Site {
+	t.Helper()
+	root := t.TempDir()
+	disabled := false
+	site, err := newSiteFromConfig(SiteConfig{
+		Name:                   name,
+		Hosts:                  hosts,
+		WebRoot:                root,
+		Default:                isDefault,
+		DefaultPages:           []string{"index.html"},
+		EnableWebConfig:        &disabled,
+		EnableDirectoryListing: &disabled,
+	}, 1)
+	if err != nil {
+		t.Fatalf("create site %s: %v", name, err)
+	}
+	if err := site.Start(); err != nil {
+		t.Fatalf("start site %s: %v", name, err)
+	}
+	t.Cleanup(site.Stop)
+	return site
+}
+
+// TestSiteRouterResolvesHosts verifies exact, wildcard, port-qualified and fallback host routing.
+func TestSiteRouterResolvesHosts(t *testing.T) {
+	originalTempDir := TempDir
+	TempDir = t.TempDir()
+	defer func() { TempDir = originalTempDir }()
+
+	shop := newTestSite(t, "shop", false, "shop.example.com", "www.shop.example.com")
+	tenants := newTestSite(t, "tenants", false, "*.tenants.example.com")
+	fallback := newTestSite(t, "fallback", true, "fallback.local")
+
+	router, err := newSiteRouter([]*Site{shop, tenants, fallback})
+	if err != nil {
+		t.Fatalf("create router: %v", err)
+	}
+
+	cases := map[string]*Site{
+		"shop.example.com":         shop,
+		"SHOP.example.com:8801":    shop,
+		"www.shop.example.com":     shop,
+		"acme.tenants.example.com": tenants,
+		"unknown.example.com":      fallback,
+		"":                         fallback,
+	}
+	for hostHeader, expected := range cases {
+		if got := router.resolve(hostHeader); got != expected {
+			t.Fatalf("host %q: expected site %q, got %+v", hostHeader, expected.Name, got)
+		}
+	}
+}
+
+// TestSiteRouterRejectsDuplicateHosts verifies one host name cannot be bound to two sites.
+func TestSiteRouterRejectsDuplicateHosts(t *testing.T) {
+	a := &Site{Name: "a", Hosts: []string{"dup.local"}}
+	b := &Site{Name: "b", Hosts: []string{"dup.local"}}
+	if _, err := newSiteRouter([]*Site{a, b}); err == nil {
+		t.Fatalf("expected duplicate host error")
+	}
+}
+
+// TestSiteRoutingServesIsolatedWebRoots verifies each Host header reaches its own web root,
+// Application object and error pages.
+func TestSiteRoutingServesIsolatedWebRoots(t *testing.T) {
+	originalTempDir := TempDir
+	originalRouter := activeSiteRouter
+	TempDir = t.TempDir()
+	defer func() {
+		TempDir = originalTempDir
+		activeSiteRouter = originalRouter
+	}()
+	useTestSessionDir(t)
+
+	siteA := newTestSite(t, "a", false, "a.local")
+	siteB := newTestSite(t, "b", false, "b.local")
+	if err := os.WriteFile(filepath.Join(siteA.RootDir, "index.html"), []byte("site A"), 0o644); err != nil {
+		t.Fatalf("write site A page: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(siteB.RootDir, "index.html"), []byte("site B"), 0o644); err != nil {
+		t.Fatalf("write site B page: %v", err)
+	}
+
+	router, err := newSiteRouter([]*Site{siteA, siteB})
+	if err != nil {
+		t.Fatalf("create router: %v", err)
+	}
+	activeSiteRouter = router
+	handler := withSiteRouting(http.HandlerFunc(handleRequest))
+
+	for hostName, expected := range map[string]string{"a.local": "site A", "b.local": "site B"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://"+hostName+"/", nil)
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != expected {
+			t.Fatalf("host %s: expected 200 %q, got %d %q", hostName, expected, rec.Code, rec.Body.String())
+		}
+	}
+
+	hostA := NewWebHost(httptest.NewRecorder(), withSite(httptest.NewRequest(http.MethodGet, "http://a.local/", nil), siteA))
+	hostB := NewWebHost(httptest.NewRecorder(), withSite(httptest.NewRequest(http.MethodGet, "http://b.local/", nil), siteB))
+	if hostA.Application() == hostB.Application() {
+		t.Fatalf("expected isolated Application objects per site")
+	}
+	if hostA.Application() == GetSharedApplication() {
+		t.Fatalf("expected site Application to differ from the legacy shared Application")
+	}
+	if got := hostA.Server().MapPath("/"); filepath.Clean(got) != filepath.Clean(siteA.RootDir) {
+		t.Fatalf("expected MapPath(/) %q, got %q", siteA.RootDir, got)
+	}
+}
+
+// TestSiteSessionCookieSettings verifies that a site overrides the session
+// cookie settings and that its applications derive their cookie from the site name.
+func TestSiteSessionCookieSettings(t *testing.T) {
+	useTestSessionDir(t)
+
+	secure := true
+	site, err := newSiteFromConfig(SiteConfig{
+		Name:                  "shop",
+		WebRoot:               t.TempDir(),
+		SessionCookieName:     "SHOPSESSION",
+		SessionCookieSecure:   &secure,
+		SessionCookieSameSite: "Strict",
+		Applications:          []ApplicationConfig{{Path: "/admin"}},
+	}, 1)
+	if err != nil {
+		t.Fatalf("create site: %v", err)
+	}
+
+	for path, expected := range map[string]string{"/default.asp": "SHOPSESSION", "/admin/users.asp": "SHOPSESSION_ADMIN"} {
+		rec := httptest.NewRecorder()
+		host := NewWebHost(rec, withSite(httptest.NewRequest(http.MethodGet, "http://shop.local"+path, nil), site))
+		host.PersistSession()
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != expected || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteStrictMode {
+			t.Fatalf("%s: expected a Secure Strict %s cookie, got %+v", path, expected, cookies)
+		}
+	}
+
+	if _, err := newSiteFromConfig(SiteConfig{Name: "bad", WebRoot: t.TempDir(), SessionCookieSameSite: "sometimes"}, 2); err == nil {
+		t.Fatalf("expected an invalid SameSite mode to be rejected")
+	}
+}
+
+// TestSiteAppOfflineRestartsApplication verifies that app_offline.htm takes a site
+// offline and that removing it restarts the application with empty state.
+func TestSiteAppOfflineRestartsApplication(t *testing.T) {
+	originalTempDir := TempDir
+	TempDir = t.TempDir()
+	defer func() { TempDir = originalTempDir }()
+
+	site := newTestSite(t, "offline", true)
+	site.Application().Set("deployed", asp.NewApplicationString("old"))
+	handler := withAppOffline(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	offlinePath := filepath.Join(site.Root(), "app_offline.htm")
+	if err := os.WriteFile(offlinePath, []byte("maintenance"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitForCondition(t, "the site to go offline", site.Offline().Offline)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, withSite(httptest.NewRequest(http.MethodGet, "/", nil), site))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "maintenance" {
+		t.Fatalf("expected 503 with the offline page, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	if err := os.Remove(offlinePath); err != nil {
+		t.Fatal(err)
+	}
+	waitForCondition(t, "the site to come back", func() bool { return !site.Offline().Offline() })
+	if _, ok := site.Application().Get("deployed"); ok {
+		t.Fatal("expected the restart to clear Application.Contents")
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withSite(httptest.NewRequest(http.MethodGet, "/", nil), site))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after the restart, got %d", rec.Code)
+	}
+}
+
+// waitForCondition polls cond until it holds or the test times out.
+func waitForCondition(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,566 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"g3pix.com.br/axonasp/axonproxy"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// TLS configuration variables.
+var (
+	EnableTLS                = false
+	TLSPort                  = "8443"
+	TLSCertFile              = ""
+	TLSKeyFile               = ""
+	TLSCertificates          = []TLSCertificatePair{}
+	TLSMinVersion            = "1.2"
+	TLSClientAuth            = "none"
+	TLSClientCAFile          = ""
+	TLSRedirectHTTP          = false
+	TLSReloadDebounceMs      = 500
+	EnableHTTP2              = true
+	activeCertificateStore   *certificateStore
+	activeCertificateStoreMu sync.RWMutex
+)
+
+// TLSCertificatePair stores one certificate/key file pair used for SNI selection.
+type TLSCertificatePair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// loadTLSConfig reads the TLS listener settings from the [server] section.
+func loadTLSConfig(v *viper.Viper) {
+	if v == nil {
+		return
+	}
+	EnableTLS = v.GetBool("server.enable_tls")
+	if port := v.GetInt("server.tls_port"); port > 0 {
+		TLSPort = strconv.Itoa(port)
+	}
+	TLSCertFile = strings.TrimSpace(v.GetString("server.tls_cert_file"))
+	TLSKeyFile = strings.TrimSpace(v.GetString("server.tls_key_file"))
+	if minVersion := strings.TrimSpace(v.GetString("server.tls_min_version")); minVersion != "" {
+		TLSMinVersion = minVersion
+	}
+	if clientAuth := strings.TrimSpace(v.GetString("server.tls_client_auth")); clientAuth != "" {
+		TLSClientAuth = strings.ToLower(clientAuth)
+	}
+	TLSClientCAFile = strings.TrimSpace(v.GetString("server.tls_client_ca_file"))
+	TLSRedirectHTTP = v.GetBool("server.tls_redirect_http")
+	if v.IsSet("server.enable_http2") {
+		EnableHTTP2 = v.GetBool("server.enable_http2")
+	}
+	if debounce := v.GetInt("server.tls_reload_debounce_ms"); debounce > 0 {
+		TLSReloadDebounceMs = debounce
+	}
+
+	TLSCertificates = TLSCertificates[:0]
+	var pairs []map[string]any
+	if err := v.UnmarshalKey("server.tls_certificates", &pairs); err == nil {
+		for _, pair := range pairs {
+			certFile := strings.TrimSpace(fmt.Sprint(pair["cert_file"]))
+			keyFile := strings.TrimSpace(fmt.Sprint(pair["key_file"]))
+			if certFile == "" || keyFile == "" || certFile == "<nil>" || keyFile == "<nil>" {
+				continue
+			}
+			TLSCertificates = append(TLSCertificates, TLSCertificatePair{CertFile: certFile, KeyFile: keyFile})
+		}
+	}
+}
+
+// configuredTLSCertificatePairs returns the default pair followed by every SNI pair.
+func configuredTLSCertificatePairs() []TLSCertificatePair {
+	pairs := make([]TLSCertificatePair, 0, len(TLSCertificates)+1)
+	if TLSCertFile != "" && TLSKeyFile != "" {
+		pairs = append(pairs, TLSCertificatePair{CertFile: TLSCertFile, KeyFile: TLSKeyFile})
+	}
+	for _, pair := range TLSCertificates {
+		if !slices.Contains(pairs, pair) {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// loadedCertificate stores one parsed certificate with its leaf for SNI matching.
+type loadedCertificate struct {
+	certificate *tls.Certificate
+	leaf        *x509.Certificate
+}
+
+// certificateStore keeps the loaded certificates and swaps them atomically on reload.
+type certificateStore struct {
+	mu          sync.RWMutex
+	pairs       []TLSCertificatePair
+	defaultCert *loadedCertificate
+	exactNames  map[string]*loadedCertificate
+	wildcards   map[string]*loadedCertificate
+	watcher     *fsnotify.Watcher
+	stopCh      chan struct{}
+}
+
+// newCertificateStore loads every configured pair and fails when none can be read.
+func newCertificateStore(pairs []TLSCertificatePair) (*certificateStore, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no TLS certificate configured")
+	}
+	store := &certificateStore{pairs: slices.Clone(pairs)}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Reload re-reads all certificate files and replaces the active set only when the default pair loads.
+func (s *certificateStore) Reload() error {
+	exactNames := make(map[string]*loadedCertificate)
+	wildcards := make(map[string]*loadedCertificate)
+	var defaultCert *loadedCertificate
+
+	for index, pair := range s.pairs {
+		certificate, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+		if err != nil {
+			if index == 0 {
+				return fmt.Errorf("load TLS certificate %s: %w", pair.CertFile, err)
+			}
+			log.Printf("Warning: Failed to load TLS certificate %s: %v\n", pair.CertFile, err)
+			continue
+		}
+		leaf := certificate.Leaf
+		if leaf == nil && len(certificate.Certificate) > 0 {
+			leaf, _ = x509.ParseCertificate(certificate.Certificate[0])
+		}
+		loaded := &loadedCertificate{certificate: &certificate, leaf: leaf}
+		if defaultCert == nil {
+			defaultCert = loaded
+		}
+		for _, name := range certificateNames(leaf) {
+			if strings.HasPrefix(name, "*.") {
+				if _, exists := wildcards[name[2:]]; !exists {
+					wildcards[name[2:]] = loaded
+				}
+				continue
+			}
+			if _, exists := exactNames[name]; !exists {
+				exactNames[name] = loaded
+			}
+		}
+	}
+
+	if defaultCert == nil {
+		return fmt.Errorf("no TLS certificate could be loaded")
+	}
+
+	s.mu.Lock()
+	s.defaultCert = defaultCert
+	s.exactNames = exactNames
+	s.wildcards = wildcards
+	s.mu.Unlock()
+	return nil
+}
+
+// certificateNames returns the lowercase DNS names a certificate is valid for.
+func certificateNames(leaf *x509.Certificate) []string {
+	if leaf == nil {
+		return nil
+	}
+	names := make([]string, 0, len(leaf.DNSNames)+1)
+	for _, name := range leaf.DNSNames {
+		names = append(names, strings.ToLower(strings.TrimSuffix(name, ".")))
+	}
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = append(names, strings.ToLower(leaf.Subject.CommonName))
+	}
+	return names
+}
+
+// lookup resolves the certificate for one SNI server name, falling back to the default pair.
+func (s *certificateStore) lookup(serverName string) *loadedCertificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(serverName), "."))
+	if name != "" {
+		if loaded, ok := s.exactNames[name]; ok {
+			return loaded
+		}
+		if dot := strings.IndexByte(name, '.'); dot > 0 {
+			if loaded, ok := s.wildcards[name[dot+1:]]; ok {
+				return loaded
+			}
+		}
+	}
+	return s.defaultCert
+}
+
+// GetCertificate implements tls.Config.GetCertificate using SNI selection.
+func (s *certificateStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	serverName := ""
+	if hello != nil {
+		serverName = hello.ServerName
+	}
+	loaded := s.lookup(serverName)
+	if loaded == nil {
+		return nil, fmt.Errorf("no TLS certificate available for %q", serverName)
+	}
+	return loaded.certificate, nil
+}
+
+// Leaf returns the parsed certificate served for one SNI server name.
+func (s *certificateStore) Leaf(serverName string) *x509.Certificate {
+	if s == nil {
+		return nil
+	}
+	loaded := s.lookup(serverName)
+	if loaded == nil {
+		return nil
+	}
+	return loaded.leaf
+}
+
+// StartWatcher reloads certificates when any configured certificate or key file changes on disk.
+// Parent directories are watched so atomic renames performed by ACME clients are also detected.
+func (s *certificateStore) StartWatcher() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	watchedFiles := make(map[string]struct{})
+	watchedDirs := make(map[string]struct{})
+	for _, pair := range s.pairs {
+		for _, file := range []string{pair.CertFile, pair.KeyFile} {
+			absFile, absErr := filepath.Abs(file)
+			if absErr != nil {
+				continue
+			}
+			watchedFiles[absFile] = struct{}{}
+			dir := filepath.Dir(absFile)
+			if _, exists := watchedDirs[dir]; exists {
+				continue
+			}
+			if addErr := watcher.Add(dir); addErr != nil {
+				log.Printf("Warning: Failed to watch TLS certificate directory %s: %v\n", dir, addErr)
+				continue
+			}
+			watchedDirs[dir] = struct{}{}
+		}
+	}
+
+	s.watcher = watcher
+	s.stopCh = make(chan struct{})
+	go s.watchLoop(watcher, s.stopCh, watchedFiles)
+	return nil
+}
+
+// watchLoop reloads the certificates on changes reported by watcher until stopCh
+// is closed. They are passed in because StopWatcher clears s.watcher while the
+// loop may still be running.
+func (s *certificateStore) watchLoop(watcher *fsnotify.Watcher, stopCh <-chan struct{}, watchedFiles map[string]struct{}) {
+	debounce := time.Duration(TLSReloadDebounceMs) * time.Millisecond
+	var timer *time.Timer
+	var timerC <-chan time.Time
+
+	for {
+		select {
+		case <-stopCh:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			absName, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			if _, watched := watchedFiles[absName]; !watched {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Chmod) == 0 {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				timer.Reset(debounce)
+			}
+			timerC = timer.C
+		case <-timerC:
+			timerC = nil
+			if err := s.Reload(); err != nil {
+				log.Printf("Warning: TLS certificate reload failed, keeping previous certificates: %v\n", err)
+				continue
+			}
+			log.Printf("TLS certificates reloaded.\n")
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Warning: TLS certificate watcher error: %v\n", err)
+		}
+	}
+}
+
+// StopWatcher stops the certificate file watcher.
+func (s *certificateStore) StopWatcher() {
+	if s == nil || s.watcher == nil {
+		return
+	}
+	close(s.stopCh)
+	_ = s.watcher.Close()
+	s.watcher = nil
+}
+
+// setActiveCertificateStore publishes the certificate store used for CERT_SERVER_* variables.
+func setActiveCertificateStore(store *certificateStore) {
+	activeCertificateStoreMu.Lock()
+	activeCertificateStore = store
+	activeCertificateStoreMu.Unlock()
+}
+
+func getActiveCertificateStore() *certificateStore {
+	activeCertificateStoreMu.RLock()
+	defer activeCertificateStoreMu.RUnlock()
+	return activeCertificateStore
+}
+
+// buildServerTLSConfig builds the tls.Config for the HTTPS listener.
+func buildServerTLSConfig(store *certificateStore) (*tls.Config, error) {
+	config := &tls.Config{
+		GetCertificate: store.GetCertificate,
+		MinVersion:     parseTLSMinVersion(TLSMinVersion),
+	}
+
+	switch TLSClientAuth {
+	case "request":
+		config.ClientAuth = tls.RequestClientCert
+	case "require":
+		config.ClientAuth = tls.RequireAnyClientCert
+	}
+
+	if TLSClientCAFile != "" {
+		pemData, err := os.ReadFile(TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("TLS client CA file %s contains no certificates", TLSClientCAFile)
+		}
+		config.ClientCAs = pool
+		switch config.ClientAuth {
+		case tls.RequestClientCert:
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		case tls.RequireAnyClientCert:
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return config, nil
+}
+
+// parseTLSMinVersion maps "1.0".."1.3" to crypto/tls constants, defaulting to TLS 1.2.
+func parseTLSMinVersion(value string) uint16 {
+	switch strings.TrimSpace(strings.TrimPrefix(strings.ToLower(value), "tls")) {
+	case "1.0", "10":
+		return tls.VersionTLS10
+	case "1.1", "11":
+		return tls.VersionTLS11
+	case "1.3", "13":
+		return tls.VersionTLS13
+	default:
+		return tls.VersionTLS12
+	}
+}
+
+// serverProtocols returns the protocol set for one listener, enabling HTTP/2 only when allowed.
+func serverProtocols(withTLS bool) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	if withTLS && EnableHTTP2 {
+		protocols.SetHTTP2(true)
+	}
+	return protocols
+}
+
+// newHTTPSRedirectHandler redirects plain HTTP requests to the HTTPS listener.
+// Requests a trusted proxy already received over HTTPS are passed to secure.
+func newHTTPSRedirectHandler(secure http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if axonproxy.IsHTTPS(r) {
+			secure.ServeHTTP(w, r)
+			return
+		}
+		host := r.Host
+		if splitHost, _, err := net.SplitHostPort(host); err == nil {
+			host = splitHost
+		}
+		if TLSPort != "443" {
+			host = net.JoinHostPort(host, TLSPort)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+// tlsServerVariables builds the HTTPS_* and CERT_* server variables for one request.
+// Plain HTTP requests receive the same keys with IIS-compatible empty values, and
+// requests a trusted proxy received over HTTPS report HTTPS=on without certificate details.
+func tlsServerVariables(r *http.Request) [][2]string {
+	if r == nil || r.TLS == nil {
+		https, secure := "off", "0"
+		if axonproxy.IsHTTPS(r) {
+			https, secure = "on", "1"
+		}
+		return [][2]string{
+			{"HTTPS", https},
+			{"HTTPS_KEYSIZE", ""},
+			{"HTTPS_SECRETKEYSIZE", ""},
+			{"HTTPS_SERVER_ISSUER", ""},
+			{"HTTPS_SERVER_SUBJECT", ""},
+			{"SERVER_PORT_SECURE", secure},
+			{"CERT_COOKIE", ""},
+			{"CERT_FLAGS", ""},
+			{"CERT_ISSUER", ""},
+			{"CERT_KEYSIZE", ""},
+			{"CERT_SECRETKEYSIZE", ""},
+			{"CERT_SERIALNUMBER", ""},
+			{"CERT_SERVER_ISSUER", ""},
+			{"CERT_SERVER_SUBJECT", ""},
+			{"CERT_SUBJECT", ""},
+		}
+	}
+
+	state := r.TLS
+	keySize := strconv.Itoa(cipherSuiteKeyBits(state.CipherSuite))
+	serverIssuer := ""
+	serverSubject := ""
+	serverSecretKeySize := ""
+	if leaf := getActiveCertificateStore().Leaf(state.ServerName); leaf != nil {
+		serverIssuer = leaf.Issuer.String()
+		serverSubject = leaf.Subject.String()
+		serverSecretKeySize = strconv.Itoa(publicKeyBits(leaf))
+	}
+
+	certCookie := ""
+	certFlags := ""
+	certIssuer := ""
+	certSubject := ""
+	certSerial := ""
+	if len(state.PeerCertificates) > 0 {
+		client := state.PeerCertificates[0]
+		certCookie = hex.EncodeToString(client.SubjectKeyId)
+		if certCookie == "" {
+			certCookie = strings.ToLower(hex.EncodeToString(client.SerialNumber.Bytes()))
+		}
+		certFlags = "1"
+		if len(state.VerifiedChains) == 0 {
+			certFlags = "3"
+		}
+		certIssuer = client.Issuer.String()
+		certSubject = client.Subject.String()
+		certSerial = formatCertificateSerial(client)
+	}
+
+	return [][2]string{
+		{"HTTPS", "on"},
+		{"HTTPS_KEYSIZE", keySize},
+		{"HTTPS_SECRETKEYSIZE", serverSecretKeySize},
+		{"HTTPS_SERVER_ISSUER", serverIssuer},
+		{"HTTPS_SERVER_SUBJECT", serverSubject},
+		{"SERVER_PORT_SECURE", "1"},
+		{"CERT_COOKIE", certCookie},
+		{"CERT_FLAGS", certFlags},
+		{"CERT_ISSUER", certIssuer},
+		{"CERT_KEYSIZE", keySize},
+		{"CERT_SECRETKEYSIZE", serverSecretKeySize},
+		{"CERT_SERIALNUMBER", certSerial},
+		{"CERT_SERVER_ISSUER", serverIssuer},
+		{"CERT_SERVER_SUBJECT", serverSubject},
+		{"CERT_SUBJECT", certSubject},
+	}
+}
+
+// cipherSuiteKeyBits reports the symmetric key length of a negotiated cipher suite.
+func cipherSuiteKeyBits(suite uint16) int {
+	name := tls.CipherSuiteName(suite)
+	switch {
+	case strings.Contains(name, "AES_256"), strings.Contains(name, "CHACHA20"):
+		return 256
+	case strings.Contains(name, "AES_128"):
+		return 128
+	case strings.Contains(name, "3DES"):
+		return 168
+	default:
+		return 0
+	}
+}
+
+// publicKeyBits reports the public key size of a certificate.
+func publicKeyBits(cert *x509.Certificate) int {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	default:
+		return 0
+	}
+}
+
+// formatCertificateSerial formats a serial number as IIS does: hex bytes separated by dashes.
+func formatCertificateSerial(cert *x509.Certificate) string {
+	if cert == nil || cert.SerialNumber == nil {
+		return ""
+	}
+	raw := cert.SerialNumber.Bytes()
+	parts := make([]string, len(raw))
+	for i, b := range raw {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, "-")
+}
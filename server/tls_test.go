@@ -0,0 +1,299 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"g3pix.com.br/axonasp/axonproxy"
+	"github.com/spf13/viper"
+)
+
+// writeTestCertificate writes a self-signed certificate for the given DNS names and returns its file paths.
+func writeTestCertificate(t *testing.T, dir string, name string, commonName string, dnsNames ...string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certPath, keyPath
+}
+
+// TestShippedConfigDeclaresTLSInServerSection verifies that the TLS keys of
+// the shipped axonasp.toml belong to [server], where loadTLSConfig reads them.
+func TestShippedConfigDeclaresTLSInServerSection(t *testing.T) {
+	shipped, err := os.ReadFile(filepath.Join("..", "config", "axonasp.toml"))
+	if err != nil {
+		t.Fatalf("read shipped config: %v", err)
+	}
+	enabled := strings.Replace(string(shipped), "\nenable_tls = false\n", "\nenable_tls = true\n", 1)
+	if enabled == string(shipped) {
+		t.Fatalf("expected the shipped config to declare enable_tls = false")
+	}
+	path := filepath.Join(t.TempDir(), "axonasp.toml")
+	if err := os.WriteFile(path, []byte(enabled), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	for _, key := range []string{"enable_tls", "tls_port", "tls_cert_file", "tls_redirect_http", "enable_http2"} {
+		if !v.IsSet("server." + key) {
+			t.Errorf("expected server.%s in the shipped config", key)
+		}
+		if v.IsSet("cli." + key) {
+			t.Errorf("expected %s outside the [cli] section", key)
+		}
+	}
+
+	previousEnabled, previousPort := EnableTLS, TLSPort
+	t.Cleanup(func() { EnableTLS, TLSPort = previousEnabled, previousPort })
+	loadTLSConfig(v)
+	if !EnableTLS {
+		t.Fatalf("expected enable_tls = true in the shipped config to enable TLS")
+	}
+	if TLSPort != "8443" {
+		t.Fatalf("expected tls_port 8443, got %q", TLSPort)
+	}
+}
+
+// TestCertificateStoreSelectsBySNI verifies exact, wildcard and default certificate selection.
+func TestCertificateStoreSelectsBySNI(t *testing.T) {
+	dir := t.TempDir()
+	defaultCert, defaultKey := writeTestCertificate(t, dir, "default", "default.local", "default.local")
+	siteCert, siteKey := writeTestCertificate(t, dir, "site", "site-a.local", "site-a.local")
+	wildCert, wildKey := writeTestCertificate(t, dir, "wild", "*.example.test", "*.example.test")
+
+	store, err := newCertificateStore([]TLSCertificatePair{
+		{CertFile: defaultCert, KeyFile: defaultKey},
+		{CertFile: siteCert, KeyFile: siteKey},
+		{CertFile: wildCert, KeyFile: wildKey},
+	})
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+
+	cases := map[string]string{
+		"site-a.local":     "site-a.local",
+		"SITE-A.LOCAL":     "site-a.local",
+		"app.example.test": "*.example.test",
+		"unknown.local":    "default.local",
+		"":                 "default.local",
+	}
+	for serverName, expected := range cases {
+		leaf := store.Leaf(serverName)
+		if leaf == nil || leaf.Subject.CommonName != expected {
+			t.Fatalf("server name %q: expected certificate %q, got %+v", serverName, expected, leaf)
+		}
+		certificate, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		if err != nil || certificate == nil {
+			t.Fatalf("server name %q: GetCertificate failed: %v", serverName, err)
+		}
+	}
+}
+
+// TestCertificateStoreReloadsChangedFiles verifies rewritten certificate files are picked up by the watcher.
+func TestCertificateStoreReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCertificate(t, dir, "site", "old.local", "old.local")
+
+	originalDebounce := TLSReloadDebounceMs
+	TLSReloadDebounceMs = 20
+	defer func() { TLSReloadDebounceMs = originalDebounce }()
+
+	store, err := newCertificateStore([]TLSCertificatePair{{CertFile: certPath, KeyFile: keyPath}})
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	if err := store.StartWatcher(); err != nil {
+		t.Fatalf("start watcher: %v", err)
+	}
+	defer store.StopWatcher()
+
+	writeTestCertificate(t, dir, "site", "new.local", "new.local")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if leaf := store.Leaf(""); leaf != nil && leaf.Subject.CommonName == "new.local" {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("expected certificate to reload to new.local, still serving %q", store.Leaf("").Subject.CommonName)
+}
+
+// TestCertificateStoreKeepsPreviousCertificateOnBrokenReload verifies a half-written renewal does not drop TLS.
+func TestCertificateStoreKeepsPreviousCertificateOnBrokenReload(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCertificate(t, dir, "site", "stable.local", "stable.local")
+
+	store, err := newCertificateStore([]TLSCertificatePair{{CertFile: certPath, KeyFile: keyPath}})
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	if err := os.WriteFile(certPath, []byte("broken"), 0o644); err != nil {
+		t.Fatalf("corrupt certificate: %v", err)
+	}
+	if err := store.Reload(); err == nil {
+		t.Fatalf("expected reload error for corrupted certificate")
+	}
+	if leaf := store.Leaf(""); leaf == nil || leaf.Subject.CommonName != "stable.local" {
+		t.Fatalf("expected previous certificate to stay active, got %+v", leaf)
+	}
+}
+
+// TestNewWebHostExposesTLSServerVariables verifies HTTPS and CERT_* variables reflect the TLS state.
+func TestNewWebHostExposesTLSServerVariables(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCertificate(t, dir, "site", "secure.local", "secure.local")
+	store, err := newCertificateStore([]TLSCertificatePair{{CertFile: certPath, KeyFile: keyPath}})
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	setActiveCertificateStore(store)
+	defer setActiveCertificateStore(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "https://secure.local/default.asp", nil)
+	req.TLS = &tls.ConnectionState{
+		ServerName:  "secure.local",
+		CipherSuite: tls.TLS_AES_256_GCM_SHA384,
+	}
+	host := NewWebHost(httptest.NewRecorder(), req)
+	vars := host.Request().ServerVars
+
+	if got := vars.Get("HTTPS"); got != "on" {
+		t.Fatalf("expected HTTPS on, got %q", got)
+	}
+	if got := vars.Get("HTTPS_KEYSIZE"); got != "256" {
+		t.Fatalf("expected HTTPS_KEYSIZE 256, got %q", got)
+	}
+	if got := vars.Get("SERVER_PORT_SECURE"); got != "1" {
+		t.Fatalf("expected SERVER_PORT_SECURE 1, got %q", got)
+	}
+	if got := vars.Get("SERVER_PORT"); got != "443" {
+		t.Fatalf("expected SERVER_PORT 443, got %q", got)
+	}
+	if got := vars.Get("CERT_SERVER_SUBJECT"); got != "CN=secure.local" {
+		t.Fatalf("expected CERT_SERVER_SUBJECT CN=secure.local, got %q", got)
+	}
+	if got := vars.Get("HTTPS_SECRETKEYSIZE"); got != "256" {
+		t.Fatalf("expected HTTPS_SECRETKEYSIZE 256 for P-256 key, got %q", got)
+	}
+
+	plain := NewWebHost(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://secure.local/default.asp", nil))
+	if got := plain.Request().ServerVars.Get("HTTPS"); got != "off" {
+		t.Fatalf("expected HTTPS off for plain request, got %q", got)
+	}
+	if got := plain.Request().ServerVars.Get("SERVER_PORT_SECURE"); got != "0" {
+		t.Fatalf("expected SERVER_PORT_SECURE 0 for plain request, got %q", got)
+	}
+}
+
+// TestHTTPSRedirectHandlerTargetsTLSPort verifies the plain listener redirects to the HTTPS port.
+func TestHTTPSRedirectHandlerTargetsTLSPort(t *testing.T) {
+	originalPort := TLSPort
+	TLSPort = "8443"
+	defer func() { TLSPort = originalPort }()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.local:8801/shop/cart.asp?id=7", nil)
+	newHTTPSRedirectHandler(nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected 301, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "https://example.local:8443/shop/cart.asp?id=7" {
+		t.Fatalf("unexpected redirect location %q", got)
+	}
+}
+
+// TestHTTPSRedirectHandlerServesProxiedHTTPS verifies requests a trusted proxy received
+// over HTTPS are served instead of being redirected again.
+func TestHTTPSRedirectHandlerServesProxiedHTTPS(t *testing.T) {
+	proxy, err := axonproxy.New(axonproxy.Config{HeadersEnabled: true, TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatalf("create proxy: %v", err)
+	}
+	handler := proxy.Handler(newHTTPSRedirectHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://example.local/default.asp", nil)
+	req.RemoteAddr = "10.0.0.2:40000"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the proxied HTTPS request to be served, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "http://example.local/default.asp", nil)
+	req.RemoteAddr = "192.0.2.5:40000"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected untrusted clients to be redirected, got %d", rec.Code)
+	}
+}
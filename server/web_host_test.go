@@ -26,10 +26,11 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
-	"path/filepath"
 	"strings"
 	"testing"
 
+	"g3pix.com.br/axonasp/axonauth"
+	"g3pix.com.br/axonasp/axonproxy"
 	"g3pix.com.br/axonasp/axonvm"
 	"g3pix.com.br/axonasp/axonvm/asp"
 )
@@ -69,8 +70,7 @@ func sessionCookieFromResponse(t *testing.T, recorder *httptest.ResponseRecorder
 
 // TestNewWebHostSetsSessionCookie verifies NewWebHost emits ASPSESSIONID for new sessions.
 func TestNewWebHostSetsSessionCookie(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	req := httptest.NewRequest(http.MethodGet, "http://example.local/default.asp", nil)
 	recorder := httptest.NewRecorder()
@@ -88,8 +88,7 @@ func TestNewWebHostSetsSessionCookie(t *testing.T) {
 
 // TestNewWebHostReusesExistingSession verifies cookie-bound sessions are loaded and reused.
 func TestNewWebHostReusesExistingSession(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	existing, err := asp.CreateSession()
 	if err != nil {
@@ -116,8 +115,7 @@ func TestNewWebHostReusesExistingSession(t *testing.T) {
 
 // TestWebHostPersistSessionKeepsSingleSessionCookie verifies session persistence rewrites ASPSESSIONID instead of duplicating it.
 func TestWebHostPersistSessionKeepsSingleSessionCookie(t *testing.T) {
-	asp.SetSessionStorageDir(t.TempDir())
-	defer asp.SetSessionStorageDir(filepath.Join("temp", "session"))
+	useTestSessionDir(t)
 
 	req := httptest.NewRequest(http.MethodGet, "http://example.local/default.asp", nil)
 	recorder := httptest.NewRecorder()
@@ -233,6 +231,29 @@ func TestNewWebHostPopulatesRequestCollections(t *testing.T) {
 	}
 }
 
+// TestNewWebHostExposesAuthenticatedUser verifies AUTH_USER, LOGON_USER and REMOTE_USER
+// are filled from the user attached by the authentication provider.
+func TestNewWebHostExposesAuthenticatedUser(t *testing.T) {
+	useTestSessionDir(t)
+	req := httptest.NewRequest(http.MethodGet, "http://example.local/intranet/index.asp", nil)
+	req.SetBasicAuth("alice", "secret")
+	req = axonauth.WithUser(req, &axonauth.User{Name: "alice", Password: "secret", AuthType: axonauth.AuthTypeBasic})
+	host := NewWebHost(httptest.NewRecorder(), req)
+
+	expected := map[string]string{
+		"AUTH_TYPE":     "Basic",
+		"AUTH_USER":     "alice",
+		"AUTH_PASSWORD": "secret",
+		"LOGON_USER":    "alice",
+		"REMOTE_USER":   "alice",
+	}
+	for name, value := range expected {
+		if got := host.Request().ServerVars.Get(name); got != value {
+			t.Fatalf("expected %s=%q, got %q", name, value, got)
+		}
+	}
+}
+
 // TestNewWebHostDoesNotEagerReadGETBody verifies GET requests do not preload the request body.
 func TestNewWebHostDoesNotEagerReadGETBody(t *testing.T) {
 	body := newCountingReadCloser("alpha=1")
@@ -321,3 +342,46 @@ Response.Flush
 		t.Fatalf("unexpected binary body: %v", recorder.Body.Bytes())
 	}
 }
+
+// TestNewWebHostAppliesTrustedProxyHeaders verifies requests rewritten by a trusted
+// proxy report the client address, scheme, host and port in the server variables.
+func TestNewWebHostAppliesTrustedProxyHeaders(t *testing.T) {
+	useTestSessionDir(t)
+	proxy, err := axonproxy.New(axonproxy.Config{HeadersEnabled: true, TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatalf("create proxy: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://backend:8801/default.asp", nil)
+	req.RemoteAddr = "10.1.2.3:40000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "www.example.com")
+
+	var host *WebHost
+	proxy.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = NewWebHost(w, r)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := map[string]string{
+		"REMOTE_ADDR": "203.0.113.9",
+		"REMOTE_HOST": "203.0.113.9",
+		"HTTPS":       "on",
+		"SERVER_NAME": "www.example.com",
+		"SERVER_PORT": "443",
+	}
+	for name, want := range expected {
+		if got := host.Request().ServerVars.Get(name); got != want {
+			t.Fatalf("expected %s %q, got %q", name, want, got)
+		}
+	}
+}
+
+// useTestSessionDir stores the sessions of the test in a temporary directory.
+func useTestSessionDir(t *testing.T) {
+	t.Helper()
+	asp.SetSessionStorageDir(t.TempDir())
+	t.Cleanup(func() {
+		asp.WaitSessionWrites()
+		asp.SetSessionStorageDir("")
+	})
+}
@@ -31,9 +31,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-)
 
-var webConfigRuleBackRefRE = regexp.MustCompile(`(?i)\{R:(\d+)\}`)
+	"g3pix.com.br/axonasp/axonhandler"
+)
 
 // WebConfigProcessor loads and applies IIS-compatible web.config directives.
 type WebConfigProcessor struct {
@@ -42,6 +42,10 @@ type WebConfigProcessor struct {
 	rewriteRules []compiledRewriteRule
 	httpRedirect *compiledHTTPRedirect
 	httpErrors   map[int]WebConfigCustomError
+	rootLayer    *webConfigLayer
+	layers       webConfigLayerCache
+	rewriteMaps  map[string]*compiledRewriteMap
+	outbound     *compiledOutboundRules
 }
 
 // RewriteResult stores the output of a rewrite or redirect decision.
@@ -51,6 +55,11 @@ type RewriteResult struct {
 	RawQuery         string
 	RedirectLocation string
 	RedirectStatus   int
+	// StatusCode and SubStatusCode describe a CustomResponse action.
+	StatusCode    int
+	SubStatusCode int
+	// ServerVariables lists the serverVariables set actions of every matched rule, in order.
+	ServerVariables []RewriteServerVariable
 }
 
 // WebConfigCustomError stores one custom error mapping from web.config.
@@ -65,9 +74,13 @@ type webConfigFile struct {
 }
 
 type webConfigSystemServer struct {
-	HTTPErrors webConfigHTTPErrors `xml:"httpErrors"`
-	Rewrite    webConfigRewrite    `xml:"rewrite"`
-	Redirect   webConfigHTTPRedir  `xml:"httpRedirect"`
+	HTTPErrors      webConfigHTTPErrors      `xml:"httpErrors"`
+	Rewrite         webConfigRewrite         `xml:"rewrite"`
+	Redirect        webConfigHTTPRedir       `xml:"httpRedirect"`
+	HTTPProtocol    webConfigHTTPProtocol    `xml:"httpProtocol"`
+	StaticContent   webConfigStaticContent   `xml:"staticContent"`
+	DefaultDocument webConfigDefaultDocument `xml:"defaultDocument"`
+	Security        webConfigSecurity        `xml:"security"`
 }
 
 type webConfigHTTPErrors struct {
@@ -81,7 +94,9 @@ type webConfigError struct {
 }
 
 type webConfigRewrite struct {
-	Rules webConfigRewriteRules `xml:"rules"`
+	Rules         webConfigRewriteRules  `xml:"rules"`
+	RewriteMaps   webConfigRewriteMaps   `xml:"rewriteMaps"`
+	OutboundRules webConfigOutboundRules `xml:"outboundRules"`
 }
 
 type webConfigRewriteRules struct {
@@ -89,11 +104,12 @@ type webConfigRewriteRules struct {
 }
 
 type webConfigRewriteRule struct {
-	Name           string                     `xml:"name,attr"`
-	StopProcessing string                     `xml:"stopProcessing,attr"`
-	Match          webConfigRewriteMatch      `xml:"match"`
-	Conditions     webConfigRewriteConditions `xml:"conditions"`
-	Action         webConfigRewriteAction     `xml:"action"`
+	Name            string                     `xml:"name,attr"`
+	StopProcessing  string                     `xml:"stopProcessing,attr"`
+	Match           webConfigRewriteMatch      `xml:"match"`
+	Conditions      webConfigRewriteConditions `xml:"conditions"`
+	ServerVariables webConfigServerVariables   `xml:"serverVariables"`
+	Action          webConfigRewriteAction     `xml:"action"`
 }
 
 type webConfigRewriteMatch struct {
@@ -103,8 +119,9 @@ type webConfigRewriteMatch struct {
 }
 
 type webConfigRewriteConditions struct {
-	LogicalGrouping string                    `xml:"logicalGrouping,attr"`
-	Conditions      []webConfigRewriteCondAdd `xml:"add"`
+	LogicalGrouping  string                    `xml:"logicalGrouping,attr"`
+	TrackAllCaptures string                    `xml:"trackAllCaptures,attr"`
+	Conditions       []webConfigRewriteCondAdd `xml:"add"`
 }
 
 type webConfigRewriteCondAdd struct {
@@ -120,6 +137,8 @@ type webConfigRewriteAction struct {
 	URL               string `xml:"url,attr"`
 	AppendQueryString string `xml:"appendQueryString,attr"`
 	RedirectType      string `xml:"redirectType,attr"`
+	StatusCode        string `xml:"statusCode,attr"`
+	SubStatusCode     string `xml:"subStatusCode,attr"`
 }
 
 type webConfigHTTPRedir struct {
@@ -139,7 +158,11 @@ type compiledRewriteRule struct {
 	AppendQueryString bool
 	RedirectType      string
 	LogicalGrouping   string
+	TrackAllCaptures  bool
 	Conditions        []compiledRewriteCondition
+	ServerVariables   []compiledServerVariable
+	StatusCode        int
+	SubStatusCode     int
 }
 
 type compiledRewriteCondition struct {
@@ -159,16 +182,20 @@ type compiledHTTPRedirect struct {
 }
 
 // NewWebConfigProcessor parses and compiles web.config from the web root.
+// A missing root web.config is not an error: web.config files in subdirectories
+// are still honored for the sections that support inheritance.
 func NewWebConfigProcessor(rootDir string) (*WebConfigProcessor, error) {
 	configPath := filepath.Join(rootDir, "web.config")
+	var parsed webConfigFile
 	data, err := os.ReadFile(configPath)
-	if err != nil {
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
 		return nil, fmt.Errorf("read web.config: %w", err)
-	}
-
-	var parsed webConfigFile
-	if err := xml.Unmarshal(data, &parsed); err != nil {
-		return nil, fmt.Errorf("parse web.config: %w", err)
+	default:
+		if err := xml.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("parse web.config: %w", err)
+		}
 	}
 
 	processor := &WebConfigProcessor{
@@ -177,13 +204,32 @@ func NewWebConfigProcessor(rootDir string) (*WebConfigProcessor, error) {
 		rewriteRules: compileRewriteRules(parsed.SystemWebServer.Rewrite),
 		httpRedirect: compileHTTPRedirect(parsed.SystemWebServer.Redirect),
 		httpErrors:   compileCustomErrors(parsed.SystemWebServer.HTTPErrors),
+		rootLayer:    compileWebConfigLayer(parsed.SystemWebServer),
+		rewriteMaps:  compileRewriteMaps(parsed.SystemWebServer.Rewrite.RewriteMaps),
+		outbound:     compileOutboundRules(parsed.SystemWebServer.Rewrite.OutboundRules),
 	}
 
 	return processor, nil
 }
 
 // Apply evaluates global redirect and rewrite rules against one request path.
+// Server variables other than URL, REQUEST_URI, QUERY_STRING and REQUEST_FILENAME
+// resolve to empty strings; use ApplyRequest when the HTTP request is available.
 func (p *WebConfigProcessor) Apply(requestPath string, rawQuery string) (RewriteResult, bool) {
+	return p.apply(nil, requestPath, rawQuery)
+}
+
+// ApplyRequest evaluates global redirect and rewrite rules against one HTTP request,
+// exposing its headers and connection details as {HTTP_*}, {HTTPS} and friends.
+func (p *WebConfigProcessor) ApplyRequest(r *http.Request) (RewriteResult, bool) {
+	requestPath := r.URL.Path
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	return p.apply(r, requestPath, r.URL.RawQuery)
+}
+
+func (p *WebConfigProcessor) apply(r *http.Request, requestPath string, rawQuery string) (RewriteResult, bool) {
 	if p == nil {
 		return RewriteResult{}, false
 	}
@@ -199,9 +245,11 @@ func (p *WebConfigProcessor) Apply(requestPath string, rawQuery string) (Rewrite
 		return RewriteResult{}, false
 	}
 
+	ctx := &rewriteContext{processor: p, request: r, requestPath: requestPath, rawQuery: rawQuery}
 	currentPath := strings.TrimPrefix(requestPath, "/")
 	currentQuery := rawQuery
 	appliedRewrite := false
+	var serverVariables []RewriteServerVariable
 
 	for _, rule := range p.rewriteRules {
 		if rule.Regex == nil {
@@ -217,11 +265,14 @@ func (p *WebConfigProcessor) Apply(requestPath string, rawQuery string) (Rewrite
 			continue
 		}
 
-		if !p.evaluateConditions(rule, requestPath, matches) {
+		ctx.ruleMatches = matches
+		ctx.conditionMatches = nil
+		if !ctx.matchConditions(rule.Conditions, rule.LogicalGrouping, rule.TrackAllCaptures) {
 			continue
 		}
+		serverVariables = ctx.setServerVariables(rule.ServerVariables, serverVariables)
 
-		target := replaceRuleBackReferences(rule.ActionURL, matches)
+		target := ctx.expand(rule.ActionURL)
 		targetPath, targetQuery, isAbsolute := splitActionTarget(target)
 		switch rule.ActionType {
 		case "redirect":
@@ -231,6 +282,7 @@ func (p *WebConfigProcessor) Apply(requestPath string, rawQuery string) (Rewrite
 				ActionType:       "redirect",
 				RedirectLocation: location,
 				RedirectStatus:   mapRedirectStatus(rule.RedirectType),
+				ServerVariables:  serverVariables,
 			}, true
 		case "rewrite":
 			if targetPath != "" {
@@ -238,6 +290,16 @@ func (p *WebConfigProcessor) Apply(requestPath string, rawQuery string) (Rewrite
 			}
 			currentQuery = mergeQueryString(targetQuery, currentQuery, rule.AppendQueryString)
 			appliedRewrite = true
+		case "customresponse":
+			return RewriteResult{
+				ActionType:      "customresponse",
+				StatusCode:      rule.StatusCode,
+				SubStatusCode:   rule.SubStatusCode,
+				ServerVariables: serverVariables,
+			}, true
+		case "abortrequest":
+			return RewriteResult{ActionType: "abortrequest", ServerVariables: serverVariables}, true
+		case "none":
 		default:
 			continue
 		}
@@ -248,10 +310,13 @@ func (p *WebConfigProcessor) Apply(requestPath string, rawQuery string) (Rewrite
 	}
 
 	if !appliedRewrite {
+		if len(serverVariables) > 0 {
+			return RewriteResult{ActionType: "none", ServerVariables: serverVariables}, true
+		}
 		return RewriteResult{}, false
 	}
 
-	return RewriteResult{ActionType: "rewrite", Path: "/" + currentPath, RawQuery: currentQuery}, true
+	return RewriteResult{ActionType: "rewrite", Path: "/" + currentPath, RawQuery: currentQuery, ServerVariables: serverVariables}, true
 }
 
 // GetCustomError resolves one custom httpErrors mapping by status code.
@@ -263,20 +328,20 @@ func (p *WebConfigProcessor) GetCustomError(statusCode int) (WebConfigCustomErro
 	return entry, ok
 }
 
-func (p *WebConfigProcessor) evaluateConditions(rule compiledRewriteRule, requestPath string, matches []string) bool {
-	if len(rule.Conditions) == 0 {
+// matchConditions evaluates one conditions collection, recording {C:n} captures on ctx.
+func (ctx *rewriteContext) matchConditions(conditions []compiledRewriteCondition, logicalGrouping string, trackAllCaptures bool) bool {
+	if len(conditions) == 0 {
 		return true
 	}
 
-	logicalGrouping := strings.ToLower(strings.TrimSpace(rule.LogicalGrouping))
+	logicalGrouping = strings.ToLower(strings.TrimSpace(logicalGrouping))
 	if logicalGrouping == "" {
 		logicalGrouping = "matchall"
 	}
 
-	results := make([]bool, len(rule.Conditions))
-	for i, condition := range rule.Conditions {
-		input := replaceRuleBackReferences(condition.Input, matches)
-		input = p.resolveConditionInput(input, requestPath)
+	results := make([]bool, len(conditions))
+	for i, condition := range conditions {
+		input := ctx.expand(condition.Input)
 
 		matched := false
 		switch strings.ToLower(condition.MatchType) {
@@ -288,7 +353,15 @@ func (p *WebConfigProcessor) evaluateConditions(rule compiledRewriteRule, reques
 			matched = err == nil && info.IsDir()
 		case "pattern", "":
 			if condition.Regex != nil {
-				matched = condition.Regex.MatchString(input)
+				captures := condition.Regex.FindStringSubmatch(input)
+				matched = captures != nil
+				if matched && !condition.Negate {
+					if trackAllCaptures {
+						ctx.conditionMatches = append(ctx.conditionMatches, captures...)
+					} else {
+						ctx.conditionMatches = captures
+					}
+				}
 			}
 		}
 
@@ -315,20 +388,6 @@ func (p *WebConfigProcessor) evaluateConditions(rule compiledRewriteRule, reques
 	return true
 }
 
-func (p *WebConfigProcessor) resolveConditionInput(input string, requestPath string) string {
-	switch strings.ToUpper(strings.TrimSpace(input)) {
-	case "{REQUEST_FILENAME}":
-		relPath := strings.TrimPrefix(requestPath, "/")
-		return filepath.Join(p.rootDir, filepath.FromSlash(relPath))
-	case "{URL}":
-		return requestPath
-	case "{REQUEST_URI}":
-		return requestPath
-	default:
-		return input
-	}
-}
-
 func compileCustomErrors(httpErrors webConfigHTTPErrors) map[int]WebConfigCustomError {
 	if len(httpErrors.Errors) == 0 {
 		return nil
@@ -370,27 +429,7 @@ func compileRewriteRules(rewrite webConfigRewrite) []compiledRewriteRule {
 
 		actionType := strings.ToLower(strings.TrimSpace(candidate.Action.Type))
 		if actionType == "" {
-			continue
-		}
-
-		conditions := make([]compiledRewriteCondition, 0, len(candidate.Conditions.Conditions))
-		for _, rawCondition := range candidate.Conditions.Conditions {
-			matchType := strings.ToLower(strings.TrimSpace(rawCondition.MatchType))
-			if matchType == "" {
-				matchType = "pattern"
-			}
-			ignoreCase := parseWebConfigBool(rawCondition.IgnoreCase, true)
-			var condRegex *regexp.Regexp
-			if matchType == "pattern" {
-				condRegex, _ = compileRewriteRegex(strings.TrimSpace(rawCondition.Pattern), ignoreCase)
-			}
-			conditions = append(conditions, compiledRewriteCondition{
-				Input:      rawCondition.Input,
-				MatchType:  matchType,
-				Regex:      condRegex,
-				IgnoreCase: ignoreCase,
-				Negate:     parseWebConfigBool(rawCondition.Negate, false),
-			})
+			actionType = "none"
 		}
 
 		rules = append(rules, compiledRewriteRule{
@@ -402,7 +441,11 @@ func compileRewriteRules(rewrite webConfigRewrite) []compiledRewriteRule {
 			AppendQueryString: parseWebConfigBool(candidate.Action.AppendQueryString, true),
 			RedirectType:      strings.TrimSpace(candidate.Action.RedirectType),
 			LogicalGrouping:   strings.TrimSpace(candidate.Conditions.LogicalGrouping),
-			Conditions:        conditions,
+			TrackAllCaptures:  parseWebConfigBool(candidate.Conditions.TrackAllCaptures, false),
+			Conditions:        compileRewriteConditions(candidate.Conditions.Conditions),
+			ServerVariables:   compileServerVariables(candidate.ServerVariables),
+			StatusCode:        parseWebConfigInt(candidate.Action.StatusCode),
+			SubStatusCode:     parseWebConfigInt(candidate.Action.SubStatusCode),
 		})
 	}
 	if len(rules) == 0 {
@@ -411,6 +454,29 @@ func compileRewriteRules(rewrite webConfigRewrite) []compiledRewriteRule {
 	return rules
 }
 
+func compileRewriteConditions(rawConditions []webConfigRewriteCondAdd) []compiledRewriteCondition {
+	conditions := make([]compiledRewriteCondition, 0, len(rawConditions))
+	for _, rawCondition := range rawConditions {
+		matchType := strings.ToLower(strings.TrimSpace(rawCondition.MatchType))
+		if matchType == "" {
+			matchType = "pattern"
+		}
+		ignoreCase := parseWebConfigBool(rawCondition.IgnoreCase, true)
+		var condRegex *regexp.Regexp
+		if matchType == "pattern" {
+			condRegex, _ = compileRewriteRegex(strings.TrimSpace(rawCondition.Pattern), ignoreCase)
+		}
+		conditions = append(conditions, compiledRewriteCondition{
+			Input:      rawCondition.Input,
+			MatchType:  matchType,
+			Regex:      condRegex,
+			IgnoreCase: ignoreCase,
+			Negate:     parseWebConfigBool(rawCondition.Negate, false),
+		})
+	}
+	return conditions
+}
+
 func compileHTTPRedirect(redirect webConfigHTTPRedir) *compiledHTTPRedirect {
 	if strings.TrimSpace(redirect.Enabled) == "" && strings.TrimSpace(redirect.Destination) == "" {
 		return nil
@@ -495,6 +561,14 @@ func parseWebConfigBool(value string, defaultValue bool) bool {
 	return value == "true" || value == "1" || value == "yes"
 }
 
+func parseWebConfigInt(value string) int {
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
+
 func compileRewriteRegex(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
 	if pattern == "" {
 		return nil, fmt.Errorf("empty regex pattern")
@@ -505,23 +579,6 @@ func compileRewriteRegex(pattern string, ignoreCase bool) (*regexp.Regexp, error
 	return regexp.Compile(pattern)
 }
 
-func replaceRuleBackReferences(input string, matches []string) string {
-	if input == "" {
-		return input
-	}
-	return webConfigRuleBackRefRE.ReplaceAllStringFunc(input, func(value string) string {
-		parts := webConfigRuleBackRefRE.FindStringSubmatch(value)
-		if len(parts) != 2 {
-			return ""
-		}
-		index, err := strconv.Atoi(parts[1])
-		if err != nil || index < 0 || index >= len(matches) {
-			return ""
-		}
-		return matches[index]
-	})
-}
-
 func splitActionTarget(target string) (string, string, bool) {
 	if target == "" {
 		return "", "", false
@@ -609,7 +666,7 @@ func serveWebConfigCustomError(w http.ResponseWriter, r *http.Request, statusCod
 
 	switch responseMode {
 	case "executeurl":
-		resolvedPath, ok := resolveCustomErrorFilePath(target)
+		resolvedPath, ok := resolveCustomErrorFilePath(siteFromRequest(r).Root(), target)
 		if !ok {
 			return false
 		}
@@ -621,10 +678,10 @@ func serveWebConfigCustomError(w http.ResponseWriter, r *http.Request, statusCod
 			executeASPWithStatus(w, r, resolvedPath, statusCode)
 			return true
 		}
-		serveStaticFileWithMIME(newSingleHeaderResponseWriter(w, statusCode), r, resolvedPath)
+		serveStaticFileWithMIME(axonhandler.NewSingleHeaderWriter(w, statusCode), r, resolvedPath)
 		return true
 	case "file":
-		resolvedPath, ok := resolveCustomErrorFilePath(target)
+		resolvedPath, ok := resolveCustomErrorFilePath(siteFromRequest(r).Root(), target)
 		if !ok {
 			return false
 		}
@@ -632,7 +689,7 @@ func serveWebConfigCustomError(w http.ResponseWriter, r *http.Request, statusCod
 		if err != nil || info.IsDir() {
 			return false
 		}
-		serveStaticFileWithMIME(newSingleHeaderResponseWriter(w, statusCode), r, resolvedPath)
+		serveStaticFileWithMIME(axonhandler.NewSingleHeaderWriter(w, statusCode), r, resolvedPath)
 		return true
 	case "redirect":
 		http.Redirect(w, r, target, http.StatusFound)
@@ -642,7 +699,7 @@ func serveWebConfigCustomError(w http.ResponseWriter, r *http.Request, statusCod
 	}
 }
 
-func resolveCustomErrorFilePath(target string) (string, bool) {
+func resolveCustomErrorFilePath(rootDir string, target string) (string, bool) {
 	if strings.HasPrefix(strings.ToLower(target), "http://") || strings.HasPrefix(strings.ToLower(target), "https://") {
 		return "", false
 	}
@@ -652,10 +709,10 @@ func resolveCustomErrorFilePath(target string) (string, bool) {
 		candidate = target
 	} else {
 		trimmed := strings.TrimPrefix(filepath.ToSlash(target), "/")
-		candidate = filepath.Join(RootDir, filepath.FromSlash(trimmed))
+		candidate = filepath.Join(rootDir, filepath.FromSlash(trimmed))
 	}
 
-	absRoot, err := filepath.Abs(RootDir)
+	absRoot, err := filepath.Abs(rootDir)
 	if err != nil {
 		return "", false
 	}
@@ -0,0 +1,176 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonaccesslog"
+	"g3pix.com.br/axonasp/axonauth"
+	"g3pix.com.br/axonasp/axonvm"
+)
+
+type webConfigAuthenticationElement struct {
+	Anonymous webConfigAuthModule `xml:"anonymousAuthentication"`
+	Basic     webConfigAuthModule `xml:"basicAuthentication"`
+}
+
+type webConfigAuthModule struct {
+	Enabled string `xml:"enabled,attr"`
+	Realm   string `xml:"realm,attr"`
+}
+
+type webConfigAuthorizationElement struct {
+	Items []webConfigAuthorizationItem `xml:",any"`
+}
+
+type webConfigAuthorizationItem struct {
+	XMLName    xml.Name
+	AccessType string `xml:"accessType,attr"`
+	Users      string `xml:"users,attr"`
+	Roles      string `xml:"roles,attr"`
+	Verbs      string `xml:"verbs,attr"`
+}
+
+// webConfigAuthLayer holds the <authentication> and <authorization> sections of one web.config file.
+type webConfigAuthLayer struct {
+	anonymousEnabled string
+	basicEnabled     string
+	realm            string
+	authorization    []webConfigAuthorizationItem
+}
+
+// WebConfigAuthentication is the effective <authentication> element for one directory.
+type WebConfigAuthentication struct {
+	AnonymousEnabled bool
+	BasicEnabled     bool
+	Realm            string
+}
+
+func compileWebConfigAuthLayer(authentication webConfigAuthenticationElement, authorization webConfigAuthorizationElement) webConfigAuthLayer {
+	return webConfigAuthLayer{
+		anonymousEnabled: strings.TrimSpace(authentication.Anonymous.Enabled),
+		basicEnabled:     strings.TrimSpace(authentication.Basic.Enabled),
+		realm:            strings.TrimSpace(authentication.Basic.Realm),
+		authorization:    authorization.Items,
+	}
+}
+
+// applyAuthLayer merges one <authentication> and <authorization> section on top of the
+// inherited settings. Authorization rules use the IIS add/remove/clear semantics, keyed
+// by users, roles and verbs.
+func (s *WebConfigSettings) applyAuthLayer(layer webConfigAuthLayer) {
+	if layer.anonymousEnabled != "" {
+		s.Authentication.AnonymousEnabled = parseWebConfigBool(layer.anonymousEnabled, true)
+	}
+	if layer.basicEnabled != "" {
+		s.Authentication.BasicEnabled = parseWebConfigBool(layer.basicEnabled, true)
+	}
+	if layer.realm != "" {
+		s.Authentication.Realm = layer.realm
+	}
+	for _, item := range layer.authorization {
+		rule := axonauth.AccessRule{
+			Allow: !strings.EqualFold(strings.TrimSpace(item.AccessType), "Deny"),
+			Users: axonauth.ParseList(item.Users),
+			Roles: axonauth.ParseList(item.Roles),
+			Verbs: axonauth.ParseList(item.Verbs),
+		}
+		switch item.XMLName.Local {
+		case "clear":
+			s.Authorization = nil
+		case "remove", "add":
+			s.Authorization = slices.DeleteFunc(slices.Clone(s.Authorization), func(existing axonauth.AccessRule) bool {
+				return sameFoldList(existing.Users, rule.Users) && sameFoldList(existing.Roles, rule.Roles) && sameFoldList(existing.Verbs, rule.Verbs)
+			})
+			if item.XMLName.Local == "add" {
+				s.Authorization = append(s.Authorization, rule)
+			}
+		}
+	}
+}
+
+func sameFoldList(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if !strings.EqualFold(a[index], b[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+// authenticateRequest runs Basic authentication and URL authorization for urlPath.
+// It returns the request carrying the authenticated user, or false after answering
+// with a 401 challenge through the error page pipeline.
+func authenticateRequest(w http.ResponseWriter, r *http.Request, site *Site, urlPath string) (*http.Request, bool) {
+	if authenticator == nil {
+		return r, true
+	}
+	settings := webConfigSettingsForURL(site, urlPath)
+	realm := settings.Authentication.Realm
+	if realm == "" {
+		realm = authenticator.Realm()
+	}
+
+	// A rewritten request is authorized again; the user verified for the
+	// original URL is kept.
+	user := axonauth.UserFromRequest(r)
+	if user == nil && settings.Authentication.BasicEnabled {
+		authenticated, err := authenticator.Authenticate(r)
+		switch {
+		case errors.Is(err, axonauth.ErrInvalidCredentials):
+			challengeRequest(w, r, realm, 1)
+			return r, false
+		case err != nil:
+			respondInternalHTTPError(w, axonvm.ErrCouldNotReadFile, err, "Failed to verify the request credentials.", urlPath)
+			return r, false
+		}
+		user = authenticated
+	}
+
+	pathRules, protected := authenticator.PathRules(urlPath)
+	if user == nil && (protected || !settings.Authentication.AnonymousEnabled) {
+		challengeRequest(w, r, realm, 2)
+		return r, false
+	}
+	if !axonauth.Authorized(settings.Authorization, user, r.Method) || !axonauth.Authorized(pathRules, user, r.Method) {
+		challengeRequest(w, r, realm, 2)
+		return r, false
+	}
+	if user == nil {
+		return r, true
+	}
+	axonaccesslog.SetUsername(r, user.Name)
+	return axonauth.WithUser(r, user), true
+}
+
+// challengeRequest answers with 401.subStatus and a Basic challenge for realm.
+func challengeRequest(w http.ResponseWriter, r *http.Request, realm string, subStatus int) {
+	axonauth.Challenge(w, realm)
+	serveErrorPageWithSubStatus(w, r, http.StatusUnauthorized, subStatus)
+}
@@ -0,0 +1,767 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonhandler"
+	"g3pix.com.br/axonasp/axonproxy"
+)
+
+// webConfigOutboundTagAttributes lists the attributes IIS rewrites for each filterByTags value.
+var webConfigOutboundTagAttributes = map[string][]string{
+	"a":      {"href"},
+	"area":   {"href"},
+	"base":   {"href"},
+	"form":   {"action"},
+	"frame":  {"src", "longdesc"},
+	"head":   {"profile"},
+	"iframe": {"src", "longdesc"},
+	"img":    {"src", "longdesc", "usemap"},
+	"input":  {"src", "usemap"},
+	"link":   {"href"},
+	"script": {"src"},
+}
+
+var (
+	webConfigHTMLTagRE       = regexp.MustCompile(`<([A-Za-z][A-Za-z0-9:-]*)(\s[^>]*)?>`)
+	webConfigHTMLAttributeRE = regexp.MustCompile(`(\s)([A-Za-z_:][-A-Za-z0-9_:.]*)(\s*=\s*)("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+// RewriteServerVariable stores one value assigned by a serverVariables set action.
+type RewriteServerVariable struct {
+	Name  string
+	Value string
+}
+
+// rewriteServerVariablesContextKey stores the non-HTTP server variables set by rewrite rules.
+type rewriteServerVariablesContextKey struct{}
+
+type webConfigRewriteMaps struct {
+	Maps []webConfigRewriteMap `xml:"rewriteMap"`
+}
+
+type webConfigRewriteMap struct {
+	Name         string                     `xml:"name,attr"`
+	DefaultValue string                     `xml:"defaultValue,attr"`
+	IgnoreCase   string                     `xml:"ignoreCase,attr"`
+	Entries      []webConfigRewriteMapEntry `xml:"add"`
+}
+
+type webConfigRewriteMapEntry struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type webConfigServerVariables struct {
+	Set []webConfigServerVariableSet `xml:"set"`
+}
+
+type webConfigServerVariableSet struct {
+	Name    string `xml:"name,attr"`
+	Value   string `xml:"value,attr"`
+	Replace string `xml:"replace,attr"`
+}
+
+type webConfigOutboundRules struct {
+	Rules         []webConfigOutboundRule `xml:"rule"`
+	PreConditions webConfigPreConditions  `xml:"preConditions"`
+	CustomTags    webConfigCustomTags     `xml:"customTags"`
+}
+
+type webConfigOutboundRule struct {
+	Name           string                     `xml:"name,attr"`
+	PreCondition   string                     `xml:"preCondition,attr"`
+	StopProcessing string                     `xml:"stopProcessing,attr"`
+	Enabled        string                     `xml:"enabled,attr"`
+	Match          webConfigOutboundMatch     `xml:"match"`
+	Conditions     webConfigRewriteConditions `xml:"conditions"`
+	Action         webConfigOutboundAction    `xml:"action"`
+}
+
+type webConfigOutboundMatch struct {
+	FilterByTags   string `xml:"filterByTags,attr"`
+	CustomTags     string `xml:"customTags,attr"`
+	ServerVariable string `xml:"serverVariable,attr"`
+	Pattern        string `xml:"pattern,attr"`
+	IgnoreCase     string `xml:"ignoreCase,attr"`
+	Negate         string `xml:"negate,attr"`
+}
+
+type webConfigOutboundAction struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type webConfigPreConditions struct {
+	Items []webConfigPreCondition `xml:"preCondition"`
+}
+
+type webConfigPreCondition struct {
+	Name            string                    `xml:"name,attr"`
+	LogicalGrouping string                    `xml:"logicalGrouping,attr"`
+	Conditions      []webConfigRewriteCondAdd `xml:"add"`
+}
+
+type webConfigCustomTags struct {
+	Groups []webConfigCustomTagGroup `xml:"tags"`
+}
+
+type webConfigCustomTagGroup struct {
+	Name string               `xml:"name,attr"`
+	Tags []webConfigCustomTag `xml:"tag"`
+}
+
+type webConfigCustomTag struct {
+	Name      string `xml:"name,attr"`
+	Attribute string `xml:"attribute,attr"`
+}
+
+type compiledRewriteMap struct {
+	defaultValue string
+	ignoreCase   bool
+	entries      map[string]string
+}
+
+type compiledServerVariable struct {
+	Name    string
+	Value   string
+	Replace bool
+}
+
+type compiledOutboundRules struct {
+	rules         []compiledOutboundRule
+	preConditions map[string]compiledPreCondition
+}
+
+type compiledPreCondition struct {
+	logicalGrouping string
+	conditions      []compiledRewriteCondition
+}
+
+type compiledOutboundRule struct {
+	preCondition     string
+	stopProcessing   bool
+	header           string
+	tags             map[string][]string
+	regex            *regexp.Regexp
+	negate           bool
+	rewrite          bool
+	value            string
+	logicalGrouping  string
+	trackAllCaptures bool
+	conditions       []compiledRewriteCondition
+}
+
+// rewriteContext carries the request, response and capture state used to expand
+// URL Rewrite templates such as {HTTP_HOST}, {R:1}, {C:1} and {MapName:{R:1}}.
+type rewriteContext struct {
+	processor        *WebConfigProcessor
+	request          *http.Request
+	requestPath      string
+	rawQuery         string
+	ruleMatches      []string
+	conditionMatches []string
+	variables        map[string]string
+	responseHeader   http.Header
+	responseStatus   int
+}
+
+func compileRewriteMaps(maps webConfigRewriteMaps) map[string]*compiledRewriteMap {
+	if len(maps.Maps) == 0 {
+		return nil
+	}
+	result := make(map[string]*compiledRewriteMap, len(maps.Maps))
+	for _, rawMap := range maps.Maps {
+		name := strings.ToLower(strings.TrimSpace(rawMap.Name))
+		if name == "" {
+			continue
+		}
+		compiled := &compiledRewriteMap{
+			defaultValue: rawMap.DefaultValue,
+			ignoreCase:   parseWebConfigBool(rawMap.IgnoreCase, true),
+			entries:      make(map[string]string, len(rawMap.Entries)),
+		}
+		for _, entry := range rawMap.Entries {
+			key := entry.Key
+			if compiled.ignoreCase {
+				key = strings.ToLower(key)
+			}
+			compiled.entries[key] = entry.Value
+		}
+		result[name] = compiled
+	}
+	return result
+}
+
+// lookup returns the mapped value for key, or the map's defaultValue.
+func (m *compiledRewriteMap) lookup(key string) string {
+	if m.ignoreCase {
+		key = strings.ToLower(key)
+	}
+	if value, ok := m.entries[key]; ok {
+		return value
+	}
+	return m.defaultValue
+}
+
+func compileServerVariables(variables webConfigServerVariables) []compiledServerVariable {
+	if len(variables.Set) == 0 {
+		return nil
+	}
+	result := make([]compiledServerVariable, 0, len(variables.Set))
+	for _, set := range variables.Set {
+		name := strings.ToUpper(strings.TrimSpace(set.Name))
+		if name == "" {
+			continue
+		}
+		result = append(result, compiledServerVariable{
+			Name:    name,
+			Value:   set.Value,
+			Replace: parseWebConfigBool(set.Replace, true),
+		})
+	}
+	return result
+}
+
+func compileOutboundRules(outbound webConfigOutboundRules) *compiledOutboundRules {
+	if len(outbound.Rules) == 0 {
+		return nil
+	}
+
+	customTags := make(map[string][]webConfigCustomTag, len(outbound.CustomTags.Groups))
+	for _, group := range outbound.CustomTags.Groups {
+		customTags[strings.ToLower(strings.TrimSpace(group.Name))] = group.Tags
+	}
+
+	compiled := &compiledOutboundRules{preConditions: make(map[string]compiledPreCondition, len(outbound.PreConditions.Items))}
+	for _, item := range outbound.PreConditions.Items {
+		compiled.preConditions[strings.ToLower(strings.TrimSpace(item.Name))] = compiledPreCondition{
+			logicalGrouping: item.LogicalGrouping,
+			conditions:      compileRewriteConditions(item.Conditions),
+		}
+	}
+
+	for _, candidate := range outbound.Rules {
+		if !parseWebConfigBool(candidate.Enabled, true) {
+			continue
+		}
+		re, err := compileRewriteRegex(strings.TrimSpace(candidate.Match.Pattern), parseWebConfigBool(candidate.Match.IgnoreCase, true))
+		if err != nil {
+			continue
+		}
+		rule := compiledOutboundRule{
+			preCondition:     strings.ToLower(strings.TrimSpace(candidate.PreCondition)),
+			stopProcessing:   parseWebConfigBool(candidate.StopProcessing, false),
+			regex:            re,
+			negate:           parseWebConfigBool(candidate.Match.Negate, false),
+			rewrite:          strings.EqualFold(strings.TrimSpace(candidate.Action.Type), "Rewrite"),
+			value:            candidate.Action.Value,
+			logicalGrouping:  candidate.Conditions.LogicalGrouping,
+			trackAllCaptures: parseWebConfigBool(candidate.Conditions.TrackAllCaptures, false),
+			conditions:       compileRewriteConditions(candidate.Conditions.Conditions),
+		}
+
+		if variable := strings.ToUpper(strings.TrimSpace(candidate.Match.ServerVariable)); variable != "" {
+			// Only RESPONSE_* variables can be rewritten once the response exists.
+			if !strings.HasPrefix(variable, "RESPONSE_") {
+				continue
+			}
+			rule.header = headerFromServerVariable(strings.TrimPrefix(variable, "RESPONSE_"))
+		} else if filter := strings.TrimSpace(candidate.Match.FilterByTags); filter != "" {
+			rule.tags = make(map[string][]string)
+			for _, tag := range strings.Split(filter, ",") {
+				tag = strings.ToLower(strings.TrimSpace(tag))
+				if tag == "customtags" {
+					for _, custom := range customTags[strings.ToLower(strings.TrimSpace(candidate.Match.CustomTags))] {
+						name := strings.ToLower(strings.TrimSpace(custom.Name))
+						rule.tags[name] = append(rule.tags[name], strings.ToLower(strings.TrimSpace(custom.Attribute)))
+					}
+					continue
+				}
+				rule.tags[tag] = append(rule.tags[tag], webConfigOutboundTagAttributes[tag]...)
+			}
+		}
+		compiled.rules = append(compiled.rules, rule)
+	}
+	if len(compiled.rules) == 0 {
+		return nil
+	}
+	return compiled
+}
+
+// expand replaces every {...} token in template: back-references, rewrite map
+// lookups, the ToLower/UrlEncode/UrlDecode functions and server variables.
+// Braces that do not form a recognized token are copied unchanged.
+func (ctx *rewriteContext) expand(template string) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	var builder strings.Builder
+	for i := 0; i < len(template); {
+		if template[i] != '{' {
+			builder.WriteByte(template[i])
+			i++
+			continue
+		}
+		end := matchingRewriteBrace(template, i)
+		if end < 0 {
+			builder.WriteString(template[i:])
+			break
+		}
+		builder.WriteString(ctx.resolveToken(template[i+1 : end]))
+		i = end + 1
+	}
+	return builder.String()
+}
+
+func matchingRewriteBrace(template string, open int) int {
+	depth := 0
+	for i := open; i < len(template); i++ {
+		switch template[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (ctx *rewriteContext) resolveToken(token string) string {
+	name, argument, hasArgument := strings.Cut(token, ":")
+	if !hasArgument {
+		if isRewriteVariableName(token) {
+			return ctx.serverVariable(token)
+		}
+		return "{" + ctx.expand(token) + "}"
+	}
+
+	switch strings.ToUpper(name) {
+	case "R":
+		if index, err := strconv.Atoi(argument); err == nil {
+			return rewriteCapture(ctx.ruleMatches, index)
+		}
+	case "C":
+		if index, err := strconv.Atoi(argument); err == nil {
+			return rewriteCapture(ctx.conditionMatches, index)
+		}
+	}
+
+	value := ctx.expand(argument)
+	if ctx.processor != nil {
+		if rewriteMap, ok := ctx.processor.rewriteMaps[strings.ToLower(name)]; ok {
+			return rewriteMap.lookup(value)
+		}
+	}
+	switch strings.ToLower(name) {
+	case "tolower":
+		return strings.ToLower(value)
+	case "urlencode":
+		return url.QueryEscape(value)
+	case "urldecode":
+		if decoded, err := url.QueryUnescape(value); err == nil {
+			return decoded
+		}
+		return value
+	}
+	return "{" + name + ":" + value + "}"
+}
+
+func rewriteCapture(matches []string, index int) string {
+	if index < 0 || index >= len(matches) {
+		return ""
+	}
+	return matches[index]
+}
+
+func isRewriteVariableName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// serverVariable resolves one IIS server variable. Unknown names resolve to an
+// empty string, as in IIS.
+func (ctx *rewriteContext) serverVariable(name string) string {
+	upper := strings.ToUpper(name)
+	if value, ok := ctx.variables[upper]; ok {
+		return value
+	}
+	if ctx.responseHeader != nil && strings.HasPrefix(upper, "RESPONSE_") {
+		if upper == "RESPONSE_STATUS" {
+			return strconv.Itoa(ctx.responseStatus)
+		}
+		return ctx.responseHeader.Get(headerFromServerVariable(strings.TrimPrefix(upper, "RESPONSE_")))
+	}
+
+	switch upper {
+	case "URL", "PATH_INFO", "SCRIPT_NAME":
+		return ctx.requestPath
+	case "REQUEST_URI":
+		if ctx.rawQuery != "" {
+			return ctx.requestPath + "?" + ctx.rawQuery
+		}
+		return ctx.requestPath
+	case "QUERY_STRING":
+		return ctx.rawQuery
+	case "REQUEST_FILENAME":
+		relPath := strings.TrimPrefix(ctx.requestPath, "/")
+		return filepath.Join(ctx.processor.rootDir, filepath.FromSlash(relPath))
+	}
+
+	r := ctx.request
+	if r == nil {
+		return ""
+	}
+	switch upper {
+	case "HTTP_HOST":
+		return r.Host
+	case "HTTPS":
+		if axonproxy.IsHTTPS(r) {
+			return "on"
+		}
+		return "off"
+	case "SERVER_PORT_SECURE":
+		if axonproxy.IsHTTPS(r) {
+			return "1"
+		}
+		return "0"
+	case "REQUEST_METHOD":
+		return r.Method
+	case "REMOTE_ADDR", "REMOTE_HOST":
+		return axonhandler.RemoteAddr(r.RemoteAddr)
+	case "REMOTE_PORT":
+		_, port, _ := net.SplitHostPort(r.RemoteAddr)
+		return port
+	case "SERVER_NAME":
+		return axonhandler.ServerName(r)
+	case "SERVER_PORT":
+		return axonhandler.ServerPort(r)
+	case "SERVER_ADDR":
+		return axonhandler.ServerAddr(r)
+	case "SERVER_PROTOCOL":
+		return r.Proto
+	case "UNENCODED_URL":
+		return r.RequestURI
+	case "CACHE_URL":
+		scheme := "http://"
+		if axonproxy.IsHTTPS(r) {
+			scheme = "https://"
+		}
+		return scheme + r.Host + r.RequestURI
+	case "CONTENT_TYPE", "CONTENT_LENGTH":
+		return r.Header.Get(headerFromServerVariable(upper))
+	}
+	if strings.HasPrefix(upper, "HTTP_") {
+		return r.Header.Get(headerFromServerVariable(strings.TrimPrefix(upper, "HTTP_")))
+	}
+	return ""
+}
+
+// setServerVariables runs the serverVariables set actions of one matched rule
+// and appends the assigned values to applied.
+func (ctx *rewriteContext) setServerVariables(variables []compiledServerVariable, applied []RewriteServerVariable) []RewriteServerVariable {
+	for _, variable := range variables {
+		if !variable.Replace && ctx.serverVariable(variable.Name) != "" {
+			continue
+		}
+		value := ctx.expand(variable.Value)
+		if ctx.variables == nil {
+			ctx.variables = make(map[string]string)
+		}
+		ctx.variables[variable.Name] = value
+		applied = append(applied, RewriteServerVariable{Name: variable.Name, Value: value})
+	}
+	return applied
+}
+
+// headerFromServerVariable converts an IIS variable suffix such as X_FORWARDED_FOR
+// into its canonical header name.
+func headerFromServerVariable(name string) string {
+	return http.CanonicalHeaderKey(strings.ReplaceAll(name, "_", "-"))
+}
+
+// applyRewriteServerVariables applies serverVariables set actions to the request.
+// HTTP_* variables become request headers; the rest are carried in the request
+// context and surface through Request.ServerVariables.
+func applyRewriteServerVariables(r *http.Request, variables []RewriteServerVariable) *http.Request {
+	if len(variables) == 0 {
+		return r
+	}
+	var custom []RewriteServerVariable
+	for _, variable := range variables {
+		switch {
+		case variable.Name == "HTTP_HOST":
+			r.Host = variable.Value
+		case strings.HasPrefix(variable.Name, "HTTP_"):
+			header := headerFromServerVariable(strings.TrimPrefix(variable.Name, "HTTP_"))
+			if variable.Value == "" {
+				r.Header.Del(header)
+			} else {
+				r.Header.Set(header, variable.Value)
+			}
+		default:
+			custom = append(custom, variable)
+		}
+	}
+	if len(custom) == 0 {
+		return r
+	}
+	custom = append(rewriteServerVariablesFromRequest(r), custom...)
+	return r.WithContext(context.WithValue(r.Context(), rewriteServerVariablesContextKey{}, custom))
+}
+
+// rewriteServerVariablesFromRequest returns the non-HTTP server variables set by rewrite rules.
+func rewriteServerVariablesFromRequest(r *http.Request) []RewriteServerVariable {
+	variables, _ := r.Context().Value(rewriteServerVariablesContextKey{}).([]RewriteServerVariable)
+	return variables
+}
+
+// OutboundWriter wraps w so that web.config outbound rules rewrite response
+// headers and HTML links. It returns nil when no outbound rules are configured;
+// otherwise the caller must Close the writer once the response is complete.
+func (p *WebConfigProcessor) OutboundWriter(w http.ResponseWriter, r *http.Request) *outboundRewriteWriter {
+	if p == nil || p.outbound == nil {
+		return nil
+	}
+	return &outboundRewriteWriter{
+		ResponseWriter: w,
+		rules:          p.outbound,
+		ctx:            &rewriteContext{processor: p, request: r, requestPath: r.URL.Path, rawQuery: r.URL.RawQuery},
+		method:         r.Method,
+	}
+}
+
+// outboundRewriteWriter applies outbound rules when the response headers are
+// committed. Bodies matched by a tag or body rule are buffered until Close.
+type outboundRewriteWriter struct {
+	http.ResponseWriter
+	rules       *compiledOutboundRules
+	ctx         *rewriteContext
+	method      string
+	bodyRules   []compiledOutboundRule
+	wroteHeader bool
+	buffer      bytes.Buffer
+}
+
+// WriteHeader rewrites matching response headers and decides whether the body must be buffered.
+func (w *outboundRewriteWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	header := w.ResponseWriter.Header()
+	w.ctx.responseHeader = header
+	w.ctx.responseStatus = statusCode
+
+	for _, rule := range w.rules.rules {
+		if !w.preConditionMatches(rule.preCondition) {
+			continue
+		}
+		if rule.header == "" {
+			w.bodyRules = append(w.bodyRules, rule)
+			continue
+		}
+		value, ok := w.ctx.applyRule(rule, header.Get(rule.header))
+		if !ok {
+			continue
+		}
+		if value == "" {
+			header.Del(rule.header)
+		} else {
+			header.Set(rule.header, value)
+		}
+		if rule.stopProcessing {
+			break
+		}
+	}
+
+	bodyless := w.method == http.MethodHead || statusCode == http.StatusNoContent || statusCode == http.StatusNotModified
+	if len(w.bodyRules) > 0 && (bodyless || header.Get("Content-Encoding") != "") {
+		w.bodyRules = nil
+	}
+	if len(w.bodyRules) > 0 {
+		header.Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write buffers the body when body rules apply and passes it through otherwise.
+func (w *outboundRewriteWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.ResponseWriter.Header().Get("Content-Type") == "" {
+			w.ResponseWriter.Header().Set("Content-Type", http.DetectContentType(data))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if len(w.bodyRules) > 0 {
+		return w.buffer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush forwards flushes only while the body is not being buffered for rewriting.
+func (w *outboundRewriteWriter) Flush() {
+	if len(w.bodyRules) > 0 {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack exposes the underlying connection for protocol upgrades.
+func (w *outboundRewriteWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *outboundRewriteWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Close rewrites and writes any buffered body.
+func (w *outboundRewriteWriter) Close() {
+	if len(w.bodyRules) == 0 || w.buffer.Len() == 0 {
+		return
+	}
+	body := w.buffer.String()
+	for _, rule := range w.bodyRules {
+		rewritten, matched := w.ctx.rewriteBody(rule, body)
+		body = rewritten
+		if matched && rule.stopProcessing {
+			break
+		}
+	}
+	_, _ = w.ResponseWriter.Write([]byte(body))
+}
+
+func (w *outboundRewriteWriter) preConditionMatches(name string) bool {
+	if name == "" {
+		return true
+	}
+	preCondition, ok := w.rules.preConditions[name]
+	if !ok {
+		return false
+	}
+	return w.ctx.matchConditions(preCondition.conditions, preCondition.logicalGrouping, false)
+}
+
+// applyRule matches one value against an outbound rule and returns the rewritten value.
+func (ctx *rewriteContext) applyRule(rule compiledOutboundRule, value string) (string, bool) {
+	matches := rule.regex.FindStringSubmatch(value)
+	matched := matches != nil
+	if rule.negate {
+		matched = !matched
+		matches = []string{value}
+	}
+	if !matched {
+		return value, false
+	}
+	ctx.ruleMatches = matches
+	ctx.conditionMatches = nil
+	if !ctx.matchConditions(rule.conditions, rule.logicalGrouping, rule.trackAllCaptures) {
+		return value, false
+	}
+	if !rule.rewrite {
+		return value, true
+	}
+	return ctx.expand(rule.value), true
+}
+
+// rewriteBody applies one body rule: either to the listed tag attributes or,
+// without filterByTags, to every pattern match in the body.
+func (ctx *rewriteContext) rewriteBody(rule compiledOutboundRule, body string) (string, bool) {
+	matched := false
+	if rule.tags == nil {
+		if rule.negate {
+			return body, false
+		}
+		rewritten := rule.regex.ReplaceAllStringFunc(body, func(match string) string {
+			value, ok := ctx.applyRule(rule, match)
+			matched = matched || ok
+			return value
+		})
+		return rewritten, matched
+	}
+
+	rewritten := webConfigHTMLTagRE.ReplaceAllStringFunc(body, func(tag string) string {
+		parts := webConfigHTMLTagRE.FindStringSubmatch(tag)
+		attributes := rule.tags[strings.ToLower(parts[1])]
+		if len(attributes) == 0 || parts[2] == "" {
+			return tag
+		}
+		rewrittenAttributes := webConfigHTMLAttributeRE.ReplaceAllStringFunc(parts[2], func(attribute string) string {
+			attrParts := webConfigHTMLAttributeRE.FindStringSubmatch(attribute)
+			if !containsFold(attributes, attrParts[2]) {
+				return attribute
+			}
+			quoted := attrParts[4]
+			quote := ""
+			value := quoted
+			if quoted[0] == '"' || quoted[0] == '\'' {
+				quote = quoted[:1]
+				value = quoted[1 : len(quoted)-1]
+			}
+			value, ok := ctx.applyRule(rule, value)
+			if !ok {
+				return attribute
+			}
+			matched = true
+			if quote == "" {
+				quote = `"`
+			}
+			return attrParts[1] + attrParts[2] + attrParts[3] + quote + value + quote
+		})
+		return "<" + parts[1] + rewrittenAttributes + ">"
+	})
+	return rewritten, matched
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
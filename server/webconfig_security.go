@@ -0,0 +1,417 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"encoding/xml"
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+
+	"g3pix.com.br/axonasp/axonvm/asp"
+)
+
+type webConfigSecurity struct {
+	RequestFiltering webConfigRequestFilteringElement `xml:"requestFiltering"`
+	IPSecurity       webConfigIPSecurityElement       `xml:"ipSecurity"`
+	Authentication   webConfigAuthenticationElement   `xml:"authentication"`
+	Authorization    webConfigAuthorizationElement    `xml:"authorization"`
+}
+
+type webConfigRequestFilteringElement struct {
+	RequestLimits    webConfigRequestLimits `xml:"requestLimits"`
+	DenyURLSequences webConfigSecurityList  `xml:"denyUrlSequences"`
+	FileExtensions   webConfigAllowList     `xml:"fileExtensions"`
+	Verbs            webConfigAllowList     `xml:"verbs"`
+	HiddenSegments   webConfigSecurityList  `xml:"hiddenSegments"`
+}
+
+type webConfigRequestLimits struct {
+	MaxAllowedContentLength string `xml:"maxAllowedContentLength,attr"`
+	MaxURL                  string `xml:"maxUrl,attr"`
+	MaxQueryString          string `xml:"maxQueryString,attr"`
+}
+
+type webConfigSecurityList struct {
+	Items []webConfigSecurityItem `xml:",any"`
+}
+
+type webConfigAllowList struct {
+	AllowUnlisted string                  `xml:"allowUnlisted,attr"`
+	Items         []webConfigSecurityItem `xml:",any"`
+}
+
+type webConfigIPSecurityElement struct {
+	AllowUnlisted string                  `xml:"allowUnlisted,attr"`
+	DenyAction    string                  `xml:"denyAction,attr"`
+	Items         []webConfigSecurityItem `xml:",any"`
+}
+
+type webConfigSecurityItem struct {
+	XMLName       xml.Name
+	Sequence      string `xml:"sequence,attr"`
+	Segment       string `xml:"segment,attr"`
+	FileExtension string `xml:"fileExtension,attr"`
+	Verb          string `xml:"verb,attr"`
+	Allowed       string `xml:"allowed,attr"`
+	IPAddress     string `xml:"ipAddress,attr"`
+	SubnetMask    string `xml:"subnetMask,attr"`
+}
+
+// webConfigSecurityLayer holds the <security> section declared by one web.config file.
+type webConfigSecurityLayer struct {
+	limits            webConfigRequestLimits
+	sequenceOps       []webConfigOp
+	extensionOps      []webConfigOp
+	extensionsDefault string
+	verbOps           []webConfigOp
+	verbsDefault      string
+	segmentOps        []webConfigOp
+	ipOps             []webConfigOp
+	ipDefault         string
+	ipDenyAction      string
+	auth              webConfigAuthLayer
+}
+
+// WebConfigRequestFiltering is the effective <requestFiltering> element for one directory.
+// Zero limits are not enforced.
+type WebConfigRequestFiltering struct {
+	MaxAllowedContentLength int64
+	MaxURL                  int
+	MaxQueryString          int
+	DeniedURLSequences      []string
+	FileExtensions          map[string]bool
+	AllowUnlistedExtensions bool
+	Verbs                   map[string]bool
+	AllowUnlistedVerbs      bool
+	HiddenSegments          []string
+}
+
+// WebConfigIPRule is one <ipSecurity> entry.
+type WebConfigIPRule struct {
+	Prefix  netip.Prefix
+	Allowed bool
+}
+
+// WebConfigIPSecurity is the effective <ipSecurity> element for one directory.
+type WebConfigIPSecurity struct {
+	Rules         []WebConfigIPRule
+	AllowUnlisted bool
+	DenyAction    string
+}
+
+// compileWebConfigSecurityLayer extracts the <security> section of one parsed web.config.
+func compileWebConfigSecurityLayer(security webConfigSecurity) webConfigSecurityLayer {
+	filtering := security.RequestFiltering
+	layer := webConfigSecurityLayer{
+		limits:            filtering.RequestLimits,
+		extensionsDefault: strings.TrimSpace(filtering.FileExtensions.AllowUnlisted),
+		verbsDefault:      strings.TrimSpace(filtering.Verbs.AllowUnlisted),
+		ipDefault:         strings.TrimSpace(security.IPSecurity.AllowUnlisted),
+		ipDenyAction:      strings.TrimSpace(security.IPSecurity.DenyAction),
+		auth:              compileWebConfigAuthLayer(security.Authentication, security.Authorization),
+	}
+	for _, item := range filtering.DenyURLSequences.Items {
+		if op, ok := collectionOp(item.XMLName.Local, item.Sequence, ""); ok {
+			layer.sequenceOps = append(layer.sequenceOps, op)
+		}
+	}
+	for _, item := range filtering.FileExtensions.Items {
+		ext := strings.ToLower(strings.TrimSpace(item.FileExtension))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext == "." {
+			// IIS uses "." for extensionless URLs.
+			ext = ".."
+		}
+		if op, ok := collectionOp(item.XMLName.Local, ext, item.Allowed); ok {
+			layer.extensionOps = append(layer.extensionOps, op)
+		}
+	}
+	for _, item := range filtering.Verbs.Items {
+		if op, ok := collectionOp(item.XMLName.Local, strings.ToUpper(item.Verb), item.Allowed); ok {
+			layer.verbOps = append(layer.verbOps, op)
+		}
+	}
+	for _, item := range filtering.HiddenSegments.Items {
+		if op, ok := collectionOp(item.XMLName.Local, item.Segment, ""); ok {
+			layer.segmentOps = append(layer.segmentOps, op)
+		}
+	}
+	for _, item := range security.IPSecurity.Items {
+		key := strings.TrimSpace(item.IPAddress)
+		if mask := strings.TrimSpace(item.SubnetMask); key != "" && mask != "" {
+			key += "/" + mask
+		}
+		if op, ok := collectionOp(item.XMLName.Local, key, item.Allowed); ok {
+			layer.ipOps = append(layer.ipOps, op)
+		}
+	}
+	return layer
+}
+
+// applySecurityLayer merges one <security> section on top of the inherited settings.
+func (s *WebConfigSettings) applySecurityLayer(layer webConfigSecurityLayer) {
+	filtering := &s.RequestFiltering
+	if value, err := strconv.ParseInt(strings.TrimSpace(layer.limits.MaxAllowedContentLength), 10, 64); err == nil && value >= 0 {
+		filtering.MaxAllowedContentLength = value
+	}
+	if value, err := strconv.Atoi(strings.TrimSpace(layer.limits.MaxURL)); err == nil && value >= 0 {
+		filtering.MaxURL = value
+	}
+	if value, err := strconv.Atoi(strings.TrimSpace(layer.limits.MaxQueryString)); err == nil && value >= 0 {
+		filtering.MaxQueryString = value
+	}
+	filtering.DeniedURLSequences = applyListOps(filtering.DeniedURLSequences, layer.sequenceOps)
+	filtering.HiddenSegments = applyListOps(filtering.HiddenSegments, layer.segmentOps)
+	filtering.FileExtensions = applyAllowOps(filtering.FileExtensions, layer.extensionOps)
+	filtering.Verbs = applyAllowOps(filtering.Verbs, layer.verbOps)
+	if layer.extensionsDefault != "" {
+		filtering.AllowUnlistedExtensions = parseWebConfigBool(layer.extensionsDefault, true)
+	}
+	if layer.verbsDefault != "" {
+		filtering.AllowUnlistedVerbs = parseWebConfigBool(layer.verbsDefault, true)
+	}
+
+	ipSecurity := &s.IPSecurity
+	for _, op := range layer.ipOps {
+		switch op.Kind {
+		case "clear":
+			ipSecurity.Rules = nil
+		case "remove", "add":
+			prefix, ok := parseWebConfigIPPrefix(op.Key)
+			if !ok {
+				continue
+			}
+			for index, rule := range ipSecurity.Rules {
+				if rule.Prefix == prefix {
+					ipSecurity.Rules = append(ipSecurity.Rules[:index:index], ipSecurity.Rules[index+1:]...)
+					break
+				}
+			}
+			if op.Kind == "add" {
+				ipSecurity.Rules = append(ipSecurity.Rules, WebConfigIPRule{Prefix: prefix, Allowed: parseWebConfigBool(op.Value, false)})
+			}
+		}
+	}
+	if layer.ipDefault != "" {
+		ipSecurity.AllowUnlisted = parseWebConfigBool(layer.ipDefault, true)
+	}
+	if layer.ipDenyAction != "" {
+		ipSecurity.DenyAction = layer.ipDenyAction
+	}
+	s.applyAuthLayer(layer.auth)
+}
+
+// applyListOps edits a case-insensitive string collection.
+func applyListOps(values []string, ops []webConfigOp) []string {
+	for _, op := range ops {
+		switch op.Kind {
+		case "clear":
+			values = nil
+		case "remove", "add":
+			kept := values[:0:0]
+			for _, value := range values {
+				if !strings.EqualFold(value, op.Key) {
+					kept = append(kept, value)
+				}
+			}
+			values = kept
+			if op.Kind == "add" {
+				values = append(values, op.Key)
+			}
+		}
+	}
+	return values
+}
+
+// applyAllowOps edits an allowed/denied collection keyed by extension or verb.
+func applyAllowOps(values map[string]bool, ops []webConfigOp) map[string]bool {
+	for _, op := range ops {
+		switch op.Kind {
+		case "clear":
+			values = nil
+		case "remove":
+			delete(values, op.Key)
+		case "add":
+			if values == nil {
+				values = make(map[string]bool)
+			}
+			values[op.Key] = parseWebConfigBool(op.Value, true)
+		}
+	}
+	return values
+}
+
+// parseWebConfigIPPrefix parses "10.0.0.0/8", "192.168.1.0/255.255.255.0" or a single address.
+func parseWebConfigIPPrefix(value string) (netip.Prefix, bool) {
+	address, mask, hasMask := strings.Cut(strings.TrimSpace(value), "/")
+	addr, err := netip.ParseAddr(strings.TrimSpace(address))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	bits := addr.BitLen()
+	if hasMask {
+		mask = strings.TrimSpace(mask)
+		if parsed, err := strconv.Atoi(mask); err == nil {
+			bits = parsed
+		} else if maskAddr, err := netip.ParseAddr(mask); err == nil && maskAddr.Is4() == addr.Is4() {
+			ones, size := net.IPMask(maskAddr.AsSlice()).Size()
+			if size == 0 {
+				return netip.Prefix{}, false
+			}
+			bits = ones
+		} else {
+			return netip.Prefix{}, false
+		}
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// checkIPSecurity returns the status and sub-status used to reject a client, or ok=true.
+func (s WebConfigSettings) checkIPSecurity(remoteAddr string) (int, int, bool) {
+	if len(s.IPSecurity.Rules) == 0 && s.IPSecurity.AllowUnlisted {
+		return 0, 0, true
+	}
+	host := remoteAddr
+	if splitHost, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = splitHost
+	}
+	allowed := s.IPSecurity.AllowUnlisted
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, rule := range s.IPSecurity.Rules {
+			if rule.Prefix.Contains(addr) {
+				allowed = rule.Allowed
+				break
+			}
+		}
+	}
+	if allowed {
+		return 0, 0, true
+	}
+	switch strings.ToLower(s.IPSecurity.DenyAction) {
+	case "notfound":
+		return http.StatusNotFound, 0, false
+	case "unauthorized":
+		return http.StatusUnauthorized, 0, false
+	case "abortrequest":
+		return 0, 0, false
+	}
+	return http.StatusForbidden, 6, false
+}
+
+// checkRequestFiltering returns the IIS 404.x status of a request rejected by <requestFiltering>, or ok=true.
+func (s WebConfigSettings) checkRequestFiltering(r *http.Request, urlPath string) (int, int, bool) {
+	filtering := s.RequestFiltering
+	if filtering.MaxAllowedContentLength > 0 && r.ContentLength > filtering.MaxAllowedContentLength {
+		return http.StatusNotFound, 13, false
+	}
+	if filtering.MaxURL > 0 && len(urlPath) > filtering.MaxURL {
+		return http.StatusNotFound, 14, false
+	}
+	if filtering.MaxQueryString > 0 && len(r.URL.RawQuery) > filtering.MaxQueryString {
+		return http.StatusNotFound, 15, false
+	}
+	lowerPath := strings.ToLower(urlPath)
+	for _, sequence := range filtering.DeniedURLSequences {
+		if strings.Contains(lowerPath, strings.ToLower(sequence)) {
+			return http.StatusNotFound, 5, false
+		}
+	}
+	for _, segment := range strings.Split(urlPath, "/") {
+		for _, hidden := range filtering.HiddenSegments {
+			if segment != "" && strings.EqualFold(segment, hidden) {
+				return http.StatusNotFound, 8, false
+			}
+		}
+	}
+	if len(filtering.FileExtensions) > 0 || !filtering.AllowUnlistedExtensions {
+		ext := strings.ToLower(path.Ext(urlPath))
+		if ext == "" {
+			ext = ".."
+		}
+		allowed, listed := filtering.FileExtensions[ext]
+		if !listed {
+			allowed = filtering.AllowUnlistedExtensions
+		}
+		if !allowed {
+			return http.StatusNotFound, 7, false
+		}
+	}
+	if len(filtering.Verbs) > 0 || !filtering.AllowUnlistedVerbs {
+		allowed, listed := filtering.Verbs[strings.ToUpper(r.Method)]
+		if !listed {
+			allowed = filtering.AllowUnlistedVerbs
+		}
+		if !allowed {
+			return http.StatusNotFound, 6, false
+		}
+	}
+	return 0, 0, true
+}
+
+// webConfigSettingsForURL merges the web.config files governing urlPath, before the
+// path is resolved on disk. Without web.config support it returns the defaults.
+func webConfigSettingsForURL(site *Site, urlPath string) WebConfigSettings {
+	baseDir := site.Root()
+	relativePath := strings.TrimPrefix(urlPath, "/")
+	if dir, rest, ok := asp.MatchVirtualDirectory(site.Applications().VirtualDirectories(), urlPath); ok {
+		baseDir = dir.PhysicalPath
+		relativePath = rest
+	}
+	return site.WebConfig().Settings(baseDir, webConfigRelativeDir(relativePath, strings.HasSuffix(urlPath, "/")), nil)
+}
+
+// enforceWebConfigSecurity applies <ipSecurity> and <requestFiltering> of the directories
+// governing urlPath. It writes the IIS-style error response and returns false when the
+// request is rejected.
+func enforceWebConfigSecurity(w http.ResponseWriter, r *http.Request, site *Site, urlPath string) bool {
+	if site.WebConfig() == nil {
+		return true
+	}
+	settings := webConfigSettingsForURL(site, urlPath)
+
+	status, subStatus, ok := settings.checkIPSecurity(r.RemoteAddr)
+	if ok {
+		status, subStatus, ok = settings.checkRequestFiltering(r, urlPath)
+	}
+	if ok {
+		if limit := settings.RequestFiltering.MaxAllowedContentLength; limit > 0 && r.ContentLength < 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		return true
+	}
+	if status == 0 {
+		panic(http.ErrAbortHandler)
+	}
+	serveErrorPageWithSubStatus(w, r, status, subStatus)
+	return false
+}
@@ -0,0 +1,396 @@
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"g3pix.com.br/axonasp/axonauth"
+)
+
+type webConfigHTTPProtocol struct {
+	CustomHeaders webConfigCollection `xml:"customHeaders"`
+}
+
+type webConfigStaticContent struct {
+	ClientCache webConfigClientCacheElement `xml:"clientCache"`
+	Items       []webConfigCollectionItem   `xml:",any"`
+}
+
+type webConfigDefaultDocument struct {
+	Enabled string              `xml:"enabled,attr"`
+	Files   webConfigCollection `xml:"files"`
+}
+
+// webConfigCollection keeps add/remove/clear children in document order.
+type webConfigCollection struct {
+	Items []webConfigCollectionItem `xml:",any"`
+}
+
+type webConfigCollectionItem struct {
+	XMLName       xml.Name
+	Name          string `xml:"name,attr"`
+	Value         string `xml:"value,attr"`
+	FileExtension string `xml:"fileExtension,attr"`
+	MimeType      string `xml:"mimeType,attr"`
+}
+
+type webConfigClientCacheElement struct {
+	CacheControlMode   string `xml:"cacheControlMode,attr"`
+	CacheControlMaxAge string `xml:"cacheControlMaxAge,attr"`
+	HTTPExpires        string `xml:"httpExpires,attr"`
+	CacheControlCustom string `xml:"cacheControlCustom,attr"`
+}
+
+// webConfigOp is one add, remove or clear entry of an inheritable collection.
+type webConfigOp struct {
+	Kind  string
+	Key   string
+	Value string
+}
+
+// webConfigLayer holds the inheritable sections declared by one web.config file.
+type webConfigLayer struct {
+	headerOps        []webConfigOp
+	mimeOps          []webConfigOp
+	documentOps      []webConfigOp
+	documentsEnabled string
+	clientCache      webConfigClientCacheElement
+	security         webConfigSecurityLayer
+}
+
+// webConfigLayerCache caches parsed subdirectory web.config files by modification time.
+type webConfigLayerCache struct {
+	mu      sync.Mutex
+	entries map[string]webConfigLayerCacheEntry
+}
+
+type webConfigLayerCacheEntry struct {
+	modTime time.Time
+	size    int64
+	layer   *webConfigLayer
+}
+
+// WebConfigHeader is one response header from <httpProtocol><customHeaders>.
+type WebConfigHeader struct {
+	Name  string
+	Value string
+}
+
+// WebConfigClientCache is the effective <staticContent><clientCache> element.
+type WebConfigClientCache struct {
+	Mode        string
+	MaxAge      time.Duration
+	HTTPExpires string
+	Custom      string
+}
+
+// WebConfigSettings is the merged view of customHeaders, mimeMap, clientCache, defaultDocument
+// and security for one directory, after applying every web.config from the web root down.
+type WebConfigSettings struct {
+	CustomHeaders          []WebConfigHeader
+	RemovedHeaders         []string
+	MIMETypes              map[string]string
+	ClientCache            WebConfigClientCache
+	DefaultDocumentEnabled bool
+	DefaultDocuments       []string
+	RequestFiltering       WebConfigRequestFiltering
+	IPSecurity             WebConfigIPSecurity
+	Authentication         WebConfigAuthentication
+	Authorization          []axonauth.AccessRule
+}
+
+// compileWebConfigLayer extracts the inheritable sections of one parsed web.config.
+func compileWebConfigLayer(section webConfigSystemServer) *webConfigLayer {
+	layer := &webConfigLayer{
+		documentsEnabled: strings.TrimSpace(section.DefaultDocument.Enabled),
+		clientCache:      section.StaticContent.ClientCache,
+		security:         compileWebConfigSecurityLayer(section.Security),
+	}
+	for _, item := range section.HTTPProtocol.CustomHeaders.Items {
+		if op, ok := collectionOp(item.XMLName.Local, item.Name, item.Value); ok {
+			layer.headerOps = append(layer.headerOps, op)
+		}
+	}
+	for _, item := range section.StaticContent.Items {
+		kind := item.XMLName.Local
+		if kind == "mimeMap" {
+			kind = "add"
+		}
+		ext := strings.ToLower(strings.TrimSpace(item.FileExtension))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if op, ok := collectionOp(kind, ext, item.MimeType); ok {
+			layer.mimeOps = append(layer.mimeOps, op)
+		}
+	}
+	for _, item := range section.DefaultDocument.Files.Items {
+		if op, ok := collectionOp(item.XMLName.Local, item.Value, ""); ok {
+			layer.documentOps = append(layer.documentOps, op)
+		}
+	}
+	return layer
+}
+
+// collectionOp builds one collection entry, ignoring unknown elements and entries without a key.
+func collectionOp(kind string, key string, value string) (webConfigOp, bool) {
+	key = strings.TrimSpace(key)
+	switch kind {
+	case "clear":
+		return webConfigOp{Kind: kind}, true
+	case "add", "remove":
+		if key == "" || key == "." {
+			return webConfigOp{}, false
+		}
+		return webConfigOp{Kind: kind, Key: key, Value: value}, true
+	}
+	return webConfigOp{}, false
+}
+
+// Settings merges the inheritable sections for a directory. baseDir is the physical root the
+// request was mapped to (the web root or a virtual directory) and relativeDir the slash-separated
+// directory below it. defaultDocuments is the server list that <defaultDocument> edits.
+func (p *WebConfigProcessor) Settings(baseDir string, relativeDir string, defaultDocuments []string) WebConfigSettings {
+	settings := WebConfigSettings{
+		DefaultDocumentEnabled: true,
+		DefaultDocuments:       defaultDocuments,
+		RequestFiltering:       WebConfigRequestFiltering{AllowUnlistedExtensions: true, AllowUnlistedVerbs: true},
+		IPSecurity:             WebConfigIPSecurity{AllowUnlisted: true},
+		Authentication:         WebConfigAuthentication{AnonymousEnabled: true, BasicEnabled: true},
+	}
+	if p == nil {
+		return settings
+	}
+
+	layers := []*webConfigLayer{p.rootLayer}
+	if filepath.Clean(baseDir) != filepath.Clean(p.rootDir) {
+		layers = append(layers, p.layers.load(baseDir))
+	}
+	dir := baseDir
+	for _, segment := range strings.Split(strings.Trim(path.Clean("/"+relativeDir), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		dir = filepath.Join(dir, segment)
+		layers = append(layers, p.layers.load(dir))
+	}
+
+	documents := slices.Clone(defaultDocuments)
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		settings.applyLayer(layer)
+		documents = applyDocumentOps(documents, layer.documentOps)
+	}
+	settings.DefaultDocuments = documents
+	return settings
+}
+
+// applyLayer merges one web.config on top of the settings inherited from its parents.
+func (s *WebConfigSettings) applyLayer(layer *webConfigLayer) {
+	for _, op := range layer.headerOps {
+		switch op.Kind {
+		case "clear":
+			s.CustomHeaders = nil
+		case "remove":
+			s.CustomHeaders = slices.DeleteFunc(s.CustomHeaders, func(h WebConfigHeader) bool { return strings.EqualFold(h.Name, op.Key) })
+			s.RemovedHeaders = append(s.RemovedHeaders, op.Key)
+		case "add":
+			s.CustomHeaders = slices.DeleteFunc(s.CustomHeaders, func(h WebConfigHeader) bool { return strings.EqualFold(h.Name, op.Key) })
+			s.CustomHeaders = append(s.CustomHeaders, WebConfigHeader{Name: op.Key, Value: op.Value})
+		}
+	}
+
+	for _, op := range layer.mimeOps {
+		switch op.Kind {
+		case "clear":
+			s.MIMETypes = nil
+		case "remove":
+			delete(s.MIMETypes, op.Key)
+		case "add":
+			if s.MIMETypes == nil {
+				s.MIMETypes = make(map[string]string)
+			}
+			s.MIMETypes[op.Key] = strings.TrimSpace(op.Value)
+		}
+	}
+
+	cache := layer.clientCache
+	if mode := strings.TrimSpace(cache.CacheControlMode); mode != "" {
+		s.ClientCache.Mode = mode
+	}
+	if maxAge, ok := parseWebConfigTimeSpan(cache.CacheControlMaxAge); ok {
+		s.ClientCache.MaxAge = maxAge
+	}
+	if expires := strings.TrimSpace(cache.HTTPExpires); expires != "" {
+		s.ClientCache.HTTPExpires = expires
+	}
+	if custom := strings.TrimSpace(cache.CacheControlCustom); custom != "" {
+		s.ClientCache.Custom = custom
+	}
+
+	if layer.documentsEnabled != "" {
+		s.DefaultDocumentEnabled = parseWebConfigBool(layer.documentsEnabled, true)
+	}
+	s.applySecurityLayer(layer.security)
+}
+
+// applyDocumentOps edits the default document list the way IIS merges <files>.
+func applyDocumentOps(documents []string, ops []webConfigOp) []string {
+	for _, op := range ops {
+		switch op.Kind {
+		case "clear":
+			documents = nil
+		case "remove":
+			documents = slices.DeleteFunc(documents, func(name string) bool { return strings.EqualFold(name, op.Key) })
+		case "add":
+			if !slices.ContainsFunc(documents, func(name string) bool { return strings.EqualFold(name, op.Key) }) {
+				documents = append(documents, op.Key)
+			}
+		}
+	}
+	return documents
+}
+
+// ApplyHeaders removes and sets the custom response headers.
+func (s WebConfigSettings) ApplyHeaders(header http.Header) {
+	for _, name := range s.RemovedHeaders {
+		header.Del(name)
+	}
+	for _, custom := range s.CustomHeaders {
+		header.Set(custom.Name, custom.Value)
+	}
+}
+
+// MIMEType returns the mimeMap content type for a file extension.
+func (s WebConfigSettings) MIMEType(ext string) (string, bool) {
+	contentType, ok := s.MIMETypes[strings.ToLower(ext)]
+	return contentType, ok && contentType != ""
+}
+
+// ApplyClientCache writes the Cache-Control and Expires headers of <clientCache> for static content.
+func (s WebConfigSettings) ApplyClientCache(header http.Header) {
+	cache := s.ClientCache
+	switch strings.ToLower(cache.Mode) {
+	case "disablecache":
+		header.Set("Cache-Control", "no-cache")
+	case "usemaxage":
+		value := "max-age=" + strconv.FormatInt(int64(cache.MaxAge/time.Second), 10)
+		if cache.Custom != "" {
+			value = cache.Custom + ", " + value
+		}
+		header.Set("Cache-Control", value)
+	case "useexpires":
+		if cache.HTTPExpires != "" {
+			header.Set("Expires", cache.HTTPExpires)
+		}
+		if cache.Custom != "" {
+			header.Set("Cache-Control", cache.Custom)
+		}
+	}
+}
+
+// parseWebConfigTimeSpan parses an IIS TimeSpan such as "7.00:00:00" or "01:30:00".
+func parseWebConfigTimeSpan(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	days := 0
+	if dayPart, clock, ok := strings.Cut(value, "."); ok && strings.Count(clock, ":") == 2 {
+		parsed, err := strconv.Atoi(dayPart)
+		if err != nil || parsed < 0 {
+			return 0, false
+		}
+		days = parsed
+		value = clock
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	total := time.Duration(days) * 24 * time.Hour
+	for index, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		parsed, err := strconv.Atoi(parts[index])
+		if err != nil || parsed < 0 {
+			return 0, false
+		}
+		total += time.Duration(parsed) * unit
+	}
+	return total, true
+}
+
+// load returns the parsed web.config of one directory, re-reading it when the file changes.
+func (c *webConfigLayerCache) load(dir string) *webConfigLayer {
+	configPath := filepath.Join(dir, "web.config")
+	info, err := os.Stat(configPath)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[configPath]; ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.layer
+	}
+
+	var layer *webConfigLayer
+	var parsed webConfigFile
+	data, err := os.ReadFile(configPath)
+	if err == nil {
+		err = xml.Unmarshal(data, &parsed)
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to load %s, ignoring it: %v\n", configPath, err)
+	} else {
+		layer = compileWebConfigLayer(parsed.SystemWebServer)
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]webConfigLayerCacheEntry)
+	}
+	c.entries[configPath] = webConfigLayerCacheEntry{modTime: info.ModTime(), size: info.Size(), layer: layer}
+	return layer
+}
+
+// webConfigRelativeDir returns the slash-separated directory that governs a request path.
+func webConfigRelativeDir(relativePath string, isDir bool) string {
+	relativePath = strings.Trim(relativePath, "/")
+	if isDir {
+		return relativePath
+	}
+	dir := path.Dir(relativePath)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
@@ -22,9 +22,13 @@ package main
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"g3pix.com.br/axonasp/axonauth"
 )
 
 func TestWebConfigRewriteBackReference(t *testing.T) {
@@ -128,3 +132,534 @@ func TestWebConfigCustomErrors(t *testing.T) {
 		t.Fatalf("unexpected custom error mode: %q", errConfig.ResponseMode)
 	}
 }
+
+func TestWebConfigNestedStaticSettings(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"web.config": `<configuration>
+  <system.webServer>
+    <httpProtocol>
+      <customHeaders>
+        <remove name="X-Powered-By" />
+        <add name="X-Frame-Options" value="DENY" />
+        <add name="X-Site" value="root" />
+      </customHeaders>
+    </httpProtocol>
+    <staticContent>
+      <mimeMap fileExtension=".data" mimeType="application/x-axon-data" />
+      <clientCache cacheControlMode="UseMaxAge" cacheControlMaxAge="1.00:00:00" cacheControlCustom="public" />
+    </staticContent>
+  </system.webServer>
+</configuration>`,
+		"docs/web.config": `<configuration>
+  <system.webServer>
+    <httpProtocol>
+      <customHeaders>
+        <remove name="X-Site" />
+        <add name="X-Section" value="docs" />
+      </customHeaders>
+    </httpProtocol>
+    <staticContent>
+      <remove fileExtension=".data" />
+      <mimeMap fileExtension=".data" mimeType="text/plain" />
+      <clientCache cacheControlMode="DisableCache" />
+    </staticContent>
+    <defaultDocument>
+      <files>
+        <clear />
+        <add value="start.htm" />
+      </files>
+    </defaultDocument>
+  </system.webServer>
+</configuration>`,
+		"report.data":      "root data",
+		"docs/start.htm":   "docs start",
+		"docs/index.html":  "docs index",
+		"docs/report.data": "docs data",
+	}
+	for name, content := range files {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatalf("create dir: %v", err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	processor, err := NewWebConfigProcessor(root)
+	if err != nil {
+		t.Fatalf("create processor: %v", err)
+	}
+	originalRoot := RootDir
+	originalWebConfig := activeWebConfig
+	RootDir = root
+	activeWebConfig = processor
+	defer func() {
+		RootDir = originalRoot
+		activeWebConfig = originalWebConfig
+	}()
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("X-Powered-By", "AxonASP")
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := serve("/report.data")
+	if rec.Body.String() != "root data" || rec.Header().Get("Content-Type") != "application/x-axon-data" {
+		t.Fatalf("unexpected root response %q %v", rec.Body.String(), rec.Header())
+	}
+	if rec.Header().Get("Cache-Control") != "public, max-age=86400" || rec.Header().Get("X-Site") != "root" || rec.Header().Get("X-Powered-By") != "" {
+		t.Fatalf("unexpected root headers %v", rec.Header())
+	}
+
+	rec = serve("/docs/report.data")
+	if rec.Header().Get("Content-Type") != "text/plain" || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("expected docs/web.config to override static settings, got %v", rec.Header())
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" || rec.Header().Get("X-Section") != "docs" || rec.Header().Get("X-Site") != "" {
+		t.Fatalf("expected inherited and overridden custom headers, got %v", rec.Header())
+	}
+
+	rec = serve("/docs/")
+	if rec.Body.String() != "docs start" {
+		t.Fatalf("expected docs default document start.htm, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWebConfigRequestFilteringAndIPSecurity(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"web.config": `<configuration>
+  <system.webServer>
+    <security>
+      <requestFiltering>
+        <requestLimits maxAllowedContentLength="16" maxQueryString="10" />
+        <denyUrlSequences><add sequence="~" /></denyUrlSequences>
+        <fileExtensions allowUnlisted="true"><add fileExtension=".bak" allowed="false" /></fileExtensions>
+        <verbs allowUnlisted="true"><add verb="TRACE" allowed="false" /></verbs>
+        <hiddenSegments><add segment="private" /></hiddenSegments>
+      </requestFiltering>
+    </security>
+  </system.webServer>
+</configuration>`,
+		"admin/web.config": `<configuration>
+  <system.webServer>
+    <security>
+      <ipSecurity allowUnlisted="false">
+        <add ipAddress="10.1.0.0" subnetMask="255.255.0.0" allowed="true" />
+        <add ipAddress="192.168.0.0/16" allowed="true" />
+      </ipSecurity>
+    </security>
+  </system.webServer>
+</configuration>`,
+		"page.html":        "home",
+		"old.bak":          "backup",
+		"admin/index.html": "admin",
+	}
+	for name, content := range files {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatalf("create dir: %v", err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	processor, err := NewWebConfigProcessor(root)
+	if err != nil {
+		t.Fatalf("create processor: %v", err)
+	}
+	originalRoot := RootDir
+	originalWebConfig := activeWebConfig
+	originalErrorPages := DefaultErrorPagesDirectory
+	RootDir = root
+	activeWebConfig = processor
+	DefaultErrorPagesDirectory = t.TempDir()
+	defer func() {
+		RootDir = originalRoot
+		activeWebConfig = originalWebConfig
+		DefaultErrorPagesDirectory = originalErrorPages
+	}()
+
+	cases := []struct {
+		method     string
+		target     string
+		body       string
+		remoteAddr string
+		status     int
+		message    string
+	}{
+		{http.MethodGet, "/page.html", "", "203.0.113.5:1000", http.StatusOK, "home"},
+		{http.MethodGet, "/old.bak", "", "203.0.113.5:1000", http.StatusNotFound, "Not Found (404.7)\n"},
+		{"TRACE", "/page.html", "", "203.0.113.5:1000", http.StatusNotFound, "Not Found (404.6)\n"},
+		{http.MethodGet, "/private/data.txt", "", "203.0.113.5:1000", http.StatusNotFound, "Not Found (404.8)\n"},
+		{http.MethodGet, "/~user/", "", "203.0.113.5:1000", http.StatusNotFound, "Not Found (404.5)\n"},
+		{http.MethodGet, "/page.html?q=0123456789", "", "203.0.113.5:1000", http.StatusNotFound, "Not Found (404.15)\n"},
+		{http.MethodPost, "/page.html", strings.Repeat("x", 32), "203.0.113.5:1000", http.StatusNotFound, "Not Found (404.13)\n"},
+		{http.MethodGet, "/admin/", "", "203.0.113.5:1000", http.StatusForbidden, "Forbidden (403.6)\n"},
+		{http.MethodGet, "/admin/", "", "10.1.20.30:1000", http.StatusOK, "admin"},
+		{http.MethodGet, "/admin/", "", "192.168.5.1:1000", http.StatusOK, "admin"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		req.RemoteAddr = tc.remoteAddr
+		rec := httptest.NewRecorder()
+		handleRequest(rec, req)
+		if rec.Code != tc.status || rec.Body.String() != tc.message {
+			t.Fatalf("%s %s from %s: expected %d %q, got %d %q", tc.method, tc.target, tc.remoteAddr, tc.status, tc.message, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestWebConfigRewriteMapsAndServerVariables(t *testing.T) {
+	root := t.TempDir()
+	config := `<?xml version="1.0" encoding="UTF-8"?>
+<configuration>
+  <system.webServer>
+    <rewrite>
+      <rewriteMaps>
+        <rewriteMap name="Legacy" defaultValue="">
+          <add key="/Old-Page.asp" value="/new/page.asp" />
+        </rewriteMap>
+      </rewriteMaps>
+      <rules>
+        <rule name="Proxy headers">
+          <match url=".*" />
+          <conditions>
+            <add input="{HTTPS}" pattern="^off$" />
+          </conditions>
+          <serverVariables>
+            <set name="HTTP_X_ORIGINAL_HOST" value="{HTTP_HOST}" />
+            <set name="APP_SCHEME" value="http" />
+          </serverVariables>
+          <action type="None" />
+        </rule>
+        <rule name="Legacy map" stopProcessing="true">
+          <match url=".*" />
+          <conditions>
+            <add input="{Legacy:{REQUEST_URI}}" pattern="(.+)" />
+          </conditions>
+          <action type="Rewrite" url="{C:1}?lang={ToLower:{HTTP_ACCEPT_LANGUAGE}}" appendQueryString="false" />
+        </rule>
+        <rule name="Blocked agent" stopProcessing="true">
+          <match url=".*" />
+          <conditions>
+            <add input="{HTTP_USER_AGENT}" pattern="BadBot" />
+          </conditions>
+          <action type="CustomResponse" statusCode="403" subStatusCode="0" />
+        </rule>
+      </rules>
+    </rewrite>
+  </system.webServer>
+</configuration>`
+	if err := os.WriteFile(filepath.Join(root, "web.config"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write web.config: %v", err)
+	}
+
+	processor, err := NewWebConfigProcessor(root)
+	if err != nil {
+		t.Fatalf("create processor: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/old-page.asp", nil)
+	req.Header.Set("Accept-Language", "PT-BR")
+	result, ok := processor.ApplyRequest(req)
+	if !ok || result.ActionType != "rewrite" {
+		t.Fatalf("expected rewrite, got %+v", result)
+	}
+	if result.Path != "/new/page.asp" || result.RawQuery != "lang=pt-br" {
+		t.Fatalf("unexpected rewrite target: %q ? %q", result.Path, result.RawQuery)
+	}
+	if len(result.ServerVariables) != 2 || result.ServerVariables[0].Value != "example.com" || result.ServerVariables[1].Name != "APP_SCHEME" {
+		t.Fatalf("unexpected server variables: %+v", result.ServerVariables)
+	}
+
+	applied := applyRewriteServerVariables(req, result.ServerVariables)
+	if applied.Header.Get("X-Original-Host") != "example.com" {
+		t.Fatalf("expected X-Original-Host header, got %q", applied.Header.Get("X-Original-Host"))
+	}
+	if custom := rewriteServerVariablesFromRequest(applied); len(custom) != 1 || custom[0].Value != "http" {
+		t.Fatalf("unexpected custom server variables: %+v", custom)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/other.asp", nil)
+	req.Header.Set("User-Agent", "BadBot/1.0")
+	result, ok = processor.ApplyRequest(req)
+	if !ok || result.ActionType != "customresponse" || result.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 custom response, got %+v", result)
+	}
+}
+
+func TestWebConfigOutboundRules(t *testing.T) {
+	root := t.TempDir()
+	config := `<?xml version="1.0" encoding="UTF-8"?>
+<configuration>
+  <system.webServer>
+    <rewrite>
+      <outboundRules>
+        <rule name="Links" preCondition="IsHTML">
+          <match filterByTags="A, Img" pattern="^http://internal:8080/(.*)" />
+          <action type="Rewrite" value="https://{HTTP_HOST}/{R:1}" />
+        </rule>
+        <rule name="Location">
+          <match serverVariable="RESPONSE_Location" pattern="^http://internal:8080/(.*)" />
+          <action type="Rewrite" value="/{R:1}" />
+        </rule>
+        <preConditions>
+          <preCondition name="IsHTML">
+            <add input="{RESPONSE_CONTENT_TYPE}" pattern="^text/html" />
+          </preCondition>
+        </preConditions>
+      </outboundRules>
+    </rewrite>
+  </system.webServer>
+</configuration>`
+	if err := os.WriteFile(filepath.Join(root, "web.config"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write web.config: %v", err)
+	}
+
+	processor, err := NewWebConfigProcessor(root)
+	if err != nil {
+		t.Fatalf("create processor: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/page.asp", nil)
+	rec := httptest.NewRecorder()
+	writer := processor.OutboundWriter(rec, req)
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Set("Location", "http://internal:8080/next.asp")
+	_, _ = writer.Write([]byte(`<a href="http://internal:8080/docs/">Docs</a> <img alt=x src='http://internal:8080/logo.png'> <p title="http://internal:8080/">`))
+	writer.Close()
+
+	if got := rec.Header().Get("Location"); got != "/next.asp" {
+		t.Fatalf("expected rewritten Location, got %q", got)
+	}
+	expected := `<a href="https://example.com/docs/">Docs</a> <img alt=x src='https://example.com/logo.png'> <p title="http://internal:8080/">`
+	if rec.Body.String() != expected {
+		t.Fatalf("unexpected body:\n%s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	writer = processor.OutboundWriter(rec, req)
+	writer.Header().Set("Content-Type", "application/json")
+	_, _ = writer.Write([]byte(`{"href":"http://internal:8080/x"}`))
+	writer.Close()
+	if rec.Body.String() != `{"href":"http://internal:8080/x"}` {
+		t.Fatalf("expected non-HTML body untouched, got %s", rec.Body.String())
+	}
+}
+
+func TestWebConfigAuthenticationAndAuthorization(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"page.html": "home",
+		"admin/web.config": `<?xml version="1.0" encoding="UTF-8"?>
+<configuration>
+  <system.webServer>
+    <security>
+      <authentication>
+        <anonymousAuthentication enabled="false" />
+        <basicAuthentication enabled="true" realm="Admin Area" />
+      </authentication>
+      <authorization>
+        <add accessType="Allow" users="alice" />
+        <add accessType="Allow" roles="Staff" verbs="GET" />
+      </authorization>
+    </security>
+  </system.webServer>
+</configuration>`,
+		"admin/page.html":   "admin",
+		"reports/page.html": "reports",
+	}
+	for name, content := range files {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatalf("create dir: %v", err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	authDir := t.TempDir()
+	passwords := filepath.Join(authDir, ".htpasswd")
+	groups := filepath.Join(authDir, ".htgroup")
+	// {SHA} of "password" for every user.
+	if err := os.WriteFile(passwords, []byte("alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\nbob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\ncarol:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0o600); err != nil {
+		t.Fatalf("write htpasswd: %v", err)
+	}
+	if err := os.WriteFile(groups, []byte("Staff: bob\n"), 0o600); err != nil {
+		t.Fatalf("write htgroup: %v", err)
+	}
+
+	processor, err := NewWebConfigProcessor(root)
+	if err != nil {
+		t.Fatalf("create processor: %v", err)
+	}
+	auth, err := axonauth.New(axonauth.Config{Enabled: true, Realm: "AxonASP", HtpasswdFile: passwords, GroupFile: groups, ProtectedPaths: []string{"/reports/"}})
+	if err != nil {
+		t.Fatalf("create authenticator: %v", err)
+	}
+	originalRoot := RootDir
+	originalWebConfig := activeWebConfig
+	originalErrorPages := DefaultErrorPagesDirectory
+	originalAuthenticator := authenticator
+	RootDir = root
+	activeWebConfig = processor
+	DefaultErrorPagesDirectory = t.TempDir()
+	authenticator = auth
+	defer func() {
+		RootDir = originalRoot
+		activeWebConfig = originalWebConfig
+		DefaultErrorPagesDirectory = originalErrorPages
+		authenticator = originalAuthenticator
+	}()
+
+	cases := []struct {
+		method    string
+		target    string
+		user      string
+		password  string
+		status    int
+		message   string
+		challenge string
+	}{
+		{http.MethodGet, "/page.html", "", "", http.StatusOK, "home", ""},
+		{http.MethodGet, "/page.html", "alice", "wrong", http.StatusUnauthorized, "Unauthorized (401.1)\n", `Basic realm="AxonASP", charset="UTF-8"`},
+		{http.MethodGet, "/admin/page.html", "", "", http.StatusUnauthorized, "Unauthorized (401.2)\n", `Basic realm="Admin Area", charset="UTF-8"`},
+		{http.MethodGet, "/admin/page.html", "alice", "password", http.StatusOK, "admin", ""},
+		{http.MethodGet, "/admin/page.html", "bob", "password", http.StatusOK, "admin", ""},
+		{http.MethodPost, "/admin/page.html", "bob", "password", http.StatusUnauthorized, "Unauthorized (401.2)\n", `Basic realm="Admin Area", charset="UTF-8"`},
+		{http.MethodGet, "/admin/page.html", "carol", "password", http.StatusUnauthorized, "Unauthorized (401.2)\n", `Basic realm="Admin Area", charset="UTF-8"`},
+		{http.MethodGet, "/reports/page.html", "", "", http.StatusUnauthorized, "Unauthorized (401.2)\n", `Basic realm="AxonASP", charset="UTF-8"`},
+		{http.MethodGet, "/reports/page.html", "carol", "password", http.StatusOK, "reports", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.target, nil)
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.password)
+		}
+		rec := httptest.NewRecorder()
+		handleRequest(rec, req)
+		if rec.Code != tc.status || rec.Body.String() != tc.message || rec.Header().Get("WWW-Authenticate") != tc.challenge {
+			t.Fatalf("%s %s as %q: expected %d %q %q, got %d %q %q", tc.method, tc.target, tc.user, tc.status, tc.message, tc.challenge, rec.Code, rec.Body.String(), rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+// TestWebConfigRewriteIsAuthorizedOnRewrittenPath verifies that a rewrite from
+// a public URL into a protected or filtered path is checked like a direct
+// request for that path, as IIS authorizes the rewritten URL.
+func TestWebConfigRewriteIsAuthorizedOnRewrittenPath(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"web.config": `<?xml version="1.0" encoding="UTF-8"?>
+<configuration>
+  <system.webServer>
+    <rewrite>
+      <rules>
+        <rule name="Reports" stopProcessing="true">
+          <match url="^go/(.*)$" />
+          <action type="Rewrite" url="/reports/{R:1}" />
+        </rule>
+        <rule name="Staff" stopProcessing="true">
+          <match url="^staff/(.*)$" />
+          <action type="Rewrite" url="/admin/{R:1}" />
+        </rule>
+        <rule name="Files" stopProcessing="true">
+          <match url="^files/(.*)$" />
+          <action type="Rewrite" url="/private/{R:1}" />
+        </rule>
+      </rules>
+    </rewrite>
+    <security>
+      <requestFiltering>
+        <hiddenSegments><add segment="private" /></hiddenSegments>
+      </requestFiltering>
+    </security>
+  </system.webServer>
+</configuration>`,
+		"admin/web.config": `<?xml version="1.0" encoding="UTF-8"?>
+<configuration>
+  <system.webServer>
+    <security>
+      <authentication>
+        <anonymousAuthentication enabled="false" />
+        <basicAuthentication enabled="true" realm="Admin Area" />
+      </authentication>
+      <authorization>
+        <add accessType="Allow" users="alice" />
+      </authorization>
+    </security>
+  </system.webServer>
+</configuration>`,
+		"admin/page.html":   "admin",
+		"reports/page.html": "reports",
+		"private/data.txt":  "private",
+	}
+	for name, content := range files {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatalf("create dir: %v", err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	passwords := filepath.Join(t.TempDir(), ".htpasswd")
+	// {SHA} of "password" for every user.
+	if err := os.WriteFile(passwords, []byte("alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\ncarol:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0o600); err != nil {
+		t.Fatalf("write htpasswd: %v", err)
+	}
+
+	processor, err := NewWebConfigProcessor(root)
+	if err != nil {
+		t.Fatalf("create processor: %v", err)
+	}
+	auth, err := axonauth.New(axonauth.Config{Enabled: true, Realm: "AxonASP", HtpasswdFile: passwords, ProtectedPaths: []string{"/reports/"}})
+	if err != nil {
+		t.Fatalf("create authenticator: %v", err)
+	}
+	originalRoot := RootDir
+	originalWebConfig := activeWebConfig
+	originalErrorPages := DefaultErrorPagesDirectory
+	originalAuthenticator := authenticator
+	RootDir = root
+	activeWebConfig = processor
+	DefaultErrorPagesDirectory = t.TempDir()
+	authenticator = auth
+	defer func() {
+		RootDir = originalRoot
+		activeWebConfig = originalWebConfig
+		DefaultErrorPagesDirectory = originalErrorPages
+		authenticator = originalAuthenticator
+	}()
+
+	cases := []struct {
+		target  string
+		user    string
+		status  int
+		message string
+	}{
+		{"/go/page.html", "", http.StatusUnauthorized, "Unauthorized (401.2)\n"},
+		{"/go/page.html", "carol", http.StatusOK, "reports"},
+		{"/staff/page.html", "", http.StatusUnauthorized, "Unauthorized (401.2)\n"},
+		{"/staff/page.html", "carol", http.StatusUnauthorized, "Unauthorized (401.2)\n"},
+		{"/staff/page.html", "alice", http.StatusOK, "admin"},
+		{"/files/data.txt", "", http.StatusNotFound, "Not Found (404.8)\n"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, "password")
+		}
+		rec := httptest.NewRecorder()
+		handleRequest(rec, req)
+		if rec.Code != tc.status || rec.Body.String() != tc.message {
+			t.Fatalf("GET %s as %q: expected %d %q, got %d %q", tc.target, tc.user, tc.status, tc.message, rec.Code, rec.Body.String())
+		}
+	}
+}
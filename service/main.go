@@ -30,6 +30,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -62,6 +63,7 @@ type program struct {
 
 	cmdMu      sync.Mutex
 	cmd        *execCmdWrapper
+	manager    *serviceManager
 	isStopping atomic.Bool
 }
 
@@ -82,15 +84,19 @@ func (p *program) run() {
 
 	cmd := buildOSCommand(childConfig.execPath, childConfig.env)
 	cmd.Dir = childConfig.workDir
+	manager := newServiceManager(cmd)
+	defer manager.Close()
 
 	p.cmdMu.Lock()
 	p.cmd = &execCmdWrapper{Cmd: cmd}
+	p.manager = manager
 	p.cmdMu.Unlock()
 
 	if err := cmd.Start(); err != nil {
 		p.logError(axonvm.ErrServiceStartProcessFailed, err.Error())
 		exitWithCode(axonvm.ErrServiceStartProcessFailed)
 	}
+	manager.started(cmd.Process.Pid)
 
 	p.logger.Info("AxonASP child server process started.")
 
@@ -100,6 +106,9 @@ func (p *program) run() {
 	p.cmd = nil
 	p.cmdMu.Unlock()
 
+	// After a graceful upgrade the child exits once the new server is ready.
+	manager.followUpgrades(cmd.Process.Pid)
+
 	if p.isStopping.Load() {
 		p.logger.Info("AxonASP child server process stopped by service request.")
 		return
@@ -120,8 +129,13 @@ func (p *program) Stop(s service.Service) error {
 
 	p.cmdMu.Lock()
 	cmd := p.cmd
+	manager := p.manager
 	p.cmdMu.Unlock()
 
+	if manager.stopUpgradedServer() {
+		p.logger.Info("Stopping upgraded AxonASP server process.")
+		return nil
+	}
 	if cmd == nil || cmd.Cmd == nil || cmd.Cmd.Process == nil {
 		return nil
 	}
@@ -242,6 +256,18 @@ func hasFileExtension(p string) bool {
 	return strings.Contains(base, ".")
 }
 
+// withoutEnvironment returns env without the named variables.
+func withoutEnvironment(env []string, names ...string) []string {
+	filtered := make([]string, 0, len(env))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if !slices.Contains(names, name) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
 // mergeServiceEnvironment validates service env entries and appends them to inherited env.
 func mergeServiceEnvironment(extra []string) ([]string, error) {
 	if len(extra) == 0 {
@@ -0,0 +1,175 @@
+//go:build !windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"g3pix.com.br/axonasp/axonupgrade"
+)
+
+// serviceManager connects the child server to systemd. It hands over the sockets of
+// socket activation, relays the sd_notify messages of the server and follows the
+// server process across graceful upgrades.
+type serviceManager struct {
+	conn      *net.UnixConn
+	path      string
+	childPID  atomic.Int64
+	serverPID atomic.Int64
+	signals   chan os.Signal
+}
+
+// newServiceManager prepares cmd before it starts: activation sockets are passed as
+// listeners and NOTIFY_SOCKET points to a relay socket owned by the wrapper.
+func newServiceManager(cmd *exec.Cmd) *serviceManager {
+	m := &serviceManager{}
+	axonupgrade.PassListeners(cmd, axonupgrade.ActivationFiles())
+
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("axonasp-service-%d.sock", os.Getpid()))
+	_ = os.Remove(path)
+	if conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"}); err == nil {
+		m.conn, m.path = conn, path
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		// The server pings the relay, which pings systemd on behalf of the wrapper.
+		cmd.Env = append(withoutEnvironment(env, "NOTIFY_SOCKET", "WATCHDOG_PID"), "NOTIFY_SOCKET="+path)
+		go m.relay()
+	}
+
+	m.signals = make(chan os.Signal, 1)
+	signal.Notify(m.signals, axonupgrade.Signals()...)
+	go m.forwardSignals()
+	return m
+}
+
+// started records the process ID of the child server.
+func (m *serviceManager) started(pid int) {
+	if m != nil {
+		m.childPID.Store(int64(pid))
+	}
+}
+
+// relay forwards readiness and watchdog messages to systemd and records the
+// MAINPID announced when the server hands over to a new process.
+func (m *serviceManager) relay() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := m.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		for line := range strings.SplitSeq(string(buf[:n]), "\n") {
+			switch {
+			case line == "READY=1", line == "WATCHDOG=1", strings.HasPrefix(line, "STATUS="):
+				_ = axonupgrade.Notify(line)
+			case strings.HasPrefix(line, "MAINPID="):
+				if pid, err := strconv.Atoi(strings.TrimPrefix(line, "MAINPID=")); err == nil && pid > 0 {
+					m.serverPID.Store(int64(pid))
+				}
+			}
+		}
+	}
+}
+
+// currentPID returns the process that serves requests: the last server announced
+// through MAINPID while it runs, otherwise the child started by the wrapper.
+func (m *serviceManager) currentPID() int {
+	if pid := int(m.serverPID.Load()); pid > 0 && processAlive(pid) {
+		return pid
+	}
+	return int(m.childPID.Load())
+}
+
+// forwardSignals passes upgrade signals, as sent by systemctl reload, to the server.
+func (m *serviceManager) forwardSignals() {
+	for sig := range m.signals {
+		if pid := m.currentPID(); pid > 0 {
+			_ = syscall.Kill(pid, sig.(syscall.Signal))
+		}
+	}
+}
+
+// followUpgrades blocks while a server that replaced exitedPID through a graceful
+// upgrade is running, following further upgrades.
+func (m *serviceManager) followUpgrades(exitedPID int) {
+	if m == nil {
+		return
+	}
+	// Give the relay a moment to read the MAINPID sent just before the exit.
+	time.Sleep(100 * time.Millisecond)
+	for {
+		pid := int(m.serverPID.Load())
+		if pid <= 0 || pid == exitedPID || !processAlive(pid) {
+			return
+		}
+		for processAlive(pid) {
+			time.Sleep(time.Second)
+		}
+		exitedPID = pid
+	}
+}
+
+// stopUpgradedServer sends SIGTERM to a server started by a graceful upgrade. It
+// reports false when the child started by the wrapper is still the server.
+func (m *serviceManager) stopUpgradedServer() bool {
+	if m == nil {
+		return false
+	}
+	pid := m.currentPID()
+	if pid <= 0 || pid == int(m.childPID.Load()) {
+		return false
+	}
+	_ = syscall.Kill(pid, syscall.SIGTERM)
+	return true
+}
+
+// Close stops the relay and the signal forwarding.
+func (m *serviceManager) Close() {
+	if m == nil {
+		return
+	}
+	signal.Stop(m.signals)
+	if m.conn != nil {
+		_ = m.conn.Close()
+		_ = os.Remove(m.path)
+	}
+}
+
+// processAlive reports whether pid names a running process.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
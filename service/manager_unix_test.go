@@ -0,0 +1,81 @@
+//go:build !windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestServiceManagerRelaysNotifyMessages verifies that the relay forwards readiness
+// to systemd and follows the MAINPID announced by an upgraded server.
+func TestServiceManagerRelaysNotifyMessages(t *testing.T) {
+	upstreamPath := filepath.Join(t.TempDir(), "systemd.sock")
+	upstream, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: upstreamPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	t.Setenv("NOTIFY_SOCKET", upstreamPath)
+	t.Setenv("WATCHDOG_PID", "1")
+
+	cmd := exec.Command("true")
+	m := newServiceManager(cmd)
+	defer m.Close()
+	if m.conn == nil {
+		t.Fatal("expected the relay socket to be created")
+	}
+	if !slices.Contains(cmd.Env, "NOTIFY_SOCKET="+m.path) || slices.Contains(cmd.Env, "WATCHDOG_PID=1") {
+		t.Fatalf("expected the child environment to point at the relay, got %v", cmd.Env)
+	}
+	m.started(1)
+
+	child, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: m.path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer child.Close()
+	if _, err := child.Write([]byte("READY=1\nMAINPID=" + strconv.Itoa(os.Getpid()))); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = upstream.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, err := upstream.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Fatalf("expected READY=1 to reach systemd, got %q (%v)", buf[:n], err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for m.currentPID() != os.Getpid() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the announced MAINPID to become the server, got %d", m.currentPID())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,48 @@
+//go:build windows
+
+/*
+ * AxonASP Server
+ * Copyright (C) 2026 G3pix Ltda. All rights reserved.
+ *
+ * Developed by Lucas Guimarães - G3pix Ltda
+ * Contact: https://g3pix.com.br
+ * Project URL: https://g3pix.com.br/axonasp
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Attribution Notice:
+ * If this software is used in other projects, the name "AxonASP Server"
+ * must be cited in the documentation or "About" section.
+ *
+ * Contribution Policy:
+ * Modifications to the core source code of AxonASP Server must be
+ * made available under this same license terms.
+ */
+package main
+
+import "os/exec"
+
+// serviceManager is not used on Windows, where the Service Control Manager has no
+// socket activation and the server does not upgrade in place.
+type serviceManager struct{}
+
+// newServiceManager returns nil on Windows.
+func newServiceManager(*exec.Cmd) *serviceManager {
+	return nil
+}
+
+// started does nothing on Windows.
+func (m *serviceManager) started(int) {}
+
+// followUpgrades returns at once on Windows.
+func (m *serviceManager) followUpgrades(int) {}
+
+// stopUpgradedServer reports false on Windows.
+func (m *serviceManager) stopUpgradedServer() bool {
+	return false
+}
+
+// Close does nothing on Windows.
+func (m *serviceManager) Close() {}